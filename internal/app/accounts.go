@@ -0,0 +1,174 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/internal/config"
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// addAccountFlags registers --profiles and --accounts-file on commands that can scan several accounts
+func addAccountFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("profiles", nil, "Scan each of these AWS profiles and report them together, e.g. prod,staging")
+	cmd.Flags().String("accounts-file", "", "YAML file listing the accounts to scan by profile and/or role ARN")
+}
+
+// accounts returns the accounts given with --profiles or --accounts-file, or
+// nil when the command scans a single account
+func (a *App) accounts(cmd *cobra.Command) ([]config.Account, error) {
+	profiles, _ := cmd.Flags().GetStringSlice("profiles")
+	accountsFile, _ := cmd.Flags().GetString("accounts-file")
+	if len(profiles) == 0 && accountsFile == "" {
+		return nil, nil
+	}
+	if len(profiles) > 0 && accountsFile != "" {
+		return nil, fmt.Errorf("--profiles and --accounts-file cannot be used together")
+	}
+
+	// Settings that pick one account conflict, unless they only come from the environment or a config file
+	for _, key := range []string{"profile", "role-arn"} {
+		if a.settingSource(key) == config.SourceFlag {
+			return nil, fmt.Errorf("--%s cannot be used with --profiles or --accounts-file", key)
+		}
+	}
+	if cmd.Flags().Changed("from-file") {
+		return nil, fmt.Errorf("--from-file cannot be used with --profiles or --accounts-file")
+	}
+	if cmd.Flags().Changed("check-objects") {
+		return nil, fmt.Errorf("--check-objects cannot be used with --profiles or --accounts-file")
+	}
+	// A bucket lives in one account, so every other account would fail to list it
+	if cmd.Flags().Changed("bucket") {
+		return nil, fmt.Errorf("--bucket cannot be used with --profiles or --accounts-file; use --filter bucket=NAME instead")
+	}
+
+	var accounts []config.Account
+	var err error
+	if accountsFile != "" {
+		accounts, err = config.LoadAccountsFile(accountsFile)
+	} else {
+		accounts, err = config.AccountsFromProfiles(profiles)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	for _, account := range accounts {
+		if err := validateAssumeRole(account.RoleARN, account.RoleSessionName, account.ExternalID, ""); err != nil {
+			return nil, fmt.Errorf("invalid configuration: account %s: %w", account.Name, err)
+		}
+	}
+	return accounts, nil
+}
+
+// settingSource returns where the effective value of a setting came from
+func (a *App) settingSource(key string) config.Source {
+	for _, setting := range a.settings {
+		if setting.Setting.Key == key {
+			return setting.Source
+		}
+	}
+	return config.SourceDefault
+}
+
+// accountScan holds the uploads of every account in a multi-account run
+type accountScan struct {
+	uploads      []types.MultipartUpload
+	inaccessible []string
+	failures     []types.AccountFailure
+}
+
+// scanAccounts lists the uploads of each account in turn and tags them with
+// the account name, measuring their sizes too when sized is set. An account
+// that fails is recorded and the others are still scanned; an error is only
+// returned when every account fails or the run is interrupted.
+func (a *App) scanAccounts(cmd *cobra.Command, accounts []config.Account, opts types.ListOptions, sized bool) (*accountScan, error) {
+	ctx := cmd.Context()
+	quiet := a.container.GetConfig().Quiet
+	logger := a.container.GetLogger()
+
+	scan := &accountScan{}
+	for i, account := range accounts {
+		if !quiet {
+			cmd.PrintErrf("Scanning account %s (%d/%d)\n", account.Name, i+1, len(accounts))
+		}
+
+		uploads, inaccessible, skipped, err := a.scanAccount(ctx, account, opts, sized)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Warn("Account scan failed", map[string]interface{}{"account": account.Name, "error": err})
+			scan.failures = append(scan.failures, types.AccountFailure{Account: account.Name, Error: err.Error()})
+			continue
+		}
+
+		for j := range uploads {
+			uploads[j].Account = account.Name
+		}
+		scan.uploads = append(scan.uploads, uploads...)
+		scan.inaccessible = append(scan.inaccessible, inaccessible...)
+		for _, bucket := range skipped {
+			bucket.Account = account.Name
+			a.accountSkipped = append(a.accountSkipped, bucket)
+		}
+	}
+
+	if len(scan.failures) == len(accounts) {
+		first := scan.failures[0]
+		return nil, fmt.Errorf("all %d accounts failed; %s: %s", len(accounts), first.Account, first.Error)
+	}
+	sort.Strings(scan.inaccessible)
+	return scan, nil
+}
+
+// scanAccount lists, and optionally measures, the uploads of one account. The
+// buckets that could not be scanned are returned, and counted as inaccessible.
+func (a *App) scanAccount(ctx context.Context, account config.Account, opts types.ListOptions, sized bool) ([]types.MultipartUpload, []string, []types.SkippedBucket, error) {
+	accountContainer, err := a.container.ForAccount(account)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	uploadService := accountContainer.GetUploadService()
+	uploads, err := uploadService.ListUploads(ctx, opts)
+	var failed types.BucketErrors
+	if errors.As(err, &failed) {
+		// The account's other buckets were listed, so only these are reported
+		for _, bucket := range failed {
+			bucket.Account = account.Name
+			a.bucketErrors = append(a.bucketErrors, bucket)
+		}
+		err = nil
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to list uploads: %w", err)
+	}
+	skipped := uploadService.SkippedBuckets()
+	if !sized {
+		return uploads, nil, skipped, nil
+	}
+
+	uploads, inaccessible, err := accountContainer.GetSizeService().HydrateUploadSizes(ctx, uploads)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to calculate upload sizes: %w", err)
+	}
+	for _, bucket := range skipped {
+		inaccessible = append(inaccessible, bucket.Bucket)
+	}
+	return uploads, inaccessible, skipped, nil
+}
+
+// printAccountFailures lists the accounts that could not be scanned on stderr
+func (a *App) printAccountFailures(cmd *cobra.Command, failures []types.AccountFailure) {
+	if len(failures) > 0 {
+		cmd.PrintErrln()
+		cmd.PrintErr(services.FormatAccountFailures(failures))
+	}
+}
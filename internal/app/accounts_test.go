@@ -0,0 +1,111 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/internal/container"
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// accountUploadService lists the uploads of one account, or fails with err,
+// and reports skipped as the buckets it could not scan
+type accountUploadService struct {
+	interfaces.UploadService
+	uploads []types.MultipartUpload
+	skipped []types.SkippedBucket
+	err     error
+}
+
+func (s *accountUploadService) ListUploads(ctx context.Context, opts types.ListOptions) ([]types.MultipartUpload, error) {
+	return s.uploads, s.err
+}
+
+func (s *accountUploadService) SkippedBuckets() []types.SkippedBucket {
+	return s.skipped
+}
+
+// runMultiAccount runs a command against one fake upload service per account
+// and returns its stdout, stderr and error
+func runMultiAccount(t *testing.T, accounts map[string]*accountUploadService, args ...string) (string, string, error) {
+	t.Helper()
+	newContainer := func() *container.Container {
+		cfg := testConfig()
+		cfg.Quiet = true
+		c, err := container.NewContainer(cfg)
+		if err != nil {
+			t.Fatalf("NewContainer() error = %v", err)
+		}
+		return c
+	}
+
+	c := newContainer()
+	for name, service := range accounts {
+		accountContainer := newContainer()
+		accountContainer.SetUploadService(service)
+		c.SetAccountContainer(name, accountContainer)
+	}
+	_, stdout, stderr, err := runInContainer(t, c, args...)
+	return stdout, stderr, err
+}
+
+func TestMultiAccountScanContinuesPastFailedAccounts(t *testing.T) {
+	initiated := time.Now().Add(-72 * time.Hour)
+	accounts := map[string]*accountUploadService{
+		"prod":    {uploads: []types.MultipartUpload{{Bucket: "prod-logs", Key: "a.tmp", UploadID: "1", Initiated: initiated, Region: "us-east-1"}}},
+		"staging": {err: errors.New("operation error S3: ListBuckets, api error ExpiredToken")},
+		"dev":     {uploads: []types.MultipartUpload{{Bucket: "dev-scratch", Key: "b.tmp", UploadID: "2", Initiated: initiated, Region: "us-east-1"}}},
+	}
+
+	stdout, stderr, err := runMultiAccount(t, accounts, "list", "--profiles", "prod,staging,dev", "--sort-by", "bucket")
+	if err != nil {
+		t.Fatalf("list error = %v", err)
+	}
+	if !strings.Contains(stdout, "Account") || !strings.Contains(stdout, "prod ") || !strings.Contains(stdout, "dev ") {
+		t.Errorf("list output = %q, want an account column with prod and dev", stdout)
+	}
+	if !strings.Contains(stderr, "Failed accounts (1):\n  staging: failed to list uploads: operation error S3: ListBuckets, api error ExpiredToken") {
+		t.Errorf("stderr = %q, want staging listed with its reason", stderr)
+	}
+
+	// Exports carry the account of every upload
+	output := filepath.Join(t.TempDir(), "uploads.csv")
+	if _, _, err := runMultiAccount(t, accounts, "export", "--profiles", "prod,staging,dev", "--output", output); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+	file, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	exported, err := services.ReadUploadsCSV(file)
+	if err != nil {
+		t.Fatalf("ReadUploadsCSV() error = %v", err)
+	}
+	if len(exported) != 2 || exported[0].Account != "prod" || exported[1].Account != "dev" {
+		t.Errorf("exported uploads = %+v, want prod then dev", exported)
+	}
+}
+
+func TestMultiAccountScanFailsWhenEveryAccountFails(t *testing.T) {
+	accounts := map[string]*accountUploadService{
+		"prod": {err: errors.New("AccessDenied")},
+		"dev":  {err: errors.New("AccessDenied")},
+	}
+	_, _, err := runMultiAccount(t, accounts, "list", "--profiles", "prod,dev")
+	if err == nil || !strings.Contains(err.Error(), "all 2 accounts failed; prod: failed to list uploads: AccessDenied") {
+		t.Errorf("list error = %v, want every account reported as failed", err)
+	}
+
+	_, _, err = runMultiAccount(t, accounts, "list", "--profiles", "prod,dev", "--bucket", "logs")
+	if err == nil || !strings.Contains(err.Error(), "use --filter bucket=NAME") {
+		t.Errorf("list --bucket error = %v, want --bucket rejected", err)
+	}
+}
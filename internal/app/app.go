@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -11,7 +12,12 @@ import (
 
 	"github.com/Garvitkul/s3mpc/internal/config"
 	"github.com/Garvitkul/s3mpc/internal/container"
+	"github.com/Garvitkul/s3mpc/internal/logging"
+	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/services"
 	"github.com/Garvitkul/s3mpc/pkg/types"
+	"github.com/Garvitkul/s3mpc/pkg/units"
 )
 
 // App represents the main application
@@ -19,6 +25,27 @@ type App struct {
 	container *container.Container
 	rootCmd   *cobra.Command
 	version   string
+	
+	// configPath is the loaded config file, and settings the effective value of each setting
+	configPath string
+	settings   []config.Resolved
+	
+	// accountSkipped holds the buckets multi-account scans could not scan
+	accountSkipped []types.SkippedBucket
+	
+	// offlineSkipped holds the buckets of saved uploads whose tags could not be read
+	offlineSkipped []types.SkippedBucket
+	
+	// bucketErrors holds the buckets whose uploads could not be listed
+	bucketErrors []types.BucketError
+	
+	// runContext is the command's context bounded by --timeout, released by stopRunTimeout
+	runContext     context.Context
+	stopRunTimeout context.CancelFunc
+	runTimeout     time.Duration
+	
+	// runStart is when the command started, for the wall time --stats prints
+	runStart time.Time
 }
 
 // NewApp creates a new application instance
@@ -33,7 +60,15 @@ func NewApp(version string) *App {
 // Run executes the application with the given arguments
 func (a *App) Run(ctx context.Context, args []string) error {
 	a.rootCmd.SetArgs(args)
-	return a.rootCmd.ExecuteContext(ctx)
+	a.bucketErrors = nil
+	a.runStart = time.Time{}
+	cmd, err := a.rootCmd.ExecuteContextC(ctx)
+	a.warnSkippedBuckets()
+	a.printRunStats(cmd)
+	if err == nil {
+		err = a.bucketErrorsResult()
+	}
+	return a.finishRunTimeout(err)
 }
 
 // setupCommands initializes the CLI command structure
@@ -42,8 +77,17 @@ func (a *App) setupCommands() {
 		Use:   "s3mpc",
 		Short: "S3 MultiPart Cleaner - Manage incomplete S3 multipart uploads",
 		Long: `s3mpc is a command-line tool for managing incomplete S3 multipart uploads.
-It helps you discover, analyze, and clean up incomplete uploads across all your S3 buckets.`,
-		PersistentPreRunE: a.initializeContainer,
+It helps you discover, analyze, and clean up incomplete uploads across all your S3 buckets.
+
+More help: s3mpc help filters | safety | performance | output`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			a.runStart = time.Now()
+			if err := a.initializeContainer(cmd, args); err != nil {
+				return err
+			}
+			a.startRunTimeout(cmd)
+			return a.preflight(cmd)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Handle --version flag
 			if version, _ := cmd.Flags().GetBool("version"); version {
@@ -56,12 +100,41 @@ It helps you discover, analyze, and clean up incomplete uploads across all your
 	}
 
 	// Global flags
+	a.rootCmd.PersistentFlags().String("config", "", "Config file (default: ./.s3mpc.yaml, then ~/.s3mpc/config.yaml)")
 	a.rootCmd.PersistentFlags().String("profile", "", "AWS profile to use")
 	a.rootCmd.PersistentFlags().String("region", "", "AWS region to focus on")
-	a.rootCmd.PersistentFlags().Int("concurrency", 10, "Number of concurrent operations")
-	a.rootCmd.PersistentFlags().Bool("verbose", false, "Enable verbose logging")
-	a.rootCmd.PersistentFlags().Bool("quiet", false, "Suppress non-essential output")
+	a.rootCmd.PersistentFlags().StringSlice("regions", nil, "Only scan buckets in these regions, e.g. us-east-1,eu-west-1")
+	a.rootCmd.PersistentFlags().Bool("include-directory-buckets", false, "Also scan S3 Express One Zone directory buckets, listed with one extra call per region")
+	a.rootCmd.PersistentFlags().String("role-arn", "", "IAM role to assume for every AWS call, e.g. an audit role in a member account")
+	a.rootCmd.PersistentFlags().String("role-session-name", "", "Session name for --role-arn (default: s3mpc)")
+	a.rootCmd.PersistentFlags().String("external-id", "", "External ID required by the trust policy of --role-arn")
+	a.rootCmd.PersistentFlags().String("mfa-serial", "", "MFA device required by --role-arn; the token code is asked for on the terminal")
+	a.rootCmd.PersistentFlags().Bool("fips", false, "Use FIPS endpoints (default: AWS_USE_FIPS_ENDPOINT or use_fips_endpoint in the AWS config)")
+	a.rootCmd.PersistentFlags().Bool("dualstack", false, "Use dual-stack IPv4 and IPv6 endpoints (default: AWS_USE_DUALSTACK_ENDPOINT or use_dualstack_endpoint in the AWS config)")
+	a.rootCmd.PersistentFlags().Int("concurrency", 10, "Number of concurrent operations, for both bucket scans and per-upload calls unless set separately")
+	a.rootCmd.PersistentFlags().Int("bucket-concurrency", 0, "Number of buckets scanned at once (default: --concurrency)")
+	a.rootCmd.PersistentFlags().Int("op-concurrency", 0, "Number of deletes, part listings and object checks run at once (default: --concurrency)")
+	a.rootCmd.PersistentFlags().Float64("rate-limit", config.DefaultConfig().RateLimitRPS, "Maximum S3 API requests per second in each region (0.1 to 10000)")
+	a.rootCmd.PersistentFlags().Int("max-bucket-failures", services.DefaultMaxBucketFailures, "Stop measuring a bucket's uploads after this many consecutive failures that hold for the whole bucket: AccessDenied, AllAccessDisabled or NoSuchBucket (0: never stop)")
+	a.rootCmd.PersistentFlags().Bool("verbose", false, "Enable verbose logging (shorthand for --log-level debug)")
+	a.rootCmd.PersistentFlags().Bool("quiet", false, "Suppress non-essential output and log only errors to the console")
 	a.rootCmd.PersistentFlags().String("log-file", "", "Write logs to file")
+	a.rootCmd.PersistentFlags().String("log-format", "text", "Log format: text or json (one object per line)")
+	a.rootCmd.PersistentFlags().String("log-file-format", "", "Format of --log-file logs: text or json (default: --log-format)")
+	a.rootCmd.PersistentFlags().String("log-level", "", "Console log level: debug, info, warn or error (default: info)")
+	a.rootCmd.PersistentFlags().String("log-file-level", "", "Level of --log-file logs, independent of --log-level (default: info, or debug with --verbose)")
+	a.rootCmd.PersistentFlags().Bool("trace-api", false, "Log every S3 API call with its parameters and latency at debug level (implies --verbose unless --log-level is set)")
+	a.rootCmd.PersistentFlags().Bool("trace-api-keys", false, "Show object keys and prefixes in --trace-api logs instead of redacting them")
+	a.rootCmd.PersistentFlags().Bool("stats", false, "Print S3 API call counts, retries, throttling and cache hit rates to stderr when the command ends")
+	a.rootCmd.PersistentFlags().Duration("age-tolerance", time.Hour, "Tolerance for age = and != filters (overridden per filter with age=7d±6h)")
+	a.rootCmd.PersistentFlags().Bool("age-calendar-days", false, "Treat age=Nd as \"initiated on the calendar day N days ago (UTC)\"")
+	a.rootCmd.PersistentFlags().Bool("no-cache", false, "Do not read or write the upload size cache (~/.s3mpc/cache.db)")
+	a.rootCmd.PersistentFlags().StringSlice("service-initiator-pattern", nil, "Additional Initiator ARN regexp marking uploads as service-initiated (repeatable)")
+	a.rootCmd.PersistentFlags().Bool("fail-on-errors", true, "Exit with an error when some buckets could not be listed; their errors are reported either way")
+	a.rootCmd.PersistentFlags().Duration("timeout", 0, "Stop the whole run after this long, e.g. 30m, reporting what was done so far (default: no limit)")
+	a.rootCmd.PersistentFlags().Duration("request-timeout", 0, "Abandon and retry an AWS API call attempt that takes longer than this, e.g. 30s (default: no limit)")
+	a.rootCmd.PersistentFlags().Bool("skip-preflight", false, "Do not check the AWS credentials with STS before running a command")
+	a.rootCmd.PersistentFlags().Bool("no-input", false, "Never prompt; confirmations fail with exit code 5 unless --force is given (default when stdin is not a terminal)")
 	a.rootCmd.Flags().BoolP("version", "v", false, "Show version information")
 
 	// Add version command
@@ -78,36 +151,195 @@ It helps you discover, analyze, and clean up incomplete uploads across all your
 	a.addCostCommand()
 	a.addListCommand()
 	a.addAgeCommand()
+	a.addStatsCommand()
+	a.addMetricsCommand()
+	a.addSnapshotCommand()
+	a.addConfigCommand()
 	a.addDeleteCommand()
 	a.addExportCommand()
+	a.addExplainCommand()
+	a.addCacheCommand()
+	a.addLifecycleCommand()
+	a.addDoctorCommand()
+	a.addCompletionCommand()
+	a.addHelpTopics()
+	a.registerCompletions()
 }
 
 // initializeContainer sets up the dependency injection container
 func (a *App) initializeContainer(cmd *cobra.Command, args []string) error {
+	// Completion requests set up clients only when a flag value needs them
+	if isCompletionRequest(cmd) {
+		return nil
+	}
+
+	// Fill in flags not given on the command line from the environment and config file
+	if err := a.applyConfigSources(cmd); err != nil {
+		return err
+	}
+	
 	// Get flag values
 	profile, _ := cmd.Flags().GetString("profile")
 	region, _ := cmd.Flags().GetString("region")
+	regions, _ := cmd.Flags().GetStringSlice("regions")
+	includeDirectoryBuckets, _ := cmd.Flags().GetBool("include-directory-buckets")
+	roleARN, _ := cmd.Flags().GetString("role-arn")
+	roleSessionName, _ := cmd.Flags().GetString("role-session-name")
+	externalID, _ := cmd.Flags().GetString("external-id")
+	mfaSerial, _ := cmd.Flags().GetString("mfa-serial")
+	useFIPS, _ := cmd.Flags().GetBool("fips")
+	useDualStack, _ := cmd.Flags().GetBool("dualstack")
 	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	bucketConcurrency, _ := cmd.Flags().GetInt("bucket-concurrency")
+	opConcurrency, _ := cmd.Flags().GetInt("op-concurrency")
+	maxBucketFailures, _ := cmd.Flags().GetInt("max-bucket-failures")
 	verbose, _ := cmd.Flags().GetBool("verbose")
 	quiet, _ := cmd.Flags().GetBool("quiet")
 	logFile, _ := cmd.Flags().GetString("log-file")
+	logFormat, _ := cmd.Flags().GetString("log-format")
+	logFileFormat, _ := cmd.Flags().GetString("log-file-format")
+	logLevel, _ := cmd.Flags().GetString("log-level")
+	logFileLevel, _ := cmd.Flags().GetString("log-file-level")
+	traceAPI, _ := cmd.Flags().GetBool("trace-api")
+	traceAPIKeys, _ := cmd.Flags().GetBool("trace-api-keys")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	requestTimeout, _ := cmd.Flags().GetDuration("request-timeout")
+	ageTolerance, _ := cmd.Flags().GetDuration("age-tolerance")
+	ageCalendarDays, _ := cmd.Flags().GetBool("age-calendar-days")
+	ageBuckets, _ := cmd.Flags().GetString("buckets")
+	livePricing, _ := cmd.Flags().GetBool("live-pricing")
+	pricingFile, _ := cmd.Flags().GetString("pricing-file")
+	costProvider, _ := cmd.Flags().GetString("cost-provider")
+	serviceInitiatorPatterns, _ := cmd.Flags().GetStringSlice("service-initiator-pattern")
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	noInput := resolveNoInput(cmd)
+	progressFormat, _ := cmd.Flags().GetString("progress-format")
+	if progressFormat != "" && progressFormat != "text" && progressFormat != "json" {
+		return fmt.Errorf("invalid configuration: --progress-format: unsupported format %q (supported: text, json)", progressFormat)
+	}
+
+	for flag, format := range map[string]string{"--log-format": logFormat, "--log-file-format": logFileFormat} {
+		if _, err := logging.ParseLogFormat(format); err != nil {
+			return fmt.Errorf("invalid configuration: %s: %w", flag, err)
+		}
+	}
+	for flag, level := range map[string]string{"--log-level": logLevel, "--log-file-level": logFileLevel} {
+		if level == "" {
+			continue
+		}
+		if _, err := logging.ParseLogLevel(level); err != nil {
+			return fmt.Errorf("invalid configuration: %s: %w (supported: debug, info, warn, error)", flag, err)
+		}
+	}
+	if traceAPIKeys && !traceAPI {
+		return fmt.Errorf("invalid configuration: --trace-api-keys requires --trace-api")
+	}
+	if traceAPI && quiet {
+		return fmt.Errorf("invalid configuration: --trace-api cannot be used with --quiet")
+	}
+	// Traced calls are debug lines, so without a level of its own the console shows them
+	if traceAPI && logLevel == "" {
+		verbose = true
+	}
+	logLevel = a.consoleLogLevel(logLevel, verbose, quiet)
+	
+	if costProvider != "" && livePricing {
+		return fmt.Errorf("invalid configuration: --cost-provider and --live-pricing cannot be used together")
+	}
+	
+	if ageTolerance < 0 {
+		return fmt.Errorf("invalid configuration: age tolerance cannot be negative, got %s", ageTolerance)
+	}
+	for flag, value := range map[string]time.Duration{"--timeout": timeout, "--request-timeout": requestTimeout} {
+		if value < 0 {
+			return fmt.Errorf("invalid configuration: %s cannot be negative, got %s", flag, value)
+		}
+	}
+	ageBoundaries, err := a.parseAgeBoundaries(ageBuckets)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: --buckets: %w", err)
+	}
 
 	// Validate configuration
+	region = awsclient.NormalizeRegion(region)
 	if err := a.validateConfig(profile, region, concurrency, verbose, quiet, logFile); err != nil {
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
+	for flag, value := range map[string]int{"--bucket-concurrency": bucketConcurrency, "--op-concurrency": opConcurrency} {
+		// Zero, the default, follows --concurrency
+		if value < 0 || value > 100 {
+			return fmt.Errorf("invalid configuration: %s must be between 1 and 100, got %d", flag, value)
+		}
+	}
+	if maxBucketFailures < 0 {
+		return fmt.Errorf("invalid configuration: --max-bucket-failures cannot be negative, got %d", maxBucketFailures)
+	}
+	rateLimit := a.rateLimit()
+	if rateLimit < 0.1 || rateLimit > 10000 {
+		return fmt.Errorf("invalid configuration: --rate-limit must be between 0.1 and 10000, got %g", rateLimit)
+	}
+	if !quiet {
+		warnConcurrencyOutpacesRateLimit(cmd, rateLimit, map[string]int{"--concurrency": concurrency, "--bucket-concurrency": bucketConcurrency, "--op-concurrency": opConcurrency})
+	}
+	if err := validateAssumeRole(roleARN, roleSessionName, externalID, mfaSerial); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	for i := range regions {
+		regions[i] = awsclient.NormalizeRegion(regions[i])
+		if !a.isValidAWSRegion(regions[i]) {
+			return fmt.Errorf("invalid configuration: --regions: invalid AWS region format: %q", regions[i])
+		}
+	}
+	if !quiet {
+		warnUnknownRegions(cmd, region, regions)
+	}
+	if err := validateBucketTagFlags(cmd); err != nil {
+		return err
+	}
 
 	// Create container configuration
 	cfg := &config.Config{
-		AWSProfile:  profile,
-		AWSRegion:   region,
-		Concurrency: concurrency,
-		Verbose:     verbose,
-		LogFile:     logFile,
+		AWSProfile:      profile,
+		AWSRegion:       region,
+		Regions:         regions,
+		ExcludeBuckets:  a.excludedBuckets(),
+		IncludeDirectoryBuckets: includeDirectoryBuckets,
+		RoleARN:         roleARN,
+		RoleSessionName: roleSessionName,
+		ExternalID:      externalID,
+		MFASerial:       mfaSerial,
+		UseFIPS:         useFIPS,
+		UseDualStack:    useDualStack,
+		Concurrency:     concurrency,
+		BucketConcurrency: bucketConcurrency,
+		OpConcurrency:   opConcurrency,
+		MaxBucketFailures: maxBucketFailures,
+		RateLimitRPS:    rateLimit,
+		RequestTimeout:  requestTimeout,
+		Verbose:         verbose,
+		Quiet:           quiet,
+		LogFile:         logFile,
+		LogFormat:       logFormat,
+		LogFileFormat:   logFileFormat,
+		LogLevel:        logLevel,
+		LogFileLevel:    logFileLevel,
+		TraceAPI:        traceAPI,
+		TraceAPIKeys:    traceAPIKeys,
+		AgeTolerance:    ageTolerance,
+		AgeCalendarDays: ageCalendarDays,
+		AgeBoundaries:   ageBoundaries,
+		LivePricing:     livePricing,
+		PricingFile:     pricingFile,
+		CostProvider:    costProvider,
+		ServiceInitiatorPatterns: serviceInitiatorPatterns,
+		NoCache:         noCache,
+		BucketListTTL:   a.bucketListTTL(),
+		NoInput:         noInput,
+		ProgressFormat:  progressFormat,
+		JSONOutput:      writesJSON(cmd),
 	}
 
 	// Initialize container
-	var err error
 	a.container, err = container.NewContainer(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to initialize application: %w", err)
@@ -116,6 +348,37 @@ func (a *App) initializeContainer(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// consoleLogLevel returns the console log level. --verbose and --quiet are
+// shorthands for debug and error; they override --log-level only when given
+// with a higher-priority source, such as a flag over a config file setting.
+func (a *App) consoleLogLevel(level string, verbose, quiet bool) string {
+	shorthand, key := "", ""
+	switch {
+	case quiet:
+		shorthand, key = "error", "quiet"
+	case verbose:
+		shorthand, key = "debug", "verbose"
+	}
+	if shorthand != "" && (level == "" || sourceRank(a.settingSource(key)) > sourceRank(a.settingSource("log-level"))) {
+		return shorthand
+	}
+	return level
+}
+
+// sourceRank orders setting sources by precedence
+func sourceRank(source config.Source) int {
+	switch source {
+	case config.SourceFlag:
+		return 3
+	case config.SourceEnv:
+		return 2
+	case config.SourceFile:
+		return 1
+	default:
+		return 0
+	}
+}
+
 // validateConfig validates the configuration parameters
 func (a *App) validateConfig(profile, region string, concurrency int, verbose, quiet bool, logFile string) error {
 	// Validate concurrency
@@ -148,31 +411,86 @@ func (a *App) validateConfig(profile, region string, concurrency int, verbose, q
 	return nil
 }
 
-// isValidAWSRegion checks if the region format is valid
+// validateAssumeRole checks --role-arn and rejects the options that only apply to it when it is missing
+func validateAssumeRole(roleARN, sessionName, externalID, mfaSerial string) error {
+	if roleARN == "" {
+		for _, option := range [][2]string{{"--role-session-name", sessionName}, {"--external-id", externalID}, {"--mfa-serial", mfaSerial}} {
+			if option[1] != "" {
+				return fmt.Errorf("%s requires --role-arn", option[0])
+			}
+		}
+		return nil
+	}
+	return awsclient.AssumeRoleConfig{RoleARN: roleARN}.Validate()
+}
+
+// isValidAWSRegion checks that the region is well-formed in one of the AWS
+// partitions, such as us-east-1, us-gov-west-1 or cn-north-1
 func (a *App) isValidAWSRegion(region string) bool {
-	// Basic validation for AWS region format
-	if len(region) < 9 {
-		return false
+	return awsclient.IsValidRegion(region)
+}
+
+// warnConcurrencyOutpacesRateLimit warns about concurrency settings so far
+// above the rate limit that the extra workers would only wait, as doctor does
+func warnConcurrencyOutpacesRateLimit(cmd *cobra.Command, rateLimit float64, settings map[string]int) {
+	flags := make([]string, 0, len(settings))
+	for flag := range settings {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+	for _, flag := range flags {
+		if services.ConcurrencyOutpacesRateLimit(settings[flag], rateLimit) {
+			cmd.PrintErrf("Warning: %s %d is far above the rate limit of %g requests per second; raise --rate-limit or the extra workers only wait for it\n", flag, settings[flag], rateLimit)
+		}
 	}
-	
-	parts := strings.Split(region, "-")
-	if len(parts) < 3 {
-		return false
+}
+
+// warnUnknownRegions warns about well-formed regions AWS is not known to have
+// launched, which are most likely typos; they are still used as given
+func warnUnknownRegions(cmd *cobra.Command, region string, regions []string) {
+	if region != "" && !awsclient.IsKnownRegion(region) {
+		cmd.PrintErrf("Warning: --region %s is not a known AWS region\n", region)
+	}
+	for _, region := range regions {
+		if !awsclient.IsKnownRegion(region) {
+			cmd.PrintErrf("Warning: --regions %s is not a known AWS region\n", region)
+		}
 	}
-	
-	// Last part should be a number
-	lastPart := parts[len(parts)-1]
-	if len(lastPart) == 0 {
-		return false
+}
+
+// listOptions returns the options for listing uploads in bucketName, or in
+// every bucket but the exclude-buckets setting when it is empty, limited to
+// the region given with --region and those given with --regions, the buckets
+// selected with --bucket-tag and the keys starting with --prefix. Directory
+// buckets are included with --include-directory-buckets.
+func (a *App) listOptions(cmd *cobra.Command, bucketName string) types.ListOptions {
+	// The tag flags were validated with the configuration
+	bucketTags, _ := bucketTagFilter(cmd)
+	return types.ListOptions{
+		BucketName:              bucketName,
+		Region:                  a.scanRegion(),
+		Regions:                 a.container.GetConfig().Regions,
+		ExcludeBuckets:          a.container.GetConfig().ExcludeBuckets,
+		KeyPrefix:               keyPrefix(cmd),
+		BucketTags:              bucketTags,
+		IncludeDirectoryBuckets: a.container.GetConfig().IncludeDirectoryBuckets,
 	}
-	
-	for _, r := range lastPart {
-		if r < '0' || r > '9' {
-			return false
+}
+
+// scanRegion returns the region scans are limited to: --region, but only
+// when it is given on the command line and --regions is not. A region from
+// the environment or config file only picks the endpoint the clients start from.
+func (a *App) scanRegion() string {
+	cfg := a.container.GetConfig()
+	if len(cfg.Regions) > 0 {
+		return ""
+	}
+	for _, setting := range a.settings {
+		if setting.Setting.Key == "region" && setting.Source == config.SourceFlag {
+			return cfg.AWSRegion
 		}
 	}
-	
-	return true
+	return ""
 }
 
 // Command implementations
@@ -183,25 +501,65 @@ func (a *App) addSizeCommand() {
 		RunE:  a.runSizeCommand,
 	}
 	cmd.Flags().Bool("json", false, "Output in JSON format")
+	addOutputFlag(cmd, outputText, outputJSON, outputMarkdown)
 	cmd.Flags().BoolP("bucket", "b", false, "Show per-bucket breakdown")
+	cmd.Flags().Bool("histogram", false, "Also show how many uploads fall in each size range, from < 10 MB to 10 GB+")
+	addPrefixFlag(cmd)
+	addBucketTagFlags(cmd)
+	addFromFileFlag(cmd)
+	addAccountFlags(cmd)
+	addFailIfEmptyFlag(cmd)
 	a.rootCmd.AddCommand(cmd)
 }
 
 func (a *App) runSizeCommand(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	
-	jsonOutput, _ := cmd.Flags().GetBool("json")
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	jsonOutput := format == outputJSON
 	bucketBreakdown, _ := cmd.Flags().GetBool("bucket")
+	histogram, _ := cmd.Flags().GetBool("histogram")
 	
 	sizeService := a.container.GetSizeService()
 	formatter := a.container.GetOutputFormatter()
 	
-	report, err := sizeService.CalculateTotalSize(ctx, types.ListOptions{})
+	accounts, err := a.accounts(cmd)
+	if err != nil {
+		return err
+	}
+	
+	var report *types.SizeReport
+	offlineUploads, offline, err := a.loadOfflineUploads(cmd)
+	if err != nil {
+		return err
+	}
+	if offline {
+		report, err = sizeService.ReportForUploads(offlineUploads)
+	} else if accounts != nil {
+		var scan *accountScan
+		if scan, err = a.scanAccounts(cmd, accounts, a.listOptions(cmd, ""), true); err == nil {
+			report, err = sizeService.ReportForUploads(scan.uploads)
+		}
+		if err == nil {
+			report.InaccessibleBuckets = scan.inaccessible
+			report.FailedAccounts = scan.failures
+		}
+	} else {
+		report, err = sizeService.CalculateTotalSize(ctx, a.listOptions(cmd, ""))
+		err = a.partialListing(err)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to calculate size: %w", err)
 	}
 	
-	if report.TotalCount == 0 {
+	if !jsonOutput {
+		defer a.printBucketErrors(cmd)
+	}
+	
+	if report.TotalCount == 0 && len(report.FailedAccounts) == 0 && len(report.InaccessibleBuckets) == 0 && len(a.failedBuckets()) == 0 {
 		if jsonOutput {
 			result := map[string]interface{}{
 				"total_uploads": 0,
@@ -216,10 +574,15 @@ func (a *App) runSizeCommand(cmd *cobra.Command, args []string) error {
 		} else {
 			cmd.Println("No incomplete multipart uploads found.")
 		}
-		return nil
+		return failIfEmpty(cmd, 0)
+	}
+	
+	if !histogram {
+		report.Distribution = nil
 	}
 	
 	if jsonOutput {
+		report.BucketErrors = a.failedBuckets()
 		jsonStr, err := formatter.FormatJSON(report)
 		if err != nil {
 			return fmt.Errorf("failed to format JSON output: %w", err)
@@ -230,11 +593,14 @@ func (a *App) runSizeCommand(cmd *cobra.Command, args []string) error {
 			report.ByBucket = make(map[string]int64)
 		}
 		
-		output := formatter.FormatSizeReport(*report)
-		cmd.Print(output)
+		if format == outputMarkdown {
+			cmd.Print(formatter.FormatSizeReportMarkdown(*report, commandLine(cmd)))
+		} else {
+			cmd.Print(formatter.FormatSizeReport(*report))
+		}
 	}
 	
-	return nil
+	return failIfEmpty(cmd, report.TotalCount)
 }
 
 func (a *App) addCostCommand() {
@@ -245,6 +611,14 @@ func (a *App) addCostCommand() {
 	}
 	cmd.Flags().Bool("storage-class", false, "Show cost breakdown by storage class")
 	cmd.Flags().Bool("json", false, "Output in JSON format")
+	addOutputFlag(cmd, outputText, outputJSON, outputMarkdown)
+	cmd.Flags().Bool("live-pricing", false, "Query the AWS Pricing API (cached in ~/.s3mpc/pricing.json) instead of built-in prices")
+	cmd.Flags().String("pricing-file", "", "YAML file with region -> storage class -> price per GB-month overrides")
+	cmd.Flags().String("cost-provider", "", "Program that returns prices, as exec:/path/to/program (see s3mpc help performance)")
+	cmd.Flags().Bool("annual", false, "Also show the annualized cost (monthly run-rate x 12)")
+	cmd.Flags().Bool("accrued", false, "Also show what the uploads have cost since they were initiated")
+	addFromFileFlag(cmd)
+	addAccountFlags(cmd)
 	a.rootCmd.AddCommand(cmd)
 }
 
@@ -252,18 +626,49 @@ func (a *App) runCostCommand(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	
 	storageClassBreakdown, _ := cmd.Flags().GetBool("storage-class")
-	jsonOutput, _ := cmd.Flags().GetBool("json")
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	jsonOutput := format == outputJSON
+	annual, _ := cmd.Flags().GetBool("annual")
+	accrued, _ := cmd.Flags().GetBool("accrued")
 	
 	uploadService := a.container.GetUploadService()
+	sizeService := a.container.GetSizeService()
 	costCalculator := a.container.GetCostCalculator()
 	formatter := a.container.GetOutputFormatter()
 	
-	uploads, err := uploadService.ListUploads(ctx, types.ListOptions{})
+	accounts, err := a.accounts(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to list uploads: %w", err)
+		return err
 	}
 	
-	if len(uploads) == 0 {
+	var failedAccounts []types.AccountFailure
+	uploads, offline, err := a.loadOfflineUploads(cmd)
+	if err != nil {
+		return err
+	}
+	if accounts != nil {
+		scan, err := a.scanAccounts(cmd, accounts, a.listOptions(cmd, ""), true)
+		if err != nil {
+			return err
+		}
+		uploads, failedAccounts = scan.uploads, scan.failures
+	} else if !offline {
+		uploads, err = uploadService.ListUploads(ctx, a.listOptions(cmd, ""))
+		if err = a.partialListing(err); err != nil {
+			return fmt.Errorf("failed to list uploads: %w", err)
+		}
+		
+		// Costs are proportional to size, which ListUploads does not report
+		uploads, _, err = sizeService.HydrateUploadSizes(ctx, uploads)
+		if err != nil {
+			return fmt.Errorf("failed to calculate upload sizes: %w", err)
+		}
+	}
+	
+	if len(uploads) == 0 && len(failedAccounts) == 0 && len(a.skippedBuckets()) == 0 && len(a.failedBuckets()) == 0 {
 		if jsonOutput {
 			result := map[string]interface{}{
 				"total_monthly_cost": 0.0,
@@ -285,6 +690,16 @@ func (a *App) runCostCommand(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to calculate costs: %w", err)
 	}
+	breakdown.FailedAccounts = failedAccounts
+	breakdown.SkippedBuckets = a.skippedBuckets()
+	breakdown.BucketErrors = a.failedBuckets()
+	
+	if accrued {
+		breakdown.AccruedCost, err = costCalculator.CalculateAccruedCost(ctx, uploads)
+		if err != nil {
+			return fmt.Errorf("failed to calculate accrued costs: %w", err)
+		}
+	}
 	
 	if jsonOutput {
 		jsonStr, err := formatter.FormatJSON(breakdown)
@@ -296,9 +711,15 @@ func (a *App) runCostCommand(cmd *cobra.Command, args []string) error {
 		if !storageClassBreakdown {
 			breakdown.ByStorageClass = make(map[string]float64)
 		}
+		if !annual {
+			breakdown.AnnualizedCost = 0
+		}
 		
-		output := formatter.FormatCostBreakdown(breakdown)
-		cmd.Print(output)
+		if format == outputMarkdown {
+			cmd.Print(formatter.FormatCostBreakdownMarkdown(breakdown, commandLine(cmd)))
+		} else {
+			cmd.Print(formatter.FormatCostBreakdown(breakdown))
+		}
 	}
 	
 	return nil
@@ -311,11 +732,23 @@ func (a *App) addListCommand() {
 		RunE:  a.runListCommand,
 	}
 	cmd.Flags().StringP("bucket", "b", "", "List uploads for specific bucket")
-	cmd.Flags().String("filter", "", "Filter uploads using query syntax")
-	cmd.Flags().String("sort-by", "age", "Sort by: age, size, bucket")
+	addPrefixFlag(cmd)
+	addBucketTagFlags(cmd)
+	cmd.Flags().String("filter", "", "Filter uploads using query syntax (age=7d matches within --age-tolerance; use age=7d±6h for a custom window)")
+	addSuffixFlags(cmd)
+	addSortFlags(cmd, "age")
 	cmd.Flags().Int("limit", 0, "Limit number of results")
 	cmd.Flags().Int("offset", 0, "Offset for pagination")
 	cmd.Flags().Bool("json", false, "Output in JSON format")
+	addOutputFlag(cmd, outputText, outputJSON, outputMarkdown)
+	cmd.Flags().Bool("show-owner", false, "Add who initiated and who owns each upload to the table")
+	cmd.Flags().String("columns", "", "Comma-separated columns to show in order, from: "+strings.Join(services.UploadColumnNames(), ", "))
+	cmd.Flags().Bool("wide", false, "Show keys and other cells in full instead of truncating them to fit the terminal")
+	cmd.Flags().String("group-by", "", "Show uploads in sections with subtotals, by bucket, region, storage-class or prefix:N (the first N key path segments)")
+	addCheckObjectsFlag(cmd)
+	addAccountFlags(cmd)
+	addFailIfEmptyFlag(cmd)
+	addWatchFlags(cmd)
 	a.rootCmd.AddCommand(cmd)
 }
 
@@ -323,70 +756,213 @@ func (a *App) runListCommand(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	
 	bucketName, _ := cmd.Flags().GetString("bucket")
-	filterStr, _ := cmd.Flags().GetString("filter")
+	filterStr := a.filterExpression(cmd)
 	sortBy, _ := cmd.Flags().GetString("sort-by")
+	order, err := uploadSort(cmd)
+	if err != nil {
+		return err
+	}
 	limit, _ := cmd.Flags().GetInt("limit")
 	offset, _ := cmd.Flags().GetInt("offset")
-	jsonOutput, _ := cmd.Flags().GetBool("json")
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	jsonOutput := format == outputJSON
+	showOwner, _ := cmd.Flags().GetBool("show-owner")
+	wide, _ := cmd.Flags().GetBool("wide")
+	tableOptions := interfaces.UploadTableOptions{ShowOwner: showOwner, Wide: wide}
+	if columnsStr, _ := cmd.Flags().GetString("columns"); columnsStr != "" {
+		if showOwner {
+			return fmt.Errorf("--show-owner cannot be used with --columns: add the owner column instead")
+		}
+		columns, err := services.ParseUploadColumns(columnsStr)
+		if err != nil {
+			return err
+		}
+		tableOptions.Columns = columns
+	}
+	groupBy, _ := cmd.Flags().GetString("group-by")
+	if groupBy != "" {
+		if _, err := services.GroupByKey(groupBy); err != nil {
+			return err
+		}
+		if format == outputMarkdown {
+			return fmt.Errorf("--group-by cannot be used with --output markdown")
+		}
+	}
 	
 	uploadService := a.container.GetUploadService()
 	filterEngine := a.container.GetFilterEngine()
 	formatter := a.container.GetOutputFormatter()
 	
-	listOpts := types.ListOptions{
-		BucketName: bucketName,
-		MaxResults: limit,
-		Offset:     offset,
-	}
-	
-	uploads, err := uploadService.ListUploads(ctx, listOpts)
-	if err != nil {
-		return fmt.Errorf("failed to list uploads: %w", err)
-	}
+	// Offset and limit apply after filtering and sorting, so the listing itself is not paginated
+	listOpts := a.listOptions(cmd, bucketName)
 	
+	var filter interfaces.Filter
 	if filterStr != "" {
-		filter, err := filterEngine.ParseFilter(filterStr)
+		parsed, err := filterEngine.ParseFilter(filterStr)
 		if err != nil {
 			return fmt.Errorf("invalid filter syntax: %w", err)
 		}
-		uploads = filterEngine.ApplyFilter(uploads, filter)
+		filter = parsed
 	}
+	filter.Key = keySuffixFilter(cmd, filter.Key)
+	checkObjects, err := checkingObjects(cmd, filter)
+	if err != nil {
+		return err
+	}
+	// Listing does not report sizes, parts or abort dates, so filters on them
+	// or on cost, size and cost sorting, group subtotals and columns showing
+	// them, including the size column and total of the default markdown
+	// table, need each upload measured
+	measure := filter.Size != nil || filter.Parts != nil || filter.Cost != nil || order.NeedsSizes() || groupBy != "" ||
+		services.ColumnsNeedMeasuring(tableOptions.Columns) || (format == outputMarkdown && len(tableOptions.Columns) == 0)
 	
-	uploads = a.sortUploads(uploads, sortBy)
-	
-	if offset > 0 {
-		if offset >= len(uploads) {
-			uploads = []types.MultipartUpload{}
-		} else {
-			uploads = uploads[offset:]
-		}
+	watch, err := watchOptions(cmd)
+	if err != nil {
+		return err
 	}
+	// Sizes are measured on every tick so growth shows
+	measure = measure || watch.Enabled
 	
-	if limit > 0 && len(uploads) > limit {
-		uploads = uploads[:limit]
+	accounts, err := a.accounts(cmd)
+	if err != nil {
+		return err
+	}
+	if accounts != nil && watch.Enabled {
+		return fmt.Errorf("--watch cannot be used with --profiles or --accounts-file")
+	}
+	if format == outputMarkdown && watch.Enabled {
+		return fmt.Errorf("--watch cannot be used with --output markdown")
 	}
 	
-	if jsonOutput {
-		result := map[string]interface{}{
-			"uploads":     uploads,
-			"total_count": len(uploads),
-			"filter":      filterStr,
-			"sort_by":     sortBy,
-			"limit":       limit,
-			"offset":      offset,
-		}
-		jsonStr, err := formatter.FormatJSON(result)
+	listUploads := func(ctx context.Context) ([]types.MultipartUpload, []types.AccountFailure, error) {
+		if accounts != nil {
+			scan, err := a.scanAccounts(cmd, accounts, listOpts, measure)
+			if err != nil {
+				return nil, nil, err
+			}
+			uploads := scan.uploads
+			if measure {
+				uploads = a.estimateCosts(ctx, uploads)
+			}
+			return a.sortUploads(ctx, filterEngine.ApplyFilter(uploads, filter), order), scan.failures, nil
+		}
+		if order.Field != "" && limit > 0 && !checkObjects && !measure {
+			// Only the first offset+limit uploads can be shown, so keep just those
+			top := services.NewTopUploads(offset+limit, a.uploadLess(ctx, order))
+			err := uploadService.WalkUploads(ctx, listOpts, func(upload types.MultipartUpload) error {
+				if filterEngine.MatchesFilter(upload, filter) {
+					top.Push(upload)
+				}
+				return nil
+			})
+			if err = a.partialListing(err); err != nil {
+				return nil, nil, fmt.Errorf("failed to list uploads: %w", err)
+			}
+			return top.Sorted(), nil, nil
+		}
+		
+		listed, err := uploadService.ListUploads(ctx, listOpts)
+		if err = a.partialListing(err); err != nil {
+			return nil, nil, fmt.Errorf("failed to list uploads: %w", err)
+		}
+		if watch.Enabled {
+			// Sizes measured on the previous tick are measured again
+			listed, err = a.container.GetSizeService().RemeasureStaleSizes(ctx, listed, watch.Interval/2)
+		} else if measure {
+			listed, _, err = a.container.GetSizeService().HydrateUploadSizes(ctx, listed)
+		}
 		if err != nil {
-			return fmt.Errorf("failed to format JSON output: %w", err)
+			return nil, nil, fmt.Errorf("failed to calculate upload sizes: %w", err)
 		}
-		cmd.Println(jsonStr)
-	} else {
+		if measure {
+			listed = a.estimateCosts(ctx, listed)
+		}
+		filtered, err := a.filterCheckingObjects(cmd, listed, filter, checkObjects)
+		if err != nil {
+			return nil, nil, err
+		}
+		return a.sortUploads(ctx, filtered, order), nil, nil
+	}
+	
+	printUploads := func(uploads []types.MultipartUpload, failedAccounts []types.AccountFailure) error {
+		if offset > 0 {
+			if offset >= len(uploads) {
+				uploads = []types.MultipartUpload{}
+			} else {
+				uploads = uploads[offset:]
+			}
+		}
+		
+		if limit > 0 && len(uploads) > limit {
+			uploads = uploads[:limit]
+		}
+		
+		// Sorting, offset and limit apply before grouping, so each group keeps the sort order
+		var groups []types.UploadGroup
+		if groupBy != "" {
+			var err error
+			groups, err = services.GroupUploads(uploads, groupBy)
+			if err != nil {
+				return err
+			}
+		}
+		
+		if jsonOutput {
+			result := map[string]interface{}{
+				"uploads":     uploads,
+				"total_count": len(uploads),
+				"filter":      filterStr,
+				"sort_by":     sortBy,
+				"limit":       limit,
+				"offset":      offset,
+			}
+			if groupBy != "" {
+				var totalSize int64
+				for _, upload := range uploads {
+					totalSize += upload.Size
+				}
+				delete(result, "uploads")
+				result["group_by"] = groupBy
+				result["groups"] = groups
+				result["total_size"] = totalSize
+			}
+			if len(failedAccounts) > 0 {
+				result["failed_accounts"] = failedAccounts
+			}
+			if skipped := a.skippedBuckets(); len(skipped) > 0 {
+				result["skipped_buckets"] = skipped
+			}
+			if failed := a.failedBuckets(); len(failed) > 0 {
+				result["errors"] = failed
+			}
+			jsonStr, err := formatter.FormatJSON(result)
+			if err != nil {
+				return fmt.Errorf("failed to format JSON output: %w", err)
+			}
+			cmd.Println(jsonStr)
+			return nil
+		}
+		
+		defer a.printAccountFailures(cmd, failedAccounts)
+		defer a.printSkippedBuckets(cmd, a.skippedBuckets())
+		defer a.printBucketErrors(cmd)
 		if len(uploads) == 0 {
 			cmd.Println("No incomplete multipart uploads found.")
 			return nil
 		}
 		
-		output := formatter.FormatUploads(uploads, true)
+		if format == outputMarkdown {
+			cmd.Print(formatter.FormatUploadsMarkdown(uploads, tableOptions, commandLine(cmd)))
+			return nil
+		}
+		
+		output := formatter.FormatUploadColumns(uploads, tableOptions)
+		if groupBy != "" {
+			output = formatter.FormatUploadGroups(groups, tableOptions)
+		}
 		cmd.Print(output)
 		
 		if limit > 0 || offset > 0 {
@@ -399,31 +975,62 @@ func (a *App) runListCommand(cmd *cobra.Command, args []string) error {
 			}
 			cmd.Println()
 		}
+		return nil
 	}
 	
-	return nil
+	if watch.Enabled {
+		return a.watchUploads(cmd, watch, listUploads, printUploads)
+	}
+	
+	uploads, failedAccounts, err := listUploads(ctx)
+	if err != nil {
+		return err
+	}
+	if err := printUploads(uploads, failedAccounts); err != nil {
+		return err
+	}
+	// Pagination can leave a page empty, but uploads still matched
+	return failIfEmpty(cmd, len(uploads))
 }
 
-func (a *App) sortUploads(uploads []types.MultipartUpload, sortBy string) []types.MultipartUpload {
-	sorted := make([]types.MultipartUpload, len(uploads))
-	copy(sorted, uploads)
-	
-	switch sortBy {
-	case "age":
-		sort.Slice(sorted, func(i, j int) bool {
-			return sorted[i].Initiated.Before(sorted[j].Initiated)
-		})
-	case "size":
-		sort.Slice(sorted, func(i, j int) bool {
-			return sorted[i].Size > sorted[j].Size
-		})
-	case "bucket":
-		sort.Slice(sorted, func(i, j int) bool {
-			return sorted[i].Bucket < sorted[j].Bucket
-		})
+// addSortFlags adds --sort-by and --desc; an empty default keeps listing order
+func addSortFlags(cmd *cobra.Command, defaultField string) {
+	cmd.Flags().String("sort-by", defaultField, "Sort by: "+strings.Join(services.SortFields, ", ")+", with an optional :asc or :desc such as size:asc (age, size and cost sort largest first by default)")
+	cmd.Flags().Bool("desc", false, "Sort in descending order")
+}
+
+// uploadSort parses --sort-by and --desc; an empty --sort-by gives an order
+// with no field, which keeps listing order
+func uploadSort(cmd *cobra.Command) (services.UploadSort, error) {
+	sortBy, _ := cmd.Flags().GetString("sort-by")
+	descending, _ := cmd.Flags().GetBool("desc")
+	if sortBy == "" {
+		if descending {
+			return services.UploadSort{}, fmt.Errorf("--desc requires --sort-by")
+		}
+		return services.UploadSort{}, nil
 	}
-	
-	return sorted
+	return services.ParseUploadSort(sortBy, descending)
+}
+
+// uploadLess returns the ordering for order, pricing uploads with the
+// configured cost calculator when sorting by cost
+func (a *App) uploadLess(ctx context.Context, order services.UploadSort) func(a, b types.MultipartUpload) bool {
+	return order.Less(services.MonthlyCostFunc(ctx, a.container.GetCostCalculator()))
+}
+
+// sortUploads returns uploads sorted by order, or unchanged without a sort field
+func (a *App) sortUploads(ctx context.Context, uploads []types.MultipartUpload, order services.UploadSort) []types.MultipartUpload {
+	if order.Field == "" {
+		return uploads
+	}
+	return services.SortUploads(uploads, a.uploadLess(ctx, order))
+}
+
+// estimateCosts sets the estimated monthly cost of measured uploads, so it can
+// be filtered on, shown and exported
+func (a *App) estimateCosts(ctx context.Context, uploads []types.MultipartUpload) []types.MultipartUpload {
+	return services.EstimateUploadCosts(ctx, a.container.GetCostCalculator(), uploads)
 }
 
 func (a *App) addAgeCommand() {
@@ -433,7 +1040,10 @@ func (a *App) addAgeCommand() {
 		RunE:  a.runAgeCommand,
 	}
 	cmd.Flags().StringP("bucket", "b", "", "Show age distribution for specific bucket")
+	cmd.Flags().String("buckets", "", "Split the distribution at these increasing ages instead of 1 day to 1 year+, e.g. 6h,24h,48h,7d")
 	cmd.Flags().Bool("json", false, "Output in JSON format")
+	addOutputFlag(cmd, outputText, outputJSON, outputMarkdown)
+	addFromFileFlag(cmd)
 	a.rootCmd.AddCommand(cmd)
 }
 
@@ -441,19 +1051,37 @@ func (a *App) runAgeCommand(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	
 	bucketName, _ := cmd.Flags().GetString("bucket")
-	jsonOutput, _ := cmd.Flags().GetBool("json")
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	jsonOutput := format == outputJSON
 	
 	uploadService := a.container.GetUploadService()
 	ageService := a.container.GetAgeService()
 	formatter := a.container.GetOutputFormatter()
 	
-	listOpts := types.ListOptions{
-		BucketName: bucketName,
+	uploads, offline, err := a.loadOfflineUploads(cmd)
+	if err != nil {
+		return err
+	}
+	if !offline {
+		listOpts := a.listOptions(cmd, bucketName)
+		
+		uploads, err = uploadService.ListUploads(ctx, listOpts)
+		if err = a.partialListing(err); err != nil {
+			return fmt.Errorf("failed to list uploads: %w", err)
+		}
+		
+		// The ranges and percentiles total sizes, which ListUploads does not report
+		uploads, _, err = a.container.GetSizeService().HydrateUploadSizes(ctx, uploads)
+		if err != nil {
+			return fmt.Errorf("failed to calculate upload sizes: %w", err)
+		}
 	}
 	
-	uploads, err := uploadService.ListUploads(ctx, listOpts)
-	if err != nil {
-		return fmt.Errorf("failed to list uploads: %w", err)
+	if !jsonOutput {
+		defer a.printBucketErrors(cmd)
 	}
 	
 	if len(uploads) == 0 {
@@ -462,6 +1090,9 @@ func (a *App) runAgeCommand(cmd *cobra.Command, args []string) error {
 				"buckets": []interface{}{},
 				"message": "No incomplete multipart uploads found",
 			}
+			if failed := a.failedBuckets(); len(failed) > 0 {
+				result["errors"] = failed
+			}
 			jsonStr, err := formatter.FormatJSON(result)
 			if err != nil {
 				return fmt.Errorf("failed to format JSON output: %w", err)
@@ -485,11 +1116,14 @@ func (a *App) runAgeCommand(cmd *cobra.Command, args []string) error {
 	}
 	
 	if jsonOutput {
+		distribution.BucketErrors = a.failedBuckets()
 		jsonStr, err := formatter.FormatJSON(distribution)
 		if err != nil {
 			return fmt.Errorf("failed to format JSON output: %w", err)
 		}
 		cmd.Println(jsonStr)
+	} else if format == outputMarkdown {
+		cmd.Print(formatter.FormatAgeDistributionMarkdown(distribution, commandLine(cmd)))
 	} else {
 		output := formatter.FormatAgeDistribution(distribution)
 		cmd.Print(output)
@@ -502,6 +1136,63 @@ func (a *App) runAgeCommand(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func (a *App) addStatsCommand() {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show size, cost and age of uploads from a single scan",
+		RunE:  a.runStatsCommand,
+	}
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.Flags().Bool("live-pricing", false, "Query the AWS Pricing API (cached in ~/.s3mpc/pricing.json) instead of built-in prices")
+	cmd.Flags().String("pricing-file", "", "YAML file with region -> storage class -> price per GB-month overrides")
+	cmd.Flags().String("cost-provider", "", "Program that returns prices, as exec:/path/to/program (see s3mpc help performance)")
+	addFromFileFlag(cmd)
+	a.rootCmd.AddCommand(cmd)
+}
+
+func (a *App) runStatsCommand(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	
+	reportService := a.container.GetReportService()
+	formatter := a.container.GetOutputFormatter()
+	
+	var report *types.StatsReport
+	uploads, offline, err := a.loadOfflineUploads(cmd)
+	if err != nil {
+		return err
+	}
+	if offline {
+		report, err = reportService.StatsForUploads(ctx, uploads, nil)
+	} else {
+		report, err = reportService.GenerateStats(ctx, a.listOptions(cmd, ""))
+		err = a.partialListing(err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate stats: %w", err)
+	}
+	
+	if jsonOutput {
+		report.BucketErrors = a.failedBuckets()
+		jsonStr, err := formatter.FormatJSON(report)
+		if err != nil {
+			return fmt.Errorf("failed to format JSON output: %w", err)
+		}
+		cmd.Println(jsonStr)
+		return nil
+	}
+	
+	defer a.printBucketErrors(cmd)
+	if report.Size.TotalCount == 0 && len(report.InaccessibleBuckets) == 0 && len(a.failedBuckets()) == 0 {
+		cmd.Println("No incomplete multipart uploads found.")
+		return nil
+	}
+	
+	cmd.Print(formatter.FormatStatsReport(*report))
+	return nil
+}
+
 func (a *App) addDeleteCommand() {
 	cmd := &cobra.Command{
 		Use:   "delete",
@@ -510,10 +1201,37 @@ func (a *App) addDeleteCommand() {
 	}
 	cmd.Flags().Bool("force", false, "Skip confirmation prompts")
 	cmd.Flags().Bool("dry-run", false, "Show what would be deleted without deleting")
-	cmd.Flags().String("older-than", "", "Delete uploads older than specified duration (e.g., 7d, 1w, 1m)")
+	cmd.Flags().String("older-than", "", "Delete uploads older than a duration (e.g., 7d, 1w) or initiated before a date (2024-05-01 or RFC3339)")
+	cmd.Flags().String("newer-than", "", "Delete uploads newer than a duration (e.g., 30d) or initiated on or after a date (2024-06-10 or RFC3339)")
+	cmd.Flags().String("initiated-before", "", "Delete uploads initiated before a date or time (2024-03-05 or RFC3339; UTC unless an offset is given)")
+	cmd.Flags().String("initiated-after", "", "Delete uploads initiated on or after a date or time (2024-03-01 or RFC3339; UTC unless an offset is given)")
 	cmd.Flags().String("smaller-than", "", "Delete uploads smaller than specified size (e.g., 100MB, 1GB)")
 	cmd.Flags().String("larger-than", "", "Delete uploads larger than specified size (e.g., 100MB, 1GB)")
 	cmd.Flags().StringP("bucket", "b", "", "Delete uploads from specific bucket")
+	addPrefixFlag(cmd)
+	addBucketTagFlags(cmd)
+	addSuffixFlags(cmd)
+	cmd.Flags().StringSlice("storage-class", nil, "Delete only uploads in these storage classes (comma-separated or repeated, case-insensitive, e.g. STANDARD,GLACIER)")
+	cmd.Flags().String("filter", "", "Delete only uploads matching a filter in query syntax, as in list (e.g. 'bucket in (a, b),parts>100')")
+	cmd.Flags().Bool("live-pricing", false, "Use the AWS Pricing API for dry-run savings estimates")
+	cmd.Flags().String("pricing-file", "", "YAML file with price overrides for dry-run savings estimates")
+	cmd.Flags().String("cost-provider", "", "Program that returns prices for dry-run savings estimates, as exec:/path/to/program")
+	cmd.Flags().Bool("include-service-initiated", false, "Also delete uploads initiated by AWS services such as replication")
+	cmd.Flags().Int("max-deletes", 0, "Delete at most this many uploads, oldest first (0 means no limit)")
+	addSkipAutoExpiringFlag(cmd)
+	cmd.Flags().String("remeasure-older-than", "", "Measure sizes again when they were measured longer ago than this (e.g., 15min), bypassing the size cache")
+	cmd.Flags().String("from-file", "", "Delete the uploads in a failure report, export or saved dry-run report instead of listing S3")
+	addCheckObjectsFlag(cmd)
+	cmd.Flags().Bool("verify-access", false, "With --dry-run, check that each bucket's uploads may be aborted, with one AbortMultipartUpload call per bucket for an upload ID that does not exist")
+	addSaveFailuresFlag(cmd)
+	addReportFileFlag(cmd)
+	addSaveFlag(cmd)
+	addFilenameTemplateFlags(cmd, "the --save file when it is given without one, and the --report-file auto one")
+	addRetryFromFlag(cmd)
+	addCheckpointFlag(cmd)
+	addFailIfEmptyFlag(cmd)
+	cmd.Flags().String("progress-format", "text", "Progress output: text (progress bar) or json (one event per line on stderr, for CI)")
+	addOutputFlag(cmd, outputText, outputMarkdown)
 	a.rootCmd.AddCommand(cmd)
 }
 
@@ -523,29 +1241,110 @@ func (a *App) runDeleteCommand(cmd *cobra.Command, args []string) error {
 	force, _ := cmd.Flags().GetBool("force")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 	olderThan, _ := cmd.Flags().GetString("older-than")
+	newerThan, _ := cmd.Flags().GetString("newer-than")
 	smallerThan, _ := cmd.Flags().GetString("smaller-than")
 	largerThan, _ := cmd.Flags().GetString("larger-than")
 	bucketName, _ := cmd.Flags().GetString("bucket")
+	includeServiceInitiated, _ := cmd.Flags().GetBool("include-service-initiated")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	remeasureOlderThan, err := remeasureAge(cmd)
+	if err != nil {
+		return err
+	}
+	maxDeletes, _ := cmd.Flags().GetInt("max-deletes")
+	checkObjects, _ := cmd.Flags().GetBool("check-objects")
+	verifyAccess, _ := cmd.Flags().GetBool("verify-access")
 	
-	uploadService := a.container.GetUploadService()
+	// Only the dry-run report shows which uploads are superseded
+	if checkObjects && !dryRun {
+		return fmt.Errorf("--check-objects can only be used with --dry-run")
+	}
+	if verifyAccess && !dryRun {
+		return fmt.Errorf("--verify-access checks ahead of a deletion, so it can only be used with --dry-run")
+	}
+	if cmd.Flags().Changed("report-file") && dryRun {
+		return fmt.Errorf("--report-file records deletions, so it cannot be used with --dry-run")
+	}
+	if cmd.Flags().Changed("save") && !dryRun {
+		return fmt.Errorf("--save saves the dry-run report, so it can only be used with --dry-run")
+	}
+	if cmd.Flags().Changed("checkpoint") && dryRun {
+		return fmt.Errorf("--checkpoint records the uploads left to delete, so it cannot be used with --dry-run")
+	}
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if format == outputMarkdown && !dryRun {
+		return fmt.Errorf("--output markdown formats the dry-run summary, so it can only be used with --dry-run")
+	}
+	if cmd.Flags().Changed("retry-from") && cmd.Flags().Changed("from-file") {
+		return fmt.Errorf("--retry-from and --from-file cannot be used together")
+	}
 	
+	skipAutoExpiring, autoExpiringWithin, args, err := a.skipAutoExpiring(cmd, args)
+	if err != nil {
+		return err
+	}
+	names, err := filenameTemplate(cmd)
+	if err != nil {
+		return err
+	}
+	if names != nil && !cmd.Flags().Changed("save") && !cmd.Flags().Changed("report-file") {
+		return fmt.Errorf("--filename-template names the files of --save and --report-file, so it needs one of them")
+	}
+	saveFile, args, err := a.dryRunSaveFile(cmd, args, names)
+	if err != nil {
+		return err
+	}
+	reportFile, err := a.deletionReportFile(cmd, names)
+	if err != nil {
+		return err
+	}
+	failures, err := a.failureWriter(cmd, args)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		failures = nil // nothing is deleted, so nothing can fail
+	}
+
+	uploadService := a.container.GetUploadService()
+	bucketTags, _ := bucketTagFilter(cmd)
+
 	deleteOpts := types.DeleteOptions{
 		Force:      force,
 		DryRun:     dryRun,
 		BucketName: bucketName,
-		Quiet:      false,
+		KeyPrefix:  keyPrefix(cmd),
+		Quiet:      quiet,
+		IncludeServiceInitiated: includeServiceInitiated,
+		MaxDeletes: maxDeletes,
+		KeySuffix:  keySuffixFilter(cmd, nil),
+		BucketTags: bucketTags,
+		SkipAutoExpiring:   skipAutoExpiring,
+		AutoExpiringWithin: autoExpiringWithin,
+		VerifyAccess:       verifyAccess,
+	}
+	
+	deleteOpts.StorageClasses, err = storageClasses(cmd)
+	if err != nil {
+		return err
 	}
-	
-	if olderThan != "" {
-		duration, err := a.parseDuration(olderThan)
-		if err != nil {
-			return fmt.Errorf("invalid --older-than value: %w", err)
-		}
-		deleteOpts.OlderThan = &duration
+	deleteOpts.OlderThan, deleteOpts.InitiatedBefore, err = a.parseAgeBound("older-than", olderThan)
+	if err != nil {
+		return err
+	}
+	deleteOpts.NewerThan, deleteOpts.InitiatedAfter, err = a.parseAgeBound("newer-than", newerThan)
+	if err != nil {
+		return err
+	}
+	if err := initiatedBounds(cmd, &deleteOpts); err != nil {
+		return err
 	}
 	
 	if smallerThan != "" {
-		size, err := a.parseSize(smallerThan)
+		size, err := units.ParseBytes(smallerThan)
 		if err != nil {
 			return fmt.Errorf("invalid --smaller-than value: %w", err)
 		}
@@ -553,134 +1352,434 @@ func (a *App) runDeleteCommand(cmd *cobra.Command, args []string) error {
 	}
 	
 	if largerThan != "" {
-		size, err := a.parseSize(largerThan)
+		size, err := units.ParseBytes(largerThan)
 		if err != nil {
 			return fmt.Errorf("invalid --larger-than value: %w", err)
 		}
 		deleteOpts.LargerThan = &size
 	}
 	
-	listOpts := types.ListOptions{
-		BucketName: bucketName,
+	filter, err := a.deleteFilter(cmd, &deleteOpts)
+	if err != nil {
+		return err
+	}
+	if _, err := checkingObjects(cmd, filter); err != nil {
+		return err
+	}
+	
+	// Reject bad options before spending minutes listing uploads
+	if err := deleteOpts.Validate(); err != nil {
+		return fmt.Errorf("invalid delete options: %w", err)
 	}
 	
-	uploads, err := uploadService.ListUploads(ctx, listOpts)
+	report := a.deletionReportWriter(cmd, reportFile, deleteOpts)
+	checkpoint, err := a.deletionCheckpoint(cmd, deleteOpts)
 	if err != nil {
-		return fmt.Errorf("failed to list uploads: %w", err)
+		return err
+	}
+	
+	// A checkpoint holds uploads already selected, so resuming it lists nothing
+	uploads, resumed, err := a.loadCheckpointUploads(cmd, checkpoint)
+	offline := resumed
+	if err == nil && !offline {
+		uploads, offline, err = a.loadOfflineUploads(cmd)
+	}
+	if err == nil && !offline {
+		uploads, offline, err = a.loadRetryUploads(ctx, cmd, report)
+	}
+	if err != nil {
+		return err
+	}
+	if offline && !resumed {
+		// Saved uploads were not listed with the tag selection, so it is applied now
+		if uploads, err = a.selectTaggedUploads(ctx, uploads, bucketTags); err != nil {
+			return err
+		}
+	} else if !offline {
+		listOpts := a.listOptions(cmd, bucketName)
+		
+		uploads, err = uploadService.ListUploads(ctx, listOpts)
+		if err = a.partialListing(err); err != nil {
+			return fmt.Errorf("failed to list uploads: %w", err)
+		}
+	}
+	if !quiet {
+		// Uploads in buckets that could not be listed are not deleted
+		defer a.printBucketErrors(cmd)
 	}
 	
 	if len(uploads) == 0 {
-		cmd.Println("No incomplete multipart uploads found.")
-		return nil
+		if !quiet {
+			cmd.Println("No incomplete multipart uploads found.")
+		}
+		if report != nil {
+			a.writeDeletionReport(cmd, report, nil, false)
+		}
+		if resumed {
+			if err := checkpoint.Remove(); err != nil {
+				return err
+			}
+		}
+		return failIfEmpty(cmd, 0)
+	}
+	
+	// Size filters and dry-run savings need real sizes, and abort dates are
+	// only known once parts have been listed
+	if resumed {
+		deleteOpts = resumedDeleteOptions(deleteOpts)
+		filter = interfaces.Filter{}
+		remeasureOlderThan = 0
+	}
+	measureForFilter := filter.Size != nil || filter.Parts != nil || filter.Cost != nil
+	if deleteOpts.SmallerThan != nil || deleteOpts.LargerThan != nil || deleteOpts.DryRun || deleteOpts.SkipAutoExpiring || remeasureOlderThan > 0 || measureForFilter {
+		uploads, _, err = a.container.GetSizeService().HydrateUploadSizes(ctx, uploads)
+		if err != nil {
+			return fmt.Errorf("failed to calculate upload sizes: %w", err)
+		}
+	}
+	
+	// Cached sizes are never expired, so refresh old ones before deciding what to delete
+	if remeasureOlderThan > 0 {
+		uploads, err = a.container.GetSizeService().RemeasureStaleSizes(ctx, uploads, remeasureOlderThan)
+		if err != nil {
+			return fmt.Errorf("failed to remeasure upload sizes: %w", err)
+		}
+	}
+	if filter.Cost != nil {
+		uploads = a.estimateCosts(ctx, uploads)
+	}
+	
+	if checkObjects {
+		uploads, err = a.checkObjects(cmd, uploads)
+		if err != nil {
+			return err
+		}
+	}
+	
+	// Record failures and outcomes as they happen; an interrupt cancels the
+	// remaining deletions so they are recorded too, and a second one exits at once
+	if failures != nil || report != nil || checkpoint != nil {
+		var stop context.CancelFunc
+		ctx, stop = interruptibleContext(ctx)
+		defer stop()
+	}
+	if failures != nil {
+		deleteOpts.OnFailure = func(upload types.MultipartUpload, err error) {
+			failures.Record(upload, err) // write errors are returned by Close
+		}
+	}
+	var outcomes []func(types.MultipartUpload, error)
+	if report != nil {
+		outcomes = append(outcomes, report.Record)
+	}
+	if checkpoint != nil {
+		outcomes = append(outcomes, checkpoint.Record)
+	}
+	// Aborted uploads are gone, so their cached sizes are dropped
+	sizeCache := a.container.GetSizeCache()
+	if sizeCache != nil {
+		outcomes = append(outcomes, func(upload types.MultipartUpload, err error) {
+			if err == nil {
+				sizeCache.Delete(upload)
+			}
+		})
+	}
+	if len(outcomes) > 0 {
+		deleteOpts.OnOutcome = func(upload types.MultipartUpload, err error) {
+			for _, record := range outcomes {
+				record(upload, err)
+			}
+		}
+	}
+	if checkpoint != nil {
+		deleteOpts.OnStart = func(uploads []types.MultipartUpload) {
+			checkpoint.Start(uploads) // save errors are returned by Close
+		}
+	}
+	if resumed {
+		// Uploads deleted after the checkpoint was last saved are gone already
+		deleteOpts.OnAlreadyCleaned = func(upload types.MultipartUpload) {
+			if report != nil {
+				report.RecordAlreadyCleaned(upload)
+			}
+			checkpoint.Record(upload, nil)
+		}
+	}
+	if format == outputMarkdown {
+		formatter := a.container.GetOutputFormatter()
+		deleteOpts.ReportDryRun = func(result types.DryRunResult) {
+			cmd.Print(formatter.FormatDryRunMarkdown(result, commandLine(cmd)))
+		}
+	}
+	var dryRunSaveErr error
+	if saveFile != "" {
+		deleteOpts.OnDryRun = func(result types.DryRunResult) {
+			dryRunSaveErr = a.saveDryRunResult(cmd, result, saveFile)
+		}
 	}
 	
 	err = uploadService.DeleteUploads(ctx, uploads, deleteOpts)
+	if sizeCache != nil {
+		_ = sizeCache.Save() // a write failure only leaves sizes of deleted uploads until they are pruned
+	}
+	if err == nil && dryRunSaveErr != nil {
+		return dryRunSaveErr
+	}
+	// Filters that match nothing leave nothing to do, which is not an error
+	// unless --fail-if-empty asks for one
+	nothingMatched := errors.Is(err, services.ErrNoUploadsMatched)
+	if nothingMatched {
+		if !quiet {
+			cmd.Printf("Nothing to delete: %v\n", err)
+		}
+		err = nil
+	}
+	
+	if report != nil {
+		a.writeDeletionReport(cmd, report, err, ctx.Err() != nil)
+	}
+	if failures != nil {
+		if saveErr := a.closeFailureReport(cmd, failures, ctx.Err() != nil); saveErr != nil {
+			if err == nil {
+				return saveErr
+			}
+			cmd.PrintErrf("Error: %v\n", saveErr)
+		}
+	}
+	if checkpoint != nil {
+		var saveErr error
+		if nothingMatched && resumed {
+			// Nothing the checkpoint left still matches, so there is nothing to resume
+			saveErr = checkpoint.Remove()
+		} else {
+			saveErr = a.closeCheckpoint(cmd, checkpoint)
+		}
+		if saveErr != nil {
+			if err == nil {
+				return saveErr
+			}
+			cmd.PrintErrf("Error: %v\n", saveErr)
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete uploads: %w", err)
 	}
+	if nothingMatched {
+		return failIfEmpty(cmd, 0)
+	}
 	
 	return nil
 }
 
-func (a *App) parseDuration(durationStr string) (time.Duration, error) {
-	if len(durationStr) < 2 {
-		return 0, fmt.Errorf("invalid duration format")
+// deleteFilter parses delete --filter into opts. Conditions that no upload
+// selected by the other flags could meet are rejected, since the deletion
+// would silently match nothing.
+func (a *App) deleteFilter(cmd *cobra.Command, opts *types.DeleteOptions) (interfaces.Filter, error) {
+	filterStr := a.filterExpression(cmd)
+	if filterStr == "" {
+		return interfaces.Filter{}, nil
+	}
+	filterEngine := a.container.GetFilterEngine()
+	filter, err := filterEngine.ParseFilter(filterStr)
+	if err != nil {
+		return interfaces.Filter{}, fmt.Errorf("invalid filter syntax: %w", err)
 	}
 	
-	unit := durationStr[len(durationStr)-1:]
-	valueStr := durationStr[:len(durationStr)-1]
-	
-	value := 0
-	for _, r := range valueStr {
-		if r < '0' || r > '9' {
-			return 0, fmt.Errorf("invalid duration format")
+	if opts.BucketName != "" && !filterEngine.MatchesFilter(types.MultipartUpload{Bucket: opts.BucketName}, interfaces.Filter{Bucket: filter.Bucket}) {
+		return interfaces.Filter{}, fmt.Errorf("--bucket %s conflicts with the bucket conditions of --filter, so no upload could match both", opts.BucketName)
+	}
+	if len(opts.StorageClasses) > 0 && len(filter.StorageClass) > 0 {
+		matched := false
+		for _, storageClass := range opts.StorageClasses {
+			matched = matched || filterEngine.MatchesFilter(types.MultipartUpload{StorageClass: storageClass}, interfaces.Filter{StorageClass: filter.StorageClass})
 		}
-		value = value*10 + int(r-'0')
+		if !matched {
+			return interfaces.Filter{}, fmt.Errorf("--storage-class %s conflicts with the storageClass conditions of --filter, so no upload could match both", strings.Join(opts.StorageClasses, ","))
+		}
+	}
+	if !opts.IncludeServiceInitiated && !filterEngine.MatchesFilter(types.MultipartUpload{}, interfaces.Filter{InitiatedBy: filter.InitiatedBy}) {
+		return interfaces.Filter{}, fmt.Errorf("the initiatedBy conditions of --filter only match service-initiated uploads, which are not deleted without --include-service-initiated")
+	}
+	if !ageConditionsMeet(filterEngine, filter, opts) {
+		return interfaces.Filter{}, fmt.Errorf("--older-than, --newer-than, --initiated-before and --initiated-after conflict with the age and initiated conditions of --filter, so no upload could match both")
+	}
+	if !keyConditionsMeet(opts.KeySuffix, filter.Key) {
+		return interfaces.Filter{}, fmt.Errorf("--suffix and --exclude-suffix conflict with the key conditions of --filter, so no upload could match both")
 	}
 	
-	switch unit {
-	case "s":
-		return time.Duration(value) * time.Second, nil
-	case "m":
-		return time.Duration(value) * time.Minute, nil
-	case "h":
-		return time.Duration(value) * time.Hour, nil
-	case "d":
-		return time.Duration(value) * 24 * time.Hour, nil
-	case "w":
-		return time.Duration(value) * 7 * 24 * time.Hour, nil
-	default:
-		return 0, fmt.Errorf("unsupported duration unit: %q (use s, m, h, d, w)", unit)
+	opts.Filter = filterStr
+	opts.FilterMatch = func(upload types.MultipartUpload) bool {
+		return filterEngine.MatchesFilter(upload, filter)
 	}
+	return filter, nil
 }
 
-func (a *App) parseSize(sizeStr string) (int64, error) {
-	if len(sizeStr) < 2 {
-		return 0, fmt.Errorf("invalid size format")
-	}
-	
-	var valueStr string
-	var unit string
-	
-	for i := len(sizeStr) - 1; i >= 0; i-- {
-		if sizeStr[i] >= '0' && sizeStr[i] <= '9' || sizeStr[i] == '.' {
-			valueStr = sizeStr[:i+1]
-			unit = sizeStr[i+1:]
-			break
+// ageConditionsMeet reports whether an upload initiated within the age bounds
+// of opts could meet the age and initiated conditions of filter. Both only
+// change what they match at their cut-offs, so it is enough to try the times
+// around each of them.
+func ageConditionsMeet(filterEngine interfaces.FilterEngine, filter interfaces.Filter, opts *types.DeleteOptions) bool {
+	now := time.Now()
+	after, before := opts.InitiatedWindow(now)
+	conditions := interfaces.Filter{Age: filter.Age, Initiated: filter.Initiated}
+	if (after == nil && before == nil) || (conditions.Age == nil && len(conditions.Initiated) == 0) {
+		return true
+	}
+	
+	cutoffs := []time.Time{now, time.Unix(0, 0)}
+	for _, bound := range []*time.Time{after, before} {
+		if bound != nil {
+			cutoffs = append(cutoffs, *bound)
 		}
 	}
-	
-	if valueStr == "" {
-		return 0, fmt.Errorf("invalid size format")
+	for _, initiated := range conditions.Initiated {
+		cutoffs = append(cutoffs, initiated.Value)
+	}
+	if conditions.Age != nil {
+		if cutoff, err := types.ParseCutoff(conditions.Age.Value); err == nil {
+			cutoffs = append(cutoffs, cutoff)
+		} else if age, err := units.ParseDuration(conditions.Age.Value); err == nil {
+			cutoffs = append(cutoffs, now.Add(-age))
+		}
 	}
 	
-	var value float64
-	dotFound := false
-	intPart := 0
-	fracPart := 0
-	fracDigits := 0
-	
-	for _, r := range valueStr {
-		if r == '.' {
-			if dotFound {
-				return 0, fmt.Errorf("invalid size format")
+	for _, cutoff := range cutoffs {
+		for _, initiated := range []time.Time{cutoff.Add(-time.Second), cutoff, cutoff.Add(time.Second)} {
+			if (after != nil && initiated.Before(*after)) || (before != nil && !initiated.Before(*before)) {
+				continue
 			}
-			dotFound = true
-		} else if r >= '0' && r <= '9' {
-			if dotFound {
-				fracPart = fracPart*10 + int(r-'0')
-				fracDigits++
-			} else {
-				intPart = intPart*10 + int(r-'0')
+			if filterEngine.MatchesFilter(types.MultipartUpload{Initiated: initiated}, conditions) {
+				return true
 			}
-		} else {
-			return 0, fmt.Errorf("invalid size format")
 		}
 	}
-	
-	value = float64(intPart)
-	if fracDigits > 0 {
-		fracValue := float64(fracPart)
-		for i := 0; i < fracDigits; i++ {
-			fracValue /= 10
+	return false
+}
+
+// keyConditionsMeet reports whether some key could pass both suffix filters.
+// A key that passes both ends with a suffix each includes, the longer of the
+// two, so it is enough to try those suffixes.
+func keyConditionsMeet(suffixes, filter *types.KeySuffixFilter) bool {
+	if suffixes.IsEmpty() || filter.IsEmpty() {
+		return true
+	}
+	keys := append([]string{""}, suffixes.Include...)
+	keys = append(keys, filter.Include...)
+	for _, key := range keys {
+		if suffixes.Matches(key) && filter.Matches(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// remeasureAge parses --remeasure-older-than like the other durations, so
+// the value the staleness warning suggests is accepted
+func remeasureAge(cmd *cobra.Command) (time.Duration, error) {
+	value, _ := cmd.Flags().GetString("remeasure-older-than")
+	if value == "" {
+		return 0, nil
+	}
+	age, err := units.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --remeasure-older-than value: %w", err)
+	}
+	return age, nil
+}
+
+// parseAgeBoundaries parses age --buckets, a comma-separated list of
+// increasing durations; an empty value keeps the default buckets
+func (a *App) parseAgeBoundaries(value string) ([]time.Duration, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var boundaries []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		boundary, err := units.ParseDuration(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid boundary %q: %w", part, err)
 		}
-		value += fracValue
+		boundaries = append(boundaries, boundary)
+	}
+	if err := services.ValidateAgeBoundaries(boundaries); err != nil {
+		return nil, err
+	}
+	return boundaries, nil
+}
+
+// parseAgeBound parses an --older-than or --newer-than value as either a
+// duration or an absolute date. An absolute date stays fixed between a
+// dry-run and the real deletion.
+func (a *App) parseAgeBound(flag, value string) (*time.Duration, *time.Time, error) {
+	if value == "" {
+		return nil, nil, nil
+	}
+	if cutoff, err := types.ParseCutoff(value); err == nil {
+		return nil, &cutoff, nil
+	}
+	duration, err := units.ParseDuration(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid --%s value: expected a duration such as 7d or a date such as 2024-05-01: %w", flag, err)
+	}
+	return &duration, nil, nil
+}
+
+// storageClasses returns the --storage-class values in S3's spelling, without
+// duplicates. A value that is not an S3 storage class would match nothing, so
+// it is rejected.
+func storageClasses(cmd *cobra.Command) ([]string, error) {
+	values, _ := cmd.Flags().GetStringSlice("storage-class")
+	known := make(map[string]bool)
+	for _, storageClass := range types.StorageClasses() {
+		known[storageClass] = true
+	}
+	var classes []string
+	seen := make(map[string]bool)
+	for _, value := range values {
+		storageClass := types.NormalizeStorageClass(value)
+		if storageClass == "" || seen[storageClass] {
+			continue
+		}
+		if !known[storageClass] {
+			return nil, fmt.Errorf("invalid --storage-class %q, supported: %s", value, strings.Join(types.StorageClasses(), ", "))
+		}
+		seen[storageClass] = true
+		classes = append(classes, storageClass)
+	}
+	return classes, nil
+}
+
+// initiatedBounds sets the --initiated-before and --initiated-after cut-offs,
+// which are the same bounds as --older-than and --newer-than given dates
+func initiatedBounds(cmd *cobra.Command, opts *types.DeleteOptions) error {
+	bounds := []struct {
+		flag, ageFlag string
+		cutoff        **time.Time
+	}{
+		{"initiated-before", "older-than", &opts.InitiatedBefore},
+		{"initiated-after", "newer-than", &opts.InitiatedAfter},
+	}
+	for _, bound := range bounds {
+		value, _ := cmd.Flags().GetString(bound.flag)
+		if value == "" {
+			continue
+		}
+		if *bound.cutoff != nil {
+			return fmt.Errorf("--%s cannot be used with a date given to --%s", bound.flag, bound.ageFlag)
+		}
+		cutoff, err := types.ParseCutoff(value)
+		if err != nil {
+			return fmt.Errorf("invalid --%s value: %w", bound.flag, err)
+		}
+		*bound.cutoff = &cutoff
 	}
 	
-	switch strings.ToUpper(unit) {
-	case "B", "":
-		return int64(value), nil
-	case "KB", "K":
-		return int64(value * 1024), nil
-	case "MB", "M":
-		return int64(value * 1024 * 1024), nil
-	case "GB", "G":
-		return int64(value * 1024 * 1024 * 1024), nil
-	case "TB", "T":
-		return int64(value * 1024 * 1024 * 1024 * 1024), nil
-	default:
-		return 0, fmt.Errorf("unsupported size unit: %q (use B, KB, MB, GB, TB)", unit)
+	before, _ := cmd.Flags().GetString("initiated-before")
+	after, _ := cmd.Flags().GetString("initiated-after")
+	if before != "" && after != "" && !opts.InitiatedAfter.Before(*opts.InitiatedBefore) {
+		return fmt.Errorf("--initiated-before must be later than --initiated-after, the time window is empty")
 	}
+	return nil
 }
 
 func (a *App) addExportCommand() {
@@ -689,10 +1788,22 @@ func (a *App) addExportCommand() {
 		Short: "Export upload data to files",
 		RunE:  a.runExportCommand,
 	}
-	cmd.Flags().String("format", "csv", "Export format: csv, json")
-	cmd.Flags().String("filter", "", "Filter uploads using query syntax")
+	cmd.Flags().String("format", "csv", "Export format: csv, json, xlsx (workbook with per-bucket and per-region summaries)")
+	cmd.Flags().String("filter", "", "Filter uploads using query syntax (age=7d matches within --age-tolerance; use age=7d±6h for a custom window)")
+	addSuffixFlags(cmd)
 	cmd.Flags().StringP("bucket", "b", "", "Export uploads from specific bucket")
+	addPrefixFlag(cmd)
+	addBucketTagFlags(cmd)
 	cmd.Flags().StringP("output", "o", "", "Output file path (auto-generated if not specified)")
+	addFilenameTemplateFlags(cmd, "the export when --output is not given")
+	cmd.Flags().Bool("compress", false, "Compress the export with gzip (implied by an --output name ending in .gz)")
+	cmd.Flags().String("split-by", "", "Write one file per bucket, named after --output and the bucket: bucket")
+	cmd.Flags().Int("max-rows", 0, "Cut the export into numbered files of at most this many uploads each")
+	cmd.Flags().String("max-file-size", "", "Cut the export into numbered files of at most this size before compression, e.g. 100MB (csv and json)")
+	cmd.Flags().Bool("include-parts", false, "Export every part's number, size, ETag and last-modified time: nested in json, or a second *_parts.csv file for csv")
+	addSortFlags(cmd, "")
+	addCheckObjectsFlag(cmd)
+	addAccountFlags(cmd)
 	a.rootCmd.AddCommand(cmd)
 }
 
@@ -700,34 +1811,112 @@ func (a *App) runExportCommand(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	
 	format, _ := cmd.Flags().GetString("format")
-	filterStr, _ := cmd.Flags().GetString("filter")
+	filterStr := a.filterExpression(cmd)
 	bucketName, _ := cmd.Flags().GetString("bucket")
 	outputFile, _ := cmd.Flags().GetString("output")
+	compress, _ := cmd.Flags().GetBool("compress")
 	
-	if format != "csv" && format != "json" {
-		return fmt.Errorf("invalid format: %q (must be csv or json)", format)
+	if format != "csv" && format != "json" && format != "xlsx" {
+		return fmt.Errorf("invalid format: %q (must be csv, json or xlsx)", format)
+	}
+	if compress && format == "xlsx" {
+		return fmt.Errorf("--compress cannot be used with --format xlsx: xlsx files are already compressed")
+	}
+	split, err := exportSplitOptions(cmd, format)
+	if err != nil {
+		return err
+	}
+	includeParts, err := exportIncludesParts(cmd, format, split)
+	if err != nil {
+		return err
+	}
+	names, err := filenameTemplate(cmd)
+	if err != nil {
+		return err
+	}
+	if names != nil && outputFile != "" {
+		return fmt.Errorf("--filename-template names the export when --output is not given, so they cannot be used together")
+	}
+	order, err := uploadSort(cmd)
+	if err != nil {
+		return err
 	}
 	
 	uploadService := a.container.GetUploadService()
 	exportService := a.container.GetExportService()
 	filterEngine := a.container.GetFilterEngine()
 	
-	listOpts := types.ListOptions{
-		BucketName: bucketName,
+	listOpts := a.listOptions(cmd, bucketName)
+	
+	var filter interfaces.Filter
+	if filterStr != "" {
+		parsed, err := filterEngine.ParseFilter(filterStr)
+		if err != nil {
+			return fmt.Errorf("invalid filter syntax: %w", err)
+		}
+		filter = parsed
+	}
+	filter.Key = keySuffixFilter(cmd, filter.Key)
+	checkObjects, err := checkingObjects(cmd, filter)
+	if err != nil {
+		return err
 	}
 	
-	uploads, err := uploadService.ListUploads(ctx, listOpts)
+	accounts, err := a.accounts(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to list uploads: %w", err)
+		return err
+	}
+	// Named before listing, so that a failed account lookup wastes no time
+	var nameData services.FilenameData
+	namedAt := time.Now()
+	if names != nil {
+		nameData = services.FilenameData{Command: "export", Format: format}
+		if accounts != nil {
+			nameData.Account = "all"
+		}
+		if nameData, err = a.filenameData(cmd, names, nameData); err != nil {
+			return err
+		}
+		if outputFile, err = renderFilename(names, nameData, namedAt); err != nil {
+			return err
+		}
 	}
 	
-	if filterStr != "" {
-		filter, err := filterEngine.ParseFilter(filterStr)
+	var uploads []types.MultipartUpload
+	if accounts != nil {
+		scan, err := a.scanAccounts(cmd, accounts, listOpts, false)
 		if err != nil {
-			return fmt.Errorf("invalid filter syntax: %w", err)
+			return err
 		}
-		uploads = filterEngine.ApplyFilter(uploads, filter)
+		uploads = scan.uploads
+		defer a.printAccountFailures(cmd, scan.failures)
+	} else {
+		uploads, err = uploadService.ListUploads(ctx, listOpts)
+		if err = a.partialListing(err); err != nil {
+			return fmt.Errorf("failed to list uploads: %w", err)
+		}
+	}
+	defer a.printBucketErrors(cmd)
+	if includeParts {
+		warnManyParts(cmd, uploads, a.container.GetSizeService())
+		// Listing the parts measures the uploads too, so sizes need no walk of their own
+		uploads, _, err = a.container.GetSizeService().HydrateUploadParts(ctx, uploads)
+		if err != nil {
+			return fmt.Errorf("failed to list upload parts: %w", err)
+		}
+		uploads = a.estimateCosts(ctx, uploads)
+	} else if order.NeedsSizes() || filter.Size != nil || filter.Parts != nil || filter.Cost != nil {
+		uploads, _, err = a.container.GetSizeService().HydrateUploadSizes(ctx, uploads)
+		if err != nil {
+			return fmt.Errorf("failed to calculate upload sizes: %w", err)
+		}
+		uploads = a.estimateCosts(ctx, uploads)
+	}
+	uploads, err = a.filterCheckingObjects(cmd, uploads, filter, checkObjects)
+	if err != nil {
+		return err
 	}
+	uploads = a.sortUploads(ctx, uploads, order)
 	
 	if len(uploads) == 0 {
 		cmd.Println("No uploads found to export.")
@@ -739,25 +1928,51 @@ func (a *App) runExportCommand(cmd *cobra.Command, args []string) error {
 		if bucketName != "" {
 			commandStr += "_" + bucketName
 		}
-		if filterStr != "" {
+		if filterStr != "" || filter.Key != nil || keyPrefix(cmd) != "" {
 			commandStr += "_filtered"
 		}
-		outputFile = exportService.GenerateExportFilename(commandStr, format)
-	}
-	
-	switch format {
-	case "csv":
-		err = exportService.ExportToCSV(ctx, uploads, outputFile)
-	case "json":
-		err = exportService.ExportToJSON(ctx, uploads, outputFile)
+		outputFile = exportService.GenerateExportFilename(commandStr, format, compress)
+	} else if compress && !services.IsCompressedFilename(outputFile) {
+		// The export service compresses by file name
+		outputFile += ".gz"
 	}
 	
-	if err != nil {
-		return fmt.Errorf("failed to export data: %w", err)
+	if includeParts && split == nil {
+		files, err := exportService.ExportWithParts(ctx, uploads, format, outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to export data: %w", err)
+		}
+		cmd.Printf("Successfully exported %d uploads to %q\n", len(uploads), outputFile)
+		if len(files) > 1 {
+			cmd.Printf("Parts written to %q\n", files[1])
+		}
+	} else if split != nil {
+		split.OutputFile = outputFile
+		if names != nil && split.SplitBy == services.ExportSplitByBucket && names.Uses("Bucket") {
+			if split.BucketFiles, err = bucketFilenames(names, nameData, namedAt, uploads, compress); err != nil {
+				return err
+			}
+		}
+		manifest, err := exportService.ExportSplit(ctx, uploads, *split)
+		if err != nil {
+			return fmt.Errorf("failed to export data: %w", err)
+		}
+		cmd.Printf("Successfully exported %d uploads to %d files listed in %q\n", len(uploads), len(manifest.Parts), manifest.Index)
+	} else {
+		switch format {
+		case "csv":
+			err = exportService.ExportToCSV(ctx, uploads, outputFile)
+		case "json":
+			err = exportService.ExportToJSON(ctx, uploads, outputFile)
+		case "xlsx":
+			err = exportService.ExportToXLSX(ctx, uploads, outputFile)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to export data: %w", err)
+		}
+		cmd.Printf("Successfully exported %d uploads to %q\n", len(uploads), outputFile)
 	}
 	
-	cmd.Printf("Successfully exported %d uploads to %q\n", len(uploads), outputFile)
-	
 	var totalSize int64
 	bucketCounts := make(map[string]int)
 	for _, upload := range uploads {
@@ -765,7 +1980,7 @@ func (a *App) runExportCommand(cmd *cobra.Command, args []string) error {
 		bucketCounts[upload.Bucket]++
 	}
 	
-	cmd.Printf("Total size: %s\n", FormatBytes(totalSize))
+	cmd.Printf("Total size: %s\n", units.FormatBytes(totalSize))
 	cmd.Printf("Buckets: %d\n", len(bucketCounts))
 	
 	if len(bucketCounts) <= 5 {
@@ -778,6 +1993,143 @@ func (a *App) runExportCommand(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// exportSplitOptions returns how --split-by, --max-rows and --max-file-size
+// split an export of format, or nil when the export is one file
+func exportSplitOptions(cmd *cobra.Command, format string) (*types.ExportOptions, error) {
+	splitBy, _ := cmd.Flags().GetString("split-by")
+	maxRows, _ := cmd.Flags().GetInt("max-rows")
+	maxFileSize, _ := cmd.Flags().GetString("max-file-size")
+
+	if splitBy != "" && splitBy != services.ExportSplitByBucket {
+		return nil, fmt.Errorf("invalid --split-by: %q (supported: %s)", splitBy, services.ExportSplitByBucket)
+	}
+	if maxRows < 0 {
+		return nil, fmt.Errorf("invalid --max-rows: cannot be negative, got %d", maxRows)
+	}
+	split := &types.ExportOptions{Format: format, SplitBy: splitBy, MaxRows: maxRows}
+	if maxFileSize != "" {
+		size, err := units.ParseBytes(maxFileSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --max-file-size value: %w", err)
+		}
+		if size <= 0 {
+			return nil, fmt.Errorf("invalid --max-file-size: must be positive, got %s", maxFileSize)
+		}
+		if format == "xlsx" {
+			return nil, fmt.Errorf("--max-file-size cannot be used with --format xlsx: workbooks are compressed as they are written; use --max-rows")
+		}
+		split.MaxFileSize = size
+	}
+	if split.SplitBy == "" && split.MaxRows == 0 && split.MaxFileSize == 0 {
+		return nil, nil
+	}
+	return split, nil
+}
+
+// manyPartsWarning is the number of parts above which --include-parts warns
+// that the export will be large
+const manyPartsWarning = 100000
+
+// exportIncludesParts reports whether --include-parts is set, rejecting
+// formats that cannot hold the parts. Split CSV exports would each need a
+// parts file of their own, so only JSON, which nests the parts, is split.
+func exportIncludesParts(cmd *cobra.Command, format string, split *types.ExportOptions) (bool, error) {
+	includeParts, _ := cmd.Flags().GetBool("include-parts")
+	if !includeParts {
+		return false, nil
+	}
+	if format == "xlsx" {
+		return false, fmt.Errorf("--include-parts cannot be used with --format xlsx; use csv or json")
+	}
+	if format == "csv" && split != nil {
+		return false, fmt.Errorf("--include-parts with --format csv writes one parts file, so it cannot be used with --split-by, --max-rows or --max-file-size; use --format json")
+	}
+	return true, nil
+}
+
+// warnManyParts warns before the parts are listed when --include-parts looks
+// set to write more than manyPartsWarning parts, since each adds a row or
+// JSON object to the export. The parts counts of measured and cached uploads
+// stand for those of the rest, so a listing no size was cached for does not warn.
+func warnManyParts(cmd *cobra.Command, uploads []types.MultipartUpload, sizeService interfaces.SizeService) {
+	if quiet, _ := cmd.Flags().GetBool("quiet"); quiet {
+		return
+	}
+	parts, known := sizeService.EstimateParts(uploads)
+	if known == 0 {
+		return
+	}
+	if estimate := parts * len(uploads) / known; estimate > manyPartsWarning {
+		cmd.PrintErrf("Warning: --include-parts will export about %d parts of %d uploads; the export may be very large\n", estimate, len(uploads))
+	}
+}
+
+func (a *App) addCacheCommand() {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the upload size cache",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "clear",
+		Short: "Remove all cached upload sizes",
+		RunE:  a.runCacheClearCommand,
+	})
+	a.rootCmd.AddCommand(cmd)
+}
+
+func (a *App) runCacheClearCommand(cmd *cobra.Command, args []string) error {
+	cache := a.container.GetSizeCache()
+	if cache == nil {
+		cache = services.NewSizeCache(a.container.SizeCachePath())
+	}
+	
+	if err := cache.Clear(); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	
+	cmd.Printf("Cleared upload size cache %q\n", cache.Path())
+	return nil
+}
+
+func (a *App) addExplainCommand() {
+	cmd := &cobra.Command{
+		Use:   "explain <filter>",
+		Short: "Explain how a filter expression will be evaluated",
+		Long: `Explain prints the effective meaning of each condition in a filter expression.
+
+Age comparisons with = and != match uploads whose age lies within a tolerance
+window around the given value. The default window is one hour and can be changed
+with --age-tolerance or per condition with the ± syntax (age=7d±6h, or age=7d+-6h).
+With --age-calendar-days, a bare day value such as age=7d instead matches uploads
+initiated on the calendar day (UTC) seven days before today.`,
+		Args: cobra.ExactArgs(1),
+		RunE: a.runExplainCommand,
+	}
+	a.rootCmd.AddCommand(cmd)
+}
+
+func (a *App) runExplainCommand(cmd *cobra.Command, args []string) error {
+	filterEngine := a.container.GetFilterEngine()
+	
+	filter, err := filterEngine.ParseFilter(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid filter syntax: %w", err)
+	}
+	
+	lines := filterEngine.ExplainFilter(filter)
+	if len(lines) == 0 {
+		cmd.Println("Empty filter: all uploads match.")
+		return nil
+	}
+	
+	cmd.Println("Filter conditions (all must match):")
+	for _, line := range lines {
+		cmd.Printf("  %s\n", line)
+	}
+	
+	return nil
+}
+
 // getVersion returns the version from main package or fallback
 func (a *App) getVersion() string {
 	if a.version != "" {
@@ -785,17 +2137,3 @@ func (a *App) getVersion() string {
 	}
 	return "1.0.3"
 }
-
-// FormatBytes formats bytes into human-readable format
-func FormatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
-}
\ No newline at end of file
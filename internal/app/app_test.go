@@ -0,0 +1,905 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/internal/config"
+	"github.com/Garvitkul/s3mpc/internal/container"
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// recordingUploadService records the options of each listing and lists nothing
+type recordingUploadService struct {
+	interfaces.UploadService
+	opts []types.ListOptions
+}
+
+func (s *recordingUploadService) ListUploads(ctx context.Context, opts types.ListOptions) ([]types.MultipartUpload, error) {
+	s.opts = append(s.opts, opts)
+	return nil, nil
+}
+
+func (s *recordingUploadService) SkippedBuckets() []types.SkippedBucket {
+	return nil
+}
+
+// testConfig returns the configuration commands run with in tests
+func testConfig() *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.AWSRegion = "us-east-1"
+	return cfg
+}
+
+// runCommand runs args, a command and its flags, against uploadService and
+// returns its stdout and error
+func runCommand(t *testing.T, uploadService interfaces.UploadService, args ...string) (string, error) {
+	t.Helper()
+	_, stdout, _, err := runCommandWithConfig(t, testConfig(), uploadService, args...)
+	return stdout, err
+}
+
+// runCommandWithConfig runs args as runCommand does, with cfg as the
+// configuration the global flags resolved to; the size and report services
+// measure the uploads of uploadService unless it is nil. It returns the app,
+// for what the run left in it, and the command's stdout, stderr and error.
+func runCommandWithConfig(t *testing.T, cfg *config.Config, uploadService interfaces.UploadService, args ...string) (*App, string, string, error) {
+	t.Helper()
+	c, err := container.NewContainer(cfg)
+	if err != nil {
+		t.Fatalf("NewContainer() error = %v", err)
+	}
+	if uploadService != nil {
+		sizeService := services.NewSizeService(uploadService)
+		c.SetUploadService(uploadService)
+		c.SetSizeService(sizeService)
+		c.SetReportService(services.NewReportService(uploadService, sizeService, c.GetCostCalculator(), c.GetAgeService()))
+	}
+	return runInContainer(t, c, args...)
+}
+
+// runInContainer runs args as runCommandWithConfig does, with the services of c
+func runInContainer(t *testing.T, c *container.Container, args ...string) (*App, string, string, error) {
+	t.Helper()
+	a := NewApp("test")
+	a.container = c
+	cmd, rest, err := a.rootCmd.Find(args)
+	if err != nil {
+		t.Fatalf("%v not registered: %v", args, err)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetContext(context.Background())
+	if err := cmd.ParseFlags(rest); err != nil {
+		t.Fatalf("ParseFlags(%v) error = %v", rest, err)
+	}
+	err = cmd.RunE(cmd, cmd.Flags().Args())
+	return a, stdout.String(), stderr.String(), err
+}
+
+func TestIsValidAWSRegion(t *testing.T) {
+	a := NewApp("test")
+	for _, region := range []string{"us-east-1", "eu-west-1", "ap-south-1", "ap-southeast-3", "us-gov-west-1", "cn-north-1", "il-central-1",
+		"us-gov-east-1", "cn-northwest-1", "us-iso-east-1", "us-isob-east-1", "eu-isoe-west-1", "us-isof-south-1", "mx-central-1"} {
+		if !a.isValidAWSRegion(region) {
+			t.Errorf("isValidAWSRegion(%q) = false, want true", region)
+		}
+	}
+	for _, region := range []string{"", "us-east", "US-EAST-1", "east-1", "us-east-1a", "us--east-1", " us-east-1", "zz-east-1"} {
+		if a.isValidAWSRegion(region) {
+			t.Errorf("isValidAWSRegion(%q) = true, want false", region)
+		}
+	}
+}
+
+func TestRegionsFlagLimitsScans(t *testing.T) {
+	regions := []string{"us-east-1", "eu-west-1"}
+	output := filepath.Join(t.TempDir(), "uploads.csv")
+	for _, args := range [][]string{
+		{"list"},
+		{"size"},
+		{"cost"},
+		{"delete", "--dry-run"},
+		{"export", "--output", output},
+	} {
+		cfg := testConfig()
+		cfg.Regions = regions
+		cfg.Quiet = true
+		uploadService := &recordingUploadService{}
+		if _, _, _, err := runCommandWithConfig(t, cfg, uploadService, args...); err != nil {
+			t.Fatalf("%s error = %v", args[0], err)
+		}
+
+		if len(uploadService.opts) == 0 {
+			t.Fatalf("%s listed no uploads", args[0])
+		}
+		for _, opts := range uploadService.opts {
+			if strings.Join(opts.Regions, ",") != "us-east-1,eu-west-1" {
+				t.Errorf("%s listed with regions %v, want %v", args[0], opts.Regions, regions)
+			}
+		}
+	}
+}
+
+func TestPrefixFlagIsSentToS3(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "uploads.csv")
+	for _, args := range [][]string{
+		{"list", "--prefix", "logs/2023/"},
+		{"size", "--prefix", "logs/2023/"},
+		{"delete", "--dry-run", "--prefix", "logs/2023/"},
+		{"export", "--output", output, "--prefix", "logs/2023/"},
+	} {
+		cfg := testConfig()
+		cfg.Quiet = true
+		uploadService := &recordingUploadService{}
+		if _, _, _, err := runCommandWithConfig(t, cfg, uploadService, args...); err != nil {
+			t.Fatalf("%s error = %v", args[0], err)
+		}
+
+		if len(uploadService.opts) == 0 || uploadService.opts[0].KeyPrefix != "logs/2023/" {
+			t.Errorf("%s listed with %+v, want KeyPrefix logs/2023/", args[0], uploadService.opts)
+		}
+	}
+}
+
+func TestExportSplitFlags(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	run := func(args ...string) (string, error) {
+		uploadService := &listedUploadService{uploads: []types.MultipartUpload{
+			{Bucket: "logs", Key: "a", UploadID: "1", Initiated: time.Now().Add(-time.Hour), StorageClass: "STANDARD", Region: "us-east-1"},
+			{Bucket: "backups", Key: "b", UploadID: "2", Initiated: time.Now().Add(-time.Hour), StorageClass: "STANDARD", Region: "us-east-1"},
+		}}
+		return runCommand(t, uploadService, append([]string{"export"}, args...)...)
+	}
+
+	out, err := run("--output", filepath.Join(dir, "uploads.csv"), "--split-by", "bucket")
+	if err != nil {
+		t.Fatalf("export --split-by bucket error = %v", err)
+	}
+	if want := fmt.Sprintf("exported 2 uploads to 2 files listed in %q", filepath.Join(dir, "uploads_index.csv")); !strings.Contains(out, want) {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	for _, name := range []string{"uploads_logs.csv", "uploads_backups.csv"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not written: %v", name, err)
+		}
+	}
+
+	// A template names each bucket's file with the bucket, and the index with all
+	template := filepath.Join(dir, "{{.Bucket}}", "uploads-{{.Bucket}}")
+	out, err = run("--split-by", "bucket", "--filename-template", template, "--compress")
+	if err != nil {
+		t.Fatalf("export --split-by bucket --filename-template error = %v", err)
+	}
+	if want := fmt.Sprintf("listed in %q", filepath.Join(dir, "all", "uploads-all_index.csv")); !strings.Contains(out, want) {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	for _, name := range []string{"logs/uploads-logs.csv.gz", "backups/uploads-backups.csv.gz"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not written: %v", name, err)
+		}
+	}
+
+	for _, test := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--split-by", "region"}, `invalid --split-by: "region"`},
+		{[]string{"--max-rows", "-1"}, "invalid --max-rows"},
+		{[]string{"--max-file-size", "lots"}, "invalid --max-file-size value"},
+		{[]string{"--format", "xlsx", "--max-file-size", "10MB"}, "--max-file-size cannot be used with --format xlsx"},
+		{[]string{"--format", "xlsx", "--include-parts"}, "--include-parts cannot be used with --format xlsx"},
+		{[]string{"--include-parts", "--split-by", "bucket"}, "writes one parts file"},
+	} {
+		if _, err := run(append(test.args, "--output", filepath.Join(dir, "rejected.csv"))...); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("export %v error = %v, want %s", test.args, err, test.err)
+		}
+	}
+}
+
+func TestExportIncludeParts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	uploadService := &listedUploadService{uploads: []types.MultipartUpload{
+		{Bucket: "logs", Key: "a", UploadID: "1", Initiated: time.Now().Add(-time.Hour), StorageClass: "STANDARD", Region: "us-east-1"},
+	}}
+	out, err := runCommand(t, uploadService, "export", "--include-parts", "--output", filepath.Join(dir, "uploads.csv"))
+	if err != nil {
+		t.Fatalf("export --include-parts error = %v", err)
+	}
+	partsFile := filepath.Join(dir, "uploads_parts.csv")
+	if !strings.Contains(out, fmt.Sprintf("Parts written to %q", partsFile)) {
+		t.Errorf("output = %q, want the parts file", out)
+	}
+	data, err := os.ReadFile(partsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "1,1,1048576,\"\"\"etag-1\"\"\",") {
+		t.Errorf("parts file = %q, want the upload's part", data)
+	}
+}
+
+func TestWarnManyPartsEstimatesBeforeListing(t *testing.T) {
+	initiated := time.Now().Add(-time.Hour)
+	cached := types.MultipartUpload{Bucket: "logs", Key: "a", UploadID: "1", Initiated: initiated, PartsCount: 60000}
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a", UploadID: "1", Initiated: initiated},
+		{Bucket: "logs", Key: "b", UploadID: "2", Initiated: initiated},
+	}
+	cache := services.NewSizeCache("")
+	sizeService := services.NewSizeServiceWithCache(&listedUploadService{}, 1, cache, nil)
+
+	warning := func() string {
+		cmd := &cobra.Command{}
+		cmd.Flags().Bool("quiet", false, "")
+		var stderr bytes.Buffer
+		cmd.SetErr(&stderr)
+		warnManyParts(cmd, uploads, sizeService)
+		return stderr.String()
+	}
+	if got := warning(); got != "" {
+		t.Errorf("warning without cached sizes = %q, want none", got)
+	}
+	// The cached upload stands for the other, so about 120000 parts are due
+	cache.Set(cached, 1<<30)
+	if got, want := warning(), "Warning: --include-parts will export about 120000 parts of 2 uploads; the export may be very large\n"; got != want {
+		t.Errorf("warning = %q, want %q", got, want)
+	}
+}
+
+func TestExportFilterMeasuresUploads(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	for _, filter := range []string{"size>512KB", "parts>=1"} {
+		uploadService := &listedUploadService{uploads: []types.MultipartUpload{
+			{Bucket: "logs", Key: "a", UploadID: "1", Initiated: time.Now().Add(-time.Hour), StorageClass: "STANDARD", Region: "us-east-1"},
+		}}
+		// Listing leaves sizes and parts unknown, so the filter needs them measured
+		out, err := runCommand(t, uploadService, "export", "--filter", filter, "--output", filepath.Join(dir, "uploads.csv"))
+		if err != nil {
+			t.Fatalf("export --filter %s error = %v", filter, err)
+		}
+		if !strings.Contains(out, "Successfully exported 1 uploads") {
+			t.Errorf("export --filter %s output = %q, want the measured upload exported", filter, out)
+		}
+	}
+}
+
+func TestAgeMeasuresUploads(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	uploadService := &listedUploadService{uploads: []types.MultipartUpload{
+		{Bucket: "logs", Key: "a", UploadID: "1", Initiated: time.Now().Add(-time.Hour), StorageClass: "STANDARD", Region: "us-east-1"},
+	}}
+	out, err := runCommand(t, uploadService, "age", "--json")
+	if err != nil {
+		t.Fatalf("age error = %v", err)
+	}
+	var distribution types.AgeDistribution
+	if err := json.Unmarshal([]byte(out), &distribution); err != nil {
+		t.Fatalf("age --json output is not JSON: %v\n%s", err, out)
+	}
+	// Listing leaves sizes unknown, so the ranges need them measured
+	if got := distribution.Buckets[0].TotalSize; got != 1<<20 {
+		t.Errorf("first range size = %d, want the measured 1 MiB", got)
+	}
+}
+
+func TestBucketTagFlagsSelectBuckets(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	output := filepath.Join(t.TempDir(), "uploads.csv")
+	tags := []string{"--bucket-tag", "env=prod", "--exclude-bucket-tag", "protected=true"}
+	for _, args := range [][]string{
+		{"list"},
+		{"size"},
+		{"delete", "--dry-run"},
+		{"export", "--output", output},
+	} {
+		cfg := testConfig()
+		cfg.Quiet = true
+		uploadService := &recordingUploadService{}
+		if _, _, _, err := runCommandWithConfig(t, cfg, uploadService, append(args, tags...)...); err != nil {
+			t.Fatalf("%s error = %v", args[0], err)
+		}
+
+		if len(uploadService.opts) == 0 || uploadService.opts[0].BucketTags.String() != "env=prod, not protected=true" {
+			t.Errorf("%s listed with %+v, want the bucket tag selection", args[0], uploadService.opts)
+		}
+	}
+
+	for _, test := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"list", "--bucket-tag", "env"}, `--bucket-tag: invalid bucket tag "env", expected key=value`},
+		{[]string{"list", "--exclude-bucket-tag", "=true"}, "--exclude-bucket-tag: invalid bucket tag"},
+		{[]string{"size", "--from-file", "uploads.json", "--bucket-tag", "env=prod"}, "cannot be used with --from-file"},
+	} {
+		a := NewApp("test")
+		cmd, rest, err := a.rootCmd.Find(test.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.ParseFlags(append(rest, "--no-cache")); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.initializeContainer(cmd, nil); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%v error = %v, want %s", test.args, err, test.err)
+		}
+	}
+}
+
+func TestRegionsFlagValidatesEachRegion(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, test := range []struct {
+		regions string
+		want    []string
+		err     string
+	}{
+		{regions: "us-east-1, us-gov-west-1,ap-southeast-3", want: []string{"us-east-1", "us-gov-west-1", "ap-southeast-3"}},
+		{regions: "us-east-1,useast1", err: `invalid AWS region format: "useast1"`},
+	} {
+		a := NewApp("test")
+		cmd, _, err := a.rootCmd.Find([]string{"list"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.ParseFlags([]string{"--regions", test.regions, "--no-cache"}); err != nil {
+			t.Fatal(err)
+		}
+
+		err = a.initializeContainer(cmd, nil)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("--regions %s error = %v, want %s", test.regions, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("--regions %s error = %v", test.regions, err)
+		}
+		if got := a.container.GetConfig().Regions; strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("--regions %s = %v, want %v", test.regions, got, test.want)
+		}
+	}
+}
+
+func TestIncludeDirectoryBucketsFlagReachesListings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, args := range [][]string{nil, {"--include-directory-buckets"}} {
+		a := NewApp("test")
+		cmd, _, err := a.rootCmd.Find([]string{"list"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.ParseFlags(append(args, "--no-cache")); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.initializeContainer(cmd, nil); err != nil {
+			t.Fatalf("initializeContainer(%v) error = %v", args, err)
+		}
+
+		want := len(args) > 0
+		if opts := a.listOptions(cmd, ""); opts.IncludeDirectoryBuckets != want {
+			t.Errorf("listOptions() with %v IncludeDirectoryBuckets = %v, want %v", args, opts.IncludeDirectoryBuckets, want)
+		}
+	}
+}
+
+// regionRecordingUploadService records the options each listing is given,
+// and lists the uploads in the regions they select, as S3 would
+type regionRecordingUploadService struct {
+	listedUploadService
+	listed []types.ListOptions
+}
+
+func (s *regionRecordingUploadService) ListUploads(ctx context.Context, opts types.ListOptions) ([]types.MultipartUpload, error) {
+	s.listed = append(s.listed, opts)
+	var uploads []types.MultipartUpload
+	for _, upload := range s.uploads {
+		if opts.Region != "" && upload.Region != opts.Region {
+			continue
+		}
+		if len(opts.Regions) > 0 && !containsString(opts.Regions, upload.Region) {
+			continue
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, nil
+}
+
+func (s *regionRecordingUploadService) DeleteUploads(ctx context.Context, uploads []types.MultipartUpload, opts types.DeleteOptions) error {
+	return nil
+}
+
+// runRegionListing runs args with the global flags in flags, against uploads
+// in us-east-1, eu-west-1 and ap-south-1, and returns its stdout and stderr
+func runRegionListing(t *testing.T, args []string, flags ...string) (*regionRecordingUploadService, string, string) {
+	t.Helper()
+	a := NewApp("test")
+	cmd, rest, err := a.rootCmd.Find(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetContext(context.Background())
+	if err := cmd.ParseFlags(append(append(rest, flags...), "--no-cache")); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.initializeContainer(cmd, nil); err != nil {
+		t.Fatalf("%v: initializeContainer() error = %v", args, err)
+	}
+	initiated := time.Now().Add(-time.Hour)
+	uploadService := &regionRecordingUploadService{listedUploadService: listedUploadService{uploads: []types.MultipartUpload{
+		{Bucket: "logs", Key: "a", UploadID: "1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "media", Key: "b", UploadID: "2", Initiated: initiated, StorageClass: "STANDARD", Region: "eu-west-1"},
+		{Bucket: "archive", Key: "c", UploadID: "3", Initiated: initiated, StorageClass: "STANDARD", Region: "ap-south-1"},
+	}}}
+	a.container.SetUploadService(uploadService)
+	a.container.SetSizeService(services.NewSizeService(uploadService))
+
+	if err := cmd.RunE(cmd, cmd.Flags().Args()); err != nil {
+		t.Fatalf("%v error = %v", args, err)
+	}
+	if len(uploadService.listed) == 0 {
+		t.Fatalf("%v did not list uploads", args)
+	}
+	return uploadService, stdout.String(), stderr.String()
+}
+
+func TestRegionFlagReachesListings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	export := filepath.Join(t.TempDir(), "uploads.csv")
+	for _, args := range [][]string{
+		{"list"},
+		{"size"},
+		{"cost"},
+		{"export", "--output", export},
+		{"delete", "--dry-run"},
+	} {
+		// Regions are compared in AWS's spelling, whatever case and spacing
+		// they are given in; --regions takes over from --region
+		uploadService, _, stderr := runRegionListing(t, args, "--region", " US-East-1", "--regions", "EU-WEST-1,us-east-9")
+		for _, opts := range uploadService.listed {
+			if opts.Region != "" || strings.Join(opts.Regions, ",") != "eu-west-1,us-east-9" {
+				t.Errorf("%v listed with Region %q and Regions %v, want only eu-west-1,us-east-9", args, opts.Region, opts.Regions)
+			}
+		}
+		if want := "Warning: --regions us-east-9 is not a known AWS region"; !strings.Contains(stderr, want) || strings.Contains(stderr, "us-east-1 is not") {
+			t.Errorf("%v stderr = %q, want only %q", args, stderr, want)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		env     string
+		flags   []string
+		buckets []string // the buckets listed
+	}{
+		{"--region", "", []string{"--region", "us-east-1"}, []string{"logs"}},
+		{"--region and --regions", "", []string{"--region", "us-east-1", "--regions", "eu-west-1,ap-south-1"}, []string{"media", "archive"}},
+		{"region from the environment", "us-east-1", nil, []string{"logs", "media", "archive"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("S3MPC_REGION", tt.env)
+			_, stdout, _ := runRegionListing(t, []string{"list"}, tt.flags...)
+			for _, bucket := range []string{"logs", "media", "archive"} {
+				if listed := strings.Contains(stdout, bucket); listed != containsString(tt.buckets, bucket) {
+					t.Errorf("bucket %s listed = %v, want %v:\n%s", bucket, listed, !listed, stdout)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitFlag(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	initialize := func(args ...string) (*App, string, error) {
+		a := NewApp("test")
+		cmd, _, err := a.rootCmd.Find([]string{"list"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var stderr bytes.Buffer
+		cmd.SetErr(&stderr)
+		cmd.SetContext(context.Background())
+		if err := cmd.ParseFlags(append(args, "--region", "us-east-1", "--no-cache")); err != nil {
+			t.Fatal(err)
+		}
+		err = a.initializeContainer(cmd, nil)
+		return a, stderr.String(), err
+	}
+
+	a, stderr, err := initialize("--rate-limit", "0.5", "--concurrency", "20", "--bucket-concurrency", "5")
+	if err != nil {
+		t.Fatalf("initializeContainer() error = %v", err)
+	}
+	if got := a.container.GetConfig().RateLimitRPS; got != 0.5 {
+		t.Errorf("RateLimitRPS = %g, want 0.5 from --rate-limit", got)
+	}
+	if want := "Warning: --concurrency 20 is far above the rate limit of 0.5"; !strings.Contains(stderr, want) {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+	if strings.Contains(stderr, "--bucket-concurrency") {
+		t.Errorf("stderr = %q, want no warning for --bucket-concurrency 5", stderr)
+	}
+
+	if _, stderr, _ := initialize("--rate-limit", "0.5", "--concurrency", "20", "--quiet"); stderr != "" {
+		t.Errorf("stderr with --quiet = %q, want nothing", stderr)
+	}
+
+	for _, value := range []string{"0", "20000"} {
+		if _, _, err := initialize("--rate-limit", value); err == nil || !strings.Contains(err.Error(), "--rate-limit must be between 0.1 and 10000") {
+			t.Errorf("--rate-limit %s error = %v, want the allowed range", value, err)
+		}
+	}
+}
+
+func TestRegionsFromEveryPartition(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, test := range []struct {
+		region        string
+		pricingRegion string // "" when the partition has no Pricing API in USD
+	}{
+		{"us-east-1", "us-east-1"},
+		{"eu-central-2", "us-east-1"},
+		{"us-gov-west-1", ""},
+		{"us-gov-east-1", ""},
+		{"cn-north-1", ""},
+		{"cn-northwest-1", ""},
+		{"us-iso-east-1", ""},
+		{"us-isob-east-1", ""},
+	} {
+		a := NewApp("test")
+		cmd, _, err := a.rootCmd.Find([]string{"cost"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.ParseFlags([]string{"--region", test.region, "--regions", test.region, "--live-pricing", "--quiet", "--no-cache"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.initializeContainer(cmd, nil); err != nil {
+			t.Fatalf("--region %s error = %v", test.region, err)
+		}
+
+		pricingClient := a.container.GetPricingClient()
+		if test.pricingRegion == "" {
+			if pricingClient != nil {
+				t.Errorf("--region %s has a Pricing client in %s, want none", test.region, pricingClient.Options().Region)
+			}
+			continue
+		}
+		if pricingClient == nil || pricingClient.Options().Region != test.pricingRegion {
+			t.Errorf("--region %s Pricing client = %v, want one in %s", test.region, pricingClient, test.pricingRegion)
+		}
+	}
+}
+
+func TestConsoleLogLevel(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte("log-level: warn\nlog-file-level: debug\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	verboseFile := filepath.Join(t.TempDir(), "verbose.yaml")
+	if err := os.WriteFile(verboseFile, []byte("verbose: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	logFile := filepath.Join(t.TempDir(), "s3mpc.log")
+
+	tests := []struct {
+		args                []string
+		wantLevel, wantFile string
+	}{
+		{args: nil, wantLevel: "info", wantFile: "info"},
+		{args: []string{"--verbose"}, wantLevel: "debug", wantFile: "debug"},
+		{args: []string{"--quiet"}, wantLevel: "error", wantFile: "info"},
+		{args: []string{"--log-level", "warn"}, wantLevel: "warn", wantFile: "info"},
+		{args: []string{"--log-level", "warn", "--verbose"}, wantLevel: "warn", wantFile: "debug"},
+		{args: []string{"--quiet", "--log-level", "warn", "--log-file-level", "error"}, wantLevel: "warn", wantFile: "error"},
+		{args: []string{"--config", configFile}, wantLevel: "warn", wantFile: "debug"},
+		// A shorthand typed on the command line beats a level from the config file
+		{args: []string{"--config", configFile, "--verbose"}, wantLevel: "debug", wantFile: "debug"},
+		{args: []string{"--config", configFile, "--quiet"}, wantLevel: "error", wantFile: "debug"},
+		{args: []string{"--config", verboseFile, "--log-level", "error"}, wantLevel: "error", wantFile: "debug"},
+	}
+	for _, tt := range tests {
+		a := NewApp("test")
+		cmd, _, err := a.rootCmd.Find([]string{"list"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.ParseFlags(append([]string{"--no-cache", "--log-file", logFile}, tt.args...)); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.initializeContainer(cmd, nil); err != nil {
+			t.Fatalf("%v: initializeContainer() error = %v", tt.args, err)
+		}
+
+		logging := a.container.GetConfig().Logging()
+		if logging.Level != tt.wantLevel || logging.FileLevel != tt.wantFile {
+			t.Errorf("%v: console, file levels = %s, %s, want %s, %s", tt.args, logging.Level, logging.FileLevel, tt.wantLevel, tt.wantFile)
+		}
+	}
+
+	a := NewApp("test")
+	cmd, _, _ := a.rootCmd.Find([]string{"list"})
+	if err := cmd.ParseFlags([]string{"--log-level", "loud"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.initializeContainer(cmd, nil); err == nil || !strings.Contains(err.Error(), "--log-level: invalid log level: loud") {
+		t.Errorf("initializeContainer(--log-level loud) error = %v, want it rejected", err)
+	}
+}
+
+func TestSkippedBucketsAreReported(t *testing.T) {
+	skipped := []types.SkippedBucket{
+		{Bucket: "locked", Error: "failed to get bucket location for locked: AccessDenied"},
+		{Bucket: "legacy", Error: "failed to get bucket location for legacy: AccessDenied"},
+	}
+	run := func(args ...string) (*App, string) {
+		t.Helper()
+		cfg := testConfig()
+		cfg.Quiet = true
+		a, stdout, stderr, err := runCommandWithConfig(t, cfg, &accountUploadService{skipped: skipped}, args...)
+		if err != nil {
+			t.Fatalf("%v error = %v", args, err)
+		}
+		return a, stdout + stderr
+	}
+
+	// Nothing was found, but the section still shows coverage is incomplete
+	section := "Skipped buckets (2):\n  locked: failed to get bucket location for locked: AccessDenied\n  legacy:"
+	for _, args := range [][]string{{"list"}, {"cost"}} {
+		if _, out := run(args...); !strings.Contains(out, section) {
+			t.Errorf("%s output = %q, want the skipped buckets listed", args[0], out)
+		}
+	}
+	if _, out := run("list", "--json"); !strings.Contains(out, `"skipped_buckets"`) || !strings.Contains(out, `"bucket": "locked"`) {
+		t.Errorf("list --json output = %q, want skipped_buckets", out)
+	}
+	if _, out := run("size", "--json"); !strings.Contains(out, "\"inaccessible_buckets\": [\n    \"legacy\",\n    \"locked\"\n  ]") {
+		t.Errorf("size --json output = %q, want the skipped buckets as inaccessible", out)
+	}
+
+	// Every command ends with a summary on stderr
+	a, _ := run("age")
+	var stderr bytes.Buffer
+	a.rootCmd.SetErr(&stderr)
+	a.warnSkippedBuckets()
+	if want := "Warning: 2 buckets could not be scanned: locked, legacy (region lookup failed)\n"; stderr.String() != want {
+		t.Errorf("warning = %q, want %q", stderr.String(), want)
+	}
+
+	var many []types.SkippedBucket
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		many = append(many, types.SkippedBucket{Bucket: name, Account: "prod"})
+	}
+	if got, want := skippedBucketsWarning(many), "Warning: 7 buckets could not be scanned: prod/a, prod/b, prod/c, prod/d, prod/e and 2 more (region lookup failed)\n"; got != want {
+		t.Errorf("skippedBucketsWarning() = %q, want %q", got, want)
+	}
+	tagged := []types.SkippedBucket{{Bucket: "locked", Lookup: types.SkippedTagLookup}, {Bucket: "legacy"}}
+	if got, want := skippedBucketsWarning(tagged[:1]), "Warning: 1 bucket could not be scanned: locked (tag lookup failed)\n"; got != want {
+		t.Errorf("skippedBucketsWarning() = %q, want %q", got, want)
+	}
+	if got, want := skippedBucketsWarning(tagged), "Warning: 2 buckets could not be scanned: locked, legacy (region or tag lookup failed)\n"; got != want {
+		t.Errorf("skippedBucketsWarning() = %q, want %q", got, want)
+	}
+	if got := skippedBucketsWarning(nil); got != "" {
+		t.Errorf("skippedBucketsWarning(nil) = %q, want no warning", got)
+	}
+}
+
+func TestMultiAccountSkippedBucketsCarryTheirAccount(t *testing.T) {
+	accounts := map[string]*accountUploadService{
+		"prod": {skipped: []types.SkippedBucket{{Bucket: "locked", Error: "AccessDenied"}}},
+		"dev":  {},
+	}
+	stdout, _, err := runMultiAccount(t, accounts, "cost", "--profiles", "prod,dev")
+	if err != nil {
+		t.Fatalf("cost error = %v", err)
+	}
+	if !strings.Contains(stdout, "Skipped buckets (1):\n  prod/locked: AccessDenied") {
+		t.Errorf("cost output = %q, want the skipped bucket with its account", stdout)
+	}
+}
+
+// objectsUploadService lists fixed uploads and reports an object at each key in objects
+type objectsUploadService struct {
+	listedUploadService
+	objects map[string]time.Time
+}
+
+func (s *objectsUploadService) GetObjectLastModified(ctx context.Context, upload types.MultipartUpload) (time.Time, bool, error) {
+	lastModified, exists := s.objects[upload.Key]
+	return lastModified, exists, nil
+}
+
+func TestCheckObjectsFlagsSupersededUploads(t *testing.T) {
+	initiated := time.Now().Add(-72 * time.Hour)
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cfg := config.DefaultConfig()
+		cfg.AWSRegion = "us-east-1"
+		cfg.Quiet = true
+		c, err := container.NewContainer(cfg)
+		if err != nil {
+			t.Fatalf("NewContainer() error = %v", err)
+		}
+		uploadService := &objectsUploadService{
+			listedUploadService: listedUploadService{uploads: []types.MultipartUpload{
+				{Bucket: "data", Key: "retried.bin", UploadID: "u1", Initiated: initiated, StorageClass: "STANDARD"},
+				{Bucket: "data", Key: "abandoned.bin", UploadID: "u2", Initiated: initiated, StorageClass: "STANDARD"},
+			}},
+			objects: map[string]time.Time{"retried.bin": initiated.Add(time.Hour)},
+		}
+		c.SetUploadService(uploadService)
+		c.SetObjectCheckService(services.NewObjectCheckService(uploadService, 2))
+
+		a := NewApp("test")
+		a.container = c
+		cmd, rest, err := a.rootCmd.Find(args)
+		if err != nil {
+			t.Fatalf("%s not registered: %v", args[0], err)
+		}
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		cmd.SetContext(context.Background())
+		if err := cmd.ParseFlags(rest); err != nil {
+			t.Fatal(err)
+		}
+		err = cmd.RunE(cmd, cmd.Flags().Args())
+		return out.String(), err
+	}
+
+	out, err := run("list", "--check-objects", "--filter", "superseded=true", "--json")
+	if err != nil {
+		t.Fatalf("list error = %v", err)
+	}
+	if !strings.Contains(out, `"upload_id": "u1"`) || strings.Contains(out, `"upload_id": "u2"`) || !strings.Contains(out, `"superseded": true`) {
+		t.Errorf("list --filter superseded=true output = %q, want only the retried upload", out)
+	}
+
+	if _, err := run("list", "--filter", "superseded=true"); err == nil || !strings.Contains(err.Error(), "needs --check-objects") {
+		t.Errorf("superseded filter without --check-objects error = %v", err)
+	}
+	if _, err := run("delete", "--check-objects"); err == nil || !strings.Contains(err.Error(), "--dry-run") {
+		t.Errorf("delete --check-objects error = %v, want it limited to dry-runs", err)
+	}
+	if _, err := run("list", "--check-objects", "--profiles", "prod,dev"); err == nil || !strings.Contains(err.Error(), "--check-objects cannot be used with --profiles") {
+		t.Errorf("list --check-objects --profiles error = %v", err)
+	}
+}
+
+// partsUploadService lists fixed uploads whose parts counts are in parts
+type partsUploadService struct {
+	listedUploadService
+	parts     map[string]int
+	abortDate *time.Time // when a lifecycle rule aborts every upload, if set
+}
+
+func (s *partsUploadService) GetUploadDetail(ctx context.Context, upload types.MultipartUpload) (types.UploadDetail, error) {
+	return types.UploadDetail{Size: int64(s.parts[upload.Key]) << 20, PartsCount: s.parts[upload.Key], AbortDate: s.abortDate}, nil
+}
+
+// runPartsList runs list with args against uploads measured by their parts
+// counts, one MB per part, and returns its output
+func runPartsList(t *testing.T, uploads []types.MultipartUpload, parts map[string]int, args ...string) string {
+	t.Helper()
+	return runMeasuredList(t, &partsUploadService{listedUploadService: listedUploadService{uploads: uploads}, parts: parts}, args...)
+}
+
+// runMeasuredList runs list with args against uploadService and returns its output
+func runMeasuredList(t *testing.T, uploadService *partsUploadService, args ...string) string {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Quiet = true
+	_, stdout, stderr, err := runCommandWithConfig(t, cfg, uploadService, append([]string{"list"}, args...)...)
+	if err != nil {
+		t.Fatalf("list %v error = %v", args, err)
+	}
+	return stdout + stderr
+}
+
+func TestListPartsFilterMeasuresUploads(t *testing.T) {
+	initiated := time.Now().Add(-72 * time.Hour)
+	uploads := []types.MultipartUpload{
+		{Bucket: "data", Key: "single.bin", UploadID: "u1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "data", Key: "many.bin", UploadID: "u2", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+	parts := map[string]int{"single.bin": 1, "many.bin": 9000}
+
+	// The limit would otherwise take the streaming path, which does not measure
+	out := runPartsList(t, uploads, parts, "--filter", "parts>1000", "--limit", "10", "--json")
+	if !strings.Contains(out, `"upload_id": "u2"`) || strings.Contains(out, `"upload_id": "u1"`) || !strings.Contains(out, `"parts_count": 9000`) {
+		t.Errorf("list --filter parts>1000 output = %q, want only the upload with 9000 parts", out)
+	}
+}
+
+func TestListAutoAbortColumnMeasuresUploads(t *testing.T) {
+	initiated := time.Now().Add(-72 * time.Hour)
+	abortDate := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	uploadService := &partsUploadService{
+		listedUploadService: listedUploadService{uploads: []types.MultipartUpload{
+			{Bucket: "data", Key: "a.bin", UploadID: "u1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		}},
+		parts:     map[string]int{"a.bin": 2},
+		abortDate: &abortDate,
+	}
+
+	// Abort dates are only known once the parts are listed
+	out := runMeasuredList(t, uploadService, "--columns", "key,auto-abort")
+	if !strings.Contains(out, "2030-01-02") {
+		t.Errorf("list --columns key,auto-abort output = %q, want the abort date", out)
+	}
+}
+
+func TestListCostFilterPricesUploads(t *testing.T) {
+	initiated := time.Now().Add(-72 * time.Hour)
+	uploads := []types.MultipartUpload{
+		{Bucket: "data", Key: "single.bin", UploadID: "u1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "data", Key: "many.bin", UploadID: "u2", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+	parts := map[string]int{"single.bin": 1, "many.bin": 9000}
+
+	out := runPartsList(t, uploads, parts, "--filter", "cost>0.10", "--json")
+	if !strings.Contains(out, `"upload_id": "u2"`) || strings.Contains(out, `"upload_id": "u1"`) || !strings.Contains(out, `"estimated_monthly_cost": 0.2021484375`) {
+		t.Errorf("list --filter cost>0.10 output = %q, want only the 9000 MB upload with its cost", out)
+	}
+
+	out = runPartsList(t, uploads, parts, "--sort-by", "cost")
+	if !strings.Contains(out, "Est. Cost/mo") || !strings.Contains(out, "0.2021") || strings.Index(out, "many.bin") > strings.Index(out, "single.bin") {
+		t.Errorf("list --sort-by cost output = %q, want a cost column, most expensive first", out)
+	}
+}
+
+func TestListGroupBy(t *testing.T) {
+	initiated := time.Now().Add(-72 * time.Hour)
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "app/2024/a.gz", UploadID: "u1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "app/2023/b.gz", UploadID: "u2", Initiated: initiated.Add(-time.Hour), StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "media", Key: "video/c.mp4", UploadID: "u3", Initiated: initiated, StorageClass: "STANDARD", Region: "eu-west-1"},
+		{Bucket: "media", Key: "video/d.mp4", UploadID: "u4", Initiated: initiated, StorageClass: "STANDARD", Region: "eu-west-1"},
+	}
+	parts := map[string]int{"app/2024/a.gz": 1, "app/2023/b.gz": 2, "video/c.mp4": 1, "video/d.mp4": 8}
+
+	out := runPartsList(t, uploads, parts, "--group-by", "region", "--filter", "parts<8", "--json")
+	var result struct {
+		GroupBy    string              `json:"group_by"`
+		Groups     []types.UploadGroup `json:"groups"`
+		TotalCount int                 `json:"total_count"`
+		TotalSize  int64               `json:"total_size"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("list --group-by --json output is not JSON: %v\n%s", err, out)
+	}
+	if result.GroupBy != "region" || len(result.Groups) != 2 || result.TotalCount != 3 || result.TotalSize != 4<<20 {
+		t.Fatalf("grouped output = %+v, want 3 filtered uploads in 2 regions", result)
+	}
+	east := result.Groups[0]
+	if east.Name != "us-east-1" || east.Count != 2 || east.Size != 3<<20 || east.Uploads[0].UploadID != "u2" {
+		t.Errorf("us-east-1 group = %+v, want both logs uploads, oldest first", east)
+	}
+
+	out = runPartsList(t, uploads, parts, "--group-by", "prefix:1")
+	if !strings.Contains(out, "app/: 2 uploads (3.0 MB)") || !strings.Contains(out, "video/: 2 uploads (9.0 MB)") || !strings.Contains(out, "Total: 4 uploads (12.0 MB) in 2 groups") {
+		t.Errorf("list --group-by prefix:1 output = %q, want per-prefix subtotals and totals", out)
+	}
+}
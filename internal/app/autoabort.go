@@ -0,0 +1,48 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/units"
+)
+
+// anyAbortDate is the --skip-auto-expiring value when the flag has no window
+const anyAbortDate = "any"
+
+// addSkipAutoExpiringFlag registers --skip-auto-expiring, which may be given without a value
+func addSkipAutoExpiringFlag(cmd *cobra.Command) {
+	cmd.Flags().String("skip-auto-expiring", "", "Leave out uploads a lifecycle rule will abort within this long (e.g., 3d), or at all if no duration is given")
+	cmd.Flags().Lookup("skip-auto-expiring").NoOptDefVal = anyAbortDate
+}
+
+// skipAutoExpiring returns whether --skip-auto-expiring is set, its window (0
+// for any abort date) and the arguments left over. As with --save,
+// "--skip-auto-expiring 3d" leaves the duration in args; it is taken from
+// there when it parses as one.
+func (a *App) skipAutoExpiring(cmd *cobra.Command, args []string) (bool, time.Duration, []string, error) {
+	within, _ := cmd.Flags().GetString("skip-auto-expiring")
+	if within == "" {
+		return false, 0, args, nil
+	}
+
+	if within == anyAbortDate {
+		if len(args) > 0 {
+			if duration, err := units.ParseDuration(args[0]); err == nil && duration > 0 {
+				return true, duration, args[1:], nil
+			}
+		}
+		return true, 0, args, nil
+	}
+
+	duration, err := units.ParseDuration(within)
+	if err != nil {
+		return false, 0, nil, fmt.Errorf("invalid --skip-auto-expiring value: %w", err)
+	}
+	if duration <= 0 {
+		return false, 0, nil, fmt.Errorf("invalid --skip-auto-expiring value: %s must be positive", within)
+	}
+	return true, duration, args, nil
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// partialListing keeps the buckets that failed in a listing error for the
+// command to report, since the uploads of the other buckets are still
+// listed. Any other error is returned.
+func (a *App) partialListing(err error) error {
+	var failed types.BucketErrors
+	if !errors.As(err, &failed) {
+		return err
+	}
+	a.bucketErrors = append(a.bucketErrors, failed...)
+	return nil
+}
+
+// failedBuckets returns the buckets whose uploads could not be listed in this run
+func (a *App) failedBuckets() types.BucketErrors {
+	return types.NewBucketErrors(a.bucketErrors)
+}
+
+// printBucketErrors adds the buckets that could not be listed to the output
+func (a *App) printBucketErrors(cmd *cobra.Command) {
+	if failed := a.failedBuckets(); len(failed) > 0 {
+		cmd.Println()
+		cmd.Print(services.FormatBucketErrors(failed))
+	}
+}
+
+// warnBucketErrors reports the buckets that could not be listed on standard
+// error, for commands whose output is read by other programs
+func (a *App) warnBucketErrors(cmd *cobra.Command) {
+	if failed := a.failedBuckets(); len(failed) > 0 {
+		cmd.PrintErr(services.FormatBucketErrors(failed))
+	}
+}
+
+// bucketErrorsResult returns the buckets that could not be listed as the
+// run's error when --fail-on-errors is set, after the command has reported
+// the uploads of the other buckets
+func (a *App) bucketErrorsResult() error {
+	failed := a.failedBuckets()
+	if len(failed) == 0 {
+		return nil
+	}
+	if failOnErrors, _ := a.rootCmd.PersistentFlags().GetBool("fail-on-errors"); !failOnErrors {
+		return nil
+	}
+	return failed
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// partialUploadService lists its uploads but reports some buckets as failed
+type partialUploadService struct {
+	listedUploadService
+	failed types.BucketErrors
+}
+
+func (s *partialUploadService) ListUploads(ctx context.Context, opts types.ListOptions) ([]types.MultipartUpload, error) {
+	return s.uploads, s.failed
+}
+
+// runPartialList runs list with args against uploads in logs while the
+// archive bucket fails, returning stdout and the error Run would return
+func runPartialList(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	return runPartialCommand(t, "list", args...)
+}
+
+// runPartialCommand runs command with args the way runPartialList runs list
+func runPartialCommand(t *testing.T, command string, args ...string) (string, error) {
+	t.Helper()
+	var stdout bytes.Buffer
+	uploadService := &partialUploadService{
+		listedUploadService: listedUploadService{
+			UploadService: services.NewUploadServiceWithOptions(nil, nil, services.NewDryRunService(services.NewCostService()), 1,
+				services.NewConsoleProgressReporter(&stdout, true), nil, &stdout, nil, nil),
+			uploads: []types.MultipartUpload{
+				{Bucket: "logs", Key: "a.tmp", UploadID: "1", Initiated: time.Now().Add(-time.Hour), StorageClass: "STANDARD", Region: "us-east-1"},
+			},
+		},
+		failed: types.BucketErrors{{Bucket: "archive", Region: "eu-west-1", Err: errors.New("AccessDenied: Access Denied")}},
+	}
+
+	a, commandOut, commandErr, err := runCommandWithConfig(t, testConfig(), uploadService, append([]string{command}, args...)...)
+	if err != nil {
+		t.Fatalf("%s error = %v, want the other buckets listed", command, err)
+	}
+	return stdout.String() + commandOut + commandErr, a.bucketErrorsResult()
+}
+
+func TestListReportsBucketErrors(t *testing.T) {
+	output, err := runPartialList(t)
+	if !strings.Contains(output, "a.tmp") {
+		t.Errorf("list output is missing the uploads of the buckets that were listed:\n%s", output)
+	}
+	if !strings.Contains(output, "Buckets with errors (1):\n  archive (eu-west-1): AccessDenied: Access Denied") {
+		t.Errorf("list output is missing the failed bucket:\n%s", output)
+	}
+	var failed types.BucketErrors
+	if !errors.As(err, &failed) || len(failed) != 1 {
+		t.Errorf("run error = %v, want the failed bucket by default", err)
+	}
+
+	if _, err := runPartialList(t, "--fail-on-errors=false"); err != nil {
+		t.Errorf("run error with --fail-on-errors=false = %v, want nil", err)
+	}
+}
+
+func TestListJSONIncludesBucketErrors(t *testing.T) {
+	output, _ := runPartialList(t, "--json")
+	var result struct {
+		Uploads []types.MultipartUpload `json:"uploads"`
+		Errors  []struct {
+			Bucket string `json:"bucket"`
+			Region string `json:"region"`
+			Error  string `json:"error"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("list --json output is not JSON: %v\n%s", err, output)
+	}
+	if len(result.Uploads) != 1 || len(result.Errors) != 1 {
+		t.Fatalf("list --json = %d uploads, %d errors, want 1 and 1", len(result.Uploads), len(result.Errors))
+	}
+	if got := result.Errors[0]; got.Bucket != "archive" || got.Region != "eu-west-1" || got.Error != "AccessDenied: Access Denied" {
+		t.Errorf("error = %+v, want archive in eu-west-1 with its error", got)
+	}
+}
+
+func TestReportsKeepTheBucketsThatWereListed(t *testing.T) {
+	for _, command := range []string{"size", "stats"} {
+		t.Run(command, func(t *testing.T) {
+			output, err := runPartialCommand(t, command)
+			if !strings.Contains(output, "1.0 MB") && !strings.Contains(output, "1.0 MiB") {
+				t.Errorf("%s output is missing the size of the buckets that were listed:\n%s", command, output)
+			}
+			if !strings.Contains(output, "Buckets with errors (1):\n  archive (eu-west-1): AccessDenied: Access Denied") {
+				t.Errorf("%s output is missing the failed bucket:\n%s", command, output)
+			}
+			var failed types.BucketErrors
+			if !errors.As(err, &failed) || len(failed) != 1 {
+				t.Errorf("run error = %v, want the failed bucket", err)
+			}
+
+			output, _ = runPartialCommand(t, command, "--json")
+			var result struct {
+				Errors []struct {
+					Bucket string `json:"bucket"`
+				} `json:"errors"`
+			}
+			if err := json.Unmarshal([]byte(output), &result); err != nil {
+				t.Fatalf("%s --json output is not JSON: %v\n%s", command, err, output)
+			}
+			if len(result.Errors) != 1 || result.Errors[0].Bucket != "archive" {
+				t.Errorf("%s --json errors = %+v, want archive", command, result.Errors)
+			}
+		})
+	}
+}
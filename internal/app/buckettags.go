@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// addBucketTagFlags registers --bucket-tag and --exclude-bucket-tag on
+// commands that select the buckets they list
+func addBucketTagFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("bucket-tag", nil, "Only buckets with this key=value tag; repeat for more tags, where values given for the same key are alternatives")
+	cmd.Flags().StringArray("exclude-bucket-tag", nil, "Skip buckets with this key=value tag; repeatable")
+}
+
+// bucketTagFilter returns the bucket tag flags as a filter, or nil when none
+// is set or the command has no such flags
+func bucketTagFilter(cmd *cobra.Command) (*types.BucketTagFilter, error) {
+	include, _ := cmd.Flags().GetStringArray("bucket-tag")
+	exclude, _ := cmd.Flags().GetStringArray("exclude-bucket-tag")
+
+	filter := &types.BucketTagFilter{}
+	for _, value := range include {
+		tag, err := types.ParseBucketTag(value)
+		if err != nil {
+			return nil, fmt.Errorf("--bucket-tag: %w", err)
+		}
+		filter.Include = append(filter.Include, tag)
+	}
+	for _, value := range exclude {
+		tag, err := types.ParseBucketTag(value)
+		if err != nil {
+			return nil, fmt.Errorf("--exclude-bucket-tag: %w", err)
+		}
+		filter.Exclude = append(filter.Exclude, tag)
+	}
+
+	if filter.IsEmpty() {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+// validateBucketTagFlags rejects malformed bucket tags, and tag selections on
+// saved uploads that are analyzed without S3
+func validateBucketTagFlags(cmd *cobra.Command) error {
+	filter, err := bucketTagFilter(cmd)
+	if err != nil || filter == nil {
+		return err
+	}
+	if fromFile, _ := cmd.Flags().GetString("from-file"); fromFile != "" && cmd.Name() != "delete" {
+		return fmt.Errorf("--bucket-tag and --exclude-bucket-tag read tags from S3, so they cannot be used with --from-file")
+	}
+	return nil
+}
+
+// selectTaggedUploads keeps the uploads from a file whose buckets have the
+// requested tags, reading the tags from S3. Uploads in buckets whose tags
+// cannot be read are left out and their buckets reported as skipped.
+func (a *App) selectTaggedUploads(ctx context.Context, uploads []types.MultipartUpload, filter *types.BucketTagFilter) ([]types.MultipartUpload, error) {
+	if filter.IsEmpty() || len(uploads) == 0 {
+		return uploads, nil
+	}
+
+	var buckets []types.Bucket
+	seen := make(map[string]bool)
+	for _, upload := range uploads {
+		if !seen[upload.Bucket] {
+			buckets = append(buckets, types.Bucket{Name: upload.Bucket, Region: upload.Region})
+			seen[upload.Bucket] = true
+		}
+	}
+
+	selected, skipped, err := a.container.GetBucketService().SelectBucketsByTags(ctx, buckets, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bucket tags: %w", err)
+	}
+	a.offlineSkipped = append(a.offlineSkipped, skipped...)
+
+	tags := make(map[string]map[string]string, len(selected))
+	for _, bucket := range selected {
+		tags[bucket.Name] = bucket.Tags
+	}
+	var kept []types.MultipartUpload
+	for _, upload := range uploads {
+		if bucketTags, ok := tags[upload.Bucket]; ok {
+			upload.BucketTags = bucketTags
+			kept = append(kept, upload)
+		}
+	}
+	return kept, nil
+}
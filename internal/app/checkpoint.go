@@ -0,0 +1,110 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// addCheckpointFlag registers --checkpoint on delete
+func addCheckpointFlag(cmd *cobra.Command) {
+	cmd.Flags().String("checkpoint", "", "Save the uploads not deleted yet to a file as the run goes, and resume from it instead of listing again when it exists; it is removed once the run completes")
+}
+
+// deletionCheckpoint returns the writer of --checkpoint for a run with opts,
+// or nil when the flag is not set. The account settings, --regions and the
+// file of --from-file or --retry-from, by content, go into its hash too.
+func (a *App) deletionCheckpoint(cmd *cobra.Command, opts types.DeleteOptions) (*services.CheckpointWriter, error) {
+	filename, _ := cmd.Flags().GetString("checkpoint")
+	if filename == "" {
+		return nil, nil
+	}
+
+	cfg := a.container.GetConfig()
+	sourceFile, _ := cmd.Flags().GetString("from-file")
+	retryFrom, _ := cmd.Flags().GetString("retry-from")
+	checkpoint := types.DeletionCheckpoint{
+		Command:    commandLine(cmd),
+		Version:    a.getVersion(),
+		Profile:    cfg.AWSProfile,
+		Region:     cfg.AWSRegion,
+		RoleARN:    cfg.RoleARN,
+		ExternalID: cfg.ExternalID,
+		Regions:    cfg.Regions,
+		SourceFile: sourceFile,
+		RetryOf:    retryFrom,
+	}
+	for _, source := range []string{sourceFile, retryFrom} {
+		if source == "" {
+			continue
+		}
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		sum := sha256.Sum256(data)
+		checkpoint.SourceHash = hex.EncodeToString(sum[:])
+	}
+	return services.NewCheckpointWriter(filename, checkpoint, opts), nil
+}
+
+// loadCheckpointUploads loads the uploads an earlier run left in the
+// checkpoint. They are not checked against S3 first: deleting one S3 no
+// longer has counts it as already cleaned. It returns false when there is no
+// checkpoint to resume.
+func (a *App) loadCheckpointUploads(cmd *cobra.Command, checkpoint *services.CheckpointWriter) ([]types.MultipartUpload, bool, error) {
+	if checkpoint == nil {
+		return nil, false, nil
+	}
+	saved, err := services.LoadCheckpoint(checkpoint.Filename())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to load --checkpoint: %w", err)
+	}
+	if err := checkpoint.Resumes(saved); err != nil {
+		return nil, true, fmt.Errorf("cannot resume --checkpoint: %w", err)
+	}
+
+	remaining := make([]types.MultipartUpload, len(saved.Remaining))
+	for i, upload := range saved.Remaining {
+		remaining[i] = upload.Upload()
+	}
+
+	if quiet, _ := cmd.Flags().GetBool("quiet"); !quiet {
+		cmd.Printf("Resuming from checkpoint %s of %s: %d of %d uploads left\n\n",
+			checkpoint.Filename(), types.FormatTimestamp(saved.UpdatedAt), len(remaining), saved.Total)
+	}
+	return remaining, true, nil
+}
+
+// resumedDeleteOptions returns the options that delete the uploads of a
+// checkpoint: they were selected by the run that wrote it, and their sizes
+// are not kept, so they are deleted without filtering them again
+func resumedDeleteOptions(opts types.DeleteOptions) types.DeleteOptions {
+	return types.DeleteOptions{
+		Force:                   opts.Force,
+		Quiet:                   opts.Quiet,
+		IncludeServiceInitiated: true,
+	}
+}
+
+// closeCheckpoint saves the checkpoint of a run cut short, or removes that of
+// a run that completed, telling the user how to resume
+func (a *App) closeCheckpoint(cmd *cobra.Command, checkpoint *services.CheckpointWriter) error {
+	if err := checkpoint.Close(); err != nil {
+		return err
+	}
+	if quiet, _ := cmd.Flags().GetBool("quiet"); !quiet && checkpoint.Remaining() > 0 {
+		cmd.PrintErrf("Saved checkpoint to %s; run the same command again to delete the %d uploads left\n", checkpoint.Filename(), checkpoint.Remaining())
+	}
+	return nil
+}
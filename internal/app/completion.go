@@ -0,0 +1,273 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/internal/config"
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+const (
+	// completionTimeout bounds the AWS calls made to complete a flag value, so
+	// a slow network leaves the shell without suggestions instead of hung
+	completionTimeout = 2 * time.Second
+	// bucketCompletionTTL is how long completed bucket names are reused
+	bucketCompletionTTL = 5 * time.Minute
+)
+
+// completionFunc completes the value of a flag
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+func (a *App) addCompletionCommand() {
+	cmd := &cobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Generate a shell completion script",
+		Long: `Generate the completion script for a shell.
+
+Bucket names, AWS profiles and the values of --sort-by, --format and
+--storage-class are completed too. Bucket names are listed with the
+credentials on the command line and cached for a few minutes in
+~/.s3mpc/completion.json; a listing that takes longer than two seconds
+offers no suggestions.
+
+  bash:        source <(s3mpc completion bash)
+  zsh:         s3mpc completion zsh > "${fpath[1]}/_s3mpc"
+  fish:        s3mpc completion fish > ~/.config/fish/completions/s3mpc.fish
+  powershell:  s3mpc completion powershell | Out-String | Invoke-Expression`,
+		DisableFlagsInUseLine: true,
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		// Scripts are generated offline and need no AWS clients
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+		RunE:              a.runCompletionCommand,
+	}
+	a.rootCmd.AddCommand(cmd)
+}
+
+func (a *App) runCompletionCommand(cmd *cobra.Command, args []string) error {
+	root := cmd.Root()
+	out := cmd.OutOrStdout()
+	switch args[0] {
+	case "bash":
+		return root.GenBashCompletionV2(out, true)
+	case "zsh":
+		return root.GenZshCompletion(out)
+	case "fish":
+		return root.GenFishCompletion(out, true)
+	default:
+		return root.GenPowerShellCompletionWithDesc(out)
+	}
+}
+
+// isCompletionRequest reports whether cmd is the hidden command shells run to
+// ask for completions
+func isCompletionRequest(cmd *cobra.Command) bool {
+	return cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd
+}
+
+// registerCompletions adds value completion to the flags of every command
+func (a *App) registerCompletions() {
+	_ = a.rootCmd.RegisterFlagCompletionFunc("profile", completeProfiles)
+
+	completions := map[string]completionFunc{
+		"bucket":        a.completeBuckets,
+		"profiles":      completeProfiles,
+		"sort-by":       completeSortFields,
+		"format":        completeExportFormats,
+		"output":        completeOutputFormats,
+		"storage-class": completeStorageClasses,
+	}
+	var register func(cmd *cobra.Command)
+	register = func(cmd *cobra.Command) {
+		for name, complete := range completions {
+			// cost --storage-class is a switch, not a list of classes
+			if flag := cmd.Flags().Lookup(name); flag != nil && flag.Value.Type() != "bool" {
+				_ = cmd.RegisterFlagCompletionFunc(name, complete)
+			}
+		}
+		for _, child := range cmd.Commands() {
+			register(child)
+		}
+	}
+	for _, cmd := range a.rootCmd.Commands() {
+		register(cmd)
+	}
+}
+
+// completeListItems completes the last item of a comma-separated list
+func completeListItems(toComplete string, items []string) []string {
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix = toComplete[:i+1]
+	}
+	completions := make([]string, len(items))
+	for i, item := range items {
+		completions[i] = prefix + item
+	}
+	return completions
+}
+
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeListItems(toComplete, config.AWSProfiles()), cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeSortFields(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if !strings.Contains(toComplete, ":") {
+		return services.SortFields, cobra.ShellCompDirectiveNoFileComp
+	}
+	field := strings.SplitN(toComplete, ":", 2)[0]
+	return []string{field + ":asc", field + ":desc"}, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeOutputFormats completes the formats of --output; export --output
+// is a file name, so it is completed as one
+func completeOutputFormats(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if formats := cmd.Flags().Lookup("output").Annotations[outputFormatsAnnotation]; formats != nil {
+		return formats, cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveDefault
+}
+
+func completeExportFormats(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return []string{
+		"csv\tComma-separated values",
+		"json\tJSON array of uploads",
+		"xlsx\tWorkbook with per-bucket and per-region summaries",
+	}, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeStorageClasses(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeListItems(toComplete, types.StorageClasses()), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeBuckets completes bucket names for the account selected by the
+// command line. Any failure, including a timeout, offers no suggestions.
+func (a *App) completeBuckets(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if err := a.applyConfigSources(cmd); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	profile, _ := cmd.Flags().GetString("profile")
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	roleARN, _ := cmd.Flags().GetString("role-arn")
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+	defer cancel()
+
+	names, err := cachedBucketNames(ctx, completionCachePath(), profile+"|"+roleARN, bucketCompletionTTL, func(ctx context.Context) ([]string, error) {
+		if a.container == nil {
+			// Completion must not log or prompt for an MFA code on the terminal
+			for _, flag := range []string{"quiet", "no-input"} {
+				if !cmd.Flags().Changed(flag) {
+					_ = cmd.Flags().Set(flag, "true")
+				}
+			}
+			if err := a.initializeContainer(cmd, args); err != nil {
+				return nil, err
+			}
+		}
+		buckets, _, err := a.container.GetBucketService().ListBuckets(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, len(buckets))
+		for i, bucket := range buckets {
+			names[i] = bucket.Name
+		}
+		return names, nil
+	})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completionCache is the file bucket names are cached in between completions
+type completionCache struct {
+	Buckets map[string]completionCacheEntry `json:"buckets"`
+}
+
+type completionCacheEntry struct {
+	Names     []string  `json:"names"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// completionCachePath returns ~/.s3mpc/completion.json
+func completionCachePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".s3mpc", "completion.json")
+	}
+	return filepath.Join(home, ".s3mpc", "completion.json")
+}
+
+// cachedBucketNames returns the bucket names cached for key when younger than
+// ttl, and otherwise lists and caches them. list runs in its own goroutine so
+// a call that ignores ctx still returns once ctx is done.
+func cachedBucketNames(ctx context.Context, path, key string, ttl time.Duration, list func(ctx context.Context) ([]string, error)) ([]string, error) {
+	var cache completionCache
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &cache)
+	}
+	if entry, exists := cache.Buckets[key]; exists && time.Since(entry.FetchedAt) < ttl {
+		return entry.Names, nil
+	}
+
+	type listResult struct {
+		names []string
+		err   error
+	}
+	results := make(chan listResult, 1)
+	go func() {
+		names, err := list(ctx)
+		results <- listResult{names, err}
+	}()
+
+	var result listResult
+	select {
+	case result = <-results:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	if cache.Buckets == nil {
+		cache.Buckets = make(map[string]completionCacheEntry)
+	}
+	cache.Buckets[key] = completionCacheEntry{Names: result.names, FetchedAt: time.Now()}
+	// A cache that cannot be written only makes the next completion slower
+	_ = saveCompletionCache(path, cache)
+	return result.names, nil
+}
+
+// saveCompletionCache writes the cache file atomically
+func saveCompletionCache(path string, cache completionCache) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create completion cache directory: %w", err)
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("failed to encode completion cache: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write completion cache: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
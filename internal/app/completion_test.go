@@ -0,0 +1,111 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runCompletion returns the suggestions for the last of args
+func runCompletion(t *testing.T, args ...string) []string {
+	t.Helper()
+	var stdout bytes.Buffer
+	a := NewApp("test")
+	a.rootCmd.SetOut(&stdout)
+	if err := a.Run(context.Background(), append([]string{"__completeNoDesc"}, args...)); err != nil {
+		t.Fatalf("completion of %v error = %v", args, err)
+	}
+	// The last line is the directive
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	return lines[:len(lines)-1]
+}
+
+func TestFlagValueCompletion(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"delete", "--storage-class", "standard,"}, "standard,DEEP_ARCHIVE"},
+		{[]string{"list", "--sort-by", ""}, "storage-class"},
+		{[]string{"list", "--sort-by", "size:"}, "size:desc"},
+		{[]string{"export", "--format", ""}, "xlsx"},
+	}
+	for _, tt := range tests {
+		if got := runCompletion(t, tt.args...); !containsString(got, tt.want) {
+			t.Errorf("completion of %v = %v, want %s", tt.args, got, tt.want)
+		}
+	}
+
+	// cost --storage-class is a switch and takes no value
+	if got := runCompletion(t, "cost", "--storage-class", ""); containsString(got, "GLACIER") {
+		t.Errorf("cost --storage-class completed storage classes: %v", got)
+	}
+}
+
+func TestCompletionCommandGeneratesScripts(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		var stdout bytes.Buffer
+		a := NewApp("test")
+		a.rootCmd.SetOut(&stdout)
+		if err := a.Run(context.Background(), []string{"completion", shell}); err != nil {
+			t.Fatalf("completion %s error = %v", shell, err)
+		}
+		if !strings.Contains(stdout.String(), "s3mpc") {
+			t.Errorf("completion %s printed no script", shell)
+		}
+	}
+}
+
+func TestCachedBucketNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "completion.json")
+	calls := 0
+	list := func(ctx context.Context) ([]string, error) {
+		calls++
+		return []string{"logs", "media"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		names, err := cachedBucketNames(context.Background(), path, "prod|", time.Minute, list)
+		if err != nil || strings.Join(names, ",") != "logs,media" {
+			t.Fatalf("cachedBucketNames() = %v, %v", names, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("listed buckets %d times, want the second completion served from the cache", calls)
+	}
+
+	// Another profile has its own entry
+	if _, err := cachedBucketNames(context.Background(), path, "staging|", time.Minute, list); err != nil || calls != 2 {
+		t.Errorf("cachedBucketNames() for another profile listed %d times, err %v", calls, err)
+	}
+}
+
+func TestCachedBucketNamesTimesOut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "completion.json")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// A call that ignores the context does not hold the completion up
+	release := make(chan struct{})
+	defer close(release)
+	names, err := cachedBucketNames(ctx, path, "prod|", time.Minute, func(ctx context.Context) ([]string, error) {
+		<-release
+		return []string{"logs"}, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || names != nil {
+		t.Errorf("cachedBucketNames() = %v, %v, want no names after the timeout", names, err)
+	}
+}
+
+func containsString(values []string, want string) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/Garvitkul/s3mpc/internal/config"
+	"github.com/Garvitkul/s3mpc/internal/term"
+	"github.com/Garvitkul/s3mpc/pkg/filter"
+	"github.com/Garvitkul/s3mpc/pkg/services"
+)
+
+// applyConfigSources loads the config file and S3MPC_* environment variables
+// and sets every setting not given as a flag from them, so the rest of the
+// app reads flags as usual. Precedence is flags > environment > file > defaults.
+func (a *App) applyConfigSources(cmd *cobra.Command) error {
+	explicit, _ := cmd.Flags().GetString("config")
+	path, err := config.FindConfigFile(explicit)
+	if err != nil {
+		return err
+	}
+
+	var file *config.File
+	if path != "" {
+		file, err = config.LoadFile(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	flags := cmd.Flags()
+	resolved, err := config.Resolve(file, lookupEnv, func(name string) (string, bool) {
+		flag := flags.Lookup(name)
+		if flag == nil || !flag.Changed {
+			return "", false
+		}
+		return flagString(flags, flag), true
+	}, func(name string) string {
+		if flag := flags.Lookup(name); flag != nil {
+			return flagString(flags, flag)
+		}
+		return ""
+	})
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	for _, setting := range resolved {
+		// The default filter is only parsed by the commands that use it, so
+		// it is checked here, where its origin is known
+		if setting.Setting.Key == "default-filter" && setting.Value != "" {
+			if _, err := filter.NewEngine().ParseFilter(setting.Value); err != nil {
+				return fmt.Errorf("invalid configuration: %s: %s: %w", setting.Origin, setting.Setting.Key, err)
+			}
+		}
+		if setting.Setting.Flag == "" || (setting.Source != config.SourceEnv && setting.Source != config.SourceFile) {
+			continue
+		}
+		if err := flags.Set(setting.Setting.Flag, setting.Value); err != nil {
+			return fmt.Errorf("invalid configuration: %s: %s: %w", setting.Origin, setting.Setting.Key, err)
+		}
+	}
+
+	a.configPath = path
+	a.settings = resolved
+	return nil
+}
+
+// settingValue returns the effective value of a setting resolved by applyConfigSources
+func (a *App) settingValue(key string) string {
+	for _, setting := range a.settings {
+		if setting.Setting.Key == key {
+			return setting.Value
+		}
+	}
+	if setting, ok := config.LookupSetting(key); ok {
+		return setting.Default
+	}
+	return ""
+}
+
+// rateLimit returns the effective requests-per-second limit
+func (a *App) rateLimit() float64 {
+	limit, err := strconv.ParseFloat(a.settingValue("rate-limit"), 64)
+	if err != nil {
+		return config.DefaultConfig().RateLimitRPS
+	}
+	return limit
+}
+
+// bucketListTTL returns how long the bucket list is reused within one run
+func (a *App) bucketListTTL() time.Duration {
+	ttl, err := time.ParseDuration(a.settingValue("bucket-list-ttl"))
+	if err != nil {
+		return services.DefaultBucketListTTL
+	}
+	return ttl
+}
+
+// excludedBuckets returns the buckets the exclude-buckets setting leaves out of scans
+func (a *App) excludedBuckets() []string {
+	var buckets []string
+	for _, bucket := range strings.Split(a.settingValue("exclude-buckets"), ",") {
+		if bucket = strings.TrimSpace(bucket); bucket != "" {
+			buckets = append(buckets, bucket)
+		}
+	}
+	return buckets
+}
+
+// filterExpression returns --filter, or the default-filter setting when the
+// flag is not given; --filter "" turns the default off
+func (a *App) filterExpression(cmd *cobra.Command) string {
+	if flag := cmd.Flags().Lookup("filter"); flag != nil && flag.Changed {
+		return flag.Value.String()
+	}
+	return a.settingValue("default-filter")
+}
+
+// lookupEnv returns an environment variable, treating empty values as unset
+func lookupEnv(name string) (string, bool) {
+	value, ok := os.LookupEnv(name)
+	if value == "" {
+		return "", false
+	}
+	return value, ok
+}
+
+// flagString returns a flag value in the form accepted by Set
+func flagString(flags *pflag.FlagSet, flag *pflag.Flag) string {
+	if flag.Value.Type() == "stringSlice" {
+		values, _ := flags.GetStringSlice(flag.Name)
+		return strings.Join(values, ",")
+	}
+	return flag.Value.String()
+}
+
+func (a *App) addConfigCommand() {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect configuration from flags, environment and config files",
+		// Configuration commands need no AWS clients
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return a.applyConfigSources(cmd)
+		},
+	}
+
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration and where each value came from",
+		RunE:  a.runConfigShowCommand,
+	}
+	showCmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.AddCommand(showCmd)
+
+	a.rootCmd.AddCommand(cmd)
+}
+
+func (a *App) runConfigShowCommand(cmd *cobra.Command, args []string) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	formatter := services.NewOutputFormatterWithTerminal(term.New(os.Stdout))
+
+	if jsonOutput {
+		type settingJSON struct {
+			Key    string `json:"key"`
+			Value  string `json:"value"`
+			Source string `json:"source"`
+			Origin string `json:"origin,omitempty"`
+		}
+		settings := make([]settingJSON, 0, len(a.settings))
+		for _, setting := range a.settings {
+			settings = append(settings, settingJSON{setting.Setting.Key, setting.Value, string(setting.Source), setting.Origin})
+		}
+		jsonStr, err := formatter.FormatJSON(map[string]interface{}{
+			"config_file": a.configPath,
+			"settings":    settings,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to format JSON output: %w", err)
+		}
+		cmd.Println(jsonStr)
+		return nil
+	}
+
+	if a.configPath != "" {
+		cmd.Printf("Config file: %s\n\n", a.configPath)
+	} else {
+		cmd.Printf("Config file: none (looked for ./%s and %s)\n\n", config.ConfigFileName, config.DefaultConfigFilePath())
+	}
+
+	headers := []string{"Setting", "Value", "Source"}
+	rows := make([][]string, 0, len(a.settings))
+	for _, setting := range a.settings {
+		source := string(setting.Source)
+		if setting.Source == config.SourceEnv || setting.Source == config.SourceFile {
+			source = fmt.Sprintf("%s (%s)", setting.Source, setting.Origin)
+		}
+		rows = append(rows, []string{setting.Setting.Key, setting.Value, source})
+	}
+	cmd.Print(formatter.FormatTable(headers, rows))
+	return nil
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Garvitkul/s3mpc/internal/config"
+)
+
+func TestApplyConfigSourcesSetsUnchangedFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("profile: file-profile\nregion: eu-west-1\nconcurrency: 30\nrate-limit: 4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("S3MPC_REGION", "us-west-2")
+
+	a := NewApp("test")
+	cmd, _, err := a.rootCmd.Find([]string{"config", "show"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.ParseFlags([]string{"--config", path, "--concurrency", "12"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.applyConfigSources(cmd); err != nil {
+		t.Fatalf("applyConfigSources() error = %v", err)
+	}
+
+	profile, _ := cmd.Flags().GetString("profile")
+	region, _ := cmd.Flags().GetString("region")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	if profile != "file-profile" || region != "us-west-2" || concurrency != 12 {
+		t.Errorf("profile, region, concurrency = %q, %q, %d, want file-profile, us-west-2 (env), 12 (flag)", profile, region, concurrency)
+	}
+	if a.rateLimit() != 4 {
+		t.Errorf("rateLimit() = %v, want 4 from the config file", a.rateLimit())
+	}
+
+	sources := make(map[string]config.Source)
+	for _, setting := range a.settings {
+		sources[setting.Setting.Key] = setting.Source
+	}
+	if sources["profile"] != config.SourceFile || sources["region"] != config.SourceEnv || sources["concurrency"] != config.SourceFlag || sources["verbose"] != config.SourceDefault {
+		t.Errorf("sources = %v", sources)
+	}
+}
+
+func TestConfigFileDefaults(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("exclude-buckets:\n  - archive\n  - backups\ndefault-filter: age>7d\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewApp("test")
+	cmd, _, err := a.rootCmd.Find([]string{"list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.ParseFlags([]string{"--config", path}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.applyConfigSources(cmd); err != nil {
+		t.Fatalf("applyConfigSources() error = %v", err)
+	}
+	if got := a.excludedBuckets(); strings.Join(got, ",") != "archive,backups" {
+		t.Errorf("excludedBuckets() = %v, want archive and backups", got)
+	}
+	if got := a.filterExpression(cmd); got != "age>7d" {
+		t.Errorf("filterExpression() = %q, want the default filter", got)
+	}
+	// --filter replaces the default, and --filter "" turns it off
+	for _, value := range []string{"size>1GB", ""} {
+		if err := cmd.Flags().Set("filter", value); err != nil {
+			t.Fatal(err)
+		}
+		if got := a.filterExpression(cmd); got != value {
+			t.Errorf("filterExpression() with --filter %q = %q", value, got)
+		}
+	}
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("default-filter: age>>7d\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a = NewApp("test")
+	cmd, _, _ = a.rootCmd.Find([]string{"list"})
+	if err := cmd.ParseFlags([]string{"--config", invalid}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.applyConfigSources(cmd); err == nil || !strings.Contains(err.Error(), invalid+": default-filter") {
+		t.Errorf("applyConfigSources() error = %v, want the file and key of the bad filter", err)
+	}
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func (a *App) addDoctorCommand() {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check credentials, permissions and settings before a large run",
+		Long: `Check that a scan or cleanup will work before starting it.
+
+doctor resolves the credentials with STS, lists buckets, and probes one bucket
+for the permissions s3mpc needs: listing uploads, listing their parts and
+aborting them. The probes name an upload that does not exist, so nothing is
+read or changed. With --live-pricing the Pricing API is queried too, and the
+rate limit and concurrency settings are checked for values that slow a run
+down or get it throttled.
+
+doctor exits with an error when a required check fails.`,
+		RunE: a.runDoctorCommand,
+	}
+	cmd.Flags().StringP("bucket", "b", "", "Probe this bucket instead of the first one listed")
+	cmd.Flags().Bool("live-pricing", false, "Also check that the AWS Pricing API is reachable")
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+	a.rootCmd.AddCommand(cmd)
+}
+
+func (a *App) runDoctorCommand(cmd *cobra.Command, args []string) error {
+	bucketName, _ := cmd.Flags().GetString("bucket")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	checks := a.container.GetDoctorService().RunChecks(cmd.Context(), types.DoctorOptions{Bucket: bucketName})
+	failed := types.DoctorFailures(checks)
+
+	if jsonOutput {
+		jsonStr, err := a.container.GetOutputFormatter().FormatJSON(map[string]interface{}{
+			"checks": checks,
+			"ok":     failed == 0,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to format JSON output: %w", err)
+		}
+		cmd.Println(jsonStr)
+	} else {
+		cmd.Print(services.FormatDoctorChecks(checks))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d required checks failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/internal/prompt"
+	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// Exit codes returned by s3mpc
+const (
+	ExitOK                   = 0
+	ExitError                = 1 // usage, configuration and other errors
+	ExitAWSError             = 2 // an AWS call or the credentials failed
+	ExitPartialFailure       = 3 // some buckets could not be listed, some uploads could not be deleted, or --timeout cut the run short
+	ExitNothingMatched       = 4 // no upload matched and --fail-if-empty is set
+	ExitConfirmationRequired = 5 // a prompt was needed but --no-input is in effect
+)
+
+// ErrNothingMatched is returned when no upload matched and --fail-if-empty is set
+var ErrNothingMatched = errors.New("no incomplete multipart uploads matched (--fail-if-empty)")
+
+// ExitCode returns the process exit code for an error returned by Run
+func ExitCode(err error) int {
+	var failedBuckets types.BucketErrors
+	var failedDeletions *services.DeletionFailuresError
+	var assumeErr *awsclient.AssumeRoleError
+	var credentialsErr *awsclient.CredentialsError
+	var runTimeout *RunTimeoutError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, prompt.ErrConfirmationRequired):
+		return ExitConfirmationRequired
+	case errors.Is(err, ErrNothingMatched):
+		return ExitNothingMatched
+	case errors.As(err, &failedBuckets):
+		return ExitPartialFailure
+	case errors.As(err, &failedDeletions) && failedDeletions.Partial():
+		return ExitPartialFailure
+	case errors.As(err, &assumeErr) || errors.As(err, &credentialsErr) || awsclient.ErrorCode(err) != "":
+		return ExitAWSError
+	case errors.As(err, &runTimeout):
+		return ExitPartialFailure
+	default:
+		return ExitError
+	}
+}
+
+// addFailIfEmptyFlag registers --fail-if-empty on commands that select uploads
+func addFailIfEmptyFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("fail-if-empty", false, "Exit with code 4 when no upload matches, for pipelines that treat that as a problem")
+}
+
+// failIfEmpty returns ErrNothingMatched when matched is zero and
+// --fail-if-empty is set
+func failIfEmpty(cmd *cobra.Command, matched int) error {
+	if failIfEmpty, _ := cmd.Flags().GetBool("fail-if-empty"); failIfEmpty && matched == 0 {
+		return ErrNothingMatched
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+
+	"github.com/Garvitkul/s3mpc/internal/config"
+	"github.com/Garvitkul/s3mpc/internal/container"
+	"github.com/Garvitkul/s3mpc/internal/prompt"
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// fakeLifecycleService plans one pending change and records whether it was applied
+type fakeLifecycleService struct {
+	applied bool
+}
+
+func (f *fakeLifecycleService) CheckBuckets(ctx context.Context, opts types.ListOptions) ([]types.LifecycleStatus, error) {
+	return nil, nil
+}
+
+func (f *fakeLifecycleService) PlanAbortRule(ctx context.Context, opts types.LifecycleApplyOptions) ([]types.LifecycleChange, error) {
+	return []types.LifecycleChange{{Bucket: opts.BucketName, Region: "us-east-1"}}, nil
+}
+
+func (f *fakeLifecycleService) ApplyAbortRule(ctx context.Context, changes []types.LifecycleChange) (*types.LifecycleApplyResult, error) {
+	f.applied = true
+	changes[0].Applied = true
+	return &types.LifecycleApplyResult{Changes: changes, Applied: 1}, nil
+}
+
+func TestExitCode(t *testing.T) {
+	if code := ExitCode(nil); code != ExitOK {
+		t.Errorf("ExitCode(nil) = %d, want %d", code, ExitOK)
+	}
+	if code := ExitCode(errors.New("boom")); code != ExitError {
+		t.Errorf("ExitCode(error) = %d, want %d", code, ExitError)
+	}
+	wrapped := fmt.Errorf("failed to get confirmation: %w", prompt.ErrConfirmationRequired)
+	if code := ExitCode(wrapped); code != ExitConfirmationRequired {
+		t.Errorf("ExitCode(confirmation required) = %d, want %d", code, ExitConfirmationRequired)
+	}
+}
+
+func TestLifecycleApplyUnderNoInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		noInput bool
+		input   string
+		force   bool
+		applied bool
+		code    int
+	}{
+		{name: "no-input requires confirmation", noInput: true, code: ExitConfirmationRequired},
+		{name: "no-input with force", noInput: true, force: true, applied: true},
+		{name: "interactive yes", input: "y\n", applied: true},
+		{name: "interactive no", input: "n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.AWSRegion = "us-east-1"
+			cfg.NoInput = tt.noInput
+			c, err := container.NewContainer(cfg)
+			if err != nil {
+				t.Fatalf("NewContainer() error = %v", err)
+			}
+			lifecycle := &fakeLifecycleService{}
+			c.SetLifecycleService(lifecycle)
+
+			a := NewApp("test")
+			a.container = c
+			cmd, _, err := a.rootCmd.Find([]string{"lifecycle", "apply"})
+			if err != nil {
+				t.Fatalf("lifecycle apply not registered: %v", err)
+			}
+			var output bytes.Buffer
+			cmd.SetIn(strings.NewReader(tt.input))
+			cmd.SetOut(&output)
+			cmd.SetErr(&output)
+			cmd.Flags().Set("bucket", "logs")
+			if tt.force {
+				cmd.Flags().Set("force", "true")
+			}
+
+			err = a.runLifecycleApplyCommand(cmd, nil)
+			if code := ExitCode(err); code != tt.code {
+				t.Errorf("exit code = %d (%v), want %d", code, err, tt.code)
+			}
+			if lifecycle.applied != tt.applied {
+				t.Errorf("applied = %v, want %v", lifecycle.applied, tt.applied)
+			}
+		})
+	}
+}
+
+func TestExplicitNoInputOverridesTerminalDetection(t *testing.T) {
+	for _, value := range []bool{true, false} {
+		a := NewApp("test")
+		if err := a.rootCmd.ParseFlags([]string{fmt.Sprintf("--no-input=%v", value)}); err != nil {
+			t.Fatalf("ParseFlags() error = %v", err)
+		}
+		if got := resolveNoInput(a.rootCmd); got != value {
+			t.Errorf("resolveNoInput(--no-input=%v) = %v", value, got)
+		}
+	}
+
+	// Without the flag, prompts follow whether stdin is a terminal
+	a := NewApp("test")
+	if got := resolveNoInput(a.rootCmd); got != !prompt.StdinIsTerminal() {
+		t.Errorf("resolveNoInput() = %v, want %v", got, !prompt.StdinIsTerminal())
+	}
+}
+
+// scriptedUploadService lists its uploads, or fails with listErr, and fails
+// deletions with deleteErr when set
+type scriptedUploadService struct {
+	listedUploadService
+	listErr   error
+	deleteErr error
+}
+
+func (s *scriptedUploadService) ListUploads(ctx context.Context, opts types.ListOptions) ([]types.MultipartUpload, error) {
+	if s.listErr != nil {
+		var failed types.BucketErrors
+		if errors.As(s.listErr, &failed) {
+			return s.uploads, s.listErr
+		}
+		return nil, s.listErr
+	}
+	return s.uploads, nil
+}
+
+func (s *scriptedUploadService) DeleteUploads(ctx context.Context, uploads []types.MultipartUpload, opts types.DeleteOptions) error {
+	if s.deleteErr != nil {
+		return s.deleteErr
+	}
+	return s.listedUploadService.DeleteUploads(ctx, uploads, opts)
+}
+
+// runForExitCode runs a command in-process against uploadService and returns
+// the exit code the process would end with
+func runForExitCode(t *testing.T, uploadService *scriptedUploadService, args ...string) int {
+	t.Helper()
+	var output bytes.Buffer
+	uploadService.UploadService = services.NewUploadServiceWithOptions(nil, nil, services.NewDryRunService(services.NewCostService()), 1,
+		services.NewConsoleProgressReporter(&output, true), nil, &output, nil, nil)
+
+	cfg := testConfig()
+	cfg.NoInput = true
+	a, _, _, err := runCommandWithConfig(t, cfg, uploadService, args...)
+	if err == nil {
+		err = a.bucketErrorsResult()
+	}
+	return ExitCode(err)
+}
+
+func TestCommandExitCodes(t *testing.T) {
+	recent := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.tmp", UploadID: "1", Initiated: time.Now().Add(-time.Hour), StorageClass: "STANDARD", Region: "us-east-1", Size: 1 << 20},
+	}
+	denied := &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"}
+
+	tests := []struct {
+		name    string
+		service scriptedUploadService
+		args    []string
+		want    int
+	}{
+		{name: "list found uploads", service: scriptedUploadService{listedUploadService: listedUploadService{uploads: recent}}, args: []string{"list", "--fail-if-empty"}, want: ExitOK},
+		{name: "list found nothing", args: []string{"list"}, want: ExitOK},
+		{name: "list found nothing with --fail-if-empty", args: []string{"list", "--fail-if-empty"}, want: ExitNothingMatched},
+		{name: "list --json found nothing with --fail-if-empty", args: []string{"list", "--json", "--fail-if-empty"}, want: ExitNothingMatched},
+		{name: "size found nothing with --fail-if-empty", args: []string{"size", "--fail-if-empty"}, want: ExitNothingMatched},
+		{name: "delete found nothing with --fail-if-empty", args: []string{"delete", "--force", "--fail-if-empty"}, want: ExitNothingMatched},
+		{name: "delete filters match nothing", service: scriptedUploadService{listedUploadService: listedUploadService{uploads: recent}}, args: []string{"delete", "--force", "--older-than", "7d"}, want: ExitOK},
+		{name: "delete filters match nothing with --fail-if-empty", service: scriptedUploadService{listedUploadService: listedUploadService{uploads: recent}}, args: []string{"delete", "--force", "--older-than", "7d", "--fail-if-empty"}, want: ExitNothingMatched},
+		{name: "delete failed for some uploads", service: scriptedUploadService{listedUploadService: listedUploadService{uploads: recent}, deleteErr: &services.DeletionFailuresError{Failed: 10, Total: 1000, Err: denied}}, args: []string{"delete", "--force"}, want: ExitPartialFailure},
+		{name: "delete failed for every upload", service: scriptedUploadService{listedUploadService: listedUploadService{uploads: recent}, deleteErr: &services.DeletionFailuresError{Failed: 1000, Total: 1000, Err: denied}}, args: []string{"delete", "--force"}, want: ExitAWSError},
+		{name: "list denied", service: scriptedUploadService{listErr: denied}, args: []string{"list"}, want: ExitAWSError},
+		{name: "list with failed buckets", service: scriptedUploadService{listedUploadService: listedUploadService{uploads: recent}, listErr: types.BucketErrors{{Bucket: "archive", Err: denied}}}, args: []string{"list"}, want: ExitPartialFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := runForExitCode(t, &tt.service, tt.args...); code != tt.want {
+				t.Errorf("exit code = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsageAndConfigErrorsExitWithError(t *testing.T) {
+	// Flag values a command parses itself are checked after the credentials
+	t.Setenv("S3MPC_SKIP_PREFLIGHT", "true")
+	for _, args := range [][]string{
+		{"list", "--no-such-flag"},
+		{"list", "--concurrency", "0"},
+		{"delete", "--older-than", "soon"},
+	} {
+		a := NewApp("test")
+		a.rootCmd.SetOut(&bytes.Buffer{})
+		a.rootCmd.SetErr(&bytes.Buffer{})
+		if code := ExitCode(a.Run(context.Background(), args)); code != ExitError {
+			t.Errorf("exit code for %v = %d, want %d", args, code, ExitError)
+		}
+	}
+}
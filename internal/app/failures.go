@@ -0,0 +1,209 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// autoFailuresFile is the --save-failures value when the flag has no file name
+const autoFailuresFile = "auto"
+
+// addSaveFailuresFlag registers --save-failures, which may be given without a value
+func addSaveFailuresFlag(cmd *cobra.Command) {
+	cmd.Flags().String("save-failures", "", "Write uploads that could not be deleted to a JSON file for --from-file (named after the current time if no file is given)")
+	cmd.Flags().Lookup("save-failures").NoOptDefVal = autoFailuresFile
+}
+
+// failureWriter returns the writer for --save-failures, or nil when the flag
+// is not set. Because the flag's value is optional, "--save-failures FILE"
+// leaves the file name in args.
+func (a *App) failureWriter(cmd *cobra.Command, args []string) (*services.FailureWriter, error) {
+	filename, _ := cmd.Flags().GetString("save-failures")
+	if filename == autoFailuresFile && len(args) == 1 {
+		filename = args[0]
+		args = nil
+	}
+	if len(args) > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", args[0])
+	}
+	if filename == "" {
+		return nil, nil
+	}
+	if filename == autoFailuresFile {
+		filename = fmt.Sprintf("s3mpc_delete_failures_%s.json", time.Now().Format("20060102_150405"))
+	}
+
+	cfg := a.container.GetConfig()
+	sourceFile, _ := cmd.Flags().GetString("from-file")
+	if retryFrom, _ := cmd.Flags().GetString("retry-from"); retryFrom != "" {
+		sourceFile = retryFrom
+	}
+	return services.NewFailureWriter(filename, types.FailureReport{
+		Command:    commandLine(cmd),
+		Version:    a.getVersion(),
+		Profile:    cfg.AWSProfile,
+		Region:     cfg.AWSRegion,
+		RoleARN:    cfg.RoleARN,
+		SourceFile: sourceFile,
+	}), nil
+}
+
+// closeFailureReport completes the failure report and tells the user how to
+// retry the uploads in it
+func (a *App) closeFailureReport(cmd *cobra.Command, failures *services.FailureWriter, interrupted bool) error {
+	if err := failures.Close(interrupted); err != nil {
+		return err
+	}
+
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	count := failures.Failed()
+	if count == 0 {
+		if !quiet {
+			cmd.Printf("No uploads failed; %s was not written\n", failures.Filename())
+		}
+		return nil
+	}
+
+	noun := "uploads"
+	if count == 1 {
+		noun = "upload"
+	}
+	cmd.PrintErrf("Saved %d failed %s to %s\n", count, noun, failures.Filename())
+	cmd.PrintErrf("Retry them with: s3mpc delete --from-file %s\n", failures.Filename())
+	return nil
+}
+
+// redactedFlags are left out of recorded command lines, since reports are
+// shared and pasted into tickets
+var redactedFlags = map[string]bool{"external-id": true}
+
+// commandLine returns the command path and the flags that were set, quoted
+// so that it can be run again, for recording how a report was produced
+func commandLine(cmd *cobra.Command) string {
+	parts := []string{cmd.CommandPath()}
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if redactedFlags[flag.Name] {
+			return
+		}
+		value := flag.Value.String()
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			value = strings.Join(slice.GetSlice(), ",")
+		}
+		parts = append(parts, fmt.Sprintf("--%s=%s", flag.Name, shellQuote(value)))
+	})
+	return strings.Join(parts, " ")
+}
+
+// shellQuote quotes value for a POSIX shell when it has characters the shell
+// would interpret
+func shellQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n'\"\\$`|&;<>()*?[]{}~#!") {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// autoReportFile is the --report-file value that names the report after the
+// current time or --filename-template
+const autoReportFile = "auto"
+
+// addReportFileFlag registers --report-file. Its value is required, unlike
+// that of --save and --save-failures, so that the file name cannot be taken
+// for the value of another of them.
+func addReportFileFlag(cmd *cobra.Command) {
+	cmd.Flags().String("report-file", "", "Write the outcome of every deletion, the options used and the totals to a JSON file (CSV if the name ends in .csv; \"auto\" names it after the current time or --filename-template)")
+}
+
+// deletionReportFile returns the file name for --report-file, or "" when the
+// flag is not set
+func (a *App) deletionReportFile(cmd *cobra.Command, names *services.FilenameTemplate) (string, error) {
+	filename, _ := cmd.Flags().GetString("report-file")
+	if filename != autoReportFile {
+		return filename, nil
+	}
+	if names == nil {
+		return fmt.Sprintf("s3mpc_delete_report_%s.json", time.Now().Format("20060102_150405")), nil
+	}
+	return a.renderFilename(cmd, names, services.FilenameData{Command: "delete", Format: "json"})
+}
+
+// deletionReportWriter returns the writer for --report-file, or nil when
+// filename is empty
+func (a *App) deletionReportWriter(cmd *cobra.Command, filename string, opts types.DeleteOptions) *services.DeletionReportWriter {
+	if filename == "" {
+		return nil
+	}
+
+	cfg := a.container.GetConfig()
+	sourceFile, _ := cmd.Flags().GetString("from-file")
+	retryFrom, _ := cmd.Flags().GetString("retry-from")
+	return services.NewDeletionReportWriter(filename, types.DeletionReport{
+		Command:    commandLine(cmd),
+		Version:    a.getVersion(),
+		Profile:    cfg.AWSProfile,
+		Region:     cfg.AWSRegion,
+		RoleARN:    cfg.RoleARN,
+		SourceFile: sourceFile,
+		RetryOf:    retryFrom,
+	}, opts)
+}
+
+// writeDeletionReport writes the deletion report. A report that cannot be
+// written is only reported, so the exit code stays that of the deletion.
+func (a *App) writeDeletionReport(cmd *cobra.Command, report *services.DeletionReportWriter, runErr error, interrupted bool) {
+	if err := report.Write(runErr, interrupted); err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	if quiet, _ := cmd.Flags().GetBool("quiet"); !quiet {
+		cmd.PrintErrf("Saved deletion report to %s\n", report.Filename())
+	}
+}
+
+// autoSaveFile is the --save value when the flag has no file name
+const autoSaveFile = "auto"
+
+// addSaveFlag registers --save, which may be given without a value
+func addSaveFlag(cmd *cobra.Command) {
+	cmd.Flags().String("save", "", "Save the dry-run report to a JSON file (CSV if the name ends in .csv), for review or --from-file (named after the current time or --filename-template if no file is given)")
+	cmd.Flags().Lookup("save").NoOptDefVal = autoSaveFile
+}
+
+// dryRunSaveFile returns the file name for --save, or "" when the flag is not
+// set, and the arguments left over. As with --save-failures, "--save FILE"
+// leaves the file name in args. Without one the file is named by names, or
+// after the current time when names is nil.
+func (a *App) dryRunSaveFile(cmd *cobra.Command, args []string, names *services.FilenameTemplate) (string, []string, error) {
+	filename, _ := cmd.Flags().GetString("save")
+	if filename == autoSaveFile && len(args) > 0 {
+		filename, args = args[0], args[1:]
+	}
+	if filename != autoSaveFile {
+		return filename, args, nil
+	}
+	if names == nil {
+		return a.container.GetDryRunService().GenerateFilename("delete", "json"), args, nil
+	}
+	filename, err := a.renderFilename(cmd, names, services.FilenameData{Command: "dry-run", Format: "json"})
+	return filename, args, err
+}
+
+// saveDryRunResult saves a dry-run report for --save, recording the version
+// that produced it, and tells the user where it is
+func (a *App) saveDryRunResult(cmd *cobra.Command, result types.DryRunResult, filename string) error {
+	result.Version = a.getVersion()
+	if err := a.container.GetDryRunService().SaveDryRunResult(result, filename); err != nil {
+		return fmt.Errorf("failed to save dry-run report: %w", err)
+	}
+	if quiet, _ := cmd.Flags().GetBool("quiet"); !quiet {
+		cmd.PrintErrf("Saved dry-run report to %s\n", filename)
+	}
+	return nil
+}
@@ -0,0 +1,591 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/internal/config"
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// failingUploadService fails to delete the uploads in fail and records the rest
+type failingUploadService struct {
+	interfaces.UploadService
+	uploads   []types.MultipartUpload
+	fail      map[string]bool
+	listed    bool
+	deleted   []string
+	cleaned   map[string]bool // uploads S3 no longer has
+	interrupt map[string]bool // uploads whose deletion an interrupt cuts short
+}
+
+func (s *failingUploadService) FindRemainingUploads(ctx context.Context, uploads []types.MultipartUpload) ([]types.MultipartUpload, []types.MultipartUpload, error) {
+	var remaining, cleaned []types.MultipartUpload
+	for _, upload := range uploads {
+		if s.cleaned[upload.Key] {
+			cleaned = append(cleaned, upload)
+		} else {
+			remaining = append(remaining, upload)
+		}
+	}
+	return remaining, cleaned, nil
+}
+
+func (s *failingUploadService) SkippedBuckets() []types.SkippedBucket {
+	return nil
+}
+
+func (s *failingUploadService) ListUploads(ctx context.Context, opts types.ListOptions) ([]types.MultipartUpload, error) {
+	s.listed = true
+	return s.uploads, nil
+}
+
+func (s *failingUploadService) DeleteUploads(ctx context.Context, uploads []types.MultipartUpload, opts types.DeleteOptions) error {
+	if opts.OnStart != nil {
+		opts.OnStart(uploads)
+	}
+	failed := 0
+	for _, upload := range uploads {
+		if s.cleaned[upload.Key] && opts.OnAlreadyCleaned != nil {
+			opts.OnAlreadyCleaned(upload)
+			continue
+		}
+		if s.interrupt[upload.Key] {
+			failed++
+			if opts.OnOutcome != nil {
+				opts.OnOutcome(upload, fmt.Errorf("failed to abort multipart upload %s: %w", upload.UploadID, context.Canceled))
+			}
+			continue
+		}
+		if s.fail[upload.Key] {
+			failed++
+			err := fmt.Errorf("failed to abort multipart upload %s: %w", upload.UploadID, errors.New("connection reset"))
+			if opts.OnFailure != nil {
+				opts.OnFailure(upload, err)
+			}
+			if opts.OnOutcome != nil {
+				opts.OnOutcome(upload, err)
+			}
+			continue
+		}
+		s.deleted = append(s.deleted, upload.Key)
+		if opts.OnOutcome != nil {
+			opts.OnOutcome(upload, nil)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d out of %d uploads", failed, len(uploads))
+	}
+	return nil
+}
+
+// runDelete runs delete with args against uploadService and returns its stderr and error
+func runDelete(t *testing.T, uploadService interfaces.UploadService, args ...string) (string, error) {
+	t.Helper()
+	return runDeleteWithConfig(t, testConfig(), uploadService, args...)
+}
+
+// runDeleteWithConfig runs delete as runDelete does, with cfg as the
+// configuration the global flags resolved to
+func runDeleteWithConfig(t *testing.T, cfg *config.Config, uploadService interfaces.UploadService, args ...string) (string, error) {
+	t.Helper()
+	_, _, stderr, err := runCommandWithConfig(t, cfg, uploadService, append([]string{"delete"}, args...)...)
+	return stderr, err
+}
+
+func TestSaveFailuresThenRetryFromFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "failures.json")
+	initiated := time.Now().Add(-72 * time.Hour).UTC().Truncate(time.Second)
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.tmp", UploadID: "1", Initiated: initiated, Region: "us-east-1"},
+		{Bucket: "media", Key: "b.tmp", UploadID: "2", Initiated: initiated, Region: "us-east-1"},
+	}
+
+	// "--save-failures FILE" leaves the name as an argument, as the value is optional
+	first := &failingUploadService{uploads: uploads, fail: map[string]bool{"b.tmp": true}}
+	stderr, err := runDelete(t, first, "--force", "--save-failures", filename)
+	if err == nil {
+		t.Fatal("delete succeeded although an upload failed")
+	}
+	if !strings.Contains(stderr, "Saved 1 failed upload to "+filename) || !strings.Contains(stderr, "s3mpc delete --from-file "+filename) {
+		t.Errorf("stderr = %q, want the saved file and the retry command", stderr)
+	}
+
+	file, err := services.LoadUploadsFile(filename)
+	if err != nil {
+		t.Fatalf("LoadUploadsFile() error = %v", err)
+	}
+	if file.Kind != services.UploadsFileFailures || len(file.Uploads) != 1 || file.Uploads[0].Key != "b.tmp" {
+		t.Fatalf("failure report = %+v, want only b.tmp", file)
+	}
+
+	// The retry deletes exactly the failed uploads without listing S3
+	retry := &failingUploadService{uploads: uploads}
+	if _, err := runDelete(t, retry, "--force", "--from-file", filename); err != nil {
+		t.Fatalf("delete --from-file error = %v", err)
+	}
+	if retry.listed || len(retry.deleted) != 1 || retry.deleted[0] != "b.tmp" {
+		t.Errorf("retry listed S3 = %v and deleted %v, want only b.tmp from the file", retry.listed, retry.deleted)
+	}
+}
+
+func TestSaveFailuresRejectsExtraArguments(t *testing.T) {
+	_, err := runDelete(t, &failingUploadService{}, "--force", "stray")
+	if err == nil || !strings.Contains(err.Error(), `unexpected argument "stray"`) {
+		t.Errorf("delete stray error = %v, want an unexpected argument error", err)
+	}
+}
+
+func TestReportFileRecordsEveryDeletion(t *testing.T) {
+	dir := t.TempDir()
+	initiated := time.Now().Add(-72 * time.Hour).UTC().Truncate(time.Second)
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.tmp", UploadID: "1", Initiated: initiated, Size: 100, Region: "us-east-1"},
+		{Bucket: "media", Key: "b.tmp", UploadID: "2", Initiated: initiated, Size: 200, Region: "us-east-1"},
+	}
+
+	filename := filepath.Join(dir, "report.json")
+	service := &failingUploadService{uploads: uploads, fail: map[string]bool{"b.tmp": true}}
+	stderr, err := runDelete(t, service, "--force", "--older-than", "1d", "--report-file", filename)
+	if err == nil {
+		t.Fatal("delete succeeded although an upload failed")
+	}
+	if !strings.Contains(stderr, "Saved deletion report to "+filename) {
+		t.Errorf("stderr = %q, want the saved report", stderr)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report types.DeletionReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("report is not JSON: %v", err)
+	}
+	if report.Kind != types.DeletionReportKind || report.Version != "test" || report.Options != "delete --older-than 1d --force" ||
+		!strings.Contains(report.Command, "--report-file="+filename) {
+		t.Errorf("report metadata = %+v", report)
+	}
+	if report.TotalProcessed != 2 || report.SuccessfulDeletes != 1 || report.FailedDeletes != 1 || report.StorageFreed != 100 ||
+		report.Error != "failed to delete 1 out of 2 uploads" {
+		t.Errorf("report totals = %+v", report)
+	}
+	if len(report.Outcomes) != 2 || !report.Outcomes[0].Success || report.Outcomes[1].Success ||
+		!strings.Contains(report.Outcomes[1].Error, "connection reset") {
+		t.Errorf("report outcomes = %+v", report.Outcomes)
+	}
+
+	// A report that cannot be written leaves the deletion's result alone
+	unwritable := filepath.Join(filename, "report.csv")
+	stderr, err = runDelete(t, &failingUploadService{uploads: uploads}, "--force", "--report-file", unwritable)
+	if err != nil {
+		t.Errorf("delete error = %v, want the successful deletion's nil", err)
+	}
+	if !strings.Contains(stderr, "Error: failed to create directory") {
+		t.Errorf("stderr = %q, want the report error", stderr)
+	}
+
+	if _, err := runDelete(t, &failingUploadService{}, "--dry-run", "--report-file", filename); err == nil {
+		t.Error("delete --dry-run --report-file succeeded, want an error")
+	}
+}
+
+func TestReportFileKeepsItsValueBesideSaveFailures(t *testing.T) {
+	initiated := time.Now().Add(-72 * time.Hour).UTC().Truncate(time.Second)
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.tmp", UploadID: "1", Initiated: initiated, Size: 100, Region: "us-east-1"},
+		{Bucket: "logs", Key: "b.tmp", UploadID: "2", Initiated: initiated, Size: 200, Region: "us-east-1"},
+	}
+
+	// Each file goes to the flag it follows, whichever comes first
+	for _, order := range []string{"report first", "failures first"} {
+		t.Run(order, func(t *testing.T) {
+			dir := t.TempDir()
+			report := filepath.Join(dir, "report.json")
+			failed := filepath.Join(dir, "failed.json")
+			args := []string{"--force", "--report-file", report, "--save-failures", failed}
+			if order == "failures first" {
+				args = []string{"--force", "--save-failures", failed, "--report-file", report}
+			}
+			if _, err := runDelete(t, &failingUploadService{uploads: uploads, fail: map[string]bool{"b.tmp": true}}, args...); err == nil {
+				t.Fatal("delete succeeded although an upload failed")
+			}
+
+			var deletion types.DeletionReport
+			if data, err := os.ReadFile(report); err != nil || json.Unmarshal(data, &deletion) != nil || deletion.Kind != types.DeletionReportKind {
+				t.Errorf("%s is not the deletion report (error = %v)", report, err)
+			}
+			var failures types.FailureReport
+			if data, err := os.ReadFile(failed); err != nil || json.Unmarshal(data, &failures) != nil || len(failures.Uploads) != 1 {
+				t.Errorf("%s is not the failure report with b.tmp (error = %v)", failed, err)
+			}
+		})
+	}
+
+	// auto names the report after the current time
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if _, err := runDelete(t, &failingUploadService{uploads: uploads}, "--force", "--report-file", "auto"); err != nil {
+		t.Fatalf("delete --report-file auto error = %v", err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "s3mpc_delete_report_*.json")); len(matches) != 1 {
+		t.Errorf("delete --report-file auto wrote %v, want one s3mpc_delete_report_<time>.json", matches)
+	}
+}
+
+func TestRetryFromRetriesOnlyFailures(t *testing.T) {
+	dir := t.TempDir()
+	initiated := time.Now().Add(-72 * time.Hour).UTC().Truncate(time.Second)
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.tmp", UploadID: "1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "b.tmp", UploadID: "2", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "c.tmp", UploadID: "3", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+
+	first := filepath.Join(dir, "first.json")
+	failing := &failingUploadService{uploads: uploads, fail: map[string]bool{"b.tmp": true, "c.tmp": true}}
+	if _, err := runDelete(t, failing, "--force", "--report-file", first); err == nil {
+		t.Fatal("delete succeeded although uploads failed")
+	}
+
+	// c.tmp was cleaned up in the meantime, so only b.tmp is retried, without listing S3
+	second := filepath.Join(dir, "second.json")
+	retry := &failingUploadService{uploads: uploads, cleaned: map[string]bool{"c.tmp": true}}
+	if _, err := runDelete(t, retry, "--force", "--retry-from", first, "--report-file", second); err != nil {
+		t.Fatalf("delete --retry-from error = %v", err)
+	}
+	if retry.listed || len(retry.deleted) != 1 || retry.deleted[0] != "b.tmp" {
+		t.Errorf("retry listed S3 = %v and deleted %v, want only b.tmp from the report", retry.listed, retry.deleted)
+	}
+
+	data, err := os.ReadFile(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report types.DeletionReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("report is not JSON: %v", err)
+	}
+	if report.RetryOf != first || report.SuccessfulDeletes != 1 || report.AlreadyCleaned != 1 || report.FailedDeletes != 0 {
+		t.Errorf("retry report = %+v, want it to chain to %s with 1 deleted and 1 already cleaned", report, first)
+	}
+
+	if _, err := runDelete(t, &failingUploadService{}, "--force", "--retry-from", first, "--from-file", first); err == nil {
+		t.Error("delete --retry-from --from-file succeeded, want an error")
+	}
+}
+
+func TestCheckpointResumesWithoutListing(t *testing.T) {
+	checkpoint := filepath.Join(t.TempDir(), "state.json")
+	initiated := time.Now().Add(-72 * time.Hour).UTC().Truncate(time.Second)
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.tmp", UploadID: "1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "b.tmp", UploadID: "2", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "c.tmp", UploadID: "3", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+
+	// The interrupt cuts the deletion of b.tmp and c.tmp short
+	first := &failingUploadService{uploads: uploads, interrupt: map[string]bool{"b.tmp": true, "c.tmp": true}}
+	stderr, err := runDelete(t, first, "--force", "--older-than", "1d", "--checkpoint", checkpoint)
+	if err == nil {
+		t.Fatal("delete succeeded although it was interrupted")
+	}
+	if !strings.Contains(stderr, "Saved checkpoint to "+checkpoint) {
+		t.Errorf("stderr = %q, want the saved checkpoint", stderr)
+	}
+
+	// Other filters would have selected other uploads, so they cannot resume it
+	_, err = runDelete(t, &failingUploadService{uploads: uploads}, "--force", "--older-than", "2d", "--checkpoint", checkpoint)
+	if err == nil || !strings.Contains(err.Error(), "cannot resume --checkpoint") {
+		t.Errorf("delete with other filters error = %v, want a refusal to resume", err)
+	}
+
+	// Neither can other regions, nor a --from-file the checkpoint was not written from
+	cfg := config.DefaultConfig()
+	cfg.AWSRegion = "us-east-1"
+	cfg.Regions = []string{"eu-west-1"}
+	_, err = runDeleteWithConfig(t, cfg, &failingUploadService{uploads: uploads}, "--force", "--older-than", "1d", "--checkpoint", checkpoint)
+	if err == nil || !strings.Contains(err.Error(), "cannot resume --checkpoint") {
+		t.Errorf("delete with other --regions error = %v, want a refusal to resume", err)
+	}
+	fromFile := filepath.Join(t.TempDir(), "uploads.json")
+	if err := os.WriteFile(fromFile, []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = runDelete(t, &failingUploadService{}, "--force", "--older-than", "1d", "--from-file", fromFile, "--checkpoint", checkpoint)
+	if err == nil || !strings.Contains(err.Error(), "cannot resume --checkpoint") {
+		t.Errorf("delete with --from-file error = %v, want a refusal to resume", err)
+	}
+
+	// c.tmp was cleaned up in the meantime, so deleting it finds it gone, and
+	// only b.tmp is deleted, without listing or checking S3 first
+	resumed := &failingUploadService{uploads: uploads, cleaned: map[string]bool{"c.tmp": true}}
+	if _, err := runDelete(t, resumed, "--force", "--older-than", "1d", "--checkpoint", checkpoint); err != nil {
+		t.Fatalf("resumed delete error = %v", err)
+	}
+	if resumed.listed || len(resumed.deleted) != 1 || resumed.deleted[0] != "b.tmp" {
+		t.Errorf("resumed run listed S3 = %v and deleted %v, want only b.tmp from the checkpoint", resumed.listed, resumed.deleted)
+	}
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Errorf("checkpoint of a completed run still exists (stat error = %v)", err)
+	}
+
+	if _, err := runDelete(t, &failingUploadService{}, "--dry-run", "--checkpoint", checkpoint); err == nil {
+		t.Error("delete --dry-run --checkpoint succeeded, want an error")
+	}
+}
+
+func TestVerifyAccessNeedsDryRun(t *testing.T) {
+	service := &failingUploadService{}
+	if _, err := runDelete(t, service, "--force", "--verify-access"); err == nil || !strings.Contains(err.Error(), "only be used with --dry-run") {
+		t.Errorf("delete --verify-access error = %v, want it limited to dry-runs", err)
+	}
+	if service.listed {
+		t.Error("delete --verify-access listed uploads before rejecting the flag")
+	}
+}
+
+// runDryRunDelete runs delete with args against uploads, with the dry-run
+// service wired in, and returns its stderr and error
+func runDryRunDelete(t *testing.T, uploads []types.MultipartUpload, args ...string) (string, error) {
+	t.Helper()
+	var stdout bytes.Buffer
+	uploadService := &listedUploadService{
+		UploadService: services.NewUploadServiceWithOptions(nil, nil, services.NewDryRunService(services.NewCostService()), 1,
+			services.NewConsoleProgressReporter(&stdout, true), nil, &stdout, nil, nil),
+		uploads: uploads,
+	}
+
+	return runDelete(t, uploadService, args...)
+}
+
+func TestDeleteInitiatedWindow(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "early", UploadID: "1", Initiated: time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC), StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "incident", UploadID: "2", Initiated: time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC), StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "late", UploadID: "3", Initiated: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+
+	filename := filepath.Join(t.TempDir(), "window.json")
+	if _, err := runDryRunDelete(t, uploads, "--dry-run", "--initiated-after", "2024-03-01", "--initiated-before", "2024-03-05", "--save", filename); err != nil {
+		t.Fatalf("delete --dry-run error = %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result types.DryRunResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalUploads != 1 {
+		t.Errorf("dry run selected %d uploads, want only the one inside the window", result.TotalUploads)
+	}
+
+	invalid := [][]string{
+		{"--initiated-after", "2024-03-05", "--initiated-before", "2024-03-01"},
+		{"--initiated-before", "2024-03-05", "--older-than", "2024-03-01"},
+		{"--initiated-after", "last week"},
+	}
+	for _, args := range invalid {
+		if _, err := runDryRunDelete(t, uploads, append([]string{"--dry-run"}, args...)...); err == nil {
+			t.Errorf("delete %v succeeded, want an error", args)
+		}
+	}
+}
+
+func TestDeleteFilter(t *testing.T) {
+	initiated := time.Now().Add(-72 * time.Hour).UTC()
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.tmp", UploadID: "1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "b.csv", UploadID: "2", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "media", Key: "c.tmp", UploadID: "3", Initiated: initiated, StorageClass: "GLACIER", Region: "us-east-1"},
+		{Bucket: "raw", Key: "d.tmp", UploadID: "4", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+
+	filename := filepath.Join(t.TempDir(), "filtered.json")
+	filter := "bucket in (logs, media),key$=.tmp"
+	if _, err := runDryRunDelete(t, uploads, "--dry-run", "--older-than", "1d", "--filter", filter, "--save", filename); err != nil {
+		t.Fatalf("delete --dry-run --filter error = %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result types.DryRunResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalUploads != 2 || result.Filters != "age>1d,"+filter {
+		t.Errorf("dry run selected %d uploads with filters %q, want a.tmp and c.tmp and the filter verbatim", result.TotalUploads, result.Filters)
+	}
+
+	conflicts := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--bucket", "logs", "--filter", "bucket=media"}, "--bucket logs conflicts with the bucket conditions of --filter"},
+		{[]string{"--bucket", "logs", "--filter", "bucket not in (logs, raw)"}, "--bucket logs conflicts"},
+		{[]string{"--storage-class", "glacier", "--filter", "storageClass=STANDARD"}, "--storage-class GLACIER conflicts"},
+		{[]string{"--filter", "initiatedBy=service"}, "--include-service-initiated"},
+		{[]string{"--older-than", "7d", "--filter", "age<1d"}, "conflict with the age and initiated conditions of --filter"},
+		{[]string{"--newer-than", "1d", "--filter", "age>=7d"}, "conflict with the age and initiated conditions"},
+		{[]string{"--initiated-before", "2024-01-01", "--filter", "initiated>=2024-03-01"}, "conflict with the age and initiated conditions"},
+		{[]string{"--suffix", ".tmp", "--filter", "key$=.log"}, "--suffix and --exclude-suffix conflict with the key conditions"},
+		{[]string{"--exclude-suffix", ".tmp", "--filter", "key$=.tmp"}, "--suffix and --exclude-suffix conflict"},
+		{[]string{"--filter", "superseded=true"}, "needs --check-objects"},
+		{[]string{"--filter", "bucket=(logs"}, "invalid filter syntax"},
+		{[]string{"--storage-class", "standard,glaicer"}, `invalid --storage-class "glaicer"`},
+	}
+	for _, conflict := range conflicts {
+		_, err := runDryRunDelete(t, uploads, append([]string{"--dry-run"}, conflict.args...)...)
+		if err == nil || !strings.Contains(err.Error(), conflict.want) {
+			t.Errorf("delete %v error = %v, want %q", conflict.args, err, conflict.want)
+		}
+	}
+
+	// Conditions the flags can meet are not conflicts
+	for _, args := range [][]string{
+		{"--bucket", "logs", "--storage-class", "STANDARD,GLACIER", "--filter", "bucket in (logs, media),storageClass!=STANDARD"},
+		{"--older-than", "1d", "--newer-than", "30d", "--filter", "age>7d"},
+		{"--older-than", "7d", "--filter", "age=10d±1d"},
+		{"--newer-than", "7d", "--filter", "age!=3d"},
+		{"--suffix", ".tmp,.log", "--exclude-suffix", "a.tmp", "--filter", "key$=.tmp"},
+	} {
+		if _, err := runDryRunDelete(t, uploads, append([]string{"--dry-run"}, args...)...); err != nil {
+			t.Errorf("delete %v with compatible --filter error = %v", args, err)
+		}
+	}
+}
+
+func TestSaveWritesDryRunReport(t *testing.T) {
+	dir := t.TempDir()
+	initiated := time.Now().Add(-72 * time.Hour).UTC().Truncate(time.Second)
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: " padded.tmp ", UploadID: "1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "media", Key: "line\rbreak.tmp", UploadID: "2", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+
+	// "--save FILE" leaves the name as an argument, as the value is optional
+	filename := filepath.Join(dir, "dryrun.json")
+	stderr, err := runDryRunDelete(t, uploads, "--dry-run", "--older-than", "1d", "--save", filename)
+	if err != nil {
+		t.Fatalf("delete --dry-run --save error = %v", err)
+	}
+	if !strings.Contains(stderr, "Saved dry-run report to "+filename) {
+		t.Errorf("stderr = %q, want the saved file", stderr)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result types.DryRunResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("report is not JSON: %v", err)
+	}
+	if result.Version != "test" || !strings.Contains(result.Filters, "1d") || result.TotalUploads != 2 {
+		t.Errorf("saved report = %+v, want the version, filters and both uploads", result)
+	}
+
+	// A .csv name writes CSV that keeps the keys exactly
+	csvFile := filepath.Join(dir, "dryrun.csv")
+	if _, err := runDryRunDelete(t, uploads, "--dry-run", "--save="+csvFile); err != nil {
+		t.Fatalf("delete --dry-run --save=%s error = %v", csvFile, err)
+	}
+	file, err := services.LoadUploadsFile(csvFile)
+	if err != nil {
+		t.Fatalf("LoadUploadsFile() error = %v", err)
+	}
+	if len(file.Uploads) != 2 || file.Uploads[0].Key != uploads[0].Key || file.Uploads[1].Key != uploads[1].Key {
+		t.Errorf("CSV uploads = %+v, want the raw keys", file.Uploads)
+	}
+
+	if _, err := runDryRunDelete(t, uploads, "--force", "--save", filename); err == nil {
+		t.Error("delete --save without --dry-run succeeded, want an error")
+	}
+}
+
+func TestSaveWithoutFileNameUsesGeneratedName(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	initiated := time.Now().Add(-72 * time.Hour)
+	uploads := []types.MultipartUpload{{Bucket: "logs", Key: "a.tmp", UploadID: "1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"}}
+	stderr, err := runDryRunDelete(t, uploads, "--dry-run", "--save")
+	if err != nil {
+		t.Fatalf("delete --dry-run --save error = %v", err)
+	}
+
+	matches, _ := filepath.Glob("s3mpc_delete_dryrun_*.json")
+	if len(matches) != 1 || !strings.Contains(stderr, "Saved dry-run report to "+matches[0]) {
+		t.Errorf("files = %v and stderr = %q, want one generated report", matches, stderr)
+	}
+}
+
+func TestFilenameTemplateNamesSaveAndReportFiles(t *testing.T) {
+	dir := t.TempDir()
+	initiated := time.Now().Add(-72 * time.Hour).UTC().Truncate(time.Second)
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.tmp", UploadID: "1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+	template := filepath.Join(dir, "{{.Region}}", "{{.Command}}-{{.Bucket}}-{{.Timestamp}}")
+	year := time.Now().Format("2006")
+
+	// Directories in the rendered name are created
+	stderr, err := runDryRunDelete(t, uploads, "--dry-run", "--bucket", "logs", "--filename-template", template, "--timestamp-format", "2006", "--save")
+	if err != nil {
+		t.Fatalf("delete --dry-run --save error = %v", err)
+	}
+	saved := filepath.Join(dir, "us-east-1", "dry-run-logs-"+year+".json")
+	if _, err := os.Stat(saved); err != nil || !strings.Contains(stderr, saved) {
+		t.Errorf("dry-run report not saved to %s: %v\n%s", saved, err, stderr)
+	}
+
+	// Without a file the template names the report too
+	stderr, err = runDelete(t, &failingUploadService{uploads: uploads}, "--force", "--filename-template", template, "--timestamp-format", "2006", "--report-file", "auto")
+	if err != nil {
+		t.Fatalf("delete --report-file error = %v", err)
+	}
+	report := filepath.Join(dir, "us-east-1", "delete-all-"+year+".json")
+	if _, err := os.Stat(report); err != nil || !strings.Contains(stderr, report) {
+		t.Errorf("deletion report not saved to %s: %v\n%s", report, err, stderr)
+	}
+
+	for _, test := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--filename-template", "{{.Owner}}", "--report-file=auto"}, "can't evaluate field Owner"},
+		{[]string{"--filename-template", "{{.Bucket", "--report-file=auto"}, "invalid --filename-template"},
+		{[]string{"--filename-template", template, "--timestamp-format", "latest", "--report-file=auto"}, "has no date or time elements"},
+		{[]string{"--timestamp-format", "2006", "--report-file=auto"}, "needs --filename-template"},
+		{[]string{"--filename-template", template}, "needs one of them"},
+	} {
+		if _, err := runDelete(t, &failingUploadService{uploads: uploads}, append([]string{"--force"}, test.args...)...); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("delete %v error = %v, want %s", test.args, err, test.err)
+		}
+	}
+}
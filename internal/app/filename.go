@@ -0,0 +1,99 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// addFilenameTemplateFlags registers --filename-template and --timestamp-format
+// on commands that name the files they write
+func addFilenameTemplateFlags(cmd *cobra.Command, names string) {
+	cmd.Flags().String("filename-template", "", "Name "+names+" with a template, e.g. 'reports/{{.Account}}/{{.Timestamp}}/uploads-{{.Bucket}}.{{.Format}}' (placeholders: Command, Timestamp, Account, Region, Bucket, Format, Filter)")
+	cmd.Flags().String("timestamp-format", services.DefaultTimestampFormat, "Go time layout of {{.Timestamp}} in --filename-template")
+}
+
+// filenameTemplate returns the parsed --filename-template, or nil when the
+// flag is not set, so that a bad template fails before anything is listed
+func filenameTemplate(cmd *cobra.Command) (*services.FilenameTemplate, error) {
+	text, _ := cmd.Flags().GetString("filename-template")
+	timestampFormat, _ := cmd.Flags().GetString("timestamp-format")
+	if text == "" {
+		if cmd.Flags().Changed("timestamp-format") {
+			return nil, fmt.Errorf("--timestamp-format formats {{.Timestamp}} in --filename-template, so it needs --filename-template")
+		}
+		return nil, nil
+	}
+	names, err := services.ParseFilenameTemplate(text, timestampFormat)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --filename-template: %w", err)
+	}
+	return names, nil
+}
+
+// renderFilename names a file of command with names, filling in what data
+// leaves empty from the command's flags and configuration. The account is
+// only looked up when the template uses it.
+func (a *App) renderFilename(cmd *cobra.Command, names *services.FilenameTemplate, data services.FilenameData) (string, error) {
+	data, err := a.filenameData(cmd, names, data)
+	if err != nil {
+		return "", err
+	}
+	return renderFilename(names, data, time.Now())
+}
+
+// filenameData fills in what data leaves empty from the command's flags and
+// configuration, for naming several files with one account lookup
+func (a *App) filenameData(cmd *cobra.Command, names *services.FilenameTemplate, data services.FilenameData) (services.FilenameData, error) {
+	if data.Bucket == "" {
+		data.Bucket, _ = cmd.Flags().GetString("bucket")
+	}
+	if data.Filter == "" {
+		data.Filter = a.filterExpression(cmd)
+	}
+	if data.Region == "" {
+		data.Region = a.container.GetConfig().AWSRegion
+	}
+	if data.Account == "" && names.Uses("Account") {
+		account, err := a.container.AccountID(cmd.Context())
+		if err != nil {
+			return data, fmt.Errorf("failed to look up the account for --filename-template: %w", err)
+		}
+		data.Account = account
+	}
+	return data, nil
+}
+
+// renderFilename renders names with data filled in by filenameData
+func renderFilename(names *services.FilenameTemplate, data services.FilenameData, now time.Time) (string, error) {
+	filename, err := names.Render(data, now)
+	if err != nil {
+		return "", fmt.Errorf("invalid --filename-template: %w", err)
+	}
+	return filename, nil
+}
+
+// bucketFilenames renders names for each bucket of uploads, for an export
+// split by bucket, with the other values of data
+func bucketFilenames(names *services.FilenameTemplate, data services.FilenameData, now time.Time, uploads []types.MultipartUpload, compress bool) (map[string]string, error) {
+	files := make(map[string]string)
+	for _, upload := range uploads {
+		if _, ok := files[upload.Bucket]; ok {
+			continue
+		}
+		data.Bucket = upload.Bucket
+		filename, err := renderFilename(names, data, now)
+		if err != nil {
+			return nil, err
+		}
+		if compress && !services.IsCompressedFilename(filename) {
+			filename += ".gz"
+		}
+		files[upload.Bucket] = filename
+	}
+	return files, nil
+}
@@ -0,0 +1,295 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/filter"
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/services"
+)
+
+// helpWidth is the column width help topics are wrapped to
+const helpWidth = 80
+
+// helpTopic is a help page shown by "s3mpc help <name>"
+type helpTopic struct {
+	Name     string
+	Short    string
+	Sections func(a *App) []helpSection
+}
+
+// helpSection is a titled block of paragraphs and term/description items
+type helpSection struct {
+	Title      string
+	Paragraphs []string
+	Items      []helpItem
+}
+
+// helpItem is a term with its description, rendered as a definition list
+type helpItem struct {
+	Term        string
+	Description string
+}
+
+// helpTopics returns all registered help topics
+func helpTopics() []helpTopic {
+	return []helpTopic{
+		{Name: "filters", Short: "Filter expression syntax and supported fields", Sections: filtersHelpSections},
+		{Name: "safety", Short: "Dry-run, confirmation and deletion protections", Sections: safetyHelpSections},
+		{Name: "performance", Short: "Concurrency, rate limiting and retries", Sections: performanceHelpSections},
+		{Name: "output", Short: "Output formats, exports and logging", Sections: outputHelpSections},
+	}
+}
+
+// addHelpTopics registers help topics as hidden commands so "s3mpc help <topic>" renders them
+func (a *App) addHelpTopics() {
+	for _, topic := range helpTopics() {
+		topic := topic
+		cmd := &cobra.Command{
+			Use:    topic.Name,
+			Short:  topic.Short,
+			Hidden: true,
+			// Topics only print help and need no AWS clients
+			PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+			Run: func(cmd *cobra.Command, args []string) {
+				cmd.Print(a.renderHelpTopic(topic))
+			},
+		}
+		cmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+			cmd.Print(a.renderHelpTopic(topic))
+		})
+		a.rootCmd.AddCommand(cmd)
+	}
+}
+
+// renderHelpTopic renders a topic wrapped to helpWidth columns
+func (a *App) renderHelpTopic(topic helpTopic) string {
+	var result strings.Builder
+
+	result.WriteString(fmt.Sprintf("s3mpc help %s - %s\n", topic.Name, topic.Short))
+
+	for _, section := range topic.Sections(a) {
+		result.WriteString("\n")
+		result.WriteString(strings.ToUpper(section.Title))
+		result.WriteString("\n")
+
+		for i, paragraph := range section.Paragraphs {
+			if i > 0 {
+				result.WriteString("\n")
+			}
+			for _, line := range wrapText(paragraph, helpWidth-2) {
+				result.WriteString("  " + line + "\n")
+			}
+		}
+
+		if len(section.Paragraphs) > 0 && len(section.Items) > 0 {
+			result.WriteString("\n")
+		}
+		for _, item := range section.Items {
+			result.WriteString("  " + item.Term + "\n")
+			for _, line := range wrapText(item.Description, helpWidth-6) {
+				result.WriteString("      " + line + "\n")
+			}
+		}
+	}
+
+	return result.String()
+}
+
+// wrapText splits text into lines of at most width columns, breaking on spaces
+func wrapText(text string, width int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil
+	}
+
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if len([]rune(line))+1+len([]rune(word)) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
+
+// helpFilterEngine returns the configured filter engine, or a default one
+// when help is shown before the container is initialized
+func (a *App) helpFilterEngine() interfaces.FilterEngine {
+	if a.container != nil {
+		return a.container.GetFilterEngine()
+	}
+	return filter.NewEngine()
+}
+
+// filtersHelpSections builds the filters topic from the engine's field list
+func filtersHelpSections(a *App) []helpSection {
+	var fields []helpItem
+	for _, field := range a.helpFilterEngine().SupportedFields() {
+		fields = append(fields, helpItem{
+			Term:        fmt.Sprintf("%s  (%s)", field.Name, strings.Join(field.Operators, " ")),
+			Description: fmt.Sprintf("%s. Example: %s", field.Description, field.Example),
+		})
+	}
+
+	ageTolerance, _ := a.rootCmd.PersistentFlags().GetDuration("age-tolerance")
+
+	return []helpSection{
+		{
+			Title: "Syntax",
+			Paragraphs: []string{
+				"A filter is a comma-separated list of conditions of the form field, operator, value. All conditions must match. Field names are case-insensitive.",
+				"Quote a value in double or single quotes when it holds commas, pipes or spaces that matter, e.g. bucket=\"logs, archive\" or key$=' .tmp'|.partial. Quoted text is kept exactly; a backslash escapes the quote character or another backslash inside it. Unquoted values are trimmed.",
+				"String fields also take a list of values: bucket in (team-a-raw, team-b-raw) matches uploads in any of them and not in matches the rest. A string field may be given more than once and every condition on it must match, as in bucket!=logs,bucket!=backups. Parentheses must balance; quote a value that contains one.",
+				"Use --filter on list and export, and \"s3mpc explain <filter>\" to see how a filter is evaluated.",
+			},
+		},
+		{Title: "Fields", Items: fields},
+		{
+			Title: "Age equality",
+			Paragraphs: []string{
+				fmt.Sprintf("age=7d and age!=7d compare within a tolerance window, currently %s (set with --age-tolerance). Override it per condition with age=7d±6h or age=7d+-6h. With --age-calendar-days, age=7d matches the calendar day (UTC) seven days ago.", formatHelpDuration(ageTolerance)),
+			},
+		},
+		{
+			Title: "Examples",
+			Items: []helpItem{
+				{Term: "age>7d", Description: "Uploads older than a week"},
+				{Term: "size>1GB,storageClass=STANDARD", Description: "Large uploads in STANDARD storage"},
+				{Term: "age>30d,region=us-east-1", Description: "Old uploads in one region"},
+			},
+		},
+	}
+}
+
+// safetyHelpSections builds the safety topic from the effective configuration
+func safetyHelpSections(a *App) []helpSection {
+	flags := a.rootCmd.PersistentFlags()
+	ageTolerance, _ := flags.GetDuration("age-tolerance")
+	extraPatterns, _ := flags.GetStringSlice("service-initiator-pattern")
+
+	var rules []helpItem
+	for _, rule := range services.DefaultInitiatorRules {
+		rules = append(rules, helpItem{Term: rule.Name, Description: rule.Pattern.String()})
+	}
+	for i, pattern := range extraPatterns {
+		rules = append(rules, helpItem{Term: fmt.Sprintf("custom-%d", i+1), Description: pattern})
+	}
+
+	return []helpSection{
+		{
+			Title: "Dry run",
+			Paragraphs: []string{
+				"Run delete with --dry-run first. It applies exactly the same selection as a real deletion and reports the uploads, storage and estimated monthly savings without aborting anything.",
+				"When you give conditions such as --older-than or --bucket, the dry-run also shows a filter funnel: how many uploads are left after each condition in turn, and how many each one matches on its own, so you can see which condition removes the uploads you expected.",
+				"--filter selects with the same expressions as list, in addition to the other flags, and appears in the funnel as written. A filter that contradicts a flag, such as --bucket a with bucket=b, is rejected.",
+			},
+		},
+		{
+			Title: "Confirmation",
+			Paragraphs: []string{
+				"Without --force, delete prints a summary of what will be aborted and asks for confirmation. Anything other than y or yes cancels. Aborted uploads cannot be recovered.",
+				"--force skips the prompt. Only use it in automation after checking the same command with --dry-run.",
+				"With --no-input (the default when stdin is not a terminal) nothing is ever asked. A confirmation that is needed is declined and s3mpc exits with code 5, so unattended runs never delete without --force.",
+			},
+		},
+		{
+			Title: "Service-initiated uploads",
+			Paragraphs: []string{
+				"Uploads whose Initiator matches one of the rules below belong to AWS services such as replication or Snow transfers. They are never deleted unless --include-service-initiated is given.",
+			},
+			Items: rules,
+		},
+		{
+			Title: "Effective thresholds",
+			Items: []helpItem{
+				{Term: "--older-than", Description: "Not set by default; every upload matches unless you pass an age (7d) or a cut-off date (2024-05-01), which stays fixed between a dry-run and the real deletion"},
+				{Term: "--age-tolerance", Description: formatHelpDuration(ageTolerance)},
+			},
+		},
+	}
+}
+
+// performanceHelpSections builds the performance topic
+func performanceHelpSections(a *App) []helpSection {
+	concurrency, _ := a.rootCmd.PersistentFlags().GetInt("concurrency")
+	rateLimit := a.rateLimit()
+
+	return []helpSection{
+		{
+			Title: "Concurrency",
+			Paragraphs: []string{
+				fmt.Sprintf("--concurrency sets how many buckets are scanned and uploads are sized or deleted in parallel. Currently %d; allowed range is 1 to 100.", concurrency),
+				"--bucket-concurrency and --op-concurrency override it for each kind of work. Bucket scans (listing uploads, looking up bucket regions, reading lifecycle rules) count against account-level limits, so keep --bucket-concurrency low; per-upload calls (deletes, part listings for sizes, object checks) spread across keys and tolerate a higher --op-concurrency. Either one left unset follows --concurrency, so --concurrency alone behaves as before.",
+			},
+		},
+		{
+			Title: "Rate limiting and retries",
+			Paragraphs: []string{
+				fmt.Sprintf("Each regional S3 client is limited to --rate-limit requests per second, currently %g; allowed range is 0.1 to 10000. Workers beyond ten per request per second only wait for the limiter, so a --concurrency, --bucket-concurrency or --op-concurrency that far above the rate limit prints a warning.", rateLimit),
+				"Throttling errors, 5xx responses and dropped connections are retried up to 3 times with exponential backoff; access denied and not-found errors fail immediately.",
+				"Each retry waits a random delay of up to the backoff (full jitter), so workers throttled together do not retry together. A Retry-After header in the response is honored instead, up to 30 seconds. Part listings are retried twice, as a failure only leaves a size unmeasured, and aborts up to 8 times, as a failed abort leaves the upload billed. With --log-level debug each attempt logs its retry delay and the total backoff so far.",
+				"--stats prints, when the command ends, how many calls each S3 API operation made, how many were retried, throttled or failed, the time spent waiting for the rate limiter and the bucket cache hit rates, so you can tell whether a slow run was throttled. It goes to stderr, as JSON when the command writes JSON.",
+			},
+		},
+		{
+			Title: "Timeouts",
+			Paragraphs: []string{
+				"--request-timeout 30s abandons an attempt of any AWS API call that takes longer and retries it like a dropped connection, so one wedged call cannot hang a run. The error names the call and bucket when every attempt times out.",
+				"--timeout 30m stops the whole run after that long. Buckets listed and uploads deleted by then are still reported, the rest are listed as not finished, and s3mpc exits with code 3. Neither is set by default.",
+			},
+		},
+		{
+			Title: "Pricing",
+			Paragraphs: []string{
+				"--live-pricing queries the AWS Pricing API once per region and storage class and caches results in ~/.s3mpc/pricing.json for 24 hours.",
+				"--cost-provider exec:PROGRAM runs PROGRAM once per region and storage class with a JSON request such as {\"schema_version\":1,\"region\":\"us-east-1\",\"storage_class\":\"STANDARD\",\"gb\":1} on stdin. It must print {\"price_per_gb_month\":0.021} within 10 seconds; on any failure s3mpc warns and uses built-in prices.",
+			},
+		},
+	}
+}
+
+// outputHelpSections builds the output topic
+func outputHelpSections(a *App) []helpSection {
+	return []helpSection{
+		{
+			Title: "Console output",
+			Items: []helpItem{
+				{Term: "--json", Description: "Machine-readable output for size, cost, stats, list, age and snapshot diff"},
+				{Term: "metrics", Description: "OpenMetrics (Prometheus) gauges; --write-to replaces a textfile collector file atomically"},
+				{Term: "--quiet", Description: "Suppress non-essential output; the console logs errors only"},
+				{Term: "--verbose", Description: "Enable verbose logging, the same as --log-level debug"},
+				{Term: "--log-level", Description: "Console log level: debug, info, warn or error"},
+				{Term: "--log-file", Description: "Also write logs to a file"},
+				{Term: "--log-format", Description: "text or json; --log-file-format sets the file's format separately"},
+				{Term: "--log-file-level", Description: "Level of the file's logs, independent of the console"},
+				{Term: "--trace-api", Description: "Log every S3 API call with its parameters and latency; keys are redacted unless --trace-api-keys"},
+			},
+		},
+		{
+			Title: "Exports",
+			Paragraphs: []string{
+				"export writes uploads as CSV or JSON (--format). Without --output a timestamped file name such as s3mpc_export_20240101_1200.csv is generated.",
+			},
+		},
+	}
+}
+
+// formatHelpDuration formats a duration without trailing zero units (6h, not 6h0m0s)
+func formatHelpDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Garvitkul/s3mpc/pkg/filter"
+)
+
+func TestHelpTopicsFitTerminalWidth(t *testing.T) {
+	app := NewApp("test")
+
+	for _, topic := range helpTopics() {
+		output := app.renderHelpTopic(topic)
+		for i, line := range strings.Split(output, "\n") {
+			if width := utf8.RuneCountInString(line); width > helpWidth {
+				t.Errorf("help %s line %d is %d columns wide: %q", topic.Name, i+1, width, line)
+			}
+		}
+	}
+}
+
+func TestFiltersHelpMatchesEngineFields(t *testing.T) {
+	app := NewApp("test")
+	engine := filter.NewEngine()
+
+	var filtersTopic helpTopic
+	for _, topic := range helpTopics() {
+		if topic.Name == "filters" {
+			filtersTopic = topic
+		}
+	}
+	output := app.renderHelpTopic(filtersTopic)
+
+	for _, field := range engine.SupportedFields() {
+		if !strings.Contains(output, field.Name+"  ("+strings.Join(field.Operators, " ")+")") {
+			t.Errorf("filters help does not document field %s with operators %v", field.Name, field.Operators)
+		}
+
+		// Every documented example must be accepted by the engine
+		if _, err := engine.ParseFilter(field.Example); err != nil {
+			t.Errorf("example %q for field %s does not parse: %v", field.Example, field.Name, err)
+		}
+	}
+
+	// Fields the engine rejects are reported with the same list the help shows
+	_, err := engine.ParseFilter("nosuchfield=1")
+	if err == nil {
+		t.Fatal("expected error for unsupported field")
+	}
+	for _, field := range engine.SupportedFields() {
+		if !strings.Contains(err.Error(), field.Name) {
+			t.Errorf("unsupported field error %q does not mention %s", err, field.Name)
+		}
+	}
+}
+
+func TestHelpTopicsAreRegistered(t *testing.T) {
+	app := NewApp("test")
+
+	for _, topic := range helpTopics() {
+		cmd, _, err := app.rootCmd.Find([]string{topic.Name})
+		if err != nil || cmd.Name() != topic.Name {
+			t.Errorf("help topic %s is not registered as a command", topic.Name)
+			continue
+		}
+		if !cmd.Hidden {
+			t.Errorf("help topic %s should be hidden", topic.Name)
+		}
+	}
+}
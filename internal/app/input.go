@@ -0,0 +1,22 @@
+package app
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/internal/prompt"
+)
+
+// resolveNoInput returns --no-input when given explicitly, and otherwise
+// disables prompts when stdin is not a terminal (CI, pipes, cron)
+func resolveNoInput(cmd *cobra.Command) bool {
+	if cmd.Flags().Changed("no-input") {
+		noInput, _ := cmd.Flags().GetBool("no-input")
+		return noInput
+	}
+	return !prompt.StdinIsTerminal()
+}
+
+// prompter returns the prompter for a command's input and output
+func (a *App) prompter(cmd *cobra.Command) *prompt.Prompter {
+	return a.container.NewPrompter(cmd.InOrStdin(), cmd.ErrOrStderr())
+}
@@ -0,0 +1,67 @@
+//go:build !windows
+
+package app
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestSecondInterruptExits runs itself in a child process that is slow to
+// stop after the first interrupt, as a deletion recording its outcomes is
+func TestSecondInterruptExits(t *testing.T) {
+	if os.Getenv("S3MPC_TEST_INTERRUPT") == "1" {
+		ctx, stop := interruptibleContext(context.Background())
+		defer stop()
+		fmt.Println("running")
+		<-ctx.Done()
+		fmt.Println("interrupted")
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	}
+
+	child := exec.Command(os.Args[0], "-test.run=^TestSecondInterruptExits$")
+	child.Env = append(os.Environ(), "S3MPC_TEST_INTERRUPT=1")
+	stdout, err := child.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer child.Process.Kill()
+	lines := bufio.NewScanner(stdout)
+	waitFor := func(line string) {
+		t.Helper()
+		for lines.Scan() {
+			if lines.Text() == line {
+				return
+			}
+		}
+		t.Fatalf("child exited before printing %q", line)
+	}
+
+	waitFor("running")
+	child.Process.Signal(os.Interrupt)
+	waitFor("interrupted")
+	child.Process.Signal(os.Interrupt)
+
+	done := make(chan error, 1)
+	go func() { done <- child.Wait() }()
+	select {
+	case err := <-done:
+		var exit *exec.ExitError
+		if !errors.As(err, &exit) || exit.Sys().(syscall.WaitStatus).Signal() != syscall.SIGINT {
+			t.Errorf("child exited with %v, want it killed by the second interrupt", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the second interrupt did not stop the child")
+	}
+}
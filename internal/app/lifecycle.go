@@ -0,0 +1,166 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func (a *App) addLifecycleCommand() {
+	cmd := &cobra.Command{
+		Use:   "lifecycle",
+		Short: "Audit bucket lifecycle rules for incomplete multipart uploads",
+	}
+
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Report which buckets lack an AbortIncompleteMultipartUpload rule",
+		RunE:  a.runLifecycleCheckCommand,
+	}
+	checkCmd.Flags().StringP("bucket", "b", "", "Check a specific bucket")
+	checkCmd.Flags().Bool("json", false, "Output in JSON format")
+	checkCmd.Flags().Bool("missing-only", false, "Only show buckets without an enabled rule covering the whole bucket")
+	cmd.AddCommand(checkCmd)
+
+	applyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Add an AbortIncompleteMultipartUpload rule, keeping existing rules",
+		RunE:  a.runLifecycleApplyCommand,
+	}
+	applyCmd.Flags().Int32("days", 7, "Abort incomplete uploads this many days after they were initiated")
+	applyCmd.Flags().StringP("bucket", "b", "", "Add the rule to a specific bucket")
+	applyCmd.Flags().Bool("all-missing", false, "Add the rule to every bucket without an enabled rule covering the whole bucket")
+	applyCmd.Flags().Bool("dry-run", false, "Show the rule and affected buckets without changing anything")
+	applyCmd.Flags().Bool("force", false, "Skip confirmation prompts")
+	cmd.AddCommand(applyCmd)
+
+	a.rootCmd.AddCommand(cmd)
+}
+
+func (a *App) runLifecycleCheckCommand(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	bucketName, _ := cmd.Flags().GetString("bucket")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	missingOnly, _ := cmd.Flags().GetBool("missing-only")
+
+	lifecycleService := a.container.GetLifecycleService()
+	formatter := a.container.GetOutputFormatter()
+
+	statuses, err := lifecycleService.CheckBuckets(ctx, types.ListOptions{BucketName: bucketName, Region: a.scanRegion()})
+	if err != nil {
+		return fmt.Errorf("failed to check lifecycle rules: %w", err)
+	}
+	// The output lists them already, but --fail-on-errors must see them too
+	a.partialListing(unlistedBuckets(statuses))
+
+	// Buckets that could not be read are kept, since their coverage is unknown
+	if missingOnly {
+		missing := make([]types.LifecycleStatus, 0, len(statuses))
+		for _, status := range statuses {
+			if !status.Covered() {
+				missing = append(missing, status)
+			}
+		}
+		statuses = missing
+	}
+
+	if jsonOutput {
+		if statuses == nil {
+			statuses = []types.LifecycleStatus{}
+		}
+		jsonStr, err := formatter.FormatJSON(statuses)
+		if err != nil {
+			return fmt.Errorf("failed to format JSON output: %w", err)
+		}
+		cmd.Println(jsonStr)
+		return nil
+	}
+
+	if missingOnly && len(statuses) == 0 {
+		cmd.Println("Every bucket has an enabled AbortIncompleteMultipartUpload rule.")
+		return nil
+	}
+
+	cmd.Print(formatter.FormatLifecycleStatus(statuses))
+	return nil
+}
+
+func (a *App) runLifecycleApplyCommand(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	days, _ := cmd.Flags().GetInt32("days")
+	bucketName, _ := cmd.Flags().GetString("bucket")
+	allMissing, _ := cmd.Flags().GetBool("all-missing")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	force, _ := cmd.Flags().GetBool("force")
+
+	lifecycleService := a.container.GetLifecycleService()
+	formatter := a.container.GetOutputFormatter()
+
+	changes, err := lifecycleService.PlanAbortRule(ctx, types.LifecycleApplyOptions{
+		Days:       days,
+		BucketName: bucketName,
+		AllMissing: allMissing,
+		Region:     a.scanRegion(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to plan lifecycle changes: %w", err)
+	}
+
+	cmd.Print(formatter.FormatLifecyclePlan(changes))
+
+	pending := 0
+	for _, change := range changes {
+		if change.Pending() {
+			pending++
+		}
+	}
+
+	if dryRun {
+		cmd.Printf("\nDry run: %d buckets would be updated.\n", pending)
+		return nil
+	}
+	if pending == 0 {
+		cmd.Println("\nNo buckets need updating.")
+		return nil
+	}
+
+	if !force {
+		cmd.Println()
+		confirmed, err := a.prompter(cmd).Confirm(fmt.Sprintf("Write the lifecycle rule to %d buckets?", pending))
+		if err != nil {
+			return fmt.Errorf("failed to get confirmation: %w", err)
+		}
+		if !confirmed {
+			cmd.Println("Lifecycle update cancelled.")
+			return nil
+		}
+	}
+
+	result, err := lifecycleService.ApplyAbortRule(ctx, changes)
+	if err != nil {
+		return fmt.Errorf("failed to apply lifecycle rule: %w", err)
+	}
+
+	cmd.Print("\n" + formatter.FormatLifecycleApplyResult(*result))
+	if result.Failed > 0 {
+		return fmt.Errorf("failed to update %d of %d buckets", result.Failed, result.Failed+result.Applied)
+	}
+	return nil
+}
+
+// unlistedBuckets returns the buckets whose uploads the check could not
+// count, as the errors of a partial listing
+func unlistedBuckets(statuses []types.LifecycleStatus) types.BucketErrors {
+	var failed types.BucketErrors
+	for _, status := range statuses {
+		if status.ListError != "" {
+			failed = append(failed, types.BucketError{Bucket: status.Bucket, Region: status.Region, Err: errors.New(status.ListError)})
+		}
+	}
+	return failed
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func (a *App) addMetricsCommand() {
+	cmd := &cobra.Command{
+		Use:   "metrics",
+		Short: "Print upload metrics in the OpenMetrics (Prometheus) text format",
+		Long: `Metrics scans uploads the same way as stats and prints the totals as
+OpenMetrics gauges, labelled by bucket, region and storage class:
+
+  s3mpc_incomplete_uploads_total
+  s3mpc_incomplete_bytes
+  s3mpc_incomplete_monthly_cost_usd
+  s3mpc_oldest_upload_age_seconds (labelled by bucket only)
+
+With --write-to the output replaces the file atomically, so node_exporter's
+textfile collector never reads a partial file.`,
+		Example: `  s3mpc metrics
+  s3mpc metrics --write-to /var/lib/node_exporter/textfile/s3mpc.prom`,
+		RunE: a.runMetricsCommand,
+	}
+	cmd.Flags().String("write-to", "", "Atomically write the metrics to this file instead of standard output")
+	cmd.Flags().Bool("live-pricing", false, "Query the AWS Pricing API (cached in ~/.s3mpc/pricing.json) instead of built-in prices")
+	cmd.Flags().String("pricing-file", "", "YAML file with region -> storage class -> price per GB-month overrides")
+	cmd.Flags().String("cost-provider", "", "Program that returns prices, as exec:/path/to/program (see s3mpc help performance)")
+	addFromFileFlag(cmd)
+	a.rootCmd.AddCommand(cmd)
+}
+
+func (a *App) runMetricsCommand(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	writeTo, _ := cmd.Flags().GetString("write-to")
+
+	reportService := a.container.GetReportService()
+	formatter := a.container.GetMetricsFormatter()
+
+	var report *types.MetricsReport
+	uploads, offline, err := a.loadOfflineUploads(cmd)
+	if err != nil {
+		return err
+	}
+	if offline {
+		report, err = reportService.MetricsForUploads(ctx, uploads, nil)
+	} else {
+		report, err = reportService.GenerateMetrics(ctx, a.listOptions(cmd, ""))
+		err = a.partialListing(err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate metrics: %w", err)
+	}
+	a.warnBucketErrors(cmd)
+
+	output := formatter.FormatOpenMetrics(*report)
+
+	if writeTo == "" {
+		fmt.Fprint(cmd.OutOrStdout(), output)
+		return nil
+	}
+
+	if err := writeFileAtomic(writeTo, []byte(output)); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	cmd.Printf("Wrote %d series to %s\n", len(report.Series), writeTo)
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file in the target directory and
+// renames it over path, so readers see either the old or the new content
+func writeFileAtomic(path string, data []byte) error {
+	// The textfile collector only reads *.prom, so the temporary name must not match
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// addCheckObjectsFlag registers --check-objects on commands that can flag superseded uploads
+func addCheckObjectsFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("check-objects", false, "Call HeadObject on each upload's key to flag uploads superseded by a newer completed object (one request per key)")
+}
+
+// checkingObjects reports whether --check-objects was given, rejecting a
+// superseded filter without it, since no upload would ever match
+func checkingObjects(cmd *cobra.Command, filter interfaces.Filter) (bool, error) {
+	check, _ := cmd.Flags().GetBool("check-objects")
+	if len(filter.Superseded) > 0 && !check {
+		return false, fmt.Errorf("the superseded filter needs --check-objects")
+	}
+	return check, nil
+}
+
+// filterCheckingObjects applies filter to uploads. With --check-objects only
+// the uploads matching its other conditions are checked, so objects are read
+// just for the uploads that can be shown.
+func (a *App) filterCheckingObjects(cmd *cobra.Command, uploads []types.MultipartUpload, filter interfaces.Filter, check bool) ([]types.MultipartUpload, error) {
+	filterEngine := a.container.GetFilterEngine()
+	if !check {
+		return filterEngine.ApplyFilter(uploads, filter), nil
+	}
+
+	superseded := filter.Superseded
+	filter.Superseded = nil
+	uploads, err := a.checkObjects(cmd, filterEngine.ApplyFilter(uploads, filter))
+	if err != nil {
+		return nil, err
+	}
+	return filterEngine.ApplyFilter(uploads, interfaces.Filter{Superseded: superseded}), nil
+}
+
+// checkObjects marks the uploads superseded by a newer completed object,
+// warning on stderr about the buckets whose objects could not be read
+func (a *App) checkObjects(cmd *cobra.Command, uploads []types.MultipartUpload) ([]types.MultipartUpload, error) {
+	service := a.container.GetObjectCheckService()
+	uploads, unchecked, err := service.MarkSuperseded(cmd.Context(), uploads)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check objects: %w", err)
+	}
+	a.container.GetLogger().Debug("Checked objects at upload keys", service.GetCacheStats())
+
+	if len(unchecked) > 0 {
+		names := make([]string, len(unchecked))
+		for i, bucket := range unchecked {
+			names[i] = types.DisplayString(bucket)
+		}
+		cmd.PrintErrf("Warning: could not check objects in %s, so their uploads are not marked superseded\n", strings.Join(names, ", "))
+	}
+	return uploads, nil
+}
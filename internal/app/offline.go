@@ -0,0 +1,43 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// addFromFileFlag registers --from-file on commands that can analyze saved uploads
+func addFromFileFlag(cmd *cobra.Command) {
+	cmd.Flags().String("from-file", "", "Analyze uploads from an export, inventory or saved dry-run report instead of scanning S3")
+}
+
+// loadOfflineUploads loads uploads from --from-file and prints the offline banner.
+// It returns false when the flag is not set and uploads should be listed from S3.
+func (a *App) loadOfflineUploads(cmd *cobra.Command) ([]types.MultipartUpload, bool, error) {
+	filename, _ := cmd.Flags().GetString("from-file")
+	if filename == "" {
+		return nil, false, nil
+	}
+
+	file, err := services.LoadUploadsFile(filename)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to load --from-file: %w", err)
+	}
+
+	generatedAt := "an unknown time"
+	if !file.GeneratedAt.IsZero() {
+		generatedAt = types.FormatTimestamp(file.GeneratedAt)
+	}
+	if quiet, _ := cmd.Flags().GetBool("quiet"); !quiet {
+		if file.Incomplete {
+			cmd.Printf("Note: %s ends early, as written by a run that was killed; using the uploads it lists in full\n", filepath.Base(filename))
+		}
+		cmd.Printf("Offline data from %s (%s %s, %d uploads)\n\n", generatedAt, file.Kind, filepath.Base(filename), len(file.Uploads))
+	}
+
+	return selectKeyPrefix(file.Uploads, keyPrefix(cmd)), true, nil
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Output formats of --output
+const (
+	outputText     = "text"
+	outputJSON     = "json"
+	outputMarkdown = "markdown"
+)
+
+// outputFormatsAnnotation records the formats a command's --output accepts
+const outputFormatsAnnotation = "s3mpc_output_formats"
+
+// addOutputFlag registers --output with the formats the command supports
+func addOutputFlag(cmd *cobra.Command, formats ...string) {
+	cmd.Flags().String("output", outputText, "Output format: "+strings.Join(formats, ", ")+" (markdown writes tables for tickets and wikis)")
+	_ = cmd.Flags().SetAnnotation("output", outputFormatsAnnotation, formats)
+}
+
+// writesJSON reports whether the command writes JSON to stdout, so that
+// nothing else, such as a text progress line, goes to the terminal with it
+func writesJSON(cmd *cobra.Command) bool {
+	if flag := cmd.Flags().Lookup("output"); flag != nil && flag.Annotations[outputFormatsAnnotation] != nil {
+		format, err := outputFormat(cmd)
+		return err == nil && format == outputJSON
+	}
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	return jsonOutput
+}
+
+// outputFormat returns the --output format; --json is the same as --output json
+func outputFormat(cmd *cobra.Command) (string, error) {
+	format, _ := cmd.Flags().GetString("output")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	if jsonOutput {
+		if cmd.Flags().Changed("output") && format != outputJSON {
+			return "", fmt.Errorf("--json cannot be used with --output %s", format)
+		}
+		return outputJSON, nil
+	}
+
+	formats := cmd.Flags().Lookup("output").Annotations[outputFormatsAnnotation]
+	for _, supported := range formats {
+		if format == supported {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("invalid --output %q (must be %s)", format, strings.Join(formats, ", "))
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestListMarkdownOutput(t *testing.T) {
+	initiated := time.Now().Add(-72 * time.Hour)
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "app/__tmp__/a.gz", UploadID: "u1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "app/b.gz", UploadID: "u2", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+	parts := map[string]int{"app/__tmp__/a.gz": 1, "app/b.gz": 2}
+
+	out := runPartsList(t, uploads, parts, "--output", "markdown", "--filter", "size>0", "--sort-by", "size")
+	for _, want := range []string{
+		"```sh\ns3mpc list --filter='size>0' --output=markdown --sort-by=size\n```",
+		"| Bucket | Key | Size | Age | Storage Class | Region |",
+		"| `logs` | `app/__tmp__/a.gz` | 1.0 MB | 3d | STANDARD | us-east-1 |",
+		"| **Total** (2 uploads) |  | 3.0 MB |  |  |  |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("list --output markdown output = %q, want it to contain %q", out, want)
+		}
+	}
+	if strings.Index(out, "app/b.gz") > strings.Index(out, "app/__tmp__/a.gz") {
+		t.Errorf("list --output markdown output = %q, want the sort order kept", out)
+	}
+
+	// The size column and total need the uploads measured without a filter too
+	out = runPartsList(t, uploads, parts, "--output", "markdown")
+	if want := "| **Total** (2 uploads) |  | 3.0 MB |  |  |  |"; !strings.Contains(out, want) {
+		t.Errorf("list --output markdown output = %q, want it to contain %q", out, want)
+	}
+}
+
+func TestOutputFlagValidation(t *testing.T) {
+	cmd := &cobra.Command{Use: "size"}
+	cmd.Flags().Bool("json", false, "")
+	addOutputFlag(cmd, outputText, outputJSON, outputMarkdown)
+
+	for _, tt := range []struct {
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{nil, outputText, ""},
+		{[]string{"--json"}, outputJSON, ""},
+		{[]string{"--output", "markdown"}, outputMarkdown, ""},
+		{[]string{"--json", "--output", "json"}, outputJSON, ""},
+		{[]string{"--json", "--output", "markdown"}, "", "--json cannot be used with --output markdown"},
+		{[]string{"--output", "html"}, "", `invalid --output "html" (must be text, json, markdown)`},
+	} {
+		_ = cmd.Flags().Set("json", "false")
+		_ = cmd.Flags().Set("output", outputText)
+		for _, flag := range []string{"json", "output"} {
+			cmd.Flags().Lookup(flag).Changed = false
+		}
+		if err := cmd.ParseFlags(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		got, err := outputFormat(cmd)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("outputFormat(%v) error = %v, want %q", tt.args, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("outputFormat(%v) = %q, %v, want %q", tt.args, got, err, tt.want)
+		}
+	}
+
+	uploads := []types.MultipartUpload{{Bucket: "logs", Key: "a", UploadID: "1", Initiated: time.Now().Add(-48 * time.Hour), StorageClass: "STANDARD", Region: "us-east-1"}}
+	if _, err := runDryRunDelete(t, uploads, "--force", "--output", "markdown"); err == nil || !strings.Contains(err.Error(), "only be used with --dry-run") {
+		t.Errorf("delete --output markdown without --dry-run error = %v, want it refused", err)
+	}
+	if _, err := runDryRunDelete(t, uploads, "--dry-run", "--output", "json"); err == nil || !strings.Contains(err.Error(), "must be text, markdown") {
+		t.Errorf("delete --output json error = %v, want it refused", err)
+	}
+}
+
+func TestWritesJSON(t *testing.T) {
+	newCmd := func(output bool) *cobra.Command {
+		cmd := &cobra.Command{Use: "size"}
+		cmd.Flags().Bool("json", false, "")
+		if output {
+			addOutputFlag(cmd, outputText, outputJSON, outputMarkdown)
+		} else {
+			// export's --output is a file name
+			cmd.Flags().String("output", "", "")
+		}
+		return cmd
+	}
+
+	for _, tt := range []struct {
+		output bool
+		args   []string
+		want   bool
+	}{
+		{true, nil, false},
+		{true, []string{"--json"}, true},
+		{true, []string{"--output", "json"}, true},
+		{true, []string{"--output", "markdown"}, false},
+		{true, []string{"--json", "--output", "markdown"}, false},
+		{false, []string{"--output", "json"}, false},
+		{false, []string{"--json"}, true},
+	} {
+		cmd := newCmd(tt.output)
+		if err := cmd.ParseFlags(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if got := writesJSON(cmd); got != tt.want {
+			t.Errorf("writesJSON(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// offlineCommands never call AWS, or check the credentials themselves.
+// Completion requests only call AWS for flag values that need it.
+var offlineCommands = map[string]bool{
+	"version":                       true,
+	"config":                        true,
+	"cache":                         true,
+	"explain":                       true,
+	"doctor":                        true,
+	"help":                          true,
+	"completion":                    true,
+	cobra.ShellCompRequestCmd:       true,
+	cobra.ShellCompNoDescRequestCmd: true,
+}
+
+// preflight checks the credentials once before a command that calls AWS runs,
+// rather than letting it fail on every bucket
+func (a *App) preflight(cmd *cobra.Command) error {
+	if a.container == nil || !a.needsPreflight(cmd) {
+		return nil
+	}
+	return a.container.CheckCredentials(cmd.Context())
+}
+
+// needsPreflight reports whether cmd calls AWS with the configured
+// credentials, so that they should be checked before it runs
+func (a *App) needsPreflight(cmd *cobra.Command) bool {
+	if skip, _ := cmd.Flags().GetBool("skip-preflight"); skip {
+		return false
+	}
+	if cmd == a.rootCmd {
+		return false
+	}
+	top := cmd
+	for top.Parent() != nil && top.Parent() != a.rootCmd {
+		top = top.Parent()
+	}
+	if offlineCommands[top.Name()] || isHelpTopic(top.Name()) {
+		return false
+	}
+
+	// Saved uploads are analyzed offline; delete --from-file still aborts them in S3
+	if fromFile, _ := cmd.Flags().GetString("from-file"); fromFile != "" && cmd.Name() != "delete" {
+		return false
+	}
+	if cmd.Flags().Lookup("live") != nil {
+		live, _ := cmd.Flags().GetBool("live")
+		return live
+	}
+	// Multi-account runs report each account's credential errors as they scan it
+	if profiles, _ := cmd.Flags().GetStringSlice("profiles"); len(profiles) > 0 {
+		return false
+	}
+	if accountsFile, _ := cmd.Flags().GetString("accounts-file"); accountsFile != "" {
+		return false
+	}
+	return true
+}
+
+// isHelpTopic reports whether name is one of the help topics, which only
+// print help
+func isHelpTopic(name string) bool {
+	for _, topic := range helpTopics() {
+		if topic.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
+)
+
+func TestNeedsPreflight(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"list"}, true},
+		{[]string{"delete", "--older-than", "7d"}, true},
+		{[]string{"delete", "--from-file", "review.json"}, true},
+		{[]string{"lifecycle", "check"}, true},
+		{[]string{"snapshot", "diff", "old.json", "--live"}, true},
+		{[]string{"list", "--skip-preflight"}, false},
+		{[]string{"size", "--from-file", "export.json"}, false},
+		{[]string{"snapshot", "diff", "old.json", "new.json"}, false},
+		{[]string{"size", "--profiles", "prod,staging"}, false},
+		{[]string{"doctor"}, false},
+		{[]string{"cache", "clear"}, false},
+		{[]string{"version"}, false},
+		{[]string{"help"}, false},
+		{[]string{"help", "list"}, false},
+		{[]string{"filters"}, false},
+		{[]string{"completion", "bash"}, false},
+		{[]string{cobra.ShellCompRequestCmd, "list", "--bucket", ""}, false},
+		{[]string{cobra.ShellCompNoDescRequestCmd, "delete", ""}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		a := NewApp("test")
+		// cobra adds these when the root command runs
+		a.rootCmd.InitDefaultHelpCmd()
+		a.rootCmd.InitDefaultCompletionCmd()
+		for _, name := range []string{cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd} {
+			a.rootCmd.AddCommand(&cobra.Command{Use: name, DisableFlagParsing: true, Run: func(*cobra.Command, []string) {}})
+		}
+		cmd, args, err := a.rootCmd.Find(tt.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatal(err)
+		}
+		if got := a.needsPreflight(cmd); got != tt.want {
+			t.Errorf("needsPreflight(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCredentialsErrorExitsWithAWSError(t *testing.T) {
+	err := awsclient.ClassifyCredentialsError(errors.New("get credentials: failed to refresh cached credentials, no EC2 IMDS role found"))
+	if code := ExitCode(err); code != ExitAWSError {
+		t.Errorf("ExitCode(%v) = %d, want %d", err, code, ExitAWSError)
+	}
+}
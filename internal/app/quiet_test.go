@@ -0,0 +1,92 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/internal/config"
+	"github.com/Garvitkul/s3mpc/internal/container"
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// listedUploadService serves fixed uploads and sizes and delegates deletion
+type listedUploadService struct {
+	interfaces.UploadService
+	uploads []types.MultipartUpload
+}
+
+func (s *listedUploadService) ListUploads(ctx context.Context, opts types.ListOptions) ([]types.MultipartUpload, error) {
+	return s.uploads, nil
+}
+
+func (s *listedUploadService) SkippedBuckets() []types.SkippedBucket {
+	return nil
+}
+
+func (s *listedUploadService) GetUploadDetail(ctx context.Context, upload types.MultipartUpload) (types.UploadDetail, error) {
+	return types.UploadDetail{Size: 1 << 20, PartsCount: 1}, nil
+}
+
+func (s *listedUploadService) GetUploadDetailWithParts(ctx context.Context, upload types.MultipartUpload) (types.UploadDetail, error) {
+	lastModified := upload.Initiated.Add(time.Minute).UTC()
+	return types.UploadDetail{Size: 1 << 20, PartsCount: 1, Parts: []types.UploadPart{{PartNumber: 1, Size: 1 << 20, ETag: `"etag-` + upload.UploadID + `"`, LastModified: lastModified}}}, nil
+}
+
+func TestQuietDeleteDryRun(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AWSRegion = "us-east-1"
+	cfg.Quiet = true
+	c, err := container.NewContainer(cfg)
+	if err != nil {
+		t.Fatalf("NewContainer() error = %v", err)
+	}
+
+	var stdout bytes.Buffer
+	initiated := time.Now().Add(-72 * time.Hour)
+	uploadService := &listedUploadService{
+		UploadService: services.NewUploadServiceWithOptions(nil, nil, services.NewDryRunService(services.NewCostService()), 1,
+			services.NewConsoleProgressReporter(&stdout, true), nil, &stdout, nil, nil),
+		uploads: []types.MultipartUpload{
+			{Bucket: "logs", Key: "a.tmp", UploadID: "1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+			{Bucket: "media", Key: "b.tmp", UploadID: "2", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		},
+	}
+	c.SetUploadService(uploadService)
+	c.SetSizeService(services.NewSizeService(uploadService))
+
+	a := NewApp("test")
+	a.container = c
+	cmd, _, err := a.rootCmd.Find([]string{"delete"})
+	if err != nil {
+		t.Fatalf("delete not registered: %v", err)
+	}
+	var stderr bytes.Buffer
+	cmd.SetOut(&stderr)
+	cmd.SetErr(&stderr)
+	if err := cmd.ParseFlags([]string{"--dry-run", "--quiet"}); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+
+	if err := a.runDeleteCommand(cmd, nil); err != nil {
+		t.Fatalf("delete --dry-run --quiet error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	want := []string{"Total uploads that would be deleted: 2", "Total storage that would be freed: 2.0 MB", "Estimated monthly savings: "}
+	if len(lines) != len(want) {
+		t.Fatalf("stdout has %d lines, want only the %d totals:\n%s", len(lines), len(want), stdout.String())
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Errorf("stdout line %d = %q, want prefix %q", i+1, lines[i], prefix)
+		}
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("quiet dry-run wrote informational output:\n%s", stderr.String())
+	}
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// addRetryFromFlag registers --retry-from on delete
+func addRetryFromFlag(cmd *cobra.Command) {
+	cmd.Flags().String("retry-from", "", "Retry only the failed deletions in a JSON deletion report (or the uploads of a failure or dry-run report), skipping uploads S3 no longer has")
+}
+
+// loadRetryUploads loads the uploads to retry from --retry-from and keeps
+// those S3 still has. The others are recorded in the deletion report, if
+// any, as already cleaned. It returns false when the flag is not set.
+func (a *App) loadRetryUploads(ctx context.Context, cmd *cobra.Command, report *services.DeletionReportWriter) ([]types.MultipartUpload, bool, error) {
+	filename, _ := cmd.Flags().GetString("retry-from")
+	if filename == "" {
+		return nil, false, nil
+	}
+
+	file, err := services.LoadUploadsFile(filename)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to load --retry-from: %w", err)
+	}
+	switch file.Kind {
+	case services.UploadsFileDeletions, services.UploadsFileFailures, services.UploadsFileDryRun:
+	default:
+		return nil, true, fmt.Errorf("--retry-from needs a deletion, failure or dry-run report, but %s is an %s; use --from-file to delete its uploads", filename, file.Kind)
+	}
+
+	uploads := selectKeyPrefix(file.Uploads, keyPrefix(cmd))
+	remaining, cleaned, err := a.container.GetUploadService().FindRemainingUploads(ctx, uploads)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to check the uploads in %s: %w", filename, err)
+	}
+	if report != nil {
+		for _, upload := range cleaned {
+			report.RecordAlreadyCleaned(upload)
+		}
+	}
+
+	if quiet, _ := cmd.Flags().GetBool("quiet"); !quiet {
+		cmd.Printf("Retrying %d uploads from %s (%s, %d already cleaned)\n\n", len(remaining), file.Kind, filepath.Base(filename), len(cleaned))
+	}
+	return remaining, true, nil
+}
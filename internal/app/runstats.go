@@ -0,0 +1,134 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
+)
+
+// runStatsCaches are the bucket service caches --stats reports, by the
+// GetCacheStats counter prefix and the name they are reported under
+var runStatsCaches = []struct{ prefix, name string }{
+	{"bucket_list", "bucket_list"},
+	{"region", "regions"},
+	{"tag", "tags"},
+}
+
+// runStats is the block --stats prints when a command ends
+type runStats struct {
+	WallTimeMS      int64                   `json:"wall_time_ms"`
+	Calls           int64                   `json:"calls"`
+	Retries         int64                   `json:"retries"`
+	Throttled       int64                   `json:"throttled"`
+	Failed          int64                   `json:"failed"`
+	RateLimitWaitMS int64                   `json:"rate_limit_wait_ms"`
+	Operations      []operationRunStats     `json:"operations"`
+	Caches          map[string]cacheHitRate `json:"caches"`
+}
+
+// operationRunStats are the calls made to one S3 API operation
+type operationRunStats struct {
+	Operation    string  `json:"operation"`
+	Calls        int64   `json:"calls"`
+	Retries      int64   `json:"retries"`
+	Throttled    int64   `json:"throttled"`
+	Failed       int64   `json:"failed"`
+	AvgLatencyMS float64 `json:"avg_latency_ms"` // per attempt
+}
+
+// cacheHitRate counts the lookups one cache answered and missed
+type cacheHitRate struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"` // 0 to 1; 0 without lookups
+}
+
+// newRunStats builds the --stats block from the API calls and cache counters
+func newRunStats(wall time.Duration, api awsclient.APIStats, cacheCounts map[string]int64) runStats {
+	stats := runStats{
+		WallTimeMS:      wall.Milliseconds(),
+		Calls:           api.Calls,
+		Retries:         api.Retries,
+		Throttled:       api.Throttled,
+		Failed:          api.Failed,
+		RateLimitWaitMS: api.RateLimitWait.Milliseconds(),
+		Operations:      []operationRunStats{},
+		Caches:          make(map[string]cacheHitRate),
+	}
+	for _, op := range api.Operations {
+		opStats := operationRunStats{
+			Operation: op.Operation,
+			Calls:     op.Calls,
+			Retries:   op.Retries,
+			Throttled: op.Throttled,
+			Failed:    op.Failed,
+		}
+		if attempts := op.Calls + op.Retries; attempts > 0 {
+			opStats.AvgLatencyMS = float64(op.Latency) / float64(time.Millisecond) / float64(attempts)
+		}
+		stats.Operations = append(stats.Operations, opStats)
+	}
+	for _, cache := range runStatsCaches {
+		rate := cacheHitRate{Hits: cacheCounts[cache.prefix+"_hits"], Misses: cacheCounts[cache.prefix+"_misses"]}
+		if lookups := rate.Hits + rate.Misses; lookups > 0 {
+			rate.HitRate = float64(rate.Hits) / float64(lookups)
+		}
+		stats.Caches[cache.name] = rate
+	}
+	return stats
+}
+
+// printRunStats writes the --stats block of the command that ran to stderr,
+// as JSON when the command wrote JSON. Commands that never set up AWS
+// clients, such as help, print nothing.
+func (a *App) printRunStats(cmd *cobra.Command) {
+	if cmd == nil || a.container == nil || a.runStart.IsZero() {
+		return
+	}
+	if enabled, _ := cmd.Flags().GetBool("stats"); !enabled {
+		return
+	}
+	stats := newRunStats(time.Since(a.runStart), a.container.GetAPIStats(), a.container.GetCacheCounts())
+	out := a.rootCmd.ErrOrStderr()
+	if writesJSON(cmd) {
+		data, err := json.Marshal(map[string]runStats{"stats": stats})
+		if err != nil {
+			fmt.Fprintf(out, "Warning: failed to format --stats: %v\n", err)
+			return
+		}
+		fmt.Fprintln(out, string(data))
+		return
+	}
+	writeRunStats(out, stats)
+}
+
+// writeRunStats writes the --stats block as text
+func writeRunStats(out io.Writer, stats runStats) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\nRun stats (wall time %s)\n", time.Duration(stats.WallTimeMS)*time.Millisecond)
+	fmt.Fprintf(&b, "  S3 API calls: %d (%d retries, %d throttled, %d failed), rate limiter wait %s\n",
+		stats.Calls, stats.Retries, stats.Throttled, stats.Failed, time.Duration(stats.RateLimitWaitMS)*time.Millisecond)
+	for _, op := range stats.Operations {
+		fmt.Fprintf(&b, "    %-26s %6d calls %5d retries %5d throttled %5d failed  avg %.1fms\n",
+			op.Operation, op.Calls, op.Retries, op.Throttled, op.Failed, op.AvgLatencyMS)
+	}
+	b.WriteString("  Cache hits:")
+	for i, cache := range runStatsCaches {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		rate := stats.Caches[cache.name]
+		fmt.Fprintf(&b, " %s %d/%d", strings.ReplaceAll(cache.name, "_", " "), rate.Hits, rate.Hits+rate.Misses)
+		if rate.Hits+rate.Misses > 0 {
+			fmt.Fprintf(&b, " (%.0f%%)", rate.HitRate*100)
+		}
+	}
+	b.WriteString("\n")
+	fmt.Fprint(out, b.String())
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/internal/config"
+	"github.com/Garvitkul/s3mpc/internal/container"
+	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
+)
+
+func TestRunStatsBlock(t *testing.T) {
+	api := awsclient.APIStats{
+		Operations: []awsclient.OperationStats{
+			{Operation: "ListMultipartUploads", Calls: 12, Retries: 3, Throttled: 2, Latency: 300 * time.Millisecond},
+			{Operation: "ListParts", Calls: 40, Failed: 1, Latency: 400 * time.Millisecond},
+		},
+		Calls: 52, Retries: 3, Throttled: 2, Failed: 1,
+		RateLimitWait: 1500 * time.Millisecond,
+	}
+	counts := map[string]int64{"bucket_list_hits": 2, "bucket_list_misses": 1, "region_hits": 9, "region_misses": 1}
+	stats := newRunStats(2345*time.Millisecond, api, counts)
+
+	if got := stats.Operations[0].AvgLatencyMS; got != 20 {
+		t.Errorf("ListMultipartUploads AvgLatencyMS = %v, want 20 over 15 attempts", got)
+	}
+	if got := stats.Caches["regions"]; got.HitRate != 0.9 {
+		t.Errorf("regions cache = %+v, want a 0.9 hit rate", got)
+	}
+	if got := stats.Caches["tags"]; got != (cacheHitRate{}) {
+		t.Errorf("tags cache = %+v, want no lookups", got)
+	}
+
+	var out bytes.Buffer
+	writeRunStats(&out, stats)
+	for _, want := range []string{
+		"Run stats (wall time 2.345s)",
+		"S3 API calls: 52 (3 retries, 2 throttled, 1 failed), rate limiter wait 1.5s",
+		"ListParts                      40 calls",
+		"avg 10.0ms",
+		"Cache hits: bucket list 2/3 (67%), regions 9/10 (90%), tags 0/0\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("stats block missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunStatsFollowOutputFormat(t *testing.T) {
+	c, err := container.NewContainer(config.DefaultConfig())
+	if err != nil {
+		t.Fatalf("NewContainer() error = %v", err)
+	}
+	a := NewApp("test")
+	a.container = c
+	a.runStart = time.Now()
+	cmd, _, err := a.rootCmd.Find([]string{"list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stderr bytes.Buffer
+	a.rootCmd.SetErr(&stderr)
+
+	// Without --stats nothing is printed
+	a.printRunStats(cmd)
+	if stderr.Len() != 0 {
+		t.Fatalf("printed without --stats: %q", stderr.String())
+	}
+
+	if err := cmd.ParseFlags([]string{"--stats", "--output", "json"}); err != nil {
+		t.Fatal(err)
+	}
+	a.printRunStats(cmd)
+	var parsed struct {
+		Stats struct {
+			Calls      *int64                  `json:"calls"`
+			Operations []operationRunStats     `json:"operations"`
+			Caches     map[string]cacheHitRate `json:"caches"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal(stderr.Bytes(), &parsed); err != nil {
+		t.Fatalf("--stats with --output json is not JSON: %v\n%s", err, stderr.String())
+	}
+	if parsed.Stats.Calls == nil || parsed.Stats.Operations == nil || len(parsed.Stats.Caches) != 3 {
+		t.Errorf("--stats JSON = %s, want calls, operations and three caches", stderr.String())
+	}
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// maxSkippedBucketNames is how many skipped buckets the end-of-run warning names
+const maxSkippedBucketNames = 5
+
+// skippedBuckets returns the buckets this run could not scan because their
+// region, or the tags they were selected by, could not be determined, in
+// every account scanned
+func (a *App) skippedBuckets() []types.SkippedBucket {
+	if a.container == nil {
+		return nil
+	}
+	skipped := append([]types.SkippedBucket(nil), a.container.GetUploadService().SkippedBuckets()...)
+	skipped = append(skipped, a.accountSkipped...)
+	return append(skipped, a.offlineSkipped...)
+}
+
+// printSkippedBuckets adds the buckets that could not be scanned to the output
+func (a *App) printSkippedBuckets(cmd *cobra.Command, skipped []types.SkippedBucket) {
+	if len(skipped) > 0 {
+		cmd.Println()
+		cmd.Print(services.FormatSkippedBuckets(skipped))
+	}
+}
+
+// warnSkippedBuckets summarizes the skipped buckets on stderr, so a report
+// that silently covers fewer buckets than expected is noticed
+func (a *App) warnSkippedBuckets() {
+	if warning := skippedBucketsWarning(a.skippedBuckets()); warning != "" {
+		fmt.Fprint(a.rootCmd.ErrOrStderr(), warning)
+	}
+}
+
+// skippedBucketsWarning returns the end-of-run warning for skipped buckets,
+// or an empty string when every bucket was scanned
+func skippedBucketsWarning(skipped []types.SkippedBucket) string {
+	if len(skipped) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, maxSkippedBucketNames)
+	for _, bucket := range skipped {
+		if len(names) == maxSkippedBucketNames {
+			break
+		}
+		names = append(names, bucket.Name())
+	}
+	list := strings.Join(names, ", ")
+	if more := len(skipped) - len(names); more > 0 {
+		list += fmt.Sprintf(" and %d more", more)
+	}
+
+	noun := "buckets"
+	if len(skipped) == 1 {
+		noun = "bucket"
+	}
+	return fmt.Sprintf("Warning: %d %s could not be scanned: %s (%s failed)\n", len(skipped), noun, list, failedLookups(skipped))
+}
+
+// failedLookups names what could not be looked up for the skipped buckets
+func failedLookups(skipped []types.SkippedBucket) string {
+	var region, tags, directory bool
+	for _, bucket := range skipped {
+		switch bucket.Lookup {
+		case types.SkippedTagLookup:
+			tags = true
+		case types.SkippedDirectoryBucketLookup:
+			directory = true
+		default:
+			region = true
+		}
+	}
+	var lookups []string
+	if region {
+		lookups = append(lookups, "region")
+	}
+	if tags {
+		lookups = append(lookups, "tag")
+	}
+	if directory {
+		lookups = append(lookups, "directory bucket")
+	}
+	if len(lookups) == 0 {
+		return "region lookup"
+	}
+	return strings.Join(lookups, " or ") + " lookup"
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func (a *App) addSnapshotCommand() {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save per-bucket upload totals and compare them over time",
+	}
+
+	saveCmd := &cobra.Command{
+		Use:   "save [file]",
+		Short: "Save upload counts and sizes per bucket, to a file or standard output",
+		Long: `Save scans uploads the same way as stats and writes a compact JSON summary:
+the time it was taken and the number and size of uploads in each bucket.
+Compare two snapshots, or a snapshot with a scan now, with snapshot diff.`,
+		Example: `  s3mpc snapshot save weekly/2024-06-03.json
+  s3mpc snapshot save > snapshot.json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: a.runSnapshotSaveCommand,
+	}
+	addPrefixFlag(saveCmd)
+	addBucketTagFlags(saveCmd)
+	addFromFileFlag(saveCmd)
+	cmd.AddCommand(saveCmd)
+
+	diffCmd := &cobra.Command{
+		Use:   "diff OLD [NEW | --live]",
+		Short: "Show what changed between two snapshots, or since a snapshot with --live",
+		Long: `Diff prints the net change in uploads and size, the buckets that have
+uploads now but had none, the buckets that were cleaned, and the buckets that
+grew the most. Buckets the newer scan could not read are not counted as cleaned.`,
+		Example: `  s3mpc snapshot diff weekly/2024-05-27.json weekly/2024-06-03.json
+  s3mpc snapshot diff weekly/2024-06-03.json --live --json`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: a.runSnapshotDiffCommand,
+	}
+	diffCmd.Flags().Bool("live", false, "Compare OLD with a scan now instead of a second snapshot")
+	diffCmd.Flags().Int("top", 10, "Show at most this many growing buckets (0 shows all)")
+	diffCmd.Flags().Bool("json", false, "Output in JSON format")
+	addPrefixFlag(diffCmd)
+	addBucketTagFlags(diffCmd)
+	cmd.AddCommand(diffCmd)
+
+	a.rootCmd.AddCommand(cmd)
+}
+
+func (a *App) runSnapshotSaveCommand(cmd *cobra.Command, args []string) error {
+	snapshot, err := a.takeSnapshot(cmd)
+	if err != nil {
+		return err
+	}
+
+	jsonStr, err := a.container.GetOutputFormatter().FormatJSON(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to format snapshot: %w", err)
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), jsonStr)
+		return nil
+	}
+
+	if err := writeFileAtomic(args[0], []byte(jsonStr+"\n")); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	cmd.Printf("Saved snapshot of %d uploads in %d buckets to %s\n", snapshot.TotalCount, len(snapshot.Buckets), args[0])
+	return nil
+}
+
+func (a *App) runSnapshotDiffCommand(cmd *cobra.Command, args []string) error {
+	live, _ := cmd.Flags().GetBool("live")
+	top, _ := cmd.Flags().GetInt("top")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	if live == (len(args) == 2) {
+		return fmt.Errorf("give either a NEW snapshot or --live to compare %s with", args[0])
+	}
+	if top < 0 {
+		return fmt.Errorf("--top cannot be negative, got %d", top)
+	}
+
+	from, err := services.LoadSnapshot(args[0])
+	if err != nil {
+		return err
+	}
+	var to *types.Snapshot
+	if live {
+		to, err = a.takeSnapshot(cmd)
+	} else {
+		to, err = services.LoadSnapshot(args[1])
+	}
+	if err != nil {
+		return err
+	}
+	if to.TakenAt.Before(from.TakenAt) {
+		cmd.PrintErrf("Warning: %s is the newer snapshot; showing changes from the older one\n", args[0])
+		from, to = to, from
+	}
+
+	diff := services.DiffSnapshots(*from, *to, top)
+	if jsonOutput {
+		jsonStr, err := a.container.GetOutputFormatter().FormatJSON(diff)
+		if err != nil {
+			return fmt.Errorf("failed to format JSON output: %w", err)
+		}
+		cmd.Println(jsonStr)
+		return nil
+	}
+
+	cmd.Print(services.FormatSnapshotDiff(diff))
+	return nil
+}
+
+// takeSnapshot scans uploads, or loads them with --from-file, and totals them by bucket
+func (a *App) takeSnapshot(cmd *cobra.Command) (*types.Snapshot, error) {
+	reportService := a.container.GetReportService()
+
+	var snapshot *types.Snapshot
+	uploads, offline, err := a.loadOfflineUploads(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if offline {
+		snapshot = reportService.SnapshotForUploads(uploads, nil)
+	} else {
+		snapshot, err = reportService.GenerateSnapshot(cmd.Context(), a.listOptions(cmd, ""))
+		if err = a.partialListing(err); err != nil {
+			return nil, fmt.Errorf("failed to take snapshot: %w", err)
+		}
+		a.warnBucketErrors(cmd)
+	}
+
+	cfg := a.container.GetConfig()
+	snapshot.Version = a.getVersion()
+	snapshot.Profile = cfg.AWSProfile
+	snapshot.Region = cfg.AWSRegion
+	return snapshot, nil
+}
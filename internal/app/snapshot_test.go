@@ -0,0 +1,79 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// runSnapshot runs a snapshot subcommand and returns its output
+func runSnapshot(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	_, stdout, stderr, err := runCommandWithConfig(t, testConfig(), nil, append([]string{"snapshot"}, args...)...)
+	return stdout + stderr, err
+}
+
+// writeInventory writes uploads of size bytes each to a JSON inventory
+func writeInventory(t *testing.T, path string, buckets ...string) {
+	t.Helper()
+	var uploads []types.MultipartUpload
+	for i, bucket := range buckets {
+		uploads = append(uploads, types.MultipartUpload{
+			Bucket:       bucket,
+			Key:          fmt.Sprintf("key-%d", i),
+			UploadID:     fmt.Sprintf("upload-%d", i),
+			Initiated:    time.Now().Add(-48 * time.Hour).UTC(),
+			Size:         1024,
+			StorageClass: "STANDARD",
+			Region:       "us-east-1",
+		})
+	}
+	data, err := json.Marshal(uploads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshotSaveThenDiff(t *testing.T) {
+	dir := t.TempDir()
+	lastWeek, thisWeek := filepath.Join(dir, "last.json"), filepath.Join(dir, "this.json")
+	inventory := filepath.Join(dir, "inventory.json")
+
+	writeInventory(t, inventory, "logs", "tmp")
+	if out, err := runSnapshot(t, "save", lastWeek, "--from-file", inventory); err != nil || !strings.Contains(out, "Saved snapshot of 2 uploads in 2 buckets") {
+		t.Fatalf("snapshot save = %q, %v", out, err)
+	}
+	writeInventory(t, inventory, "logs", "logs", "media")
+	if _, err := runSnapshot(t, "save", thisWeek, "--from-file", inventory); err != nil {
+		t.Fatalf("snapshot save error = %v", err)
+	}
+
+	out, err := runSnapshot(t, "diff", lastWeek, thisWeek, "--json")
+	if err != nil {
+		t.Fatalf("snapshot diff error = %v", err)
+	}
+	var diff types.SnapshotDiff
+	if err := json.Unmarshal([]byte(out), &diff); err != nil {
+		t.Fatalf("snapshot diff --json printed %q: %v", out, err)
+	}
+	if diff.CountChange != 1 || len(diff.NewBuckets) != 1 || len(diff.CleanedBuckets) != 1 || len(diff.TopGrowing) != 1 {
+		t.Errorf("diff = %+v, want media new, tmp cleaned and logs growing", diff)
+	}
+}
+
+func TestSnapshotDiffNeedsOneNewerSide(t *testing.T) {
+	for _, args := range [][]string{{"diff", "old.json"}, {"diff", "old.json", "new.json", "--live"}} {
+		if _, err := runSnapshot(t, args...); err == nil || !strings.Contains(err.Error(), "either a NEW snapshot or --live") {
+			t.Errorf("snapshot %v error = %v, want a usage error", args, err)
+		}
+	}
+}
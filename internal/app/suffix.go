@@ -0,0 +1,64 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// addSuffixFlags registers --suffix, --exclude-suffix and --case-sensitive on
+// commands that select uploads by key
+func addSuffixFlags(cmd *cobra.Command) {
+	cmd.Flags().String("suffix", "", "Only uploads whose key ends with one of these comma-separated suffixes (e.g. .tmp,.partial)")
+	cmd.Flags().String("exclude-suffix", "", "Skip uploads whose key ends with one of these comma-separated suffixes")
+	cmd.Flags().Bool("case-sensitive", false, "Match key suffixes case-sensitively")
+}
+
+// addPrefixFlag registers --prefix on commands that can list part of a bucket
+func addPrefixFlag(cmd *cobra.Command) {
+	cmd.Flags().String("prefix", "", "Only uploads whose key starts with this prefix, matched by S3 (case-sensitive, no wildcards)")
+}
+
+// keyPrefix returns the --prefix value, or "" on commands without the flag
+func keyPrefix(cmd *cobra.Command) string {
+	prefix, _ := cmd.Flags().GetString("prefix")
+	return prefix
+}
+
+// selectKeyPrefix keeps the uploads whose key starts with prefix, for uploads
+// that were not listed from S3 with it
+func selectKeyPrefix(uploads []types.MultipartUpload, prefix string) []types.MultipartUpload {
+	if prefix == "" {
+		return uploads
+	}
+	var selected []types.MultipartUpload
+	for _, upload := range uploads {
+		if strings.HasPrefix(upload.Key, prefix) {
+			selected = append(selected, upload)
+		}
+	}
+	return selected
+}
+
+// keySuffixFilter merges the suffix flags into an existing key filter, which
+// may come from key$= conditions in --filter. It returns nil when no suffix is set.
+func keySuffixFilter(cmd *cobra.Command, existing *types.KeySuffixFilter) *types.KeySuffixFilter {
+	include, _ := cmd.Flags().GetString("suffix")
+	exclude, _ := cmd.Flags().GetString("exclude-suffix")
+	caseSensitive, _ := cmd.Flags().GetBool("case-sensitive")
+
+	merged := &types.KeySuffixFilter{CaseSensitive: caseSensitive}
+	if existing != nil {
+		merged.Include = append(merged.Include, existing.Include...)
+		merged.Exclude = append(merged.Exclude, existing.Exclude...)
+	}
+	merged.Include = append(merged.Include, types.ParseSuffixList(include)...)
+	merged.Exclude = append(merged.Exclude, types.ParseSuffixList(exclude)...)
+
+	if merged.IsEmpty() {
+		return nil
+	}
+	return merged
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// RunTimeoutError reports a run stopped by --timeout. Err is the error the
+// command ended with, if any; whatever it printed before is partial.
+type RunTimeoutError struct {
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *RunTimeoutError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("run stopped after --timeout %s; results are partial", e.Timeout)
+	}
+	return fmt.Sprintf("run stopped after --timeout %s: %v", e.Timeout, e.Err)
+}
+
+func (e *RunTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// startRunTimeout bounds the context of cmd, and so every AWS call it makes,
+// by --timeout
+func (a *App) startRunTimeout(cmd *cobra.Command) {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	if timeout <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	cmd.SetContext(ctx)
+	a.runContext, a.stopRunTimeout, a.runTimeout = ctx, cancel, timeout
+}
+
+// finishRunTimeout releases the run's deadline and wraps err in a
+// RunTimeoutError when the run ran out of time
+func (a *App) finishRunTimeout(err error) error {
+	if a.runContext == nil {
+		return err
+	}
+	timedOut := errors.Is(a.runContext.Err(), context.DeadlineExceeded)
+	a.stopRunTimeout()
+	a.runContext, a.stopRunTimeout = nil, nil
+	if !timedOut {
+		return err
+	}
+	return &RunTimeoutError{Timeout: a.runTimeout, Err: err}
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestRunTimeoutBoundsTheCommand(t *testing.T) {
+	a := NewApp("test")
+	cmd, _, err := a.rootCmd.Find([]string{"list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.ParseFlags([]string{"--timeout", "20ms"}); err != nil {
+		t.Fatal(err)
+	}
+	cmd.SetContext(context.Background())
+
+	a.startRunTimeout(cmd)
+	if _, ok := cmd.Context().Deadline(); !ok {
+		t.Fatal("--timeout set no deadline on the command's context")
+	}
+	<-cmd.Context().Done()
+
+	// Buckets that were not finished are still reported as partial results
+	partial := types.NewBucketErrors([]types.BucketError{{Bucket: "archive", Err: context.DeadlineExceeded}})
+	err = a.finishRunTimeout(partial)
+	var runTimeout *RunTimeoutError
+	if !errors.As(err, &runTimeout) || runTimeout.Timeout != 20*time.Millisecond {
+		t.Fatalf("finishRunTimeout() = %v, want a RunTimeoutError", err)
+	}
+	if code := ExitCode(err); code != ExitPartialFailure {
+		t.Errorf("ExitCode(%v) = %d, want %d", err, code, ExitPartialFailure)
+	}
+	if code := ExitCode(&RunTimeoutError{Timeout: time.Minute}); code != ExitPartialFailure {
+		t.Errorf("ExitCode(timeout without error) = %d, want %d", code, ExitPartialFailure)
+	}
+
+	// A run that finishes in time is left alone
+	b := NewApp("test")
+	quick := &cobra.Command{}
+	quick.Flags().Duration("timeout", time.Hour, "")
+	quick.SetContext(context.Background())
+	b.startRunTimeout(quick)
+	if err := b.finishRunTimeout(nil); err != nil {
+		t.Errorf("finishRunTimeout() = %v for a run that finished in time, want nil", err)
+	}
+}
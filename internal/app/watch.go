@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Garvitkul/s3mpc/internal/term"
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// clearScreen moves the cursor home and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+// watchSettings are the list --watch options
+type watchSettings struct {
+	Enabled    bool
+	Interval   time.Duration
+	UntilEmpty bool
+}
+
+// addWatchFlags registers --watch, --interval and --until-empty
+func addWatchFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("watch", false, "Scan again every --interval and redraw the table with what changed since the last scan, until Ctrl-C")
+	cmd.Flags().Duration("interval", time.Minute, "Time between scans with --watch")
+	cmd.Flags().Bool("until-empty", false, "Watch until no uploads remain, then exit 0 (implies --watch)")
+}
+
+// watchOptions reads and validates the watch flags
+func watchOptions(cmd *cobra.Command) (watchSettings, error) {
+	watch, _ := cmd.Flags().GetBool("watch")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	untilEmpty, _ := cmd.Flags().GetBool("until-empty")
+	settings := watchSettings{Enabled: watch || untilEmpty, Interval: interval, UntilEmpty: untilEmpty}
+	if !settings.Enabled {
+		if cmd.Flags().Changed("interval") {
+			return settings, fmt.Errorf("--interval requires --watch")
+		}
+		return settings, nil
+	}
+
+	if interval <= 0 {
+		return settings, fmt.Errorf("--interval must be positive, got %s", interval)
+	}
+	for _, flag := range []string{"json", "fail-if-empty"} {
+		if set, _ := cmd.Flags().GetBool(flag); set {
+			return settings, fmt.Errorf("--%s cannot be used with --watch", flag)
+		}
+	}
+	return settings, nil
+}
+
+// watchUploads lists and prints uploads every interval, with what changed
+// since the previous scan. On a terminal each scan replaces the last one;
+// otherwise scans are appended. Ctrl-C stops watching without an error.
+func (a *App) watchUploads(cmd *cobra.Command, watch watchSettings,
+	listUploads func(ctx context.Context) ([]types.MultipartUpload, []types.AccountFailure, error),
+	printUploads func(uploads []types.MultipartUpload, failedAccounts []types.AccountFailure) error) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	interactive := isTerminal(cmd.OutOrStdout())
+
+	var previous []types.MultipartUpload
+	for scan := 1; ; scan++ {
+		// Each scan reports its own failed buckets
+		a.bucketErrors = nil
+		uploads, failedAccounts, err := listUploads(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if interactive {
+			cmd.Print(clearScreen)
+		} else if scan > 1 {
+			cmd.Println()
+		}
+		cmd.Printf("Every %s, scan %d at %s (Ctrl-C to stop)\n", watch.Interval, scan, time.Now().Format("15:04:05"))
+		if scan > 1 {
+			cmd.Print(services.FormatUploadDelta(services.DiffUploads(previous, uploads)))
+		}
+		cmd.Println()
+		if err := printUploads(uploads, failedAccounts); err != nil {
+			return err
+		}
+
+		if watch.UntilEmpty && len(uploads) == 0 {
+			return nil
+		}
+		previous = uploads
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watch.Interval):
+		}
+	}
+}
+
+// isTerminal reports whether w is an interactive terminal
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	terminal := term.New(file)
+	defer terminal.Close()
+	return terminal.IsTerminal()
+}
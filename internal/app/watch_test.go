@@ -0,0 +1,108 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/internal/config"
+	"github.com/Garvitkul/s3mpc/internal/container"
+	"github.com/Garvitkul/s3mpc/pkg/services"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// scanningUploadService returns the next scan on each listing, repeating the
+// last one, and measures each upload at its size in the current scan
+type scanningUploadService struct {
+	listedUploadService
+	scans [][]types.MultipartUpload
+	scan  int
+}
+
+func (s *scanningUploadService) ListUploads(ctx context.Context, opts types.ListOptions) ([]types.MultipartUpload, error) {
+	if s.scan < len(s.scans)-1 {
+		s.scan++
+	}
+	return s.scans[s.scan], nil
+}
+
+func (s *scanningUploadService) GetUploadDetail(ctx context.Context, upload types.MultipartUpload) (types.UploadDetail, error) {
+	for _, scanned := range s.scans[s.scan] {
+		if scanned.UploadID == upload.UploadID {
+			return types.UploadDetail{Size: scanned.Size, PartsCount: 1}, nil
+		}
+	}
+	return types.UploadDetail{}, nil
+}
+
+func TestListWatchUntilEmpty(t *testing.T) {
+	initiated := time.Now().Add(-time.Hour)
+	a1 := types.MultipartUpload{Bucket: "logs", Key: "a.gz", UploadID: "1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1", Size: 1 << 20}
+	b := types.MultipartUpload{Bucket: "logs", Key: "b.gz", UploadID: "2", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1", Size: 1 << 20}
+	a2 := a1
+	a2.Size = 3 << 20
+	uploadService := &scanningUploadService{scans: [][]types.MultipartUpload{{a1}, {a2, b}, {b}, {}}, scan: -1}
+
+	cfg := config.DefaultConfig()
+	cfg.AWSRegion = "us-east-1"
+	c, err := container.NewContainer(cfg)
+	if err != nil {
+		t.Fatalf("NewContainer() error = %v", err)
+	}
+	c.SetUploadService(uploadService)
+	c.SetSizeService(services.NewSizeService(uploadService))
+
+	a := NewApp("test")
+	a.container = c
+	cmd, _, err := a.rootCmd.Find([]string{"list"})
+	if err != nil {
+		t.Fatalf("list not registered: %v", err)
+	}
+	var stdout bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stdout)
+	cmd.SetContext(context.Background())
+	if err := cmd.ParseFlags([]string{"--until-empty", "--interval", "1ms"}); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+
+	if err := a.runListCommand(cmd, nil); err != nil {
+		t.Fatalf("list --until-empty error = %v", err)
+	}
+	output := stdout.String()
+	for _, want := range []string{
+		"scan 1 at",
+		"Since last scan: 1 new, 0 completed or aborted, 1 growing, size +3.0 MB\n  + logs/b.gz\n",
+		"Since last scan: 0 new, 1 completed or aborted, 0 growing, size -3.0 MB\n  - logs/a.gz\n",
+		"scan 4 at",
+		"No incomplete multipart uploads found.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("watch output is missing %q:\n%s", want, output)
+		}
+	}
+	// Output is not a terminal, so scans are appended rather than redrawn
+	if strings.Contains(output, clearScreen) {
+		t.Errorf("watch cleared a non-terminal output:\n%s", output)
+	}
+}
+
+func TestWatchOptionsRejectsConflicts(t *testing.T) {
+	for _, args := range [][]string{
+		{"--watch", "--json"},
+		{"--watch", "--fail-if-empty"},
+		{"--watch", "--interval", "0s"},
+		{"--interval", "10s"},
+	} {
+		a := NewApp("test")
+		cmd, _, _ := a.rootCmd.Find([]string{"list"})
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatalf("ParseFlags(%v) error = %v", args, err)
+		}
+		if _, err := watchOptions(cmd); err == nil {
+			t.Errorf("watchOptions(%v) accepted conflicting flags", args)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Package benchutil provides synthetic data and allocation budgets shared by
+// the benchmark suite, so performance changes can be measured objectively.
+package benchutil
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// baseTime anchors synthetic upload times so runs are reproducible
+var baseTime = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	regions        = []string{"us-east-1", "us-west-2", "eu-west-1", "ap-southeast-2"}
+	storageClasses = []string{"STANDARD", "STANDARD_IA", "INTELLIGENT_TIERING", "GLACIER"}
+)
+
+// SyntheticUploads returns n deterministic uploads spread over 100 buckets,
+// several regions and storage classes, ages up to a year and sizes up to 5GB
+func SyntheticUploads(n int) []types.MultipartUpload {
+	uploads := make([]types.MultipartUpload, n)
+	for i := range uploads {
+		uploads[i] = types.MultipartUpload{
+			Bucket:       fmt.Sprintf("bucket-%03d", i%100),
+			Key:          fmt.Sprintf("data/%04d/object-%08d.bin", i%1000, i),
+			UploadID:     fmt.Sprintf("upload-%016x", i),
+			Initiated:    baseTime.Add(-time.Duration(i%365*24) * time.Hour),
+			Size:         int64(i%5120+1) * 1024 * 1024,
+			StorageClass: storageClasses[i%len(storageClasses)],
+			Region:       regions[i%len(regions)],
+		}
+	}
+	return uploads
+}
+
+// CheckAllocsPerUpload fails t when fn allocates more than budget times per
+// upload on average. Budgets live next to the benchmarks they guard; raise one
+// only together with the numbers in benchmarks/baseline.txt.
+func CheckAllocsPerUpload(t testing.TB, name string, uploads int, budget float64, fn func()) {
+	t.Helper()
+
+	perUpload := testing.AllocsPerRun(5, fn) / float64(uploads)
+	if perUpload > budget {
+		t.Errorf("ALLOCATION BUDGET EXCEEDED: %s allocated %.2f times per upload, budget is %.2f", name, perUpload, budget)
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Account is one AWS account scanned in a multi-account run. Credentials come
+// from Profile, or the default chain when it is empty, and RoleARN is assumed
+// with them when set.
+type Account struct {
+	Name            string `yaml:"name"`
+	Profile         string `yaml:"profile"`
+	RoleARN         string `yaml:"role_arn"`
+	ExternalID      string `yaml:"external_id"`
+	RoleSessionName string `yaml:"role_session_name"`
+}
+
+// accountsFile is the layout of an --accounts-file
+type accountsFile struct {
+	Accounts []Account `yaml:"accounts"`
+}
+
+// AccountsFromProfiles returns one account per AWS profile, named after it
+func AccountsFromProfiles(profiles []string) ([]Account, error) {
+	accounts := make([]Account, 0, len(profiles))
+	for _, profile := range profiles {
+		profile = strings.TrimSpace(profile)
+		if profile == "" {
+			return nil, fmt.Errorf("profile names cannot be empty")
+		}
+		accounts = append(accounts, Account{Name: profile, Profile: profile})
+	}
+	return accounts, checkAccountNames(accounts)
+}
+
+// LoadAccountsFile reads the accounts listed in a YAML file
+func LoadAccountsFile(path string) ([]Account, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read accounts file %s: %w", path, err)
+	}
+	return ParseAccountsFile(path, data)
+}
+
+// ParseAccountsFile parses accounts file contents; path is only used in error
+// messages. Accounts without a name are named after their profile, or the
+// account ID in their role ARN.
+func ParseAccountsFile(path string, data []byte) ([]Account, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var file accountsFile
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse accounts file %s: %w", path, err)
+	}
+	if len(file.Accounts) == 0 {
+		return nil, fmt.Errorf("accounts file %s lists no accounts", path)
+	}
+
+	for i := range file.Accounts {
+		account := &file.Accounts[i]
+		if account.Profile == "" && account.RoleARN == "" {
+			return nil, fmt.Errorf("accounts file %s: account %d needs a profile or a role_arn", path, i+1)
+		}
+		if account.Name == "" {
+			account.Name = account.defaultName()
+		}
+	}
+
+	if err := checkAccountNames(file.Accounts); err != nil {
+		return nil, fmt.Errorf("accounts file %s: %w", path, err)
+	}
+	return file.Accounts, nil
+}
+
+// defaultName returns the profile, or the account ID of the role ARN
+func (a Account) defaultName() string {
+	if a.RoleARN != "" {
+		// arn:partition:iam::account-id:role/name
+		if parts := strings.Split(a.RoleARN, ":"); len(parts) == 6 && parts[4] != "" {
+			return parts[4]
+		}
+		return a.RoleARN
+	}
+	return a.Profile
+}
+
+// checkAccountNames rejects accounts that share a name, since uploads are
+// tagged and totaled by account name
+func checkAccountNames(accounts []Account) error {
+	seen := make(map[string]bool, len(accounts))
+	for _, account := range accounts {
+		if seen[account.Name] {
+			return fmt.Errorf("account %q is listed more than once", account.Name)
+		}
+		seen[account.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAccountsFile(t *testing.T) {
+	accounts, err := ParseAccountsFile("accounts.yaml", []byte(`accounts:
+  - profile: prod
+  - name: payments
+    role_arn: arn:aws:iam::111111111111:role/s3mpc-audit
+    external_id: s3mpc
+  - role_arn: arn:aws:iam::222222222222:role/s3mpc-audit
+    profile: org-admin
+`))
+	if err != nil {
+		t.Fatalf("ParseAccountsFile() error = %v", err)
+	}
+
+	names := make([]string, len(accounts))
+	for i, account := range accounts {
+		names[i] = account.Name
+	}
+	if strings.Join(names, ",") != "prod,payments,222222222222" {
+		t.Errorf("account names = %v, want the name, then the profile, then the account ID", names)
+	}
+	if accounts[1].ExternalID != "s3mpc" || accounts[2].Profile != "org-admin" {
+		t.Errorf("accounts = %+v", accounts)
+	}
+}
+
+func TestParseAccountsFileRejectsBadAccounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"empty", "", "lists no accounts"},
+		{"unknown field", "accounts:\n  - profil: prod\n", "line 2: field profil not found"},
+		{"no credentials", "accounts:\n  - name: prod\n", "account 1 needs a profile or a role_arn"},
+		{"duplicate", "accounts:\n  - profile: prod\n  - name: prod\n    profile: prod-admin\n", `account "prod" is listed more than once`},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseAccountsFile("accounts.yaml", []byte(tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ParseAccountsFile() error = %v, want it to contain %q", tt.name, err, tt.want)
+		}
+	}
+
+	if _, err := AccountsFromProfiles([]string{"prod", "prod"}); err == nil {
+		t.Error("AccountsFromProfiles() accepted a profile listed twice")
+	}
+}
@@ -1,14 +1,72 @@
 package config
 
+import "time"
+
 // Config holds container configuration
 type Config struct {
-	AWSProfile   string
-	AWSRegion    string
-	Concurrency  int
-	RateLimitRPS float64
-	Verbose      bool
-	Quiet        bool
-	LogFile      string
+	AWSProfile      string
+	AWSRegion       string
+	// Regions, if set, limits scans to buckets in these regions
+	Regions         []string
+	// ExcludeBuckets are left out of scans of every bucket
+	ExcludeBuckets  []string
+	// IncludeDirectoryBuckets also scans S3 Express One Zone directory buckets
+	IncludeDirectoryBuckets bool
+	// RoleARN, if set, is assumed for every AWS call
+	RoleARN         string
+	RoleSessionName string
+	ExternalID      string
+	MFASerial       string
+	// UseFIPS and UseDualStack select FIPS and dual-stack S3 endpoints
+	UseFIPS         bool
+	UseDualStack    bool
+	Concurrency     int
+	// BucketConcurrency limits buckets scanned at once and OpConcurrency the
+	// per-upload calls (deletes, part listings, object checks); zero follows Concurrency
+	BucketConcurrency int
+	OpConcurrency   int
+	// MaxBucketFailures is how many consecutive non-retryable failures stop
+	// the measuring of a bucket's uploads; zero never stops
+	MaxBucketFailures int
+	RateLimitRPS    float64
+	// RequestTimeout bounds each attempt of an AWS API call; zero means no bound
+	RequestTimeout  time.Duration
+	Verbose         bool
+	Quiet           bool
+	LogFile         string
+	// LogFormat is the console log format and LogFileFormat the --log-file format ("text" or "json")
+	LogFormat       string
+	LogFileFormat   string
+	// LogLevel is the console log level and LogFileLevel the --log-file level
+	// (debug, info, warn or error); empty levels follow Verbose and Quiet
+	LogLevel        string
+	LogFileLevel    string
+	// TraceAPI logs every S3 API call with its parameters at debug level, and
+	// TraceAPIKeys shows the object keys in them instead of redacting them
+	TraceAPI        bool
+	TraceAPIKeys    bool
+	AgeTolerance    time.Duration
+	AgeCalendarDays bool
+	// AgeBoundaries, if set, splits the age distribution at these increasing ages
+	AgeBoundaries   []time.Duration
+	LivePricing     bool
+	PricingFile     string
+	// CostProvider is an external price source such as exec:/path/to/program
+	CostProvider    string
+	// ServiceInitiatorPatterns are extra Initiator ARN regexps that mark uploads as service-initiated
+	ServiceInitiatorPatterns []string
+	NoCache         bool
+	SizeCacheFile   string
+	// BucketListTTL is how long the bucket list is reused within one process; zero uses the default
+	BucketListTTL   time.Duration
+	// NoInput disables interactive prompts; each prompt resolves to its safe default
+	NoInput         bool
+	// ProgressFormat is "text" for a console progress bar or "json" for one
+	// event per line on stderr; empty means text
+	ProgressFormat  string
+	// JSONOutput is set when the command writes JSON to stdout, so text
+	// progress lines are not shown
+	JSONOutput      bool
 }
 
 // DefaultConfig returns default configuration
@@ -16,61 +74,191 @@ func DefaultConfig() *Config {
 	return &Config{
 		Concurrency:  10,
 		RateLimitRPS: 10.0,
+		MaxBucketFailures: 5,
 		Verbose:      false,
 		Quiet:        false,
+		AgeTolerance: time.Hour,
 	}
 }
 
 // AWS returns AWS configuration
 func (c *Config) AWS() AWSConfig {
 	return AWSConfig{
-		Profile: c.AWSProfile,
-		Region:  c.AWSRegion,
+		Profile:         c.AWSProfile,
+		Region:          c.AWSRegion,
+		RoleARN:         c.RoleARN,
+		RoleSessionName: c.RoleSessionName,
+		ExternalID:      c.ExternalID,
+		MFASerial:       c.MFASerial,
+		UseFIPS:         c.UseFIPS,
+		UseDualStack:    c.UseDualStack,
 	}
 }
 
 // Performance returns performance configuration
 func (c *Config) Performance() PerformanceConfig {
+	bucketConcurrency := c.BucketConcurrency
+	if bucketConcurrency <= 0 {
+		bucketConcurrency = c.Concurrency
+	}
+	opConcurrency := c.OpConcurrency
+	if opConcurrency <= 0 {
+		opConcurrency = c.Concurrency
+	}
 	return PerformanceConfig{
-		Concurrency:  c.Concurrency,
-		RateLimitRPS: c.RateLimitRPS,
+		Concurrency:       c.Concurrency,
+		BucketConcurrency: bucketConcurrency,
+		OpConcurrency:     opConcurrency,
+		MaxBucketFailures: c.MaxBucketFailures,
+		RateLimitRPS:      c.RateLimitRPS,
+		RequestTimeout:    c.RequestTimeout,
 	}
 }
 
 // App returns application configuration
 func (c *Config) App() AppConfig {
 	return AppConfig{
-		Verbose: c.Verbose,
-		Quiet:   c.Quiet,
+		Verbose:        c.Verbose,
+		Quiet:          c.Quiet,
+		NoInput:        c.NoInput,
+		ProgressFormat: c.ProgressFormat,
+		JSONOutput:     c.JSONOutput,
 	}
 }
 
 // Logging returns logging configuration
 func (c *Config) Logging() LoggingConfig {
+	fileFormat := c.LogFileFormat
+	if fileFormat == "" {
+		fileFormat = c.LogFormat
+	}
+	level := c.LogLevel
+	if level == "" {
+		level = "info"
+		if c.Verbose {
+			level = "debug"
+		}
+		if c.Quiet {
+			level = "error"
+		}
+	}
+	// The file keeps informational lines under --quiet
+	fileLevel := c.LogFileLevel
+	if fileLevel == "" {
+		fileLevel = "info"
+		if c.Verbose {
+			fileLevel = "debug"
+		}
+	}
 	return LoggingConfig{
-		File: c.LogFile,
+		File:       c.LogFile,
+		Format:     c.LogFormat,
+		FileFormat: fileFormat,
+		Level:      level,
+		FileLevel:  fileLevel,
+		TraceAPI:   c.TraceAPI,
+		TraceKeys:  c.TraceAPIKeys,
+	}
+}
+
+// Pricing returns pricing configuration
+func (c *Config) Pricing() PricingConfig {
+	return PricingConfig{
+		Live:     c.LivePricing,
+		File:     c.PricingFile,
+		Provider: c.CostProvider,
+	}
+}
+
+// Cache returns upload size cache configuration
+func (c *Config) Cache() CacheConfig {
+	return CacheConfig{
+		Enabled:       !c.NoCache,
+		File:          c.SizeCacheFile,
+		BucketListTTL: c.BucketListTTL,
+	}
+}
+
+// Filter returns filter engine configuration
+func (c *Config) Filter() FilterConfig {
+	return FilterConfig{
+		AgeTolerance:    c.AgeTolerance,
+		AgeCalendarDays: c.AgeCalendarDays,
+		ServiceInitiatorPatterns: c.ServiceInitiatorPatterns,
+	}
+}
+
+// Age returns age distribution configuration
+func (c *Config) Age() AgeConfig {
+	return AgeConfig{
+		Boundaries: c.AgeBoundaries,
 	}
 }
 
 // AWSConfig holds AWS-specific configuration
 type AWSConfig struct {
-	Profile string
-	Region  string
+	Profile         string
+	Region          string
+	RoleARN         string
+	RoleSessionName string
+	ExternalID      string
+	MFASerial       string
+	UseFIPS         bool
+	UseDualStack    bool
 }
 
 // PerformanceConfig holds performance-related configuration
 type PerformanceConfig struct {
-	Concurrency  int
-	RateLimitRPS float64
+	Concurrency       int
+	BucketConcurrency int // buckets scanned at once
+	OpConcurrency     int // per-upload calls made at once
+	MaxBucketFailures int // consecutive non-retryable failures that stop a bucket's measuring, 0 for never
+	RateLimitRPS      float64
+	RequestTimeout    time.Duration // per attempt of an API call, 0 for none
 }
 
 // AppConfig holds application-level configuration
 type AppConfig struct {
-	Verbose bool
-	Quiet   bool
+	Verbose        bool
+	Quiet          bool
+	NoInput        bool
+	ProgressFormat string // "text" (default) or "json"
+	JSONOutput     bool   // stdout carries JSON, so text progress is not shown
 }
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	File string
+	File       string
+	Format     string // console format, "text" (default) or "json"
+	FileFormat string // log file format, defaults to Format
+	Level      string // console level
+	FileLevel  string // log file level, independent of Level
+	TraceAPI   bool   // log every S3 API call, not only failed ones
+	TraceKeys  bool   // show object keys in traced calls
+}
+
+// FilterConfig holds filter engine configuration
+type FilterConfig struct {
+	AgeTolerance    time.Duration
+	AgeCalendarDays bool
+	ServiceInitiatorPatterns []string
+}
+
+// AgeConfig holds age distribution configuration
+type AgeConfig struct {
+	Boundaries []time.Duration // nil uses the default buckets
+}
+
+// CacheConfig holds upload size and bucket list cache configuration
+type CacheConfig struct {
+	Enabled       bool
+	File          string        // defaults to ~/.s3mpc/cache.db
+	BucketListTTL time.Duration // defaults to 5 minutes
+}
+
+// PricingConfig holds pricing configuration
+type PricingConfig struct {
+	Live     bool
+	File     string
+	Provider string
 }
\ No newline at end of file
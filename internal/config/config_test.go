@@ -0,0 +1,41 @@
+package config
+
+import "testing"
+
+func TestLoggingLevels(t *testing.T) {
+	tests := []struct {
+		name                string
+		config              Config
+		wantLevel, wantFile string
+	}{
+		{name: "defaults", wantLevel: "info", wantFile: "info"},
+		{name: "verbose", config: Config{Verbose: true}, wantLevel: "debug", wantFile: "debug"},
+		{name: "quiet keeps informational file lines", config: Config{Quiet: true}, wantLevel: "error", wantFile: "info"},
+		{name: "explicit levels", config: Config{Quiet: true, LogLevel: "warn", LogFileLevel: "debug"}, wantLevel: "warn", wantFile: "debug"},
+		{name: "file level alone", config: Config{Verbose: true, LogFileLevel: "error"}, wantLevel: "debug", wantFile: "error"},
+	}
+	for _, tt := range tests {
+		logging := tt.config.Logging()
+		if logging.Level != tt.wantLevel || logging.FileLevel != tt.wantFile {
+			t.Errorf("%s: levels = %s, %s, want %s, %s", tt.name, logging.Level, logging.FileLevel, tt.wantLevel, tt.wantFile)
+		}
+	}
+}
+
+func TestPerformanceConcurrency(t *testing.T) {
+	tests := []struct {
+		name               string
+		config             Config
+		wantBucket, wantOp int
+	}{
+		{name: "concurrency alone", config: Config{Concurrency: 10}, wantBucket: 10, wantOp: 10},
+		{name: "bucket override", config: Config{Concurrency: 10, BucketConcurrency: 2}, wantBucket: 2, wantOp: 10},
+		{name: "both overrides", config: Config{Concurrency: 10, BucketConcurrency: 4, OpConcurrency: 50}, wantBucket: 4, wantOp: 50},
+	}
+	for _, tt := range tests {
+		performance := tt.config.Performance()
+		if performance.BucketConcurrency != tt.wantBucket || performance.OpConcurrency != tt.wantOp {
+			t.Errorf("%s: concurrency = %d, %d, want %d, %d", tt.name, performance.BucketConcurrency, performance.OpConcurrency, tt.wantBucket, tt.wantOp)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source says where an effective setting value came from
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// SettingKind is the type of a setting's value
+type SettingKind int
+
+const (
+	KindString SettingKind = iota
+	KindInt
+	KindFloat
+	KindBool
+	KindDuration
+	KindList // comma-separated in environment variables, a sequence in files
+)
+
+// Setting is a value that can be given as a flag, an environment variable or
+// a config file key. Settings without a flag are only read from the
+// environment and config files.
+type Setting struct {
+	Key     string // config file key
+	Flag    string // global flag name, if any
+	Env     string
+	Kind    SettingKind
+	Default string   // used when the setting has no flag
+	Choices []string // allowed values, if restricted
+	Min     float64  // minimum for numeric kinds, if Max is set
+	Max     float64
+}
+
+// Settings lists every setting a config file or environment variable can set
+var Settings = []Setting{
+	{Key: "profile", Flag: "profile", Env: "S3MPC_PROFILE", Kind: KindString},
+	{Key: "region", Flag: "region", Env: "S3MPC_REGION", Kind: KindString},
+	{Key: "regions", Flag: "regions", Env: "S3MPC_REGIONS", Kind: KindList},
+	{Key: "role-arn", Flag: "role-arn", Env: "S3MPC_ROLE_ARN", Kind: KindString},
+	{Key: "role-session-name", Flag: "role-session-name", Env: "S3MPC_ROLE_SESSION_NAME", Kind: KindString},
+	{Key: "external-id", Flag: "external-id", Env: "S3MPC_EXTERNAL_ID", Kind: KindString},
+	{Key: "mfa-serial", Flag: "mfa-serial", Env: "S3MPC_MFA_SERIAL", Kind: KindString},
+	{Key: "fips", Flag: "fips", Env: "S3MPC_FIPS", Kind: KindBool},
+	{Key: "dualstack", Flag: "dualstack", Env: "S3MPC_DUALSTACK", Kind: KindBool},
+	{Key: "concurrency", Flag: "concurrency", Env: "S3MPC_CONCURRENCY", Kind: KindInt, Min: 1, Max: 100},
+	{Key: "bucket-concurrency", Flag: "bucket-concurrency", Env: "S3MPC_BUCKET_CONCURRENCY", Kind: KindInt, Min: 1, Max: 100},
+	{Key: "op-concurrency", Flag: "op-concurrency", Env: "S3MPC_OP_CONCURRENCY", Kind: KindInt, Min: 1, Max: 100},
+	{Key: "rate-limit", Flag: "rate-limit", Env: "S3MPC_RATE_LIMIT", Kind: KindFloat, Min: 0.1, Max: 10000},
+	{Key: "verbose", Flag: "verbose", Env: "S3MPC_VERBOSE", Kind: KindBool},
+	{Key: "quiet", Flag: "quiet", Env: "S3MPC_QUIET", Kind: KindBool},
+	{Key: "log-file", Flag: "log-file", Env: "S3MPC_LOG_FILE", Kind: KindString},
+	{Key: "log-format", Flag: "log-format", Env: "S3MPC_LOG_FORMAT", Kind: KindString, Choices: []string{"text", "json"}},
+	{Key: "log-file-format", Flag: "log-file-format", Env: "S3MPC_LOG_FILE_FORMAT", Kind: KindString, Choices: []string{"text", "json"}},
+	{Key: "log-level", Flag: "log-level", Env: "S3MPC_LOG_LEVEL", Kind: KindString, Choices: []string{"debug", "info", "warn", "error"}},
+	{Key: "log-file-level", Flag: "log-file-level", Env: "S3MPC_LOG_FILE_LEVEL", Kind: KindString, Choices: []string{"debug", "info", "warn", "error"}},
+	{Key: "trace-api", Flag: "trace-api", Env: "S3MPC_TRACE_API", Kind: KindBool},
+	{Key: "trace-api-keys", Flag: "trace-api-keys", Env: "S3MPC_TRACE_API_KEYS", Kind: KindBool},
+	{Key: "stats", Flag: "stats", Env: "S3MPC_STATS", Kind: KindBool},
+	{Key: "age-tolerance", Flag: "age-tolerance", Env: "S3MPC_AGE_TOLERANCE", Kind: KindDuration},
+	{Key: "age-calendar-days", Flag: "age-calendar-days", Env: "S3MPC_AGE_CALENDAR_DAYS", Kind: KindBool},
+	{Key: "no-cache", Flag: "no-cache", Env: "S3MPC_NO_CACHE", Kind: KindBool},
+	{Key: "bucket-list-ttl", Env: "S3MPC_BUCKET_LIST_TTL", Kind: KindDuration, Default: "5m"},
+	{Key: "timeout", Flag: "timeout", Env: "S3MPC_TIMEOUT", Kind: KindDuration},
+	{Key: "request-timeout", Flag: "request-timeout", Env: "S3MPC_REQUEST_TIMEOUT", Kind: KindDuration},
+	{Key: "skip-preflight", Flag: "skip-preflight", Env: "S3MPC_SKIP_PREFLIGHT", Kind: KindBool},
+	{Key: "no-input", Flag: "no-input", Env: "S3MPC_NO_INPUT", Kind: KindBool},
+	{Key: "fail-on-errors", Flag: "fail-on-errors", Env: "S3MPC_FAIL_ON_ERRORS", Kind: KindBool},
+	{Key: "service-initiator-pattern", Flag: "service-initiator-pattern", Env: "S3MPC_SERVICE_INITIATOR_PATTERN", Kind: KindList},
+	{Key: "exclude-buckets", Env: "S3MPC_EXCLUDE_BUCKETS", Kind: KindList},
+	{Key: "default-filter", Env: "S3MPC_DEFAULT_FILTER", Kind: KindString},
+}
+
+// ConfigFileName is the config file looked up in the working directory
+const ConfigFileName = ".s3mpc.yaml"
+
+// DefaultConfigFilePath returns ~/.s3mpc/config.yaml
+func DefaultConfigFilePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".s3mpc", "config.yaml")
+}
+
+// FindConfigFile returns the config file to load: the explicit path if given,
+// otherwise ./.s3mpc.yaml or ~/.s3mpc/config.yaml, whichever exists first.
+// It returns "" when there is no config file.
+func FindConfigFile(explicit string) (string, error) {
+	if explicit != "" {
+		if _, err := os.Stat(explicit); err != nil {
+			return "", fmt.Errorf("config file %s: %w", explicit, err)
+		}
+		return explicit, nil
+	}
+
+	for _, path := range []string{ConfigFileName, DefaultConfigFilePath()} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+	return "", nil
+}
+
+// File holds the validated values of a config file, keyed by setting key.
+// List values are joined with commas.
+type File struct {
+	Path   string
+	Values map[string]string
+}
+
+// LoadFile reads and validates a config file
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	return ParseFile(path, data)
+}
+
+// ParseFile parses config file contents; path is only used in error messages
+func ParseFile(path string, data []byte) (*File, error) {
+	file := &File{Path: path, Values: make(map[string]string)}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if len(root.Content) == 0 {
+		return file, nil
+	}
+
+	document := root.Content[0]
+	if document.Kind != yaml.MappingNode {
+		return nil, fileError(path, document, "expected a mapping of setting names to values")
+	}
+
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		key, value := document.Content[i], document.Content[i+1]
+
+		setting, ok := LookupSetting(key.Value)
+		if !ok {
+			return nil, fileError(path, key, fmt.Sprintf("unknown key '%s'", key.Value))
+		}
+
+		raw, err := nodeValue(setting, value)
+		if err == nil {
+			err = setting.Validate(raw)
+		}
+		if err != nil {
+			return nil, fileError(path, value, fmt.Sprintf("%s: %v", setting.Key, err))
+		}
+		file.Values[setting.Key] = raw
+	}
+
+	return file, nil
+}
+
+// nodeValue converts a YAML value to the string form used by flags
+func nodeValue(setting Setting, node *yaml.Node) (string, error) {
+	if setting.Kind == KindList && node.Kind == yaml.SequenceNode {
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("list items must be plain values")
+			}
+			if strings.Contains(item.Value, ",") {
+				return "", fmt.Errorf("list items cannot contain commas, got '%s'", item.Value)
+			}
+			items = append(items, item.Value)
+		}
+		return strings.Join(items, ","), nil
+	}
+	if node.Kind != yaml.ScalarNode {
+		return "", fmt.Errorf("must be a single value")
+	}
+	return node.Value, nil
+}
+
+// fileError formats a validation error with file and line context
+func fileError(path string, node *yaml.Node, message string) error {
+	return fmt.Errorf("%s:%d:%d: %s", path, node.Line, node.Column, message)
+}
+
+// LookupSetting returns the setting with a config file key
+func LookupSetting(key string) (Setting, bool) {
+	for _, setting := range Settings {
+		if setting.Key == key {
+			return setting, true
+		}
+	}
+	return Setting{}, false
+}
+
+// Validate checks that a value has the setting's type and is in range
+func (s Setting) Validate(value string) error {
+	var number float64
+	switch s.Kind {
+	case KindInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("must be an integer, got '%s'", value)
+		}
+		number = float64(n)
+	case KindFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number, got '%s'", value)
+		}
+		number = f
+	case KindBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("must be true or false, got '%s'", value)
+		}
+	case KindDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("must be a duration such as 30m or 1h, got '%s'", value)
+		}
+		if d < 0 {
+			return fmt.Errorf("cannot be negative, got '%s'", value)
+		}
+	}
+
+	if s.Max != 0 && (number < s.Min || number > s.Max) {
+		return fmt.Errorf("must be between %v and %v, got %s", s.Min, s.Max, value)
+	}
+
+	if len(s.Choices) > 0 {
+		for _, choice := range s.Choices {
+			if value == choice {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s, got '%s'", strings.Join(s.Choices, ", "), value)
+	}
+
+	return nil
+}
+
+// Resolved is the effective value of a setting and where it came from
+type Resolved struct {
+	Setting Setting
+	Value   string
+	Source  Source
+	Origin  string // the flag, environment variable or file the value came from
+}
+
+// Resolve picks the effective value of every setting, with precedence
+// flags > environment variables > config file > defaults. flagValue returns
+// the value of a flag given on the command line, and defaultValue the
+// default of a flag.
+func Resolve(file *File, lookupEnv func(string) (string, bool), flagValue func(flag string) (string, bool), defaultValue func(flag string) string) ([]Resolved, error) {
+	resolved := make([]Resolved, 0, len(Settings))
+
+	for _, setting := range Settings {
+		if setting.Flag != "" {
+			if value, ok := flagValue(setting.Flag); ok {
+				resolved = append(resolved, Resolved{Setting: setting, Value: value, Source: SourceFlag, Origin: "--" + setting.Flag})
+				continue
+			}
+		}
+
+		if value, ok := lookupEnv(setting.Env); ok {
+			if err := setting.Validate(value); err != nil {
+				return nil, fmt.Errorf("%s: %w", setting.Env, err)
+			}
+			resolved = append(resolved, Resolved{Setting: setting, Value: value, Source: SourceEnv, Origin: setting.Env})
+			continue
+		}
+
+		if file != nil {
+			if value, ok := file.Values[setting.Key]; ok {
+				resolved = append(resolved, Resolved{Setting: setting, Value: value, Source: SourceFile, Origin: file.Path})
+				continue
+			}
+		}
+
+		value := setting.Default
+		if setting.Flag != "" {
+			value = defaultValue(setting.Flag)
+		}
+		resolved = append(resolved, Resolved{Setting: setting, Value: value, Source: SourceDefault})
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFileRejectsBadValuesWithLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown key", "profile: prod\nprofiel: dev\n", "team.yaml:2:1: unknown key 'profiel'"},
+		{"bad integer", "concurrency: lots\n", "team.yaml:1:14: concurrency: must be an integer, got 'lots'"},
+		{"out of range", "concurrency: 0\n", "team.yaml:1:14: concurrency: must be between 1 and 100, got 0"},
+		{"bad choice", "log-format: xml\n", "team.yaml:1:13: log-format: must be one of text, json, got 'xml'"},
+		{"bad duration", "age-tolerance: soon\n", "team.yaml:1:16: age-tolerance: must be a duration"},
+		{"nested value", "region:\n  name: eu-west-1\n", "team.yaml:2:3: region: must be a single value"},
+		{"not a mapping", "- profile\n", "team.yaml:1:1: expected a mapping"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseFile("team.yaml", []byte(tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ParseFile() error = %v, want it to contain %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseFileValues(t *testing.T) {
+	file, err := ParseFile("team.yaml", []byte("# shared defaults\nprofile: prod\nrate-limit: 25.5\nverbose: true\nservice-initiator-pattern:\n  - ^arn:aws:iam::1:role/backup$\n  - ^arn:aws:iam::1:role/etl$\n"))
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	want := map[string]string{
+		"profile":                   "prod",
+		"rate-limit":                "25.5",
+		"verbose":                   "true",
+		"service-initiator-pattern": "^arn:aws:iam::1:role/backup$,^arn:aws:iam::1:role/etl$",
+	}
+	for key, value := range want {
+		if file.Values[key] != value {
+			t.Errorf("Values[%q] = %q, want %q", key, file.Values[key], value)
+		}
+	}
+
+	if empty, err := ParseFile("empty.yaml", nil); err != nil || len(empty.Values) != 0 {
+		t.Errorf("ParseFile(empty) = %+v, %v, want no values", empty, err)
+	}
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	file := &File{Path: "team.yaml", Values: map[string]string{"profile": "file", "region": "eu-west-1", "concurrency": "30", "rate-limit": "5"}}
+	env := map[string]string{"S3MPC_PROFILE": "env", "S3MPC_REGION": "us-west-2"}
+	flags := map[string]string{"profile": "flag"}
+	defaults := map[string]string{"concurrency": "10", "verbose": "false"}
+
+	resolved, err := Resolve(file,
+		func(name string) (string, bool) { v, ok := env[name]; return v, ok },
+		func(name string) (string, bool) { v, ok := flags[name]; return v, ok },
+		func(name string) string { return defaults[name] })
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	want := map[string]struct {
+		value  string
+		source Source
+	}{
+		"profile":     {"flag", SourceFlag},
+		"region":      {"us-west-2", SourceEnv},
+		"concurrency": {"30", SourceFile},
+		"rate-limit":  {"5", SourceFile},
+		"verbose":     {"false", SourceDefault},
+	}
+	for _, r := range resolved {
+		if w, ok := want[r.Setting.Key]; ok && (r.Value != w.value || r.Source != w.source) {
+			t.Errorf("%s = %q from %s, want %q from %s", r.Setting.Key, r.Value, r.Source, w.value, w.source)
+		}
+	}
+
+	env["S3MPC_CONCURRENCY"] = "many"
+	_, err = Resolve(nil,
+		func(name string) (string, bool) { v, ok := env[name]; return v, ok },
+		func(name string) (string, bool) { return "", false },
+		func(name string) string { return "" })
+	if err == nil || !strings.Contains(err.Error(), "S3MPC_CONCURRENCY: must be an integer") {
+		t.Errorf("Resolve() error = %v, want it to name S3MPC_CONCURRENCY", err)
+	}
+}
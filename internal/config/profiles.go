@@ -0,0 +1,77 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// AWSProfiles returns the names of the profiles in the shared AWS config and
+// credentials files, honouring AWS_CONFIG_FILE and AWS_SHARED_CREDENTIALS_FILE.
+// Missing or unreadable files contribute no profiles.
+func AWSProfiles() []string {
+	seen := make(map[string]bool)
+	for _, name := range sharedFileProfiles(awsSharedFile("AWS_CONFIG_FILE", "config"), true) {
+		seen[name] = true
+	}
+	for _, name := range sharedFileProfiles(awsSharedFile("AWS_SHARED_CREDENTIALS_FILE", "credentials"), false) {
+		seen[name] = true
+	}
+
+	profiles := make([]string, 0, len(seen))
+	for name := range seen {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// awsSharedFile returns the path in env, or else ~/.aws/name
+func awsSharedFile(env, name string) string {
+	if path := os.Getenv(env); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aws", name)
+}
+
+// sharedFileProfiles reads the profile section names of a shared AWS file.
+// The config file names profiles [profile NAME], except for [default], and
+// has other sections such as [sso-session NAME]; the credentials file names
+// them [NAME].
+func sharedFileProfiles(path string, configFile bool) []string {
+	if path == "" {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var profiles []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+			continue
+		}
+		section := strings.TrimSpace(line[1 : len(line)-1])
+		if configFile && section != "default" {
+			name, ok := strings.CutPrefix(section, "profile ")
+			if !ok {
+				continue
+			}
+			section = strings.TrimSpace(name)
+		}
+		if section != "" {
+			profiles = append(profiles, section)
+		}
+	}
+	return profiles
+}
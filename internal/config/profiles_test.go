@@ -0,0 +1,42 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAWSProfiles(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config")
+	credentialsFile := filepath.Join(dir, "credentials")
+	if err := os.WriteFile(configFile, []byte(`[default]
+region = us-east-1
+
+[profile prod]
+sso_session = corp
+
+[sso-session corp]
+sso_region = us-east-1
+
+[ profile staging ]
+role_arn = arn:aws:iam::111111111111:role/s3mpc-audit
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(credentialsFile, []byte("[default]\naws_access_key_id = AKIA\n[legacy]\naws_access_key_id = AKIA\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsFile)
+
+	if got := strings.Join(AWSProfiles(), ","); got != "default,legacy,prod,staging" {
+		t.Errorf("AWSProfiles() = %s, want the profiles of both files without sso-session sections", got)
+	}
+
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "missing"))
+	if got := strings.Join(AWSProfiles(), ","); got != "default,legacy" {
+		t.Errorf("AWSProfiles() with no config file = %s, want the credentials profiles", got)
+	}
+}
@@ -3,14 +3,19 @@ package container
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 
-	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"golang.org/x/time/rate"
 
 	"github.com/Garvitkul/s3mpc/internal/config"
 	"github.com/Garvitkul/s3mpc/internal/logging"
+	"github.com/Garvitkul/s3mpc/internal/prompt"
+	"github.com/Garvitkul/s3mpc/internal/term"
 	"github.com/Garvitkul/s3mpc/pkg/aws"
 	"github.com/Garvitkul/s3mpc/pkg/filter"
 	"github.com/Garvitkul/s3mpc/pkg/interfaces"
@@ -25,7 +30,12 @@ type Container struct {
 	// AWS clients
 	s3Client        *s3.Client
 	s3ClientWrapper *aws.S3Client
-	pricingClient   *pricing.Client
+	pricingClient   *pricing.Client // nil when the partition has no Pricing API
+	stsClient       *sts.Client
+	// pricingUnavailable says why the partition has no Pricing API
+	pricingUnavailable string
+	// accountID is the caller's account, once AccountID has looked it up
+	accountID string
 	
 	// Core services
 	uploadService     interfaces.UploadService
@@ -36,10 +46,19 @@ type Container struct {
 	dryRunService     interfaces.DryRunService
 	exportService     interfaces.ExportService
 	outputFormatter   interfaces.OutputFormatter
+	metricsFormatter  interfaces.MetricsFormatter
 	sizeService       interfaces.SizeService
+	sizeCache         *services.SizeCache
+	reportService     interfaces.ReportService
+	lifecycleService  interfaces.LifecycleService
+	objectCheckService interfaces.ObjectCheckService
+	doctorService     interfaces.DoctorService
 	
 	// Logging
 	logger *logging.Logger
+	
+	// accounts are the containers of the accounts in a multi-account run, by name
+	accounts map[string]*Container
 }
 
 // NewContainer creates a new dependency injection container
@@ -72,85 +91,249 @@ func NewContainer(cfg *config.Config) (*Container, error) {
 func (c *Container) initializeAWSClients() error {
 	ctx := context.Background()
 	
-	// Load AWS configuration
-	var opts []func(*awsconfig.LoadOptions) error
-	
-	awsConfig := c.config.AWS()
-	if awsConfig.Profile != "" {
-		opts = append(opts, awsconfig.WithSharedConfigProfile(awsConfig.Profile))
-	}
-	
-	if awsConfig.Region != "" {
-		opts = append(opts, awsconfig.WithRegion(awsConfig.Region))
-	}
+	s3ClientConfig := c.clientConfig()
 	
-	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	// Load AWS configuration once so every client shares its credentials,
+	// including the cached credentials of an assumed role
+	cfg, err := aws.LoadConfig(ctx, s3ClientConfig)
 	if err != nil {
-		return fmt.Errorf("failed to load AWS config: %w", err)
+		return err
 	}
 	
 	// Initialize S3 client
 	c.s3Client = s3.NewFromConfig(cfg)
 	
 	// Initialize S3 client wrapper with retry logic and rate limiting
-	awsConf := c.config.AWS()
-	perfConfig := c.config.Performance()
-	s3ClientConfig := aws.ClientConfig{
-		Profile:   awsConf.Profile,
-		Region:    awsConf.Region,
-		RateLimit: rate.Limit(perfConfig.RateLimitRPS),
-	}
+	c.s3ClientWrapper = aws.NewS3ClientFromConfig(cfg, s3ClientConfig)
 	
-	c.s3ClientWrapper, err = aws.NewS3Client(ctx, s3ClientConfig)
-	if err != nil {
-		return fmt.Errorf("failed to create S3 client wrapper: %w", err)
+	// Initialize Pricing client in the region that serves the Pricing API
+	// for the configured region's partition, if it has one
+	if pricingRegion, err := aws.PricingRegion(cfg.Region); err != nil {
+		c.pricingUnavailable = err.Error()
+	} else {
+		pricingCfg := cfg.Copy()
+		pricingCfg.Region = pricingRegion
+		// The Pricing API has no FIPS or dual-stack endpoints
+		c.pricingClient = pricing.NewFromConfig(pricingCfg, func(o *pricing.Options) {
+			o.EndpointOptions.UseFIPSEndpoint = awssdk.FIPSEndpointStateDisabled
+			o.EndpointOptions.UseDualStackEndpoint = awssdk.DualStackEndpointStateDisabled
+		})
 	}
 	
-	// Initialize Pricing client (always use us-east-1 for pricing API)
-	pricingCfg := cfg.Copy()
-	pricingCfg.Region = "us-east-1"
-	c.pricingClient = pricing.NewFromConfig(pricingCfg)
+	// Initialize STS client, which resolves the caller identity for doctor and
+	// the credential check made before commands run. The SDK resolves its
+	// endpoint, like S3's, from the region's partition.
+	c.stsClient = sts.NewFromConfig(cfg)
 	
 	return nil
 }
 
+// clientConfig returns the configuration every AWS client is built from
+func (c *Container) clientConfig() aws.ClientConfig {
+	awsConf := c.config.AWS()
+	perfConfig := c.config.Performance()
+	loggingConfig := c.config.Logging()
+	return aws.ClientConfig{
+		Profile:        awsConf.Profile,
+		Region:         awsConf.Region,
+		RateLimit:      rate.Limit(perfConfig.RateLimitRPS),
+		Logger:         c.logger,
+		Trace:          aws.TraceConfig{Enabled: loggingConfig.TraceAPI, ShowKeys: loggingConfig.TraceKeys},
+		AssumeRole:     c.assumeRoleConfig(),
+		RequestTimeout: perfConfig.RequestTimeout,
+		UseFIPS:        awsConf.UseFIPS,
+		UseDualStack:   awsConf.UseDualStack,
+	}
+}
+
+// assumeRoleConfig returns the role to assume from --role-arn, or nil. MFA
+// token codes are asked for on the terminal when the role requires them.
+func (c *Container) assumeRoleConfig() *aws.AssumeRoleConfig {
+	awsConf := c.config.AWS()
+	if awsConf.RoleARN == "" {
+		return nil
+	}
+	
+	roleConfig := &aws.AssumeRoleConfig{
+		RoleARN:     awsConf.RoleARN,
+		SessionName: awsConf.RoleSessionName,
+		ExternalID:  awsConf.ExternalID,
+		MFASerial:   awsConf.MFASerial,
+	}
+	if awsConf.MFASerial != "" {
+		prompter := c.NewPrompter(os.Stdin, os.Stderr)
+		roleConfig.TokenProvider = func() (string, error) {
+			return prompter.Ask("MFA token code for " + awsConf.MFASerial)
+		}
+	}
+	return roleConfig
+}
+
 // initializeServices sets up service implementations
 func (c *Container) initializeServices() error {
 	// Initialize bucket service
-	c.bucketService = services.NewBucketService(c.s3ClientWrapper)
+	bucketService := services.NewBucketServiceWithTTL(c.s3ClientWrapper, c.config.Cache().BucketListTTL, c.logger)
+	bucketService.SetConcurrency(c.config.Performance().BucketConcurrency)
+	c.bucketService = bucketService
 	
 	// Initialize cost calculator
-	c.costCalculator = services.NewCostService()
+	costCalculator, err := c.newCostCalculator()
+	if err != nil {
+		return err
+	}
+	c.costCalculator = costCalculator
 	
 	// Initialize filter engine
-	c.filterEngine = filter.NewEngine()
+	filterConfig := c.config.Filter()
+	c.filterEngine = filter.NewEngineWithOptions(filter.EngineOptions{
+		AgeTolerance:    filterConfig.AgeTolerance,
+		CalendarDayAges: filterConfig.AgeCalendarDays,
+	})
 	
 	// Initialize age service
 	c.ageService = services.NewAgeService()
+	if boundaries := c.config.Age().Boundaries; boundaries != nil {
+		c.ageService = services.NewAgeServiceWithBoundaries(boundaries)
+	}
 	
 	// Initialize dry-run service
 	c.dryRunService = services.NewDryRunService(c.costCalculator)
 	
 	// Initialize export service
-	c.exportService = services.NewExportService()
+	c.exportService = services.NewExportServiceWithCost(c.costCalculator)
 	
 	// Initialize output formatter
-	c.outputFormatter = services.NewOutputFormatter()
+	c.outputFormatter = services.NewOutputFormatterWithTerminal(term.New(os.Stdout))
+	c.metricsFormatter = services.NewMetricsFormatter()
 	
 	// Initialize upload service with dry-run service
-	c.uploadService = services.NewUploadServiceWithConcurrency(
+	initiatorClassifier, err := services.NewInitiatorClassifier(filterConfig.ServiceInitiatorPatterns)
+	if err != nil {
+		return err
+	}
+	uploadService := services.NewUploadServiceWithOptions(
 		c.s3ClientWrapper, 
 		c.bucketService, 
 		c.dryRunService, 
-		c.config.Performance().Concurrency,
-	)
+		c.config.Performance().OpConcurrency,
+		c.newProgressReporter(),
+		c.NewPrompter(os.Stdin, os.Stdout),
+		os.Stdout,
+		initiatorClassifier,
+		c.logger,
+	).(*services.UploadService)
+	uploadService.SetBucketConcurrency(c.config.Performance().BucketConcurrency)
+	uploadService.SetClientConfig(c.clientConfig())
+	c.uploadService = uploadService
 	
 	// Initialize size service (depends on upload service)
-	c.sizeService = services.NewSizeServiceWithConcurrency(c.uploadService, c.config.Performance().Concurrency)
+	if c.config.Cache().Enabled && c.sizeCache == nil {
+		c.sizeCache = services.NewSizeCache(c.SizeCachePath())
+	}
+	sizeService := services.NewSizeServiceWithCache(c.uploadService, c.config.Performance().OpConcurrency, c.sizeCache, c.logger)
+	sizeService.SetMaxBucketFailures(c.config.Performance().MaxBucketFailures)
+	appConfig := c.config.App()
+	if appConfig.ProgressFormat == "json" {
+		sizeService.SetProgressReporter(c.newProgressReporter())
+	} else if !appConfig.Quiet && !appConfig.JSONOutput {
+		sizeService.SetProgressWriter(os.Stderr)
+	}
+	c.sizeService = sizeService
+	
+	// Initialize report service (combines size, cost and age analysis)
+	c.reportService = services.NewReportService(c.uploadService, c.sizeService, c.costCalculator, c.ageService)
+	
+	// Initialize lifecycle service (audits abort-incomplete-uploads rules)
+	c.lifecycleService = services.NewLifecycleService(c.bucketService, c.uploadService, c.config.Performance().BucketConcurrency, c.s3ClientWrapper)
+	
+	// Initialize object check service (reads the objects at upload keys for --check-objects)
+	c.objectCheckService = services.NewObjectCheckService(c.uploadService, c.config.Performance().OpConcurrency)
+	
+	// Initialize doctor service (checks credentials, permissions and settings)
+	c.doctorService = c.newDoctorService()
 	
 	return nil
 }
 
+// newDoctorService builds the doctor service, probing buckets with regional
+// clients and the Pricing API only when live pricing is enabled
+func (c *Container) newDoctorService() interfaces.DoctorService {
+	var pricingClient services.PricingAPIClient
+	if c.config.Pricing().Live && c.pricingClient != nil {
+		pricingClient = c.pricingClient
+	}
+	newProbeClient := func(region string) services.S3ProbeClient {
+		if region == "" {
+			return c.s3ClientWrapper
+		}
+		return c.s3ClientWrapper.ForRegion(region)
+	}
+	perfConfig := c.config.Performance()
+	return services.NewDoctorService(c.stsClient, c.bucketService, newProbeClient, pricingClient, services.DoctorSettings{
+		RateLimit:          perfConfig.RateLimitRPS,
+		BucketConcurrency:  perfConfig.BucketConcurrency,
+		OpConcurrency:      perfConfig.OpConcurrency,
+		PricingUnavailable: c.pricingUnavailable,
+	})
+}
+
+// newProgressReporter returns the deletion progress reporter for
+// --progress-format: JSON events on stderr, or a progress bar on stdout with
+// errors on stderr. The progress bar is left out under --quiet and when
+// stdout carries JSON.
+func (c *Container) newProgressReporter() services.ProgressReporter {
+	appConfig := c.config.App()
+	if appConfig.ProgressFormat == "json" {
+		return services.NewJSONProgressReporter(os.Stderr)
+	}
+	reporter := services.NewConsoleProgressReporter(os.Stdout, appConfig.Quiet || appConfig.JSONOutput)
+	reporter.SetErrorWriter(os.Stderr)
+	return reporter
+}
+
+// newCostCalculator builds the cost calculator from the pricing configuration.
+// A pricing file takes precedence over a cost provider, then live prices, then
+// the built-in table.
+func (c *Container) newCostCalculator() (interfaces.CostCalculator, error) {
+	pricingConfig := c.config.Pricing()
+	
+	var sources services.PricingSourceChain
+	currency := "USD"
+	
+	if pricingConfig.File != "" {
+		fileSource, err := services.LoadPricingFile(pricingConfig.File)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, fileSource)
+		currency = fileSource.Currency()
+	}
+	
+	if pricingConfig.Provider != "" {
+		providerSource, err := services.ParseCostProvider(pricingConfig.Provider, currency)
+		if err != nil {
+			return nil, err
+		}
+		if !c.config.App().Quiet {
+			providerSource.SetWarningWriter(os.Stderr)
+		}
+		sources = append(sources, providerSource)
+	}
+	
+	if pricingConfig.Live {
+		if c.pricingClient != nil {
+			sources = append(sources, services.NewPricingAPISource(c.pricingClient, "", 0))
+		} else if !c.config.App().Quiet {
+			fmt.Fprintf(os.Stderr, "Warning: --live-pricing ignored: %s; using built-in prices\n", c.pricingUnavailable)
+		}
+	}
+	
+	if len(sources) == 0 {
+		return services.NewCostService(), nil
+	}
+	return services.NewCostServiceWithOptions(sources, currency), nil
+}
+
 // GetUploadService returns the upload service instance
 func (c *Container) GetUploadService() interfaces.UploadService {
 	return c.uploadService
@@ -196,6 +379,49 @@ func (c *Container) GetSizeService() interfaces.SizeService {
 	return c.sizeService
 }
 
+// GetMetricsFormatter returns the metrics formatter instance
+func (c *Container) GetMetricsFormatter() interfaces.MetricsFormatter {
+	return c.metricsFormatter
+}
+
+// GetReportService returns the report service instance
+func (c *Container) GetReportService() interfaces.ReportService {
+	return c.reportService
+}
+
+// GetLifecycleService returns the lifecycle service instance
+func (c *Container) GetLifecycleService() interfaces.LifecycleService {
+	return c.lifecycleService
+}
+
+// GetObjectCheckService returns the object check service instance
+func (c *Container) GetObjectCheckService() interfaces.ObjectCheckService {
+	return c.objectCheckService
+}
+
+// GetDoctorService returns the doctor service instance
+func (c *Container) GetDoctorService() interfaces.DoctorService {
+	return c.doctorService
+}
+
+// NewPrompter creates a prompter on in and out that honors --no-input
+func (c *Container) NewPrompter(in io.Reader, out io.Writer) *prompt.Prompter {
+	return prompt.New(in, out, c.config.App().NoInput)
+}
+
+// GetSizeCache returns the upload size cache, or nil when caching is disabled
+func (c *Container) GetSizeCache() *services.SizeCache {
+	return c.sizeCache
+}
+
+// SizeCachePath returns the file used to persist the upload size cache
+func (c *Container) SizeCachePath() string {
+	if path := c.config.Cache().File; path != "" {
+		return path
+	}
+	return services.DefaultSizeCachePath()
+}
+
 // GetS3Client returns the S3 client
 func (c *Container) GetS3Client() *s3.Client {
 	return c.s3Client
@@ -206,11 +432,70 @@ func (c *Container) GetS3ClientWrapper() *aws.S3Client {
 	return c.s3ClientWrapper
 }
 
-// GetPricingClient returns the Pricing client
+// GetAPIStats returns the S3 API calls made so far, including those made in
+// the accounts of a multi-account scan
+func (c *Container) GetAPIStats() aws.APIStats {
+	var stats aws.APIStats
+	if c.s3ClientWrapper != nil {
+		stats = c.s3ClientWrapper.GetStats()
+	}
+	for _, account := range c.accounts {
+		stats = stats.Add(account.GetAPIStats())
+	}
+	return stats
+}
+
+// GetCacheCounts returns the counters of the bucket service caches, such as
+// region_hits, summed over the accounts of a multi-account scan
+func (c *Container) GetCacheCounts() map[string]int64 {
+	counts := make(map[string]int64)
+	if c.bucketService != nil {
+		for name, value := range c.bucketService.GetCacheStats() {
+			if count, ok := value.(int64); ok {
+				counts[name] += count
+			}
+		}
+	}
+	for _, account := range c.accounts {
+		for name, count := range account.GetCacheCounts() {
+			counts[name] += count
+		}
+	}
+	return counts
+}
+
+// GetPricingClient returns the Pricing client, or nil when the configured
+// region's partition has no Pricing API
 func (c *Container) GetPricingClient() *pricing.Client {
 	return c.pricingClient
 }
 
+// GetSTSClient returns the STS client
+func (c *Container) GetSTSClient() *sts.Client {
+	return c.stsClient
+}
+
+// CheckCredentials resolves the configured credentials and checks them with
+// STS before a command calls AWS, so that missing or expired credentials
+// fail fast with one actionable error
+func (c *Container) CheckCredentials(ctx context.Context) error {
+	return aws.CheckCredentials(ctx, c.stsClient, aws.PreflightTimeout)
+}
+
+// AccountID returns the ID of the account the configured credentials belong
+// to, asking STS the first time
+func (c *Container) AccountID(ctx context.Context) (string, error) {
+	if c.accountID != "" {
+		return c.accountID, nil
+	}
+	account, err := aws.CallerAccount(ctx, c.stsClient, aws.PreflightTimeout)
+	if err != nil {
+		return "", err
+	}
+	c.accountID = account
+	return account, nil
+}
+
 // GetConfig returns the container configuration
 func (c *Container) GetConfig() *config.Config {
 	return c.config
@@ -261,6 +546,70 @@ func (c *Container) SetSizeService(service interfaces.SizeService) {
 	c.sizeService = service
 }
 
+// SetMetricsFormatter sets the metrics formatter (for dependency injection)
+func (c *Container) SetMetricsFormatter(formatter interfaces.MetricsFormatter) {
+	c.metricsFormatter = formatter
+}
+
+// SetReportService sets the report service (for dependency injection)
+func (c *Container) SetReportService(service interfaces.ReportService) {
+	c.reportService = service
+}
+
+// SetLifecycleService sets the lifecycle service (for dependency injection)
+func (c *Container) SetLifecycleService(service interfaces.LifecycleService) {
+	c.lifecycleService = service
+}
+
+// SetObjectCheckService sets the object check service (for dependency injection)
+func (c *Container) SetObjectCheckService(service interfaces.ObjectCheckService) {
+	c.objectCheckService = service
+}
+
+// SetDoctorService sets the doctor service (for dependency injection)
+func (c *Container) SetDoctorService(service interfaces.DoctorService) {
+	c.doctorService = service
+}
+
+// ForAccount returns a container whose AWS clients and services use the
+// credentials of account, creating it on first use. It shares the logger and
+// size cache of c, and every other setting is the same.
+func (c *Container) ForAccount(account config.Account) (*Container, error) {
+	if existing, ok := c.accounts[account.Name]; ok {
+		return existing, nil
+	}
+	
+	cfg := *c.config
+	cfg.AWSProfile = account.Profile
+	cfg.RoleARN = account.RoleARN
+	cfg.ExternalID = account.ExternalID
+	cfg.RoleSessionName = account.RoleSessionName
+	cfg.MFASerial = ""
+	
+	accountContainer := &Container{
+		config:    &cfg,
+		logger:    c.logger,
+		sizeCache: c.sizeCache,
+	}
+	if err := accountContainer.initializeAWSClients(); err != nil {
+		return nil, fmt.Errorf("failed to initialize AWS clients for account %s: %w", account.Name, err)
+	}
+	if err := accountContainer.initializeServices(); err != nil {
+		return nil, fmt.Errorf("failed to initialize services for account %s: %w", account.Name, err)
+	}
+	
+	c.SetAccountContainer(account.Name, accountContainer)
+	return accountContainer, nil
+}
+
+// SetAccountContainer sets the container of an account (for dependency injection)
+func (c *Container) SetAccountContainer(name string, account *Container) {
+	if c.accounts == nil {
+		c.accounts = make(map[string]*Container)
+	}
+	c.accounts[name] = account
+}
+
 // GetLogger returns the logger instance
 func (c *Container) GetLogger() *logging.Logger {
 	return c.logger
@@ -273,20 +622,35 @@ func (c *Container) initializeLogging() error {
 	// Console logger
 	appConfig := c.config.App()
 	loggingConfig := c.config.Logging()
-	consoleLogger := logging.NewConsoleLogger(appConfig.Verbose, appConfig.Quiet)
+	consoleLevel, err := logging.ParseLogLevel(loggingConfig.Level)
+	if err != nil {
+		return err
+	}
+	consoleLogger := logging.NewLogger(consoleLevel, os.Stderr, appConfig.Quiet)
+	consoleFormat, err := logging.ParseLogFormat(loggingConfig.Format)
+	if err != nil {
+		return err
+	}
+	consoleLogger.SetFormat(consoleFormat)
 	loggers = append(loggers, consoleLogger)
 	
 	// File logger if specified
 	if loggingConfig.File != "" {
-		level := logging.LevelInfo
-		if appConfig.Verbose {
-			level = logging.LevelDebug
+		level, err := logging.ParseLogLevel(loggingConfig.FileLevel)
+		if err != nil {
+			return err
+		}
+		
+		fileFormat, err := logging.ParseLogFormat(loggingConfig.FileFormat)
+		if err != nil {
+			return err
 		}
 		
 		fileLogger, err := logging.NewFileLogger(loggingConfig.File, level)
 		if err != nil {
 			return fmt.Errorf("failed to create file logger: %w", err)
 		}
+		fileLogger.SetFormat(fileFormat)
 		loggers = append(loggers, fileLogger)
 	}
 	
@@ -304,6 +668,9 @@ func (c *Container) initializeLogging() error {
 		"verbose":  appConfig.Verbose,
 		"quiet":    appConfig.Quiet,
 		"log_file": loggingConfig.File,
+		"log_format": loggingConfig.Format,
+		"log_level": loggingConfig.Level,
+		"log_file_level": loggingConfig.FileLevel,
 	})
 	
 	return nil
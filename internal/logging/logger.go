@@ -1,11 +1,16 @@
 package logging
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
 )
 
 // LogLevel represents the severity level of a log message
@@ -50,11 +55,48 @@ func ParseLogLevel(level string) (LogLevel, error) {
 	}
 }
 
+// LogFormat selects how log lines are written
+type LogFormat int
+
+const (
+	// FormatText writes "[ts] [LEVEL] message (key=value, ...)" lines
+	FormatText LogFormat = iota
+	// FormatJSON writes one {"ts","level","msg","fields"} object per line
+	FormatJSON
+)
+
+// String returns the string representation of the log format
+func (f LogFormat) String() string {
+	switch f {
+	case FormatJSON:
+		return "json"
+	default:
+		return "text"
+	}
+}
+
+// ParseLogFormat parses a string into a LogFormat
+func ParseLogFormat(format string) (LogFormat, error) {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return FormatText, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return FormatText, fmt.Errorf("invalid log format: %s (supported: text, json)", format)
+	}
+}
+
 // Logger represents a structured logger
 type Logger struct {
 	level  LogLevel
 	output io.Writer
 	quiet  bool
+	format LogFormat
+	mutex  sync.Mutex
+	
+	// children are the destinations of a multi-logger, each with its own level and format
+	children []*Logger
 }
 
 // NewLogger creates a new logger instance
@@ -66,6 +108,22 @@ func NewLogger(level LogLevel, output io.Writer, quiet bool) *Logger {
 	}
 }
 
+// levelOff is above every level, so nothing is logged
+const levelOff = LevelError + 1
+
+// NewNopLogger creates a logger that discards every message, for services
+// that were not given one
+func NewNopLogger() *Logger {
+	return NewLogger(levelOff, io.Discard, true)
+}
+
+// NewJSONLogger creates a logger that writes one JSON object per line
+func NewJSONLogger(output io.Writer, level LogLevel) *Logger {
+	logger := NewLogger(level, output, false)
+	logger.format = FormatJSON
+	return logger
+}
+
 // NewConsoleLogger creates a logger that writes to stdout/stderr
 func NewConsoleLogger(verbose, quiet bool) *Logger {
 	level := LevelInfo
@@ -99,23 +157,22 @@ func NewMultiLogger(loggers ...*Logger) *Logger {
 		return loggers[0]
 	}
 	
-	// Find the minimum log level
+	// Find the minimum log level; each destination still filters by its own
 	minLevel := LevelError
-	var writers []io.Writer
 	quiet := true
 	
 	for _, logger := range loggers {
 		if logger.level < minLevel {
 			minLevel = logger.level
 		}
-		writers = append(writers, logger.output)
 		if !logger.quiet {
 			quiet = false
 		}
 	}
 	
-	multiWriter := io.MultiWriter(writers...)
-	return NewLogger(minLevel, multiWriter, quiet)
+	multi := NewLogger(minLevel, io.Discard, quiet)
+	multi.children = loggers
+	return multi
 }
 
 // SetLevel sets the minimum log level
@@ -123,6 +180,11 @@ func (l *Logger) SetLevel(level LogLevel) {
 	l.level = level
 }
 
+// SetFormat sets the format of log lines
+func (l *Logger) SetFormat(format LogFormat) {
+	l.format = format
+}
+
 // IsEnabled checks if a log level is enabled
 func (l *Logger) IsEnabled(level LogLevel) bool {
 	return level >= l.level
@@ -134,19 +196,31 @@ func (l *Logger) log(level LogLevel, message string, fields map[string]interface
 		return
 	}
 	
+	if l.children != nil {
+		for _, child := range l.children {
+			child.log(level, message, fields)
+		}
+		return
+	}
+	
 	timestamp := time.Now().Format("2006-01-02T15:04:05.000Z07:00")
 	
+	if l.format == FormatJSON {
+		l.write(formatJSONLine(timestamp, level, message, fields))
+		return
+	}
+	
 	// Build the log message
 	var parts []string
 	parts = append(parts, fmt.Sprintf("[%s]", timestamp))
 	parts = append(parts, fmt.Sprintf("[%s]", level.String()))
-	parts = append(parts, message)
+	parts = append(parts, clampField(types.DisplayString(message)))
 	
-	// Add fields if any
+	// Add fields if any, escaped so that keys with line breaks stay on one line
 	if len(fields) > 0 {
 		var fieldParts []string
 		for key, value := range fields {
-			fieldParts = append(fieldParts, fmt.Sprintf("%s=%v", key, value))
+			fieldParts = append(fieldParts, fmt.Sprintf("%s=%s", key, clampField(types.DisplayString(fmt.Sprint(value)))))
 		}
 		parts = append(parts, fmt.Sprintf("(%s)", strings.Join(fieldParts, ", ")))
 	}
@@ -154,7 +228,70 @@ func (l *Logger) log(level LogLevel, message string, fields map[string]interface
 	logLine := strings.Join(parts, " ") + "\n"
 	
 	// Write to output
-	l.output.Write([]byte(logLine))
+	l.write([]byte(logLine))
+}
+
+// write writes one complete line, so concurrent lines never interleave
+func (l *Logger) write(line []byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.output.Write(line)
+}
+
+// MaxFieldLength is the longest a log message or field value is written, in
+// bytes. Longer values, such as errors quoting a pathological key, are cut
+// and marked so that one line cannot exceed a log pipeline's line limit.
+const MaxFieldLength = 512
+
+// clampField cuts s to MaxFieldLength bytes at a rune boundary, noting how much was cut
+func clampField(s string) string {
+	if len(s) <= MaxFieldLength {
+		return s
+	}
+	cut := MaxFieldLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...[%d bytes truncated]", s[:cut], len(s)-cut)
+}
+
+// jsonLine is the shape of a JSON log line
+type jsonLine struct {
+	Timestamp string                 `json:"ts"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"msg"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+// formatJSONLine encodes a log line as JSON. Errors are written as their
+// message and values that cannot be encoded fall back to their %v form.
+func formatJSONLine(timestamp string, level LogLevel, message string, fields map[string]interface{}) []byte {
+	line := jsonLine{Timestamp: timestamp, Level: level.String(), Message: clampField(message)}
+	if len(fields) > 0 {
+		line.Fields = make(map[string]interface{}, len(fields))
+		for key, value := range fields {
+			switch v := value.(type) {
+			case string:
+				line.Fields[key] = clampField(v)
+			case error:
+				line.Fields[key] = clampField(v.Error())
+			case time.Duration:
+				line.Fields[key] = v.String()
+			default:
+				if _, err := json.Marshal(v); err != nil {
+					line.Fields[key] = fmt.Sprintf("%v", v)
+				} else {
+					line.Fields[key] = v
+				}
+			}
+		}
+	}
+	
+	data, err := json.Marshal(line)
+	if err != nil {
+		data, _ = json.Marshal(jsonLine{Timestamp: timestamp, Level: level.String(), Message: message})
+	}
+	return append(data, '\n')
 }
 
 // Debug logs a debug message
@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLoggerWritesOneObjectPerLine(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewJSONLogger(&buf, LevelDebug)
+
+	logger.Debug("S3 API call", map[string]interface{}{
+		"operation": "ListParts",
+		"attempt":   2,
+		"error":     errors.New("api error SlowDown"),
+		"elapsed":   1500 * time.Millisecond,
+		"callback":  func() {},
+	})
+	logger.Info("line with \"quotes\"\nand a newline")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+
+	var first struct {
+		Timestamp string                 `json:"ts"`
+		Level     string                 `json:"level"`
+		Message   string                 `json:"msg"`
+		Fields    map[string]interface{} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("line is not valid JSON: %v\n%s", err, lines[0])
+	}
+	if _, err := time.Parse(time.RFC3339, first.Timestamp); err != nil {
+		t.Errorf("ts = %q, want RFC3339: %v", first.Timestamp, err)
+	}
+	if first.Level != "DEBUG" || first.Message != "S3 API call" {
+		t.Errorf("level, msg = %q, %q", first.Level, first.Message)
+	}
+	if first.Fields["error"] != "api error SlowDown" || first.Fields["elapsed"] != "1.5s" || first.Fields["attempt"] != float64(2) {
+		t.Errorf("fields = %v", first.Fields)
+	}
+	if _, ok := first.Fields["callback"].(string); !ok {
+		t.Errorf("unencodable field = %#v, want its %%v form", first.Fields["callback"])
+	}
+
+	var second map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("line is not valid JSON: %v\n%s", err, lines[1])
+	}
+	if _, ok := second["fields"]; ok {
+		t.Errorf("line without fields has a fields key: %s", lines[1])
+	}
+}
+
+func TestMultiLoggerKeepsDestinationFormats(t *testing.T) {
+	var console, file bytes.Buffer
+	consoleLogger := NewLogger(LevelInfo, &console, false)
+	fileLogger := NewJSONLogger(&file, LevelDebug)
+
+	logger := NewMultiLogger(consoleLogger, fileLogger)
+	logger.Debug("debug only in file")
+	logger.Info("scan finished", map[string]interface{}{"uploads": 3})
+
+	if strings.Contains(console.String(), "debug only in file") {
+		t.Errorf("console logger wrote a debug line:\n%s", console.String())
+	}
+	if !strings.Contains(console.String(), "[INFO] scan finished (uploads=3)") {
+		t.Errorf("console output is not text:\n%s", console.String())
+	}
+
+	lines := strings.Split(strings.TrimSuffix(file.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("file got %d lines, want 2:\n%s", len(lines), file.String())
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("file line is not valid JSON: %s", line)
+		}
+	}
+}
+
+func TestParseLogFormat(t *testing.T) {
+	for input, want := range map[string]LogFormat{"": FormatText, "text": FormatText, "JSON": FormatJSON} {
+		if got, err := ParseLogFormat(input); err != nil || got != want {
+			t.Errorf("ParseLogFormat(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseLogFormat("xml"); err == nil {
+		t.Error("ParseLogFormat(\"xml\") accepted an unknown format")
+	}
+}
+
+func TestLoggerEscapesAndClampsHostileValues(t *testing.T) {
+	key := strings.Repeat("evil\n\x1b[2J", 100)
+
+	var text bytes.Buffer
+	NewLogger(LevelInfo, &text, false).Info("deleting\nupload", map[string]interface{}{"key": key})
+	line := strings.TrimSuffix(text.String(), "\n")
+	if strings.ContainsAny(line, "\n\x1b") {
+		t.Errorf("text line contains control characters: %q", line)
+	}
+	if !strings.Contains(line, `deleting\nupload`) || !strings.Contains(line, "bytes truncated]") || len(line) > 4*MaxFieldLength {
+		t.Errorf("text line = %q, want an escaped message and a truncated key", line)
+	}
+
+	var structured bytes.Buffer
+	NewJSONLogger(&structured, LevelInfo).Error("abort failed", map[string]interface{}{"error": errors.New(key)})
+	var got jsonLine
+	if err := json.Unmarshal(structured.Bytes(), &got); err != nil {
+		t.Fatalf("line is not valid JSON: %v", err)
+	}
+	if value, _ := got.Fields["error"].(string); !strings.HasPrefix(value, key[:MaxFieldLength]) || !strings.HasSuffix(value, "[388 bytes truncated]") {
+		t.Errorf("error field = %q, want the first %d bytes of the error", value, MaxFieldLength)
+	}
+}
@@ -0,0 +1,82 @@
+// Package prompt asks interactive questions. Every prompt in s3mpc goes
+// through a Prompter so that --no-input disables all of them in one place.
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	xterm "golang.org/x/term"
+)
+
+// ErrConfirmationRequired is returned when a confirmation is needed but prompts are disabled
+var ErrConfirmationRequired = errors.New("confirmation required: prompts are disabled by --no-input, rerun with --force to proceed")
+
+// ErrInputRequired is returned when a value must be typed but prompts are disabled
+var ErrInputRequired = errors.New("input required: prompts are disabled by --no-input")
+
+// Prompter asks yes/no questions on in and out. In no-input mode it never
+// reads in; every question resolves to its safe default instead.
+type Prompter struct {
+	in      *bufio.Reader
+	out     io.Writer
+	noInput bool
+}
+
+// New creates a Prompter reading answers from in and writing questions to out
+func New(in io.Reader, out io.Writer, noInput bool) *Prompter {
+	if in == nil {
+		in = os.Stdin
+	}
+	if out == nil {
+		out = os.Stdout
+	}
+	return &Prompter{in: bufio.NewReader(in), out: out, noInput: noInput}
+}
+
+// NoInput reports whether prompts are disabled
+func (p *Prompter) NoInput() bool {
+	return p.noInput
+}
+
+// Confirm asks whether to go ahead with a destructive action. The safe default
+// is to decline, so in no-input mode it returns ErrConfirmationRequired.
+func (p *Prompter) Confirm(question string) (bool, error) {
+	if p.noInput {
+		return false, ErrConfirmationRequired
+	}
+
+	fmt.Fprintf(p.out, "%s (y/N): ", question)
+	response, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || response == "") {
+		return false, fmt.Errorf("failed to read user input: %w", err)
+	}
+
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes", nil
+}
+
+// Ask asks for a line of input, such as an MFA token code, and returns it
+// without surrounding whitespace. In no-input mode it returns ErrInputRequired.
+func (p *Prompter) Ask(question string) (string, error) {
+	if p.noInput {
+		return "", ErrInputRequired
+	}
+
+	fmt.Fprintf(p.out, "%s: ", question)
+	response, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || response == "") {
+		return "", fmt.Errorf("failed to read user input: %w", err)
+	}
+	return strings.TrimSpace(response), nil
+}
+
+// StdinIsTerminal reports whether standard input is an interactive terminal.
+// When it is not, --no-input is enabled unless given explicitly.
+func StdinIsTerminal() bool {
+	return xterm.IsTerminal(int(os.Stdin.Fd()))
+}
@@ -0,0 +1,81 @@
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// failingReader fails the test if a prompt reads input
+type failingReader struct {
+	t *testing.T
+}
+
+func (r failingReader) Read(p []byte) (int, error) {
+	r.t.Fatal("prompt read input in no-input mode")
+	return 0, io.EOF
+}
+
+func TestConfirmAnswers(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"yes", true}, // no trailing newline (piped input)
+	}
+
+	for _, tt := range tests {
+		var output bytes.Buffer
+		confirmed, err := New(strings.NewReader(tt.input), &output, false).Confirm("Proceed?")
+		if err != nil {
+			t.Errorf("Confirm(%q) error = %v", tt.input, err)
+		}
+		if confirmed != tt.want {
+			t.Errorf("Confirm(%q) = %v, want %v", tt.input, confirmed, tt.want)
+		}
+		if output.String() != "Proceed? (y/N): " {
+			t.Errorf("question written as %q", output.String())
+		}
+	}
+
+	if _, err := New(strings.NewReader(""), io.Discard, false).Confirm("Proceed?"); err == nil {
+		t.Error("Confirm() on closed input succeeded, want an error")
+	}
+}
+
+func TestConfirmUnderNoInput(t *testing.T) {
+	var output bytes.Buffer
+	prompter := New(failingReader{t}, &output, true)
+
+	confirmed, err := prompter.Confirm("Delete everything?")
+	if confirmed || !errors.Is(err, ErrConfirmationRequired) {
+		t.Errorf("Confirm() = %v, %v, want false and ErrConfirmationRequired", confirmed, err)
+	}
+	if output.Len() != 0 {
+		t.Errorf("no-input prompt wrote %q", output.String())
+	}
+	if !prompter.NoInput() {
+		t.Error("NoInput() = false")
+	}
+}
+
+func TestAsk(t *testing.T) {
+	var output bytes.Buffer
+	answer, err := New(strings.NewReader(" 123456 \n"), &output, false).Ask("MFA token code")
+	if err != nil || answer != "123456" {
+		t.Errorf("Ask() = %q, %v, want 123456", answer, err)
+	}
+	if output.String() != "MFA token code: " {
+		t.Errorf("question written as %q", output.String())
+	}
+
+	if _, err := New(failingReader{t}, io.Discard, true).Ask("MFA token code"); !errors.Is(err, ErrInputRequired) {
+		t.Errorf("Ask() under no-input error = %v, want ErrInputRequired", err)
+	}
+}
@@ -0,0 +1,50 @@
+package term
+
+import "sync"
+
+// Fake is a Terminal with a settable width, for tests
+type Fake struct {
+	mutex   sync.Mutex
+	width   int
+	resized chan struct{}
+}
+
+// NewFake creates a fake terminal with the given width
+func NewFake(width int) *Fake {
+	return &Fake{
+		width:   width,
+		resized: make(chan struct{}, 1),
+	}
+}
+
+// Width returns the current fake width
+func (f *Fake) Width() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.width
+}
+
+// IsTerminal always reports true
+func (f *Fake) IsTerminal() bool {
+	return true
+}
+
+// Resized returns the resize notification channel
+func (f *Fake) Resized() <-chan struct{} {
+	return f.resized
+}
+
+// Close does nothing for a fake terminal
+func (f *Fake) Close() {}
+
+// SetWidth changes the width and signals a resize, like SIGWINCH would
+func (f *Fake) SetWidth(width int) {
+	f.mutex.Lock()
+	f.width = width
+	f.mutex.Unlock()
+
+	select {
+	case f.resized <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package term
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchResize forwards SIGWINCH to the terminal's resize channel
+func watchResize(t *fileTerminal) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGWINCH)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-signals:
+				t.notify()
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package term
+
+import "time"
+
+// resizePollInterval is how often the width is checked on Windows, which has no SIGWINCH
+const resizePollInterval = 250 * time.Millisecond
+
+// watchResize polls the terminal width and notifies when it changes
+func watchResize(t *fileTerminal) {
+	go func() {
+		ticker := time.NewTicker(resizePollInterval)
+		defer ticker.Stop()
+
+		width := t.Width()
+		for {
+			select {
+			case <-ticker.C:
+				if current := t.Width(); current != width {
+					width = current
+					t.notify()
+				}
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
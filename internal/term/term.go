@@ -0,0 +1,103 @@
+// Package term provides a small abstraction over the output terminal so that
+// progress and table output can adapt to the terminal width.
+package term
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	xterm "golang.org/x/term"
+)
+
+// Terminal reports the width of an output terminal and notifies on resize
+type Terminal interface {
+	// Width returns the current width in columns, or 0 when the output is not a terminal
+	Width() int
+
+	// IsTerminal reports whether the output is an interactive terminal
+	IsTerminal() bool
+
+	// Resized returns a channel that receives a value after each width change
+	Resized() <-chan struct{}
+
+	// Close stops watching for resizes
+	Close()
+}
+
+// SupportsUnicode reports whether the terminal and locale can show Unicode
+// block characters, judging by $TERM and the first of $LC_ALL, $LC_CTYPE and
+// $LANG that is set. Without a UTF-8 locale, output should stay ASCII.
+func SupportsUnicode() bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			locale = strings.ToLower(locale)
+			return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+		}
+	}
+	return false
+}
+
+// fileTerminal implements Terminal for an *os.File such as os.Stdout
+type fileTerminal struct {
+	fd       int
+	terminal bool
+	resized  chan struct{}
+	stop     chan struct{}
+	watch    sync.Once
+	once     sync.Once
+}
+
+// New creates a Terminal for file. When file is not a terminal, Width returns
+// $COLUMNS if set and 0 otherwise, and Resized never fires.
+func New(file *os.File) Terminal {
+	return &fileTerminal{
+		fd:       int(file.Fd()),
+		terminal: xterm.IsTerminal(int(file.Fd())),
+		resized:  make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Width returns the current terminal width
+func (t *fileTerminal) Width() int {
+	if t.terminal {
+		if width, _, err := xterm.GetSize(t.fd); err == nil && width > 0 {
+			return width
+		}
+	}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return 0
+}
+
+// IsTerminal reports whether the file is a terminal
+func (t *fileTerminal) IsTerminal() bool {
+	return t.terminal
+}
+
+// Resized returns the resize notification channel, starting the watcher on first use
+func (t *fileTerminal) Resized() <-chan struct{} {
+	if t.terminal {
+		t.watch.Do(func() { watchResize(t) })
+	}
+	return t.resized
+}
+
+// Close stops the resize watcher
+func (t *fileTerminal) Close() {
+	t.once.Do(func() { close(t.stop) })
+}
+
+// notify records a resize without blocking when one is already pending
+func (t *fileTerminal) notify() {
+	select {
+	case t.resized <- struct{}{}:
+	default:
+	}
+}
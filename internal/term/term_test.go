@@ -0,0 +1,66 @@
+package term
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFakeSignalsResize(t *testing.T) {
+	fake := NewFake(120)
+	if fake.Width() != 120 {
+		t.Fatalf("Width() = %d, want 120", fake.Width())
+	}
+
+	fake.SetWidth(60)
+	select {
+	case <-fake.Resized():
+	case <-time.After(time.Second):
+		t.Fatal("expected a resize notification")
+	}
+	if fake.Width() != 60 {
+		t.Errorf("Width() = %d after resize, want 60", fake.Width())
+	}
+}
+
+func TestNewNonTerminalUsesColumns(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	t.Setenv("COLUMNS", "")
+	terminal := New(file)
+	defer terminal.Close()
+	if terminal.IsTerminal() || terminal.Width() != 0 {
+		t.Errorf("regular file: IsTerminal() = %v, Width() = %d, want false, 0", terminal.IsTerminal(), terminal.Width())
+	}
+
+	t.Setenv("COLUMNS", "132")
+	if terminal.Width() != 132 {
+		t.Errorf("Width() = %d with COLUMNS=132, want 132", terminal.Width())
+	}
+}
+
+func TestSupportsUnicode(t *testing.T) {
+	tests := []struct {
+		term, lcAll, lang string
+		want              bool
+	}{
+		{"xterm-256color", "", "en_US.UTF-8", true},
+		{"xterm-256color", "", "de_DE.utf8", true},
+		{"xterm-256color", "C", "en_US.UTF-8", false},
+		{"xterm-256color", "", "", false},
+		{"dumb", "", "en_US.UTF-8", false},
+	}
+	for _, tt := range tests {
+		t.Setenv("TERM", tt.term)
+		t.Setenv("LC_ALL", tt.lcAll)
+		t.Setenv("LC_CTYPE", "")
+		t.Setenv("LANG", tt.lang)
+		if got := SupportsUnicode(); got != tt.want {
+			t.Errorf("SupportsUnicode() with TERM=%q LC_ALL=%q LANG=%q = %v, want %v", tt.term, tt.lcAll, tt.lang, got, tt.want)
+		}
+	}
+}
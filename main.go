@@ -19,9 +19,9 @@ var (
 func main() {
 	ctx := context.Background()
 	
-	app := app.NewApp(Version)
-	if err := app.Run(ctx, os.Args[1:]); err != nil {
+	cli := app.NewApp(Version)
+	if err := cli.Run(ctx, os.Args[1:]); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(app.ExitCode(err))
 	}
 }
\ No newline at end of file
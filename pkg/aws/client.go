@@ -5,14 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"math"
-	"net"
+	"math/rand"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/middleware"
 	"golang.org/x/time/rate"
+
+	"github.com/Garvitkul/s3mpc/internal/logging"
 )
 
 // RetryConfig defines retry behavior configuration
@@ -21,6 +24,11 @@ type RetryConfig struct {
 	BaseDelay     time.Duration `json:"base_delay"`
 	MaxDelay      time.Duration `json:"max_delay"`
 	BackoffFactor float64       `json:"backoff_factor"`
+	// JitterMode randomizes each delay; empty means JitterFull
+	JitterMode    JitterMode    `json:"jitter_mode,omitempty"`
+	// Operations replaces the configuration for the named API operations,
+	// such as ListParts or AbortMultipartUpload
+	Operations    map[string]RetryConfig `json:"operations,omitempty"`
 }
 
 // DefaultRetryConfig returns the default retry configuration
@@ -30,14 +38,56 @@ func DefaultRetryConfig() RetryConfig {
 		BaseDelay:     100 * time.Millisecond,
 		MaxDelay:      30 * time.Second,
 		BackoffFactor: 2.0,
+		JitterMode:    JitterFull,
+		Operations: map[string]RetryConfig{
+			// A part listing that fails only leaves one size unmeasured
+			"ListParts": {
+				MaxRetries:    2,
+				BaseDelay:     100 * time.Millisecond,
+				MaxDelay:      5 * time.Second,
+				BackoffFactor: 2.0,
+				JitterMode:    JitterFull,
+			},
+			// An abort that gives up leaves the upload's storage billed
+			"AbortMultipartUpload": {
+				MaxRetries:    8,
+				BaseDelay:     200 * time.Millisecond,
+				MaxDelay:      30 * time.Second,
+				BackoffFactor: 2.0,
+				JitterMode:    JitterFull,
+			},
+		},
 	}
 }
 
+// ForOperation returns the retry configuration of an API operation: its
+// override from Operations, or the configuration itself
+func (r RetryConfig) ForOperation(operation string) RetryConfig {
+	if override, exists := r.Operations[operation]; exists {
+		return override
+	}
+	return r
+}
+
+// listBucketsPageSize is how many buckets each ListBuckets page asks for
+const listBucketsPageSize = 1000
+
 // S3Client wraps the AWS S3 client with retry logic and rate limiting
 type S3Client struct {
 	client      *s3.Client
 	retryConfig RetryConfig
 	rateLimiter *rate.Limiter
+	logger      *logging.Logger
+	trace       TraceConfig
+	classifier  RetryClassifier
+	// requestTimeout bounds each attempt of an API call; 0 means no bound
+	requestTimeout time.Duration
+	// awsConfig is kept so regional clients share its credentials cache
+	awsConfig aws.Config
+	// jitter randomizes retry delays; regional clients share it
+	jitter *jitterSource
+	// stats counts the API calls; regional clients share it
+	stats *apiStats
 }
 
 // ClientConfig contains configuration for creating an S3Client
@@ -45,30 +95,73 @@ type ClientConfig struct {
 	Profile     string
 	Region      string
 	RetryConfig RetryConfig
-	RateLimit   rate.Limit // requests per second
+	RateLimit   rate.Limit      // requests per second
+	Logger      *logging.Logger // defaults to the global logger
+	// Trace logs every API call with its parameters, not only failed ones
+	Trace TraceConfig
+	// RetryClassifier decides which errors are retried; defaults to NewErrorRetryClassifier
+	RetryClassifier RetryClassifier
+	// AssumeRole, if set, makes every call with the credentials of that role
+	AssumeRole *AssumeRoleConfig
+	// RequestTimeout abandons an attempt of an API call that takes longer,
+	// which is then retried like a transient error; 0 means no timeout
+	RequestTimeout time.Duration
+	// RandSource seeds the retry jitter, for deterministic tests; defaults
+	// to a source seeded with the current time
+	RandSource rand.Source
+	// UseFIPS and UseDualStack select FIPS and dual-stack endpoints. When
+	// false, AWS_USE_FIPS_ENDPOINT, AWS_USE_DUALSTACK_ENDPOINT and the
+	// use_fips_endpoint and use_dualstack_endpoint shared config settings apply.
+	UseFIPS      bool
+	UseDualStack bool
 }
 
-// NewS3Client creates a new S3Client with retry logic and rate limiting
-func NewS3Client(ctx context.Context, cfg ClientConfig) (*S3Client, error) {
-	// Load AWS configuration
-	var awsConfig aws.Config
-	var err error
-
+// LoadConfig loads the AWS configuration for cfg. Every client is built from
+// a configuration loaded here, so the endpoint (AWS_ENDPOINT_URL,
+// AWS_ENDPOINT_URL_S3 or a services section of the shared config), retry mode
+// and FIPS and dual-stack settings of the environment and shared config apply
+// to all of them, including the regional clients of ForRegion. With
+// cfg.AssumeRole the credentials are those of the assumed role, cached and
+// refreshed before they expire, so every client built from the configuration
+// shares them.
+func LoadConfig(ctx context.Context, cfg ClientConfig) (aws.Config, error) {
+	var opts []func(*config.LoadOptions) error
 	if cfg.Profile != "" {
-		awsConfig, err = config.LoadDefaultConfig(ctx,
-			config.WithSharedConfigProfile(cfg.Profile),
-			config.WithRegion(cfg.Region),
-		)
-	} else {
-		awsConfig, err = config.LoadDefaultConfig(ctx,
-			config.WithRegion(cfg.Region),
-		)
+		opts = append(opts, config.WithSharedConfigProfile(cfg.Profile))
+	}
+	if cfg.Region != "" {
+		opts = append(opts, config.WithRegion(cfg.Region))
+	}
+	if cfg.UseFIPS {
+		opts = append(opts, config.WithUseFIPSEndpoint(aws.FIPSEndpointStateEnabled))
+	}
+	if cfg.UseDualStack {
+		opts = append(opts, config.WithUseDualStackEndpoint(aws.DualStackEndpointStateEnabled))
 	}
 
+	awsConfig, err := config.LoadDefaultConfig(ctx, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
+	if cfg.AssumeRole != nil {
+		awsConfig.Credentials = cfg.AssumeRole.credentials(awsConfig)
+	}
+	return awsConfig, nil
+}
+
+// NewS3Client creates a new S3Client with retry logic and rate limiting
+func NewS3Client(ctx context.Context, cfg ClientConfig) (*S3Client, error) {
+	awsConfig, err := LoadConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewS3ClientFromConfig(awsConfig, cfg), nil
+}
+
+// NewS3ClientFromConfig creates an S3Client from an already loaded AWS
+// configuration; cfg.Profile, cfg.Region and cfg.AssumeRole are not used
+func NewS3ClientFromConfig(awsConfig aws.Config, cfg ClientConfig) *S3Client {
 	// Create S3 client
 	s3Client := s3.NewFromConfig(awsConfig)
 
@@ -85,134 +178,346 @@ func NewS3Client(ctx context.Context, cfg ClientConfig) (*S3Client, error) {
 	}
 
 	return &S3Client{
-		client:      s3Client,
-		retryConfig: retryConfig,
-		rateLimiter: rate.NewLimiter(rateLimit, int(rateLimit)),
-	}, nil
+		client:         s3Client,
+		retryConfig:    retryConfig,
+		rateLimiter:    rate.NewLimiter(rateLimit, rateLimitBurst(rateLimit)),
+		logger:         cfg.Logger,
+		trace:          cfg.Trace,
+		classifier:     cfg.RetryClassifier,
+		requestTimeout: cfg.RequestTimeout,
+		awsConfig:      awsConfig,
+		jitter:         newJitterSource(cfg.RandSource),
+		stats:          newAPIStats(),
+	}
 }
 
-// isRetryableError determines if an error should be retried
-func (c *S3Client) isRetryableError(err error) bool {
-	if err == nil {
-		return false
+// ForRegion returns a client for another region with the same credentials,
+// endpoint settings, retry behavior and per-client rate limit
+func (c *S3Client) ForRegion(region string) *S3Client {
+	awsConfig := c.awsConfig.Copy()
+	awsConfig.Region = region
+	client := NewS3ClientFromConfig(awsConfig, ClientConfig{
+		RetryConfig:     c.retryConfig,
+		RateLimit:       c.rateLimiter.Limit(),
+		Logger:          c.logger,
+		Trace:           c.trace,
+		RetryClassifier: c.classifier,
+		RequestTimeout:  c.requestTimeout,
+	})
+	client.jitter = c.jitter
+	client.stats = c.stats
+	return client
+}
+
+// apiCall identifies an S3 API call in debug logs and timeout errors
+type apiCall struct {
+	operation string
+	bucket    string
+	// params are the other parameters of the call, logged when tracing
+	params map[string]string
+}
+
+// String names the call, e.g. "ListParts on bucket logs"
+func (c apiCall) String() string {
+	if c.bucket == "" {
+		return c.operation
 	}
+	return c.operation + " on bucket " + c.bucket
+}
 
-	// Network errors are retryable
-	var netErr net.Error
-	if errors.As(err, &netErr) {
-		return netErr.Timeout() || netErr.Temporary()
+// log returns the logger API calls are written to
+func (c *S3Client) log() *logging.Logger {
+	if c.logger != nil {
+		return c.logger
 	}
+	return logging.GetGlobalLogger()
+}
 
-	// AWS service errors
-	var apiErr *types.NoSuchBucket
-	if errors.As(err, &apiErr) {
-		return false // Not retryable
+// logAttempt logs one attempt of an API call at debug level, including the
+// AWS request ID so failures can be traced with AWS support, and the retry
+// that follows it. A first attempt that succeeds is only logged when tracing.
+func (c *S3Client) logAttempt(call apiCall, attempt int, duration time.Duration, metadata middleware.Metadata, err error, retry retryStats) {
+	if err == nil && attempt == 0 && !c.trace.Enabled {
+		return
+	}
+	logger := c.log()
+	if !logger.IsEnabled(logging.LevelDebug) {
+		return
 	}
 
-	// Check for specific AWS error codes that are retryable
-	errStr := err.Error()
-	retryableErrors := []string{
-		"RequestTimeout",
-		"ServiceUnavailable",
-		"InternalError",
-		"SlowDown",
-		"TooManyRequests",
-		"RequestTimeTooSkewed",
+	fields := map[string]interface{}{
+		"operation":   call.operation,
+		"attempt":     attempt + 1,
+		"duration_ms": duration.Milliseconds(),
+	}
+	if call.bucket != "" {
+		fields["bucket"] = call.bucket
+	}
+	if c.trace.Enabled {
+		c.trace.addParams(fields, call.params)
+	}
+	if requestID := requestIDOf(metadata, err); requestID != "" {
+		fields["request_id"] = requestID
+	}
+	if err != nil {
+		fields["error"] = err
+	}
+	if attempt > 0 {
+		fields["total_backoff_ms"] = retry.totalBackoff.Milliseconds()
 	}
+	if retry.scheduled {
+		fields["retry_delay_ms"] = retry.delay.Milliseconds()
+		if retry.hinted {
+			fields["retry_after"] = true
+		}
+	}
+	logger.Debug("S3 API call", fields)
+}
 
-	for _, retryableErr := range retryableErrors {
-		if contains(errStr, retryableErr) {
-			return true
+// requestIDOf extracts the AWS request ID from a response or, for failed
+// calls, from the error
+func requestIDOf(metadata middleware.Metadata, err error) string {
+	if err != nil {
+		var responseErr interface{ ServiceRequestID() string }
+		if errors.As(err, &responseErr) {
+			return responseErr.ServiceRequestID()
 		}
+		return ""
 	}
+	requestID, _ := awsmiddleware.GetRequestIDMetadata(metadata)
+	return requestID
+}
 
-	return false
+// retryClassifier returns the classifier that decides which errors are retried
+func (c *S3Client) retryClassifier() RetryClassifier {
+	if c.classifier != nil {
+		return c.classifier
+	}
+	return NewErrorRetryClassifier()
 }
 
-// contains checks if a string contains a substring
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || 
-		(len(s) > len(substr) && (s[:len(substr)] == substr || 
-		s[len(s)-len(substr):] == substr || 
-		containsSubstring(s, substr))))
+// attemptContext returns the context of one attempt of an API call, bounded by
+// the request timeout when one is set
+func (c *S3Client) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.requestTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.requestTimeout)
 }
 
-func containsSubstring(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
+// calculateBackoffDelay calculates the delay for a retry attempt: the
+// exponential backoff capped at the maximum delay, randomized by the jitter
+// mode so workers throttled together do not retry together
+func (c *S3Client) calculateBackoffDelay(config RetryConfig, attempt int) time.Duration {
+	backoff := time.Duration(float64(config.BaseDelay) * math.Pow(config.BackoffFactor, float64(attempt)))
+	if backoff > config.MaxDelay {
+		backoff = config.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	switch config.JitterMode {
+	case JitterNone:
+		return backoff
+	case JitterEqual:
+		half := backoff / 2
+		return half + time.Duration(c.jitter.int63n(int64(backoff-half)+1))
+	default:
+		return time.Duration(c.jitter.int63n(int64(backoff) + 1))
+	}
+}
+
+// retryDelay returns how long to wait before retrying after a failed attempt.
+// A Retry-After hint in the response is honored up to the maximum delay.
+func (c *S3Client) retryDelay(config RetryConfig, attempt int, err error) (time.Duration, bool) {
+	if hint, ok := retryAfter(err, time.Now()); ok {
+		if hint > config.MaxDelay {
+			hint = config.MaxDelay
 		}
+		return hint, true
 	}
-	return false
+	return c.calculateBackoffDelay(config, attempt), false
 }
 
-// calculateBackoffDelay calculates the delay for a retry attempt
-func (c *S3Client) calculateBackoffDelay(attempt int) time.Duration {
-	delay := time.Duration(float64(c.retryConfig.BaseDelay) * math.Pow(c.retryConfig.BackoffFactor, float64(attempt)))
-	if delay > c.retryConfig.MaxDelay {
-		delay = c.retryConfig.MaxDelay
+// executeWithRetry executes a function with retry logic. The operation returns
+// the response metadata so each attempt can be logged with its request ID.
+// Each attempt gets its own context, bounded by the request timeout, so a slow
+// attempt is abandoned and retried while the caller's deadline still holds.
+func (c *S3Client) executeWithRetry(ctx context.Context, call apiCall, operation func(ctx context.Context) (middleware.Metadata, error)) error {
+	err := c.attemptWithRetry(ctx, call, operation)
+	if err != nil {
+		c.stats.operation(call.operation).failed.Add(1)
 	}
-	return delay
+	return err
 }
 
-// executeWithRetry executes a function with retry logic
-func (c *S3Client) executeWithRetry(ctx context.Context, operation func() error) error {
+// attemptWithRetry makes the attempts of executeWithRetry, counting them in
+// the client's stats
+func (c *S3Client) attemptWithRetry(ctx context.Context, call apiCall, operation func(ctx context.Context) (middleware.Metadata, error)) error {
 	var lastErr error
+	config := c.retryConfig.ForOperation(call.operation)
+	var totalBackoff time.Duration
+	counters := c.stats.operation(call.operation)
+	counters.calls.Add(1)
+
+	for attempt := 0; attempt <= config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			counters.retries.Add(1)
+		}
 
-	for attempt := 0; attempt <= c.retryConfig.MaxRetries; attempt++ {
 		// Wait for rate limiter
+		waitStart := time.Now()
 		if err := c.rateLimiter.Wait(ctx); err != nil {
-			return fmt.Errorf("rate limiter error: %w", err)
+			return &AttemptsError{Attempts: attempt, Err: fmt.Errorf("%s: rate limiter error: %w", call, err)}
 		}
+		wait := time.Since(waitStart)
+		c.stats.waited(wait)
+		c.logRateLimitWait(call, wait)
 
 		// Execute the operation
-		err := operation()
+		attemptCtx, cancel := c.attemptContext(ctx)
+		start := time.Now()
+		metadata, err := operation(attemptCtx)
+		counters.latency.Add(int64(time.Since(start)))
+		timedOut := err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded)
+		cancel()
+		if timedOut {
+			err = &RequestTimeoutError{Operation: call.operation, Bucket: call.bucket, Timeout: c.requestTimeout, Err: err}
+		}
+		if err != nil && ClassifyError(err).Category == CategoryThrottling {
+			counters.throttled.Add(1)
+		}
+
+		// The delay is decided before logging so each attempt is one line
+		retry := retryStats{totalBackoff: totalBackoff}
+		if err != nil && ctx.Err() == nil && attempt < config.MaxRetries && (timedOut || c.retryClassifier().IsRetryable(err)) {
+			retry.scheduled = true
+			retry.delay, retry.hinted = c.retryDelay(config, attempt, err)
+		}
+		c.logAttempt(call, attempt, time.Since(start), metadata, err, retry)
 		if err == nil {
 			return nil // Success
 		}
 
+		// The run's deadline passed or it was interrupted; name the call it stopped
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return &AttemptsError{Attempts: attempt + 1, Err: fmt.Errorf("%s: %w", call, ctx.Err())}
+		}
+
 		lastErr = err
 
 		// Don't retry on the last attempt
-		if attempt == c.retryConfig.MaxRetries {
+		if attempt == config.MaxRetries {
 			break
 		}
 
-		// Check if error is retryable
-		if !c.isRetryableError(err) {
-			return err // Non-retryable error
+		// Check if error is retryable; an abandoned attempt may succeed next time
+		if !timedOut && !c.retryClassifier().IsRetryable(err) {
+			return &AttemptsError{Attempts: attempt + 1, Err: err} // Non-retryable error
 		}
 
-		// Calculate backoff delay
-		delay := c.calculateBackoffDelay(attempt)
-
 		// Wait before retry
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(delay):
-			// Continue to next attempt
+			return &AttemptsError{Attempts: attempt + 1, Err: ctx.Err()}
+		case <-time.After(retry.delay):
+			totalBackoff += retry.delay
 		}
 	}
 
-	return fmt.Errorf("operation failed after %d retries: %w", c.retryConfig.MaxRetries, lastErr)
+	return &AttemptsError{
+		Attempts: config.MaxRetries + 1,
+		Err:      fmt.Errorf("operation failed after %d retries: %w", config.MaxRetries, lastErr),
+	}
 }
 
-// ListBuckets lists all S3 buckets with retry logic
+// ListBuckets lists all S3 buckets, following continuation tokens until the
+// last page. Each page is retried on its own, so a throttled page does not
+// restart the listing.
 func (c *S3Client) ListBuckets(ctx context.Context) (*s3.ListBucketsOutput, error) {
-	var result *s3.ListBucketsOutput
-	var err error
+	var all *s3.ListBucketsOutput
+	input := &s3.ListBucketsInput{MaxBuckets: aws.Int32(listBucketsPageSize)}
+
+	for {
+		var result *s3.ListBucketsOutput
+		var err error
+		call := apiCall{operation: "ListBuckets", params: map[string]string{"continuation_token": aws.ToString(input.ContinuationToken)}}
+
+		operation := func(ctx context.Context) (middleware.Metadata, error) {
+			result, err = c.client.ListBuckets(ctx, input)
+			if err != nil {
+				return middleware.Metadata{}, err
+			}
+			return result.ResultMetadata, nil
+		}
 
-	operation := func() error {
-		result, err = c.client.ListBuckets(ctx, &s3.ListBucketsInput{})
-		return err
+		if retryErr := c.executeWithRetry(ctx, call, operation); retryErr != nil {
+			return nil, retryErr
+		}
+
+		if all == nil {
+			all = result
+		} else {
+			all.Buckets = append(all.Buckets, result.Buckets...)
+		}
+
+		token := aws.ToString(result.ContinuationToken)
+		if token == "" {
+			break
+		}
+		// A repeated token would list the same page forever
+		if token == aws.ToString(input.ContinuationToken) {
+			return nil, fmt.Errorf("ListBuckets returned the same continuation token twice")
+		}
+		input = &s3.ListBucketsInput{MaxBuckets: aws.Int32(listBucketsPageSize), ContinuationToken: aws.String(token)}
 	}
 
-	if retryErr := c.executeWithRetry(ctx, operation); retryErr != nil {
-		return nil, retryErr
+	all.ContinuationToken = nil
+	return all, nil
+}
+
+// ListDirectoryBuckets lists the directory buckets (S3 Express One Zone) in
+// the client's region, following continuation tokens like ListBuckets.
+// ListBuckets only returns general purpose buckets.
+func (c *S3Client) ListDirectoryBuckets(ctx context.Context) (*s3.ListDirectoryBucketsOutput, error) {
+	var all *s3.ListDirectoryBucketsOutput
+	input := &s3.ListDirectoryBucketsInput{MaxDirectoryBuckets: aws.Int32(listBucketsPageSize)}
+
+	for {
+		var result *s3.ListDirectoryBucketsOutput
+		var err error
+
+		operation := func(ctx context.Context) (middleware.Metadata, error) {
+			result, err = c.client.ListDirectoryBuckets(ctx, input)
+			if err != nil {
+				return middleware.Metadata{}, err
+			}
+			return result.ResultMetadata, nil
+		}
+
+		if retryErr := c.executeWithRetry(ctx, apiCall{operation: "ListDirectoryBuckets"}, operation); retryErr != nil {
+			return nil, retryErr
+		}
+
+		if all == nil {
+			all = result
+		} else {
+			all.Buckets = append(all.Buckets, result.Buckets...)
+		}
+
+		token := aws.ToString(result.ContinuationToken)
+		if token == "" {
+			break
+		}
+		if token == aws.ToString(input.ContinuationToken) {
+			return nil, fmt.Errorf("ListDirectoryBuckets returned the same continuation token twice")
+		}
+		input = &s3.ListDirectoryBucketsInput{MaxDirectoryBuckets: aws.Int32(listBucketsPageSize), ContinuationToken: aws.String(token)}
 	}
 
-	return result, nil
+	all.ContinuationToken = nil
+	return all, nil
 }
 
 // GetBucketLocation gets the region of a specific bucket with retry logic
@@ -220,14 +525,17 @@ func (c *S3Client) GetBucketLocation(ctx context.Context, bucket string) (*s3.Ge
 	var result *s3.GetBucketLocationOutput
 	var err error
 
-	operation := func() error {
+	operation := func(ctx context.Context) (middleware.Metadata, error) {
 		result, err = c.client.GetBucketLocation(ctx, &s3.GetBucketLocationInput{
 			Bucket: aws.String(bucket),
 		})
-		return err
+		if err != nil {
+			return middleware.Metadata{}, err
+		}
+		return result.ResultMetadata, nil
 	}
 
-	if retryErr := c.executeWithRetry(ctx, operation); retryErr != nil {
+	if retryErr := c.executeWithRetry(ctx, apiCall{operation: "GetBucketLocation", bucket: bucket}, operation); retryErr != nil {
 		return nil, retryErr
 	}
 
@@ -239,12 +547,19 @@ func (c *S3Client) ListMultipartUploads(ctx context.Context, input *s3.ListMulti
 	var result *s3.ListMultipartUploadsOutput
 	var err error
 
-	operation := func() error {
+	operation := func(ctx context.Context) (middleware.Metadata, error) {
 		result, err = c.client.ListMultipartUploads(ctx, input)
-		return err
+		if err != nil {
+			return middleware.Metadata{}, err
+		}
+		return result.ResultMetadata, nil
 	}
 
-	if retryErr := c.executeWithRetry(ctx, operation); retryErr != nil {
+	if retryErr := c.executeWithRetry(ctx, apiCall{operation: "ListMultipartUploads", bucket: aws.ToString(input.Bucket), params: map[string]string{
+		"prefix":           aws.ToString(input.Prefix),
+		"key_marker":       aws.ToString(input.KeyMarker),
+		"upload_id_marker": aws.ToString(input.UploadIdMarker),
+	}}, operation); retryErr != nil {
 		return nil, retryErr
 	}
 
@@ -256,12 +571,41 @@ func (c *S3Client) ListParts(ctx context.Context, input *s3.ListPartsInput) (*s3
 	var result *s3.ListPartsOutput
 	var err error
 
-	operation := func() error {
+	operation := func(ctx context.Context) (middleware.Metadata, error) {
 		result, err = c.client.ListParts(ctx, input)
-		return err
+		if err != nil {
+			return middleware.Metadata{}, err
+		}
+		return result.ResultMetadata, nil
+	}
+
+	if retryErr := c.executeWithRetry(ctx, apiCall{operation: "ListParts", bucket: aws.ToString(input.Bucket), params: map[string]string{
+		"key":                aws.ToString(input.Key),
+		"upload_id":          aws.ToString(input.UploadId),
+		"part_number_marker": aws.ToString(input.PartNumberMarker),
+	}}, operation); retryErr != nil {
+		return nil, retryErr
 	}
 
-	if retryErr := c.executeWithRetry(ctx, operation); retryErr != nil {
+	return result, nil
+}
+
+// HeadObject reads the metadata of a completed object with retry logic
+func (c *S3Client) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	var result *s3.HeadObjectOutput
+	var err error
+
+	operation := func(ctx context.Context) (middleware.Metadata, error) {
+		result, err = c.client.HeadObject(ctx, input)
+		if err != nil {
+			return middleware.Metadata{}, err
+		}
+		return result.ResultMetadata, nil
+	}
+
+	if retryErr := c.executeWithRetry(ctx, apiCall{operation: "HeadObject", bucket: aws.ToString(input.Bucket), params: map[string]string{
+		"key": aws.ToString(input.Key),
+	}}, operation); retryErr != nil {
 		return nil, retryErr
 	}
 
@@ -273,12 +617,18 @@ func (c *S3Client) AbortMultipartUpload(ctx context.Context, input *s3.AbortMult
 	var result *s3.AbortMultipartUploadOutput
 	var err error
 
-	operation := func() error {
+	operation := func(ctx context.Context) (middleware.Metadata, error) {
 		result, err = c.client.AbortMultipartUpload(ctx, input)
-		return err
+		if err != nil {
+			return middleware.Metadata{}, err
+		}
+		return result.ResultMetadata, nil
 	}
 
-	if retryErr := c.executeWithRetry(ctx, operation); retryErr != nil {
+	if retryErr := c.executeWithRetry(ctx, apiCall{operation: "AbortMultipartUpload", bucket: aws.ToString(input.Bucket), params: map[string]string{
+		"key":       aws.ToString(input.Key),
+		"upload_id": aws.ToString(input.UploadId),
+	}}, operation); retryErr != nil {
 		return nil, retryErr
 	}
 
@@ -290,14 +640,89 @@ func (c *S3Client) HeadBucket(ctx context.Context, bucket string) (*s3.HeadBucke
 	var result *s3.HeadBucketOutput
 	var err error
 
-	operation := func() error {
+	operation := func(ctx context.Context) (middleware.Metadata, error) {
 		result, err = c.client.HeadBucket(ctx, &s3.HeadBucketInput{
 			Bucket: aws.String(bucket),
 		})
-		return err
+		if err != nil {
+			return middleware.Metadata{}, err
+		}
+		return result.ResultMetadata, nil
+	}
+
+	if retryErr := c.executeWithRetry(ctx, apiCall{operation: "HeadBucket", bucket: bucket}, operation); retryErr != nil {
+		return nil, retryErr
+	}
+
+	return result, nil
+}
+
+// GetBucketLifecycleConfiguration gets the lifecycle rules of a bucket with retry logic
+func (c *S3Client) GetBucketLifecycleConfiguration(ctx context.Context, bucket string) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	var result *s3.GetBucketLifecycleConfigurationOutput
+	var err error
+
+	operation := func(ctx context.Context) (middleware.Metadata, error) {
+		result, err = c.client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{
+			Bucket: aws.String(bucket),
+		})
+		if err != nil {
+			return middleware.Metadata{}, err
+		}
+		return result.ResultMetadata, nil
+	}
+
+	if retryErr := c.executeWithRetry(ctx, apiCall{operation: "GetBucketLifecycleConfiguration", bucket: bucket}, operation); retryErr != nil {
+		return nil, retryErr
+	}
+
+	return result, nil
+}
+
+// noSuchTagSetCode is returned by GetBucketTagging for a bucket without tags
+const noSuchTagSetCode = "NoSuchTagSet"
+
+// GetBucketTagging gets the tags of a bucket with retry logic. A bucket
+// without tags returns an empty tag set rather than the NoSuchTagSet error.
+func (c *S3Client) GetBucketTagging(ctx context.Context, bucket string) (*s3.GetBucketTaggingOutput, error) {
+	var result *s3.GetBucketTaggingOutput
+	var err error
+
+	operation := func(ctx context.Context) (middleware.Metadata, error) {
+		result, err = c.client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{
+			Bucket: aws.String(bucket),
+		})
+		if err != nil {
+			if ErrorCode(err) == noSuchTagSetCode {
+				result = &s3.GetBucketTaggingOutput{}
+				return middleware.Metadata{}, nil
+			}
+			return middleware.Metadata{}, err
+		}
+		return result.ResultMetadata, nil
 	}
 
-	if retryErr := c.executeWithRetry(ctx, operation); retryErr != nil {
+	if retryErr := c.executeWithRetry(ctx, apiCall{operation: "GetBucketTagging", bucket: bucket}, operation); retryErr != nil {
+		return nil, retryErr
+	}
+
+	return result, nil
+}
+
+// PutBucketLifecycleConfiguration replaces the lifecycle rules of a bucket with retry logic
+func (c *S3Client) PutBucketLifecycleConfiguration(ctx context.Context, input *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	var result *s3.PutBucketLifecycleConfigurationOutput
+	var err error
+
+	operation := func(ctx context.Context) (middleware.Metadata, error) {
+		result, err = c.client.PutBucketLifecycleConfiguration(ctx, input)
+		if err != nil {
+			return middleware.Metadata{}, err
+		}
+		return result.ResultMetadata, nil
+	}
+
+	if retryErr := c.executeWithRetry(ctx, apiCall{operation: "PutBucketLifecycleConfiguration", bucket: aws.ToString(input.Bucket)}, operation); retryErr != nil {
 		return nil, retryErr
 	}
 
@@ -317,5 +742,14 @@ func (c *S3Client) GetRetryConfig() RetryConfig {
 // UpdateRateLimit updates the rate limiter with a new limit
 func (c *S3Client) UpdateRateLimit(limit rate.Limit) {
 	c.rateLimiter.SetLimit(limit)
-	c.rateLimiter.SetBurst(int(limit))
+	c.rateLimiter.SetBurst(rateLimitBurst(limit))
+}
+
+// rateLimitBurst returns the burst for a rate limit: one second of requests,
+// and at least one so that limits below 1/s still let requests through
+func rateLimitBurst(limit rate.Limit) int {
+	if limit < 1 {
+		return 1
+	}
+	return int(limit)
 }
\ No newline at end of file
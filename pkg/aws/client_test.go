@@ -0,0 +1,490 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"golang.org/x/time/rate"
+
+	"github.com/Garvitkul/s3mpc/internal/logging"
+)
+
+// scriptedHTTPClient replays canned S3 responses in order
+type scriptedHTTPClient struct {
+	responses []scriptedResponse
+	calls     int
+	queries   []string // raw query of each request
+}
+
+type scriptedResponse struct {
+	status     int
+	requestID  string
+	body       string
+	retryAfter string // Retry-After header, if set
+}
+
+func (c *scriptedHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	response := c.responses[c.calls]
+	c.calls++
+	c.queries = append(c.queries, req.URL.RawQuery)
+	header := http.Header{"X-Amz-Request-Id": []string{response.requestID}, "Content-Type": []string{"application/xml"}}
+	if response.retryAfter != "" {
+		header.Set("Retry-After", response.retryAfter)
+	}
+	return &http.Response{
+		StatusCode: response.status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(response.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestAPICallLogsRequestIDOnRetriedFailure(t *testing.T) {
+	httpClient := &scriptedHTTPClient{responses: []scriptedResponse{
+		{status: 503, requestID: "REQ-FAILED", body: `<Error><Code>ServiceUnavailable</Code><Message>Please reduce your request rate.</Message><RequestId>REQ-FAILED</RequestId></Error>`},
+		{status: 200, requestID: "REQ-OK", body: `<ListMultipartUploadsResult><Bucket>logs</Bucket><IsTruncated>false</IsTruncated></ListMultipartUploadsResult>`},
+	}}
+
+	var buf bytes.Buffer
+	client := &S3Client{
+		client: s3.New(s3.Options{
+			Region:      "us-east-1",
+			Credentials: aws.AnonymousCredentials{},
+			HTTPClient:  httpClient,
+			Retryer:     aws.NopRetryer{},
+		}),
+		retryConfig: RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1},
+		rateLimiter: rate.NewLimiter(rate.Inf, 1),
+		logger:      logging.NewJSONLogger(&buf, logging.LevelDebug),
+	}
+
+	if _, err := client.ListMultipartUploads(context.Background(), &s3.ListMultipartUploadsInput{Bucket: aws.String("logs")}); err != nil {
+		t.Fatalf("ListMultipartUploads() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want one per attempt:\n%s", len(lines), buf.String())
+	}
+
+	want := []struct {
+		requestID string
+		failed    bool
+	}{{"REQ-FAILED", true}, {"REQ-OK", false}}
+	for i, line := range lines {
+		var entry struct {
+			Level  string                 `json:"level"`
+			Fields map[string]interface{} `json:"fields"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v\n%s", i, err, line)
+		}
+		if entry.Level != "DEBUG" || entry.Fields["operation"] != "ListMultipartUploads" || entry.Fields["bucket"] != "logs" {
+			t.Errorf("line %d = %s, want a debug ListMultipartUploads call on logs", i, line)
+		}
+		if entry.Fields["attempt"] != float64(i+1) {
+			t.Errorf("line %d attempt = %v, want %d", i, entry.Fields["attempt"], i+1)
+		}
+		if entry.Fields["request_id"] != want[i].requestID {
+			t.Errorf("line %d request_id = %v, want %s", i, entry.Fields["request_id"], want[i].requestID)
+		}
+		if _, failed := entry.Fields["error"]; failed != want[i].failed {
+			t.Errorf("line %d error field present = %v, want %v", i, failed, want[i].failed)
+		}
+		if _, ok := entry.Fields["duration_ms"]; !ok {
+			t.Errorf("line %d has no duration_ms", i)
+		}
+	}
+}
+
+func TestListBucketsFollowsContinuationTokens(t *testing.T) {
+	httpClient := &scriptedHTTPClient{responses: []scriptedResponse{
+		{status: 200, body: `<ListAllMyBucketsResult><Buckets><Bucket><Name>logs</Name><BucketRegion>eu-west-1</BucketRegion></Bucket></Buckets><ContinuationToken>page-2</ContinuationToken></ListAllMyBucketsResult>`},
+		{status: 503, body: `<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>`},
+		{status: 200, body: `<ListAllMyBucketsResult><Buckets><Bucket><Name>backups</Name><BucketRegion>us-east-1</BucketRegion></Bucket></Buckets></ListAllMyBucketsResult>`},
+	}}
+	client := &S3Client{
+		client: s3.New(s3.Options{
+			Region:      "us-east-1",
+			Credentials: aws.AnonymousCredentials{},
+			HTTPClient:  httpClient,
+			Retryer:     aws.NopRetryer{},
+		}),
+		retryConfig: RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1},
+		rateLimiter: rate.NewLimiter(rate.Inf, 1),
+	}
+
+	output, err := client.ListBuckets(context.Background())
+	if err != nil {
+		t.Fatalf("ListBuckets() error = %v", err)
+	}
+	if len(output.Buckets) != 2 || aws.ToString(output.Buckets[1].Name) != "backups" || aws.ToString(output.Buckets[0].BucketRegion) != "eu-west-1" {
+		t.Errorf("ListBuckets() = %+v, want the buckets of both pages", output.Buckets)
+	}
+	if output.ContinuationToken != nil {
+		t.Errorf("ContinuationToken = %q, want none after the last page", aws.ToString(output.ContinuationToken))
+	}
+
+	// The throttled second page is retried with its token, not listed from the start
+	if len(httpClient.queries) != 3 || strings.Contains(httpClient.queries[0], "continuation-token") {
+		t.Fatalf("queries = %q, want three calls starting without a token", httpClient.queries)
+	}
+	for _, query := range httpClient.queries[1:] {
+		if !strings.Contains(query, "continuation-token=page-2") || !strings.Contains(query, "max-buckets=1000") {
+			t.Errorf("query = %q, want the page-2 token", query)
+		}
+	}
+}
+
+func TestFailedCallRecordsAttempts(t *testing.T) {
+	unavailable := scriptedResponse{status: 503, requestID: "REQ", body: `<Error><Code>ServiceUnavailable</Code><Message>Please reduce your request rate.</Message></Error>`}
+	denied := scriptedResponse{status: 403, requestID: "REQ", body: `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`}
+
+	tests := []struct {
+		name      string
+		responses []scriptedResponse
+		want      int
+	}{
+		{"retries exhausted", []scriptedResponse{unavailable, unavailable, unavailable}, 3},
+		{"permanent error after a retry", []scriptedResponse{unavailable, denied}, 2},
+		{"permanent error", []scriptedResponse{denied}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &S3Client{
+				client: s3.New(s3.Options{
+					Region:      "us-east-1",
+					Credentials: aws.AnonymousCredentials{},
+					HTTPClient:  &scriptedHTTPClient{responses: tt.responses},
+					Retryer:     aws.NopRetryer{},
+				}),
+				retryConfig: RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1},
+				rateLimiter: rate.NewLimiter(rate.Inf, 1),
+			}
+
+			_, err := client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{Bucket: aws.String("logs"), Key: aws.String("a"), UploadId: aws.String("u")})
+			if err == nil {
+				t.Fatal("AbortMultipartUpload() succeeded")
+			}
+			if got := Attempts(err); got != tt.want {
+				t.Errorf("Attempts() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if got := Attempts(context.Canceled); got != 0 {
+		t.Errorf("Attempts(context.Canceled) = %d, want 0", got)
+	}
+}
+
+func TestProbesTreatNoSuchUploadAsAllowed(t *testing.T) {
+	noSuchUpload := scriptedResponse{status: 404, body: `<Error><Code>NoSuchUpload</Code><Message>The specified upload does not exist.</Message></Error>`}
+	denied := scriptedResponse{status: 403, body: `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`}
+
+	newClient := func(responses ...scriptedResponse) *S3Client {
+		return &S3Client{
+			client: s3.New(s3.Options{
+				Region:      "us-east-1",
+				Credentials: aws.AnonymousCredentials{},
+				HTTPClient:  &scriptedHTTPClient{responses: responses},
+				Retryer:     aws.NopRetryer{},
+			}),
+			retryConfig: RetryConfig{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1},
+			rateLimiter: rate.NewLimiter(rate.Inf, 1),
+		}
+	}
+
+	probes := map[string]func(*S3Client) error{
+		"ListParts":            func(c *S3Client) error { return c.ProbeListParts(context.Background(), "logs") },
+		"AbortMultipartUpload": func(c *S3Client) error { return c.ProbeAbortMultipartUpload(context.Background(), "logs") },
+	}
+	for name, probe := range probes {
+		if err := probe(newClient(noSuchUpload)); err != nil {
+			t.Errorf("%s probe with NoSuchUpload error = %v, want nil", name, err)
+		}
+		if err := probe(newClient(denied)); ErrorCode(err) != "AccessDenied" {
+			t.Errorf("%s probe with AccessDenied error = %v, want AccessDenied", name, err)
+		}
+	}
+}
+
+func TestGetBucketTaggingTreatsNoSuchTagSetAsNoTags(t *testing.T) {
+	newClient := func(responses ...scriptedResponse) *S3Client {
+		return &S3Client{
+			client: s3.New(s3.Options{
+				Region:      "us-east-1",
+				Credentials: aws.AnonymousCredentials{},
+				HTTPClient:  &scriptedHTTPClient{responses: responses},
+				Retryer:     aws.NopRetryer{},
+			}),
+			retryConfig: RetryConfig{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1},
+			rateLimiter: rate.NewLimiter(rate.Inf, 1),
+		}
+	}
+
+	tagged := scriptedResponse{status: 200, body: `<Tagging><TagSet><Tag><Key>env</Key><Value>prod</Value></Tag></TagSet></Tagging>`}
+	output, err := newClient(tagged).GetBucketTagging(context.Background(), "logs")
+	if err != nil || len(output.TagSet) != 1 || aws.ToString(output.TagSet[0].Key) != "env" {
+		t.Fatalf("GetBucketTagging() = %+v, %v, want the env tag", output, err)
+	}
+
+	untagged := scriptedResponse{status: 404, body: `<Error><Code>NoSuchTagSet</Code><Message>The TagSet does not exist</Message></Error>`}
+	output, err = newClient(untagged).GetBucketTagging(context.Background(), "logs")
+	if err != nil || output == nil || len(output.TagSet) != 0 {
+		t.Errorf("GetBucketTagging() on an untagged bucket = %+v, %v, want an empty tag set", output, err)
+	}
+
+	denied := scriptedResponse{status: 403, body: `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`}
+	if _, err := newClient(denied).GetBucketTagging(context.Background(), "logs"); ErrorCode(err) != "AccessDenied" {
+		t.Errorf("GetBucketTagging() error = %v, want AccessDenied", err)
+	}
+}
+
+// blockingHTTPClient blocks each of the first blocked requests until its
+// context ends, then answers the rest with an empty ListParts result
+type blockingHTTPClient struct {
+	blocked int
+	calls   int
+}
+
+func (c *blockingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.calls++
+	if c.calls <= c.blocked {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"application/xml"}},
+		Body:       io.NopCloser(strings.NewReader(`<ListPartsResult><Bucket>logs</Bucket><Key>a</Key><UploadId>u</UploadId></ListPartsResult>`)),
+		Request:    req,
+	}, nil
+}
+
+func TestRequestTimeoutAbandonsSlowAttempts(t *testing.T) {
+	newClient := func(httpClient *blockingHTTPClient, requestTimeout time.Duration) *S3Client {
+		return &S3Client{
+			client: s3.New(s3.Options{
+				Region:      "us-east-1",
+				Credentials: aws.AnonymousCredentials{},
+				HTTPClient:  httpClient,
+				Retryer:     aws.NopRetryer{},
+			}),
+			retryConfig:    RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1},
+			rateLimiter:    rate.NewLimiter(rate.Inf, 1),
+			requestTimeout: requestTimeout,
+		}
+	}
+	input := &s3.ListPartsInput{Bucket: aws.String("logs"), Key: aws.String("a"), UploadId: aws.String("u")}
+
+	// A slow attempt is abandoned and the retry succeeds
+	httpClient := &blockingHTTPClient{blocked: 1}
+	if _, err := newClient(httpClient, 20*time.Millisecond).ListParts(context.Background(), input); err != nil {
+		t.Fatalf("ListParts() error = %v, want the retry to succeed", err)
+	}
+	if httpClient.calls != 2 {
+		t.Errorf("ListParts() made %d calls, want 2", httpClient.calls)
+	}
+
+	// Every attempt times out: the error names the call
+	_, err := newClient(&blockingHTTPClient{blocked: 3}, 20*time.Millisecond).ListParts(context.Background(), input)
+	var timeoutErr *RequestTimeoutError
+	if !errors.As(err, &timeoutErr) || !strings.Contains(err.Error(), "ListParts on bucket logs timed out after 20ms") {
+		t.Errorf("ListParts() error = %v, want a request timeout naming ListParts on bucket logs", err)
+	}
+	if got := Attempts(err); got != 3 {
+		t.Errorf("Attempts() = %d, want 3", got)
+	}
+	if got := ErrorCode(err); got != "RequestTimeout" {
+		t.Errorf("ErrorCode() = %q, want RequestTimeout", got)
+	}
+
+	// The caller's deadline stops the call without retrying
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	httpClient = &blockingHTTPClient{blocked: 3}
+	_, err = newClient(httpClient, 0).ListParts(ctx, input)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "ListParts on bucket logs") || errors.As(err, &timeoutErr) {
+		t.Errorf("ListParts() error = %v, want the deadline naming ListParts on bucket logs", err)
+	}
+	if httpClient.calls != 1 {
+		t.Errorf("ListParts() made %d calls after the deadline, want 1", httpClient.calls)
+	}
+}
+
+func TestRateLimitsBelowOnePerSecondLetRequestsThrough(t *testing.T) {
+	awsConfig := aws.Config{
+		Region:      "us-east-1",
+		Credentials: aws.AnonymousCredentials{},
+		HTTPClient:  &throttlingHTTPClient{throttled: make(map[string]bool)},
+		Retryer:     func() aws.Retryer { return aws.NopRetryer{} },
+	}
+	client := NewS3ClientFromConfig(awsConfig, ClientConfig{RateLimit: 0.5, Logger: logging.NewNopLogger()})
+	input := &s3.AbortMultipartUploadInput{Bucket: aws.String("logs"), Key: aws.String("ok"), UploadId: aws.String("u")}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := client.AbortMultipartUpload(ctx, input); err != nil {
+		t.Fatalf("AbortMultipartUpload() at 0.5 requests per second error = %v, want the first request through", err)
+	}
+
+	// The next request waits two seconds, past the deadline
+	if _, err := client.AbortMultipartUpload(ctx, input); err == nil || !strings.Contains(err.Error(), "rate limiter") {
+		t.Errorf("second AbortMultipartUpload() error = %v, want the rate limiter to hold it past the deadline", err)
+	}
+
+	// Regional clients get their own limiter at the same limit
+	regional := client.ForRegion("eu-west-1")
+	if _, err := regional.AbortMultipartUpload(ctx, input); err != nil {
+		t.Fatalf("regional AbortMultipartUpload() error = %v, want the first request through", err)
+	}
+	if _, err := regional.AbortMultipartUpload(ctx, input); err == nil {
+		t.Error("second regional AbortMultipartUpload() succeeded, want the 0.5 requests per second limit")
+	}
+}
+
+func TestTraceLogsEveryCallWithRedactedKeys(t *testing.T) {
+	trace := func(config TraceConfig) []map[string]interface{} {
+		httpClient := &scriptedHTTPClient{responses: []scriptedResponse{
+			{status: 200, requestID: "REQ-OK", body: `<ListPartsResult><Bucket>logs</Bucket><Key>secret/report.csv</Key><UploadId>u1</UploadId></ListPartsResult>`},
+		}}
+		var buf bytes.Buffer
+		client := &S3Client{
+			client: s3.New(s3.Options{
+				Region:      "us-east-1",
+				Credentials: aws.AnonymousCredentials{},
+				HTTPClient:  httpClient,
+				Retryer:     aws.NopRetryer{},
+			}),
+			retryConfig: RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1},
+			rateLimiter: rate.NewLimiter(rate.Inf, 1),
+			logger:      logging.NewJSONLogger(&buf, logging.LevelDebug),
+			trace:       config,
+		}
+		input := &s3.ListPartsInput{Bucket: aws.String("logs"), Key: aws.String("secret/report.csv"), UploadId: aws.String("u1")}
+		if _, err := client.ListParts(context.Background(), input); err != nil {
+			t.Fatalf("ListParts() error = %v", err)
+		}
+
+		var entries []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var entry struct {
+				Fields map[string]interface{} `json:"fields"`
+			}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("line is not valid JSON: %v\n%s", err, line)
+			}
+			entries = append(entries, entry.Fields)
+		}
+		return entries
+	}
+
+	// Without tracing a call that succeeds at once is not logged
+	if entries := trace(TraceConfig{}); len(entries) != 0 {
+		t.Errorf("untraced successful call logged %v, want nothing", entries)
+	}
+
+	entries := trace(TraceConfig{Enabled: true})
+	if len(entries) != 1 {
+		t.Fatalf("traced call logged %d lines, want 1", len(entries))
+	}
+	fields := entries[0]
+	if fields["operation"] != "ListParts" || fields["bucket"] != "logs" || fields["upload_id"] != "u1" || fields["request_id"] != "REQ-OK" {
+		t.Errorf("traced call = %v, want ListParts on logs with its upload ID and request ID", fields)
+	}
+	if _, ok := fields["duration_ms"]; !ok {
+		t.Errorf("traced call = %v, want its latency", fields)
+	}
+	if key, _ := fields["key"].(string); !strings.HasPrefix(key, "redacted:") || strings.Contains(key, "secret") {
+		t.Errorf("traced key = %q, want it redacted", key)
+	}
+	if _, ok := fields["part_number_marker"]; ok {
+		t.Errorf("traced call = %v, want empty parameters left out", fields)
+	}
+
+	entries = trace(TraceConfig{Enabled: true, ShowKeys: true})
+	if len(entries) != 1 || entries[0]["key"] != "secret/report.csv" {
+		t.Errorf("traced call with ShowKeys = %v, want the key as it is", entries)
+	}
+}
+
+// hostRecordingHTTPClient answers every request with an empty bucket list and
+// records the host each was sent to
+type hostRecordingHTTPClient struct {
+	hosts []string
+}
+
+func (c *hostRecordingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.hosts = append(c.hosts, req.URL.Host)
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"application/xml"}},
+		Body:       io.NopCloser(strings.NewReader(`<ListAllMyBucketsResult></ListAllMyBucketsResult>`)),
+		Request:    req,
+	}, nil
+}
+
+func TestRegionalClientsInheritEndpointSettings(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(configFile, []byte("[profile local]\naws_access_key_id = AKIDEXAMPLE\naws_secret_access_key = secret\nservices = local-s3\n\n[services local-s3]\ns3 =\n  endpoint_url = http://config.example:9000\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	for _, name := range []string{"AWS_PROFILE", "AWS_REGION", "AWS_ENDPOINT_URL", "AWS_ENDPOINT_URL_S3", "AWS_USE_FIPS_ENDPOINT", "AWS_USE_DUALSTACK_ENDPOINT"} {
+		t.Setenv(name, "")
+	}
+
+	regionalHost := func(t *testing.T, cfg ClientConfig) string {
+		t.Helper()
+		cfg.Region = "us-east-1"
+		cfg.Logger = logging.NewNopLogger()
+		awsConfig, err := LoadConfig(context.Background(), cfg)
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+		httpClient := &hostRecordingHTTPClient{}
+		awsConfig.HTTPClient = httpClient
+		if _, err := NewS3ClientFromConfig(awsConfig, cfg).ForRegion("us-west-2").ListBuckets(context.Background()); err != nil {
+			t.Fatalf("ListBuckets() error = %v", err)
+		}
+		return httpClient.hosts[0]
+	}
+
+	if got := regionalHost(t, ClientConfig{}); got != "s3.us-west-2.amazonaws.com" {
+		t.Errorf("host = %s, want the regional endpoint without endpoint settings", got)
+	}
+	if got := regionalHost(t, ClientConfig{UseFIPS: true, UseDualStack: true}); got != "s3-fips.dualstack.us-west-2.amazonaws.com" {
+		t.Errorf("host with UseFIPS and UseDualStack = %s, want the FIPS dual-stack endpoint", got)
+	}
+	if got := regionalHost(t, ClientConfig{Profile: "local"}); got != "config.example:9000" {
+		t.Errorf("host with a services section = %s, want its endpoint_url", got)
+	}
+
+	t.Setenv("AWS_USE_FIPS_ENDPOINT", "true")
+	if got := regionalHost(t, ClientConfig{}); got != "s3-fips.us-west-2.amazonaws.com" {
+		t.Errorf("host with AWS_USE_FIPS_ENDPOINT = %s, want the FIPS endpoint", got)
+	}
+	t.Setenv("AWS_USE_FIPS_ENDPOINT", "")
+
+	t.Setenv("AWS_ENDPOINT_URL_S3", "http://env.example:9000")
+	if got := regionalHost(t, ClientConfig{}); got != "env.example:9000" {
+		t.Errorf("host with AWS_ENDPOINT_URL_S3 = %s, want it", got)
+	}
+}
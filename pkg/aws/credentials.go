@@ -0,0 +1,101 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// DefaultRoleSessionName is the session name used when none is given
+const DefaultRoleSessionName = "s3mpc"
+
+// roleARNPattern matches IAM role ARNs in any partition, including role paths
+var roleARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/.+$`)
+
+// AssumeRoleConfig describes a role to assume for every AWS call
+type AssumeRoleConfig struct {
+	RoleARN     string
+	SessionName string // defaults to DefaultRoleSessionName
+	ExternalID  string
+	// MFASerial is the ARN or serial of the MFA device the role requires, if any
+	MFASerial string
+	// TokenProvider returns the current MFA token code; required with MFASerial
+	TokenProvider func() (string, error)
+	// Client makes the AssumeRole calls; defaults to an STS client using the
+	// base credentials. Tests inject a fake here.
+	Client stscreds.AssumeRoleAPIClient
+}
+
+// Validate checks that the role ARN is present and looks like a role ARN
+func (c AssumeRoleConfig) Validate() error {
+	if c.RoleARN == "" {
+		return errors.New("role ARN is required")
+	}
+	if !roleARNPattern.MatchString(c.RoleARN) {
+		return fmt.Errorf("invalid role ARN %q: expected arn:<partition>:iam::<account>:role/<name>", c.RoleARN)
+	}
+	if c.MFASerial != "" && c.TokenProvider == nil {
+		return errors.New("an MFA token provider is required with an MFA serial")
+	}
+	return nil
+}
+
+// credentials returns a cached, auto-refreshing provider of the role's
+// credentials, assumed with the base credentials of awsConfig
+func (c AssumeRoleConfig) credentials(awsConfig aws.Config) aws.CredentialsProvider {
+	client := c.Client
+	if client == nil {
+		client = sts.NewFromConfig(awsConfig)
+	}
+
+	provider := stscreds.NewAssumeRoleProvider(client, c.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = c.SessionName
+		if o.RoleSessionName == "" {
+			o.RoleSessionName = DefaultRoleSessionName
+		}
+		if c.ExternalID != "" {
+			o.ExternalID = aws.String(c.ExternalID)
+		}
+		if c.MFASerial != "" {
+			o.SerialNumber = aws.String(c.MFASerial)
+			o.TokenProvider = c.TokenProvider
+		}
+	})
+
+	return aws.NewCredentialsCache(&assumeRoleProvider{roleARN: c.RoleARN, provider: provider})
+}
+
+// assumeRoleProvider names the role in AssumeRole errors, which the SDK
+// otherwise reports as a generic credential refresh failure
+type assumeRoleProvider struct {
+	roleARN  string
+	provider aws.CredentialsProvider
+}
+
+// Retrieve assumes the role
+func (p *assumeRoleProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	credentials, err := p.provider.Retrieve(ctx)
+	if err != nil {
+		return aws.Credentials{}, &AssumeRoleError{RoleARN: p.roleARN, Err: err}
+	}
+	return credentials, nil
+}
+
+// AssumeRoleError reports that a role could not be assumed
+type AssumeRoleError struct {
+	RoleARN string
+	Err     error
+}
+
+func (e *AssumeRoleError) Error() string {
+	return fmt.Sprintf("failed to assume role %s: %v", e.RoleARN, e.Err)
+}
+
+func (e *AssumeRoleError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,128 @@
+package aws
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/aws/smithy-go"
+	"golang.org/x/time/rate"
+)
+
+// fakeSTS implements stscreds.AssumeRoleAPIClient, recording each request
+type fakeSTS struct {
+	calls int64
+	input *sts.AssumeRoleInput
+	err   error
+}
+
+func (f *fakeSTS) AssumeRole(ctx context.Context, input *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	atomic.AddInt64(&f.calls, 1)
+	f.input = input
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &sts.AssumeRoleOutput{Credentials: &ststypes.Credentials{
+		AccessKeyId:     aws.String("ASIAASSUMED"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour)),
+	}}, nil
+}
+
+// assumedRoleClient returns an S3Client using the assumed role credentials and
+// canned S3 responses
+func assumedRoleClient(role AssumeRoleConfig, responses int) *S3Client {
+	var scripted []scriptedResponse
+	for i := 0; i < responses; i++ {
+		scripted = append(scripted, scriptedResponse{status: 200, requestID: "REQ", body: `<ListMultipartUploadsResult><Bucket>logs</Bucket><IsTruncated>false</IsTruncated></ListMultipartUploadsResult>`})
+	}
+
+	awsConfig := aws.Config{
+		Region:     "us-east-1",
+		HTTPClient: &scriptedHTTPClient{responses: scripted},
+		Retryer:    func() aws.Retryer { return aws.NopRetryer{} },
+	}
+	awsConfig.Credentials = role.credentials(awsConfig)
+
+	client := NewS3ClientFromConfig(awsConfig, ClientConfig{RetryConfig: RetryConfig{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1}})
+	client.rateLimiter = rate.NewLimiter(rate.Inf, 1)
+	return client
+}
+
+func TestAssumeRoleCredentialsAreSharedAcrossRegionalClients(t *testing.T) {
+	fake := &fakeSTS{}
+	client := assumedRoleClient(AssumeRoleConfig{
+		RoleARN:       "arn:aws:iam::123456789012:role/audit",
+		ExternalID:    "ext-123",
+		MFASerial:     "arn:aws:iam::111111111111:mfa/ops",
+		TokenProvider: func() (string, error) { return "654321", nil },
+		Client:        fake,
+	}, 2)
+
+	ctx := context.Background()
+	input := &s3.ListMultipartUploadsInput{Bucket: aws.String("logs")}
+	if _, err := client.ListMultipartUploads(ctx, input); err != nil {
+		t.Fatalf("ListMultipartUploads() error = %v", err)
+	}
+	if _, err := client.ForRegion("eu-west-1").ListMultipartUploads(ctx, input); err != nil {
+		t.Fatalf("regional ListMultipartUploads() error = %v", err)
+	}
+
+	if fake.calls != 1 {
+		t.Errorf("AssumeRole called %d times, want the regional client to reuse cached credentials", fake.calls)
+	}
+	if aws.ToString(fake.input.RoleArn) != "arn:aws:iam::123456789012:role/audit" ||
+		aws.ToString(fake.input.ExternalId) != "ext-123" ||
+		aws.ToString(fake.input.RoleSessionName) != DefaultRoleSessionName ||
+		aws.ToString(fake.input.SerialNumber) != "arn:aws:iam::111111111111:mfa/ops" ||
+		aws.ToString(fake.input.TokenCode) != "654321" {
+		t.Errorf("AssumeRole input = %+v", fake.input)
+	}
+}
+
+func TestAssumeRoleAccessDeniedNamesTheRole(t *testing.T) {
+	fake := &fakeSTS{err: &smithy.GenericAPIError{Code: "AccessDenied", Message: "User: arn:aws:iam::111111111111:user/ops is not authorized to perform: sts:AssumeRole"}}
+	client := assumedRoleClient(AssumeRoleConfig{RoleARN: "arn:aws:iam::123456789012:role/audit", Client: fake}, 1)
+
+	_, err := client.ListMultipartUploads(context.Background(), &s3.ListMultipartUploadsInput{Bucket: aws.String("logs")})
+	if err == nil {
+		t.Fatal("ListMultipartUploads() succeeded without credentials")
+	}
+	if !strings.Contains(err.Error(), "failed to assume role arn:aws:iam::123456789012:role/audit") {
+		t.Errorf("error = %v, want it to name the role", err)
+	}
+
+	class := ClassifyError(err)
+	if class.Category != CategoryCredentials || class.Code != "AccessDenied" || !strings.Contains(class.Explanation, "role/audit") {
+		t.Errorf("ClassifyError() = %+v, want a credentials error naming the role", class)
+	}
+	if NewErrorRetryClassifier().IsRetryable(err) {
+		t.Error("AssumeRole AccessDenied was classified as retryable")
+	}
+}
+
+func TestAssumeRoleConfigValidate(t *testing.T) {
+	tests := []struct {
+		config  AssumeRoleConfig
+		wantErr bool
+	}{
+		{AssumeRoleConfig{RoleARN: "arn:aws:iam::123456789012:role/audit"}, false},
+		{AssumeRoleConfig{RoleARN: "arn:aws-cn:iam::123456789012:role/path/audit"}, false},
+		{AssumeRoleConfig{}, true},
+		{AssumeRoleConfig{RoleARN: "audit"}, true},
+		{AssumeRoleConfig{RoleARN: "arn:aws:iam::123456789012:user/ops"}, true},
+		{AssumeRoleConfig{RoleARN: "arn:aws:iam::123456789012:role/audit", MFASerial: "arn:aws:iam::123456789012:mfa/ops"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.config, err, tt.wantErr)
+		}
+	}
+}
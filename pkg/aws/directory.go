@@ -0,0 +1,14 @@
+package aws
+
+// DirectoryBucketRegions are the regions that offer directory buckets (S3
+// Express One Zone). Directory buckets are listed per region, so these are
+// the regions searched when no region is requested.
+var DirectoryBucketRegions = []string{
+	"us-east-1",
+	"us-east-2",
+	"us-west-2",
+	"ap-south-1",
+	"ap-northeast-1",
+	"eu-west-1",
+	"eu-north-1",
+}
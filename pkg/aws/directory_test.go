@@ -0,0 +1,65 @@
+package aws
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"golang.org/x/time/rate"
+)
+
+func TestListDirectoryBucketsFollowsContinuationTokens(t *testing.T) {
+	httpClient := &scriptedHTTPClient{responses: []scriptedResponse{
+		{status: 200, body: `<ListAllMyDirectoryBucketsResult><Buckets><Bucket><Name>fast--use1-az4--x-s3</Name><BucketRegion>us-east-1</BucketRegion></Bucket></Buckets><ContinuationToken>page-2</ContinuationToken></ListAllMyDirectoryBucketsResult>`},
+		{status: 200, body: `<ListAllMyDirectoryBucketsResult><Buckets><Bucket><Name>scratch--use1-az5--x-s3</Name><BucketRegion>us-east-1</BucketRegion></Bucket></Buckets></ListAllMyDirectoryBucketsResult>`},
+	}}
+	client := &S3Client{
+		client: s3.New(s3.Options{
+			Region:      "us-east-1",
+			Credentials: aws.AnonymousCredentials{},
+			HTTPClient:  httpClient,
+			Retryer:     aws.NopRetryer{},
+		}),
+		retryConfig: RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1},
+		rateLimiter: rate.NewLimiter(rate.Inf, 1),
+	}
+
+	output, err := client.ListDirectoryBuckets(context.Background())
+	if err != nil {
+		t.Fatalf("ListDirectoryBuckets() error = %v", err)
+	}
+	if len(output.Buckets) != 2 || aws.ToString(output.Buckets[1].Name) != "scratch--use1-az5--x-s3" {
+		t.Errorf("ListDirectoryBuckets() = %+v, want the buckets of both pages", output.Buckets)
+	}
+	if output.ContinuationToken != nil {
+		t.Errorf("ContinuationToken = %q, want none after the last page", aws.ToString(output.ContinuationToken))
+	}
+}
+
+func TestDirectoryBucketsUseZonalEndpoints(t *testing.T) {
+	ctx := context.Background()
+	for _, region := range DirectoryBucketRegions {
+		if !IsValidRegion(region) {
+			t.Errorf("IsValidRegion(%q) = false, want true", region)
+		}
+	}
+
+	// Calls on a directory bucket go to the zonal endpoint named by its
+	// availability zone ID, general purpose buckets to the regional one
+	tests := map[string]string{
+		"fast--use1-az4--x-s3": "fast--use1-az4--x-s3.s3express-use1-az4.us-east-1.amazonaws.com",
+		"logs":                 "logs.s3.us-east-1.amazonaws.com",
+	}
+	for bucket, want := range tests {
+		params := s3.EndpointParameters{Region: aws.String("us-east-1"), Bucket: aws.String(bucket)}.WithDefaults()
+		endpoint, err := s3.NewDefaultEndpointResolverV2().ResolveEndpoint(ctx, params)
+		if err != nil {
+			t.Fatalf("endpoint for %s: %v", bucket, err)
+		}
+		if endpoint.URI.Host != want {
+			t.Errorf("endpoint for %s = %s, want %s", bucket, endpoint.URI.Host, want)
+		}
+	}
+}
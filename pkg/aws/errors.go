@@ -0,0 +1,244 @@
+package aws
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/aws/smithy-go"
+)
+
+// ErrorCategory groups AWS error codes by what the operator has to do about them
+type ErrorCategory string
+
+// Error categories reported to operators
+const (
+	CategoryPermission    ErrorCategory = "permission"
+	CategoryCredentials   ErrorCategory = "credentials"
+	CategoryNotFound      ErrorCategory = "not-found"
+	CategoryRegion        ErrorCategory = "region"
+	CategoryThrottling    ErrorCategory = "throttling"
+	CategoryTransient     ErrorCategory = "transient"
+	CategoryConfiguration ErrorCategory = "configuration"
+	CategoryUnknown       ErrorCategory = "unknown"
+)
+
+// ErrorClass describes an AWS error code for operators
+type ErrorClass struct {
+	Code        string
+	Category    ErrorCategory
+	Explanation string
+	Remediation string
+	// Benign errors mean the desired state already holds (e.g. the upload is already gone)
+	Benign bool
+}
+
+// noCredentialsCode is reported for credential resolution failures, which have no AWS error code
+const noCredentialsCode = "NoCredentials"
+
+// errorClasses maps error codes seen during scans and deletions to their class
+var errorClasses = map[string]ErrorClass{
+	"AccessDenied": {
+		Category:    CategoryPermission,
+		Explanation: "The request was denied by IAM or by the bucket policy",
+		Remediation: "Grant the s3 action named in the error to your identity and check the bucket policy for an explicit Deny",
+	},
+	"AllAccessDisabled": {
+		Category:    CategoryPermission,
+		Explanation: "All access to the bucket has been disabled by AWS",
+		Remediation: "Contact AWS Support; the bucket cannot be accessed by any identity",
+	},
+	"AccountProblem": {
+		Category:    CategoryPermission,
+		Explanation: "The AWS account has a problem that prevents the operation",
+		Remediation: "Check the account status and billing in the AWS console",
+	},
+	"InvalidAccessKeyId": {
+		Category:    CategoryCredentials,
+		Explanation: "The access key ID does not exist in AWS",
+		Remediation: "Check AWS_ACCESS_KEY_ID or the --profile credentials; the key may have been deleted",
+	},
+	"SignatureDoesNotMatch": {
+		Category:    CategoryCredentials,
+		Explanation: "The secret access key does not match the access key ID",
+		Remediation: "Check AWS_SECRET_ACCESS_KEY or the --profile credentials",
+	},
+	"ExpiredToken": {
+		Category:    CategoryCredentials,
+		Explanation: "The session token has expired",
+		Remediation: "Refresh your session (for example aws sso login) and rerun",
+	},
+	"InvalidToken": {
+		Category:    CategoryCredentials,
+		Explanation: "The session token is malformed or invalid",
+		Remediation: "Refresh your session credentials and rerun",
+	},
+	noCredentialsCode: {
+		Category:    CategoryCredentials,
+		Explanation: "No AWS credentials could be found",
+		Remediation: "Configure credentials with aws configure, set AWS_PROFILE, or pass --profile",
+	},
+	"RequestTimeTooSkewed": {
+		Category:    CategoryConfiguration,
+		Explanation: "The local clock differs too much from AWS time",
+		Remediation: "Synchronize the system clock (NTP) and rerun",
+	},
+	"NoSuchUpload": {
+		Category:    CategoryNotFound,
+		Explanation: "The upload no longer exists; it was already aborted or completed",
+		Remediation: "No action needed",
+		Benign:      true,
+	},
+	"NoSuchLifecycleConfiguration": {
+		Category:    CategoryNotFound,
+		Explanation: "The bucket has no lifecycle configuration",
+		Remediation: "Add an AbortIncompleteMultipartUpload lifecycle rule so stale uploads are removed automatically",
+		Benign:      true,
+	},
+	"NoSuchBucket": {
+		Category:    CategoryNotFound,
+		Explanation: "The bucket was deleted after it was listed",
+		Remediation: "No action needed unless the bucket should exist",
+	},
+	"PermanentRedirect": {
+		Category:    CategoryRegion,
+		Explanation: "The bucket is in a different region than the client used",
+		Remediation: "Rerun without --region, or with the bucket's region",
+	},
+	"AuthorizationHeaderMalformed": {
+		Category:    CategoryRegion,
+		Explanation: "The request was signed for the wrong region",
+		Remediation: "Rerun without --region, or with the bucket's region",
+	},
+	"SlowDown": {
+		Category:    CategoryThrottling,
+		Explanation: "S3 is throttling requests to this bucket",
+		Remediation: "Lower --concurrency and rerun",
+	},
+	"Throttling": {
+		Category:    CategoryThrottling,
+		Explanation: "The API is throttling requests",
+		Remediation: "Lower --concurrency and rerun",
+	},
+	"TooManyRequests": {
+		Category:    CategoryThrottling,
+		Explanation: "The API is throttling requests",
+		Remediation: "Lower --concurrency and rerun",
+	},
+	"InternalError": {
+		Category:    CategoryTransient,
+		Explanation: "S3 had an internal error",
+		Remediation: "Rerun; the operation is safe to retry",
+	},
+	"ServiceUnavailable": {
+		Category:    CategoryTransient,
+		Explanation: "S3 is temporarily unavailable",
+		Remediation: "Rerun later; the operation is safe to retry",
+	},
+	"RequestTimeout": {
+		Category:    CategoryTransient,
+		Explanation: "The connection timed out before the request completed",
+		Remediation: "Rerun; check network connectivity if it persists",
+	},
+}
+
+var (
+	// apiErrorPattern extracts the code from SDK error strings such as
+	// "... HostID: x, api error AccessDenied: Access Denied" or "... HostID: x, NoSuchUpload: ..."
+	apiErrorPattern  = regexp.MustCompile(`(?:api error |HostID: [^,]*, )([A-Za-z][A-Za-z0-9.]*):`)
+	operationPattern = regexp.MustCompile(`operation error S3: (\w+)`)
+)
+
+// credentialErrorMarkers identify credential resolution failures in SDK error strings
+var credentialErrorMarkers = []string{
+	"failed to retrieve credentials",
+	"failed to refresh cached credentials",
+	"no EC2 IMDS role found",
+	"NoCredentialProviders",
+}
+
+// ErrorCode returns the AWS error code of err, or "" when it has none
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() != "" {
+		return apiErr.ErrorCode()
+	}
+	var timeoutErr *RequestTimeoutError
+	if errors.As(err, &timeoutErr) {
+		return "RequestTimeout"
+	}
+
+	message := err.Error()
+	if matches := apiErrorPattern.FindStringSubmatch(message); matches != nil {
+		return matches[1]
+	}
+	for _, marker := range credentialErrorMarkers {
+		if strings.Contains(message, marker) {
+			return noCredentialsCode
+		}
+	}
+	return ""
+}
+
+// ClassifyError returns the class of err. Unknown codes pass through verbatim
+// with CategoryUnknown and the error message as the explanation.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClass{}
+	}
+
+	code := ErrorCode(err)
+
+	// Credential failures surface on whichever S3 call came first, so name
+	// the role rather than the S3 action
+	var assumeErr *AssumeRoleError
+	if errors.As(err, &assumeErr) {
+		if code == "" || code == noCredentialsCode {
+			code = "AssumeRoleFailed"
+		}
+		return ErrorClass{
+			Code:        code,
+			Category:    CategoryCredentials,
+			Explanation: "Could not assume role " + assumeErr.RoleARN,
+			Remediation: "Check that the role's trust policy allows your identity (and --external-id, if it requires one) and that your identity may call sts:AssumeRole on it",
+		}
+	}
+
+	class, known := errorClasses[code]
+	if !known {
+		return ErrorClass{Code: code, Category: CategoryUnknown, Explanation: err.Error()}
+	}
+	class.Code = code
+
+	if code == "AccessDenied" {
+		refineAccessDenied(&class, err.Error())
+	}
+	return class
+}
+
+// refineAccessDenied distinguishes a missing IAM permission from a resource
+// policy Deny using the detail AWS includes in the message
+func refineAccessDenied(class *ErrorClass, message string) {
+	action := "the s3 action named in the error"
+	if matches := operationPattern.FindStringSubmatch(message); matches != nil {
+		action = "s3:" + matches[1]
+	}
+
+	switch {
+	case strings.Contains(message, "explicit deny in a resource-based policy"):
+		class.Explanation = "The bucket policy explicitly denies this request"
+		class.Remediation = "Ask the bucket owner to remove the Deny for " + action + " or run as an allowed principal"
+	case strings.Contains(message, "explicit deny"):
+		class.Explanation = "An IAM policy, permissions boundary or SCP explicitly denies this request"
+		class.Remediation = "Remove the Deny for " + action + " from the policy that applies to your identity"
+	case strings.Contains(message, "no identity-based policy allows"):
+		class.Explanation = "Your IAM identity is missing a permission"
+		class.Remediation = "Grant " + action + " on the bucket to your IAM identity"
+	default:
+		class.Remediation = "Grant " + action + " to your identity and check the bucket policy for an explicit Deny"
+	}
+}
@@ -0,0 +1,71 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/smithy-go"
+)
+
+// Error strings as printed by the AWS SDK for Go v2
+const (
+	sdkAccessDeniedIdentity = "operation error S3: AbortMultipartUpload, https response error StatusCode: 403, RequestID: 4Y8ZK9XW3B1C2D3E, HostID: q2N1bX7yZk2Q9V0ab6Jm4gH1oEw=, api error AccessDenied: User: arn:aws:iam::123456789012:user/ops is not authorized to perform: s3:AbortMultipartUpload on resource: \"arn:aws:s3:::logs/a.bin\" because no identity-based policy allows the s3:AbortMultipartUpload action"
+	sdkAccessDeniedPolicy   = "operation error S3: AbortMultipartUpload, https response error StatusCode: 403, RequestID: 8C1D2E3F4A5B6C7D, HostID: Zm9vYmFy=, api error AccessDenied: User: arn:aws:iam::123456789012:user/ops is not authorized to perform: s3:AbortMultipartUpload on resource: \"arn:aws:s3:::logs/a.bin\" with an explicit deny in a resource-based policy"
+	sdkAccessDeniedBare     = "operation error S3: ListParts, https response error StatusCode: 403, RequestID: 1A2B3C4D5E6F7A8B, HostID: YmF6=, api error AccessDenied: Access Denied"
+	sdkNoSuchUpload         = "operation error S3: AbortMultipartUpload, https response error StatusCode: 404, RequestID: 2B3C4D5E6F7A8B9C, HostID: cXV4=, NoSuchUpload: "
+	sdkPermanentRedirect    = "operation error S3: ListMultipartUploads, https response error StatusCode: 301, RequestID: 3C4D5E6F7A8B9C0D, HostID: d2Fs=, api error PermanentRedirect: The bucket you are attempting to access must be addressed using the specified endpoint. Please send all future requests to this endpoint."
+	sdkSlowDown             = "operation error S3: ListParts, exceeded maximum number of attempts, 3, https response error StatusCode: 503, RequestID: 4D5E6F7A8B9C0D1E, HostID: eHl6=, api error SlowDown: Please reduce your request rate."
+	sdkExpiredToken         = "operation error S3: ListBuckets, https response error StatusCode: 400, RequestID: 5E6F7A8B9C0D1E2F, HostID: YWJj=, api error ExpiredToken: The provided token has expired."
+	sdkNoCredentials        = "operation error S3: ListBuckets, get identity: get credentials: failed to refresh cached credentials, no EC2 IMDS role found, operation error ec2imds: GetMetadata, request canceled, context deadline exceeded"
+	sdkClockSkew            = "operation error S3: ListBuckets, https response error StatusCode: 403, RequestID: 6F7A8B9C0D1E2F3A, HostID: ZGVm=, api error RequestTimeTooSkewed: The difference between the request time and the current time is too large."
+)
+
+func TestClassifyErrorSDKStrings(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		code        string
+		category    ErrorCategory
+		benign      bool
+		remediation string
+	}{
+		{"missing IAM permission", errors.New(sdkAccessDeniedIdentity), "AccessDenied", CategoryPermission, false, "Grant s3:AbortMultipartUpload on the bucket to your IAM identity"},
+		{"bucket policy deny", errors.New(sdkAccessDeniedPolicy), "AccessDenied", CategoryPermission, false, "bucket owner"},
+		{"bare access denied", errors.New(sdkAccessDeniedBare), "AccessDenied", CategoryPermission, false, "s3:ListParts"},
+		{"upload already gone", errors.New(sdkNoSuchUpload), "NoSuchUpload", CategoryNotFound, true, "No action needed"},
+		{"wrong region", errors.New(sdkPermanentRedirect), "PermanentRedirect", CategoryRegion, false, "--region"},
+		{"throttled", errors.New(sdkSlowDown), "SlowDown", CategoryThrottling, false, "--concurrency"},
+		{"expired session", errors.New(sdkExpiredToken), "ExpiredToken", CategoryCredentials, false, "Refresh"},
+		{"no credentials", errors.New(sdkNoCredentials), noCredentialsCode, CategoryCredentials, false, "--profile"},
+		{"clock skew", errors.New(sdkClockSkew), "RequestTimeTooSkewed", CategoryConfiguration, false, "clock"},
+		{"wrapped by s3mpc", fmt.Errorf("failed to abort multipart upload u1 in bucket logs: %w", errors.New(sdkNoSuchUpload)), "NoSuchUpload", CategoryNotFound, true, "No action needed"},
+		{"smithy API error", fmt.Errorf("wrapped: %w", &smithy.GenericAPIError{Code: "SlowDown", Message: "Please reduce your request rate."}), "SlowDown", CategoryThrottling, false, "--concurrency"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class := ClassifyError(tt.err)
+			if class.Code != tt.code || class.Category != tt.category || class.Benign != tt.benign {
+				t.Errorf("ClassifyError() = %+v, want code %s, category %s, benign %v", class, tt.code, tt.category, tt.benign)
+			}
+			if !strings.Contains(class.Remediation, tt.remediation) {
+				t.Errorf("Remediation = %q, want it to mention %q", class.Remediation, tt.remediation)
+			}
+		})
+	}
+}
+
+func TestClassifyErrorUnknownPassesThrough(t *testing.T) {
+	unknownCode := errors.New("operation error S3: ListParts, https response error StatusCode: 400, RequestID: 7A8B9C0D1E2F3A4B, HostID: Z2hp=, api error InvalidArgument: Part number must be an integer between 1 and 10000")
+	class := ClassifyError(unknownCode)
+	if class.Code != "InvalidArgument" || class.Category != CategoryUnknown || class.Explanation != unknownCode.Error() {
+		t.Errorf("ClassifyError(unknown code) = %+v, want code passed through with the verbatim message", class)
+	}
+
+	plain := errors.New("dial tcp: lookup s3.amazonaws.com: no such host")
+	if class := ClassifyError(plain); class.Code != "" || class.Explanation != plain.Error() {
+		t.Errorf("ClassifyError(plain) = %+v, want no code and the verbatim message", class)
+	}
+}
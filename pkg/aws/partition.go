@@ -0,0 +1,105 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Partition is a group of AWS regions with its own endpoints, credentials and
+// services, such as the commercial regions, GovCloud or China
+type Partition struct {
+	// ID is the partition identifier used in ARNs, such as aws or aws-cn
+	ID string
+	// Name is how the partition is described in messages
+	Name string
+	// PricingRegion is the region that serves the Pricing API for the
+	// partition, or empty when its Pricing API cannot be used
+	PricingRegion string
+
+	// pricingUnavailable says why a partition with a Pricing API cannot use it
+	pricingUnavailable string
+
+	regionPattern *regexp.Regexp
+}
+
+// partitions mirrors the region patterns of the SDK's partition metadata. The
+// patterns of the other partitions also match the commercial one's, so it
+// comes last.
+var partitions = []Partition{
+	{ID: "aws-us-gov", Name: "AWS GovCloud (US)", regionPattern: regexp.MustCompile(`^us-gov(-[a-z]+)+-[0-9]+$`)},
+	// The China Pricing API quotes CNY, while costs are reported in USD
+	{ID: "aws-cn", Name: "AWS China", pricingUnavailable: "the Pricing API of the AWS China partition quotes prices in CNY, not USD", regionPattern: regexp.MustCompile(`^cn(-[a-z]+)+-[0-9]+$`)},
+	{ID: "aws-iso", Name: "AWS ISO (US)", regionPattern: regexp.MustCompile(`^us-iso(-[a-z]+)+-[0-9]+$`)},
+	{ID: "aws-iso-b", Name: "AWS ISOB (US)", regionPattern: regexp.MustCompile(`^us-isob(-[a-z]+)+-[0-9]+$`)},
+	{ID: "aws-iso-e", Name: "AWS ISOE (Europe)", regionPattern: regexp.MustCompile(`^eu-isoe(-[a-z]+)+-[0-9]+$`)},
+	{ID: "aws-iso-f", Name: "AWS ISOF", regionPattern: regexp.MustCompile(`^us-isof(-[a-z]+)+-[0-9]+$`)},
+	{ID: "aws", Name: "AWS", PricingRegion: "us-east-1", regionPattern: regexp.MustCompile(`^(us|eu|ap|sa|ca|me|af|il|mx)(-[a-z]+)+-[0-9]+$`)},
+}
+
+// PartitionForRegion returns the partition a region belongs to, or false
+// when the region is not a well-formed name in any partition
+func PartitionForRegion(region string) (Partition, bool) {
+	for _, partition := range partitions {
+		if partition.regionPattern.MatchString(region) {
+			return partition, true
+		}
+	}
+	return Partition{}, false
+}
+
+// IsValidRegion reports whether region is a well-formed region name in one of
+// the AWS partitions, such as us-east-1, us-gov-west-1 or cn-north-1
+func IsValidRegion(region string) bool {
+	_, ok := PartitionForRegion(region)
+	return ok
+}
+
+// PricingRegion returns the region to call the Pricing API in for a client
+// configured for region. It fails for partitions without a Pricing API, such
+// as GovCloud, and for China, whose prices are not in USD. An empty or unknown
+// region is treated as commercial AWS.
+func PricingRegion(region string) (string, error) {
+	partition, ok := PartitionForRegion(region)
+	if !ok {
+		partition = partitions[len(partitions)-1]
+	}
+	if partition.pricingUnavailable != "" {
+		return "", errors.New(partition.pricingUnavailable)
+	}
+	if partition.PricingRegion == "" {
+		return "", fmt.Errorf("the Pricing API is not available in the %s partition", partition.Name)
+	}
+	return partition.PricingRegion, nil
+}
+
+// knownRegions are the regions AWS has launched. Well-formed regions missing
+// here are still used, since AWS launches new ones; they are only warned about.
+var knownRegions = map[string]bool{
+	"us-east-1": true, "us-east-2": true, "us-west-1": true, "us-west-2": true,
+	"af-south-1": true,
+	"ap-east-1":  true, "ap-east-2": true, "ap-south-1": true, "ap-south-2": true,
+	"ap-southeast-1": true, "ap-southeast-2": true, "ap-southeast-3": true, "ap-southeast-4": true,
+	"ap-southeast-5": true, "ap-southeast-6": true, "ap-southeast-7": true,
+	"ap-northeast-1": true, "ap-northeast-2": true, "ap-northeast-3": true,
+	"ca-central-1": true, "ca-west-1": true,
+	"eu-central-1": true, "eu-central-2": true, "eu-north-1": true, "eu-south-1": true, "eu-south-2": true,
+	"eu-west-1": true, "eu-west-2": true, "eu-west-3": true,
+	"il-central-1": true, "me-central-1": true, "me-south-1": true, "mx-central-1": true, "sa-east-1": true,
+	"us-gov-east-1": true, "us-gov-west-1": true,
+	"cn-north-1": true, "cn-northwest-1": true,
+	"us-iso-east-1": true, "us-iso-west-1": true, "us-isob-east-1": true,
+	"eu-isoe-west-1": true, "us-isof-east-1": true, "us-isof-south-1": true,
+}
+
+// NormalizeRegion returns a region as AWS writes it, in lower case without
+// surrounding whitespace, so that US-EAST-1 and " us-east-1" are us-east-1
+func NormalizeRegion(region string) string {
+	return strings.ToLower(strings.TrimSpace(region))
+}
+
+// IsKnownRegion reports whether region is one AWS is known to have launched
+func IsKnownRegion(region string) bool {
+	return knownRegions[NormalizeRegion(region)]
+}
@@ -0,0 +1,106 @@
+package aws
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+func TestPartitionForRegion(t *testing.T) {
+	tests := []struct {
+		region    string
+		partition string
+		pricing   string // "" when the partition has no Pricing API
+	}{
+		{"us-east-1", "aws", "us-east-1"},
+		{"eu-west-1", "aws", "us-east-1"},
+		{"ap-southeast-3", "aws", "us-east-1"},
+		{"il-central-1", "aws", "us-east-1"},
+		{"mx-central-1", "aws", "us-east-1"},
+		{"us-gov-west-1", "aws-us-gov", ""},
+		{"us-gov-east-1", "aws-us-gov", ""},
+		{"cn-north-1", "aws-cn", ""},
+		{"cn-northwest-1", "aws-cn", ""},
+		{"us-iso-east-1", "aws-iso", ""},
+		{"us-isob-east-1", "aws-iso-b", ""},
+		{"eu-isoe-west-1", "aws-iso-e", ""},
+		{"us-isof-south-1", "aws-iso-f", ""},
+	}
+
+	for _, tt := range tests {
+		partition, ok := PartitionForRegion(tt.region)
+		if !ok || partition.ID != tt.partition {
+			t.Errorf("PartitionForRegion(%q) = %q, %v, want %q", tt.region, partition.ID, ok, tt.partition)
+			continue
+		}
+		pricingRegion, err := PricingRegion(tt.region)
+		if pricingRegion != tt.pricing || (err != nil) != (tt.pricing == "") {
+			t.Errorf("PricingRegion(%q) = %q, %v, want %q", tt.region, pricingRegion, err, tt.pricing)
+		}
+	}
+
+	for _, region := range []string{"", "us-east", "US-EAST-1", "east-1", "us-east-1a", "us--east-1", " us-east-1", "zz-east-1", "cn-north"} {
+		if IsValidRegion(region) {
+			t.Errorf("IsValidRegion(%q) = true, want false", region)
+		}
+	}
+
+	// China's Pricing API quotes CNY, so it is skipped like GovCloud's absent one
+	if _, err := PricingRegion("cn-north-1"); err == nil || !strings.Contains(err.Error(), "CNY") {
+		t.Errorf("PricingRegion(cn-north-1) error = %v, want live pricing skipped for its CNY prices", err)
+	}
+
+	// Without a region the SDK's default, commercial AWS, is priced
+	if pricingRegion, err := PricingRegion(""); err != nil || pricingRegion != "us-east-1" {
+		t.Errorf("PricingRegion(\"\") = %q, %v, want us-east-1", pricingRegion, err)
+	}
+}
+
+func TestKnownRegions(t *testing.T) {
+	for _, region := range append([]string{"US-EAST-1", " eu-west-1 ", "us-gov-west-1", "cn-northwest-1"}, DirectoryBucketRegions...) {
+		if !IsKnownRegion(region) {
+			t.Errorf("IsKnownRegion(%q) = false, want true", region)
+		}
+	}
+	// Well-formed but not launched, so only warned about
+	if IsKnownRegion("us-east-9") || !IsValidRegion("us-east-9") {
+		t.Error("us-east-9 should be well-formed but unknown")
+	}
+	if got := NormalizeRegion("  Eu-West-1\t"); got != "eu-west-1" {
+		t.Errorf("NormalizeRegion() = %q, want eu-west-1", got)
+	}
+}
+
+func TestSDKEndpointsFollowPartitions(t *testing.T) {
+	ctx := context.Background()
+	suffixes := map[string]string{
+		"us-east-1":      ".amazonaws.com",
+		"us-gov-west-1":  ".amazonaws.com",
+		"cn-north-1":     ".amazonaws.com.cn",
+		"cn-northwest-1": ".amazonaws.com.cn",
+	}
+
+	for region, suffix := range suffixes {
+		if !IsValidRegion(region) {
+			t.Errorf("IsValidRegion(%q) = false, want true", region)
+		}
+
+		s3Endpoint, err := s3.NewDefaultEndpointResolverV2().ResolveEndpoint(ctx, s3.EndpointParameters{Region: aws.String(region)}.WithDefaults())
+		if err != nil {
+			t.Fatalf("S3 endpoint for %s: %v", region, err)
+		}
+		stsEndpoint, err := sts.NewDefaultEndpointResolverV2().ResolveEndpoint(ctx, sts.EndpointParameters{Region: aws.String(region)}.WithDefaults())
+		if err != nil {
+			t.Fatalf("STS endpoint for %s: %v", region, err)
+		}
+		for service, host := range map[string]string{"S3": s3Endpoint.URI.Host, "STS": stsEndpoint.URI.Host} {
+			if !strings.HasSuffix(host, suffix) || !strings.Contains(host, region) {
+				t.Errorf("%s endpoint for %s = %s, want a regional %s host", service, region, host, suffix)
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// PreflightTimeout bounds the credential check made before a command runs
+const PreflightTimeout = 5 * time.Second
+
+// CallerIdentityAPI resolves the identity behind the configured credentials
+type CallerIdentityAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// ssoExpiredMarkers identify an expired or missing SSO session in SDK error strings
+var ssoExpiredMarkers = []string{
+	"the SSO session has expired or is invalid",
+	"cached SSO token is expired",
+	"failed to read cached SSO token file",
+	"refresh cached SSO token failed",
+}
+
+// CredentialsError is a credential problem found by the preflight check,
+// described in one line with what to do about it
+type CredentialsError struct {
+	Problem string
+	Hint    string
+	Err     error
+}
+
+func (e *CredentialsError) Error() string {
+	return fmt.Sprintf("%s; %s", e.Problem, e.Hint)
+}
+
+func (e *CredentialsError) Unwrap() error {
+	return e.Err
+}
+
+// CheckCredentials resolves the configured credentials and calls STS
+// GetCallerIdentity with them, giving up after timeout. Failures are returned
+// as a *CredentialsError.
+func CheckCredentials(ctx context.Context, client CallerIdentityAPI, timeout time.Duration) error {
+	_, err := CallerAccount(ctx, client, timeout)
+	return err
+}
+
+// CallerAccount returns the ID of the account the configured credentials
+// belong to, checking them as CheckCredentials does
+func CallerAccount(ctx context.Context, client CallerIdentityAPI, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	output, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", ClassifyCredentialsError(err)
+	}
+	if output.Account == nil {
+		return "", nil
+	}
+	return *output.Account, nil
+}
+
+// ClassifyCredentialsError translates an error from resolving credentials or
+// calling STS into a CredentialsError naming the usual fix
+func ClassifyCredentialsError(err error) *CredentialsError {
+	message := err.Error()
+
+	var assumeErr *AssumeRoleError
+	if errors.As(err, &assumeErr) {
+		class := ClassifyError(err)
+		return &CredentialsError{Problem: "could not assume role " + assumeErr.RoleARN, Hint: lowerFirst(class.Remediation), Err: err}
+	}
+
+	if ssoSessionExpired(err) {
+		return &CredentialsError{Problem: "the AWS SSO session has expired", Hint: "run aws sso login (with --profile for a named profile) and retry", Err: err}
+	}
+
+	switch ErrorCode(err) {
+	case noCredentialsCode:
+		return &CredentialsError{Problem: "no AWS credentials found", Hint: "run aws configure, set AWS_PROFILE or AWS_ACCESS_KEY_ID, or pass --profile", Err: err}
+	case "ExpiredToken", "ExpiredTokenException", "TokenRefreshRequired":
+		return &CredentialsError{Problem: "the AWS session token has expired", Hint: "refresh the temporary credentials (for example aws sso login, or a new AWS_SESSION_TOKEN) and retry", Err: err}
+	case "RequestTimeTooSkewed":
+		return clockSkewError(err)
+	case "SignatureDoesNotMatch":
+		// STS reports a skewed clock as an expired or future signature
+		if strings.Contains(message, "Signature expired") || strings.Contains(message, "Signature not yet current") {
+			return clockSkewError(err)
+		}
+		return &CredentialsError{Problem: "the AWS secret access key does not match the access key ID", Hint: "check AWS_SECRET_ACCESS_KEY or the --profile credentials", Err: err}
+	case "InvalidClientTokenId", "InvalidAccessKeyId":
+		return &CredentialsError{Problem: "the AWS access key ID is not valid", Hint: "check AWS_ACCESS_KEY_ID or the --profile credentials; the key may have been deleted", Err: err}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &CredentialsError{Problem: "could not reach AWS STS to check credentials", Hint: "check network access and proxy settings, or pass --skip-preflight", Err: err}
+	}
+	return &CredentialsError{Problem: "could not verify AWS credentials: " + firstLine(message), Hint: "run s3mpc doctor for details, or pass --skip-preflight", Err: err}
+}
+
+// ssoSessionExpired reports whether err comes from an expired or missing SSO
+// session, which also shows up as SSO rejecting the cached token
+func ssoSessionExpired(err error) bool {
+	var tokenErr *ssocreds.InvalidTokenError
+	if errors.As(err, &tokenErr) {
+		return true
+	}
+	message := err.Error()
+	for _, marker := range ssoExpiredMarkers {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return strings.Contains(message, "operation error SSO") && strings.Contains(message, "UnauthorizedException")
+}
+
+// clockSkewError reports a local clock too far from AWS time
+func clockSkewError(err error) *CredentialsError {
+	return &CredentialsError{Problem: "the local clock differs too much from AWS time", Hint: "synchronize the system clock (NTP) and retry", Err: err}
+}
+
+// firstLine returns s up to its first line break
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// lowerFirst lowercases the first letter of s, to continue a sentence with it
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
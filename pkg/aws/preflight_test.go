@@ -0,0 +1,112 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+// Error strings as printed by the AWS SDK for Go v2 for GetCallerIdentity
+const (
+	sdkSTSNoCredentials   = "operation error STS: GetCallerIdentity, get identity: get credentials: failed to refresh cached credentials, no EC2 IMDS role found, operation error ec2imds: GetMetadata, request canceled, context deadline exceeded"
+	sdkSTSSSOUnauthorized = "operation error STS: GetCallerIdentity, get identity: get credentials: failed to refresh cached credentials, operation error SSO: GetRoleCredentials, https response error StatusCode: 401, RequestID: 7A8B9C0D1E2F3A4B, UnauthorizedException: Session token not found or invalid"
+	sdkSTSExpiredToken    = "operation error STS: GetCallerIdentity, https response error StatusCode: 403, RequestID: 8B9C0D1E2F3A4B5C, api error ExpiredToken: The security token included in the request is expired"
+	sdkSTSSignatureSkew   = "operation error STS: GetCallerIdentity, https response error StatusCode: 403, RequestID: 9C0D1E2F3A4B5C6D, api error SignatureDoesNotMatch: Signature expired: 20240601T101500Z is now earlier than 20240601T102000Z (20240601T103500Z - 15 min.)"
+	sdkSTSBadSecret       = "operation error STS: GetCallerIdentity, https response error StatusCode: 403, RequestID: 0D1E2F3A4B5C6D7E, api error SignatureDoesNotMatch: The request signature we calculated does not match the signature you provided."
+	sdkSTSBadKey          = "operation error STS: GetCallerIdentity, https response error StatusCode: 403, RequestID: 1E2F3A4B5C6D7E8F, api error InvalidClientTokenId: The security token included in the request is invalid."
+)
+
+func TestClassifyCredentialsError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		problem string
+		hint    string
+	}{
+		{"no credentials", errors.New(sdkSTSNoCredentials), "no AWS credentials found", "aws configure"},
+		{"expired SSO token", fmt.Errorf("get credentials: failed to refresh cached credentials, %w", &ssocreds.InvalidTokenError{}), "SSO session has expired", "aws sso login"},
+		{"SSO session rejected", errors.New(sdkSTSSSOUnauthorized), "SSO session has expired", "aws sso login"},
+		{"expired session token", errors.New(sdkSTSExpiredToken), "session token has expired", "refresh"},
+		{"expired session token from smithy", &smithy.OperationError{ServiceID: "STS", OperationName: "GetCallerIdentity", Err: &smithy.GenericAPIError{Code: "ExpiredToken"}}, "session token has expired", "refresh"},
+		{"clock skew", errors.New(sdkSTSSignatureSkew), "local clock", "NTP"},
+		{"wrong secret key", errors.New(sdkSTSBadSecret), "secret access key", "AWS_SECRET_ACCESS_KEY"},
+		{"unknown access key", errors.New(sdkSTSBadKey), "access key ID is not valid", "AWS_ACCESS_KEY_ID"},
+		{"assume role denied", &AssumeRoleError{RoleARN: "arn:aws:iam::123456789012:role/audit", Err: errors.New("api error AccessDenied: not authorized")}, "could not assume role arn:aws:iam::123456789012:role/audit", "trust policy"},
+		{"STS unreachable", fmt.Errorf("operation error STS: GetCallerIdentity, https response error: %w", context.DeadlineExceeded), "could not reach AWS STS", "--skip-preflight"},
+		{"anything else", errors.New("operation error STS: GetCallerIdentity, tls: handshake failure\nmore detail"), "could not verify AWS credentials: operation error STS: GetCallerIdentity, tls: handshake failure", "s3mpc doctor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credErr := ClassifyCredentialsError(tt.err)
+			if !strings.Contains(credErr.Problem, tt.problem) || !strings.Contains(credErr.Hint, tt.hint) {
+				t.Errorf("ClassifyCredentialsError() = %q, want problem %q and hint mentioning %q", credErr.Error(), tt.problem, tt.hint)
+			}
+			if strings.Contains(credErr.Error(), "\n") {
+				t.Errorf("Error() = %q, want a single line", credErr.Error())
+			}
+			if !errors.Is(credErr, tt.err) {
+				t.Error("CredentialsError does not unwrap to the SDK error")
+			}
+		})
+	}
+}
+
+// identityClient returns account or err from GetCallerIdentity, or waits
+// for the context to end when block is set
+type identityClient struct {
+	account string
+	err     error
+	block   bool
+}
+
+func (c *identityClient) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	if c.block {
+		<-ctx.Done()
+		return nil, fmt.Errorf("operation error STS: GetCallerIdentity, %w", ctx.Err())
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &sts.GetCallerIdentityOutput{Account: &c.account}, nil
+}
+
+func TestCheckCredentials(t *testing.T) {
+	if err := CheckCredentials(context.Background(), &identityClient{}, time.Second); err != nil {
+		t.Errorf("CheckCredentials() error = %v, want nil", err)
+	}
+
+	var credErr *CredentialsError
+	err := CheckCredentials(context.Background(), &identityClient{err: errors.New(sdkSTSNoCredentials)}, time.Second)
+	if !errors.As(err, &credErr) || credErr.Problem != "no AWS credentials found" {
+		t.Errorf("CheckCredentials() error = %v, want no credentials", err)
+	}
+
+	start := time.Now()
+	err = CheckCredentials(context.Background(), &identityClient{block: true}, 50*time.Millisecond)
+	if !errors.As(err, &credErr) || !strings.Contains(credErr.Problem, "could not reach AWS STS") {
+		t.Errorf("CheckCredentials() error = %v, want STS unreachable", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("CheckCredentials() took %s, want it to give up after the timeout", elapsed)
+	}
+}
+
+func TestCallerAccount(t *testing.T) {
+	account, err := CallerAccount(context.Background(), &identityClient{account: "123456789012"}, time.Second)
+	if err != nil || account != "123456789012" {
+		t.Errorf("CallerAccount() = %q, %v, want 123456789012", account, err)
+	}
+
+	var credErr *CredentialsError
+	if _, err := CallerAccount(context.Background(), &identityClient{err: errors.New(sdkSTSNoCredentials)}, time.Second); !errors.As(err, &credErr) {
+		t.Errorf("CallerAccount() error = %v, want a CredentialsError", err)
+	}
+}
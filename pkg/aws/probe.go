@@ -0,0 +1,57 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Probes name an upload that cannot exist, so they check permissions without
+// reading or changing any data
+const (
+	probeKey      = ".s3mpc-doctor-probe"
+	probeUploadID = "s3mpc-doctor-probe"
+)
+
+// ProbeListMultipartUploads checks that the caller may list the incomplete
+// uploads of bucket, reading at most one
+func (c *S3Client) ProbeListMultipartUploads(ctx context.Context, bucket string) error {
+	_, err := c.ListMultipartUploads(ctx, &s3.ListMultipartUploadsInput{
+		Bucket:     aws.String(bucket),
+		MaxUploads: aws.Int32(1),
+	})
+	return err
+}
+
+// ProbeListParts checks that the caller may list the parts of uploads in
+// bucket. S3 checks permissions before looking the upload up, so NoSuchUpload
+// for the probe upload means the call is allowed.
+func (c *S3Client) ProbeListParts(ctx context.Context, bucket string) error {
+	_, err := c.ListParts(ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(probeKey),
+		UploadId: aws.String(probeUploadID),
+		MaxParts: aws.Int32(1),
+	})
+	return probeResult(err)
+}
+
+// ProbeAbortMultipartUpload checks that the caller may abort uploads in
+// bucket by aborting the probe upload, which does not exist
+func (c *S3Client) ProbeAbortMultipartUpload(ctx context.Context, bucket string) error {
+	_, err := c.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(probeKey),
+		UploadId: aws.String(probeUploadID),
+	})
+	return probeResult(err)
+}
+
+// probeResult treats NoSuchUpload for the probe upload as success
+func probeResult(err error) error {
+	if ErrorCode(err) == "NoSuchUpload" {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,228 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+// JitterMode selects how retry delays are randomized
+type JitterMode string
+
+const (
+	// JitterFull waits a random delay between zero and the backoff, as AWS
+	// recommends, so workers throttled together spread their retries out
+	JitterFull JitterMode = "full"
+	// JitterEqual waits half the backoff plus a random delay up to the other half
+	JitterEqual JitterMode = "equal"
+	// JitterNone waits exactly the backoff
+	JitterNone JitterMode = "none"
+)
+
+// jitterSource draws random retry delays. rand.Rand is not safe for
+// concurrent use, so draws are serialized.
+type jitterSource struct {
+	mutex  sync.Mutex
+	random *rand.Rand
+}
+
+// newJitterSource returns a jitter source drawing from source, or from a
+// source seeded with the current time when source is nil
+func newJitterSource(source rand.Source) *jitterSource {
+	if source == nil {
+		source = rand.NewSource(time.Now().UnixNano())
+	}
+	return &jitterSource{random: rand.New(source)}
+}
+
+// int63n returns a random number in [0, n). A nil source draws from the
+// global one, for clients built without NewS3ClientFromConfig.
+func (j *jitterSource) int63n(n int64) int64 {
+	if j == nil {
+		return rand.Int63n(n)
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.random.Int63n(n)
+}
+
+// retryStats describes the retry after one attempt of an API call, for the
+// attempt's debug log line
+type retryStats struct {
+	// scheduled is set when the attempt failed and is retried after delay
+	scheduled bool
+	delay     time.Duration
+	// hinted is set when delay came from a Retry-After header
+	hinted bool
+	// totalBackoff is how long the call waited between its earlier attempts
+	totalBackoff time.Duration
+}
+
+// retryAfter returns the delay a failed response asked for in its
+// Retry-After header, given in seconds or as an HTTP date
+func retryAfter(err error, now time.Time) (time.Duration, bool) {
+	var responseErr *awshttp.ResponseError
+	if !errors.As(err, &responseErr) || responseErr.Response == nil {
+		return 0, false
+	}
+	value := strings.TrimSpace(responseErr.Response.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := at.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// RetryClassifier decides whether a failed S3 call is worth retrying
+type RetryClassifier interface {
+	IsRetryable(err error) bool
+}
+
+// ErrorRetryClassifier classifies errors by their API error code, HTTP status
+// and connection state. The code sets take precedence over the status code,
+// so a throttled 503 and a NoSuchUpload 404 are classified by their code.
+type ErrorRetryClassifier struct {
+	// ThrottleCodes are API error codes that mean the caller should slow down
+	ThrottleCodes retry.ThrottleErrorCode
+	// TransientCodes are API error codes for failures that may succeed on retry
+	TransientCodes map[string]struct{}
+	// PermanentCodes are API error codes that never succeed on retry
+	PermanentCodes map[string]struct{}
+}
+
+// NewErrorRetryClassifier returns the classifier used by S3Client: the SDK's
+// throttling and transient codes plus the S3-specific ones
+func NewErrorRetryClassifier() ErrorRetryClassifier {
+	throttleCodes := map[string]struct{}{"TooManyRequests": {}}
+	for code := range retry.DefaultThrottleErrorCodes {
+		throttleCodes[code] = struct{}{}
+	}
+
+	transientCodes := map[string]struct{}{
+		"InternalError":      {},
+		"ServiceUnavailable": {},
+	}
+	for code := range retry.DefaultRetryableErrorCodes {
+		transientCodes[code] = struct{}{}
+	}
+
+	return ErrorRetryClassifier{
+		ThrottleCodes:  retry.ThrottleErrorCode{Codes: throttleCodes},
+		TransientCodes: transientCodes,
+		PermanentCodes: map[string]struct{}{
+			"AccessDenied":         {},
+			"NoSuchBucket":         {},
+			"NoSuchKey":            {},
+			"NoSuchUpload":         {},
+			"RequestTimeTooSkewed": {},
+		},
+	}
+}
+
+// IsRetryable reports whether err is a throttling error, a transient service
+// error (5xx) or a transient connection error. Cancelled and timed-out
+// contexts, 403s, 404s and permanent codes are never retried.
+func (c ErrorRetryClassifier) IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// The caller gave up; retrying would only fail again
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		(retry.NoRetryCanceledError{}).IsErrorRetryable(err) == aws.FalseTernary {
+		return false
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code := apiErr.ErrorCode()
+		if _, permanent := c.PermanentCodes[code]; permanent {
+			return false
+		}
+		if c.ThrottleCodes.IsErrorThrottle(err) == aws.TrueTernary {
+			return true
+		}
+		if _, transient := c.TransientCodes[code]; transient {
+			return true
+		}
+	}
+
+	var responseErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &responseErr) {
+		status := responseErr.HTTPStatusCode()
+		switch {
+		case status == http.StatusForbidden || status == http.StatusNotFound:
+			return false
+		case status == http.StatusTooManyRequests || status >= http.StatusInternalServerError:
+			return true
+		}
+		// Any other response means the request reached S3 and was rejected
+		return false
+	}
+
+	return retry.RetryableConnectionError{}.IsErrorRetryable(err) == aws.TrueTernary
+}
+
+// RequestTimeoutError reports an attempt of an API call that was abandoned
+// after the request timeout
+type RequestTimeoutError struct {
+	Operation string
+	Bucket    string
+	Timeout   time.Duration
+	Err       error
+}
+
+func (e *RequestTimeoutError) Error() string {
+	call := apiCall{operation: e.Operation, bucket: e.Bucket}
+	return fmt.Sprintf("%s timed out after %s (--request-timeout)", call, e.Timeout)
+}
+
+func (e *RequestTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// AttemptsError records how many attempts a failed S3Client call made
+type AttemptsError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *AttemptsError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AttemptsError) Unwrap() error {
+	return e.Err
+}
+
+// Attempts returns the number of attempts recorded in err, or 0 when err did
+// not come from an S3Client call
+func Attempts(err error) int {
+	var attemptsErr *AttemptsError
+	if errors.As(err, &attemptsErr) {
+		return attemptsErr.Attempts
+	}
+	return 0
+}
@@ -0,0 +1,262 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"golang.org/x/time/rate"
+
+	"github.com/Garvitkul/s3mpc/internal/logging"
+)
+
+// responseError wraps err in the SDK's HTTP response error with a status code
+func responseError(status int, err error) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+			Err:      err,
+		},
+		RequestID: "REQ",
+	}
+}
+
+// operationError wraps err the way the SDK reports a failed operation
+func operationError(err error) error {
+	return &smithy.OperationError{ServiceID: "S3", OperationName: "ListParts", Err: err}
+}
+
+func apiError(code string) error {
+	return &smithy.GenericAPIError{Code: code, Message: code}
+}
+
+func TestErrorRetryClassifier(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"SlowDown 503", operationError(responseError(503, apiError("SlowDown"))), true},
+		{"Throttling 400", operationError(responseError(400, apiError("Throttling"))), true},
+		{"TooManyRequests 429", operationError(responseError(429, apiError("TooManyRequests"))), true},
+		{"503 without a code", operationError(responseError(503, errors.New("service unavailable"))), true},
+		{"500 InternalError", operationError(responseError(500, apiError("InternalError"))), true},
+		{"400 RequestTimeout", operationError(responseError(400, apiError("RequestTimeout"))), true},
+		{"wrapped 502", fmt.Errorf("failed to list parts: %w", operationError(responseError(502, errors.New("bad gateway")))), true},
+		{"403 AccessDenied", operationError(responseError(403, apiError("AccessDenied"))), false},
+		{"403 without a code", operationError(responseError(403, errors.New("forbidden"))), false},
+		{"403 RequestTimeTooSkewed", operationError(responseError(403, apiError("RequestTimeTooSkewed"))), false},
+		{"404 NoSuchUpload", operationError(responseError(404, apiError("NoSuchUpload"))), false},
+		{"404 NoSuchBucket", operationError(responseError(404, apiError("NoSuchBucket"))), false},
+		{"NoSuchUpload on a 503", operationError(responseError(503, apiError("NoSuchUpload"))), false},
+		{"400 InvalidArgument", operationError(responseError(400, apiError("InvalidArgument"))), false},
+		{"connection reset", operationError(&url.Error{Op: "Get", URL: "https://s3", Err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}}), true},
+		{"dial failure", operationError(&url.Error{Op: "Get", URL: "https://s3", Err: &net.OpError{Op: "dial", Err: errors.New("no route to host")}}), true},
+		{"connection refused", operationError(&url.Error{Op: "Get", URL: "https://s3", Err: syscall.ECONNREFUSED}), true},
+		{"unknown host", operationError(&url.Error{Op: "Get", URL: "https://s3", Err: &net.DNSError{Err: "no such host", Name: "s3", IsNotFound: true}}), false},
+		{"context canceled", operationError(context.Canceled), false},
+		{"context deadline", fmt.Errorf("list parts: %w", context.DeadlineExceeded), false},
+		{"plain error mentioning SlowDown", errors.New("SlowDown"), false},
+	}
+
+	classifier := NewErrorRetryClassifier()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifier.IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	config := RetryConfig{MaxRetries: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, BackoffFactor: 2}
+	newClient := func(seed int64) *S3Client {
+		return NewS3ClientFromConfig(aws.Config{}, ClientConfig{RetryConfig: config, RandSource: rand.NewSource(seed)})
+	}
+
+	// Full jitter spreads delays over [0, backoff], the same way for one seed
+	first, second := newClient(7), newClient(7)
+	distinct := map[time.Duration]bool{}
+	for i := 0; i < 50; i++ {
+		attempt := i % 5
+		backoff := config.BaseDelay << attempt
+		if backoff > config.MaxDelay {
+			backoff = config.MaxDelay
+		}
+		delay := first.calculateBackoffDelay(config, attempt)
+		if delay < 0 || delay > backoff {
+			t.Errorf("full jitter delay for attempt %d = %s, want within [0, %s]", attempt, delay, backoff)
+		}
+		if again := second.calculateBackoffDelay(config, attempt); again != delay {
+			t.Errorf("delay %d = %s with the same seed, want %s", i, again, delay)
+		}
+		distinct[delay] = true
+	}
+	if len(distinct) < 40 {
+		t.Errorf("full jitter drew %d distinct delays out of 50, want them spread out", len(distinct))
+	}
+
+	config.JitterMode = JitterEqual
+	for attempt := 0; attempt < 5; attempt++ {
+		backoff := config.BaseDelay << attempt
+		if backoff > config.MaxDelay {
+			backoff = config.MaxDelay
+		}
+		if delay := first.calculateBackoffDelay(config, attempt); delay < backoff/2 || delay > backoff {
+			t.Errorf("equal jitter delay for attempt %d = %s, want within [%s, %s]", attempt, delay, backoff/2, backoff)
+		}
+	}
+
+	config.JitterMode = JitterNone
+	if delay := first.calculateBackoffDelay(config, 2); delay != 400*time.Millisecond {
+		t.Errorf("delay without jitter = %s, want 400ms", delay)
+	}
+	if delay := first.calculateBackoffDelay(config, 10); delay != time.Second {
+		t.Errorf("delay without jitter = %s, want the 1s cap", delay)
+	}
+
+	// Regional clients draw from their parent's source
+	if regional := first.ForRegion("eu-west-1"); regional.jitter != first.jitter {
+		t.Error("ForRegion() client has its own jitter source, want the parent's")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	withHeader := func(value string) error {
+		header := http.Header{}
+		header.Set("Retry-After", value)
+		return &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: 503, Header: header}},
+				Err:      apiError("SlowDown"),
+			},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		err   error
+		delay time.Duration
+		ok    bool
+	}{
+		{"seconds", withHeader("3"), 3 * time.Second, true},
+		{"HTTP date", withHeader(now.Add(90 * time.Second).Format(http.TimeFormat)), 90 * time.Second, true},
+		{"date in the past", withHeader(now.Add(-time.Minute).Format(http.TimeFormat)), 0, true},
+		{"malformed", withHeader("soon"), 0, false},
+		{"negative", withHeader("-1"), 0, false},
+		{"no header", responseError(503, apiError("SlowDown")), 0, false},
+		{"no response", apiError("SlowDown"), 0, false},
+	}
+	for _, tt := range tests {
+		delay, ok := retryAfter(operationError(tt.err), now)
+		if delay != tt.delay || ok != tt.ok {
+			t.Errorf("%s: retryAfter() = %s, %v, want %s, %v", tt.name, delay, ok, tt.delay, tt.ok)
+		}
+	}
+}
+
+func TestRetriesHonorRetryAfterAndLogBackoff(t *testing.T) {
+	slowDown := `<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>`
+	httpClient := &scriptedHTTPClient{responses: []scriptedResponse{
+		{status: 503, body: slowDown, retryAfter: "1"},
+		{status: 503, body: slowDown},
+		{status: 200, body: `<ListPartsResult><Bucket>logs</Bucket><Key>a</Key><UploadId>u</UploadId></ListPartsResult>`},
+	}}
+	var buf bytes.Buffer
+	client := &S3Client{
+		client: s3.New(s3.Options{
+			Region:      "us-east-1",
+			Credentials: aws.AnonymousCredentials{},
+			HTTPClient:  httpClient,
+			Retryer:     aws.NopRetryer{},
+		}),
+		retryConfig: RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, BackoffFactor: 1, JitterMode: JitterNone},
+		rateLimiter: rate.NewLimiter(rate.Inf, 1),
+		logger:      logging.NewJSONLogger(&buf, logging.LevelDebug),
+		jitter:      newJitterSource(rand.NewSource(1)),
+	}
+
+	if _, err := client.ListParts(context.Background(), &s3.ListPartsInput{Bucket: aws.String("logs"), Key: aws.String("a"), UploadId: aws.String("u")}); err != nil {
+		t.Fatalf("ListParts() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d log lines, want one per attempt:\n%s", len(lines), buf.String())
+	}
+	// The 1s Retry-After is capped at MaxDelay; the second retry backs off
+	want := []map[string]interface{}{
+		{"retry_delay_ms": float64(5), "retry_after": true},
+		{"retry_delay_ms": float64(1), "total_backoff_ms": float64(5)},
+		{"total_backoff_ms": float64(6)},
+	}
+	for i, line := range lines {
+		var entry struct {
+			Fields map[string]interface{} `json:"fields"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v\n%s", i, err, line)
+		}
+		for _, field := range []string{"retry_delay_ms", "retry_after", "total_backoff_ms"} {
+			if got, want := entry.Fields[field], want[i][field]; got != want {
+				t.Errorf("line %d %s = %v, want %v", i, field, got, want)
+			}
+		}
+	}
+}
+
+func TestRetryBudgetPerOperation(t *testing.T) {
+	unavailable := scriptedResponse{status: 503, body: `<Error><Code>ServiceUnavailable</Code><Message>Service Unavailable</Message></Error>`}
+	newClient := func(responses ...scriptedResponse) *S3Client {
+		return &S3Client{
+			client: s3.New(s3.Options{
+				Region:      "us-east-1",
+				Credentials: aws.AnonymousCredentials{},
+				HTTPClient:  &scriptedHTTPClient{responses: responses},
+				Retryer:     aws.NopRetryer{},
+			}),
+			retryConfig: RetryConfig{
+				MaxRetries: 0, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1,
+				Operations: map[string]RetryConfig{
+					"AbortMultipartUpload": {MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1},
+				},
+			},
+			rateLimiter: rate.NewLimiter(rate.Inf, 1),
+		}
+	}
+
+	_, err := newClient(unavailable, unavailable, unavailable, unavailable).AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{Bucket: aws.String("logs"), Key: aws.String("a"), UploadId: aws.String("u")})
+	if got := Attempts(err); got != 4 {
+		t.Errorf("AbortMultipartUpload() attempts = %d, want the 4 of its own budget", got)
+	}
+	_, err = newClient(unavailable).ListParts(context.Background(), &s3.ListPartsInput{Bucket: aws.String("logs"), Key: aws.String("a"), UploadId: aws.String("u")})
+	if got := Attempts(err); got != 1 {
+		t.Errorf("ListParts() attempts = %d, want 1 from the default budget", got)
+	}
+
+	defaults := DefaultRetryConfig()
+	if parts, abort := defaults.ForOperation("ListParts"), defaults.ForOperation("AbortMultipartUpload"); parts.MaxRetries >= abort.MaxRetries {
+		t.Errorf("default retries: ListParts %d, AbortMultipartUpload %d, want aborts retried more", parts.MaxRetries, abort.MaxRetries)
+	}
+	if got := defaults.ForOperation("ListMultipartUploads").MaxRetries; got != defaults.MaxRetries {
+		t.Errorf("ListMultipartUploads retries = %d, want the default %d", got, defaults.MaxRetries)
+	}
+}
@@ -0,0 +1,153 @@
+package aws
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OperationStats counts the calls made to one API operation
+type OperationStats struct {
+	Operation string
+	// Calls counts each call once, however many attempts it took
+	Calls int64
+	// Retries counts the attempts after the first
+	Retries int64
+	// Throttled counts the attempts S3 refused with a throttling error such as SlowDown
+	Throttled int64
+	// Failed counts the calls that still failed after their last attempt
+	Failed int64
+	// Latency is the time spent in attempts, excluding backoff and rate limiter waits
+	Latency time.Duration
+}
+
+// APIStats are the API calls made by a client and the regional clients made from it
+type APIStats struct {
+	Operations []OperationStats // by operation name
+	Calls      int64
+	Retries    int64
+	Throttled  int64
+	Failed     int64
+	// RateLimitWait is the time calls waited for the rate limiter, summed over concurrent calls
+	RateLimitWait time.Duration
+}
+
+// Operation returns the counts of the named operation, zero if it was never called
+func (s APIStats) Operation(name string) OperationStats {
+	for _, op := range s.Operations {
+		if op.Operation == name {
+			return op
+		}
+	}
+	return OperationStats{Operation: name}
+}
+
+// Add returns the sum of s and other, such as the calls made in two accounts
+func (s APIStats) Add(other APIStats) APIStats {
+	sum := APIStats{
+		Calls:         s.Calls + other.Calls,
+		Retries:       s.Retries + other.Retries,
+		Throttled:     s.Throttled + other.Throttled,
+		Failed:        s.Failed + other.Failed,
+		RateLimitWait: s.RateLimitWait + other.RateLimitWait,
+	}
+	names := make(map[string]bool)
+	for _, op := range append(append([]OperationStats(nil), s.Operations...), other.Operations...) {
+		names[op.Operation] = true
+	}
+	for name := range names {
+		a, b := s.Operation(name), other.Operation(name)
+		sum.Operations = append(sum.Operations, OperationStats{
+			Operation: name,
+			Calls:     a.Calls + b.Calls,
+			Retries:   a.Retries + b.Retries,
+			Throttled: a.Throttled + b.Throttled,
+			Failed:    a.Failed + b.Failed,
+			Latency:   a.Latency + b.Latency,
+		})
+	}
+	sortOperations(sum.Operations)
+	return sum
+}
+
+// sortOperations sorts operation stats by name
+func sortOperations(operations []OperationStats) {
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].Operation < operations[j].Operation
+	})
+}
+
+// operationCounters are the live counters of one operation
+type operationCounters struct {
+	calls     atomic.Int64
+	retries   atomic.Int64
+	throttled atomic.Int64
+	failed    atomic.Int64
+	latency   atomic.Int64 // nanoseconds
+}
+
+// apiStats counts API calls per operation. It is shared by a client and its
+// regional clients, which call it concurrently.
+type apiStats struct {
+	operations    sync.Map // operation name -> *operationCounters
+	rateLimitWait atomic.Int64
+}
+
+func newAPIStats() *apiStats {
+	return &apiStats{}
+}
+
+// operation returns the counters of the named operation. A client built
+// without stats counts into counters that are thrown away.
+func (s *apiStats) operation(name string) *operationCounters {
+	if s == nil {
+		return &operationCounters{}
+	}
+	if counters, exists := s.operations.Load(name); exists {
+		return counters.(*operationCounters)
+	}
+	counters, _ := s.operations.LoadOrStore(name, &operationCounters{})
+	return counters.(*operationCounters)
+}
+
+// waited adds a rate limiter wait
+func (s *apiStats) waited(wait time.Duration) {
+	if s != nil {
+		s.rateLimitWait.Add(int64(wait))
+	}
+}
+
+// snapshot returns the counts so far
+func (s *apiStats) snapshot() APIStats {
+	var stats APIStats
+	if s == nil {
+		return stats
+	}
+	s.operations.Range(func(name, value interface{}) bool {
+		counters := value.(*operationCounters)
+		op := OperationStats{
+			Operation: name.(string),
+			Calls:     counters.calls.Load(),
+			Retries:   counters.retries.Load(),
+			Throttled: counters.throttled.Load(),
+			Failed:    counters.failed.Load(),
+			Latency:   time.Duration(counters.latency.Load()),
+		}
+		stats.Operations = append(stats.Operations, op)
+		stats.Calls += op.Calls
+		stats.Retries += op.Retries
+		stats.Throttled += op.Throttled
+		stats.Failed += op.Failed
+		return true
+	})
+	sortOperations(stats.Operations)
+	stats.RateLimitWait = time.Duration(s.rateLimitWait.Load())
+	return stats
+}
+
+// GetStats returns the API calls made so far by the client and every
+// regional client made from it
+func (c *S3Client) GetStats() APIStats {
+	return c.stats.snapshot()
+}
@@ -0,0 +1,99 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"golang.org/x/time/rate"
+
+	"github.com/Garvitkul/s3mpc/internal/logging"
+)
+
+// throttlingHTTPClient answers concurrent requests by object key: keys
+// starting with "slow" are throttled on their first attempt and keys
+// starting with "denied" are always refused
+type throttlingHTTPClient struct {
+	mu        sync.Mutex
+	throttled map[string]bool
+}
+
+func (c *throttlingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	key := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	status, body := 204, ""
+	switch {
+	case strings.HasPrefix(key, "denied"):
+		status, body = 403, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`
+	case strings.HasPrefix(key, "slow"):
+		c.mu.Lock()
+		if !c.throttled[key] {
+			c.throttled[key] = true
+			status, body = 503, `<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>`
+		}
+		c.mu.Unlock()
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/xml"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestStatsCountConcurrentCalls(t *testing.T) {
+	awsConfig := aws.Config{
+		Region:      "us-east-1",
+		Credentials: aws.AnonymousCredentials{},
+		HTTPClient:  &throttlingHTTPClient{throttled: make(map[string]bool)},
+		Retryer:     func() aws.Retryer { return aws.NopRetryer{} },
+	}
+	client := NewS3ClientFromConfig(awsConfig, ClientConfig{
+		RetryConfig: RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1},
+		RateLimit:   rate.Inf,
+		Logger:      logging.NewNopLogger(),
+	})
+	// Regional clients count into the same stats
+	clients := []*S3Client{client, client.ForRegion("eu-west-1")}
+
+	const callsPerKind = 40
+	var wg sync.WaitGroup
+	for i := 0; i < callsPerKind; i++ {
+		for _, prefix := range []string{"ok", "slow", "denied"} {
+			wg.Add(1)
+			go func(regional *S3Client, key string) {
+				defer wg.Done()
+				regional.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{Bucket: aws.String("logs"), Key: aws.String(key), UploadId: aws.String("u")})
+			}(clients[i%len(clients)], fmt.Sprintf("%s-%d", prefix, i))
+		}
+		wg.Add(1)
+		go func(regional *S3Client) {
+			defer wg.Done()
+			regional.GetStats()
+		}(clients[i%len(clients)])
+	}
+	wg.Wait()
+
+	stats := client.GetStats()
+	abort := stats.Operation("AbortMultipartUpload")
+	want := OperationStats{Operation: "AbortMultipartUpload", Calls: 3 * callsPerKind, Retries: callsPerKind, Throttled: callsPerKind, Failed: callsPerKind}
+	abort.Latency = 0
+	if abort != want {
+		t.Errorf("AbortMultipartUpload stats = %+v, want %+v", abort, want)
+	}
+	if stats.Calls != want.Calls || stats.Retries != want.Retries || stats.Throttled != want.Throttled || stats.Failed != want.Failed {
+		t.Errorf("totals = %+v, want the AbortMultipartUpload counts", stats)
+	}
+	if len(stats.Operations) != 1 {
+		t.Errorf("Operations = %+v, want AbortMultipartUpload only", stats.Operations)
+	}
+	if got := stats.Operation("ListParts"); got.Calls != 0 {
+		t.Errorf("ListParts stats = %+v, want none", got)
+	}
+}
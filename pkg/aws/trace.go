@@ -0,0 +1,65 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/internal/logging"
+)
+
+// TraceConfig selects which API calls are logged at debug level. Without
+// tracing only failed attempts and the retries that follow them are logged.
+type TraceConfig struct {
+	// Enabled logs every attempt of every API call with its parameters
+	Enabled bool
+	// ShowKeys logs object keys and prefixes as they are instead of redacting them
+	ShowKeys bool
+}
+
+// keyParams are the call parameters that hold object keys or parts of them
+var keyParams = map[string]bool{"key": true, "prefix": true, "key_marker": true}
+
+// rateLimitWaitLogThreshold is the shortest rate limiter wait that is logged
+const rateLimitWaitLogThreshold = 100 * time.Millisecond
+
+// addParams adds the non-empty parameters of a traced call to fields,
+// redacting keys unless ShowKeys is set
+func (t TraceConfig) addParams(fields map[string]interface{}, params map[string]string) {
+	for name, value := range params {
+		if value == "" {
+			continue
+		}
+		if keyParams[name] && !t.ShowKeys {
+			value = redactKey(value)
+		}
+		fields[name] = value
+	}
+}
+
+// redactKey replaces a key with a short hash of it, so calls on the same key
+// can still be matched up in a trace
+func redactKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "redacted:" + hex.EncodeToString(sum[:4])
+}
+
+// logRateLimitWait logs a wait for the rate limiter before a call at debug
+// level, when it was long enough to slow the run down
+func (c *S3Client) logRateLimitWait(call apiCall, wait time.Duration) {
+	if wait < rateLimitWaitLogThreshold {
+		return
+	}
+	logger := c.log()
+	if !logger.IsEnabled(logging.LevelDebug) {
+		return
+	}
+	fields := map[string]interface{}{
+		"operation": call.operation,
+		"wait_ms":   wait.Milliseconds(),
+	}
+	if call.bucket != "" {
+		fields["bucket"] = call.bucket
+	}
+	logger.Debug("Waited for the S3 rate limiter", fields)
+}
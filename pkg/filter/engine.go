@@ -2,6 +2,7 @@ package filter
 
 import (
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -9,17 +10,76 @@ import (
 
 	"github.com/Garvitkul/s3mpc/pkg/interfaces"
 	"github.com/Garvitkul/s3mpc/pkg/types"
+	"github.com/Garvitkul/s3mpc/pkg/units"
 )
 
+// DefaultAgeTolerance is the window used by age = and != comparisons when no
+// explicit tolerance is given in the filter
+const DefaultAgeTolerance = time.Hour
+
+// ageToleranceSeparators separate an age value from its tolerance (e.g. "7d±6h")
+var ageToleranceSeparators = []string{"±", "+-"}
+
+// comparisonOperators, setOperators and the rest are the operators accepted per field type
+var (
+	comparisonOperators = []string{">", "<", ">=", "<=", "=", "!="}
+	orderingOperators   = []string{">", "<", ">=", "<="}
+	setOperators        = []string{"=", "!=", "in", "not in"}
+	suffixOperators     = []string{"$=", "!$="}
+)
+
+// supportedFields describes every field handled by parseCondition
+var supportedFields = []interfaces.FilterField{
+	{Name: "age", Operators: comparisonOperators, Description: "Time since the upload was initiated (min, h, d, w, mo, y units), or an absolute date (YYYY-MM-DD or RFC3339) meaning initiated before it for >", Example: "age>7d"},
+	{Name: "initiated", Operators: orderingOperators, Description: "When the upload was initiated, as a date (YYYY-MM-DD, midnight UTC) or a timestamp (UTC unless it has an offset); use two conditions for a window", Example: "initiated>=2024-03-01,initiated<2024-03-05"},
+	{Name: "size", Operators: comparisonOperators, Description: "Total size of the uploaded parts", Example: "size>100MB"},
+	{Name: "parts", Operators: comparisonOperators, Description: "Number of parts uploaded so far", Example: "parts>1000"},
+	{Name: "cost", Operators: orderingOperators, Description: "Estimated monthly cost of the upload in the run's currency", Example: "cost>0.10"},
+	{Name: "storageClass", Operators: setOperators, Description: "Storage class of the upload", Example: "storageClass not in (GLACIER, DEEP_ARCHIVE)"},
+	{Name: "region", Operators: setOperators, Description: "Region of the bucket", Example: "region=us-east-1"},
+	{Name: "bucket", Operators: setOperators, Description: "Bucket name", Example: "bucket in (team-a-raw, team-b-raw)"},
+	{Name: "initiatedBy", Operators: setOperators, Description: "service for uploads started by AWS services, user otherwise", Example: "initiatedBy=user"},
+	{Name: "initiator", Operators: setOperators, Description: "ID (an ARN on AWS) or display name of whoever initiated the upload", Example: "initiator=arn:aws:iam::123456789012:user/alice"},
+	{Name: "superseded", Operators: setOperators, Description: "true when a completed object newer than the upload exists at its key; needs --check-objects", Example: "superseded=true"},
+	{Name: "key", Operators: suffixOperators, Description: "Object key ends with (or, for !$=, does not end with) one of the |-separated suffixes, case-insensitive", Example: "key$=.tmp|.partial"},
+}
+
+// EngineOptions configures filter engine behavior
+type EngineOptions struct {
+	// AgeTolerance is the default tolerance for age = and != comparisons
+	AgeTolerance time.Duration
+	// CalendarDayAges interprets bare day values (age=7d) as "initiated on that
+	// calendar day (UTC) relative to today" instead of a tolerance window
+	CalendarDayAges bool
+}
+
 // Engine implements the FilterEngine interface
-type Engine struct{}
+type Engine struct {
+	ageTolerance    time.Duration
+	calendarDayAges bool
+	now             func() time.Time
+}
 
 // NewEngine creates a new filter engine
 func NewEngine() interfaces.FilterEngine {
-	return &Engine{}
+	return NewEngineWithOptions(EngineOptions{})
 }
 
-// ParseFilter parses a filter string into a structured filter
+// NewEngineWithOptions creates a new filter engine with custom options
+func NewEngineWithOptions(opts EngineOptions) interfaces.FilterEngine {
+	tolerance := opts.AgeTolerance
+	if tolerance <= 0 {
+		tolerance = DefaultAgeTolerance
+	}
+	return &Engine{
+		ageTolerance:    tolerance,
+		calendarDayAges: opts.CalendarDayAges,
+		now:             time.Now,
+	}
+}
+
+// ParseFilter parses a filter string into a structured filter. Values may be
+// quoted with double or single quotes to hold commas, pipes or whitespace.
 func (e *Engine) ParseFilter(filterStr string) (interfaces.Filter, error) {
 	if strings.TrimSpace(filterStr) == "" {
 		return interfaces.Filter{}, nil
@@ -27,8 +87,11 @@ func (e *Engine) ParseFilter(filterStr string) (interfaces.Filter, error) {
 
 	filter := interfaces.Filter{}
 	
-	// Split by comma for AND logic
-	conditions := strings.Split(filterStr, ",")
+	// Split by comma for AND logic, except inside quoted values
+	conditions, err := splitTopLevel(filterStr, ',')
+	if err != nil {
+		return interfaces.Filter{}, err
+	}
 	
 	for _, condition := range conditions {
 		condition = strings.TrimSpace(condition)
@@ -50,20 +113,41 @@ func (e *Engine) ValidateFilter(filterStr string) error {
 	return err
 }
 
+// inListPattern matches a condition such as "bucket in (a, b)": the field,
+// in or not in, and the list of values
+var inListPattern = regexp.MustCompile(`(?is)^(\w+)\s+(not\s+in|in)\b\s*(.*)$`)
+
 // parseCondition parses a single condition and updates the filter
 func (e *Engine) parseCondition(condition string, filter *interfaces.Filter) error {
-	// Regular expression to match: field operator value
-	re := regexp.MustCompile(`^(\w+)\s*(>=|<=|!=|>|<|=)\s*(.+)$`)
-	matches := re.FindStringSubmatch(condition)
-	
-	if len(matches) != 4 {
-		return fmt.Errorf("invalid syntax, expected 'field operator value'")
+	var field, operator, value, rawValue string
+	var values []string // of an in or not in list
+	if matches := inListPattern.FindStringSubmatch(condition); matches != nil {
+		field = strings.ToLower(matches[1])
+		operator = strings.ToLower(strings.Join(strings.Fields(matches[2]), " "))
+		list, err := parseValueList(matches[3])
+		if err != nil {
+			return err
+		}
+		values = list
+	} else {
+		// Regular expression to match: field operator value
+		re := regexp.MustCompile(`(?s)^(\w+)\s*(>=|<=|!\$=|\$=|!=|>|<|=)\s*(.+)$`)
+		matches := re.FindStringSubmatch(condition)
+		
+		if len(matches) != 4 {
+			return fmt.Errorf("invalid syntax, expected 'field operator value' or 'field in (value, ...)'")
+		}
+		
+		field = strings.ToLower(matches[1])
+		operator = matches[2]
+		rawValue = matches[3]
+		var quoted bool
+		value, quoted = unquoteValue(rawValue)
+		if value == "" && quoted {
+			return fmt.Errorf("value cannot be empty")
+		}
 	}
 	
-	field := strings.ToLower(matches[1])
-	operator := matches[2]
-	value := strings.TrimSpace(matches[3])
-	
 	switch field {
 	case "age":
 		if filter.Age != nil {
@@ -72,13 +156,36 @@ func (e *Engine) parseCondition(condition string, filter *interfaces.Filter) err
 		if err := e.validateAgeOperator(operator); err != nil {
 			return err
 		}
-		if err := e.validateAgeValue(value); err != nil {
+		ageValue, tolerance, hasTolerance := splitAgeTolerance(value)
+		if err := e.validateAgeValue(ageValue); err != nil {
 			return err
 		}
+		if hasTolerance {
+			if operator != "=" && operator != "!=" {
+				return fmt.Errorf("age tolerance is only supported with = and != operators")
+			}
+			if _, err := parseToleranceDuration(tolerance); err != nil {
+				return err
+			}
+		}
 		filter.Age = &interfaces.AgeFilter{
-			Operator: operator,
-			Value:    value,
+			Operator:  operator,
+			Value:     ageValue,
+			Tolerance: tolerance,
+		}
+		
+	case "initiated":
+		if operator != ">" && operator != "<" && operator != ">=" && operator != "<=" {
+			return fmt.Errorf("invalid operator '%s' for initiated field, supported: >, <, >=, <=", operator)
+		}
+		initiated, err := types.ParseCutoff(value)
+		if err != nil {
+			return err
 		}
+		filter.Initiated = append(filter.Initiated, interfaces.TimeFilter{
+			Operator: operator,
+			Value:    initiated,
+		})
 		
 	case "size":
 		if filter.Size != nil {
@@ -95,49 +202,144 @@ func (e *Engine) parseCondition(condition string, filter *interfaces.Filter) err
 			Value:    value,
 		}
 		
-	case "storageclass":
-		if filter.StorageClass != nil {
-			return fmt.Errorf("storageClass filter already specified")
+	case "parts":
+		if filter.Parts != nil {
+			return fmt.Errorf("parts filter already specified")
 		}
-		if err := e.validateStringOperator(operator); err != nil {
-			return err
+		if err := e.validateSizeOperator(operator); err != nil {
+			return fmt.Errorf("invalid operator '%s' for parts field, supported: >, <, >=, <=, =, !=", operator)
+		}
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || count < 0 {
+			return fmt.Errorf("invalid parts value '%s', expected a whole number", value)
 		}
-		filter.StorageClass = &interfaces.StringFilter{
+		filter.Parts = &interfaces.CountFilter{
 			Operator: operator,
-			Value:    value,
+			Value:    count,
 		}
 		
-	case "region":
-		if filter.Region != nil {
-			return fmt.Errorf("region filter already specified")
+	case "cost":
+		if filter.Cost != nil {
+			return fmt.Errorf("cost filter already specified")
 		}
-		if err := e.validateStringOperator(operator); err != nil {
-			return err
+		if operator != ">" && operator != "<" && operator != ">=" && operator != "<=" {
+			return fmt.Errorf("invalid operator '%s' for cost field, supported: >, <, >=, <=", operator)
+		}
+		cost, err := strconv.ParseFloat(value, 64)
+		if err != nil || cost < 0 || math.IsInf(cost, 0) || math.IsNaN(cost) {
+			return fmt.Errorf("invalid cost value '%s', expected a non-negative amount such as 0.10", value)
 		}
-		filter.Region = &interfaces.StringFilter{
+		filter.Cost = &interfaces.CostFilter{
 			Operator: operator,
-			Value:    value,
+			Value:    cost,
+		}
+		
+	case "storageclass":
+		condition, err := e.stringCondition(operator, value, values)
+		if err != nil {
+			return err
 		}
+		filter.StorageClass = append(filter.StorageClass, condition)
+		
+	case "region":
+		condition, err := e.stringCondition(operator, value, values)
+		if err != nil {
+			return err
+		}
+		filter.Region = append(filter.Region, condition)
 		
 	case "bucket":
-		if filter.Bucket != nil {
-			return fmt.Errorf("bucket filter already specified")
+		condition, err := e.stringCondition(operator, value, values)
+		if err != nil {
+			return err
 		}
-		if err := e.validateStringOperator(operator); err != nil {
+		filter.Bucket = append(filter.Bucket, condition)
+		
+	case "initiatedby":
+		condition, err := e.stringCondition(operator, value, values)
+		if err != nil {
 			return err
 		}
-		filter.Bucket = &interfaces.StringFilter{
-			Operator: operator,
-			Value:    value,
+		for _, value := range conditionValues(condition) {
+			if !strings.EqualFold(value, "service") && !strings.EqualFold(value, "user") {
+				return fmt.Errorf("invalid initiatedBy value '%s', supported: service, user", value)
+			}
+		}
+		filter.InitiatedBy = append(filter.InitiatedBy, condition)
+		
+	case "initiator":
+		condition, err := e.stringCondition(operator, value, values)
+		if err != nil {
+			return err
+		}
+		filter.Initiator = append(filter.Initiator, condition)
+		
+	case "superseded":
+		condition, err := e.stringCondition(operator, value, values)
+		if err != nil {
+			return err
+		}
+		for _, value := range conditionValues(condition) {
+			if !strings.EqualFold(value, "true") && !strings.EqualFold(value, "false") {
+				return fmt.Errorf("invalid superseded value '%s', supported: true, false", value)
+			}
+		}
+		filter.Superseded = append(filter.Superseded, condition)
+		
+	case "key":
+		if operator != "$=" && operator != "!$=" {
+			return fmt.Errorf("invalid operator '%s' for key, supported operators: $=, !$=", operator)
+		}
+		// Alternatives are separated by | outside quotes; quotes were
+		// checked when the filter was split into conditions
+		alternatives, _ := splitTopLevel(rawValue, '|')
+		var suffixes []string
+		for _, alternative := range alternatives {
+			if suffix, _ := unquoteValue(alternative); suffix != "" {
+				suffixes = append(suffixes, suffix)
+			}
+		}
+		if len(suffixes) == 0 {
+			return fmt.Errorf("key suffix cannot be empty")
+		}
+		if filter.Key == nil {
+			filter.Key = &types.KeySuffixFilter{}
+		}
+		if operator == "$=" {
+			if len(filter.Key.Include) > 0 {
+				return fmt.Errorf("key$= filter already specified, separate alternative suffixes with |")
+			}
+			filter.Key.Include = suffixes
+		} else {
+			if len(filter.Key.Exclude) > 0 {
+				return fmt.Errorf("key!$= filter already specified, separate alternative suffixes with |")
+			}
+			filter.Key.Exclude = suffixes
 		}
 		
 	default:
-		return fmt.Errorf("unsupported field '%s', supported fields: age, size, storageClass, region, bucket", field)
+		return fmt.Errorf("unsupported field '%s', supported fields: %s", field, supportedFieldNames())
 	}
 	
 	return nil
 }
 
+// SupportedFields lists the fields and operators accepted in filter expressions
+func (e *Engine) SupportedFields() []interfaces.FilterField {
+	fields := make([]interfaces.FilterField, len(supportedFields))
+	copy(fields, supportedFields)
+	return fields
+}
+
+// supportedFieldNames returns the comma-separated names of all supported fields
+func supportedFieldNames() string {
+	names := make([]string, len(supportedFields))
+	for i, field := range supportedFields {
+		names[i] = field.Name
+	}
+	return strings.Join(names, ", ")
+}
+
 // validateAgeOperator validates operators for age filters
 func (e *Engine) validateAgeOperator(operator string) error {
 	validOperators := map[string]bool{
@@ -163,117 +365,110 @@ func (e *Engine) validateSizeOperator(operator string) error {
 // validateStringOperator validates operators for string filters
 func (e *Engine) validateStringOperator(operator string) error {
 	validOperators := map[string]bool{
-		"=": true, "!=": true,
+		"=": true, "!=": true, "in": true, "not in": true,
 	}
 	if !validOperators[operator] {
-		return fmt.Errorf("invalid operator '%s' for string field, supported: =, !=", operator)
+		return fmt.Errorf("invalid operator '%s' for string field, supported: =, !=, in, not in", operator)
 	}
 	return nil
 }
 
+// stringCondition builds the condition of a string field from its operator
+// and its value, or its values for in and not in
+func (e *Engine) stringCondition(operator, value string, values []string) (interfaces.StringFilter, error) {
+	if err := e.validateStringOperator(operator); err != nil {
+		return interfaces.StringFilter{}, err
+	}
+	if operator == "in" || operator == "not in" {
+		return interfaces.StringFilter{Operator: operator, Values: values}, nil
+	}
+	return interfaces.StringFilter{Operator: operator, Value: value}, nil
+}
+
+// conditionValues returns the value of a string condition, or its values
+// for in and not in
+func conditionValues(filter interfaces.StringFilter) []string {
+	if filter.Operator == "in" || filter.Operator == "not in" {
+		return filter.Values
+	}
+	return []string{filter.Value}
+}
+
 // validateAgeValue validates age value format
 func (e *Engine) validateAgeValue(value string) error {
+	if isAbsoluteAge(value) {
+		_, err := types.ParseCutoff(value)
+		return err
+	}
 	_, err := e.parseAgeDuration(value)
 	return err
 }
 
+// isAbsoluteAge reports whether an age value is a date (2024-05-01...) rather than a duration
+func isAbsoluteAge(value string) bool {
+	return len(value) >= len(types.DateLayout) && value[4] == '-'
+}
+
+// ageCutoff returns the initiated time an age value corresponds to: the date
+// itself for absolute values, otherwise now minus the duration
+func (e *Engine) ageCutoff(value string, now time.Time) (time.Time, error) {
+	// Only attempt date parsing for date-shaped values; failed parses allocate
+	// and this runs once per upload
+	if isAbsoluteAge(value) {
+		return types.ParseCutoff(value)
+	}
+	duration, err := e.parseAgeDuration(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.Add(-duration), nil
+}
+
+// splitAgeTolerance splits an age value such as "7d±6h" into its value and tolerance parts
+func splitAgeTolerance(value string) (string, string, bool) {
+	for _, sep := range ageToleranceSeparators {
+		if idx := strings.Index(value, sep); idx >= 0 {
+			return strings.TrimSpace(value[:idx]), strings.TrimSpace(value[idx+len(sep):]), true
+		}
+	}
+	return value, "", false
+}
+
+// parseToleranceDuration parses an age tolerance (e.g. "6h", "30m", "1d")
+func parseToleranceDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, fmt.Errorf("age tolerance cannot be empty")
+	}
+	tolerance, err := units.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age tolerance: %w", err)
+	}
+	return tolerance, nil
+}
+
 // validateSizeValue validates size value format
 func (e *Engine) validateSizeValue(value string) error {
 	_, err := e.parseSizeBytes(value)
 	return err
 }
 
-// parseAgeDuration parses age duration from string (e.g., "7d", "1w", "1m", "1y")
+// parseAgeDuration parses an age duration (e.g. "7d", "1w", "1mo", "1y").
+// A bare "m" used to mean months in ages and now means minutes everywhere
+// else, so it is refused rather than silently changing what a filter matches.
 func (e *Engine) parseAgeDuration(value string) (time.Duration, error) {
-	if len(value) < 2 {
-		return 0, fmt.Errorf("invalid age format '%s', expected format like '7d', '1w', '1m', '1y'", value)
+	if trimmed := strings.TrimSpace(value); strings.HasSuffix(trimmed, "m") || strings.HasSuffix(trimmed, "M") {
+		return 0, fmt.Errorf("ambiguous age unit in '%s', use 'mo' for months or 'min' for minutes", value)
 	}
-	
-	numStr := value[:len(value)-1]
-	unit := strings.ToLower(value[len(value)-1:])
-	
-	num, err := strconv.ParseFloat(numStr, 64)
+	duration, err := units.ParseDuration(value)
 	if err != nil {
-		return 0, fmt.Errorf("invalid number in age '%s': %w", value, err)
-	}
-	
-	if num < 0 {
-		return 0, fmt.Errorf("age cannot be negative: %s", value)
+		return 0, fmt.Errorf("invalid age: %w", err)
 	}
-	
-	var duration time.Duration
-	switch unit {
-	case "d":
-		duration = time.Duration(num * float64(24*time.Hour))
-	case "w":
-		duration = time.Duration(num * float64(7*24*time.Hour))
-	case "m":
-		duration = time.Duration(num * float64(30*24*time.Hour)) // Approximate month
-	case "y":
-		duration = time.Duration(num * float64(365*24*time.Hour)) // Approximate year
-	default:
-		return 0, fmt.Errorf("invalid age unit '%s', supported units: d (days), w (weeks), m (months), y (years)", unit)
-	}
-	
 	return duration, nil
 }
 
 // parseSizeBytes parses size from string (e.g., "100MB", "1GB", "500KB")
 func (e *Engine) parseSizeBytes(value string) (int64, error) {
-	value = strings.ToUpper(strings.TrimSpace(value))
-	
-	if len(value) < 2 {
-		return 0, fmt.Errorf("invalid size format '%s', expected format like '100MB', '1GB'", value)
-	}
-	
-	// Handle case where value is just a number (assume bytes)
-	if num, err := strconv.ParseInt(value, 10, 64); err == nil {
-		if num < 0 {
-			return 0, fmt.Errorf("size cannot be negative: %s", value)
-		}
-		return num, nil
-	}
-	
-	// Extract number and unit
-	var numStr, unit string
-	for i := len(value) - 1; i >= 0; i-- {
-		if value[i] >= '0' && value[i] <= '9' || value[i] == '.' {
-			numStr = value[:i+1]
-			unit = value[i+1:]
-			break
-		}
-	}
-	
-	if numStr == "" {
-		return 0, fmt.Errorf("invalid size format '%s', no number found", value)
-	}
-	
-	num, err := strconv.ParseFloat(numStr, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid number in size '%s': %w", value, err)
-	}
-	
-	if num < 0 {
-		return 0, fmt.Errorf("size cannot be negative: %s", value)
-	}
-	
-	var multiplier int64
-	switch unit {
-	case "B", "":
-		multiplier = 1
-	case "KB":
-		multiplier = 1024
-	case "MB":
-		multiplier = 1024 * 1024
-	case "GB":
-		multiplier = 1024 * 1024 * 1024
-	case "TB":
-		multiplier = 1024 * 1024 * 1024 * 1024
-	default:
-		return 0, fmt.Errorf("invalid size unit '%s', supported units: B, KB, MB, GB, TB", unit)
-	}
-	
-	return int64(num * float64(multiplier)), nil
+	return units.ParseBytes(value)
 }
 
 // ApplyFilter applies a filter to a list of uploads
@@ -293,10 +488,15 @@ func (e *Engine) ApplyFilter(uploads []types.MultipartUpload, filter interfaces.
 	return filtered
 }
 
+// MatchesFilter reports whether a single upload matches a filter
+func (e *Engine) MatchesFilter(upload types.MultipartUpload, filter interfaces.Filter) bool {
+	return e.isEmptyFilter(filter) || e.matchesFilter(upload, filter)
+}
+
 // isEmptyFilter checks if the filter is empty
 func (e *Engine) isEmptyFilter(filter interfaces.Filter) bool {
-	return filter.Age == nil && filter.Size == nil && filter.StorageClass == nil && 
-		   filter.Region == nil && filter.Bucket == nil
+	return filter.Age == nil && filter.Size == nil && filter.Parts == nil && filter.Cost == nil && len(filter.StorageClass) == 0 && 
+		   len(filter.Region) == 0 && len(filter.Bucket) == 0 && len(filter.InitiatedBy) == 0 && len(filter.Initiator) == 0 && len(filter.Initiated) == 0 && len(filter.Superseded) == 0 && filter.Key.IsEmpty()
 }
 
 // matchesFilter checks if an upload matches the filter criteria
@@ -311,58 +511,291 @@ func (e *Engine) matchesFilter(upload types.MultipartUpload, filter interfaces.F
 		return false
 	}
 	
-	if filter.StorageClass != nil && !e.matchesStringFilter(upload.StorageClass, *filter.StorageClass) {
+	if filter.Parts != nil && !compareCount(int64(upload.PartsCount), filter.Parts.Operator, filter.Parts.Value) {
+		return false
+	}
+	
+	if filter.Cost != nil && !compareCost(upload.EstimatedMonthlyCost, filter.Cost.Operator, filter.Cost.Value) {
+		return false
+	}
+	
+	if !e.matchesStringFilters(upload.StorageClass, filter.StorageClass) {
+		return false
+	}
+	
+	if !e.matchesStringFilters(upload.Region, filter.Region) {
+		return false
+	}
+	
+	if !e.matchesStringFilters(upload.Bucket, filter.Bucket) {
 		return false
 	}
 	
-	if filter.Region != nil && !e.matchesStringFilter(upload.Region, *filter.Region) {
+	if !e.matchesStringFilters(initiatedBy(upload), filter.InitiatedBy) {
 		return false
 	}
 	
-	if filter.Bucket != nil && !e.matchesStringFilter(upload.Bucket, *filter.Bucket) {
+	for _, initiator := range filter.Initiator {
+		if !e.matchesInitiator(upload, initiator) {
+			return false
+		}
+	}
+	
+	for _, initiated := range filter.Initiated {
+		if !matchesTimeFilter(upload.Initiated, initiated) {
+			return false
+		}
+	}
+	
+	if !e.matchesStringFilters(strconv.FormatBool(upload.Superseded), filter.Superseded) {
+		return false
+	}
+	
+	if !filter.Key.Matches(upload.Key) {
 		return false
 	}
 	
 	return true
 }
 
+// initiatedBy returns "service" for service-initiated uploads and "user" otherwise
+func initiatedBy(upload types.MultipartUpload) string {
+	if upload.ServiceInitiated {
+		return "service"
+	}
+	return "user"
+}
+
+// matchesInitiator checks the initiator filter against both the initiator's
+// ID and its display name; uploads without an initiator never equal a value
+func (e *Engine) matchesInitiator(upload types.MultipartUpload, filter interfaces.StringFilter) bool {
+	return matchesCondition(filter, func(value string) bool {
+		return strings.EqualFold(upload.Initiator, value) ||
+			(upload.InitiatorName != "" && strings.EqualFold(upload.InitiatorName, value))
+	})
+}
+
+// matchesTimeFilter compares a time with a time filter
+func matchesTimeFilter(t time.Time, filter interfaces.TimeFilter) bool {
+	switch filter.Operator {
+	case ">":
+		return t.After(filter.Value)
+	case "<":
+		return t.Before(filter.Value)
+	case ">=":
+		return !t.Before(filter.Value)
+	case "<=":
+		return !t.After(filter.Value)
+	default:
+		return false
+	}
+}
+
 // matchesAgeFilter checks if upload matches age filter
 func (e *Engine) matchesAgeFilter(upload types.MultipartUpload, filter interfaces.AgeFilter) bool {
-	uploadAge := time.Since(upload.Initiated)
-	filterDuration, err := e.parseAgeDuration(filter.Value)
+	now := e.now()
+	cutoff, err := e.ageCutoff(filter.Value, now)
 	if err != nil {
 		// This should not happen if validation was done properly
 		return false
 	}
 	
+	// Older than the cut-off means initiated before it
 	switch filter.Operator {
 	case ">":
-		return uploadAge > filterDuration
+		return upload.Initiated.Before(cutoff)
 	case "<":
-		return uploadAge < filterDuration
+		return upload.Initiated.After(cutoff)
 	case ">=":
-		return uploadAge >= filterDuration
+		return !upload.Initiated.After(cutoff)
 	case "<=":
-		return uploadAge <= filterDuration
-	case "=":
-		// For age equality, we allow some tolerance (within 1 hour)
-		diff := uploadAge - filterDuration
-		if diff < 0 {
-			diff = -diff
+		return !upload.Initiated.Before(cutoff)
+	case "=", "!=":
+		from, to, err := e.ageWindow(filter, now)
+		if err != nil {
+			return false
 		}
-		return diff <= time.Hour
-	case "!=":
-		// For age inequality, we check if it's outside the tolerance
-		diff := uploadAge - filterDuration
-		if diff < 0 {
-			diff = -diff
+		// The window is inclusive of its start and end
+		inWindow := !upload.Initiated.Before(from) && !upload.Initiated.After(to)
+		if filter.Operator == "=" {
+			return inWindow
 		}
-		return diff > time.Hour
+		return !inWindow
 	default:
 		return false
 	}
 }
 
+// ageWindow returns the initiated-time window matched by an age = or != filter
+func (e *Engine) ageWindow(filter interfaces.AgeFilter, now time.Time) (time.Time, time.Time, error) {
+	if start, err := time.Parse(types.DateLayout, filter.Value); err == nil && filter.Tolerance == "" {
+		// A plain date matches that whole UTC day
+		return start, start.Add(24*time.Hour - time.Nanosecond), nil
+	}
+
+	target, err := e.ageCutoff(filter.Value, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if filterDuration, err := e.parseAgeDuration(filter.Value); err == nil && filter.Tolerance == "" && e.calendarDayAges && strings.HasSuffix(strings.ToLower(filter.Value), "d") {
+		// Calendar mode: the whole UTC day that lies N days before today
+		days := int(filterDuration / (24 * time.Hour))
+		today := now.UTC().Truncate(24 * time.Hour)
+		start := today.AddDate(0, 0, -days)
+		return start, start.Add(24*time.Hour - time.Nanosecond), nil
+	}
+
+	tolerance := e.ageTolerance
+	if filter.Tolerance != "" {
+		tolerance, err = parseToleranceDuration(filter.Tolerance)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+
+	return target.Add(-tolerance), target.Add(tolerance), nil
+}
+
+// ExplainFilter describes each condition of a filter, including the effective
+// initiated-time window for age = and != comparisons
+func (e *Engine) ExplainFilter(filter interfaces.Filter) []string {
+	var lines []string
+	now := e.now()
+
+	if filter.Age != nil {
+		age := *filter.Age
+		condition := fmt.Sprintf("age%s%s", age.Operator, age.Value)
+		if age.Tolerance != "" {
+			condition += "±" + age.Tolerance
+		}
+
+		switch age.Operator {
+		case "=", "!=":
+			from, to, err := e.ageWindow(age, now)
+			if err != nil {
+				lines = append(lines, fmt.Sprintf("%s: invalid (%v)", condition, err))
+				break
+			}
+			verb := "initiated between"
+			if age.Operator == "!=" {
+				verb = "initiated outside"
+			}
+			lines = append(lines, fmt.Sprintf("%s: %s %s and %s",
+				condition, verb, from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339)))
+		default:
+			cutoffTime, _ := e.ageCutoff(age.Value, now)
+			cutoff := cutoffTime.UTC().Format(time.RFC3339)
+			descriptions := map[string]string{
+				">":  "initiated before " + cutoff,
+				">=": "initiated at or before " + cutoff,
+				"<":  "initiated after " + cutoff,
+				"<=": "initiated at or after " + cutoff,
+			}
+			lines = append(lines, fmt.Sprintf("%s: %s", condition, descriptions[age.Operator]))
+		}
+	}
+
+	for _, initiated := range filter.Initiated {
+		descriptions := map[string]string{
+			">":  "initiated after",
+			">=": "initiated at or after",
+			"<":  "initiated before",
+			"<=": "initiated at or before",
+		}
+		value := types.FormatCutoff(initiated.Value)
+		lines = append(lines, fmt.Sprintf("initiated%s%s: %s %s", initiated.Operator, value, descriptions[initiated.Operator], initiated.Value.UTC().Format(time.RFC3339)))
+	}
+
+	if filter.Size != nil {
+		size := *filter.Size
+		bytes, _ := e.parseSizeBytes(size.Value)
+		lines = append(lines, fmt.Sprintf("size%s%s: size %s %d bytes", size.Operator, size.Value, size.Operator, bytes))
+	}
+
+	if filter.Parts != nil {
+		parts := *filter.Parts
+		lines = append(lines, fmt.Sprintf("parts%s%d: parts count %s %d", parts.Operator, parts.Value, parts.Operator, parts.Value))
+	}
+
+	if filter.Cost != nil {
+		cost := *filter.Cost
+		value := strconv.FormatFloat(cost.Value, 'f', -1, 64)
+		lines = append(lines, fmt.Sprintf("cost%s%s: estimated monthly cost %s %s", cost.Operator, value, cost.Operator, value))
+	}
+
+	stringFields := []struct {
+		name    string
+		filters []interfaces.StringFilter
+	}{
+		{"storageClass", filter.StorageClass},
+		{"region", filter.Region},
+		{"bucket", filter.Bucket},
+		{"initiatedBy", filter.InitiatedBy},
+		{"initiator", filter.Initiator},
+		{"superseded", filter.Superseded},
+	}
+	for _, field := range stringFields {
+		for _, condition := range field.filters {
+			switch condition.Operator {
+			case "in", "not in":
+				quoted := make([]string, len(condition.Values))
+				described := make([]string, len(condition.Values))
+				for i, value := range condition.Values {
+					quoted[i] = quoteValue(value)
+					described[i] = strconv.Quote(value)
+				}
+				verb := "is one of"
+				if condition.Operator == "not in" {
+					verb = "is none of"
+				}
+				lines = append(lines, fmt.Sprintf("%s %s (%s): %s %s %s (case-insensitive)",
+					field.name, condition.Operator, strings.Join(quoted, ", "), field.name, verb, strings.Join(described, ", ")))
+			default:
+				verb := "equals"
+				if condition.Operator == "!=" {
+					verb = "does not equal"
+				}
+				lines = append(lines, fmt.Sprintf("%s%s%s: %s %s %q (case-insensitive)",
+					field.name, condition.Operator, quoteValue(condition.Value), field.name, verb, condition.Value))
+			}
+		}
+	}
+
+	if key := filter.Key; !key.IsEmpty() {
+		sensitivity := "case-insensitive"
+		if key.CaseSensitive {
+			sensitivity = "case-sensitive"
+		}
+		if len(key.Include) > 0 {
+			lines = append(lines, fmt.Sprintf("key$=%s: key ends with %s (%s)", joinSuffixes(key.Include), quoteList(key.Include), sensitivity))
+		}
+		if len(key.Exclude) > 0 {
+			lines = append(lines, fmt.Sprintf("key!$=%s: key does not end with %s (%s)", joinSuffixes(key.Exclude), quoteList(key.Exclude), sensitivity))
+		}
+	}
+
+	return lines
+}
+
+// joinSuffixes writes key suffixes as they are given in a filter
+func joinSuffixes(suffixes []string) string {
+	quoted := make([]string, len(suffixes))
+	for i, suffix := range suffixes {
+		quoted[i] = quoteValue(suffix)
+	}
+	return strings.Join(quoted, "|")
+}
+
+// quoteList quotes values and joins them with "or"
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	return strings.Join(quoted, " or ")
+}
+
 // matchesSizeFilter checks if upload matches size filter
 func (e *Engine) matchesSizeFilter(upload types.MultipartUpload, filter interfaces.SizeFilter) bool {
 	filterSize, err := e.parseSizeBytes(filter.Value)
@@ -389,13 +822,75 @@ func (e *Engine) matchesSizeFilter(upload types.MultipartUpload, filter interfac
 	}
 }
 
+// compareCount compares a count with a filter value using operator
+func compareCount(count int64, operator string, value int64) bool {
+	switch operator {
+	case ">":
+		return count > value
+	case "<":
+		return count < value
+	case ">=":
+		return count >= value
+	case "<=":
+		return count <= value
+	case "=":
+		return count == value
+	case "!=":
+		return count != value
+	default:
+		return false
+	}
+}
+
+// compareCost compares an estimated monthly cost with a filter value using operator
+func compareCost(cost float64, operator string, value float64) bool {
+	switch operator {
+	case ">":
+		return cost > value
+	case "<":
+		return cost < value
+	case ">=":
+		return cost >= value
+	case "<=":
+		return cost <= value
+	default:
+		return false
+	}
+}
+
 // matchesStringFilter checks if a string value matches string filter
 func (e *Engine) matchesStringFilter(value string, filter interfaces.StringFilter) bool {
+	return matchesCondition(filter, func(want string) bool {
+		return strings.EqualFold(value, want)
+	})
+}
+
+// matchesStringFilters checks a string value against every condition given
+// for its field
+func (e *Engine) matchesStringFilters(value string, filters []interfaces.StringFilter) bool {
+	for _, filter := range filters {
+		if !e.matchesStringFilter(value, filter) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesCondition applies the operator of a string condition, given whether
+// the upload equals each of its values
+func matchesCondition(filter interfaces.StringFilter, equals func(value string) bool) bool {
+	found := false
+	for _, value := range conditionValues(filter) {
+		if equals(value) {
+			found = true
+			break
+		}
+	}
 	switch filter.Operator {
-	case "=":
-		return strings.EqualFold(value, filter.Value)
-	case "!=":
-		return !strings.EqualFold(value, filter.Value)
+	case "=", "in":
+		return found
+	case "!=", "not in":
+		return !found
 	default:
 		return false
 	}
@@ -0,0 +1,62 @@
+package filter
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/Garvitkul/s3mpc/internal/benchutil"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+const benchFilter = "age>30d,size>100MB,storageClass=STANDARD,region!=eu-west-1"
+
+var (
+	millionUploads     []types.MultipartUpload
+	millionUploadsOnce sync.Once
+)
+
+// benchUploads returns one million synthetic uploads, generated once per process
+func benchUploads() []types.MultipartUpload {
+	millionUploadsOnce.Do(func() {
+		millionUploads = benchutil.SyntheticUploads(1_000_000)
+	})
+	return millionUploads
+}
+
+func BenchmarkParseFilter(b *testing.B) {
+	engine := NewEngine()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := engine.ParseFilter(benchFilter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkApplyFilter1M(b *testing.B) {
+	engine := NewEngine()
+	filter, err := engine.ParseFilter(benchFilter)
+	if err != nil {
+		b.Fatal(err)
+	}
+	uploads := benchUploads()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.ApplyFilter(uploads, filter)
+	}
+}
+
+func TestApplyFilterAllocBudget(t *testing.T) {
+	engine := NewEngine()
+	filter, err := engine.ParseFilter(benchFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploads := benchutil.SyntheticUploads(10_000)
+
+	benchutil.CheckAllocsPerUpload(t, "ApplyFilter", len(uploads), 2.5, func() {
+		engine.ApplyFilter(uploads, filter)
+	})
+}
@@ -1,9 +1,11 @@
 package filter
 
 import (
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
 	"github.com/Garvitkul/s3mpc/pkg/types"
 )
 
@@ -60,6 +62,16 @@ func TestParseFilter(t *testing.T) {
 			filterStr: "size<invalid",
 			wantErr:   true,
 		},
+		{
+			name:      "valid initiatedBy filter",
+			filterStr: "initiatedBy=service",
+			wantErr:   false,
+		},
+		{
+			name:      "invalid initiatedBy value",
+			filterStr: "initiatedBy=robot",
+			wantErr:   true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -173,4 +185,626 @@ func TestApplyFilter(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestAgeTolerance(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	upload := func(age time.Duration) types.MultipartUpload {
+		return types.MultipartUpload{
+			Bucket:       "bucket1",
+			Key:          "key",
+			UploadID:     "upload",
+			Initiated:    now.Add(-age),
+			StorageClass: "STANDARD",
+			Region:       "us-east-1",
+		}
+	}
+
+	tests := []struct {
+		name      string
+		opts      EngineOptions
+		filterStr string
+		age       time.Duration
+		want      bool
+	}{
+		{"default tolerance inside", EngineOptions{}, "age=7d", 7*24*time.Hour + 30*time.Minute, true},
+		{"default tolerance outside", EngineOptions{}, "age=7d", 7*24*time.Hour + 2*time.Hour, false},
+		{"explicit tolerance inside", EngineOptions{}, "age=7d±6h", 7*24*time.Hour - 5*time.Hour, true},
+		{"explicit tolerance outside", EngineOptions{}, "age=7d±6h", 7*24*time.Hour + 7*time.Hour, false},
+		{"ascii tolerance separator", EngineOptions{}, "age=7d+-1d", 8*24*time.Hour - time.Hour, true},
+		{"configured tolerance", EngineOptions{AgeTolerance: 12 * time.Hour}, "age=7d", 7*24*time.Hour + 11*time.Hour, true},
+		{"not equal outside window", EngineOptions{}, "age!=0d", 2 * time.Hour, true},
+		{"not equal inside window", EngineOptions{}, "age!=0d", 30 * time.Minute, false},
+		{"calendar day start", EngineOptions{CalendarDayAges: true}, "age=7d", 7*24*time.Hour + 12*time.Hour, true},
+		{"calendar day end", EngineOptions{CalendarDayAges: true}, "age=7d", 6*24*time.Hour + 12*time.Hour + time.Minute, true},
+		{"calendar previous day", EngineOptions{CalendarDayAges: true}, "age=7d", 7*24*time.Hour + 12*time.Hour + time.Minute, false},
+		{"calendar ignored with explicit tolerance", EngineOptions{CalendarDayAges: true}, "age=7d±1h", 7*24*time.Hour + 6*time.Hour, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngineWithOptions(tt.opts).(*Engine)
+			engine.now = func() time.Time { return now }
+
+			filter, err := engine.ParseFilter(tt.filterStr)
+			if err != nil {
+				t.Fatalf("ParseFilter() error = %v", err)
+			}
+
+			filtered := engine.ApplyFilter([]types.MultipartUpload{upload(tt.age)}, filter)
+			if got := len(filtered) == 1; got != tt.want {
+				t.Errorf("ApplyFilter(%s) matched = %v, want %v", tt.filterStr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgeAbsoluteDate(t *testing.T) {
+	midnight := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	engine := NewEngine().(*Engine)
+	// The cut-off must not move as time passes
+	engine.now = func() time.Time { return midnight.AddDate(1, 0, 0) }
+
+	tests := []struct {
+		filterStr string
+		initiated time.Time
+		want      bool
+	}{
+		{"age>2024-05-01", midnight.Add(-time.Nanosecond), true},
+		{"age>2024-05-01", midnight, false},
+		{"age>=2024-05-01", midnight, true},
+		{"age<2024-05-01", midnight, false},
+		{"age<=2024-05-01", midnight, true},
+		{"age>2024-05-01T00:00:00Z", midnight.Add(-time.Second), true},
+		{"age>2024-05-01T02:00:00+02:00", midnight, false},
+		{"age=2024-05-01", midnight.Add(23 * time.Hour), true},
+		{"age=2024-05-01", midnight.Add(24 * time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		filter, err := engine.ParseFilter(tt.filterStr)
+		if err != nil {
+			t.Fatalf("ParseFilter(%s) error = %v", tt.filterStr, err)
+		}
+		upload := types.MultipartUpload{Bucket: "b", Key: "k", UploadID: "u", Initiated: tt.initiated}
+		if got := len(engine.ApplyFilter([]types.MultipartUpload{upload}, filter)) == 1; got != tt.want {
+			t.Errorf("ApplyFilter(%s) for %s matched = %v, want %v", tt.filterStr, tt.initiated.Format(time.RFC3339Nano), got, tt.want)
+		}
+	}
+}
+
+func TestParseAgeToleranceErrors(t *testing.T) {
+	engine := NewEngine()
+
+	for _, filterStr := range []string{"age>7d±6h", "age=7d±", "age=7d±abc", "age=7d±-1h"} {
+		if _, err := engine.ParseFilter(filterStr); err == nil {
+			t.Errorf("ParseFilter(%s) expected error", filterStr)
+		}
+	}
+}
+
+func TestExplainFilterAgeWindow(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	engine := NewEngine().(*Engine)
+	engine.now = func() time.Time { return now }
+
+	filter, err := engine.ParseFilter("age=7d±6h,bucket=logs")
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+
+	lines := engine.ExplainFilter(filter)
+	if len(lines) != 2 {
+		t.Fatalf("ExplainFilter() returned %d lines, want 2: %v", len(lines), lines)
+	}
+
+	want := "age=7d±6h: initiated between 2024-06-08T06:00:00Z and 2024-06-08T18:00:00Z"
+	if lines[0] != want {
+		t.Errorf("ExplainFilter() age line = %q, want %q", lines[0], want)
+	}
+}
+
+func TestInitiatedByFilter(t *testing.T) {
+	engine := NewEngine()
+	uploads := []types.MultipartUpload{
+		{Key: "user-upload"},
+		{Key: "replica-upload", ServiceInitiated: true},
+	}
+
+	filter, err := engine.ParseFilter("initiatedBy=service")
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+	result := engine.ApplyFilter(uploads, filter)
+	if len(result) != 1 || result[0].Key != "replica-upload" {
+		t.Errorf("initiatedBy=service matched %v, want only replica-upload", result)
+	}
+
+	filter, err = engine.ParseFilter("initiatedBy!=service")
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+	result = engine.ApplyFilter(uploads, filter)
+	if len(result) != 1 || result[0].Key != "user-upload" {
+		t.Errorf("initiatedBy!=service matched %v, want only user-upload", result)
+	}
+}
+
+func TestSupersededFilter(t *testing.T) {
+	engine := NewEngine()
+	uploads := []types.MultipartUpload{
+		{Key: "abandoned"},
+		{Key: "retried", Superseded: true},
+	}
+
+	filter, err := engine.ParseFilter("superseded=true")
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+	result := engine.ApplyFilter(uploads, filter)
+	if len(result) != 1 || result[0].Key != "retried" {
+		t.Errorf("superseded=true matched %v, want only retried", result)
+	}
+
+	if _, err := engine.ParseFilter("superseded=yes"); err == nil {
+		t.Error("ParseFilter(superseded=yes) succeeded, want an error")
+	}
+}
+
+func TestInitiatorFilter(t *testing.T) {
+	engine := NewEngine()
+	uploads := []types.MultipartUpload{
+		{Key: "alice", Initiator: "arn:aws:iam::123456789012:user/alice", InitiatorName: "alice"},
+		{Key: "bob", Initiator: "arn:aws:iam::123456789012:user/bob", InitiatorName: "bob"},
+		{Key: "unknown"}, // S3-compatible stores may not report an initiator
+	}
+
+	for _, expr := range []string{"initiator=arn:aws:iam::123456789012:user/alice", "initiator=ALICE"} {
+		filter, err := engine.ParseFilter(expr)
+		if err != nil {
+			t.Fatalf("ParseFilter(%s) error = %v", expr, err)
+		}
+		if result := engine.ApplyFilter(uploads, filter); len(result) != 1 || result[0].Key != "alice" {
+			t.Errorf("%s matched %v, want only alice", expr, result)
+		}
+	}
+
+	filter, err := engine.ParseFilter("initiator!=alice")
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+	if result := engine.ApplyFilter(uploads, filter); len(result) != 2 || result[0].Key != "bob" || result[1].Key != "unknown" {
+		t.Errorf("initiator!=alice matched %v, want bob and unknown", result)
+	}
+}
+
+func TestPartsFilter(t *testing.T) {
+	engine := NewEngine()
+	uploads := []types.MultipartUpload{
+		{Key: "single", PartsCount: 1},
+		{Key: "many", PartsCount: 9000},
+	}
+
+	filter, err := engine.ParseFilter("parts>1000")
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+	if result := engine.ApplyFilter(uploads, filter); len(result) != 1 || result[0].Key != "many" {
+		t.Errorf("parts>1000 matched %v, want only many", result)
+	}
+
+	for _, invalid := range []string{"parts>1k", "parts>-1", "parts$=1"} {
+		if _, err := engine.ParseFilter(invalid); err == nil {
+			t.Errorf("ParseFilter(%s) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestCostFilter(t *testing.T) {
+	engine := NewEngine()
+	uploads := []types.MultipartUpload{
+		{Key: "cheap", EstimatedMonthlyCost: 0.02},
+		{Key: "expensive", EstimatedMonthlyCost: 1.5},
+	}
+
+	filter, err := engine.ParseFilter("cost>0.10")
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+	if result := engine.ApplyFilter(uploads, filter); len(result) != 1 || result[0].Key != "expensive" {
+		t.Errorf("cost>0.10 matched %v, want only expensive", result)
+	}
+	if explained := engine.ExplainFilter(filter); len(explained) != 1 || explained[0] != "cost>0.1: estimated monthly cost > 0.1" {
+		t.Errorf("ExplainFilter() = %v", explained)
+	}
+
+	for _, invalid := range []string{"cost>$1", "cost>-1", "cost=0.10", "cost>NaN"} {
+		if _, err := engine.ParseFilter(invalid); err == nil {
+			t.Errorf("ParseFilter(%s) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestInitiatedFilter(t *testing.T) {
+	engine := NewEngine()
+	day := func(d, h int) time.Time { return time.Date(2024, 3, d, h, 0, 0, 0, time.UTC) }
+	uploads := []types.MultipartUpload{
+		{Key: "before", Initiated: day(1, 0).Add(-time.Second)},
+		{Key: "start", Initiated: day(1, 0)},
+		{Key: "inside", Initiated: day(4, 23)},
+		{Key: "end", Initiated: day(5, 0)},
+	}
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"initiated>=2024-03-01,initiated<2024-03-05", []string{"start", "inside"}},
+		{"initiated>=2024-03-01T00:00:00Z, initiated<=2024-03-05", []string{"start", "inside", "end"}},
+		{"initiated>2024-03-01", []string{"inside", "end"}},
+		// 01:00 at +02:00 is 23:00 UTC the day before
+		{"initiated<2024-03-05T01:00:00+02:00", []string{"before", "start"}},
+		{"initiated>=2024-03-04T23:00:00", []string{"inside", "end"}},
+	}
+	for _, test := range tests {
+		filter, err := engine.ParseFilter(test.filter)
+		if err != nil {
+			t.Fatalf("ParseFilter(%s) error = %v", test.filter, err)
+		}
+		var got []string
+		for _, upload := range engine.ApplyFilter(uploads, filter) {
+			got = append(got, upload.Key)
+		}
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("%s matched %v, want %v", test.filter, got, test.want)
+		}
+	}
+
+	for _, invalid := range []string{"initiated=2024-03-01", "initiated>7d", "initiated<2024-13-01"} {
+		if _, err := engine.ParseFilter(invalid); err == nil {
+			t.Errorf("ParseFilter(%s) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestSupportedFieldsOperators(t *testing.T) {
+	engine := NewEngine()
+	values := map[string]string{
+		"age": "7d", "size": "100MB", "storageClass": "STANDARD",
+		"region": "us-east-1", "bucket": "my-bucket", "initiatedBy": "user",
+		"key": ".tmp", "superseded": "true", "initiator": "alice", "parts": "1000",
+		"initiated": "2024-03-01", "cost": "0.10",
+	}
+
+	for _, field := range engine.SupportedFields() {
+		value, ok := values[field.Name]
+		if !ok {
+			t.Errorf("no test value for field %s", field.Name)
+			continue
+		}
+
+		accepted := make(map[string]bool)
+		for _, operator := range field.Operators {
+			accepted[operator] = true
+		}
+		for _, operator := range append(append([]string{}, comparisonOperators...), suffixOperators...) {
+			_, err := engine.ParseFilter(field.Name + operator + value)
+			if accepted[operator] && err != nil {
+				t.Errorf("%s%s%s should parse: %v", field.Name, operator, value, err)
+			}
+			if !accepted[operator] && err == nil {
+				t.Errorf("%s%s%s should be rejected", field.Name, operator, value)
+			}
+		}
+	}
+}
+
+func TestKeySuffixFilter(t *testing.T) {
+	engine := NewEngine()
+	uploads := []types.MultipartUpload{
+		{Bucket: "b", Key: "report.tmp"},
+		{Bucket: "b", Key: "REPORT.TMP"},
+		{Bucket: "b", Key: "report.tmp.gz"},
+		{Bucket: "b", Key: "tmp/report.csv"},
+		{Bucket: "b", Key: "video.partial"},
+		{Bucket: "b", Key: "video.partial/chunk"},
+		{Bucket: "b", Key: "tmp"},
+		{Bucket: "b", Key: "data.upload"},
+	}
+	keys := func(filtered []types.MultipartUpload) string {
+		var keys []string
+		for _, upload := range filtered {
+			keys = append(keys, upload.Key)
+		}
+		return strings.Join(keys, ",")
+	}
+
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		// Suffixes are anchored to the end of the key, so mid-key matches are ignored
+		{"key$=.tmp", "report.tmp,REPORT.TMP"},
+		{"key$=.tmp|.partial", "report.tmp,REPORT.TMP,video.partial"},
+		{"key!$=.tmp|.partial|.upload", "report.tmp.gz,tmp/report.csv,video.partial/chunk,tmp"},
+		{"key$=.tmp|.upload,key!$=report.tmp", "data.upload"},
+		{"KEY $= .partial", "video.partial"},
+	}
+	for _, tt := range tests {
+		filter, err := engine.ParseFilter(tt.filter)
+		if err != nil {
+			t.Errorf("ParseFilter(%q) error = %v", tt.filter, err)
+			continue
+		}
+		if got := keys(engine.ApplyFilter(uploads, filter)); got != tt.want {
+			t.Errorf("%s matched %s, want %s", tt.filter, got, tt.want)
+		}
+	}
+
+	filter, err := engine.ParseFilter("key$=.tmp")
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+	filter.Key.CaseSensitive = true
+	if got := keys(engine.ApplyFilter(uploads, filter)); got != "report.tmp" {
+		t.Errorf("case-sensitive key$=.tmp matched %s, want report.tmp", got)
+	}
+	if lines := engine.ExplainFilter(filter); len(lines) != 1 || !strings.Contains(lines[0], `ends with ".tmp" (case-sensitive)`) {
+		t.Errorf("ExplainFilter() = %v, want the case-sensitive suffix", lines)
+	}
+
+	for _, invalid := range []string{"key=.tmp", "key$=|", "key$=.tmp,key$=.partial", "bucket$=logs"} {
+		if _, err := engine.ParseFilter(invalid); err == nil {
+			t.Errorf("ParseFilter(%q) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestFilterValuesUseSharedUnits(t *testing.T) {
+	engine := NewEngine()
+	now := time.Now()
+	uploads := []types.MultipartUpload{
+		{Key: "small", Initiated: now.Add(-2 * time.Hour), Size: 512 * 1024},
+		{Key: "large", Initiated: now.Add(-45 * 24 * time.Hour), Size: 3 * 1024 * 1024 * 1024},
+	}
+
+	// The same spellings as --smaller-than and --older-than
+	for filterStr, want := range map[string]string{
+		"size>1.5GB":      "large",
+		"size>1.5 gb":     "large",
+		"size<1m":         "small",
+		"size<1024k":      "small",
+		"size>1GiB":       "large",
+		"age>1mo":         "large",
+		"age>1.5w":        "large",
+		"age<90min":       "",
+		"age<3h":          "small",
+		"age<1d12h":       "small",
+		"age>30D":         "large",
+		"age=2h±30min":    "small",
+		"size>2147483648": "large",
+	} {
+		filter, err := engine.ParseFilter(filterStr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q) error = %v", filterStr, err)
+			continue
+		}
+		var keys []string
+		for _, upload := range engine.ApplyFilter(uploads, filter) {
+			keys = append(keys, upload.Key)
+		}
+		if got := strings.Join(keys, ","); got != want {
+			t.Errorf("%s matched %q, want %q", filterStr, got, want)
+		}
+	}
+
+	// "m" meant months in ages before it meant minutes, so it is refused
+	for _, filterStr := range []string{"age>1m", "age>6M", "age<1h30m"} {
+		if _, err := engine.ParseFilter(filterStr); err == nil || !strings.Contains(err.Error(), "'mo' for months") {
+			t.Errorf("ParseFilter(%q) error = %v, want the ambiguous unit refused", filterStr, err)
+		}
+	}
+}
+
+func TestQuotedFilterValues(t *testing.T) {
+	engine := NewEngine()
+	values := []string{
+		"reports/2024,Q1",
+		"  leading and trailing spaces  ",
+		`say "hi"`,
+		"it's",
+		`back\slash`,
+		"a|b",
+		"данные-桶-📦",
+	}
+	quote := func(q byte, value string) string {
+		escaped := strings.NewReplacer(`\`, `\\`, string(q), `\`+string(q)).Replace(value)
+		return string(q) + escaped + string(q)
+	}
+	fields := []struct {
+		name  string
+		value func(interfaces.Filter) []interfaces.StringFilter
+	}{
+		{"storageClass", func(f interfaces.Filter) []interfaces.StringFilter { return f.StorageClass }},
+		{"region", func(f interfaces.Filter) []interfaces.StringFilter { return f.Region }},
+		{"bucket", func(f interfaces.Filter) []interfaces.StringFilter { return f.Bucket }},
+		{"initiator", func(f interfaces.Filter) []interfaces.StringFilter { return f.Initiator }},
+	}
+
+	for _, field := range fields {
+		for _, value := range values {
+			for _, q := range []byte{'"', '\''} {
+				// Other conditions around the quoted one still split at commas
+				expr := "age>1d," + field.name + "=" + quote(q, value) + ",size>1KB"
+				filter, err := engine.ParseFilter(expr)
+				if err != nil {
+					t.Errorf("ParseFilter(%s) error = %v", expr, err)
+					continue
+				}
+				if got := field.value(filter); len(got) != 1 || got[0].Value != value {
+					t.Errorf("ParseFilter(%s) %s = %+v, want %q", expr, field.name, got, value)
+					continue
+				}
+				if filter.Age == nil || filter.Size == nil {
+					t.Errorf("ParseFilter(%s) lost the age or size condition", expr)
+				}
+			}
+		}
+	}
+
+	// Explanations quote values where needed, so they can be pasted back
+	for _, value := range values {
+		explained := engine.ExplainFilter(interfaces.Filter{Bucket: []interfaces.StringFilter{{Operator: "=", Value: value}}})[0]
+		condition := explained[:strings.Index(explained, ": bucket equals")]
+		reparsed, err := engine.ParseFilter(condition)
+		if err != nil || len(reparsed.Bucket) != 1 || reparsed.Bucket[0].Value != value {
+			t.Errorf("explained condition %s parses to %+v, %v, want %q", condition, reparsed.Bucket, err, value)
+		}
+	}
+
+	// Unquoted values keep trimming whitespace; quoted parts join what is around them
+	for expr, want := range map[string]string{
+		"bucket= logs ":           "logs",
+		`bucket=reports/"a, b"/x`: "reports/a, b/x",
+		`bucket="it's"`:           "it's",
+		`bucket='say "hi"'`:       `say "hi"`,
+	} {
+		filter, err := engine.ParseFilter(expr)
+		if err != nil || len(filter.Bucket) != 1 || filter.Bucket[0].Value != want {
+			t.Errorf("ParseFilter(%s) = %+v, %v, want %q", expr, filter.Bucket, err, want)
+		}
+	}
+
+	// Fields with fixed values accept them quoted
+	filter, err := engine.ParseFilter(`initiatedBy="service",superseded='true'`)
+	if err != nil || filter.InitiatedBy[0].Value != "service" || filter.Superseded[0].Value != "true" {
+		t.Errorf("quoted initiatedBy and superseded = %+v, %v", filter, err)
+	}
+	if _, err := engine.ParseFilter(`initiatedBy="service,user"`); err == nil {
+		t.Error("initiatedBy with a quoted comma succeeded, want an invalid value error")
+	}
+
+	// Key suffixes split at | outside quotes only
+	filter, err = engine.ParseFilter(`key$="2024,Q1.tmp"|' .part'|.gz|"a|b"|"ファイル.tmp"`)
+	if err != nil {
+		t.Fatalf("ParseFilter() quoted key suffixes error = %v", err)
+	}
+	wantSuffixes := []string{"2024,Q1.tmp", " .part", ".gz", "a|b", "ファイル.tmp"}
+	if strings.Join(filter.Key.Include, "\x00") != strings.Join(wantSuffixes, "\x00") {
+		t.Errorf("key suffixes = %q, want %q", filter.Key.Include, wantSuffixes)
+	}
+	uploads := []types.MultipartUpload{
+		{Key: "reports/2024,Q1.tmp"},
+		{Key: "video .part"},
+		{Key: "video.part"},
+		{Key: "x/a|b"},
+		{Key: "新しいファイル.tmp"},
+	}
+	if got := engine.ApplyFilter(uploads, filter); len(got) != 4 || got[2].Key != "x/a|b" {
+		t.Errorf("quoted key suffixes matched %v, want all but video.part", got)
+	}
+	explained := engine.ExplainFilter(filter)[0]
+	if !strings.HasPrefix(explained, `key$="2024,Q1.tmp"|" .part"|.gz|"a|b"|ファイル.tmp:`) {
+		t.Errorf("ExplainFilter() = %s, want the suffixes quoted where needed", explained)
+	}
+
+	errors := map[string]string{
+		`bucket="logs,region=us-east-1`: `unterminated " quote at position 8`,
+		`bucket=日本,region='x`:           `unterminated ' quote at position 18`,
+		`bucket="escaped \"quote`:       `unterminated " quote at position 8`,
+		`key$=".tmp|.part`:              `unterminated " quote at position 6`,
+		`bucket=""`:                     "value cannot be empty",
+		`key$=""|''`:                    "key suffix cannot be empty",
+	}
+	for expr, want := range errors {
+		if _, err := engine.ParseFilter(expr); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseFilter(%s) error = %v, want %q", expr, err, want)
+		}
+	}
+}
+
+func TestInListFilters(t *testing.T) {
+	engine := NewEngine()
+	uploads := []types.MultipartUpload{
+		{Bucket: "team-a-raw", Key: "a1", StorageClass: "STANDARD"},
+		{Bucket: "team-b-raw", Key: "b1", StorageClass: "GLACIER"},
+		{Bucket: "team-c-raw", Key: "c1", StorageClass: "DEEP_ARCHIVE"},
+		{Bucket: "logs", Key: "l1", StorageClass: "STANDARD", Initiator: "arn:aws:iam::123456789012:user/alice", InitiatorName: "alice"},
+		{Bucket: "a,b", Key: "q1", StorageClass: "STANDARD_IA", Initiator: "arn:aws:iam::123456789012:user/bob"},
+	}
+	keys := func(filtered []types.MultipartUpload) string {
+		var keys []string
+		for _, upload := range filtered {
+			keys = append(keys, upload.Key)
+		}
+		return strings.Join(keys, ",")
+	}
+
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{"bucket in (team-a-raw, team-b-raw, team-c-raw)", "a1,b1,c1"},
+		{"storageClass not in (GLACIER, DEEP_ARCHIVE)", "a1,l1,q1"},
+		{"storageClass in(glacier)", "b1"},
+		{`BUCKET NOT  IN ('a,b', "logs"),size<1KB`, "a1,b1,c1"},
+		{"initiator in (alice, arn:aws:iam::123456789012:user/bob)", "l1,q1"},
+		// A field given more than once must match every condition
+		{"bucket!=logs,bucket!=team-a-raw,storageClass=STANDARD", ""},
+		{"bucket in (team-a-raw, logs),bucket!=logs", "a1"},
+		{"storageClass in (STANDARD, GLACIER),bucket not in (logs)", "a1,b1"},
+	}
+	for _, tt := range tests {
+		filter, err := engine.ParseFilter(tt.filter)
+		if err != nil {
+			t.Errorf("ParseFilter(%q) error = %v", tt.filter, err)
+			continue
+		}
+		if got := keys(engine.ApplyFilter(uploads, filter)); got != tt.want {
+			t.Errorf("%s matched %q, want %q", tt.filter, got, tt.want)
+		}
+	}
+
+	filter, err := engine.ParseFilter(`bucket in (team-a-raw, "x, y"),bucket not in (logs)`)
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+	lines := engine.ExplainFilter(filter)
+	want := []string{
+		`bucket in (team-a-raw, "x, y"): bucket is one of "team-a-raw", "x, y" (case-insensitive)`,
+		`bucket not in (logs): bucket is none of "logs" (case-insensitive)`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ExplainFilter() = %q, want %q", lines, want)
+	}
+
+	errors := map[string]string{
+		"bucket in (a, b":                 "unclosed ( at position 11",
+		"age>1d,bucket in (a, (b)":        "unclosed ( at position 18",
+		"bucket in a, b)":                 "unexpected ) at position 15 with no ( before it",
+		"bucket in (a)),age>1d":           "unexpected ) at position 14 with no ( before it",
+		`bucket in (a, ")")) `:            "unexpected ) at position 19 with no ( before it",
+		"bucket in (a) b":                 `unexpected "b" after the ) closing the list of values`,
+		"bucket in ()":                    "the list of values is empty",
+		"bucket in (a,,b)":                "value 2 of the list is empty",
+		`bucket in (a, "")`:               "value 2 of the list is empty",
+		"bucket in a":                     "expected a list of values in parentheses",
+		"initiatedBy in (service, robot)": "invalid initiatedBy value 'robot'",
+		"superseded not in (maybe)":       "invalid superseded value 'maybe'",
+		"age in (7d)":                     "invalid operator 'in' for age field",
+		"size not in (1MB)":               "invalid operator 'not in' for size field",
+		"key in (.tmp)":                   "invalid operator 'in' for key",
+		"initiated in (2024-03-01)":       "invalid operator 'in' for initiated field",
+	}
+	for expr, want := range errors {
+		if _, err := engine.ParseFilter(expr); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseFilter(%s) error = %v, want %q", expr, err, want)
+		}
+	}
+
+	// Quoted parentheses are values, not groups
+	filter, err = engine.ParseFilter(`bucket in ("(a", 'b)'),region=us-east-1`)
+	if err != nil || len(filter.Bucket) != 1 || strings.Join(filter.Bucket[0].Values, "|") != "(a|b)" || len(filter.Region) != 1 {
+		t.Errorf("ParseFilter() with quoted parentheses = %+v, %v", filter, err)
+	}
+}
@@ -0,0 +1,152 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// splitTopLevel splits s at each sep that is outside single or double quotes
+// and parentheses, so the values of an in list stay together. Unterminated
+// quotes and unbalanced parentheses are errors naming their position in s.
+func splitTopLevel(s string, sep byte) ([]string, error) {
+	var parts []string
+	start := 0
+	err := scanOutsideQuotes(s, func(i, depth int) {
+		if s[i] == sep && depth == 0 {
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(parts, s[start:]), nil
+}
+
+// scanOutsideQuotes calls visit with each byte of s that is outside quotes
+// and how many parentheses it is in; a parenthesis counts as inside the
+// group it opens or closes. Inside quotes a backslash escapes the quote
+// character or another backslash.
+func scanOutsideQuotes(s string, visit func(i, depth int)) error {
+	var quote byte
+	quoteAt := 0
+	var open []int // positions of the parentheses not closed yet
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(s) && (s[i+1] == quote || s[i+1] == '\\') {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote, quoteAt = c, i
+		case c == '(':
+			open = append(open, i)
+			visit(i, len(open))
+		case c == ')':
+			if len(open) == 0 {
+				return fmt.Errorf("unexpected ) at position %d with no ( before it", position(s, i))
+			}
+			visit(i, len(open))
+			open = open[:len(open)-1]
+		default:
+			visit(i, len(open))
+		}
+	}
+	if quote != 0 {
+		return fmt.Errorf("unterminated %c quote at position %d", quote, position(s, quoteAt))
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("unclosed ( at position %d", position(s, open[0]))
+	}
+	return nil
+}
+
+// parseValueList parses the list of an in condition, such as "(a, 'b c')",
+// into its unquoted values. Its parentheses and quotes have been checked by
+// splitTopLevel.
+func parseValueList(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.HasPrefix(raw, "(") {
+		return nil, fmt.Errorf("expected a list of values in parentheses, e.g. (a, b)")
+	}
+	end := -1
+	scanOutsideQuotes(raw, func(i, depth int) {
+		if end < 0 && raw[i] == ')' && depth == 1 {
+			end = i
+		}
+	})
+	if end < 0 {
+		return nil, fmt.Errorf("unclosed ( in the list of values")
+	}
+	if rest := strings.TrimSpace(raw[end+1:]); rest != "" {
+		return nil, fmt.Errorf("unexpected %q after the ) closing the list of values", rest)
+	}
+
+	items, err := splitTopLevel(raw[1:end], ',')
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 1 && strings.TrimSpace(items[0]) == "" {
+		return nil, fmt.Errorf("the list of values is empty")
+	}
+	values := make([]string, len(items))
+	for i, item := range items {
+		value, _ := unquoteValue(item)
+		if value == "" {
+			return nil, fmt.Errorf("value %d of the list is empty", i+1)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// position returns the position of byte i of s, counted in characters from 1
+func position(s string, i int) int {
+	return utf8.RuneCountInString(s[:i]) + 1
+}
+
+// unquoteValue returns a filter value as written, without the whitespace
+// around it. Quoted parts are kept exactly, including their whitespace, so
+// `key$=" .tmp"` and `bucket="a,b"` mean what they say; quotes must be
+// terminated, as splitUnquoted checks. quoted reports whether the value had
+// any quoted part.
+func unquoteValue(raw string) (value string, quoted bool) {
+	raw = strings.TrimSpace(raw)
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(raw) && (raw[i+1] == quote || raw[i+1] == '\\') {
+				i++
+				b.WriteByte(raw[i])
+			} else if c == quote {
+				quote = 0
+			} else {
+				b.WriteByte(c)
+			}
+		case c == '"' || c == '\'':
+			quote, quoted = c, true
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), quoted
+}
+
+// quoteValue returns value as it can be written in a filter: in double
+// quotes when it has separators, quotes, parentheses or whitespace
+func quoteValue(value string) string {
+	needsQuotes := value == "" || strings.ContainsAny(value, `,|"'()`) || strings.IndexFunc(value, unicode.IsSpace) >= 0
+	if !needsQuotes {
+		return value
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+	return `"` + escaped + `"`
+}
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"context"
+	"time"
 
 	"github.com/Garvitkul/s3mpc/pkg/types"
 )
@@ -11,30 +12,70 @@ type UploadService interface {
 	// ListUploads retrieves all incomplete multipart uploads
 	ListUploads(ctx context.Context, opts types.ListOptions) ([]types.MultipartUpload, error)
 	
+	// WalkUploads calls fn for each incomplete multipart upload as it is listed,
+	// without collecting them; an error from fn stops the walk
+	WalkUploads(ctx context.Context, opts types.ListOptions, fn func(types.MultipartUpload) error) error
+	
 	// DeleteUpload deletes a specific multipart upload
 	DeleteUpload(ctx context.Context, upload types.MultipartUpload) error
 	
 	// GetUploadSize calculates the size of an incomplete upload
 	GetUploadSize(ctx context.Context, upload types.MultipartUpload) (int64, error)
 	
+	// GetUploadDetail measures the size, the number of parts and the lifecycle
+	// abort date of an incomplete upload in one walk of its parts
+	GetUploadDetail(ctx context.Context, upload types.MultipartUpload) (types.UploadDetail, error)
+	
+	// GetUploadDetailWithParts measures an upload as GetUploadDetail does and
+	// also returns every part, in the same walk
+	GetUploadDetailWithParts(ctx context.Context, upload types.MultipartUpload) (types.UploadDetail, error)
+	
+	// GetObjectLastModified returns when the completed object at the key of an
+	// upload was last written, and false when there is no such object
+	GetObjectLastModified(ctx context.Context, upload types.MultipartUpload) (time.Time, bool, error)
+	
 	// DeleteUploads deletes multiple uploads with options
 	DeleteUploads(ctx context.Context, uploads []types.MultipartUpload, opts types.DeleteOptions) error
+	
+	// FindRemainingUploads splits uploads into those S3 still has and those
+	// it no longer has
+	FindRemainingUploads(ctx context.Context, uploads []types.MultipartUpload) ([]types.MultipartUpload, []types.MultipartUpload, error)
+	
+	// SkippedBuckets returns the buckets the most recent listing left out
+	// because their region could not be determined
+	SkippedBuckets() []types.SkippedBucket
 }
 
 // BucketService handles S3 bucket operations
 type BucketService interface {
-	// ListBuckets retrieves all accessible S3 buckets
-	ListBuckets(ctx context.Context, region string) ([]types.Bucket, error)
+	// ListBuckets retrieves all accessible S3 buckets, and the buckets whose
+	// region could not be determined
+	ListBuckets(ctx context.Context, region string) ([]types.Bucket, []types.SkippedBucket, error)
 	
 	// GetBucketRegion retrieves the region for a specific bucket
 	GetBucketRegion(ctx context.Context, bucketName string) (string, error)
 	
 	// ListBucketsInRegion retrieves buckets in a specific region
-	ListBucketsInRegion(ctx context.Context, region string) ([]types.Bucket, error)
+	ListBucketsInRegion(ctx context.Context, region string) ([]types.Bucket, []types.SkippedBucket, error)
+	
+	// ListDirectoryBuckets retrieves the directory buckets (S3 Express One
+	// Zone) in region, which ListBuckets does not return
+	ListDirectoryBuckets(ctx context.Context, region string) ([]types.Bucket, error)
+	
+	// GetBucketTags retrieves the tags of a bucket in region, with an empty map
+	// for a bucket without tags
+	GetBucketTags(ctx context.Context, bucketName, region string) (map[string]string, error)
+	
+	// SelectBucketsByTags keeps the buckets whose tags match filter, and
+	// returns the buckets whose tags could not be read separately
+	SelectBucketsByTags(ctx context.Context, buckets []types.Bucket, filter *types.BucketTagFilter) ([]types.Bucket, []types.SkippedBucket, error)
 	
-	// ClearRegionCache clears the region cache (useful for testing)
+	// ClearRegionCache clears the region and tag caches (useful for testing)
 	ClearRegionCache()
 	
+	// Invalidate drops the cached bucket list so the next call lists buckets again
+	Invalidate()
+	
 	// GetCacheStats returns cache statistics (useful for monitoring)
 	GetCacheStats() map[string]interface{}
 }
@@ -49,6 +90,9 @@ type CostCalculator interface {
 	
 	// EstimateSavings calculates potential cost savings from deletion
 	EstimateSavings(ctx context.Context, uploads []types.MultipartUpload) (float64, error)
+	
+	// CalculateAccruedCost calculates what uploads have cost since they were initiated
+	CalculateAccruedCost(ctx context.Context, uploads []types.MultipartUpload) (float64, error)
 }
 
 // AgeService handles age analysis and distribution calculations
@@ -71,8 +115,25 @@ type FilterEngine interface {
 	// ApplyFilter applies a filter to a list of uploads
 	ApplyFilter(uploads []types.MultipartUpload, filter Filter) []types.MultipartUpload
 	
+	// MatchesFilter reports whether a single upload matches a filter
+	MatchesFilter(upload types.MultipartUpload, filter Filter) bool
+	
 	// ValidateFilter validates filter syntax
 	ValidateFilter(filterStr string) error
+	
+	// ExplainFilter describes the effective meaning of each filter condition
+	ExplainFilter(filter Filter) []string
+	
+	// SupportedFields lists the fields and operators accepted in filter expressions
+	SupportedFields() []FilterField
+}
+
+// FilterField describes a field accepted in filter expressions
+type FilterField struct {
+	Name        string
+	Operators   []string
+	Description string
+	Example     string
 }
 
 // DryRunService handles dry-run operations and result generation
@@ -87,19 +148,28 @@ type DryRunService interface {
 	GenerateFilename(command string, format string) string
 }
 
-// Filter represents parsed filter criteria
+// Filter represents parsed filter criteria. String fields may be given more
+// than once, and all of their conditions must match.
 type Filter struct {
 	Age          *AgeFilter
 	Size         *SizeFilter
-	StorageClass *StringFilter
-	Region       *StringFilter
-	Bucket       *StringFilter
+	Parts        *CountFilter // needs measured uploads
+	Cost         *CostFilter  // needs measured and priced uploads
+	StorageClass []StringFilter
+	Region       []StringFilter
+	Bucket       []StringFilter
+	InitiatedBy  []StringFilter // "service" or "user"
+	Initiator    []StringFilter // initiator ID or display name
+	Initiated    []TimeFilter   // all must match, so a window is two bounds
+	Superseded   []StringFilter // "true" or "false"
+	Key          *types.KeySuffixFilter
 }
 
 // AgeFilter represents age-based filtering
 type AgeFilter struct {
-	Operator string // >, <, >=, <=, =, !=
-	Value    string // e.g., "7d", "1w", "1m"
+	Operator  string // >, <, >=, <=, =, !=
+	Value     string // e.g., "7d", "1w", "1m"
+	Tolerance string // optional window for = and != (e.g., "6h"), from "7d±6h"
 }
 
 // SizeFilter represents size-based filtering
@@ -108,22 +178,64 @@ type SizeFilter struct {
 	Value    string // e.g., "100MB", "1GB"
 }
 
+// CountFilter represents filtering on a count, such as the number of parts
+type CountFilter struct {
+	Operator string // >, <, >=, <=, =, !=
+	Value    int64
+}
+
+// CostFilter represents filtering on an upload's estimated monthly cost
+type CostFilter struct {
+	Operator string // >, <, >=, <=
+	Value    float64
+}
+
+// TimeFilter represents filtering on a point in time, such as when an upload was initiated
+type TimeFilter struct {
+	Operator string // >, <, >=, <=
+	Value    time.Time
+}
+
 // StringFilter represents string-based filtering
 type StringFilter struct {
-	Operator string // =, !=
-	Value    string
+	Operator string   // =, !=, in, not in
+	Value    string   // for = and !=
+	Values   []string // for in and not in
+}
+
+// UploadTableOptions selects the columns of an upload table
+type UploadTableOptions struct {
+	// ShowOwner adds initiator and owner columns to the default layout
+	ShowOwner bool
+	// Columns, if set, are the columns to show in order, such as bucket, key
+	// and size; they replace the default layout
+	Columns   []string
+	// Wide disables truncating cells to fit the terminal
+	Wide      bool
 }
 
 // ExportService handles data export operations
 type ExportService interface {
-	// ExportToCSV exports uploads to CSV format
+	// ExportToCSV exports uploads to CSV format. Every export is gzip-compressed
+	// when filename ends in .gz.
 	ExportToCSV(ctx context.Context, uploads []types.MultipartUpload, filename string) error
 	
 	// ExportToJSON exports uploads to JSON format
 	ExportToJSON(ctx context.Context, uploads []types.MultipartUpload, filename string) error
 	
-	// GenerateExportFilename generates a filename for export results
-	GenerateExportFilename(command string, format string) string
+	// ExportToXLSX exports uploads to an Excel workbook with per-bucket and per-region summary sheets
+	ExportToXLSX(ctx context.Context, uploads []types.MultipartUpload, filename string) error
+	
+	// ExportSplit exports uploads to one file per bucket or to chunks of
+	// limited rows or size, each with its own header, plus an index file listing them
+	ExportSplit(ctx context.Context, uploads []types.MultipartUpload, opts types.ExportOptions) (types.ExportManifest, error)
+	
+	// ExportWithParts streams uploads whose parts were listed to csv, with a
+	// second file of parts, or to json with the parts nested, returning the files written
+	ExportWithParts(ctx context.Context, uploads []types.MultipartUpload, format string, filename string) ([]string, error)
+	
+	// GenerateExportFilename generates a filename for export results, ending in .gz when compress is set
+	GenerateExportFilename(command string, format string, compress bool) string
 	
 	// StreamExportToCSV exports large datasets to CSV with streaming
 	StreamExportToCSV(ctx context.Context, uploads <-chan types.MultipartUpload, filename string) error
@@ -137,6 +249,15 @@ type OutputFormatter interface {
 	// FormatUploads formats uploads for human-readable console output
 	FormatUploads(uploads []types.MultipartUpload, showDetails bool) string
 	
+	// FormatUploadTable formats uploads as a detailed table, with initiator and owner columns if showOwner is set
+	FormatUploadTable(uploads []types.MultipartUpload, showOwner bool) string
+	
+	// FormatUploadColumns formats uploads as a table laid out by options
+	FormatUploadColumns(uploads []types.MultipartUpload, options UploadTableOptions) string
+	
+	// FormatUploadGroups formats one table per group with its subtotals, then the totals across groups
+	FormatUploadGroups(groups []types.UploadGroup, options UploadTableOptions) string
+	
 	// FormatSizeReport formats size report for console output
 	FormatSizeReport(report types.SizeReport) string
 	
@@ -146,21 +267,120 @@ type OutputFormatter interface {
 	// FormatAgeDistribution formats age distribution for console output
 	FormatAgeDistribution(distribution types.AgeDistribution) string
 	
+	// FormatStatsReport formats a combined size, cost and age report for console output
+	FormatStatsReport(report types.StatsReport) string
+	
+	// FormatLifecycleStatus formats a lifecycle rule audit for console output
+	FormatLifecycleStatus(statuses []types.LifecycleStatus) string
+	
+	// FormatLifecyclePlan formats planned lifecycle rule changes, including the rule JSON
+	FormatLifecyclePlan(changes []types.LifecycleChange) string
+	
+	// FormatLifecycleApplyResult formats the per-bucket outcome of writing lifecycle rules
+	FormatLifecycleApplyResult(result types.LifecycleApplyResult) string
+	
 	// FormatJSON formats any data structure as JSON
 	FormatJSON(data interface{}) (string, error)
 	
 	// FormatTable formats data as a table with headers and rows
 	FormatTable(headers []string, rows [][]string) string
+	
+	// FormatMarkdownTable formats data as a GitHub-flavored markdown table
+	FormatMarkdownTable(headers []string, rows [][]string) string
+	
+	// FormatUploadsMarkdown formats uploads as a markdown table with a totals row, headed by the command line
+	FormatUploadsMarkdown(uploads []types.MultipartUpload, options UploadTableOptions, command string) string
+	
+	// FormatSizeReportMarkdown formats a size report as markdown, headed by the command line
+	FormatSizeReportMarkdown(report types.SizeReport, command string) string
+	
+	// FormatCostBreakdownMarkdown formats a cost breakdown as markdown, headed by the command line
+	FormatCostBreakdownMarkdown(breakdown types.CostBreakdown, command string) string
+	
+	// FormatAgeDistributionMarkdown formats an age distribution as markdown, headed by the command line
+	FormatAgeDistributionMarkdown(distribution types.AgeDistribution, command string) string
+	
+	// FormatDryRunMarkdown formats a delete dry-run summary as markdown, headed by the command line
+	FormatDryRunMarkdown(result types.DryRunResult, command string) string
+}
+
+// ReportService combines size, cost and age analysis over a single scan
+type ReportService interface {
+	// GenerateStats lists uploads once, fetches their sizes and analyzes them.
+	// When some buckets could not be listed, the stats of the others are
+	// returned with their types.BucketErrors; so are those of GenerateMetrics
+	// and GenerateSnapshot.
+	GenerateStats(ctx context.Context, opts types.ListOptions) (*types.StatsReport, error)
+	
+	// StatsForUploads analyzes uploads whose sizes are already known
+	StatsForUploads(ctx context.Context, uploads []types.MultipartUpload, inaccessibleBuckets []string) (*types.StatsReport, error)
+	
+	// GenerateMetrics lists uploads once, fetches their sizes and totals them for monitoring
+	GenerateMetrics(ctx context.Context, opts types.ListOptions) (*types.MetricsReport, error)
+	
+	// MetricsForUploads totals uploads whose sizes are already known for monitoring
+	MetricsForUploads(ctx context.Context, uploads []types.MultipartUpload, inaccessibleBuckets []string) (*types.MetricsReport, error)
+	
+	// GenerateSnapshot lists uploads once, fetches their sizes and totals them by bucket
+	GenerateSnapshot(ctx context.Context, opts types.ListOptions) (*types.Snapshot, error)
+	
+	// SnapshotForUploads totals uploads whose sizes are already known by bucket
+	SnapshotForUploads(uploads []types.MultipartUpload, inaccessibleBuckets []string) *types.Snapshot
+}
+
+// MetricsFormatter renders upload metrics for monitoring systems
+type MetricsFormatter interface {
+	// FormatOpenMetrics renders a metrics report in the OpenMetrics text format
+	FormatOpenMetrics(report types.MetricsReport) string
+}
+
+// DoctorService checks that credentials, permissions and settings will work before a scan
+type DoctorService interface {
+	// RunChecks makes every check in order and reports each outcome
+	RunChecks(ctx context.Context, opts types.DoctorOptions) []types.DoctorCheck
+}
+
+// LifecycleService audits bucket lifecycle rules for incomplete multipart uploads
+type LifecycleService interface {
+	// CheckBuckets reports the abort-incomplete-uploads rule and upload count of each bucket
+	CheckBuckets(ctx context.Context, opts types.ListOptions) ([]types.LifecycleStatus, error)
+	
+	// PlanAbortRule works out the abort rule change for each selected bucket without writing anything
+	PlanAbortRule(ctx context.Context, opts types.LifecycleApplyOptions) ([]types.LifecycleChange, error)
+	
+	// ApplyAbortRule writes pending changes, merging the rule into each bucket's current rules
+	ApplyAbortRule(ctx context.Context, changes []types.LifecycleChange) (*types.LifecycleApplyResult, error)
 }
 
 // SizeService handles size calculation and reporting
 type SizeService interface {
-	// CalculateTotalSize calculates the total size of all incomplete multipart uploads
+	// CalculateTotalSize calculates the total size of all incomplete multipart
+	// uploads, returning the report of the other buckets with the
+	// types.BucketErrors of those that could not be listed
 	CalculateTotalSize(ctx context.Context, opts types.ListOptions) (*types.SizeReport, error)
 	
 	// CalculateBucketSizes calculates sizes grouped by bucket
 	CalculateBucketSizes(ctx context.Context, opts types.ListOptions) (*types.SizeReport, error)
 	
+	// HydrateUploadSizes fills in the size of each upload, returning inaccessible buckets
+	HydrateUploadSizes(ctx context.Context, uploads []types.MultipartUpload) ([]types.MultipartUpload, []string, error)
+	
+	// HydrateUploadParts fills in the size and every part of each upload in one walk, bypassing the cache
+	HydrateUploadParts(ctx context.Context, uploads []types.MultipartUpload) ([]types.MultipartUpload, []string, error)
+	
+	// EstimateParts totals the parts counts already known for uploads, from
+	// them or the cache, without listing any, and counts the uploads it knew
+	EstimateParts(uploads []types.MultipartUpload) (int, int)
+	
+	// RemeasureStaleSizes measures again uploads whose size is older than olderThan, bypassing the cache
+	RemeasureStaleSizes(ctx context.Context, uploads []types.MultipartUpload, olderThan time.Duration) ([]types.MultipartUpload, error)
+	
+	// ReportForUploads builds a size report from uploads whose sizes are already known
+	ReportForUploads(uploads []types.MultipartUpload) (*types.SizeReport, error)
+	
+	// CalculateSizeDistribution counts uploads whose sizes are already known by size range
+	CalculateSizeDistribution(uploads []types.MultipartUpload) types.SizeDistribution
+	
 	// GetSortedBucketSizes returns bucket sizes sorted by size in descending order
 	GetSortedBucketSizes(report *types.SizeReport) []BucketSize
 	
@@ -168,6 +388,15 @@ type SizeService interface {
 	GetStorageClassBreakdown(report *types.SizeReport) []StorageClassSize
 }
 
+// ObjectCheckService compares incomplete uploads with the completed objects at their keys
+type ObjectCheckService interface {
+	// MarkSuperseded flags uploads whose key holds a newer completed object, returning the buckets it could not check
+	MarkSuperseded(ctx context.Context, uploads []types.MultipartUpload) ([]types.MultipartUpload, []string, error)
+	
+	// GetCacheStats returns the number of HeadObject calls made and avoided
+	GetCacheStats() map[string]interface{}
+}
+
 // BucketSize represents a bucket with its size for sorting
 type BucketSize struct {
 	Bucket string `json:"bucket"`
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+
+	"github.com/Garvitkul/s3mpc/internal/logging"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// accessClient answers aborts with the error of the bucket, recording them
+type accessClient struct {
+	mockAbortClient
+	errors map[string]error
+
+	mutex  sync.Mutex
+	aborts []s3.AbortMultipartUploadInput
+}
+
+func (c *accessClient) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	c.mutex.Lock()
+	c.aborts = append(c.aborts, *input)
+	c.mutex.Unlock()
+	return nil, c.errors[*input.Bucket]
+}
+
+func TestDryRunVerifiesDeleteAccess(t *testing.T) {
+	client := &accessClient{errors: map[string]error{
+		"open":   &smithy.GenericAPIError{Code: "NoSuchUpload", Message: "The specified upload does not exist"},
+		"locked": &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"},
+	}}
+	// throttled is in another region, probed by that region's client
+	west := &accessClient{errors: map[string]error{
+		"throttled": &smithy.GenericAPIError{Code: "SlowDown", Message: "Please reduce your request rate"},
+	}}
+	var output, log bytes.Buffer
+	service := &UploadService{
+		client:              client,
+		dryRunService:       NewDryRunService(NewCostService()),
+		concurrency:         1,
+		regionalClients:     map[string]S3UploadClientInterface{"us-east-1": client, "eu-west-1": west},
+		progressReporter:    NewConsoleProgressReporter(&output, true),
+		outputWriter:        &output,
+		logger:              logging.NewLogger(logging.LevelInfo, &log, false),
+		accessProbeInterval: 20 * time.Millisecond,
+	}
+
+	initiated := time.Now().Add(-48 * time.Hour)
+	uploads := []types.MultipartUpload{
+		{Bucket: "open", Key: "logs/a.tmp", UploadID: "1", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1", Size: 100},
+		{Bucket: "open", Key: "logs/b.tmp", UploadID: "2", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1", Size: 100},
+		{Bucket: "locked", Key: "media/c.tmp", UploadID: "3", Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1", Size: 300},
+		{Bucket: "throttled", Key: "d.tmp", UploadID: "4", Initiated: initiated, StorageClass: "STANDARD", Region: "eu-west-1", Size: 200},
+	}
+
+	var result types.DryRunResult
+	start := time.Now()
+	err := service.DeleteUploads(context.Background(), uploads, types.DeleteOptions{
+		DryRun:       true,
+		VerifyAccess: true,
+		OnDryRun:     func(r types.DryRunResult) { result = r },
+	})
+	if err != nil {
+		t.Fatalf("DeleteUploads() error = %v", err)
+	}
+	// The probes wait for each other, so three buckets take two intervals
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("three probes took %v, want them at least 20ms apart", elapsed)
+	}
+
+	if len(client.aborts) != 2 || len(west.aborts) != 1 || *west.aborts[0].Bucket != "throttled" {
+		t.Fatalf("made %d aborts in us-east-1 and %d in eu-west-1, want one probe per bucket through its region's client", len(client.aborts), len(west.aborts))
+	}
+	for _, abort := range append(client.aborts, west.aborts...) {
+		if *abort.UploadId != AccessProbeUploadID {
+			t.Errorf("aborted upload %s in %s, want only the probe upload", *abort.UploadId, *abort.Bucket)
+		}
+		if *abort.Bucket == "locked" && *abort.Key != "media/c.tmp" {
+			t.Errorf("probed key %s in locked, want the key of an upload it would delete", *abort.Key)
+		}
+	}
+
+	if len(result.AccessByBucket) != 2 || !result.AccessByBucket["open"] || result.AccessByBucket["locked"] {
+		t.Errorf("AccessByBucket = %v, want open deletable and locked not", result.AccessByBucket)
+	}
+	if result.AccessErrors["throttled"] != "SlowDown" {
+		t.Errorf("AccessErrors = %v, want throttled unverified with SlowDown", result.AccessErrors)
+	}
+
+	for _, want := range []string{
+		"Verifying delete access in 3 buckets",
+		"Deletable buckets (verified): 1 of 3",
+		"locked: s3:AbortMultipartUpload denied, so its 1 uploads would fail",
+		"throttled: could not verify (SlowDown)",
+		"open: 2 uploads (200 B, $0.00/month, deletable: yes)",
+		"locked: 1 uploads (300 B, $0.00/month, deletable: no)",
+		"throttled: 1 uploads (200 B, $0.00/month, deletable: unknown)",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, output.String())
+		}
+	}
+	if got := strings.Count(log.String(), "Probed delete access"); got != 3 {
+		t.Errorf("logged %d probes, want 3:\n%s", got, log.String())
+	}
+}
@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/Garvitkul/s3mpc/pkg/interfaces"
@@ -9,17 +11,45 @@ import (
 )
 
 // ageService implements the AgeService interface
-type ageService struct{}
+type ageService struct {
+	boundaries []time.Duration // nil uses the default buckets
+}
 
 // NewAgeService creates a new age service instance
 func NewAgeService() interfaces.AgeService {
 	return &ageService{}
 }
 
-// CalculateAgeDistribution calculates age distribution of uploads
-func (s *ageService) CalculateAgeDistribution(ctx context.Context, uploads []types.MultipartUpload) (types.AgeDistribution, error) {
-	// Define age buckets based on requirements: 1 day, 1 week, 1 month, 3 months, 6 months, 1 year+
-	buckets := []types.AgeBucket{
+// NewAgeServiceWithBoundaries creates an age service whose buckets are split
+// at boundaries, as validated by ValidateAgeBoundaries, instead of the
+// default 1 day to 1 year+ buckets
+func NewAgeServiceWithBoundaries(boundaries []time.Duration) interfaces.AgeService {
+	return &ageService{boundaries: boundaries}
+}
+
+// ValidateAgeBoundaries checks that age bucket boundaries are positive and
+// strictly increasing
+func ValidateAgeBoundaries(boundaries []time.Duration) error {
+	if len(boundaries) == 0 {
+		return fmt.Errorf("at least one boundary is required")
+	}
+	for i, boundary := range boundaries {
+		if boundary <= 0 {
+			return fmt.Errorf("boundary %s must be positive", ageLabel(boundary))
+		}
+		if i > 0 && boundary == boundaries[i-1] {
+			return fmt.Errorf("duplicate boundary %s", ageLabel(boundary))
+		}
+		if i > 0 && boundary < boundaries[i-1] {
+			return fmt.Errorf("boundaries must be in increasing order, but %s follows %s", ageLabel(boundary), ageLabel(boundaries[i-1]))
+		}
+	}
+	return nil
+}
+
+// defaultAgeBuckets returns the default buckets: 1 day, 1 week, 1 month, 3 months, 6 months, 1 year+
+func defaultAgeBuckets() []types.AgeBucket {
+	return []types.AgeBucket{
 		{Label: "1 day", MinAge: 0, MaxAge: 24 * time.Hour, Count: 0, TotalSize: 0},
 		{Label: "1 week", MinAge: 24 * time.Hour, MaxAge: 7 * 24 * time.Hour, Count: 0, TotalSize: 0},
 		{Label: "1 month", MinAge: 7 * 24 * time.Hour, MaxAge: 30 * 24 * time.Hour, Count: 0, TotalSize: 0},
@@ -27,6 +57,46 @@ func (s *ageService) CalculateAgeDistribution(ctx context.Context, uploads []typ
 		{Label: "6 months", MinAge: 90 * 24 * time.Hour, MaxAge: 180 * 24 * time.Hour, Count: 0, TotalSize: 0},
 		{Label: "1 year+", MinAge: 180 * 24 * time.Hour, MaxAge: time.Duration(0), Count: 0, TotalSize: 0}, // MaxAge 0 means no upper limit
 	}
+}
+
+// boundaryAgeBuckets returns one bucket below the first boundary, one between
+// each pair and one open-ended bucket from the last, such as "< 6h", "6h - 1d"
+// and "7d+"
+func boundaryAgeBuckets(boundaries []time.Duration) []types.AgeBucket {
+	buckets := make([]types.AgeBucket, 0, len(boundaries)+1)
+	buckets = append(buckets, types.AgeBucket{Label: "< " + ageLabel(boundaries[0]), MaxAge: boundaries[0]})
+	for i := 1; i < len(boundaries); i++ {
+		buckets = append(buckets, types.AgeBucket{
+			Label:  ageLabel(boundaries[i-1]) + " - " + ageLabel(boundaries[i]),
+			MinAge: boundaries[i-1],
+			MaxAge: boundaries[i],
+		})
+	}
+	last := boundaries[len(boundaries)-1]
+	return append(buckets, types.AgeBucket{Label: ageLabel(last) + "+", MinAge: last})
+}
+
+// ageLabel writes a boundary in the largest unit that divides it exactly,
+// such as 2d for 48h and 90m for 1h30m
+func ageLabel(age time.Duration) string {
+	units := []struct {
+		size   time.Duration
+		suffix string
+	}{{24 * time.Hour, "d"}, {time.Hour, "h"}, {time.Minute, "m"}}
+	for _, unit := range units {
+		if age != 0 && age%unit.size == 0 {
+			return fmt.Sprintf("%d%s", age/unit.size, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%ds", int64(age/time.Second))
+}
+
+// CalculateAgeDistribution calculates age distribution of uploads
+func (s *ageService) CalculateAgeDistribution(ctx context.Context, uploads []types.MultipartUpload) (types.AgeDistribution, error) {
+	buckets := defaultAgeBuckets()
+	if s.boundaries != nil {
+		buckets = boundaryAgeBuckets(s.boundaries)
+	}
 
 	now := time.Now()
 
@@ -38,7 +108,7 @@ func (s *ageService) CalculateAgeDistribution(ctx context.Context, uploads []typ
 		for i := range buckets {
 			bucket := &buckets[i]
 			
-			// For the last bucket (1 year+), MaxAge of 0 means no upper limit
+			// For the last bucket, MaxAge of 0 means no upper limit
 			if bucket.MaxAge == 0 {
 				if age >= bucket.MinAge {
 					bucket.Count++
@@ -56,7 +126,37 @@ func (s *ageService) CalculateAgeDistribution(ctx context.Context, uploads []typ
 		}
 	}
 
-	return types.AgeDistribution{Buckets: buckets}, nil
+	return types.AgeDistribution{Buckets: buckets, Percentiles: agePercentiles(uploads, now)}, nil
+}
+
+// agePercentiles ranks uploads by age using the nearest-rank method, or
+// returns nil without uploads
+func agePercentiles(uploads []types.MultipartUpload, now time.Time) *types.AgePercentiles {
+	if len(uploads) == 0 {
+		return nil
+	}
+
+	ranked := make([]types.MultipartUpload, len(uploads))
+	copy(ranked, uploads)
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].Initiated.After(ranked[j].Initiated)
+	})
+
+	at := func(percent int) types.AgePercentile {
+		rank := (percent*len(ranked) + 99) / 100
+		if rank < 1 {
+			rank = 1
+		}
+		initiated := ranked[rank-1].Initiated
+		percentile := types.AgePercentile{Age: now.Sub(initiated)}
+		for _, upload := range ranked {
+			if !upload.Initiated.After(initiated) {
+				percentile.Size += upload.Size
+			}
+		}
+		return percentile
+	}
+	return &types.AgePercentiles{Min: at(0), Median: at(50), P90: at(90), Max: at(100)}
 }
 
 // GetAgeDistributionForBucket calculates age distribution for a specific bucket
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestAgeDistributionWithBoundaries(t *testing.T) {
+	now := time.Now()
+	uploads := []types.MultipartUpload{
+		{Key: "a", Initiated: now.Add(-1 * time.Hour), Size: 100},
+		{Key: "b", Initiated: now.Add(-12 * time.Hour), Size: 200},
+		{Key: "c", Initiated: now.Add(-30 * time.Hour), Size: 300},
+		{Key: "d", Initiated: now.Add(-10 * 24 * time.Hour), Size: 400},
+	}
+
+	boundaries := []time.Duration{6 * time.Hour, 24 * time.Hour, 48 * time.Hour, 7 * 24 * time.Hour}
+	dist, err := NewAgeServiceWithBoundaries(boundaries).CalculateAgeDistribution(context.Background(), uploads)
+	if err != nil {
+		t.Fatalf("CalculateAgeDistribution() error = %v", err)
+	}
+
+	want := []struct {
+		label string
+		count int
+	}{{"< 6h", 1}, {"6h - 1d", 1}, {"1d - 2d", 1}, {"2d - 7d", 0}, {"7d+", 1}}
+	if len(dist.Buckets) != len(want) {
+		t.Fatalf("got %d buckets, want %d", len(dist.Buckets), len(want))
+	}
+	for i, w := range want {
+		if got := dist.Buckets[i]; got.Label != w.label || got.Count != w.count {
+			t.Errorf("bucket %d = %s with %d, want %s with %d", i, got.Label, got.Count, w.label, w.count)
+		}
+	}
+
+	p := dist.Percentiles
+	if p == nil {
+		t.Fatal("Percentiles = nil, want statistics for 4 uploads")
+	}
+	// Median is the second youngest by nearest rank; everything at least
+	// 12h old adds up to 900 bytes
+	if p.Median.Age < 12*time.Hour || p.Median.Age > 13*time.Hour || p.Median.Size != 900 {
+		t.Errorf("Median = %+v, want about 12h with 900 bytes", p.Median)
+	}
+	if p.Min.Size != 1000 || p.Max.Size != 400 || p.P90.Size != 400 {
+		t.Errorf("Min, P90, Max sizes = %d, %d, %d, want 1000, 400, 400", p.Min.Size, p.P90.Size, p.Max.Size)
+	}
+}
+
+func TestAgeDistributionWithoutUploadsHasNoPercentiles(t *testing.T) {
+	dist, err := NewAgeService().CalculateAgeDistribution(context.Background(), nil)
+	if err != nil || dist.Percentiles != nil {
+		t.Errorf("CalculateAgeDistribution(nil) percentiles = %+v, %v, want none", dist.Percentiles, err)
+	}
+	if len(dist.Buckets) != 6 {
+		t.Errorf("got %d default buckets, want 6", len(dist.Buckets))
+	}
+}
+
+func TestValidateAgeBoundaries(t *testing.T) {
+	tests := []struct {
+		boundaries []time.Duration
+		wantErr    string
+	}{
+		{[]time.Duration{6 * time.Hour, 48 * time.Hour}, ""},
+		{nil, "at least one boundary"},
+		{[]time.Duration{0}, "must be positive"},
+		{[]time.Duration{24 * time.Hour, 48 * time.Hour, 48 * time.Hour}, "duplicate boundary 2d"},
+		{[]time.Duration{48 * time.Hour, 6 * time.Hour}, "6h follows 2d"},
+	}
+	for _, tt := range tests {
+		err := ValidateAgeBoundaries(tt.boundaries)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("ValidateAgeBoundaries(%v) error = %v", tt.boundaries, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ValidateAgeBoundaries(%v) error = %v, want %q", tt.boundaries, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFormatAgeDistributionPercentiles(t *testing.T) {
+	formatter := NewOutputFormatter()
+	dist := types.AgeDistribution{
+		Buckets: []types.AgeBucket{{Label: "7d+", MinAge: 7 * 24 * time.Hour, Count: 1, TotalSize: 2048}},
+		Percentiles: &types.AgePercentiles{
+			Min:    types.AgePercentile{Age: 8 * 24 * time.Hour, Size: 2048},
+			Median: types.AgePercentile{Age: 8 * 24 * time.Hour, Size: 2048},
+			P90:    types.AgePercentile{Age: 8 * 24 * time.Hour, Size: 2048},
+			Max:    types.AgePercentile{Age: 8 * 24 * time.Hour, Size: 2048},
+		},
+	}
+	out := formatter.FormatAgeDistribution(dist)
+	for _, want := range []string{"Median", "P90", "Size At Least This Old"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("FormatAgeDistribution() missing %q:\n%s", want, out)
+		}
+	}
+
+	json, err := formatter.FormatJSON(dist)
+	if err != nil || !strings.Contains(json, `"percentiles"`) {
+		t.Errorf("FormatJSON() = %s, %v, want percentiles", json, err)
+	}
+}
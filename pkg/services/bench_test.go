@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/Garvitkul/s3mpc/internal/benchutil"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// latencyClient implements S3UploadClientInterface, returning a fixed page of
+// uploads after a simulated round trip
+type latencyClient struct {
+	latency time.Duration
+	page    []s3types.MultipartUpload
+}
+
+func (c *latencyClient) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (*s3.ListMultipartUploadsOutput, error) {
+	time.Sleep(c.latency)
+	return &s3.ListMultipartUploadsOutput{Uploads: c.page}, nil
+}
+
+func (c *latencyClient) ListParts(ctx context.Context, input *s3.ListPartsInput) (*s3.ListPartsOutput, error) {
+	time.Sleep(c.latency)
+	return &s3.ListPartsOutput{}, nil
+}
+
+func (c *latencyClient) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (c *latencyClient) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	return nil, &s3types.NotFound{}
+}
+
+// fanOutService returns an UploadService over the given number of buckets, each
+// holding uploadsPerBucket uploads behind a client with latency
+func fanOutService(buckets, uploadsPerBucket int, latency time.Duration) (*UploadService, []types.Bucket) {
+	page := make([]s3types.MultipartUpload, uploadsPerBucket)
+	for i := range page {
+		page[i] = s3types.MultipartUpload{
+			Key:          aws.String(fmt.Sprintf("data/object-%06d.bin", i)),
+			UploadId:     aws.String(fmt.Sprintf("upload-%06d", i)),
+			Initiated:    aws.Time(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+			StorageClass: s3types.StorageClassStandard,
+		}
+	}
+
+	bucketList := make([]types.Bucket, buckets)
+	for i := range bucketList {
+		bucketList[i] = types.Bucket{Name: fmt.Sprintf("bucket-%03d", i), Region: "us-east-1"}
+	}
+
+	service := &UploadService{
+		concurrency:     10,
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": &latencyClient{latency: latency, page: page}},
+	}
+	return service, bucketList
+}
+
+func BenchmarkListUploadsForBuckets(b *testing.B) {
+	service, buckets := fanOutService(100, 100, 5*time.Millisecond)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := service.listUploadsForBuckets(ctx, buckets, types.ListOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDeleteUploads(b *testing.B) {
+	service := deleteService()
+	uploads := benchutil.SyntheticUploads(100_000)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := service.deleteUploadsWithProgress(ctx, uploads, types.DeleteOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(uploads)*b.N)/b.Elapsed().Seconds(), "uploads/s")
+}
+
+// deleteService returns an UploadService whose deletions succeed at once, in
+// every region of the synthetic uploads, and report no progress
+func deleteService() *UploadService {
+	client := &latencyClient{}
+	return &UploadService{
+		client:      client,
+		concurrency: 10,
+		regionalClients: map[string]S3UploadClientInterface{
+			"us-east-1": client, "us-west-2": client, "eu-west-1": client, "ap-southeast-2": client,
+		},
+		progressReporter: NewConsoleProgressReporter(io.Discard, true),
+	}
+}
+
+func BenchmarkGenerateSizeReport(b *testing.B) {
+	service := NewSizeServiceWithConcurrency(nil, 10)
+	uploads := benchutil.SyntheticUploads(100_000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		service.generateSizeReport(uploads, nil)
+	}
+}
+
+func BenchmarkExportCSV(b *testing.B) {
+	benchmarkExport(b, (*ExportService).ExportToCSV, "uploads.csv")
+}
+
+func BenchmarkExportJSON(b *testing.B) {
+	benchmarkExport(b, (*ExportService).ExportToJSON, "uploads.json")
+}
+
+// benchmarkExport measures export throughput in uploads per second for 100k uploads
+func benchmarkExport(b *testing.B, export func(*ExportService, context.Context, []types.MultipartUpload, string) error, name string) {
+	service := &ExportService{}
+	uploads := benchutil.SyntheticUploads(100_000)
+	filename := filepath.Join(b.TempDir(), name)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := export(service, ctx, uploads, filename); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(uploads)*b.N)/b.Elapsed().Seconds(), "uploads/s")
+}
+
+func BenchmarkFormatUploads100k(b *testing.B) {
+	formatter := &OutputFormatter{}
+	uploads := benchutil.SyntheticUploads(100_000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		formatter.FormatUploads(uploads, true)
+	}
+}
+
+func TestScanPipelineAllocBudgets(t *testing.T) {
+	if testing.Short() {
+		t.Skip("allocation budgets are measured in full test runs")
+	}
+
+	uploads := benchutil.SyntheticUploads(10_000)
+	ctx := context.Background()
+
+	service, buckets := fanOutService(10, 1_000, 0)
+	benchutil.CheckAllocsPerUpload(t, "listUploadsForBuckets", 10*1_000, 4, func() {
+		service.listUploadsForBuckets(ctx, buckets, types.ListOptions{})
+	})
+
+	deletions := deleteService()
+	benchutil.CheckAllocsPerUpload(t, "deleteUploadsWithProgress", len(uploads), 6, func() {
+		deletions.deleteUploadsWithProgress(ctx, uploads, types.DeleteOptions{})
+	})
+
+	sizeService := NewSizeServiceWithConcurrency(nil, 10)
+	benchutil.CheckAllocsPerUpload(t, "generateSizeReport", len(uploads), 0.1, func() {
+		sizeService.generateSizeReport(uploads, nil)
+	})
+
+	filename := filepath.Join(t.TempDir(), "uploads.csv")
+	exportService := &ExportService{}
+	benchutil.CheckAllocsPerUpload(t, "ExportToCSV", len(uploads), 4, func() {
+		exportService.ExportToCSV(ctx, uploads, filename)
+	})
+
+	formatter := &OutputFormatter{}
+	benchutil.CheckAllocsPerUpload(t, "FormatUploads", len(uploads), 30, func() {
+		formatter.FormatUploads(uploads, true)
+	})
+}
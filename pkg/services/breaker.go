@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// DefaultMaxBucketFailures is how many consecutive bucket-level failures
+// trip the breaker of a bucket
+const DefaultMaxBucketFailures = 5
+
+// errBucketTripped is the result of an upload that was not measured because
+// the breaker of its bucket tripped
+var errBucketTripped = errors.New("skipped after repeated failures in the bucket")
+
+// bucketFailureCodes are the errors that hold for every upload in a bucket,
+// rather than for the one upload a call was about
+var bucketFailureCodes = map[string]bool{
+	"AccessDenied":      true,
+	"AllAccessDisabled": true,
+	"NoSuchBucket":      true,
+}
+
+// bucketBreaker stops per-upload calls to a bucket after repeated failures
+// that hold for the whole bucket, such as AccessDenied, so the other buckets
+// keep the rate limit budget. Failures only count while they are consecutive.
+type bucketBreaker struct {
+	threshold int // zero never trips
+
+	mutex   sync.Mutex
+	buckets map[string]*breakerState
+}
+
+// breakerState is the failure count of one bucket and, once tripped, what
+// tripped it and how many uploads were skipped since
+type breakerState struct {
+	consecutive int
+	tripped     bool
+	code        string
+	skipped     int
+}
+
+// newBucketBreaker returns a breaker that trips after threshold consecutive
+// bucket-level failures in a bucket; zero or less never trips
+func newBucketBreaker(threshold int) *bucketBreaker {
+	return &bucketBreaker{
+		threshold: threshold,
+		buckets:   make(map[string]*breakerState),
+	}
+}
+
+// allow reports whether bucket may be called. An upload refused by a tripped
+// breaker is counted as skipped.
+func (b *bucketBreaker) allow(bucket string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	state := b.buckets[bucket]
+	if state == nil || !state.tripped {
+		return true
+	}
+	state.skipped++
+	return false
+}
+
+// success resets the failure count of bucket
+func (b *bucketBreaker) success(bucket string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if state := b.buckets[bucket]; state != nil && !state.tripped {
+		state.consecutive = 0
+	}
+}
+
+// failure counts a failed call to bucket and reports whether it tripped the
+// breaker. Only bucketFailureCodes are counted: throttling may pass, and an
+// upload that is gone, such as on NoSuchUpload, says nothing of the others.
+func (b *bucketBreaker) failure(bucket string, err error) bool {
+	if b.threshold <= 0 || !bucketFailureCodes[awsclient.ErrorCode(err)] {
+		return false
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	state := b.buckets[bucket]
+	if state == nil {
+		state = &breakerState{}
+		b.buckets[bucket] = state
+	}
+	if state.tripped {
+		return false
+	}
+	state.consecutive++
+	if state.consecutive < b.threshold {
+		return false
+	}
+	state.tripped = true
+	state.code = awsclient.ErrorCode(err)
+	return true
+}
+
+// tripped returns the buckets whose breaker tripped, sorted by name
+func (b *bucketBreaker) tripped() []types.TrippedBucket {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var tripped []types.TrippedBucket
+	for bucket, state := range b.buckets {
+		if state.tripped {
+			tripped = append(tripped, types.TrippedBucket{
+				Bucket:         bucket,
+				ErrorCode:      state.code,
+				Failures:       state.consecutive,
+				SkippedUploads: state.skipped,
+			})
+		}
+	}
+	sort.Slice(tripped, func(i, j int) bool { return tripped[i].Bucket < tripped[j].Bucket })
+	return tripped
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// lockedBucketClient lists uploads in every bucket and denies part listings
+// in the locked bucket, counting the calls each bucket gets
+type lockedBucketClient struct {
+	mockPartsClient
+	uploads map[string]int // uploads per bucket
+
+	mutex     sync.Mutex
+	partCalls map[string]int
+}
+
+func (c *lockedBucketClient) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (*s3.ListMultipartUploadsOutput, error) {
+	bucket := aws.ToString(input.Bucket)
+	output := &s3.ListMultipartUploadsOutput{}
+	for i := 0; i < c.uploads[bucket]; i++ {
+		output.Uploads = append(output.Uploads, s3types.MultipartUpload{
+			Key:       aws.String(fmt.Sprintf("%s/%d.bin", bucket, i)),
+			UploadId:  aws.String(fmt.Sprintf("%s-%d", bucket, i)),
+			Initiated: aws.Time(time.Now().Add(-48 * time.Hour)),
+		})
+	}
+	return output, nil
+}
+
+func (c *lockedBucketClient) ListParts(ctx context.Context, input *s3.ListPartsInput) (*s3.ListPartsOutput, error) {
+	bucket := aws.ToString(input.Bucket)
+	c.mutex.Lock()
+	c.partCalls[bucket]++
+	c.mutex.Unlock()
+
+	if bucket == "locked" {
+		return nil, &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"}
+	}
+	return c.mockPartsClient.ListParts(ctx, input)
+}
+
+func TestSizeSkipsBucketAfterRepeatedAccessDenied(t *testing.T) {
+	client := &lockedBucketClient{
+		mockPartsClient: mockPartsClient{parts: []int64{1024}},
+		uploads:         map[string]int{"locked": 200, "open": 3},
+		partCalls:       map[string]int{},
+	}
+	uploadService := &UploadService{
+		client: failingClient{},
+		bucketService: &regionBucketService{buckets: []types.Bucket{
+			{Name: "locked", Region: "us-east-1"},
+			{Name: "open", Region: "us-east-1"},
+		}},
+		concurrency:     2,
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": client},
+	}
+	// One part listing at a time, so the locked bucket gets exactly the limit
+	sizeService := NewSizeServiceWithConcurrency(uploadService, 1)
+	sizeService.SetMaxBucketFailures(5)
+
+	report, err := sizeService.CalculateTotalSize(context.Background(), types.ListOptions{})
+	if err != nil {
+		t.Fatalf("CalculateTotalSize() error = %v", err)
+	}
+
+	if client.partCalls["locked"] != 5 {
+		t.Errorf("locked bucket got %d part listings, want 5 before the breaker tripped", client.partCalls["locked"])
+	}
+	if client.partCalls["open"] != 3 || report.TotalCount != 3 {
+		t.Errorf("open bucket got %d part listings and %d measured uploads, want all 3", client.partCalls["open"], report.TotalCount)
+	}
+	want := []types.TrippedBucket{{Bucket: "locked", ErrorCode: "AccessDenied", Failures: 5, SkippedUploads: 195}}
+	if len(report.TrippedBuckets) != 1 || report.TrippedBuckets[0] != want[0] {
+		t.Errorf("TrippedBuckets = %+v, want %+v", report.TrippedBuckets, want)
+	}
+	if len(report.InaccessibleBuckets) != 1 || report.InaccessibleBuckets[0] != "locked" {
+		t.Errorf("InaccessibleBuckets = %v, want [locked]", report.InaccessibleBuckets)
+	}
+	// Only the failed calls are listed as errors, not the skipped uploads
+	if len(report.ErrorSummary) != 1 || report.ErrorSummary[0].Count != 5 {
+		t.Errorf("ErrorSummary = %+v, want the 5 AccessDenied failures", report.ErrorSummary)
+	}
+
+	text := NewOutputFormatter().FormatSizeReport(*report)
+	if !strings.Contains(text, "Skipped 195 uploads in bucket locked after repeated AccessDenied") {
+		t.Errorf("size report does not name the skipped uploads:\n%s", text)
+	}
+
+	// Without a limit every upload is tried
+	client.partCalls = map[string]int{}
+	sizeService.SetMaxBucketFailures(0)
+	report, err = sizeService.CalculateTotalSize(context.Background(), types.ListOptions{})
+	if err != nil {
+		t.Fatalf("CalculateTotalSize() error = %v", err)
+	}
+	if client.partCalls["locked"] != 200 || len(report.TrippedBuckets) != 0 {
+		t.Errorf("locked bucket got %d part listings and tripped %v without a limit, want 200 and none", client.partCalls["locked"], report.TrippedBuckets)
+	}
+}
+
+func TestBucketBreakerCountsConsecutivePermanentFailures(t *testing.T) {
+	denied := &smithy.GenericAPIError{Code: "AccessDenied"}
+	throttled := &smithy.GenericAPIError{Code: "SlowDown"}
+	gone := &smithy.GenericAPIError{Code: "NoSuchUpload"}
+
+	breaker := newBucketBreaker(3)
+	// A success in between resets the count, and neither throttling nor an
+	// upload that is gone is counted
+	for _, err := range []error{denied, denied, nil, denied, throttled, gone, gone, gone, denied} {
+		if err == nil {
+			breaker.success("logs")
+			continue
+		}
+		if breaker.failure("logs", err) {
+			t.Fatalf("breaker tripped after %v, want it closed", err)
+		}
+	}
+	if !breaker.allow("logs") {
+		t.Fatal("allow() = false before the breaker tripped")
+	}
+	if !breaker.failure("logs", denied) {
+		t.Fatal("failure() = false on the third consecutive AccessDenied, want the breaker tripped")
+	}
+	if breaker.allow("logs") || breaker.allow("logs") || !breaker.allow("other") {
+		t.Error("allow() lets calls into the tripped bucket or refuses the others")
+	}
+
+	// Uploads completed or aborted during a scan never trip the bucket
+	for i := 0; i < 10; i++ {
+		if breaker.failure("busy", fmt.Errorf("failed to list parts: %w", gone)) {
+			t.Fatal("breaker tripped on NoSuchUpload, want only bucket-level errors counted")
+		}
+	}
+
+	want := types.TrippedBucket{Bucket: "logs", ErrorCode: "AccessDenied", Failures: 3, SkippedUploads: 2}
+	if tripped := breaker.tripped(); len(tripped) != 1 || tripped[0] != want {
+		t.Errorf("tripped() = %+v, want %+v", tripped, want)
+	}
+}
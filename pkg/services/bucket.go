@@ -2,12 +2,17 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/Garvitkul/s3mpc/internal/logging"
 	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
 	"github.com/Garvitkul/s3mpc/pkg/interfaces"
 	pkgtypes "github.com/Garvitkul/s3mpc/pkg/types"
@@ -17,8 +22,27 @@ import (
 type S3ClientInterface interface {
 	ListBuckets(ctx context.Context) (*s3.ListBucketsOutput, error)
 	GetBucketLocation(ctx context.Context, bucket string) (*s3.GetBucketLocationOutput, error)
+	HeadBucket(ctx context.Context, bucket string) (*s3.HeadBucketOutput, error)
 }
 
+// S3BucketTaggingClientInterface reads bucket tags, which S3 only serves from
+// the region of the bucket
+type S3BucketTaggingClientInterface interface {
+	GetBucketTagging(ctx context.Context, bucket string) (*s3.GetBucketTaggingOutput, error)
+}
+
+// S3DirectoryBucketClientInterface lists directory buckets, which S3 only
+// lists per region
+type S3DirectoryBucketClientInterface interface {
+	ListDirectoryBuckets(ctx context.Context) (*s3.ListDirectoryBucketsOutput, error)
+}
+
+// bucketRegionHeader carries the region of a bucket in HeadBucket responses
+const bucketRegionHeader = "x-amz-bucket-region"
+
+// DefaultBucketListTTL is how long a fetched bucket list is reused
+const DefaultBucketListTTL = 5 * time.Minute
+
 // BucketService implements the interfaces.BucketService interface
 type BucketService struct {
 	client      S3ClientInterface
@@ -26,42 +50,125 @@ type BucketService struct {
 	cacheMutex  sync.RWMutex
 	cacheExpiry time.Duration
 	cacheTime   map[string]time.Time
+	// Tags are cached next to regions, under cacheMutex and for as long
+	tagCache    map[string]map[string]string
+	tagTime     map[string]time.Time
+	// concurrency limits the bucket region and tag lookups made at once
+	concurrency int
+
+	// regionalClients are the clients tags and directory buckets are read with
+	regionalClients map[string]*awsclient.S3Client
+	clientMutex     sync.Mutex
+
+	// The bucket list is cached separately from regions, for a shorter time,
+	// so several operations in one process share one ListBuckets call
+	listMutex    sync.Mutex
+	listTTL      time.Duration
+	listBuckets  []types.Bucket
+	listTime     time.Time
+	listInFlight *bucketListCall
+	listHits     int64
+	listMisses   int64
+	now          func() time.Time
+
+	// Region and tag lookups answered from the caches, counted atomically
+	// because they only take the read lock
+	regionHits   int64
+	regionMisses int64
+	tagHits      int64
+	tagMisses    int64
+
+	logger *logging.Logger // receives diagnostics; never nil once constructed
+}
+
+// bucketListCall is a ListBuckets call shared by every caller that arrives
+// while it is in flight
+type bucketListCall struct {
+	done    chan struct{}
+	buckets []types.Bucket
+	err     error
 }
 
 // NewBucketService creates a new BucketService instance
 func NewBucketService(client *awsclient.S3Client) interfaces.BucketService {
+	return NewBucketServiceWithTTL(client, DefaultBucketListTTL, nil)
+}
+
+// NewBucketServiceWithTTL creates a BucketService that reuses the bucket list
+// for listTTL; zero uses DefaultBucketListTTL. A nil logger logs nothing.
+func NewBucketServiceWithTTL(client S3ClientInterface, listTTL time.Duration, logger *logging.Logger) *BucketService {
+	if listTTL <= 0 {
+		listTTL = DefaultBucketListTTL
+	}
+	if logger == nil {
+		logger = logging.NewNopLogger()
+	}
 	return &BucketService{
 		client:      client,
 		regionCache: make(map[string]string),
 		cacheTime:   make(map[string]time.Time),
+		tagCache:    make(map[string]map[string]string),
+		tagTime:     make(map[string]time.Time),
 		cacheExpiry: 1 * time.Hour, // Cache regions for 1 hour
+		concurrency: 10,
+		listTTL:     listTTL,
+		now:         time.Now,
+		logger:      logger,
+	}
+}
+
+// log returns the logger of the service
+func (s *BucketService) log() *logging.Logger {
+	if s.logger == nil {
+		return logging.NewNopLogger()
 	}
+	return s.logger
 }
 
-// ListBuckets retrieves all accessible S3 buckets
-func (s *BucketService) ListBuckets(ctx context.Context, region string) ([]pkgtypes.Bucket, error) {
+// ListBuckets retrieves all accessible S3 buckets. Buckets whose region
+// cannot be determined are returned separately, as they cannot be scanned.
+func (s *BucketService) ListBuckets(ctx context.Context, region string) ([]pkgtypes.Bucket, []pkgtypes.SkippedBucket, error) {
+	start := time.Now()
+	
 	// List all buckets
-	output, err := s.client.ListBuckets(ctx)
+	awsBuckets, err := s.fetchBucketList(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list buckets: %w", err)
+		return nil, nil, fmt.Errorf("failed to list buckets: %w", err)
 	}
 
 	var buckets []pkgtypes.Bucket
-	
-	// Convert AWS bucket types to our bucket types and get their regions
-	for _, bucket := range output.Buckets {
+	var skipped []pkgtypes.SkippedBucket
+
+	// Regions are looked up concurrently, keeping the listed order
+	regions := make([]string, len(awsBuckets))
+	lookupErrs := make([]error, len(awsBuckets))
+	s.forEachBucket(len(awsBuckets), func(i int) {
+		if name := awsBuckets[i].Name; name != nil {
+			regions[i], lookupErrs[i] = s.GetBucketRegion(ctx, *name)
+		}
+	})
+
+	// Convert AWS bucket types to our bucket types
+	for i, bucket := range awsBuckets {
 		if bucket.Name == nil {
 			continue
 		}
 
-		bucketRegion, err := s.GetBucketRegion(ctx, *bucket.Name)
+		bucketRegion, err := regions[i], lookupErrs[i]
 		if err != nil {
-			// Skip buckets we can't access
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			s.log().Debug("Failed to look up bucket region", map[string]interface{}{
+				"bucket": *bucket.Name,
+				"error":  err.Error(),
+			})
+			skipped = append(skipped, pkgtypes.SkippedBucket{Bucket: *bucket.Name, Error: err.Error()})
 			continue
 		}
 
 		// If region filter is specified, only include matching buckets
-		if region != "" && bucketRegion != region {
+		if region != "" && !sameRegion(bucketRegion, region) {
 			continue
 		}
 
@@ -71,11 +178,81 @@ func (s *BucketService) ListBuckets(ctx context.Context, region string) ([]pkgty
 		})
 	}
 
-	return buckets, nil
+	s.log().Debug("Listed buckets", map[string]interface{}{
+		"listed":   len(awsBuckets),
+		"selected": len(buckets),
+		"skipped":  len(skipped),
+		"region":   region,
+		"duration": time.Since(start).Round(time.Millisecond),
+	})
+	return buckets, skipped, nil
+}
+
+// fetchBucketList returns the cached bucket list, calling ListBuckets when it
+// is missing or older than the TTL. Concurrent callers share one call.
+func (s *BucketService) fetchBucketList(ctx context.Context) ([]types.Bucket, error) {
+	s.listMutex.Lock()
+	if s.listBuckets != nil && s.now().Sub(s.listTime) < s.listTTL {
+		s.listHits++
+		buckets := s.listBuckets
+		s.listMutex.Unlock()
+		return buckets, nil
+	}
+	if call := s.listInFlight; call != nil {
+		s.listHits++
+		s.listMutex.Unlock()
+		select {
+		case <-call.done:
+			return call.buckets, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	s.listMisses++
+	call := &bucketListCall{done: make(chan struct{})}
+	s.listInFlight = call
+	s.listMutex.Unlock()
+
+	output, err := s.client.ListBuckets(ctx)
+	if err == nil {
+		call.buckets = output.Buckets
+		if call.buckets == nil {
+			call.buckets = []types.Bucket{}
+		}
+	}
+	call.err = err
+
+	// Listings name the region of each bucket, saving a GetBucketLocation call each
+	for _, bucket := range call.buckets {
+		if bucket.Name != nil && aws.ToString(bucket.BucketRegion) != "" {
+			s.cacheRegion(*bucket.Name, *bucket.BucketRegion)
+		}
+	}
+
+	s.listMutex.Lock()
+	if err == nil {
+		s.listBuckets = call.buckets
+		s.listTime = s.now()
+	}
+	s.listInFlight = nil
+	s.listMutex.Unlock()
+	close(call.done)
+
+	return call.buckets, call.err
+}
+
+// Invalidate drops the cached bucket list so the next call lists buckets again,
+// for example after a bucket was created or deleted
+func (s *BucketService) Invalidate() {
+	s.listMutex.Lock()
+	defer s.listMutex.Unlock()
+	
+	s.listBuckets = nil
+	s.listTime = time.Time{}
 }
 
 // ListBucketsInRegion retrieves buckets in a specific region
-func (s *BucketService) ListBucketsInRegion(ctx context.Context, region string) ([]pkgtypes.Bucket, error) {
+func (s *BucketService) ListBucketsInRegion(ctx context.Context, region string) ([]pkgtypes.Bucket, []pkgtypes.SkippedBucket, error) {
 	return s.ListBuckets(ctx, region)
 }
 
@@ -87,121 +264,287 @@ func (s *BucketService) GetBucketRegion(ctx context.Context, bucketName string)
 		if cacheTime, timeExists := s.cacheTime[bucketName]; timeExists {
 			if time.Since(cacheTime) < s.cacheExpiry {
 				s.cacheMutex.RUnlock()
+				atomic.AddInt64(&s.regionHits, 1)
 				return cachedRegion, nil
 			}
 		}
 	}
 	s.cacheMutex.RUnlock()
+	atomic.AddInt64(&s.regionMisses, 1)
 
 	// Cache miss or expired, fetch from AWS
+	region, err := s.bucketLocation(ctx, bucketName)
+	if err != nil {
+		// HeadBucket reports the region even when GetBucketLocation is denied
+		headRegion, headErr := s.headBucketRegion(ctx, bucketName)
+		if headErr != nil {
+			return "", fmt.Errorf("failed to get bucket location for %s: %w", bucketName, err)
+		}
+		region = headRegion
+	}
+
+	s.cacheRegion(bucketName, region)
+	return region, nil
+}
+
+// cacheRegion remembers the region of a bucket until the cache expires
+func (s *BucketService) cacheRegion(bucketName, region string) {
+	s.cacheMutex.Lock()
+	s.regionCache[bucketName] = region
+	s.cacheTime[bucketName] = time.Now()
+	s.cacheMutex.Unlock()
+}
+
+// bucketLocation reads the region of a bucket with GetBucketLocation
+func (s *BucketService) bucketLocation(ctx context.Context, bucketName string) (string, error) {
 	output, err := s.client.GetBucketLocation(ctx, bucketName)
 	if err != nil {
-		return "", fmt.Errorf("failed to get bucket location for %s: %w", bucketName, err)
+		return "", err
 	}
 
 	// AWS returns empty string for us-east-1
-	region := "us-east-1"
-	if output.LocationConstraint != "" {
-		region = string(output.LocationConstraint)
+	if output.LocationConstraint == "" {
+		return "us-east-1", nil
+	}
+	return string(output.LocationConstraint), nil
+}
+
+// headBucketRegion reads the region of a bucket from a HeadBucket response.
+// S3 sends the x-amz-bucket-region header with 301 and 403 responses too, so
+// this works for buckets in other regions or that we may not read.
+func (s *BucketService) headBucketRegion(ctx context.Context, bucketName string) (string, error) {
+	output, err := s.client.HeadBucket(ctx, bucketName)
+	if err == nil {
+		if output.BucketRegion == nil || *output.BucketRegion == "" {
+			return "", fmt.Errorf("HeadBucket returned no region for %s", bucketName)
+		}
+		return *output.BucketRegion, nil
+	}
+
+	var responseErr *awshttp.ResponseError
+	if errors.As(err, &responseErr) && responseErr.Response != nil {
+		if region := responseErr.Response.Header.Get(bucketRegionHeader); region != "" {
+			return region, nil
+		}
+	}
+	return "", err
+}
+
+// GetBucketTags retrieves the tags of a bucket in region with caching. A bucket
+// without tags has an empty map.
+func (s *BucketService) GetBucketTags(ctx context.Context, bucketName, region string) (map[string]string, error) {
+	s.cacheMutex.RLock()
+	if cachedTags, exists := s.tagCache[bucketName]; exists && time.Since(s.tagTime[bucketName]) < s.cacheExpiry {
+		s.cacheMutex.RUnlock()
+		atomic.AddInt64(&s.tagHits, 1)
+		return cachedTags, nil
+	}
+	s.cacheMutex.RUnlock()
+	atomic.AddInt64(&s.tagMisses, 1)
+
+	client, err := s.taggingClient(region)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.GetBucketTagging(ctx, bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tags of bucket %s: %w", bucketName, err)
+	}
+
+	tags := make(map[string]string, len(output.TagSet))
+	for _, tag := range output.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
 	}
 
-	// Update cache
 	s.cacheMutex.Lock()
-	s.regionCache[bucketName] = region
-	s.cacheTime[bucketName] = time.Now()
+	s.tagCache[bucketName] = tags
+	s.tagTime[bucketName] = time.Now()
 	s.cacheMutex.Unlock()
+	return tags, nil
+}
 
-	return region, nil
+// taggingClient returns the client that reads tags of buckets in region
+func (s *BucketService) taggingClient(region string) (S3BucketTaggingClientInterface, error) {
+	if _, ok := s.client.(*awsclient.S3Client); !ok {
+		if client, ok := s.client.(S3BucketTaggingClientInterface); ok {
+			return client, nil
+		}
+	}
+	if client := s.regionalClient(region); client != nil {
+		return client, nil
+	}
+	return nil, fmt.Errorf("bucket tags cannot be read without an S3 client")
 }
 
-// filterBucketsByRegion filters buckets by the specified region
-func (s *BucketService) filterBucketsByRegion(ctx context.Context, awsBuckets []types.Bucket, targetRegion string) ([]pkgtypes.Bucket, error) {
-	var buckets []pkgtypes.Bucket
-	
-	// Use a channel to collect results from concurrent goroutines
-	type bucketResult struct {
-		bucket pkgtypes.Bucket
-		err    error
+// directoryClient returns the client that lists directory buckets in region
+func (s *BucketService) directoryClient(region string) (S3DirectoryBucketClientInterface, error) {
+	if _, ok := s.client.(*awsclient.S3Client); !ok {
+		if client, ok := s.client.(S3DirectoryBucketClientInterface); ok {
+			return client, nil
+		}
 	}
-	
-	resultChan := make(chan bucketResult, len(awsBuckets))
-	
-	// Process buckets concurrently
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, 10) // Limit concurrent operations to 10
-	
-	for _, bucket := range awsBuckets {
+	if client := s.regionalClient(region); client != nil {
+		return client, nil
+	}
+	return nil, fmt.Errorf("directory buckets cannot be listed without an S3 client")
+}
+
+// regionalClient returns the client for region, or nil when the main client is
+// not an *awsclient.S3Client; test clients are then used for every region.
+// Regional clients share the main client's credentials and rate limit settings.
+func (s *BucketService) regionalClient(region string) *awsclient.S3Client {
+	parent, ok := s.client.(*awsclient.S3Client)
+	if !ok || parent == nil {
+		return nil
+	}
+
+	s.clientMutex.Lock()
+	defer s.clientMutex.Unlock()
+
+	if s.regionalClients == nil {
+		s.regionalClients = make(map[string]*awsclient.S3Client)
+	}
+	if client, exists := s.regionalClients[region]; exists {
+		return client
+	}
+	client := parent.ForRegion(region)
+	s.regionalClients[region] = client
+	return client
+}
+
+// ListDirectoryBuckets retrieves the directory buckets (S3 Express One Zone)
+// in region. Their regions are cached like those of general purpose buckets.
+func (s *BucketService) ListDirectoryBuckets(ctx context.Context, region string) ([]pkgtypes.Bucket, error) {
+	client, err := s.directoryClient(region)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.ListDirectoryBuckets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list directory buckets in %s: %w", region, err)
+	}
+
+	var buckets []pkgtypes.Bucket
+	for _, bucket := range output.Buckets {
 		if bucket.Name == nil {
 			continue
 		}
-		
-		wg.Add(1)
-		go func(bucketName string) {
-			defer wg.Done()
-			
-			// Acquire semaphore
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-			
-			region, err := s.GetBucketRegion(ctx, bucketName)
-			if err != nil {
-				resultChan <- bucketResult{err: err}
-				return
-			}
-			
-			// Only include buckets in the target region
-			if region == targetRegion {
-				resultChan <- bucketResult{
-					bucket: pkgtypes.Bucket{
-						Name:   bucketName,
-						Region: region,
-					},
-				}
-			}
-		}(*bucket.Name)
+		bucketRegion := aws.ToString(bucket.BucketRegion)
+		if bucketRegion == "" {
+			bucketRegion = region
+		}
+		s.cacheRegion(*bucket.Name, bucketRegion)
+		buckets = append(buckets, pkgtypes.Bucket{
+			Name:       *bucket.Name,
+			Region:     bucketRegion,
+			BucketType: pkgtypes.BucketTypeDirectory,
+		})
 	}
-	
-	// Close channel when all goroutines complete
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-	
-	// Collect results
-	var errors []error
-	for result := range resultChan {
+	return buckets, nil
+}
+
+// SelectBucketsByTags keeps the buckets whose tags match filter, looking tags
+// up concurrently. Each selected bucket carries the tags the filter looked at.
+// Buckets whose tags cannot be read are returned separately, as it cannot be
+// told whether they were selected.
+func (s *BucketService) SelectBucketsByTags(ctx context.Context, buckets []pkgtypes.Bucket, filter *pkgtypes.BucketTagFilter) ([]pkgtypes.Bucket, []pkgtypes.SkippedBucket, error) {
+	if filter.IsEmpty() {
+		return buckets, nil, nil
+	}
+
+	type tagResult struct {
+		tags map[string]string
+		err  error
+	}
+	results := make([]tagResult, len(buckets))
+	s.forEachBucket(len(buckets), func(i int) {
+		tags, err := s.GetBucketTags(ctx, buckets[i].Name, buckets[i].Region)
+		results[i] = tagResult{tags: tags, err: err}
+	})
+
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+
+	// Results are kept in the order the buckets were given
+	var selected []pkgtypes.Bucket
+	var skipped []pkgtypes.SkippedBucket
+	for i, bucket := range buckets {
+		result := results[i]
 		if result.err != nil {
-			errors = append(errors, result.err)
+			s.log().Debug("Failed to read bucket tags", map[string]interface{}{
+				"bucket": bucket.Name,
+				"error":  result.err.Error(),
+			})
+			skipped = append(skipped, pkgtypes.SkippedBucket{Bucket: bucket.Name, Error: result.err.Error(), Lookup: pkgtypes.SkippedTagLookup})
+			continue
+		}
+		if !filter.Matches(result.tags) {
 			continue
 		}
-		buckets = append(buckets, result.bucket)
+		bucket.Tags = filter.Selected(result.tags)
+		selected = append(selected, bucket)
 	}
-	
-	// If we have errors but also some successful results, we might want to return partial results
-	// For now, we'll return an error if any bucket failed
-	if len(errors) > 0 {
-		return buckets, fmt.Errorf("failed to get region for some buckets: %d errors occurred", len(errors))
+	return selected, skipped, nil
+}
+
+// SetConcurrency sets how many bucket regions and tags are looked up at once
+func (s *BucketService) SetConcurrency(concurrency int) {
+	if concurrency > 0 {
+		s.concurrency = concurrency
 	}
-	
-	return buckets, nil
 }
 
-// ClearRegionCache clears the region cache (useful for testing)
+// forEachBucket calls lookup with every index below n, up to s.concurrency
+// at a time, and returns when all calls have. Each call makes its own API
+// requests, which the client's rate limiter paces.
+func (s *BucketService) forEachBucket(n int, lookup func(i int)) {
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, s.concurrency)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			lookup(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// ClearRegionCache clears the region and tag caches (useful for testing)
 func (s *BucketService) ClearRegionCache() {
 	s.cacheMutex.Lock()
 	defer s.cacheMutex.Unlock()
 	
 	s.regionCache = make(map[string]string)
 	s.cacheTime = make(map[string]time.Time)
+	s.tagCache = make(map[string]map[string]string)
+	s.tagTime = make(map[string]time.Time)
 }
 
 // GetCacheStats returns cache statistics (useful for monitoring)
 func (s *BucketService) GetCacheStats() map[string]interface{} {
 	s.cacheMutex.RLock()
-	defer s.cacheMutex.RUnlock()
+	cachedRegions := len(s.regionCache)
+	cachedTags := len(s.tagCache)
+	s.cacheMutex.RUnlock()
+	
+	s.listMutex.Lock()
+	defer s.listMutex.Unlock()
 	
 	return map[string]interface{}{
-		"cached_regions": len(s.regionCache),
-		"cache_expiry":   s.cacheExpiry.String(),
+		"cached_regions":     cachedRegions,
+		"cached_tags":        cachedTags,
+		"cache_expiry":       s.cacheExpiry.String(),
+		"bucket_list_cached": s.listBuckets != nil && s.now().Sub(s.listTime) < s.listTTL,
+		"bucket_list_ttl":    s.listTTL.String(),
+		"bucket_list_hits":   s.listHits,
+		"bucket_list_misses": s.listMisses,
+		"region_hits":        atomic.LoadInt64(&s.regionHits),
+		"region_misses":      atomic.LoadInt64(&s.regionMisses),
+		"tag_hits":           atomic.LoadInt64(&s.tagHits),
+		"tag_misses":         atomic.LoadInt64(&s.tagMisses),
 	}
 }
\ No newline at end of file
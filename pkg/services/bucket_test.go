@@ -0,0 +1,579 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// countingBucketClient implements S3ClientInterface, holding each ListBuckets
+// call open until release is closed so concurrent callers overlap
+type countingBucketClient struct {
+	release   chan struct{}
+	listCalls int64
+}
+
+func (c *countingBucketClient) ListBuckets(ctx context.Context) (*s3.ListBucketsOutput, error) {
+	atomic.AddInt64(&c.listCalls, 1)
+	<-c.release
+	return &s3.ListBucketsOutput{Buckets: []s3types.Bucket{
+		{Name: aws.String("logs")},
+		{Name: aws.String("backups")},
+	}}, nil
+}
+
+func (c *countingBucketClient) GetBucketLocation(ctx context.Context, bucket string) (*s3.GetBucketLocationOutput, error) {
+	if bucket == "backups" {
+		return &s3.GetBucketLocationOutput{LocationConstraint: s3types.BucketLocationConstraintEuWest1}, nil
+	}
+	return &s3.GetBucketLocationOutput{}, nil
+}
+
+func (c *countingBucketClient) HeadBucket(ctx context.Context, bucket string) (*s3.HeadBucketOutput, error) {
+	return nil, errors.New("HeadBucket not expected")
+}
+
+func TestBucketListCachedPerTTLWindow(t *testing.T) {
+	client := &countingBucketClient{release: make(chan struct{})}
+	service := NewBucketServiceWithTTL(client, time.Minute, nil)
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	// Concurrent callers in one window share a single ListBuckets call
+	listConcurrently := func() {
+		t.Helper()
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				var err error
+				if i%2 == 0 {
+					_, _, err = service.ListBuckets(context.Background(), "")
+				} else {
+					_, _, err = service.ListBucketsInRegion(context.Background(), "eu-west-1")
+				}
+				if err != nil {
+					t.Errorf("list error = %v", err)
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	go func() {
+		// Let every caller reach the in-flight call before it returns
+		time.Sleep(20 * time.Millisecond)
+		close(client.release)
+	}()
+	listConcurrently()
+	if calls := atomic.LoadInt64(&client.listCalls); calls != 1 {
+		t.Errorf("ListBuckets called %d times for 20 concurrent callers, want 1", calls)
+	}
+
+	buckets, _, err := service.ListBucketsInRegion(context.Background(), "eu-west-1")
+	if err != nil || len(buckets) != 1 || buckets[0].Name != "backups" {
+		t.Errorf("ListBucketsInRegion(eu-west-1) = %v, %v, want backups", buckets, err)
+	}
+	if calls := atomic.LoadInt64(&client.listCalls); calls != 1 {
+		t.Errorf("ListBuckets called %d times within the TTL, want 1", calls)
+	}
+
+	stats := service.GetCacheStats()
+	if stats["bucket_list_misses"] != int64(1) || stats["bucket_list_hits"] != int64(20) || stats["bucket_list_cached"] != true {
+		t.Errorf("GetCacheStats() = %v, want 1 miss, 20 hits and a cached list", stats)
+	}
+
+	// The next window lists buckets again, once
+	now = now.Add(time.Minute)
+	listConcurrently()
+	if calls := atomic.LoadInt64(&client.listCalls); calls != 2 {
+		t.Errorf("ListBuckets called %d times after the TTL expired, want 2", calls)
+	}
+
+	// Invalidate forces a fresh list inside the window
+	service.Invalidate()
+	if _, _, err := service.ListBuckets(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if calls := atomic.LoadInt64(&client.listCalls); calls != 3 {
+		t.Errorf("ListBuckets called %d times after Invalidate, want 3", calls)
+	}
+}
+
+// regionLookupClient denies GetBucketLocation on every bucket but "open".
+// HeadBucket answers "redirected" with a 301 carrying the region header,
+// "denied" with a 403 carrying it too, and "unknown" with a bare 403.
+type regionLookupClient struct {
+	headCalls int64
+}
+
+func (c *regionLookupClient) ListBuckets(ctx context.Context) (*s3.ListBucketsOutput, error) {
+	var buckets []s3types.Bucket
+	for _, name := range []string{"open", "redirected", "denied", "unknown"} {
+		buckets = append(buckets, s3types.Bucket{Name: aws.String(name)})
+	}
+	return &s3.ListBucketsOutput{Buckets: buckets}, nil
+}
+
+func (c *regionLookupClient) GetBucketLocation(ctx context.Context, bucket string) (*s3.GetBucketLocationOutput, error) {
+	if bucket == "open" {
+		return &s3.GetBucketLocationOutput{LocationConstraint: s3types.BucketLocationConstraintEuWest1}, nil
+	}
+	return nil, errors.New("AccessDenied")
+}
+
+func (c *regionLookupClient) HeadBucket(ctx context.Context, bucket string) (*s3.HeadBucketOutput, error) {
+	atomic.AddInt64(&c.headCalls, 1)
+	response := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}
+	switch bucket {
+	case "redirected":
+		response.StatusCode = http.StatusMovedPermanently
+		response.Header.Set("X-Amz-Bucket-Region", "ap-south-1")
+	case "denied":
+		response.Header.Set("X-Amz-Bucket-Region", "us-west-2")
+	}
+	return nil, &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: response},
+		Err:      errors.New("Forbidden"),
+	}}
+}
+
+func TestListBucketsFallsBackToHeadBucketAndReportsSkippedBuckets(t *testing.T) {
+	client := &regionLookupClient{}
+	service := NewBucketServiceWithTTL(client, time.Minute, nil)
+
+	buckets, skipped, err := service.ListBuckets(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ListBuckets() error = %v", err)
+	}
+	regions := map[string]string{}
+	for _, bucket := range buckets {
+		regions[bucket.Name] = bucket.Region
+	}
+	want := map[string]string{"open": "eu-west-1", "redirected": "ap-south-1", "denied": "us-west-2"}
+	if len(regions) != len(want) {
+		t.Errorf("ListBuckets() = %v, want %v", regions, want)
+	}
+	for name, region := range want {
+		if regions[name] != region {
+			t.Errorf("region of %s = %q, want %s", name, regions[name], region)
+		}
+	}
+	if len(skipped) != 1 || skipped[0].Bucket != "unknown" || !strings.Contains(skipped[0].Error, "AccessDenied") {
+		t.Errorf("skipped = %+v, want unknown with the GetBucketLocation error", skipped)
+	}
+	if calls := atomic.LoadInt64(&client.headCalls); calls != 3 {
+		t.Errorf("HeadBucket called %d times, want once per denied bucket", calls)
+	}
+
+	// Regions found with HeadBucket are cached like any other
+	if _, _, err := service.ListBuckets(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if calls := atomic.LoadInt64(&client.headCalls); calls != 4 {
+		t.Errorf("HeadBucket called %d times after a second listing, want only the unknown bucket retried", calls)
+	}
+}
+
+// regionedBucketClient lists buckets that carry their region, as S3 does
+// across the pages of a ListBuckets listing, and counts region lookups
+type regionedBucketClient struct {
+	buckets       []s3types.Bucket
+	locationCalls int64
+}
+
+func (c *regionedBucketClient) ListBuckets(ctx context.Context) (*s3.ListBucketsOutput, error) {
+	return &s3.ListBucketsOutput{Buckets: c.buckets}, nil
+}
+
+func (c *regionedBucketClient) GetBucketLocation(ctx context.Context, bucket string) (*s3.GetBucketLocationOutput, error) {
+	atomic.AddInt64(&c.locationCalls, 1)
+	return &s3.GetBucketLocationOutput{}, nil
+}
+
+func (c *regionedBucketClient) HeadBucket(ctx context.Context, bucket string) (*s3.HeadBucketOutput, error) {
+	return nil, errors.New("HeadBucket not expected")
+}
+
+func TestListedBucketRegionsSkipLocationLookups(t *testing.T) {
+	// More buckets than the old single-response limit, most naming their region
+	client := &regionedBucketClient{}
+	for i := 0; i < 1500; i++ {
+		bucket := s3types.Bucket{Name: aws.String(fmt.Sprintf("bucket-%04d", i))}
+		if i != 0 {
+			bucket.BucketRegion = aws.String("eu-west-1")
+		}
+		client.buckets = append(client.buckets, bucket)
+	}
+	regional := &mockPartsClient{uploads: []s3types.MultipartUpload{{
+		Key:          aws.String("data.bin"),
+		UploadId:     aws.String("upload-1"),
+		Initiated:    aws.Time(time.Now().Add(-time.Hour)),
+		StorageClass: s3types.StorageClassStandard,
+	}}}
+	uploadService := &UploadService{
+		client:          failingClient{},
+		bucketService:   NewBucketServiceWithTTL(client, time.Minute, nil),
+		concurrency:     4,
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": regional, "eu-west-1": regional},
+	}
+
+	uploads, err := uploadService.ListUploads(context.Background(), types.ListOptions{})
+	if err != nil {
+		t.Fatalf("ListUploads() error = %v", err)
+	}
+	if len(uploads) != len(client.buckets) {
+		t.Errorf("ListUploads() found uploads in %d buckets, want all %d", len(uploads), len(client.buckets))
+	}
+	if calls := atomic.LoadInt64(&client.locationCalls); calls != 1 {
+		t.Errorf("GetBucketLocation called %d times, want only for the bucket without a listed region", calls)
+	}
+}
+
+// taggedBucketClient lists buckets in us-east-1 and serves their tags,
+// failing for buckets without an entry, and counts tag lookups
+type taggedBucketClient struct {
+	tags     map[string]map[string]string
+	names    []string
+	tagCalls int64
+}
+
+func (c *taggedBucketClient) ListBuckets(ctx context.Context) (*s3.ListBucketsOutput, error) {
+	output := &s3.ListBucketsOutput{}
+	for _, name := range c.names {
+		output.Buckets = append(output.Buckets, s3types.Bucket{Name: aws.String(name), BucketRegion: aws.String("us-east-1")})
+	}
+	return output, nil
+}
+
+func (c *taggedBucketClient) GetBucketLocation(ctx context.Context, bucket string) (*s3.GetBucketLocationOutput, error) {
+	return &s3.GetBucketLocationOutput{}, nil
+}
+
+func (c *taggedBucketClient) HeadBucket(ctx context.Context, bucket string) (*s3.HeadBucketOutput, error) {
+	return nil, errors.New("HeadBucket not expected")
+}
+
+func (c *taggedBucketClient) GetBucketTagging(ctx context.Context, bucket string) (*s3.GetBucketTaggingOutput, error) {
+	atomic.AddInt64(&c.tagCalls, 1)
+	tags, ok := c.tags[bucket]
+	if !ok {
+		return nil, errors.New("api error AccessDenied: Access Denied")
+	}
+	output := &s3.GetBucketTaggingOutput{}
+	for key, value := range tags {
+		output.TagSet = append(output.TagSet, s3types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return output, nil
+}
+
+func TestListUploadsSelectsBucketsByTagBeforeListing(t *testing.T) {
+	client := &taggedBucketClient{
+		names: []string{"prod-logs", "prod-vault", "staging", "untagged", "locked"},
+		tags: map[string]map[string]string{
+			"prod-logs":  {"env": "prod", "team": "data"},
+			"prod-vault": {"env": "prod", "protected": "true"},
+			"staging":    {"env": "staging"},
+			"untagged":   {},
+		},
+	}
+	regional := &mockPartsClient{uploads: []s3types.MultipartUpload{{
+		Key:          aws.String("data.bin"),
+		UploadId:     aws.String("upload-1"),
+		Initiated:    aws.Time(time.Now().Add(-time.Hour)),
+		StorageClass: s3types.StorageClassStandard,
+	}}}
+	uploadService := &UploadService{
+		client:          failingClient{},
+		bucketService:   NewBucketServiceWithTTL(client, time.Minute, nil),
+		concurrency:     4,
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": regional},
+	}
+
+	selection := &types.BucketTagFilter{
+		Include: []types.BucketTag{{Key: "env", Value: "prod"}},
+		Exclude: []types.BucketTag{{Key: "protected", Value: "true"}},
+	}
+	uploads, err := uploadService.ListUploads(context.Background(), types.ListOptions{BucketTags: selection})
+	if err != nil {
+		t.Fatalf("ListUploads() error = %v", err)
+	}
+	if len(uploads) != 1 || uploads[0].Bucket != "prod-logs" {
+		t.Fatalf("ListUploads() = %+v, want the upload in prod-logs only", uploads)
+	}
+	// Only the tags the selection looked at are carried, for auditing
+	if want := map[string]string{"env": "prod"}; !reflect.DeepEqual(uploads[0].BucketTags, want) {
+		t.Errorf("BucketTags = %v, want %v", uploads[0].BucketTags, want)
+	}
+	skipped := uploadService.SkippedBuckets()
+	if len(skipped) != 1 || skipped[0].Bucket != "locked" || skipped[0].Lookup != types.SkippedTagLookup || !strings.Contains(skipped[0].Error, "AccessDenied") {
+		t.Errorf("SkippedBuckets() = %+v, want locked with the tag lookup error", skipped)
+	}
+
+	// Tags are cached with regions, so a second listing reads them again only
+	// for the bucket whose lookup failed
+	if _, err := uploadService.ListUploads(context.Background(), types.ListOptions{BucketTags: selection}); err != nil {
+		t.Fatal(err)
+	}
+	if calls := atomic.LoadInt64(&client.tagCalls); calls != 6 {
+		t.Errorf("GetBucketTagging called %d times, want 5 and then 1 for the locked bucket", calls)
+	}
+	if stats := uploadService.bucketService.GetCacheStats(); stats["tag_hits"] != int64(4) || stats["tag_misses"] != int64(6) {
+		t.Errorf("GetCacheStats() = %v, want 4 tag hits and 6 misses", stats)
+	}
+
+	// A named bucket outside the selection is refused rather than listed
+	_, err = uploadService.ListUploads(context.Background(), types.ListOptions{BucketName: "prod-vault", BucketTags: selection})
+	if err == nil || !strings.Contains(err.Error(), "env=prod, not protected=true") {
+		t.Errorf("ListUploads() of an excluded bucket error = %v, want the selection named", err)
+	}
+}
+
+// directoryBucketClient implements S3ClientInterface with one general purpose
+// bucket, and lists one directory bucket in every region
+type directoryBucketClient struct {
+	directoryCalls int64
+}
+
+func (c *directoryBucketClient) ListBuckets(ctx context.Context) (*s3.ListBucketsOutput, error) {
+	return &s3.ListBucketsOutput{Buckets: []s3types.Bucket{{Name: aws.String("logs"), BucketRegion: aws.String("us-east-1")}}}, nil
+}
+
+func (c *directoryBucketClient) ListDirectoryBuckets(ctx context.Context) (*s3.ListDirectoryBucketsOutput, error) {
+	atomic.AddInt64(&c.directoryCalls, 1)
+	return &s3.ListDirectoryBucketsOutput{Buckets: []s3types.Bucket{{Name: aws.String("fast--use1-az4--x-s3"), BucketRegion: aws.String("us-east-1")}}}, nil
+}
+
+func (c *directoryBucketClient) GetBucketLocation(ctx context.Context, bucket string) (*s3.GetBucketLocationOutput, error) {
+	return nil, errors.New("GetBucketLocation not expected")
+}
+
+func (c *directoryBucketClient) HeadBucket(ctx context.Context, bucket string) (*s3.HeadBucketOutput, error) {
+	return nil, errors.New("HeadBucket not expected")
+}
+
+// directoryUploadClient serves uploads in directory buckets only, and records
+// the prefixes listed with and the uploads aborted
+type directoryUploadClient struct {
+	mockPartsClient
+	prefixes []string
+	aborted  []string
+}
+
+func (c *directoryUploadClient) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (*s3.ListMultipartUploadsOutput, error) {
+	if !types.IsDirectoryBucketName(aws.ToString(input.Bucket)) {
+		return &s3.ListMultipartUploadsOutput{}, nil
+	}
+	c.prefixes = append(c.prefixes, aws.ToString(input.Prefix))
+	return c.mockPartsClient.ListMultipartUploads(ctx, input)
+}
+
+func (c *directoryUploadClient) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	c.aborted = append(c.aborted, aws.ToString(input.Bucket)+"/"+aws.ToString(input.Key))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func TestDirectoryBucketsAreListedSizedAndDeleted(t *testing.T) {
+	const mb = 1024 * 1024
+	client := &directoryBucketClient{}
+	regional := &directoryUploadClient{mockPartsClient: mockPartsClient{
+		uploads: []s3types.MultipartUpload{
+			{Key: aws.String("batch/job-1/out.parquet"), UploadId: aws.String("upload-1"), Initiated: aws.Time(time.Now().Add(-48 * time.Hour))},
+			{Key: aws.String("batch/other.parquet"), UploadId: aws.String("upload-2"), Initiated: aws.Time(time.Now().Add(-48 * time.Hour))},
+		},
+		parts: []int64{512 * mb, 512 * mb},
+	}}
+	uploadService := &UploadService{
+		client:          failingClient{},
+		bucketService:   NewBucketServiceWithTTL(client, time.Minute, nil),
+		concurrency:     2,
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": regional},
+	}
+	ctx := context.Background()
+
+	// Directory buckets are only looked for when asked to
+	if _, err := uploadService.ListUploads(ctx, types.ListOptions{}); err != nil {
+		t.Fatalf("ListUploads() error = %v", err)
+	}
+	if calls := atomic.LoadInt64(&client.directoryCalls); calls != 0 {
+		t.Errorf("ListDirectoryBuckets called %d times without IncludeDirectoryBuckets, want 0", calls)
+	}
+
+	opts := types.ListOptions{Regions: []string{"us-east-1"}, KeyPrefix: "batch/job", IncludeDirectoryBuckets: true}
+	uploads, err := uploadService.ListUploads(ctx, opts)
+	if err != nil {
+		t.Fatalf("ListUploads() error = %v", err)
+	}
+	if len(uploads) != 1 || uploads[0].Bucket != "fast--use1-az4--x-s3" || uploads[0].Key != "batch/job-1/out.parquet" {
+		t.Fatalf("ListUploads() = %+v, want the upload under batch/job in the directory bucket", uploads)
+	}
+	if uploads[0].BucketType != types.BucketTypeDirectory || uploads[0].StorageClass != "EXPRESS_ONEZONE" || uploads[0].Region != "us-east-1" {
+		t.Errorf("upload = %+v, want a directory bucket upload in EXPRESS_ONEZONE", uploads[0])
+	}
+	// Directory buckets only take prefixes ending in a slash
+	if want := []string{"batch/"}; !reflect.DeepEqual(regional.prefixes, want) {
+		t.Errorf("prefixes listed with = %q, want %q", regional.prefixes, want)
+	}
+
+	uploads, _, err = NewSizeServiceWithCache(uploadService, 2, nil, nil).HydrateUploadSizes(ctx, uploads)
+	if err != nil {
+		t.Fatalf("HydrateUploadSizes() error = %v", err)
+	}
+	if uploads[0].Size != 1024*mb {
+		t.Errorf("Size = %d, want %d", uploads[0].Size, 1024*mb)
+	}
+
+	breakdown, err := NewCostService().CalculateStorageCost(ctx, uploads)
+	if err != nil {
+		t.Fatalf("CalculateStorageCost() error = %v", err)
+	}
+	if breakdown.TotalMonthlyCost != 0.11 || len(breakdown.PricingWarnings) != 0 {
+		t.Errorf("cost = %v with warnings %v, want the EXPRESS_ONEZONE price of 1GB", breakdown.TotalMonthlyCost, breakdown.PricingWarnings)
+	}
+
+	if err := uploadService.DeleteUpload(ctx, uploads[0]); err != nil {
+		t.Fatalf("DeleteUpload() error = %v", err)
+	}
+	if want := []string{"fast--use1-az4--x-s3/batch/job-1/out.parquet"}; !reflect.DeepEqual(regional.aborted, want) {
+		t.Errorf("aborted = %v, want %v", regional.aborted, want)
+	}
+}
+
+// regionalDirectoryBucketService lists one directory bucket in each region,
+// and fails to list those of the regions in fail
+type regionalDirectoryBucketService struct {
+	staticBucketService
+	fail map[string]bool
+}
+
+func (b *regionalDirectoryBucketService) ListDirectoryBuckets(ctx context.Context, region string) ([]types.Bucket, error) {
+	if b.fail[region] {
+		return nil, errors.New("AccessDenied: not authorized to perform s3express:ListAllMyDirectoryBuckets")
+	}
+	return []types.Bucket{{Name: "fast--use1-az4--x-s3", Region: region, BucketType: types.BucketTypeDirectory}}, nil
+}
+
+func TestDirectoryBucketRegionFailureIsSkipped(t *testing.T) {
+	regional := &directoryUploadClient{mockPartsClient: mockPartsClient{
+		uploads: []s3types.MultipartUpload{
+			{Key: aws.String("batch/out.parquet"), UploadId: aws.String("upload-1"), Initiated: aws.Time(time.Now().Add(-48 * time.Hour))},
+		},
+	}}
+	uploadService := &UploadService{
+		client: failingClient{},
+		bucketService: &regionalDirectoryBucketService{
+			staticBucketService: staticBucketService{bucket: types.Bucket{Name: "logs", Region: "us-east-1"}},
+			fail:                map[string]bool{"us-west-2": true},
+		},
+		concurrency:     2,
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": regional},
+	}
+
+	// The region whose directory buckets cannot be listed is skipped, and the rest scanned
+	uploads, err := uploadService.ListUploads(context.Background(), types.ListOptions{Regions: []string{"us-west-2", "us-east-1"}, IncludeDirectoryBuckets: true})
+	if err != nil {
+		t.Fatalf("ListUploads() error = %v", err)
+	}
+	if len(uploads) != 1 || uploads[0].Bucket != "fast--use1-az4--x-s3" {
+		t.Errorf("ListUploads() = %+v, want the upload in the directory bucket of us-east-1", uploads)
+	}
+	skipped := uploadService.SkippedBuckets()
+	if len(skipped) != 1 || skipped[0].Region != "us-west-2" || skipped[0].Lookup != types.SkippedDirectoryBucketLookup || !strings.Contains(skipped[0].Error, "AccessDenied") {
+		t.Fatalf("SkippedBuckets() = %+v, want the directory buckets of us-west-2", skipped)
+	}
+	if got := FormatSkippedBuckets(skipped); !strings.Contains(got, "directory buckets in us-west-2: AccessDenied") {
+		t.Errorf("FormatSkippedBuckets() = %q, want the region named", got)
+	}
+}
+
+// slowLocationClient lists many buckets and records the most region lookups
+// in flight at once. Each lookup takes delay, or 20ms when it is zero; the
+// buckets in fail cannot be located.
+type slowLocationClient struct {
+	looking, maxLooking atomic.Int32
+	delay               time.Duration
+	fail                map[string]bool
+}
+
+func (c *slowLocationClient) ListBuckets(ctx context.Context) (*s3.ListBucketsOutput, error) {
+	var buckets []s3types.Bucket
+	for i := 0; i < 16; i++ {
+		buckets = append(buckets, s3types.Bucket{Name: aws.String(fmt.Sprintf("bucket-%02d", i))})
+	}
+	return &s3.ListBucketsOutput{Buckets: buckets}, nil
+}
+
+func (c *slowLocationClient) GetBucketLocation(ctx context.Context, bucket string) (*s3.GetBucketLocationOutput, error) {
+	if c.delay > 0 {
+		time.Sleep(c.delay)
+	} else {
+		track(&c.looking, &c.maxLooking)
+	}
+	if c.fail[bucket] {
+		return nil, errors.New("AccessDenied")
+	}
+	return &s3.GetBucketLocationOutput{}, nil
+}
+
+func (c *slowLocationClient) HeadBucket(ctx context.Context, bucket string) (*s3.HeadBucketOutput, error) {
+	if c.fail[bucket] {
+		return nil, errors.New("AccessDenied")
+	}
+	return nil, errors.New("HeadBucket not expected")
+}
+
+func TestListBucketsLooksUpRegionsAtTheBucketConcurrency(t *testing.T) {
+	for _, concurrency := range []int{2, 8} {
+		client := &slowLocationClient{}
+		service := NewBucketServiceWithTTL(client, time.Minute, nil)
+		service.SetConcurrency(concurrency)
+
+		buckets, _, err := service.ListBuckets(context.Background(), "")
+		if err != nil {
+			t.Fatalf("ListBuckets() error = %v", err)
+		}
+		if got := client.maxLooking.Load(); got != int32(concurrency) {
+			t.Errorf("%d region lookups at once, want the concurrency of %d", got, concurrency)
+		}
+		if len(buckets) != 16 || buckets[0].Name != "bucket-00" || buckets[15].Name != "bucket-15" {
+			t.Errorf("ListBuckets() = %+v, want all 16 buckets in listed order", buckets)
+		}
+	}
+}
+
+func TestListBucketsLooksUpRegionsInParallel(t *testing.T) {
+	// 16 lookups of 50ms take 800ms one at a time and 100ms eight at a time
+	client := &slowLocationClient{delay: 50 * time.Millisecond, fail: map[string]bool{"bucket-03": true}}
+	service := NewBucketServiceWithTTL(client, time.Minute, nil)
+	service.SetConcurrency(8)
+
+	start := time.Now()
+	buckets, skipped, err := service.ListBuckets(context.Background(), "")
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("ListBuckets() error = %v", err)
+	}
+	if elapsed > 400*time.Millisecond {
+		t.Errorf("ListBuckets() took %s, want the lookups run eight at a time", elapsed)
+	}
+	// A bucket that cannot be located is reported, not dropped
+	if len(buckets) != 15 || len(skipped) != 1 || skipped[0].Bucket != "bucket-03" || !strings.Contains(skipped[0].Error, "AccessDenied") {
+		t.Errorf("ListBuckets() = %d buckets, skipped %+v, want bucket-03 skipped with its error", len(buckets), skipped)
+	}
+}
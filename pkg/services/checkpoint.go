@@ -0,0 +1,286 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+const (
+	// CheckpointInterval is how often a delete run saves its checkpoint
+	CheckpointInterval = 30 * time.Second
+	// CheckpointEvery is how many deletions a delete run saves its checkpoint after
+	CheckpointEvery = 1000
+)
+
+// CheckpointWriter keeps the checkpoint of a delete run, saving the uploads
+// not deleted yet every CheckpointInterval or CheckpointEvery deletions, so
+// that a run that crashes or is interrupted can be resumed without listing
+// again. The checkpoint of a run that completes is removed.
+type CheckpointWriter struct {
+	filename   string
+	checkpoint types.DeletionCheckpoint
+	interval   time.Duration
+	every      int
+	now        func() time.Time
+
+	mutex     sync.Mutex
+	uploads   []types.CheckpointUpload // not changed once started
+	index     map[string]int
+	done      []bool
+	remaining int
+	pending   int // deletions recorded since the last save
+	savedAt   time.Time
+	version   int // of done, counting its changes
+
+	// Saves happen outside mutex, so deletions are not held up while a
+	// checkpoint is written; saveMutex orders them
+	saveMutex    sync.Mutex
+	savedVersion int
+	err          error // the first save that failed
+}
+
+// checkpointState is what a save writes: which uploads were done, as of version
+type checkpointState struct {
+	done      []bool
+	remaining int
+	version   int
+}
+
+// NewCheckpointWriter returns a writer for filename. The checkpoint holds the
+// run metadata; the writer adds opts and a hash of them and of the account
+// settings, which a resumed run must match.
+func NewCheckpointWriter(filename string, checkpoint types.DeletionCheckpoint, opts types.DeleteOptions) *CheckpointWriter {
+	checkpoint.SchemaVersion = types.SchemaVersion
+	checkpoint.Kind = types.DeletionCheckpointKind
+	checkpoint.Options = deleteCommandString(opts)
+	checkpoint.OptionsHash = checkpointOptionsHash(checkpoint, opts)
+	if checkpoint.StartedAt.IsZero() {
+		checkpoint.StartedAt = time.Now().UTC()
+	}
+	return &CheckpointWriter{
+		filename:   filename,
+		checkpoint: checkpoint,
+		interval:   CheckpointInterval,
+		every:      CheckpointEvery,
+		now:        time.Now,
+	}
+}
+
+// checkpointOptionsHash hashes what selects the uploads of a run: the
+// options, the account settings, the regions and the file the uploads were
+// read from. --force and --quiet do not change the selection, so a run can be
+// resumed without them.
+func checkpointOptionsHash(checkpoint types.DeletionCheckpoint, opts types.DeleteOptions) string {
+	opts.Force = false
+	opts.Quiet = false
+	regions := append([]string(nil), checkpoint.Regions...)
+	sort.Strings(regions)
+	selection := strings.Join([]string{
+		deleteCommandString(opts),
+		checkpoint.Profile, checkpoint.Region, checkpoint.RoleARN, checkpoint.ExternalID,
+		strings.Join(regions, ","),
+		"from-file=" + checkpoint.SourceFile, "retry-from=" + checkpoint.RetryOf, checkpoint.SourceHash,
+	}, "\n")
+	sum := sha256.Sum256([]byte(selection))
+	return hex.EncodeToString(sum[:])
+}
+
+// Filename returns the path of the checkpoint
+func (w *CheckpointWriter) Filename() string {
+	return w.filename
+}
+
+// Resumes checks that checkpoint was written by a run with the same options
+// as this one, so that its uploads were selected the way this run would
+func (w *CheckpointWriter) Resumes(checkpoint types.DeletionCheckpoint) error {
+	if checkpoint.OptionsHash != w.checkpoint.OptionsHash {
+		return fmt.Errorf("%s was written by a run with other options, account settings, regions or source file (%s); rerun with the same ones, or remove it to start over", w.filename, checkpoint.Options)
+	}
+	return nil
+}
+
+// Start saves the uploads the run is about to delete as the remaining ones
+func (w *CheckpointWriter) Start(uploads []types.MultipartUpload) error {
+	w.mutex.Lock()
+	w.uploads = make([]types.CheckpointUpload, len(uploads))
+	w.index = make(map[string]int, len(uploads))
+	for i, upload := range uploads {
+		w.uploads[i] = types.NewCheckpointUpload(upload)
+		w.index[checkpointKey(upload)] = i
+	}
+	w.done = make([]bool, len(uploads))
+	w.remaining = len(uploads)
+	state := w.state()
+	w.mutex.Unlock()
+
+	return w.save(state)
+}
+
+// Record marks upload as done, unless the run was interrupted before its
+// deletion could complete, and saves the checkpoint when it is due. A failed
+// deletion is done too: it is left to --retry-from, not to the checkpoint.
+// It is safe for concurrent use.
+func (w *CheckpointWriter) Record(upload types.MultipartUpload, err error) {
+	if err != nil && interrupted(err) {
+		return
+	}
+
+	w.mutex.Lock()
+	i, ok := w.index[checkpointKey(upload)]
+	if !ok || w.done[i] {
+		w.mutex.Unlock()
+		return
+	}
+	w.done[i] = true
+	w.remaining--
+	w.pending++
+	w.version++
+	if w.pending < w.every && w.now().Sub(w.savedAt) < w.interval {
+		w.mutex.Unlock()
+		return
+	}
+	state := w.state()
+	w.mutex.Unlock()
+
+	w.save(state) // kept in w.err, and returned by Close
+}
+
+// Close ends the run. The checkpoint is removed when every upload was done,
+// and saved otherwise. It returns the first save that failed.
+func (w *CheckpointWriter) Close() error {
+	w.mutex.Lock()
+	started := w.done != nil
+	state := w.state()
+	w.mutex.Unlock()
+
+	// A run that never started deleting leaves any checkpoint it resumed as it was
+	if started && state.remaining == 0 {
+		if err := w.Remove(); err != nil {
+			return err
+		}
+	} else if started {
+		w.save(state)
+	}
+
+	w.saveMutex.Lock()
+	defer w.saveMutex.Unlock()
+	return w.err
+}
+
+// Remove removes the checkpoint, for a resumed run that found nothing left
+// to delete
+func (w *CheckpointWriter) Remove() error {
+	if err := os.Remove(w.filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove checkpoint %s: %w", w.filename, err)
+	}
+	return nil
+}
+
+// Remaining returns how many uploads are not done yet
+func (w *CheckpointWriter) Remaining() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.remaining
+}
+
+// state copies which uploads are done for a save, and starts counting
+// towards the next one. The caller holds the mutex.
+func (w *CheckpointWriter) state() checkpointState {
+	w.pending = 0
+	w.savedAt = w.now()
+	return checkpointState{done: append([]bool(nil), w.done...), remaining: w.remaining, version: w.version}
+}
+
+// save writes the uploads not done in state to a temporary file and renames
+// it over the checkpoint, so a crash mid-write leaves the previous one whole.
+// A state older than the last one saved is not written.
+func (w *CheckpointWriter) save(state checkpointState) error {
+	w.saveMutex.Lock()
+	defer w.saveMutex.Unlock()
+	if state.version < w.savedVersion {
+		return nil
+	}
+
+	checkpoint := w.checkpoint
+	checkpoint.Total = len(w.uploads)
+	checkpoint.UpdatedAt = w.now().UTC()
+	checkpoint.Remaining = make([]types.CheckpointUpload, 0, state.remaining)
+	for i, upload := range w.uploads {
+		if !state.done[i] {
+			checkpoint.Remaining = append(checkpoint.Remaining, upload)
+		}
+	}
+
+	if err := writeCheckpoint(w.filename, checkpoint); err != nil {
+		err = fmt.Errorf("failed to save checkpoint %s: %w", w.filename, err)
+		if w.err == nil {
+			w.err = err
+		}
+		return err
+	}
+	w.savedVersion = state.version
+	return nil
+}
+
+// writeCheckpoint writes checkpoint to a temporary file next to filename,
+// syncs it and renames it over filename
+func writeCheckpoint(filename string, checkpoint types.DeletionCheckpoint) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if err := json.NewEncoder(tmp).Encode(checkpoint); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filename)
+}
+
+// LoadCheckpoint reads a checkpoint written by delete --checkpoint
+func LoadCheckpoint(filename string) (types.DeletionCheckpoint, error) {
+	var checkpoint types.DeletionCheckpoint
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return checkpoint, err
+	}
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return checkpoint, fmt.Errorf("%s is not a checkpoint: %w", filename, err)
+	}
+	if checkpoint.Kind != types.DeletionCheckpointKind {
+		return checkpoint, fmt.Errorf("%s is not a checkpoint written by delete --checkpoint", filename)
+	}
+	if err := checkSchemaVersion(filename, checkpoint.SchemaVersion); err != nil {
+		return checkpoint, err
+	}
+	return checkpoint, nil
+}
+
+// checkpointKey identifies an upload in a checkpoint
+func checkpointKey(upload types.MultipartUpload) string {
+	return upload.Bucket + "\x00" + upload.Key + "\x00" + upload.UploadID
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// interruptingClient aborts uploads until after of them are aborted, then
+// interrupts the run, failing the rest as a cancelled call would. Uploads in
+// gone answer NoSuchUpload.
+type interruptingClient struct {
+	mockAbortClient
+	after  int
+	cancel context.CancelFunc
+	gone   map[string]bool
+
+	mutex   sync.Mutex
+	aborted []string
+}
+
+func (c *interruptingClient) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.gone[*input.UploadId] {
+		return nil, &smithy.GenericAPIError{Code: "NoSuchUpload", Message: "The specified upload does not exist"}
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.aborted = append(c.aborted, *input.UploadId)
+	if len(c.aborted) == c.after {
+		c.cancel()
+	}
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func checkpointUploads(n int) []types.MultipartUpload {
+	uploads := make([]types.MultipartUpload, n)
+	for i := range uploads {
+		uploads[i] = types.MultipartUpload{Bucket: "bucket", Key: fmt.Sprintf("key-%02d", i), UploadID: fmt.Sprintf("upload-%02d", i), Initiated: time.Now().Add(-time.Hour), StorageClass: "STANDARD", Region: "us-east-1", Size: 100}
+	}
+	return uploads
+}
+
+func TestCheckpointResumesAnInterruptedDeletion(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state", "checkpoint.json")
+	uploads := checkpointUploads(10)
+	opts := types.DeleteOptions{Force: true, Quiet: true, BucketName: "bucket"}
+
+	run := func(ctx context.Context, client S3UploadClientInterface, uploads []types.MultipartUpload, checkpoint *CheckpointWriter, cleaned *[]string) error {
+		service := &UploadService{
+			client:           client,
+			concurrency:      1,
+			regionalClients:  map[string]S3UploadClientInterface{"us-east-1": client},
+			progressReporter: NewConsoleProgressReporter(io.Discard, true),
+		}
+		runOpts := opts
+		runOpts.OnStart = func(uploads []types.MultipartUpload) { checkpoint.Start(uploads) }
+		runOpts.OnOutcome = checkpoint.Record
+		if cleaned != nil {
+			runOpts.OnAlreadyCleaned = func(upload types.MultipartUpload) {
+				*cleaned = append(*cleaned, upload.UploadID)
+				checkpoint.Record(upload, nil)
+			}
+		}
+		return service.DeleteUploads(ctx, uploads, runOpts)
+	}
+
+	// The first run is interrupted after 7 deletions, having saved every 3
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &interruptingClient{after: 7, cancel: cancel}
+	checkpoint := NewCheckpointWriter(filename, types.DeletionCheckpoint{Profile: "prod"}, opts)
+	checkpoint.every = 3
+	checkpoint.interval = time.Hour
+	if err := run(ctx, client, uploads, checkpoint, nil); err == nil {
+		t.Fatal("interrupted run error = nil, want the cancelled deletions")
+	}
+
+	// A crash now would leave the last periodic save, taken after 6 deletions
+	saved, err := LoadCheckpoint(filename)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() after the periodic save error = %v", err)
+	}
+	if saved.Total != 10 || len(saved.Remaining) != 4 {
+		t.Errorf("periodic save has total %d and %d remaining, want 10 and 4", saved.Total, len(saved.Remaining))
+	}
+
+	if err := checkpoint.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	saved, err = LoadCheckpoint(filename)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error = %v", err)
+	}
+	if len(saved.Remaining) != 3 || saved.Remaining[0].UploadID != "upload-07" {
+		t.Fatalf("checkpoint remaining = %v, want the 3 uploads from upload-07 that were not deleted", saved.Remaining)
+	}
+	if saved.Kind != types.DeletionCheckpointKind || saved.Options != "delete -b bucket --force --quiet" || saved.UpdatedAt.IsZero() {
+		t.Errorf("checkpoint metadata = %+v", saved)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(filename))
+	if len(entries) != 1 {
+		t.Errorf("checkpoint directory has %d files, want only the checkpoint and no temporary files", len(entries))
+	}
+
+	// The resumed run deletes only what is left, counting what S3 no longer
+	// has as already cleaned, and removes the checkpoint once it is done;
+	// --force and --quiet may change between runs
+	resumeOpts := opts
+	resumeOpts.Force, resumeOpts.Quiet = true, false
+	resumed := NewCheckpointWriter(filename, types.DeletionCheckpoint{Profile: "prod"}, resumeOpts)
+	if err := resumed.Resumes(saved); err != nil {
+		t.Fatalf("Resumes() error = %v", err)
+	}
+	remaining := make([]types.MultipartUpload, len(saved.Remaining))
+	for i, upload := range saved.Remaining {
+		remaining[i] = upload.Upload()
+	}
+	second := &interruptingClient{cancel: func() {}, gone: map[string]bool{"upload-08": true}}
+	var cleaned []string
+	if err := run(context.Background(), second, remaining, resumed, &cleaned); err != nil {
+		t.Fatalf("resumed run error = %v", err)
+	}
+	if got := strings.Join(second.aborted, ","); got != "upload-07,upload-09" {
+		t.Errorf("resumed run aborted %s, want upload-07,upload-09", got)
+	}
+	if len(cleaned) != 1 || cleaned[0] != "upload-08" {
+		t.Errorf("already cleaned = %v, want upload-08", cleaned)
+	}
+	if err := resumed.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("checkpoint of a completed run still exists (stat error = %v)", err)
+	}
+}
+
+func TestCheckpointRefusesOtherOptions(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "checkpoint.json")
+	olderThan := 7 * 24 * time.Hour
+	writer := NewCheckpointWriter(filename, types.DeletionCheckpoint{Region: "us-east-1"}, types.DeleteOptions{OlderThan: &olderThan})
+	if err := writer.Start(checkpointUploads(2)); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	saved, err := LoadCheckpoint(filename)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		checkpoint types.DeletionCheckpoint
+		opts       types.DeleteOptions
+	}{
+		{"other filters", types.DeletionCheckpoint{Region: "us-east-1"}, types.DeleteOptions{}},
+		{"other region", types.DeletionCheckpoint{Region: "eu-west-1"}, types.DeleteOptions{OlderThan: &olderThan}},
+		{"other regions", types.DeletionCheckpoint{Region: "us-east-1", Regions: []string{"us-east-1"}}, types.DeleteOptions{OlderThan: &olderThan}},
+		{"other source file", types.DeletionCheckpoint{Region: "us-east-1", SourceFile: "uploads.json", SourceHash: "abc"}, types.DeleteOptions{OlderThan: &olderThan}},
+		{"other external ID", types.DeletionCheckpoint{Region: "us-east-1", ExternalID: "s3mpc"}, types.DeleteOptions{OlderThan: &olderThan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewCheckpointWriter(filename, tt.checkpoint, tt.opts).Resumes(saved)
+			if err == nil || !strings.Contains(err.Error(), "other options") {
+				t.Errorf("Resumes() error = %v, want a refusal", err)
+			}
+		})
+	}
+}
+
+func TestLoadCheckpointRejectsOtherFiles(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(filename, []byte(`{"schema_version": 1, "kind": "delete-report"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCheckpoint(filename); err == nil || !strings.Contains(err.Error(), "not a checkpoint") {
+		t.Errorf("LoadCheckpoint() error = %v, want not a checkpoint", err)
+	}
+}
@@ -3,20 +3,46 @@ package services
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/Garvitkul/s3mpc/pkg/types"
 )
 
+// daysPerMonth is the average month length used to prorate accrued costs
+const daysPerMonth = 365.25 / 12
+
 // CostService implements the CostCalculator interface
 type CostService struct {
 	pricingData map[string]map[string]float64 // region -> storage class -> price per GB per month
+	source      PricingSource                 // optional override source, consulted before pricingData
+	currency    string
+	now         func() time.Time
 }
 
 // NewCostService creates a new CostService with AWS S3 pricing data
 func NewCostService() *CostService {
+	return NewCostServiceWithOptions(nil, "USD")
+}
+
+// NewCostServiceWithSource creates a new CostService that prefers prices from
+// source and falls back to the embedded pricing data when it fails
+func NewCostServiceWithSource(source PricingSource) *CostService {
+	return NewCostServiceWithOptions(source, "USD")
+}
+
+// NewCostServiceWithOptions creates a new CostService with an optional pricing
+// source and the currency its prices are expressed in
+func NewCostServiceWithOptions(source PricingSource, currency string) *CostService {
+	if currency == "" {
+		currency = "USD"
+	}
 	return &CostService{
 		pricingData: getAWSS3PricingData(),
+		source:      source,
+		currency:    currency,
+		now:         time.Now,
 	}
 }
 
@@ -27,27 +53,31 @@ func (c *CostService) CalculateStorageCost(ctx context.Context, uploads []types.
 			TotalMonthlyCost: 0.0,
 			ByRegion:         make(map[string]float64),
 			ByStorageClass:   make(map[string]float64),
-			Currency:         "USD",
+			Currency:         c.currency,
 		}, nil
 	}
 
 	breakdown := types.CostBreakdown{
 		ByRegion:       make(map[string]float64),
 		ByStorageClass: make(map[string]float64),
-		Currency:       "USD",
+		Currency:       c.currency,
 	}
 
 	var totalCost float64
+	warnings := make(map[string]bool)
 
 	for _, upload := range uploads {
 		// Convert size from bytes to GB
 		sizeGB := float64(upload.Size) / (1024 * 1024 * 1024)
 		
-		// Get pricing for this region and storage class
-		price, err := c.GetRegionalPricing(ctx, upload.Region, upload.StorageClass)
+		// Get pricing for this region and storage class, noting when it had
+		// to fall back to the default estimate
+		price, warning, err := c.priceFor(ctx, upload.Region, upload.StorageClass)
 		if err != nil {
-			// If we can't get pricing, use a default estimate
-			price = c.getDefaultPricing(upload.StorageClass)
+			return types.CostBreakdown{}, err
+		}
+		if warning != "" {
+			warnings[warning] = true
 		}
 
 		// Calculate monthly cost for this upload
@@ -57,20 +87,70 @@ func (c *CostService) CalculateStorageCost(ctx context.Context, uploads []types.
 		totalCost += monthlyCost
 		breakdown.ByRegion[upload.Region] += monthlyCost
 		breakdown.ByStorageClass[upload.StorageClass] += monthlyCost
+		if upload.Account != "" {
+			if breakdown.ByAccount == nil {
+				breakdown.ByAccount = make(map[string]float64)
+			}
+			breakdown.ByAccount[upload.Account] += monthlyCost
+		}
 	}
 
 	breakdown.TotalMonthlyCost = totalCost
+	breakdown.AnnualizedCost = totalCost * 12
+	for warning := range warnings {
+		breakdown.PricingWarnings = append(breakdown.PricingWarnings, warning)
+	}
+	sort.Strings(breakdown.PricingWarnings)
 
 	return breakdown, nil
 }
 
-// GetRegionalPricing retrieves pricing for a region and storage class
+// CalculateAccruedCost calculates size × price × elapsed months for each upload,
+// prorating uploads younger than a month by days
+func (c *CostService) CalculateAccruedCost(ctx context.Context, uploads []types.MultipartUpload) (float64, error) {
+	now := c.now()
+	var totalCost float64
+
+	for _, upload := range uploads {
+		elapsed := now.Sub(upload.Initiated)
+		if elapsed <= 0 {
+			continue
+		}
+		elapsedMonths := elapsed.Hours() / 24 / daysPerMonth
+
+		sizeGB := float64(upload.Size) / (1024 * 1024 * 1024)
+
+		price, _, err := c.priceFor(ctx, upload.Region, upload.StorageClass)
+		if err != nil {
+			return 0, err
+		}
+
+		totalCost += sizeGB * price * elapsedMonths
+	}
+
+	return totalCost, nil
+}
+
+// GetRegionalPricing retrieves pricing for a region and storage class. The
+// embedded prices are in USD, so with sources in another currency a price
+// they do not have is an error.
 func (c *CostService) GetRegionalPricing(ctx context.Context, region, storageClass string) (float64, error) {
 	// Normalize region name
 	normalizedRegion := c.normalizeRegion(region)
 	
 	// Normalize storage class name
-	normalizedStorageClass := c.normalizeStorageClass(storageClass)
+	normalizedStorageClass := types.NormalizeStorageClass(storageClass)
+
+	// Prefer the live source; any failure falls through to the embedded table
+	if c.source != nil {
+		price, err := c.source.GetPrice(ctx, normalizedRegion, normalizedStorageClass)
+		if err == nil {
+			return price, nil
+		}
+		if !c.pricedInUSD() {
+			return 0, fmt.Errorf("%w, and the built-in prices are in USD, not %s", err, c.currency)
+		}
+	}
 
 	// Check if we have pricing data for this region
 	regionPricing, exists := c.pricingData[normalizedRegion]
@@ -87,6 +167,33 @@ func (c *CostService) GetRegionalPricing(ctx context.Context, region, storageCla
 	return price, nil
 }
 
+// priceFor returns the price for a region and storage class, falling back to
+// the default estimate when no regional price is known. The returned warning
+// names what was estimated, and is empty when the regional price was used.
+// The default estimate is in USD, so prices in another currency cannot fall
+// back to it.
+func (c *CostService) priceFor(ctx context.Context, region, storageClass string) (float64, string, error) {
+	price, err := c.GetRegionalPricing(ctx, region, storageClass)
+	if err == nil {
+		return price, "", nil
+	}
+	if !c.pricedInUSD() {
+		return 0, "", err
+	}
+
+	price = c.getDefaultPricing(storageClass)
+	if _, known := c.pricingData[c.normalizeRegion(region)]; !known {
+		return price, fmt.Sprintf("region %s", region), nil
+	}
+	return price, fmt.Sprintf("storage class %s in region %s", types.NormalizeStorageClass(storageClass), region), nil
+}
+
+// pricedInUSD reports whether prices are in USD, the currency of the
+// embedded and default prices
+func (c *CostService) pricedInUSD() bool {
+	return strings.EqualFold(c.currency, "USD")
+}
+
 // EstimateSavings calculates potential cost savings from deletion
 func (c *CostService) EstimateSavings(ctx context.Context, uploads []types.MultipartUpload) (float64, error) {
 	breakdown, err := c.CalculateStorageCost(ctx, uploads)
@@ -129,35 +236,6 @@ func (c *CostService) normalizeRegion(region string) string {
 	return region
 }
 
-// normalizeStorageClass normalizes storage class names to match our pricing data keys
-func (c *CostService) normalizeStorageClass(storageClass string) string {
-	// Handle common storage class name variations
-	storageClass = strings.ToUpper(strings.TrimSpace(storageClass))
-	
-	// Map some common variations
-	classMap := map[string]string{
-		"STANDARD":                    "STANDARD",
-		"STANDARD_IA":                 "STANDARD_IA",
-		"STANDARD-IA":                 "STANDARD_IA",
-		"ONEZONE_IA":                  "ONEZONE_IA",
-		"ONEZONE-IA":                  "ONEZONE_IA",
-		"REDUCED_REDUNDANCY":          "REDUCED_REDUNDANCY",
-		"GLACIER":                     "GLACIER",
-		"GLACIER_IR":                  "GLACIER_IR",
-		"GLACIER-IR":                  "GLACIER_IR",
-		"DEEP_ARCHIVE":                "DEEP_ARCHIVE",
-		"DEEP-ARCHIVE":                "DEEP_ARCHIVE",
-		"INTELLIGENT_TIERING":         "INTELLIGENT_TIERING",
-		"INTELLIGENT-TIERING":         "INTELLIGENT_TIERING",
-	}
-
-	if normalized, exists := classMap[storageClass]; exists {
-		return normalized
-	}
-
-	return storageClass
-}
-
 // getDefaultPricing returns default pricing when specific pricing is not available
 func (c *CostService) getDefaultPricing(storageClass string) float64 {
 	// Default pricing based on US East 1 rates (as of 2024)
@@ -170,9 +248,10 @@ func (c *CostService) getDefaultPricing(storageClass string) float64 {
 		"GLACIER_IR":           0.004,  // $0.004 per GB per month
 		"DEEP_ARCHIVE":         0.00099, // $0.00099 per GB per month
 		"INTELLIGENT_TIERING":  0.0125,  // $0.0125 per GB per month (average)
+		"EXPRESS_ONEZONE":      0.11,    // $0.11 per GB per month, directory buckets only
 	}
 
-	normalizedClass := c.normalizeStorageClass(storageClass)
+	normalizedClass := types.NormalizeStorageClass(storageClass)
 	if price, exists := defaultPrices[normalizedClass]; exists {
 		return price
 	}
@@ -182,7 +261,10 @@ func (c *CostService) getDefaultPricing(storageClass string) float64 {
 }
 
 // getAWSS3PricingData returns AWS S3 pricing data for different regions and storage classes
-// Prices are in USD per GB per month (as of 2024)
+// Prices are in USD per GB per month (as of 2024), for every commercial region
+// and the GovCloud and China regions; the newer regions are priced like the
+// nearest comparable region. EXPRESS_ONEZONE is only priced in the regions
+// that offer directory buckets, at the rates of the April 2025 price cut.
 func getAWSS3PricingData() map[string]map[string]float64 {
 	return map[string]map[string]float64{
 		"us-east-1": {
@@ -194,6 +276,7 @@ func getAWSS3PricingData() map[string]map[string]float64 {
 			"GLACIER_IR":           0.004,
 			"DEEP_ARCHIVE":         0.00099,
 			"INTELLIGENT_TIERING":  0.0125,
+			"EXPRESS_ONEZONE":      0.11,
 		},
 		"us-east-2": {
 			"STANDARD":             0.023,
@@ -204,6 +287,7 @@ func getAWSS3PricingData() map[string]map[string]float64 {
 			"GLACIER_IR":           0.004,
 			"DEEP_ARCHIVE":         0.00099,
 			"INTELLIGENT_TIERING":  0.0125,
+			"EXPRESS_ONEZONE":      0.11,
 		},
 		"us-west-1": {
 			"STANDARD":             0.026,
@@ -224,6 +308,7 @@ func getAWSS3PricingData() map[string]map[string]float64 {
 			"GLACIER_IR":           0.004,
 			"DEEP_ARCHIVE":         0.00099,
 			"INTELLIGENT_TIERING":  0.0125,
+			"EXPRESS_ONEZONE":      0.11,
 		},
 		"eu-west-1": {
 			"STANDARD":             0.025,
@@ -234,6 +319,7 @@ func getAWSS3PricingData() map[string]map[string]float64 {
 			"GLACIER_IR":           0.0045,
 			"DEEP_ARCHIVE":         0.00108,
 			"INTELLIGENT_TIERING":  0.0138,
+			"EXPRESS_ONEZONE":      0.12,
 		},
 		"eu-west-2": {
 			"STANDARD":             0.025,
@@ -294,6 +380,7 @@ func getAWSS3PricingData() map[string]map[string]float64 {
 			"GLACIER_IR":           0.0045,
 			"DEEP_ARCHIVE":         0.00108,
 			"INTELLIGENT_TIERING":  0.0138,
+			"EXPRESS_ONEZONE":      0.13,
 		},
 		"ap-northeast-2": {
 			"STANDARD":             0.025,
@@ -314,6 +401,7 @@ func getAWSS3PricingData() map[string]map[string]float64 {
 			"GLACIER_IR":           0.0045,
 			"DEEP_ARCHIVE":         0.00108,
 			"INTELLIGENT_TIERING":  0.0138,
+			"EXPRESS_ONEZONE":      0.11,
 		},
 		"sa-east-1": {
 			"STANDARD":             0.027,
@@ -335,5 +423,228 @@ func getAWSS3PricingData() map[string]map[string]float64 {
 			"DEEP_ARCHIVE":         0.00108,
 			"INTELLIGENT_TIERING":  0.0138,
 		},
+		"af-south-1": {
+			"STANDARD":             0.027,
+			"STANDARD_IA":          0.015,
+			"ONEZONE_IA":           0.012,
+			"REDUCED_REDUNDANCY":   0.028,
+			"GLACIER":              0.0048,
+			"GLACIER_IR":           0.0048,
+			"DEEP_ARCHIVE":         0.00115,
+			"INTELLIGENT_TIERING":  0.015,
+		},
+		"ap-east-1": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0045,
+			"GLACIER_IR":           0.0045,
+			"DEEP_ARCHIVE":         0.00108,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		"ap-east-2": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0045,
+			"GLACIER_IR":           0.0045,
+			"DEEP_ARCHIVE":         0.00108,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		"ap-northeast-3": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0045,
+			"GLACIER_IR":           0.0045,
+			"DEEP_ARCHIVE":         0.00108,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		"ap-south-2": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0045,
+			"GLACIER_IR":           0.0045,
+			"DEEP_ARCHIVE":         0.00108,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		"ap-southeast-3": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0045,
+			"GLACIER_IR":           0.0045,
+			"DEEP_ARCHIVE":         0.00108,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		"ap-southeast-4": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0045,
+			"GLACIER_IR":           0.0045,
+			"DEEP_ARCHIVE":         0.00108,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		"ap-southeast-5": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0045,
+			"GLACIER_IR":           0.0045,
+			"DEEP_ARCHIVE":         0.00108,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		"ap-southeast-7": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0045,
+			"GLACIER_IR":           0.0045,
+			"DEEP_ARCHIVE":         0.00108,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		"ca-west-1": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0045,
+			"GLACIER_IR":           0.0045,
+			"DEEP_ARCHIVE":         0.00108,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		"eu-central-2": {
+			"STANDARD":             0.026,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.027,
+			"GLACIER":              0.004,
+			"GLACIER_IR":           0.004,
+			"DEEP_ARCHIVE":         0.00099,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		"eu-north-1": {
+			"STANDARD":             0.023,
+			"STANDARD_IA":          0.0125,
+			"ONEZONE_IA":           0.01,
+			"REDUCED_REDUNDANCY":   0.024,
+			"GLACIER":              0.004,
+			"GLACIER_IR":           0.004,
+			"DEEP_ARCHIVE":         0.00099,
+			"INTELLIGENT_TIERING":  0.0125,
+			"EXPRESS_ONEZONE":      0.12,
+		},
+		"eu-south-1": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0045,
+			"GLACIER_IR":           0.0045,
+			"DEEP_ARCHIVE":         0.00108,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		"eu-south-2": {
+			"STANDARD":             0.023,
+			"STANDARD_IA":          0.0125,
+			"ONEZONE_IA":           0.01,
+			"REDUCED_REDUNDANCY":   0.024,
+			"GLACIER":              0.004,
+			"GLACIER_IR":           0.004,
+			"DEEP_ARCHIVE":         0.00099,
+			"INTELLIGENT_TIERING":  0.0125,
+		},
+		"il-central-1": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0045,
+			"GLACIER_IR":           0.0045,
+			"DEEP_ARCHIVE":         0.00108,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		"me-central-1": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0045,
+			"GLACIER_IR":           0.0045,
+			"DEEP_ARCHIVE":         0.00108,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		"me-south-1": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0045,
+			"GLACIER_IR":           0.0045,
+			"DEEP_ARCHIVE":         0.00108,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		"mx-central-1": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0138,
+			"ONEZONE_IA":           0.011,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0045,
+			"GLACIER_IR":           0.0045,
+			"DEEP_ARCHIVE":         0.00108,
+			"INTELLIGENT_TIERING":  0.0138,
+		},
+		// AWS GovCloud (US)
+		"us-gov-west-1": {
+			"STANDARD":             0.039,
+			"STANDARD_IA":          0.02,
+			"ONEZONE_IA":           0.016,
+			"REDUCED_REDUNDANCY":   0.042,
+			"GLACIER":              0.0054,
+			"GLACIER_IR":           0.005,
+			"DEEP_ARCHIVE":         0.0018,
+			"INTELLIGENT_TIERING":  0.039,
+		},
+		"us-gov-east-1": {
+			"STANDARD":             0.039,
+			"STANDARD_IA":          0.02,
+			"ONEZONE_IA":           0.016,
+			"REDUCED_REDUNDANCY":   0.042,
+			"GLACIER":              0.0054,
+			"GLACIER_IR":           0.005,
+			"DEEP_ARCHIVE":         0.0018,
+			"INTELLIGENT_TIERING":  0.039,
+		},
+		// AWS China regions bill in CNY; these are USD equivalents
+		"cn-north-1": {
+			"STANDARD":             0.025,
+			"STANDARD_IA":          0.0146,
+			"ONEZONE_IA":           0.0117,
+			"REDUCED_REDUNDANCY":   0.027,
+			"GLACIER":              0.0046,
+			"GLACIER_IR":           0.0056,
+			"DEEP_ARCHIVE":         0.0014,
+			"INTELLIGENT_TIERING":  0.025,
+		},
+		"cn-northwest-1": {
+			"STANDARD":             0.0237,
+			"STANDARD_IA":          0.0139,
+			"ONEZONE_IA":           0.0111,
+			"REDUCED_REDUNDANCY":   0.026,
+			"GLACIER":              0.0044,
+			"GLACIER_IR":           0.0053,
+			"DEEP_ARCHIVE":         0.0013,
+			"INTELLIGENT_TIERING":  0.0237,
+		},
 	}
 }
\ No newline at end of file
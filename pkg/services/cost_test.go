@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestCalculateAccruedCost(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	service := NewCostService()
+	service.now = func() time.Time { return now }
+
+	const gb = 1024 * 1024 * 1024
+	month := time.Duration(daysPerMonth * 24 * float64(time.Hour))
+
+	tests := []struct {
+		name      string
+		initiated time.Time
+		want      float64
+	}{
+		{"two months old", now.Add(-2 * month), 10 * 0.023 * 2},
+		{"prorated by days", now.Add(-month / 2), 10 * 0.023 * 0.5},
+		{"initiated in the future", now.Add(time.Hour), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploads := []types.MultipartUpload{
+				{Size: 10 * gb, Region: "us-east-1", StorageClass: "STANDARD", Initiated: tt.initiated},
+			}
+			got, err := service.CalculateAccruedCost(context.Background(), uploads)
+			if err != nil {
+				t.Fatalf("CalculateAccruedCost() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("CalculateAccruedCost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateStorageCostAnnualized(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Size: 1024 * 1024 * 1024, Region: "us-east-1", StorageClass: "STANDARD", Initiated: time.Now()},
+	}
+	breakdown, err := NewCostService().CalculateStorageCost(context.Background(), uploads)
+	if err != nil {
+		t.Fatalf("CalculateStorageCost() error = %v", err)
+	}
+	if math.Abs(breakdown.AnnualizedCost-breakdown.TotalMonthlyCost*12) > 1e-9 {
+		t.Errorf("AnnualizedCost = %v, want %v", breakdown.AnnualizedCost, breakdown.TotalMonthlyCost*12)
+	}
+}
+
+func TestSubtotalsByAccount(t *testing.T) {
+	const gb = 1024 * 1024 * 1024
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Size: 3 * gb, Region: "us-east-1", StorageClass: "STANDARD", Account: "prod", Initiated: time.Now()},
+		{Bucket: "media", Size: 1 * gb, Region: "us-east-1", StorageClass: "STANDARD", Account: "prod", Initiated: time.Now()},
+		{Bucket: "scratch", Size: 2 * gb, Region: "us-east-1", StorageClass: "STANDARD", Account: "dev", Initiated: time.Now()},
+	}
+
+	breakdown, err := NewCostService().CalculateStorageCost(context.Background(), uploads)
+	if err != nil {
+		t.Fatalf("CalculateStorageCost() error = %v", err)
+	}
+	if math.Abs(breakdown.ByAccount["prod"]-4*0.023) > 1e-9 || math.Abs(breakdown.ByAccount["dev"]-2*0.023) > 1e-9 {
+		t.Errorf("ByAccount = %v, want prod 4 GB and dev 2 GB worth", breakdown.ByAccount)
+	}
+
+	report, err := (&SizeService{}).ReportForUploads(uploads)
+	if err != nil {
+		t.Fatalf("ReportForUploads() error = %v", err)
+	}
+	if report.ByAccount["prod"] != 4*gb || report.ByAccount["dev"] != 2*gb {
+		t.Errorf("ByAccount = %v, want prod 4 GB and dev 2 GB", report.ByAccount)
+	}
+
+	report.FailedAccounts = []types.AccountFailure{{Account: "audit", Error: "failed to list uploads: AccessDenied"}}
+	output := (&OutputFormatter{}).FormatSizeReport(*report)
+	if !strings.Contains(output, "Breakdown by account:\n  prod: 4.0 GB (66.7%)\n  dev: 2.0 GB (33.3%)") || !strings.Contains(output, "Failed accounts (1):\n  audit: failed to list uploads: AccessDenied") {
+		t.Errorf("FormatSizeReport() = %q, want account subtotals and the failed account", output)
+	}
+
+	// Single-account uploads have no account subtotals
+	uploads[0].Account, uploads[1].Account, uploads[2].Account = "", "", ""
+	if breakdown, _ := NewCostService().CalculateStorageCost(context.Background(), uploads); breakdown.ByAccount != nil {
+		t.Errorf("ByAccount = %v without accounts, want nil", breakdown.ByAccount)
+	}
+}
+
+func TestCalculateStorageCostWarnsAboutDefaultPricing(t *testing.T) {
+	const gb = 1024 * 1024 * 1024
+	var uploads []types.MultipartUpload
+	for i := 0; i < 25; i++ {
+		uploads = append(uploads, types.MultipartUpload{Size: gb, Region: "xx-unknown-1", StorageClass: "STANDARD", Initiated: time.Now()})
+	}
+	uploads = append(uploads,
+		types.MultipartUpload{Size: gb, Region: "me-central-1", StorageClass: "STANDARD", Initiated: time.Now()},
+		types.MultipartUpload{Size: gb, Region: "us-east-1", StorageClass: "STANDARD", Initiated: time.Now()},
+	)
+
+	breakdown, err := NewCostService().CalculateStorageCost(context.Background(), uploads)
+	if err != nil {
+		t.Fatalf("CalculateStorageCost() error = %v", err)
+	}
+	if len(breakdown.PricingWarnings) != 1 || breakdown.PricingWarnings[0] != "region xx-unknown-1" {
+		t.Fatalf("PricingWarnings = %v, want exactly one warning for xx-unknown-1", breakdown.PricingWarnings)
+	}
+	if math.Abs(breakdown.ByRegion["xx-unknown-1"]-25*0.023) > 1e-9 {
+		t.Errorf("ByRegion[xx-unknown-1] = %v, want the default STANDARD price", breakdown.ByRegion["xx-unknown-1"])
+	}
+
+	output := (&OutputFormatter{}).FormatCostBreakdown(breakdown)
+	if !strings.Contains(output, "⚠ estimated using default pricing: region xx-unknown-1") {
+		t.Errorf("FormatCostBreakdown() = %q, want the default pricing footnote", output)
+	}
+
+	// Every commercial, GovCloud and China region has its own prices
+	uploads = append(uploads,
+		types.MultipartUpload{Size: gb, Region: "us-gov-west-1", StorageClass: "STANDARD", Initiated: time.Now()},
+		types.MultipartUpload{Size: gb, Region: "cn-north-1", StorageClass: "GLACIER", Initiated: time.Now()},
+	)
+	breakdown, _ = NewCostService().CalculateStorageCost(context.Background(), uploads[25:])
+	if breakdown.PricingWarnings != nil {
+		t.Errorf("PricingWarnings = %v for known regions, want none", breakdown.PricingWarnings)
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// deletionReportCSVHeader is the header of CSV deletion reports, which follows
+// the run metadata written as "# name: value" comment lines
+var deletionReportCSVHeader = []string{
+	"bucket",
+	"key",
+	"upload_id",
+	"size",
+	"region",
+	"status",
+	"error",
+	"error_code",
+	"timestamp",
+}
+
+// DeletionReportWriter collects the outcome of every deletion in a delete run
+// and writes them with the run's options and totals. Unlike the console, which
+// shows the first few errors, the report has every upload.
+type DeletionReportWriter struct {
+	filename string
+	report   types.DeletionReport
+	now      func() time.Time
+
+	mutex    sync.Mutex
+	outcomes []types.DeletionOutcome
+}
+
+// NewDeletionReportWriter returns a writer for filename, which is written as
+// CSV if it ends in .csv and as JSON otherwise. The report holds the run
+// metadata; the writer adds opts and the outcomes.
+func NewDeletionReportWriter(filename string, report types.DeletionReport, opts types.DeleteOptions) *DeletionReportWriter {
+	report.SchemaVersion = types.SchemaVersion
+	report.Kind = types.DeletionReportKind
+	report.Options = deleteCommandString(opts)
+	report.Filters = deleteFilterString(opts)
+	if report.StartedAt.IsZero() {
+		report.StartedAt = time.Now().UTC()
+	}
+	return &DeletionReportWriter{filename: filename, report: report, now: time.Now}
+}
+
+// Filename returns the path of the report
+func (w *DeletionReportWriter) Filename() string {
+	return w.filename
+}
+
+// Record adds the outcome of deleting upload; err is nil if it was deleted.
+// It is safe for concurrent use.
+func (w *DeletionReportWriter) Record(upload types.MultipartUpload, err error) {
+	outcome := types.DeletionOutcome{MultipartUpload: upload, DeletionStatus: types.DeletionStatus{Success: err == nil}}
+	if err != nil {
+		outcome.Error = err.Error()
+		outcome.ErrorCode = awsclient.ClassifyError(err).Code
+	}
+	w.add(outcome)
+}
+
+// RecordAlreadyCleaned adds an upload a retry found S3 no longer had
+func (w *DeletionReportWriter) RecordAlreadyCleaned(upload types.MultipartUpload) {
+	w.add(types.DeletionOutcome{MultipartUpload: upload, DeletionStatus: types.DeletionStatus{Success: true, AlreadyCleaned: true}})
+}
+
+// add timestamps an outcome and adds it to the report
+func (w *DeletionReportWriter) add(outcome types.DeletionOutcome) {
+	outcome.Timestamp = w.now().UTC()
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.outcomes = append(w.outcomes, outcome)
+}
+
+// Write writes the report with the outcomes recorded so far. runErr is the
+// error the run ended with, if any, and interrupted whether it was cut short.
+func (w *DeletionReportWriter) Write(runErr error, interrupted bool) error {
+	report := w.finish(runErr, interrupted)
+
+	file, err := createExportFile(w.filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if strings.ToLower(filepath.Ext(w.filename)) == ".csv" {
+		err = writeDeletionReportCSV(file, report)
+	} else {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write deletion report %s: %w", w.filename, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close deletion report %s: %w", w.filename, err)
+	}
+	return nil
+}
+
+// finish returns the report with the outcomes and the totals they add up to
+func (w *DeletionReportWriter) finish(runErr error, interrupted bool) types.DeletionReport {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	report := w.report
+	report.FinishedAt = w.now().UTC()
+	report.DurationSeconds = report.FinishedAt.Sub(report.StartedAt).Seconds()
+	report.Interrupted = interrupted
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+	report.Outcomes = append([]types.DeletionOutcome{}, w.outcomes...)
+	for _, outcome := range report.Outcomes {
+		report.TotalProcessed++
+		if outcome.AlreadyCleaned {
+			report.AlreadyCleaned++
+		} else if outcome.Success {
+			report.SuccessfulDeletes++
+			report.StorageFreed += outcome.Size
+		} else {
+			report.FailedDeletes++
+		}
+	}
+	return report
+}
+
+// writeDeletionReportCSV writes the run metadata as comment lines, then one
+// record per outcome
+func writeDeletionReportCSV(w io.Writer, report types.DeletionReport) error {
+	metadata := [][2]string{
+		{"kind", report.Kind},
+		{"s3mpc_version", report.Version},
+		{"command", report.Command},
+		{"options", report.Options},
+		{"filters", report.Filters},
+		{"profile", report.Profile},
+		{"region", report.Region},
+		{"role_arn", report.RoleARN},
+		{"source_file", report.SourceFile},
+		{"started_at", types.FormatTimestamp(report.StartedAt)},
+		{"finished_at", types.FormatTimestamp(report.FinishedAt)},
+		{"total_processed", strconv.Itoa(report.TotalProcessed)},
+		{"successful_deletes", strconv.Itoa(report.SuccessfulDeletes)},
+		{"failed_deletes", strconv.Itoa(report.FailedDeletes)},
+		{"already_cleaned", strconv.Itoa(report.AlreadyCleaned)},
+		{"storage_freed", strconv.FormatInt(report.StorageFreed, 10)},
+		{"interrupted", strconv.FormatBool(report.Interrupted)},
+		{"error", report.Error},
+		{"retry_of", report.RetryOf},
+	}
+	for _, field := range metadata {
+		if field[1] == "" {
+			continue
+		}
+		line := strings.NewReplacer("\r", " ", "\n", " ").Replace(field[1]) // keep each field on its comment line
+		if _, err := fmt.Fprintf(w, "# %s: %s\n", field[0], line); err != nil {
+			return err
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(deletionReportCSVHeader); err != nil {
+		return err
+	}
+	for _, outcome := range report.Outcomes {
+		status := "deleted"
+		if outcome.AlreadyCleaned {
+			status = "already_cleaned"
+		} else if !outcome.Success {
+			status = "failed"
+		}
+		if err := writer.Write([]string{
+			outcome.Bucket,
+			outcome.Key,
+			outcome.UploadID,
+			strconv.FormatInt(outcome.Size, 10),
+			outcome.Region,
+			status,
+			outcome.Error,
+			outcome.ErrorCode,
+			types.FormatTimestamp(outcome.Timestamp),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// cleanedUploadsClient answers ListParts with NoSuchUpload for the uploads in
+// gone and fails for those in failing
+type cleanedUploadsClient struct {
+	mockAbortClient
+	gone    map[string]bool
+	failing map[string]bool
+}
+
+func (c *cleanedUploadsClient) ListParts(ctx context.Context, input *s3.ListPartsInput) (*s3.ListPartsOutput, error) {
+	switch id := aws.ToString(input.UploadId); {
+	case c.gone[id]:
+		return nil, &s3types.NoSuchUpload{}
+	case c.failing[id]:
+		return nil, errors.New("SlowDown: please reduce your request rate")
+	}
+	return &s3.ListPartsOutput{}, nil
+}
+
+func TestDeletionReportWriterCSV(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "reports", "cleanup.csv")
+	week := 7 * 24 * time.Hour
+	opts := types.DeleteOptions{OlderThan: &week, BucketName: "logs", Force: true}
+	report := NewDeletionReportWriter(filename, types.DeletionReport{Command: "s3mpc delete", Version: "1.2.3"}, opts)
+
+	// Every upload the delete attempts reaches the report
+	client := &mockAbortClient{}
+	service := &UploadService{
+		client:           client,
+		regionalClients:  map[string]S3UploadClientInterface{"us-east-1": client},
+		concurrency:      2,
+		progressReporter: NewConsoleProgressReporter(io.Discard, true),
+		outputWriter:     io.Discard,
+	}
+	opts.OnOutcome = report.Record
+	initiated := time.Now().Add(-30 * 24 * time.Hour)
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a,b.tmp", UploadID: "1", Initiated: initiated, Size: 10, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "c.tmp", UploadID: "2", Initiated: initiated, Size: 20, StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+	if err := service.DeleteUploads(context.Background(), uploads, opts); err != nil {
+		t.Fatalf("DeleteUploads() error = %v", err)
+	}
+	report.Record(types.MultipartUpload{Bucket: "logs", Key: "d.tmp", UploadID: "3", Size: 40}, errors.New("access denied"))
+
+	runErr := errors.New("failed to delete 1 out of 3 uploads\nsee above")
+	if err := report.Write(runErr, false); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	for _, line := range []string{
+		"# kind: delete-report\n",
+		"# s3mpc_version: 1.2.3\n",
+		"# options: delete -b logs --older-than 7d --force\n",
+		"# filters: age>7d,bucket=logs\n",
+		"# total_processed: 3\n",
+		"# storage_freed: 30\n",
+		"# error: failed to delete 1 out of 3 uploads see above\n",
+	} {
+		if !strings.Contains(content, line) {
+			t.Errorf("report = %q, want line %q", content, line)
+		}
+	}
+
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("report is not CSV: %v", err)
+	}
+	if len(records) != 4 || strings.Join(records[0], ",") != strings.Join(deletionReportCSVHeader, ",") {
+		t.Fatalf("records = %v, want the header and 3 outcomes", records)
+	}
+	statuses := map[string]string{}
+	for _, record := range records[1:] {
+		statuses[record[1]] = record[5] + " " + record[6]
+	}
+	want := map[string]string{"a,b.tmp": "deleted ", "c.tmp": "deleted ", "d.tmp": "failed access denied"}
+	for key, status := range want {
+		if statuses[key] != status {
+			t.Errorf("outcome of %s = %q, want %q", key, statuses[key], status)
+		}
+	}
+}
+
+func TestRetryFromDeletionReport(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cleanup.json")
+	initiated := time.Now().Add(-30 * 24 * time.Hour).UTC().Truncate(time.Second)
+	upload := func(key, id string) types.MultipartUpload {
+		return types.MultipartUpload{Bucket: "logs", Key: key, UploadID: id, Initiated: initiated, Size: 10, StorageClass: "STANDARD", Region: "us-east-1"}
+	}
+
+	report := NewDeletionReportWriter(filename, types.DeletionReport{Command: "s3mpc delete"}, types.DeleteOptions{})
+	report.Record(upload("deleted.tmp", "1"), nil)
+	for _, failed := range []types.MultipartUpload{upload("gone.tmp", "2"), upload("throttled.tmp", "3"), upload("left.tmp", "4")} {
+		report.Record(failed, errors.New("SlowDown: please reduce your request rate"))
+	}
+	if err := report.Write(nil, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the failed uploads are read back, in full
+	file, err := LoadUploadsFile(filename)
+	if err != nil {
+		t.Fatalf("LoadUploadsFile() error = %v", err)
+	}
+	if file.Kind != UploadsFileDeletions || len(file.Uploads) != 3 || !reflect.DeepEqual(file.Uploads[0], upload("gone.tmp", "2")) {
+		t.Fatalf("LoadUploadsFile() = %+v, want the 3 failed uploads", file)
+	}
+
+	// An upload S3 no longer has is already cleaned; one that cannot be checked is retried
+	client := &cleanedUploadsClient{gone: map[string]bool{"2": true}, failing: map[string]bool{"3": true}}
+	service := &UploadService{
+		client:          client,
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": client},
+		concurrency:     2,
+	}
+	remaining, cleaned, err := service.FindRemainingUploads(context.Background(), file.Uploads)
+	if err != nil {
+		t.Fatalf("FindRemainingUploads() error = %v", err)
+	}
+	if len(remaining) != 2 || remaining[0].Key != "throttled.tmp" || remaining[1].Key != "left.tmp" {
+		t.Errorf("remaining = %+v, want throttled.tmp and left.tmp", remaining)
+	}
+	if len(cleaned) != 1 || cleaned[0].Key != "gone.tmp" {
+		t.Errorf("cleaned = %+v, want gone.tmp", cleaned)
+	}
+}
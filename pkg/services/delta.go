@@ -0,0 +1,77 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+	"github.com/Garvitkul/s3mpc/pkg/units"
+)
+
+// maxDeltaUploads is how many new and gone uploads FormatUploadDelta names
+const maxDeltaUploads = 10
+
+// DiffUploads compares two scans of the same buckets. Uploads are matched by
+// bucket, key and upload ID, so an upload restarted under the same key counts
+// as gone and new.
+func DiffUploads(previous, current []types.MultipartUpload) types.UploadDelta {
+	uploadKey := func(upload types.MultipartUpload) string {
+		return upload.Bucket + "\x00" + upload.Key + "\x00" + upload.UploadID
+	}
+
+	before := make(map[string]types.MultipartUpload, len(previous))
+	for _, upload := range previous {
+		before[uploadKey(upload)] = upload
+	}
+
+	var delta types.UploadDelta
+	for _, upload := range current {
+		key := uploadKey(upload)
+		old, exists := before[key]
+		if !exists {
+			delta.New = append(delta.New, upload)
+			delta.SizeChange += upload.Size
+			continue
+		}
+		delete(before, key)
+		if upload.Size > old.Size {
+			delta.Grown++
+		}
+		delta.SizeChange += upload.Size - old.Size
+	}
+	// What is left was in the previous scan only; keep the scan's order
+	for _, upload := range previous {
+		if _, gone := before[uploadKey(upload)]; gone {
+			delta.Gone = append(delta.Gone, upload)
+			delta.SizeChange -= upload.Size
+		}
+	}
+	return delta
+}
+
+// FormatUploadDelta summarizes a delta on one line, then names the first new
+// and gone uploads
+func FormatUploadDelta(delta types.UploadDelta) string {
+	sign := "+"
+	size := delta.SizeChange
+	if size < 0 {
+		sign = "-"
+		size = -size
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Since last scan: %d new, %d completed or aborted, %d growing, size %s%s\n",
+		len(delta.New), len(delta.Gone), delta.Grown, sign, units.FormatBytes(size)))
+	writeUploads := func(marker string, uploads []types.MultipartUpload) {
+		for i, upload := range uploads {
+			if i == maxDeltaUploads {
+				result.WriteString(fmt.Sprintf("  %s ... and %d more\n", marker, len(uploads)-maxDeltaUploads))
+				break
+			}
+			result.WriteString(fmt.Sprintf("  %s %s/%s\n", marker, types.DisplayString(upload.Bucket), types.DisplayString(upload.Key)))
+		}
+	}
+	writeUploads("+", delta.New)
+	writeUploads("-", delta.Gone)
+	return result.String()
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestDiffUploads(t *testing.T) {
+	previous := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.gz", UploadID: "1", Size: 100},
+		{Bucket: "logs", Key: "b.gz", UploadID: "2", Size: 200},
+		{Bucket: "media", Key: "c.mp4", UploadID: "3", Size: 300},
+	}
+	current := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.gz", UploadID: "1", Size: 150},
+		{Bucket: "media", Key: "c.mp4", UploadID: "3", Size: 300},
+		// Restarted under the same key
+		{Bucket: "logs", Key: "b.gz", UploadID: "4", Size: 10},
+	}
+
+	delta := DiffUploads(previous, current)
+	if len(delta.New) != 1 || delta.New[0].UploadID != "4" {
+		t.Errorf("New = %v, want the restarted upload", delta.New)
+	}
+	if len(delta.Gone) != 1 || delta.Gone[0].UploadID != "2" {
+		t.Errorf("Gone = %v, want the replaced upload", delta.Gone)
+	}
+	if delta.Grown != 1 {
+		t.Errorf("Grown = %d, want 1", delta.Grown)
+	}
+	if want := int64(50 + 10 - 200); delta.SizeChange != want {
+		t.Errorf("SizeChange = %d, want %d", delta.SizeChange, want)
+	}
+}
+
+func TestFormatUploadDelta(t *testing.T) {
+	var delta types.UploadDelta
+	for i := 0; i < maxDeltaUploads+2; i++ {
+		delta.New = append(delta.New, types.MultipartUpload{Bucket: "logs", Key: "new.gz"})
+	}
+	delta.Gone = []types.MultipartUpload{{Bucket: "media", Key: "done.mp4"}}
+	delta.SizeChange = -2048
+
+	output := FormatUploadDelta(delta)
+	for _, want := range []string{
+		"Since last scan: 12 new, 1 completed or aborted, 0 growing, size -2.0 KB\n",
+		"  + logs/new.gz\n",
+		"  + ... and 2 more\n",
+		"  - media/done.mp4\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("FormatUploadDelta() is missing %q:\n%s", want, output)
+		}
+	}
+}
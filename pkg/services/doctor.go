@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// CallerIdentityClient resolves the identity behind the configured credentials
+type CallerIdentityClient interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// S3ProbeClient checks S3 permissions on a bucket without changing any data
+type S3ProbeClient interface {
+	ProbeListMultipartUploads(ctx context.Context, bucket string) error
+	ProbeListParts(ctx context.Context, bucket string) error
+	ProbeAbortMultipartUpload(ctx context.Context, bucket string) error
+}
+
+// ProbeClientFactory returns the probe client for a bucket's region
+type ProbeClientFactory func(region string) S3ProbeClient
+
+// DoctorSettings are the performance settings the doctor checks
+type DoctorSettings struct {
+	RateLimit         float64 // requests per second per client
+	BucketConcurrency int
+	OpConcurrency     int
+	// PricingUnavailable says why the partition has no Pricing API; empty
+	// when it has one
+	PricingUnavailable string
+}
+
+// Limits past which the settings check warns
+const (
+	// maxSensibleRateLimit is the request rate S3 supports for writes to one prefix
+	maxSensibleRateLimit = 3500
+	// maxWorkersPerRequest is how many workers per request per second can
+	// still be kept busy; more only wait for the rate limiter
+	maxWorkersPerRequest = 10
+)
+
+// DoctorService implements the interfaces.DoctorService interface
+type DoctorService struct {
+	identity       CallerIdentityClient
+	bucketService  interfaces.BucketService
+	newProbeClient ProbeClientFactory
+	pricing        PricingAPIClient // nil unless live pricing is enabled
+	settings       DoctorSettings
+}
+
+// NewDoctorService creates a new DoctorService. pricingClient is nil when live
+// pricing is disabled, which skips the Pricing API check.
+func NewDoctorService(identity CallerIdentityClient, bucketService interfaces.BucketService, newProbeClient ProbeClientFactory, pricingClient PricingAPIClient, settings DoctorSettings) *DoctorService {
+	return &DoctorService{
+		identity:       identity,
+		bucketService:  bucketService,
+		newProbeClient: newProbeClient,
+		pricing:        pricingClient,
+		settings:       settings,
+	}
+}
+
+// Names of the checks, in the order they are made
+const (
+	checkCredentials  = "Credentials"
+	checkListBuckets  = "List buckets"
+	checkListUploads  = "List multipart uploads"
+	checkListParts    = "List upload parts"
+	checkAbortUploads = "Abort multipart uploads"
+	checkPricing      = "Pricing API"
+	checkSettings     = "Rate limit and concurrency"
+)
+
+// RunChecks makes every check in order. Checks that depend on a failed one
+// are reported as skipped, since they would fail for the same reason.
+func (s *DoctorService) RunChecks(ctx context.Context, opts types.DoctorOptions) []types.DoctorCheck {
+	checks := []types.DoctorCheck{s.checkCredentials(ctx)}
+	if checks[0].Status == types.DoctorFail {
+		for _, name := range []string{checkListBuckets, checkListUploads, checkListParts, checkAbortUploads} {
+			checks = append(checks, skippedCheck(name, "credentials did not resolve"))
+		}
+	} else {
+		listCheck, bucket := s.checkListBuckets(ctx, opts.Bucket)
+		checks = append(checks, listCheck)
+		checks = append(checks, s.checkBucketPermissions(ctx, bucket)...)
+	}
+	checks = append(checks, s.checkPricing(ctx))
+	return append(checks, s.checkSettings())
+}
+
+// checkCredentials resolves the caller's identity with STS
+func (s *DoctorService) checkCredentials(ctx context.Context) types.DoctorCheck {
+	check := types.DoctorCheck{Name: checkCredentials, Required: true}
+	output, err := s.identity.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return failedCheck(check, err, "Check --profile, --role-arn and the AWS_* environment variables, or run 'aws sso login' if the profile uses SSO")
+	}
+	check.Status = types.DoctorPass
+	check.Detail = fmt.Sprintf("account %s, %s", aws.ToString(output.Account), aws.ToString(output.Arn))
+	return check
+}
+
+// checkListBuckets lists buckets and returns the bucket to probe: the one
+// requested, or else the first one listed. The returned bucket has no name
+// when there is none to probe.
+func (s *DoctorService) checkListBuckets(ctx context.Context, requested string) (types.DoctorCheck, types.Bucket) {
+	check := types.DoctorCheck{Name: checkListBuckets, Required: true}
+	buckets, _, err := s.bucketService.ListBuckets(ctx, "")
+	if err != nil {
+		return failedCheck(check, err, "Grant s3:ListAllMyBuckets, or pass --bucket to work on a single bucket"), types.Bucket{}
+	}
+	check.Status = types.DoctorPass
+	check.Detail = fmt.Sprintf("%d buckets", len(buckets))
+
+	if requested == "" {
+		if len(buckets) == 0 {
+			return check, types.Bucket{}
+		}
+		return check, buckets[0]
+	}
+	for _, bucket := range buckets {
+		if bucket.Name == requested {
+			return check, bucket
+		}
+	}
+	// The bucket may belong to another account that grants access to it
+	region, err := s.bucketService.GetBucketRegion(ctx, requested)
+	if err != nil {
+		return check, types.Bucket{Name: requested}
+	}
+	return check, types.Bucket{Name: requested, Region: region}
+}
+
+// checkBucketPermissions probes the upload permissions on bucket
+func (s *DoctorService) checkBucketPermissions(ctx context.Context, bucket types.Bucket) []types.DoctorCheck {
+	probes := []struct {
+		name     string
+		action   string
+		required bool
+		probe    func(S3ProbeClient) error
+	}{
+		{checkListUploads, "s3:ListBucketMultipartUploads", true, func(c S3ProbeClient) error { return c.ProbeListMultipartUploads(ctx, bucket.Name) }},
+		{checkListParts, "s3:ListMultipartUploadParts", true, func(c S3ProbeClient) error { return c.ProbeListParts(ctx, bucket.Name) }},
+		// Listing and reporting work without it, so only delete is affected
+		{checkAbortUploads, "s3:AbortMultipartUpload", false, func(c S3ProbeClient) error { return c.ProbeAbortMultipartUpload(ctx, bucket.Name) }},
+	}
+
+	checks := make([]types.DoctorCheck, 0, len(probes))
+	if bucket.Name == "" {
+		for _, probe := range probes {
+			checks = append(checks, skippedCheck(probe.name, "no bucket to probe"))
+		}
+		return checks
+	}
+
+	client := s.newProbeClient(bucket.Region)
+	for _, probe := range probes {
+		check := types.DoctorCheck{Name: probe.name, Required: probe.required}
+		if err := probe.probe(client); err != nil {
+			checks = append(checks, failedCheck(check, err, fmt.Sprintf("Grant %s on %s, and check SCPs and the bucket policy for an explicit Deny", probe.action, bucket.Name)))
+			continue
+		}
+		check.Status = types.DoctorPass
+		check.Detail = "allowed on " + bucket.Name
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkPricing queries the Pricing API when live pricing is enabled
+func (s *DoctorService) checkPricing(ctx context.Context) types.DoctorCheck {
+	check := types.DoctorCheck{Name: checkPricing, Required: true}
+	if s.pricing == nil {
+		if s.settings.PricingUnavailable != "" {
+			return skippedCheck(checkPricing, s.settings.PricingUnavailable)
+		}
+		return skippedCheck(checkPricing, "live pricing is not enabled")
+	}
+	_, err := s.pricing.GetProducts(ctx, &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonS3"),
+		MaxResults:  aws.Int32(1),
+	})
+	if err != nil {
+		return failedCheck(check, err, "Grant pricing:GetProducts, or drop --live-pricing to use the built-in prices")
+	}
+	check.Status = types.DoctorPass
+	check.Detail = "reachable"
+	return check
+}
+
+// ConcurrencyOutpacesRateLimit reports whether workers is so far above the
+// requests per second allowed that most workers would only wait for the rate
+// limiter
+func ConcurrencyOutpacesRateLimit(workers int, rateLimit float64) bool {
+	return float64(workers) > rateLimit*maxWorkersPerRequest
+}
+
+// checkSettings warns about rate limits and concurrency that slow scans
+// down or get throttled
+func (s *DoctorService) checkSettings() types.DoctorCheck {
+	check := types.DoctorCheck{Name: checkSettings}
+	settings := s.settings
+
+	var warnings, remediations []string
+	if settings.RateLimit > maxSensibleRateLimit {
+		warnings = append(warnings, fmt.Sprintf("rate limit %g/s is above the %d/s S3 supports per prefix", settings.RateLimit, maxSensibleRateLimit))
+		remediations = append(remediations, "lower rate-limit to avoid SlowDown errors")
+	}
+	for _, setting := range []struct {
+		flag  string
+		value int
+	}{{"--bucket-concurrency", settings.BucketConcurrency}, {"--op-concurrency", settings.OpConcurrency}} {
+		if ConcurrencyOutpacesRateLimit(setting.value, settings.RateLimit) {
+			warnings = append(warnings, fmt.Sprintf("%s %d is far above the rate limit of %g/s", setting.flag, setting.value, settings.RateLimit))
+			remediations = append(remediations, "lower "+setting.flag+" or raise rate-limit; extra workers only wait for the rate limiter")
+		}
+	}
+
+	if len(warnings) > 0 {
+		check.Status = types.DoctorWarn
+		check.Detail = strings.Join(warnings, "; ")
+		check.Remediation = strings.Join(remediations, "; ")
+		return check
+	}
+	check.Status = types.DoctorPass
+	check.Detail = fmt.Sprintf("rate limit %g/s, bucket concurrency %d, op concurrency %d", settings.RateLimit, settings.BucketConcurrency, settings.OpConcurrency)
+	return check
+}
+
+// failedCheck marks check as failed with err. hint is the remediation for
+// permission errors; other errors use the remediation of their error code.
+func failedCheck(check types.DoctorCheck, err error, hint string) types.DoctorCheck {
+	class := awsclient.ClassifyError(err)
+	check.Status = types.DoctorFail
+	check.Detail = err.Error()
+	if class.Category != awsclient.CategoryUnknown {
+		check.Detail = class.Code + ": " + class.Explanation
+	}
+	check.Remediation = hint
+	if class.Remediation != "" && class.Category != awsclient.CategoryPermission {
+		check.Remediation = class.Remediation
+	}
+	if !check.Required {
+		check.Status = types.DoctorWarn
+	}
+	return check
+}
+
+// skippedCheck reports a check that was not made, and why
+func skippedCheck(name, reason string) types.DoctorCheck {
+	return types.DoctorCheck{Name: name, Status: types.DoctorSkip, Detail: reason}
+}
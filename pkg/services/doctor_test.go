@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// fakeIdentity returns a fixed caller identity, or err
+type fakeIdentity struct {
+	err error
+}
+
+func (f *fakeIdentity) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &sts.GetCallerIdentityOutput{Account: aws.String("123456789012"), Arn: aws.String("arn:aws:iam::123456789012:user/ops")}, nil
+}
+
+// fakeProbeClient fails the probes named in errors and records the buckets probed
+type fakeProbeClient struct {
+	errors map[string]error
+	probed []string
+}
+
+func (f *fakeProbeClient) probe(name, bucket string) error {
+	f.probed = append(f.probed, name+":"+bucket)
+	return f.errors[name]
+}
+
+func (f *fakeProbeClient) ProbeListMultipartUploads(ctx context.Context, bucket string) error {
+	return f.probe("ListMultipartUploads", bucket)
+}
+
+func (f *fakeProbeClient) ProbeListParts(ctx context.Context, bucket string) error {
+	return f.probe("ListParts", bucket)
+}
+
+func (f *fakeProbeClient) ProbeAbortMultipartUpload(ctx context.Context, bucket string) error {
+	return f.probe("AbortMultipartUpload", bucket)
+}
+
+// fakePricingClient answers GetProducts with err
+type fakePricingClient struct {
+	err error
+}
+
+func (f *fakePricingClient) GetProducts(ctx context.Context, params *pricing.GetProductsInput, optFns ...func(*pricing.Options)) (*pricing.GetProductsOutput, error) {
+	return &pricing.GetProductsOutput{}, f.err
+}
+
+func newTestDoctor(identity *fakeIdentity, probes *fakeProbeClient, pricingClient PricingAPIClient, regions *[]string) *DoctorService {
+	buckets := &bucketListService{buckets: []types.Bucket{{Name: "logs", Region: "eu-west-1"}, {Name: "media", Region: "us-east-1"}}}
+	newProbeClient := func(region string) S3ProbeClient {
+		*regions = append(*regions, region)
+		return probes
+	}
+	return NewDoctorService(identity, buckets, newProbeClient, pricingClient, DoctorSettings{RateLimit: 10, BucketConcurrency: 10, OpConcurrency: 10})
+}
+
+// checkStatuses maps each check name to its status
+func checkStatuses(checks []types.DoctorCheck) map[string]string {
+	statuses := make(map[string]string)
+	for _, check := range checks {
+		statuses[check.Name] = check.Status
+	}
+	return statuses
+}
+
+func TestDoctorPassesWithAllPermissions(t *testing.T) {
+	probes := &fakeProbeClient{}
+	var regions []string
+	checks := newTestDoctor(&fakeIdentity{}, probes, nil, &regions).RunChecks(context.Background(), types.DoctorOptions{})
+
+	want := map[string]string{
+		checkCredentials:  types.DoctorPass,
+		checkListBuckets:  types.DoctorPass,
+		checkListUploads:  types.DoctorPass,
+		checkListParts:    types.DoctorPass,
+		checkAbortUploads: types.DoctorPass,
+		checkPricing:      types.DoctorSkip,
+		checkSettings:     types.DoctorPass,
+	}
+	for name, status := range checkStatuses(checks) {
+		if want[name] != status {
+			t.Errorf("%s = %s, want %s", name, status, want[name])
+		}
+	}
+	if failures := types.DoctorFailures(checks); failures != 0 {
+		t.Errorf("DoctorFailures() = %d, want 0", failures)
+	}
+	if !strings.Contains(checks[0].Detail, "123456789012") || !strings.Contains(checks[0].Detail, "user/ops") {
+		t.Errorf("credentials detail = %q, want the account and ARN", checks[0].Detail)
+	}
+	// The first bucket is probed with a client for its region
+	if len(regions) != 1 || regions[0] != "eu-west-1" || probes.probed[0] != "ListMultipartUploads:logs" {
+		t.Errorf("probed %v with clients for %v, want logs in eu-west-1", probes.probed, regions)
+	}
+}
+
+func TestDoctorReportsMissingPermissions(t *testing.T) {
+	denied := &smithy.GenericAPIError{Code: "AccessDenied", Message: "User is not authorized to perform: s3:ListMultipartUploadParts with an explicit deny in a service control policy"}
+	probes := &fakeProbeClient{errors: map[string]error{"ListParts": denied, "AbortMultipartUpload": denied}}
+	var regions []string
+	checks := newTestDoctor(&fakeIdentity{}, probes, nil, &regions).RunChecks(context.Background(), types.DoctorOptions{Bucket: "media"})
+
+	statuses := checkStatuses(checks)
+	if statuses[checkListParts] != types.DoctorFail {
+		t.Errorf("%s = %s, want fail", checkListParts, statuses[checkListParts])
+	}
+	// Only delete needs to abort uploads, so that is a warning
+	if statuses[checkAbortUploads] != types.DoctorWarn {
+		t.Errorf("%s = %s, want warn", checkAbortUploads, statuses[checkAbortUploads])
+	}
+	if failures := types.DoctorFailures(checks); failures != 1 {
+		t.Errorf("DoctorFailures() = %d, want 1", failures)
+	}
+	for _, check := range checks {
+		if check.Name == checkListParts && (!strings.Contains(check.Remediation, "s3:ListMultipartUploadParts on media") || !strings.Contains(check.Detail, "SCP")) {
+			t.Errorf("%s = %+v, want the permission to grant and the SCP deny", check.Name, check)
+		}
+	}
+	if regions[0] != "us-east-1" {
+		t.Errorf("probe client region = %s, want the region of the requested bucket", regions[0])
+	}
+}
+
+func TestDoctorSkipsChecksAfterCredentialFailure(t *testing.T) {
+	probes := &fakeProbeClient{}
+	var regions []string
+	identity := &fakeIdentity{err: errors.New("failed to refresh cached credentials, no EC2 IMDS role found")}
+	checks := newTestDoctor(identity, probes, &fakePricingClient{}, &regions).RunChecks(context.Background(), types.DoctorOptions{})
+
+	statuses := checkStatuses(checks)
+	if statuses[checkCredentials] != types.DoctorFail || statuses[checkListBuckets] != types.DoctorSkip || statuses[checkListUploads] != types.DoctorSkip {
+		t.Errorf("statuses = %v, want credentials failed and the S3 checks skipped", statuses)
+	}
+	if len(probes.probed) != 0 {
+		t.Errorf("probed %v after a credential failure", probes.probed)
+	}
+	if statuses[checkPricing] != types.DoctorPass {
+		t.Errorf("%s = %s, want pass with live pricing enabled", checkPricing, statuses[checkPricing])
+	}
+}
+
+func TestDoctorExplainsPartitionWithoutPricingAPI(t *testing.T) {
+	var regions []string
+	doctor := newTestDoctor(&fakeIdentity{}, &fakeProbeClient{}, nil, &regions)
+	doctor.settings.PricingUnavailable = "the Pricing API is not available in the AWS GovCloud (US) partition"
+	if check := doctor.checkPricing(context.Background()); check.Status != types.DoctorSkip || check.Detail != doctor.settings.PricingUnavailable {
+		t.Errorf("checkPricing() = %+v, want skipped with the partition reason", check)
+	}
+}
+
+func TestDoctorWarnsAboutSettings(t *testing.T) {
+	var regions []string
+	doctor := newTestDoctor(&fakeIdentity{}, &fakeProbeClient{}, nil, &regions)
+	doctor.settings = DoctorSettings{RateLimit: 5000, BucketConcurrency: 10, OpConcurrency: 100}
+	if check := doctor.checkSettings(); check.Status != types.DoctorWarn || !strings.Contains(check.Detail, "rate limit") {
+		t.Errorf("checkSettings() = %+v, want a warning about the rate limit", check)
+	}
+
+	doctor.settings = DoctorSettings{RateLimit: 2, BucketConcurrency: 10, OpConcurrency: 100}
+	check := doctor.checkSettings()
+	if check.Status != types.DoctorWarn || !strings.Contains(check.Detail, "--op-concurrency 100") || strings.Contains(check.Detail, "--bucket-concurrency") {
+		t.Errorf("checkSettings() = %+v, want a warning about --op-concurrency only", check)
+	}
+}
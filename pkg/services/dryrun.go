@@ -2,15 +2,19 @@ package services
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Garvitkul/s3mpc/pkg/interfaces"
 	"github.com/Garvitkul/s3mpc/pkg/types"
+	"github.com/Garvitkul/s3mpc/pkg/units"
 )
 
 // DryRunService implements the interfaces.DryRunService interface
@@ -32,21 +36,40 @@ func (d *DryRunService) SimulateDeletion(ctx context.Context, uploads []types.Mu
 	}
 
 	// Filter uploads based on options (same logic as actual deletion)
-	filteredUploads := d.filterUploadsForDeletion(uploads, opts)
+	now := time.Now()
+	clauses := deletionClauses(opts, now)
+	matchingUploads, excludedServiceInitiated := selectUploads(uploads, clauses, opts.IncludeServiceInitiated)
+	matchingUploads, autoExpiring := excludeAutoExpiring(matchingUploads, opts, now)
+	// Saved reports are diffed in review, so the uploads are oldest first
+	// whatever order they were listed in
+	filteredUploads := sortOldestFirst(limitDeletions(matchingUploads, opts.MaxDeletes))
+	// Each upload is priced like the totals, so saved rows add up to the savings
+	filteredUploads = EstimateUploadCosts(ctx, d.costCalculator, filteredUploads)
 
 	// Calculate cost savings
-	estimatedSavings, err := d.costCalculator.EstimateSavings(ctx, filteredUploads)
+	estimatedSavings := 0.0
+	currency := "USD"
+	if breakdown, err := d.costCalculator.CalculateStorageCost(ctx, filteredUploads); err == nil {
+		estimatedSavings = breakdown.TotalMonthlyCost
+		if breakdown.Currency != "" {
+			currency = breakdown.Currency
+		}
+	}
+	// If cost calculation fails, continue with 0 savings
+
+	alreadyWasted, err := d.costCalculator.CalculateAccruedCost(ctx, filteredUploads)
 	if err != nil {
-		// If cost calculation fails, continue with 0 savings
-		estimatedSavings = 0
+		alreadyWasted = 0
 	}
 
 	// Generate breakdown statistics
 	result := types.DryRunResult{
+		SchemaVersion:         types.SchemaVersion,
 		TotalUploads:          len(filteredUploads),
 		TotalSize:             d.calculateTotalSize(filteredUploads),
 		EstimatedSavings:      estimatedSavings,
-		Currency:              "USD",
+		AlreadyWasted:         alreadyWasted,
+		Currency:              currency,
 		UploadsByBucket:       make(map[string]int),
 		SizeByBucket:          make(map[string]int64),
 		SavingsByBucket:       make(map[string]float64),
@@ -60,6 +83,14 @@ func (d *DryRunService) SimulateDeletion(ctx context.Context, uploads []types.Mu
 		GeneratedAt:           time.Now(),
 		Command:               d.buildCommandString(opts),
 		Filters:               d.buildFilterString(opts),
+		ExcludedServiceInitiated: excludedServiceInitiated,
+		SizesMeasuredAt:       types.OldestMeasurement(filteredUploads),
+		Funnel:                d.filterFunnel(uploads, clauses, opts.IncludeServiceInitiated),
+		ExcludedAutoExpiring:  summarizeAutoExpiring(autoExpiring),
+		TagsByBucket:          types.TagsByBucket(filteredUploads),
+	}
+	if len(filteredUploads) < len(matchingUploads) {
+		result.MatchingUploads = len(matchingUploads)
 	}
 
 	// Calculate breakdowns
@@ -113,37 +144,302 @@ func (d *DryRunService) GenerateFilename(command string, format string) string {
 	return fmt.Sprintf("s3mpc_%s_dryrun_%s.%s", sanitizedCommand, timestamp, format)
 }
 
-// filterUploadsForDeletion filters uploads based on delete options
-func (d *DryRunService) filterUploadsForDeletion(uploads []types.MultipartUpload, opts types.DeleteOptions) []types.MultipartUpload {
-	var filtered []types.MultipartUpload
+// deletionClause is one condition of a deletion, named as in the Filters string
+type deletionClause struct {
+	name    string
+	matches func(types.MultipartUpload) bool
+}
+
+// deletionClauses returns the conditions set in opts, as of now, in the order
+// they appear in the Filters string. The service-initiated exclusion is not a
+// clause, since it is not a filter the user asked for.
+func deletionClauses(opts types.DeleteOptions, now time.Time) []deletionClause {
+	var clauses []deletionClause
+
+	if opts.OlderThan != nil {
+		olderThan := *opts.OlderThan
+		clauses = append(clauses, deletionClause{fmt.Sprintf("age>%s", units.FormatDuration(olderThan)), func(upload types.MultipartUpload) bool {
+			return now.Sub(upload.Initiated) >= olderThan
+		}})
+	}
+
+	if opts.InitiatedBefore != nil {
+		before := *opts.InitiatedBefore
+		clauses = append(clauses, deletionClause{fmt.Sprintf("initiated<%s", types.FormatTimestamp(before)), func(upload types.MultipartUpload) bool {
+			return upload.Initiated.Before(before)
+		}})
+	}
+
+	if opts.NewerThan != nil {
+		newerThan := *opts.NewerThan
+		clauses = append(clauses, deletionClause{fmt.Sprintf("age<%s", units.FormatDuration(newerThan)), func(upload types.MultipartUpload) bool {
+			return now.Sub(upload.Initiated) <= newerThan
+		}})
+	}
+
+	if opts.InitiatedAfter != nil {
+		after := *opts.InitiatedAfter
+		clauses = append(clauses, deletionClause{fmt.Sprintf("initiated>=%s", types.FormatTimestamp(after)), func(upload types.MultipartUpload) bool {
+			return !upload.Initiated.Before(after)
+		}})
+	}
+
+	if opts.SmallerThan != nil {
+		smallerThan := *opts.SmallerThan
+		clauses = append(clauses, deletionClause{fmt.Sprintf("size<%s", filterBytes(smallerThan)), func(upload types.MultipartUpload) bool {
+			return upload.Size < smallerThan
+		}})
+	}
+
+	if opts.LargerThan != nil {
+		largerThan := *opts.LargerThan
+		clauses = append(clauses, deletionClause{fmt.Sprintf("size>%s", filterBytes(largerThan)), func(upload types.MultipartUpload) bool {
+			return upload.Size > largerThan
+		}})
+	}
+
+	if opts.BucketName != "" {
+		bucket := opts.BucketName
+		clauses = append(clauses, deletionClause{fmt.Sprintf("bucket=%s", bucket), func(upload types.MultipartUpload) bool {
+			return upload.Bucket == bucket
+		}})
+	}
+
+	if tags := opts.BucketTags; !tags.IsEmpty() {
+		// Uploads carry the tags their bucket was selected by
+		values := tags.IncludedValues()
+		for _, key := range tags.IncludedKeys() {
+			include := &types.BucketTagFilter{}
+			for _, value := range values[key] {
+				include.Include = append(include.Include, types.BucketTag{Key: key, Value: value})
+			}
+			clauses = append(clauses, deletionClause{fmt.Sprintf("tag.%s=%s", key, strings.Join(values[key], "|")), func(upload types.MultipartUpload) bool {
+				return include.Matches(upload.BucketTags)
+			}})
+		}
+		for _, tag := range tags.Exclude {
+			exclude := &types.BucketTagFilter{Exclude: []types.BucketTag{tag}}
+			clauses = append(clauses, deletionClause{fmt.Sprintf("tag.%s!=%s", tag.Key, tag.Value), func(upload types.MultipartUpload) bool {
+				return exclude.Matches(upload.BucketTags)
+			}})
+		}
+	}
+
+	if opts.KeyPrefix != "" {
+		prefix := opts.KeyPrefix
+		clauses = append(clauses, deletionClause{fmt.Sprintf("prefix=%s", types.DisplayString(prefix)), func(upload types.MultipartUpload) bool {
+			return strings.HasPrefix(upload.Key, prefix)
+		}})
+	}
+
+	if len(opts.StorageClasses) > 0 {
+		storageClasses := make(map[string]bool)
+		for _, storageClass := range opts.StorageClasses {
+			storageClasses[types.NormalizeStorageClass(storageClass)] = true
+		}
+		clauses = append(clauses, deletionClause{fmt.Sprintf("storageClass=%s", strings.Join(opts.StorageClasses, "|")), func(upload types.MultipartUpload) bool {
+			return storageClasses[types.NormalizeStorageClass(upload.StorageClass)]
+		}})
+	}
+
+	if key := opts.KeySuffix; !key.IsEmpty() {
+		sensitivity := ""
+		if key.CaseSensitive {
+			sensitivity = " (case-sensitive)"
+		}
+		if len(key.Include) > 0 {
+			include := &types.KeySuffixFilter{Include: key.Include, CaseSensitive: key.CaseSensitive}
+			clauses = append(clauses, deletionClause{fmt.Sprintf("key$=%s%s", strings.Join(key.Include, "|"), sensitivity), func(upload types.MultipartUpload) bool {
+				return include.Matches(upload.Key)
+			}})
+		}
+		if len(key.Exclude) > 0 {
+			exclude := &types.KeySuffixFilter{Exclude: key.Exclude, CaseSensitive: key.CaseSensitive}
+			clauses = append(clauses, deletionClause{fmt.Sprintf("key!$=%s%s", strings.Join(key.Exclude, "|"), sensitivity), func(upload types.MultipartUpload) bool {
+				return exclude.Matches(upload.Key)
+			}})
+		}
+	}
+
+	// The filter expression is one clause, named as it was written
+	if opts.FilterMatch != nil {
+		clauses = append(clauses, deletionClause{opts.Filter, opts.FilterMatch})
+	}
+
+	return clauses
+}
 
+// filterUploadsForDeletion filters uploads based on delete options and returns
+// the number of matching service-initiated uploads that were excluded
+func (d *DryRunService) filterUploadsForDeletion(uploads []types.MultipartUpload, opts types.DeleteOptions) ([]types.MultipartUpload, int) {
+	return selectForDeletion(uploads, opts)
+}
+
+// selectForDeletion returns the uploads matching the delete options and the
+// number of matching service-initiated uploads left out. Deletions and dry-runs
+// both select with it, so a dry-run shows what a deletion would abort.
+func selectForDeletion(uploads []types.MultipartUpload, opts types.DeleteOptions) ([]types.MultipartUpload, int) {
+	return selectUploads(uploads, deletionClauses(opts, time.Now()), opts.IncludeServiceInitiated)
+}
+
+// excludeAutoExpiring splits uploads into those to delete and those a
+// lifecycle rule will abort within the --skip-auto-expiring window. Uploads
+// whose abort date is unknown are kept.
+func excludeAutoExpiring(uploads []types.MultipartUpload, opts types.DeleteOptions, now time.Time) ([]types.MultipartUpload, []types.MultipartUpload) {
+	if !opts.SkipAutoExpiring {
+		return uploads, nil
+	}
+
+	var kept, excluded []types.MultipartUpload
 	for _, upload := range uploads {
-		// Filter by bucket if specified
-		if opts.BucketName != "" && upload.Bucket != opts.BucketName {
+		if upload.AbortDate != nil && (opts.AutoExpiringWithin == 0 || !upload.AbortDate.After(now.Add(opts.AutoExpiringWithin))) {
+			excluded = append(excluded, upload)
 			continue
 		}
+		kept = append(kept, upload)
+	}
+	return kept, excluded
+}
 
-		// Filter by age if specified
-		if opts.OlderThan != nil {
-			age := time.Since(upload.Initiated)
-			if age < *opts.OlderThan {
-				continue
-			}
+// summarizeAutoExpiring totals uploads left to lifecycle rules by bucket and
+// rule, soonest abort first
+func summarizeAutoExpiring(uploads []types.MultipartUpload) []types.AutoExpiringUploads {
+	type ruleKey struct{ bucket, rule string }
+	byRule := make(map[ruleKey]*types.AutoExpiringUploads)
+	var summaries []*types.AutoExpiringUploads
+	for _, upload := range uploads {
+		key := ruleKey{upload.Bucket, upload.AbortRuleID}
+		summary, ok := byRule[key]
+		if !ok {
+			summary = &types.AutoExpiringUploads{Bucket: upload.Bucket, RuleID: upload.AbortRuleID, FirstAbortDate: *upload.AbortDate}
+			byRule[key] = summary
+			summaries = append(summaries, summary)
 		}
+		summary.Uploads++
+		summary.Size += upload.Size
+		if upload.AbortDate.Before(summary.FirstAbortDate) {
+			summary.FirstAbortDate = *upload.AbortDate
+		}
+	}
 
-		// Filter by size if specified
-		if opts.SmallerThan != nil && upload.Size >= *opts.SmallerThan {
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].FirstAbortDate.Before(summaries[j].FirstAbortDate)
+	})
+	result := make([]types.AutoExpiringUploads, len(summaries))
+	for i, summary := range summaries {
+		result[i] = *summary
+	}
+	return result
+}
+
+// limitDeletions returns the oldest maxDeletes uploads, or all of them in their
+// listed order when there are no more than that or maxDeletes is 0. Ties are
+// broken by bucket, key and upload ID, so a dry-run and the deletion after it
+// pick the same uploads.
+func limitDeletions(uploads []types.MultipartUpload, maxDeletes int) []types.MultipartUpload {
+	if maxDeletes <= 0 || len(uploads) <= maxDeletes {
+		return uploads
+	}
+
+	return sortOldestFirst(uploads)[:maxDeletes]
+}
+
+// sortOldestFirst returns a copy of uploads sorted by when they were
+// initiated, then by bucket, key and upload ID
+func sortOldestFirst(uploads []types.MultipartUpload) []types.MultipartUpload {
+	oldest := make([]types.MultipartUpload, len(uploads))
+	copy(oldest, uploads)
+	sort.Slice(oldest, func(i, j int) bool {
+		a, b := oldest[i], oldest[j]
+		if !a.Initiated.Equal(b.Initiated) {
+			return a.Initiated.Before(b.Initiated)
+		}
+		if a.Bucket != b.Bucket {
+			return a.Bucket < b.Bucket
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.UploadID < b.UploadID
+	})
+	return oldest
+}
+
+// selectUploads returns the uploads matching every clause, leaving out
+// service-initiated ones unless includeServiceInitiated is set, and the number
+// of matching service-initiated uploads left out
+func selectUploads(uploads []types.MultipartUpload, clauses []deletionClause, includeServiceInitiated bool) ([]types.MultipartUpload, int) {
+	var filtered []types.MultipartUpload
+	excludedServiceInitiated := 0
+
+	for _, upload := range uploads {
+		if !matchesAllClauses(upload, clauses) {
 			continue
 		}
 
-		if opts.LargerThan != nil && upload.Size <= *opts.LargerThan {
+		if upload.ServiceInitiated && !includeServiceInitiated {
+			excludedServiceInitiated++
 			continue
 		}
 
 		filtered = append(filtered, upload)
 	}
 
-	return filtered
+	return filtered, excludedServiceInitiated
+}
+
+// matchesAllClauses reports whether an upload matches every clause
+func matchesAllClauses(upload types.MultipartUpload, clauses []deletionClause) bool {
+	for _, clause := range clauses {
+		if !clause.matches(upload) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterFunnel applies the clauses to the candidate uploads one after another,
+// with the service-initiated exclusion last, and records what is left after
+// each step and what each step matches on its own. It returns nil when the
+// deletion has no clauses.
+func (d *DryRunService) filterFunnel(uploads []types.MultipartUpload, clauses []deletionClause, includeServiceInitiated bool) *types.FilterFunnel {
+	if len(clauses) == 0 {
+		return nil
+	}
+	if !includeServiceInitiated {
+		// Cap the capacity so the caller's clauses are never appended to
+		clauses = append(clauses[:len(clauses):len(clauses)], deletionClause{"initiatedBy=user", func(upload types.MultipartUpload) bool {
+			return !upload.ServiceInitiated
+		}})
+	}
+
+	funnel := &types.FilterFunnel{
+		Uploads: len(uploads),
+		Size:    d.calculateTotalSize(uploads),
+		Steps:   make([]types.FunnelStep, len(clauses)),
+	}
+	for i, clause := range clauses {
+		funnel.Steps[i].Clause = clause.name
+	}
+
+	for _, upload := range uploads {
+		remaining := true
+		for i, clause := range clauses {
+			step := &funnel.Steps[i]
+			matched := clause.matches(upload)
+			if matched {
+				step.Matched++
+				step.MatchedSize += upload.Size
+			}
+			remaining = remaining && matched
+			if remaining {
+				step.Uploads++
+				step.Size += upload.Size
+			}
+		}
+	}
+
+	return funnel
 }
 
 // calculateTotalSize calculates the total size of uploads
@@ -170,6 +466,11 @@ func (d *DryRunService) calculateBreakdowns(ctx context.Context, uploads []types
 		// By storage class
 		result.UploadsByStorageClass[upload.StorageClass]++
 		result.SizeByStorageClass[upload.StorageClass] += upload.Size
+
+		if upload.Superseded {
+			result.SupersededUploads++
+			result.SupersededSize += upload.Size
+		}
 	}
 
 	// Calculate cost savings breakdowns
@@ -213,22 +514,89 @@ func (d *DryRunService) calculateCostBreakdowns(ctx context.Context, uploads []t
 
 // buildCommandString builds a command string representation
 func (d *DryRunService) buildCommandString(opts types.DeleteOptions) string {
+	return deleteCommandString(opts)
+}
+
+// deleteCommandString writes delete options as the flags that select them
+func deleteCommandString(opts types.DeleteOptions) string {
 	parts := []string{"delete"}
 
 	if opts.BucketName != "" {
 		parts = append(parts, fmt.Sprintf("-b %s", opts.BucketName))
 	}
 
+	if opts.KeyPrefix != "" {
+		parts = append(parts, fmt.Sprintf("--prefix %s", opts.KeyPrefix))
+	}
+
+	if tags := opts.BucketTags; !tags.IsEmpty() {
+		for _, tag := range tags.Include {
+			parts = append(parts, fmt.Sprintf("--bucket-tag %s", tag))
+		}
+		for _, tag := range tags.Exclude {
+			parts = append(parts, fmt.Sprintf("--exclude-bucket-tag %s", tag))
+		}
+	}
+
 	if opts.OlderThan != nil {
-		parts = append(parts, fmt.Sprintf("--older-than %s", d.formatDuration(*opts.OlderThan)))
+		parts = append(parts, fmt.Sprintf("--older-than %s", units.FormatDuration(*opts.OlderThan)))
+	}
+
+	if opts.InitiatedBefore != nil {
+		parts = append(parts, fmt.Sprintf("--older-than %s", types.FormatCutoff(*opts.InitiatedBefore)))
+	}
+
+	if opts.NewerThan != nil {
+		parts = append(parts, fmt.Sprintf("--newer-than %s", units.FormatDuration(*opts.NewerThan)))
+	}
+
+	if opts.InitiatedAfter != nil {
+		parts = append(parts, fmt.Sprintf("--newer-than %s", types.FormatCutoff(*opts.InitiatedAfter)))
 	}
 
 	if opts.SmallerThan != nil {
-		parts = append(parts, fmt.Sprintf("--smaller-than %s", d.formatBytes(*opts.SmallerThan)))
+		parts = append(parts, fmt.Sprintf("--smaller-than %s", filterBytes(*opts.SmallerThan)))
 	}
 
 	if opts.LargerThan != nil {
-		parts = append(parts, fmt.Sprintf("--larger-than %s", d.formatBytes(*opts.LargerThan)))
+		parts = append(parts, fmt.Sprintf("--larger-than %s", filterBytes(*opts.LargerThan)))
+	}
+
+	if len(opts.StorageClasses) > 0 {
+		parts = append(parts, fmt.Sprintf("--storage-class %s", strings.Join(opts.StorageClasses, ",")))
+	}
+
+	if key := opts.KeySuffix; !key.IsEmpty() {
+		if len(key.Include) > 0 {
+			parts = append(parts, fmt.Sprintf("--suffix %s", strings.Join(key.Include, ",")))
+		}
+		if len(key.Exclude) > 0 {
+			parts = append(parts, fmt.Sprintf("--exclude-suffix %s", strings.Join(key.Exclude, ",")))
+		}
+		if key.CaseSensitive {
+			parts = append(parts, "--case-sensitive")
+		}
+	}
+
+	if opts.Filter != "" {
+		// Filters are full of characters the shell interprets, such as > and spaces
+		parts = append(parts, fmt.Sprintf("--filter '%s'", strings.ReplaceAll(opts.Filter, "'", `'\''`)))
+	}
+
+	if opts.IncludeServiceInitiated {
+		parts = append(parts, "--include-service-initiated")
+	}
+
+	if opts.MaxDeletes > 0 {
+		parts = append(parts, fmt.Sprintf("--max-deletes %d", opts.MaxDeletes))
+	}
+
+	if opts.SkipAutoExpiring {
+		if opts.AutoExpiringWithin > 0 {
+			parts = append(parts, fmt.Sprintf("--skip-auto-expiring=%s", units.FormatDuration(opts.AutoExpiringWithin)))
+		} else {
+			parts = append(parts, "--skip-auto-expiring")
+		}
 	}
 
 	if opts.Force {
@@ -244,66 +612,44 @@ func (d *DryRunService) buildCommandString(opts types.DeleteOptions) string {
 
 // buildFilterString builds a filter string representation
 func (d *DryRunService) buildFilterString(opts types.DeleteOptions) string {
-	var filters []string
-
-	if opts.OlderThan != nil {
-		filters = append(filters, fmt.Sprintf("age>%s", d.formatDuration(*opts.OlderThan)))
-	}
-
-	if opts.SmallerThan != nil {
-		filters = append(filters, fmt.Sprintf("size<%s", d.formatBytes(*opts.SmallerThan)))
-	}
-
-	if opts.LargerThan != nil {
-		filters = append(filters, fmt.Sprintf("size>%s", d.formatBytes(*opts.LargerThan)))
-	}
+	return deleteFilterString(opts)
+}
 
-	if opts.BucketName != "" {
-		filters = append(filters, fmt.Sprintf("bucket=%s", opts.BucketName))
+// deleteFilterString lists the conditions of delete options, comma-separated
+func deleteFilterString(opts types.DeleteOptions) string {
+	var filters []string
+	for _, clause := range deletionClauses(opts, time.Now()) {
+		filters = append(filters, clause.name)
 	}
-
 	return strings.Join(filters, ",")
 }
 
-// formatDuration formats a duration for display
-func (d *DryRunService) formatDuration(duration time.Duration) string {
-	days := int(duration.Hours() / 24)
-	if days > 0 {
-		return fmt.Sprintf("%dd", days)
-	}
-	
-	hours := int(duration.Hours())
-	if hours > 0 {
-		return fmt.Sprintf("%dh", hours)
-	}
-	
-	minutes := int(duration.Minutes())
-	if minutes > 0 {
-		return fmt.Sprintf("%dm", minutes)
-	}
-	
-	return fmt.Sprintf("%ds", int(duration.Seconds()))
+
+// filterBytes formats a size as written in delete flags and filter strings,
+// without the space so that it stays one argument
+func filterBytes(bytes int64) string {
+	return strings.ReplaceAll(units.FormatBytes(bytes), " ", "")
 }
 
-// formatBytes formats bytes for display
-func (d *DryRunService) formatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%dB", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f%cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+// dryRunCSVHeader is the header of saved dry-run CSV reports
+var dryRunCSVHeader = []string{
+	"bucket",
+	"key",
+	"upload_id",
+	"initiated",
+	"age_days",
+	"size",
+	"measured_at",
+	"storage_class",
+	"region",
+	"estimated_monthly_cost",
 }
 
 // saveAsJSON saves the result as JSON
 func (d *DryRunService) saveAsJSON(result types.DryRunResult, filename string) error {
-	file, err := os.Create(filename)
+	file, err := createExportFile(filename)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filename, err)
+		return err
 	}
 	defer file.Close()
 
@@ -314,57 +660,49 @@ func (d *DryRunService) saveAsJSON(result types.DryRunResult, filename string) e
 		return fmt.Errorf("failed to encode JSON: %w", err)
 	}
 
-	return nil
+	return file.Close()
 }
 
 // saveAsCSV saves the result as CSV
 func (d *DryRunService) saveAsCSV(result types.DryRunResult, filename string) error {
-	file, err := os.Create(filename)
+	file, err := createExportFile(filename)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filename, err)
+		return err
 	}
 	defer file.Close()
 
-	// Write CSV header
-	header := "bucket,key,upload_id,initiated,age_days,size,storage_class,region,estimated_monthly_cost\n"
-	if _, err := file.WriteString(header); err != nil {
+	writer := csv.NewWriter(file)
+	if err := writer.Write(dryRunCSVHeader); err != nil {
 		return fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
-	// Write upload data
 	for _, upload := range result.Uploads {
 		ageDays := int(time.Since(upload.Initiated).Hours() / 24)
-		
-		// Calculate individual upload cost (simplified)
-		sizeGB := float64(upload.Size) / (1024 * 1024 * 1024)
-		estimatedCost := sizeGB * 0.023 // Use default STANDARD pricing
-		
-		line := fmt.Sprintf("%s,%s,%s,%s,%d,%d,%s,%s,%.6f\n",
-			d.escapeCSV(upload.Bucket),
-			d.escapeCSV(upload.Key),
-			d.escapeCSV(upload.UploadID),
-			upload.Initiated.Format("2006-01-02T15:04:05Z"),
-			ageDays,
-			upload.Size,
-			d.escapeCSV(upload.StorageClass),
-			d.escapeCSV(upload.Region),
-			estimatedCost,
-		)
-		
-		if _, err := file.WriteString(line); err != nil {
-			return fmt.Errorf("failed to write CSV line: %w", err)
+
+		measuredAt := ""
+		if !upload.MeasuredAt.IsZero() {
+			measuredAt = types.FormatTimestamp(upload.MeasuredAt)
 		}
-	}
 
-	return nil
-}
+		if err := writer.Write([]string{
+			upload.Bucket,
+			upload.Key,
+			upload.UploadID,
+			types.FormatTimestamp(upload.Initiated),
+			strconv.Itoa(ageDays),
+			strconv.FormatInt(upload.Size, 10),
+			measuredAt,
+			upload.StorageClass,
+			upload.Region,
+			strconv.FormatFloat(upload.EstimatedMonthlyCost, 'f', 6, 64),
+		}); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
 
-// escapeCSV escapes CSV values that contain commas or quotes
-func (d *DryRunService) escapeCSV(value string) string {
-	if strings.Contains(value, ",") || strings.Contains(value, "\"") || strings.Contains(value, "\n") {
-		// Escape quotes by doubling them and wrap in quotes
-		escaped := strings.ReplaceAll(value, "\"", "\"\"")
-		return fmt.Sprintf("\"%s\"", escaped)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
 	}
-	return value
-}
\ No newline at end of file
+	return file.Close()
+}
@@ -0,0 +1,529 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/filter"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestOlderThanDateCutoff(t *testing.T) {
+	cutoff := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	uploads := []types.MultipartUpload{
+		{Bucket: "b", Key: "before", UploadID: "1", Initiated: cutoff.Add(-time.Nanosecond)},
+		{Bucket: "b", Key: "midnight", UploadID: "2", Initiated: cutoff},
+		{Bucket: "b", Key: "after", UploadID: "3", Initiated: cutoff.Add(time.Second)},
+	}
+	opts := types.DeleteOptions{InitiatedBefore: &cutoff}
+
+	dryRun := &DryRunService{}
+	simulated, _ := dryRun.filterUploadsForDeletion(uploads, opts)
+	deleted, _ := (&UploadService{}).filterUploadsForDeletion(uploads, opts)
+
+	for name, filtered := range map[string][]types.MultipartUpload{"dry-run": simulated, "delete": deleted} {
+		if len(filtered) != 1 || filtered[0].Key != "before" {
+			t.Errorf("%s selected %v, want only the upload initiated before midnight UTC", name, filtered)
+		}
+	}
+
+	if got, want := dryRun.buildCommandString(opts), "delete --older-than 2024-05-01"; got != want {
+		t.Errorf("buildCommandString() = %q, want %q", got, want)
+	}
+	if got, want := dryRun.buildFilterString(opts), "initiated<2024-05-01T00:00:00Z"; got != want {
+		t.Errorf("buildFilterString() = %q, want %q", got, want)
+	}
+}
+
+func TestAgeWindowWithSizeFilters(t *testing.T) {
+	june10 := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+	june12 := time.Date(2024, 6, 12, 0, 0, 0, 0, time.UTC)
+	smallerThan, largerThan := int64(100<<20), int64(1<<20)
+
+	uploads := []types.MultipartUpload{
+		{Bucket: "b", Key: "in-window", UploadID: "1", Initiated: june10.Add(time.Hour), Size: 10 << 20},
+		{Bucket: "b", Key: "window-start", UploadID: "2", Initiated: june10, Size: 10 << 20},
+		{Bucket: "b", Key: "before-window", UploadID: "3", Initiated: june10.Add(-time.Second), Size: 10 << 20},
+		{Bucket: "b", Key: "window-end", UploadID: "4", Initiated: june12, Size: 10 << 20},
+		{Bucket: "b", Key: "too-large", UploadID: "5", Initiated: june10.Add(time.Hour), Size: 200 << 20},
+		{Bucket: "b", Key: "too-small", UploadID: "6", Initiated: june10.Add(time.Hour), Size: 1 << 10},
+	}
+
+	dryRun := &DryRunService{}
+	check := func(name string, opts types.DeleteOptions, want ...string) {
+		t.Helper()
+		if err := opts.Validate(); err != nil {
+			t.Fatalf("%s: Validate() error = %v", name, err)
+		}
+		simulated, _ := dryRun.filterUploadsForDeletion(uploads, opts)
+		deleted, _ := (&UploadService{}).filterUploadsForDeletion(uploads, opts)
+		for flow, filtered := range map[string][]types.MultipartUpload{"dry-run": simulated, "delete": deleted} {
+			var keys []string
+			for _, upload := range filtered {
+				keys = append(keys, upload.Key)
+			}
+			if strings.Join(keys, ",") != strings.Join(want, ",") {
+				t.Errorf("%s: %s selected %v, want %v", name, flow, keys, want)
+			}
+		}
+	}
+
+	dates := types.DeleteOptions{InitiatedAfter: &june10, InitiatedBefore: &june12, SmallerThan: &smallerThan, LargerThan: &largerThan}
+	check("dates", dates, "in-window", "window-start")
+
+	if got, want := dryRun.buildCommandString(dates), "delete --older-than 2024-06-12 --newer-than 2024-06-10 --smaller-than 100.0MB --larger-than 1.0MB"; got != want {
+		t.Errorf("buildCommandString() = %q, want %q", got, want)
+	}
+	if got, want := dryRun.buildFilterString(dates), "initiated<2024-06-12T00:00:00Z,initiated>=2024-06-10T00:00:00Z,size<100.0MB,size>1.0MB"; got != want {
+		t.Errorf("buildFilterString() = %q, want %q", got, want)
+	}
+
+	// The same window as durations relative to now
+	now := time.Now()
+	recent := []types.MultipartUpload{
+		{Bucket: "b", Key: "two-days", UploadID: "1", Initiated: now.Add(-48 * time.Hour), Size: 10 << 20},
+		{Bucket: "b", Key: "ten-days", UploadID: "2", Initiated: now.Add(-240 * time.Hour), Size: 10 << 20},
+		{Bucket: "b", Key: "one-hour", UploadID: "3", Initiated: now.Add(-time.Hour), Size: 10 << 20},
+	}
+	uploads = recent
+	day, week := 24*time.Hour, 7*24*time.Hour
+	durations := types.DeleteOptions{OlderThan: &day, NewerThan: &week, LargerThan: &largerThan}
+	check("durations", durations, "two-days")
+	if got, want := dryRun.buildFilterString(durations), "age>1d,age<7d,size>1.0MB"; got != want {
+		t.Errorf("buildFilterString() = %q, want %q", got, want)
+	}
+
+	// Minutes are written so the filter string parses as a --filter again
+	minutes := 30 * time.Minute
+	filterStr := dryRun.buildFilterString(types.DeleteOptions{OlderThan: &minutes})
+	if filterStr != "age>30min" {
+		t.Errorf("buildFilterString() = %q, want age>30min", filterStr)
+	}
+	if _, err := filter.NewEngine().ParseFilter(filterStr); err != nil {
+		t.Errorf("ParseFilter(%q) error = %v", filterStr, err)
+	}
+}
+
+func TestKeySuffixDeleteOptions(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "b", Key: "backup.tmp", UploadID: "1"},
+		{Bucket: "b", Key: "backup.TMP", UploadID: "2"},
+		{Bucket: "b", Key: "backup.tmp.gz", UploadID: "3"},
+		{Bucket: "b", Key: "movie.partial", UploadID: "4"},
+		{Bucket: "b", Key: "keep.partial", UploadID: "5"},
+	}
+	opts := types.DeleteOptions{KeySuffix: &types.KeySuffixFilter{Include: []string{".tmp", ".partial"}, Exclude: []string{"keep.partial"}}}
+
+	dryRun := &DryRunService{}
+	for _, caseSensitive := range []bool{false, true} {
+		opts.KeySuffix.CaseSensitive = caseSensitive
+		want := "backup.tmp,backup.TMP,movie.partial"
+		if caseSensitive {
+			want = "backup.tmp,movie.partial"
+		}
+
+		simulated, _ := dryRun.filterUploadsForDeletion(uploads, opts)
+		deleted, _ := (&UploadService{}).filterUploadsForDeletion(uploads, opts)
+		for flow, filtered := range map[string][]types.MultipartUpload{"dry-run": simulated, "delete": deleted} {
+			var keys []string
+			for _, upload := range filtered {
+				keys = append(keys, upload.Key)
+			}
+			if strings.Join(keys, ",") != want {
+				t.Errorf("case-sensitive %v: %s selected %v, want %s", caseSensitive, flow, keys, want)
+			}
+		}
+	}
+
+	if got, want := dryRun.buildCommandString(opts), "delete --suffix .tmp,.partial --exclude-suffix keep.partial --case-sensitive"; got != want {
+		t.Errorf("buildCommandString() = %q, want %q", got, want)
+	}
+	if got, want := dryRun.buildFilterString(opts), "key$=.tmp|.partial (case-sensitive),key!$=keep.partial (case-sensitive)"; got != want {
+		t.Errorf("buildFilterString() = %q, want %q", got, want)
+	}
+}
+
+func TestBucketTagDeleteOptions(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a", UploadID: "1", BucketTags: map[string]string{"env": "prod"}},
+		{Bucket: "vault", Key: "b", UploadID: "2", BucketTags: map[string]string{"env": "prod", "protected": "true"}},
+		{Bucket: "saved", Key: "c", UploadID: "3"}, // not selected by tag
+	}
+	opts := types.DeleteOptions{BucketTags: &types.BucketTagFilter{
+		Include: []types.BucketTag{{Key: "env", Value: "prod"}, {Key: "env", Value: "staging"}},
+		Exclude: []types.BucketTag{{Key: "protected", Value: "true"}},
+	}}
+
+	dryRun := &DryRunService{}
+	filtered, _ := dryRun.filterUploadsForDeletion(uploads, opts)
+	if len(filtered) != 1 || filtered[0].Bucket != "logs" {
+		t.Errorf("filterUploadsForDeletion() = %+v, want only the upload in logs", filtered)
+	}
+	if got, want := dryRun.buildCommandString(opts), "delete --bucket-tag env=prod --bucket-tag env=staging --exclude-bucket-tag protected=true"; got != want {
+		t.Errorf("buildCommandString() = %q, want %q", got, want)
+	}
+	if got, want := dryRun.buildFilterString(opts), "tag.env=prod|staging,tag.protected!=true"; got != want {
+		t.Errorf("buildFilterString() = %q, want %q", got, want)
+	}
+	if got := types.TagsByBucket(filtered); len(got) != 1 || got["logs"]["env"] != "prod" {
+		t.Errorf("TagsByBucket() = %v, want the env tag of logs", got)
+	}
+}
+
+func TestStorageClassDeleteOptions(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "b", Key: "standard", UploadID: "1", StorageClass: "STANDARD"},
+		{Bucket: "b", Key: "glacier", UploadID: "2", StorageClass: "GLACIER"},
+		{Bucket: "b", Key: "infrequent", UploadID: "3", StorageClass: "STANDARD_IA"},
+		{Bucket: "b", Key: "archive", UploadID: "4", StorageClass: "DEEP_ARCHIVE"},
+	}
+	opts := types.DeleteOptions{StorageClasses: []string{"GLACIER", "standard-ia"}}
+
+	simulated, _ := (&DryRunService{}).filterUploadsForDeletion(uploads, opts)
+	deleted, _ := (&UploadService{}).filterUploadsForDeletion(uploads, opts)
+	for flow, filtered := range map[string][]types.MultipartUpload{"dry-run": simulated, "delete": deleted} {
+		var keys []string
+		for _, upload := range filtered {
+			keys = append(keys, upload.Key)
+		}
+		if strings.Join(keys, ",") != "glacier,infrequent" {
+			t.Errorf("%s selected %v, want glacier and infrequent", flow, keys)
+		}
+	}
+
+	if got, want := deleteCommandString(opts), "delete --storage-class GLACIER,standard-ia"; got != want {
+		t.Errorf("deleteCommandString() = %q, want %q", got, want)
+	}
+}
+
+func TestFilterDeleteOption(t *testing.T) {
+	month := 30 * 24 * time.Hour
+	old := time.Now().Add(-2 * month)
+	uploads := []types.MultipartUpload{
+		{Bucket: "raw", Key: "a", UploadID: "1", Initiated: old, PartsCount: 500},
+		{Bucket: "raw", Key: "b", UploadID: "2", Initiated: old, PartsCount: 20},
+		{Bucket: "logs", Key: "c", UploadID: "3", Initiated: old, PartsCount: 800},
+		{Bucket: "raw", Key: "d", UploadID: "4", Initiated: time.Now(), PartsCount: 900},
+	}
+	filter := "bucket in (raw, 'team b'),parts>100"
+	opts := types.DeleteOptions{
+		OlderThan: &month,
+		Filter:    filter,
+		FilterMatch: func(upload types.MultipartUpload) bool {
+			return upload.Bucket == "raw" && upload.PartsCount > 100
+		},
+	}
+
+	dryRun := &DryRunService{costCalculator: NewCostService()}
+	result, err := dryRun.SimulateDeletion(context.Background(), uploads, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, _ := (&UploadService{}).filterUploadsForDeletion(uploads, opts)
+	if result.TotalUploads != 1 || result.Uploads[0].Key != "a" || len(deleted) != 1 || deleted[0].Key != "a" {
+		t.Errorf("dry-run selected %+v and delete %+v, want only a", result.Uploads, deleted)
+	}
+	if want := "age>30d," + filter; result.Filters != want {
+		t.Errorf("Filters = %q, want %q with the filter verbatim", result.Filters, want)
+	}
+	if steps := result.Funnel.Steps; len(steps) != 3 || steps[1].Clause != filter || steps[1].Matched != 2 {
+		t.Errorf("funnel steps = %+v, want the filter as one clause matching 2 uploads", steps)
+	}
+
+	if got, want := deleteCommandString(opts), `delete --older-than 30d --filter 'bucket in (raw, '\''team b'\''),parts>100'`; got != want {
+		t.Errorf("deleteCommandString() = %q, want %q", got, want)
+	}
+}
+
+func TestSimulateDeletionReportsFilterFunnel(t *testing.T) {
+	const gb = int64(1 << 30)
+	now := time.Now()
+	old, recent := now.Add(-45*24*time.Hour), now.Add(-5*24*time.Hour)
+	uploads := []types.MultipartUpload{
+		{Bucket: "data", Key: "a", UploadID: "1", Initiated: old, Size: 2 * gb},
+		{Bucket: "data", Key: "b", UploadID: "2", Initiated: old, Size: gb / 2},
+		{Bucket: "logs", Key: "c", UploadID: "3", Initiated: old, Size: 3 * gb},
+		{Bucket: "data", Key: "d", UploadID: "4", Initiated: recent, Size: 4 * gb},
+		{Bucket: "data", Key: "e", UploadID: "5", Initiated: old, Size: 5 * gb, ServiceInitiated: true},
+		{Bucket: "logs", Key: "f", UploadID: "6", Initiated: recent, Size: gb / 4},
+	}
+	month, size := 30*24*time.Hour, gb
+	opts := types.DeleteOptions{OlderThan: &month, LargerThan: &size, BucketName: "data"}
+
+	dryRun := &DryRunService{costCalculator: NewCostService()}
+	result, err := dryRun.SimulateDeletion(context.Background(), uploads, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalUploads != 1 || result.Uploads[0].Key != "a" {
+		t.Fatalf("SimulateDeletion() selected %+v, want only a", result.Uploads)
+	}
+
+	funnel := result.Funnel
+	if funnel == nil || funnel.Uploads != 6 || funnel.Size != 15*gb-gb/4 {
+		t.Fatalf("funnel = %+v, want all 6 candidates", funnel)
+	}
+	want := []types.FunnelStep{
+		{Clause: "age>30d", Uploads: 4, Size: 10*gb + gb/2, Matched: 4, MatchedSize: 10*gb + gb/2},
+		{Clause: "size>1.0GB", Uploads: 3, Size: 10 * gb, Matched: 4, MatchedSize: 14 * gb},
+		{Clause: "bucket=data", Uploads: 2, Size: 7 * gb, Matched: 4, MatchedSize: 11*gb + gb/2},
+		{Clause: "initiatedBy=user", Uploads: 1, Size: 2 * gb, Matched: 5, MatchedSize: 10*gb - gb/4},
+	}
+	if len(funnel.Steps) != len(want) {
+		t.Fatalf("funnel steps = %+v, want %+v", funnel.Steps, want)
+	}
+	for i, step := range funnel.Steps {
+		if step != want[i] {
+			t.Errorf("step %d = %+v, want %+v", i, step, want[i])
+		}
+	}
+	if got := dryRun.buildFilterString(opts); got != "age>30d,size>1.0GB,bucket=data" {
+		t.Errorf("buildFilterString() = %q, want the funnel clauses in order", got)
+	}
+
+	// The console dry-run shows the funnel, including when nothing is left
+	var output bytes.Buffer
+	service := &UploadService{dryRunService: dryRun, outputWriter: &output}
+	opts.DryRun = true
+	if err := service.DeleteUploads(context.Background(), uploads, opts); err != nil {
+		t.Fatalf("DeleteUploads(dry-run) error = %v", err)
+	}
+	for _, line := range []string{
+		"  All uploads       6 uploads (14.8 GB)\n",
+		"  size>1.0GB        3 uploads (10.0 GB), 4 (14.0 GB) on its own\n",
+		"  initiatedBy=user  1 uploads (2.0 GB), 5 (9.8 GB) on its own\n",
+	} {
+		if !strings.Contains(output.String(), line) {
+			t.Errorf("dry-run output = %q, want line %q", output.String(), line)
+		}
+	}
+
+	output.Reset()
+	opts.BucketName = "archive"
+	if err := service.DeleteUploads(context.Background(), uploads, opts); err == nil {
+		t.Error("DeleteUploads(dry-run) matching nothing error = nil")
+	}
+	if !strings.Contains(output.String(), "  bucket=archive    0 uploads (0 B), 0 (0 B) on its own\n") {
+		t.Errorf("dry-run output = %q, want the funnel to show bucket=archive matching nothing", output.String())
+	}
+
+	if result, _ := dryRun.SimulateDeletion(context.Background(), uploads, types.DeleteOptions{}); result.Funnel != nil {
+		t.Errorf("funnel without conditions = %+v, want none", result.Funnel)
+	}
+}
+
+func TestMaxDeletesKeepsOldestUploads(t *testing.T) {
+	const gb = int64(1 << 30)
+	now := time.Now()
+	uploads := []types.MultipartUpload{
+		{Bucket: "data", Key: "new", UploadID: "1", Initiated: now.Add(-time.Hour), Size: gb, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "data", Key: "tie-b", UploadID: "2", Initiated: now.Add(-72 * time.Hour), Size: gb, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "data", Key: "oldest", UploadID: "3", Initiated: now.Add(-96 * time.Hour), Size: 2 * gb, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "data", Key: "tie-a", UploadID: "4", Initiated: now.Add(-72 * time.Hour), Size: 4 * gb, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "data", Key: "mid", UploadID: "5", Initiated: now.Add(-24 * time.Hour), Size: gb, StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+	opts := types.DeleteOptions{MaxDeletes: 3}
+
+	var keys []string
+	for _, upload := range limitDeletions(uploads, opts.MaxDeletes) {
+		keys = append(keys, upload.Key)
+	}
+	if got, want := strings.Join(keys, ","), "oldest,tie-a,tie-b"; got != want {
+		t.Errorf("limitDeletions() kept %s, want %s", got, want)
+	}
+	if got := limitDeletions(uploads, 0); len(got) != len(uploads) {
+		t.Errorf("limitDeletions(0) kept %d uploads, want all %d", len(got), len(uploads))
+	}
+
+	// The dry-run's savings are for the same capped set a real run deletes
+	dryRun := &DryRunService{costCalculator: NewCostService()}
+	result, err := dryRun.SimulateDeletion(context.Background(), uploads, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalUploads != 3 || result.TotalSize != 7*gb || result.MatchingUploads != 5 {
+		t.Errorf("SimulateDeletion() = %d uploads (%d bytes) of %d matching, want 3 (%d bytes) of 5",
+			result.TotalUploads, result.TotalSize, result.MatchingUploads, 7*gb)
+	}
+
+	var aborted int
+	var output bytes.Buffer
+	client := &mockAbortClient{onAbort: func() { aborted++ }}
+	service := &UploadService{
+		client:           client,
+		regionalClients:  map[string]S3UploadClientInterface{"us-east-1": client},
+		concurrency:      1,
+		progressReporter: NewConsoleProgressReporter(&output, true),
+		outputWriter:     &output,
+	}
+	opts.Force = true
+	if err := service.DeleteUploads(context.Background(), uploads, opts); err != nil {
+		t.Fatalf("DeleteUploads() error = %v", err)
+	}
+	if aborted != 3 {
+		t.Errorf("DeleteUploads() aborted %d uploads, want 3", aborted)
+	}
+
+	if got, want := dryRun.buildCommandString(opts), "delete --max-deletes 3 --force"; got != want {
+		t.Errorf("buildCommandString() = %q, want %q", got, want)
+	}
+	if got, want := maxDeletesSummary(12000, 500), "Matching: 12,000, will delete: 500 (limited by --max-deletes, oldest first)"; got != want {
+		t.Errorf("maxDeletesSummary() = %q, want %q", got, want)
+	}
+	if err := (&types.DeleteOptions{MaxDeletes: -1}).Validate(); err == nil {
+		t.Error("Validate() accepted a negative MaxDeletes")
+	}
+}
+
+func TestSkipAutoExpiringExcludesUploadsLifecycleWillAbort(t *testing.T) {
+	const mb = int64(1 << 20)
+	now := time.Now()
+	soon := now.Add(48 * time.Hour).UTC()
+	later := now.Add(20 * 24 * time.Hour).UTC()
+	old := now.Add(-30 * 24 * time.Hour)
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a", UploadID: "1", Initiated: old, Size: mb, AbortDate: &soon, AbortRuleID: "abort-7d"},
+		{Bucket: "logs", Key: "b", UploadID: "2", Initiated: old, Size: 2 * mb, AbortDate: &soon, AbortRuleID: "abort-7d"},
+		{Bucket: "data", Key: "c", UploadID: "3", Initiated: old, Size: 4 * mb, AbortDate: &later, AbortRuleID: "abort-30d"},
+		{Bucket: "data", Key: "d", UploadID: "4", Initiated: old, Size: 8 * mb},
+	}
+	dryRun := &DryRunService{costCalculator: NewCostService()}
+
+	result, err := dryRun.SimulateDeletion(context.Background(), uploads, types.DeleteOptions{SkipAutoExpiring: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalUploads != 1 || result.TotalSize != 8*mb {
+		t.Errorf("SimulateDeletion() = %d uploads (%d bytes), want only the upload with no abort date", result.TotalUploads, result.TotalSize)
+	}
+	if len(result.ExcludedAutoExpiring) != 2 {
+		t.Fatalf("ExcludedAutoExpiring = %+v, want 2 bucket/rule groups", result.ExcludedAutoExpiring)
+	}
+	first := result.ExcludedAutoExpiring[0]
+	if first.Bucket != "logs" || first.RuleID != "abort-7d" || first.Uploads != 2 || first.Size != 3*mb || !first.FirstAbortDate.Equal(soon) {
+		t.Errorf("first group = %+v, want logs/abort-7d with 2 uploads of %d bytes aborting soonest", first, 3*mb)
+	}
+
+	// With a window, uploads a rule aborts later than that are still deleted
+	opts := types.DeleteOptions{SkipAutoExpiring: true, AutoExpiringWithin: 3 * 24 * time.Hour}
+	if result, err = dryRun.SimulateDeletion(context.Background(), uploads, opts); err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalUploads != 2 || result.TotalSize != 12*mb {
+		t.Errorf("SimulateDeletion(within 3d) = %d uploads (%d bytes), want 2 (%d bytes)", result.TotalUploads, result.TotalSize, 12*mb)
+	}
+	if got, want := dryRun.buildCommandString(opts), "delete --skip-auto-expiring=3d"; got != want {
+		t.Errorf("buildCommandString() = %q, want %q", got, want)
+	}
+
+	// Without the flag, abort dates change nothing
+	if result, err = dryRun.SimulateDeletion(context.Background(), uploads, types.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalUploads != 4 || len(result.ExcludedAutoExpiring) != 0 {
+		t.Errorf("SimulateDeletion() without --skip-auto-expiring = %d uploads, %d excluded groups, want 4, 0", result.TotalUploads, len(result.ExcludedAutoExpiring))
+	}
+	if err := (&types.DeleteOptions{SkipAutoExpiring: true, AutoExpiringWithin: -time.Hour}).Validate(); err == nil {
+		t.Error("Validate() accepted a negative AutoExpiringWithin")
+	}
+}
+
+func TestBreakdownsHaveStableOrder(t *testing.T) {
+	initiated := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var uploads []types.MultipartUpload
+	for i, bucket := range []string{"logs", "media", "raw", "archive", "tmp", "backups"} {
+		for _, region := range []string{"us-east-1", "eu-west-1", "ap-south-1"} {
+			uploads = append(uploads, types.MultipartUpload{
+				Bucket: bucket, Key: region, UploadID: bucket + region, Region: region,
+				StorageClass: []string{"STANDARD", "GLACIER", "STANDARD_IA"}[i%3],
+				Initiated:    initiated, Size: int64(1+i%2) << 20,
+			})
+		}
+	}
+	// Uploads initiated together are ordered by bucket, key and upload ID
+	older := initiated.Add(-24 * time.Hour)
+	uploads = append(uploads,
+		types.MultipartUpload{Bucket: "tmp", Key: "same", UploadID: "b", Region: "us-east-1", StorageClass: "STANDARD", Initiated: older},
+		types.MultipartUpload{Bucket: "tmp", Key: "same", UploadID: "a", Region: "us-east-1", StorageClass: "STANDARD", Initiated: older})
+	inaccessible := []string{"locked", "denied", "gone"}
+
+	encode := func(uploads []types.MultipartUpload, inaccessible []string) map[string][]byte {
+		t.Helper()
+		result, err := (&DryRunService{costCalculator: NewCostService()}).SimulateDeletion(context.Background(), uploads, types.DeleteOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Accrued costs grow as the test runs
+		result.GeneratedAt, result.AlreadyWasted = time.Time{}, 0
+		encoded := make(map[string][]byte)
+		for name, value := range map[string]interface{}{
+			"DryRunResult": result,
+			"SizeReport":   (&SizeService{}).generateSizeReport(uploads, inaccessible),
+		} {
+			data, err := json.Marshal(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			encoded[name] = data
+		}
+		return encoded
+	}
+
+	// Saved reports are diffed in review, so the same uploads encode the same
+	// way in whatever order they were listed and buckets failed
+	want := encode(uploads, inaccessible)
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		shuffled := append([]types.MultipartUpload(nil), uploads...)
+		random.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		failed := append([]string(nil), inaccessible...)
+		random.Shuffle(len(failed), func(i, j int) { failed[i], failed[j] = failed[j], failed[i] })
+		for name, got := range encode(shuffled, failed) {
+			if !bytes.Equal(got, want[name]) {
+				t.Fatalf("%s of shuffled uploads encodes differently:\n%s\n%s", name, want[name], got)
+			}
+		}
+	}
+
+	result, err := (&DryRunService{costCalculator: NewCostService()}).SimulateDeletion(context.Background(), uploads, types.DeleteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The oldest come first
+	if first := result.Uploads[:3]; first[0].UploadID != "a" || first[1].UploadID != "b" || first[2].UploadID != "archiveap-south-1" {
+		t.Errorf("uploads start %s, %s, %s, want the oldest by upload ID, then by bucket and key", first[0].UploadID, first[1].UploadID, first[2].UploadID)
+	}
+	report := &types.SizeReport{ByBucket: result.SizeByBucket, ByStorageClass: result.SizeByStorageClass}
+	if got := (&SizeService{}).generateSizeReport(uploads, inaccessible).InaccessibleBuckets; strings.Join(got, ",") != "denied,gone,locked" {
+		t.Errorf("InaccessibleBuckets = %v, want them by name", got)
+	}
+
+	// Console breakdowns are largest first, then by name
+	var output bytes.Buffer
+	(&UploadService{outputWriter: &output}).reportDryRunResultsFromService(result, false)
+	var sorted []string
+	for _, bucket := range (&SizeService{}).GetSortedBucketSizes(report) {
+		sorted = append(sorted, bucket.Bucket)
+	}
+	order := []string{"archive", "backups", "media", "logs", "raw", "tmp"}
+	for name, got := range map[string]string{
+		"dry-run":              output.String(),
+		"FormatSizeReport":     NewOutputFormatter().FormatSizeReport(*report),
+		"GetSortedBucketSizes": strings.Join(sorted, ","),
+	} {
+		last := -1
+		for _, bucket := range order {
+			at := strings.Index(got, bucket)
+			if at < last {
+				t.Errorf("%s = %q, want buckets in the order %v", name, got, order)
+				break
+			}
+			last = at
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// bucketError is an error encountered while working on a bucket
+type bucketError struct {
+	bucket string
+	err    error
+}
+
+// summarizeErrors groups errors by AWS error code and cause, most frequent first
+func summarizeErrors(bucketErrors []bucketError) []types.ErrorSummary {
+	var summaries []types.ErrorSummary
+	index := make(map[string]int)
+	bucketSeen := make(map[string]map[string]bool)
+
+	for _, be := range bucketErrors {
+		class := awsclient.ClassifyError(be.err)
+		key := class.Code + "\x00" + class.Explanation + "\x00" + class.Remediation
+
+		i, exists := index[key]
+		if !exists {
+			i = len(summaries)
+			index[key] = i
+			bucketSeen[key] = make(map[string]bool)
+			summaries = append(summaries, types.ErrorSummary{
+				Code:        class.Code,
+				Category:    string(class.Category),
+				Explanation: class.Explanation,
+				Remediation: class.Remediation,
+				Benign:      class.Benign,
+				Buckets:     []string{},
+			})
+		}
+
+		summaries[i].Count++
+		if be.bucket != "" && !bucketSeen[key][be.bucket] {
+			bucketSeen[key][be.bucket] = true
+			summaries[i].Buckets = append(summaries[i].Buckets, be.bucket)
+		}
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Count > summaries[j].Count
+	})
+	return summaries
+}
+
+// formatErrorSummaries renders error summaries with their remediation hints
+func formatErrorSummaries(summaries []types.ErrorSummary, noun string) string {
+	var result strings.Builder
+
+	for _, summary := range summaries {
+		count := fmt.Sprintf("%d %s", summary.Count, noun)
+		if summary.Count != 1 {
+			count += "s"
+		}
+
+		if summary.Category == string(awsclient.CategoryUnknown) {
+			label := summary.Code
+			if label == "" {
+				label = "Other error"
+			}
+			result.WriteString(fmt.Sprintf("  %s (%s): %s\n", label, count, types.DisplayString(summary.Explanation)))
+		} else {
+			result.WriteString(fmt.Sprintf("  %s (%s, %s): %s\n", summary.Code, summary.Category, count, types.DisplayString(summary.Explanation)))
+			if summary.Remediation != "" {
+				result.WriteString(fmt.Sprintf("    Hint: %s\n", summary.Remediation))
+			}
+		}
+		if len(summary.Buckets) > 0 {
+			result.WriteString(fmt.Sprintf("    Buckets: %s\n", types.DisplayString(strings.Join(summary.Buckets, ", "))))
+		}
+	}
+
+	return result.String()
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestReportCompletionGroupsErrorsByCode(t *testing.T) {
+	noSuchUpload := errors.New("operation error S3: AbortMultipartUpload, https response error StatusCode: 404, RequestID: 2B3C, HostID: cXV4=, NoSuchUpload: ")
+	accessDenied := errors.New("operation error S3: AbortMultipartUpload, https response error StatusCode: 403, RequestID: 1A2B, HostID: YmF6=, api error AccessDenied: Access Denied")
+	deletionError := func(bucket string, err error) DeletionError {
+		return DeletionError{Upload: types.MultipartUpload{Bucket: bucket, Key: "k"}, Error: err, Time: time.Now()}
+	}
+
+	output := &syncBuffer{}
+	reporter := NewConsoleProgressReporterWithTerminal(output, false, nil)
+	reporter.ReportCompletion(DeletionResult{
+		TotalProcessed: 3,
+		FailedDeletes:  3,
+		Errors: []DeletionError{
+			deletionError("logs", noSuchUpload),
+			deletionError("media", noSuchUpload),
+			deletionError("secure", accessDenied),
+		},
+	})
+
+	text := output.String()
+	for _, want := range []string{
+		"NoSuchUpload (not-found, 2 uploads): The upload no longer exists",
+		"Buckets: logs, media",
+		"AccessDenied (permission, 1 upload)",
+		"Hint: Grant s3:AbortMultipartUpload",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("completion report missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Index(text, "NoSuchUpload (") > strings.Index(text, "AccessDenied (") {
+		t.Error("error groups are not ordered by frequency")
+	}
+}
+
+func TestSizeReportSummarizesInaccessibleBuckets(t *testing.T) {
+	uploadService := &countingUploadService{
+		uploads: []types.MultipartUpload{
+			{Bucket: "open", Key: "a", UploadID: "1", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+			{Bucket: "locked", Key: "b", UploadID: "2", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+		},
+		failBuckets: map[string]bool{"locked": true},
+	}
+
+	report, err := NewSizeServiceWithCache(uploadService, 1, nil, nil).CalculateTotalSize(nil, types.ListOptions{})
+	if err != nil {
+		t.Fatalf("CalculateTotalSize() error = %v", err)
+	}
+	if len(report.ErrorSummary) != 1 || report.ErrorSummary[0].Count != 1 || report.ErrorSummary[0].Buckets[0] != "locked" {
+		t.Fatalf("ErrorSummary = %+v, want one entry for bucket locked", report.ErrorSummary)
+	}
+	// Unknown errors keep their message verbatim
+	if report.ErrorSummary[0].Explanation != "access denied listing parts in locked" {
+		t.Errorf("Explanation = %q, want the original error message", report.ErrorSummary[0].Explanation)
+	}
+}
+
+func TestQuietReportCompletionOnlyReportsErrors(t *testing.T) {
+	output, errOutput := &syncBuffer{}, &syncBuffer{}
+	reporter := NewConsoleProgressReporterWithTerminal(output, true, nil)
+	reporter.errWriter = errOutput
+
+	reporter.ReportProgress(DeletionProgress{TotalUploads: 1, StartTime: time.Now()})
+	reporter.ReportCompletion(DeletionResult{TotalProcessed: 1, SuccessfulDeletes: 1})
+	if output.String() != "" || errOutput.String() != "" {
+		t.Errorf("quiet reporter wrote %q and %q for a successful deletion, want nothing", output.String(), errOutput.String())
+	}
+
+	reporter.ReportCompletion(DeletionResult{
+		TotalProcessed: 1,
+		FailedDeletes:  1,
+		Errors:         []DeletionError{{Upload: types.MultipartUpload{Bucket: "logs", Key: "k"}, Error: errors.New("api error AccessDenied: Access Denied")}},
+	})
+	if output.String() != "" {
+		t.Errorf("quiet reporter wrote to the output writer:\n%s", output.String())
+	}
+	if !strings.Contains(errOutput.String(), "logs/k: api error AccessDenied") {
+		t.Errorf("quiet reporter did not write the error:\n%s", errOutput.String())
+	}
+}
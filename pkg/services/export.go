@@ -1,77 +1,202 @@
 package services
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Garvitkul/s3mpc/pkg/interfaces"
 	"github.com/Garvitkul/s3mpc/pkg/types"
+	"github.com/Garvitkul/s3mpc/pkg/units"
 )
 
 // ExportService implements the interfaces.ExportService interface
-type ExportService struct{}
+type ExportService struct {
+	costCalculator interfaces.CostCalculator // nil leaves costs out of spreadsheet summaries
+}
 
 // NewExportService creates a new ExportService instance
 func NewExportService() interfaces.ExportService {
 	return &ExportService{}
 }
 
-// ExportToCSV exports uploads to CSV format
-func (e *ExportService) ExportToCSV(ctx context.Context, uploads []types.MultipartUpload, filename string) error {
-	// Ensure directory exists
+// NewExportServiceWithCost creates an ExportService whose spreadsheet summaries
+// include estimated monthly costs from costCalculator
+func NewExportServiceWithCost(costCalculator interfaces.CostCalculator) interfaces.ExportService {
+	return &ExportService{costCalculator: costCalculator}
+}
+
+// uploadCSVHeader is the header of upload CSV exports. measured_at is empty
+// for uploads whose size was never measured, account outside multi-account runs,
+// the initiator and owner names when the store does not report them, and
+// parts_count and estimated_monthly_cost for uploads whose parts were never listed.
+var uploadCSVHeader = []string{
+	"bucket",
+	"key",
+	"upload_id",
+	"initiated",
+	"age_days",
+	"size",
+	"measured_at",
+	"storage_class",
+	"region",
+	"initiator",
+	"initiated_by",
+	"account",
+	"initiator_name",
+	"owner",
+	"owner_id",
+	"parts_count",
+	"estimated_monthly_cost",
+}
+
+// appendUploadCSVRecord appends the CSV fields of an upload in uploadCSVHeader
+// order, so exports can reuse one record slice for every upload
+func appendUploadCSVRecord(record []string, upload types.MultipartUpload) []string {
+	ageDays := int(time.Since(upload.Initiated).Hours() / 24)
+
+	measuredAt := ""
+	if !upload.MeasuredAt.IsZero() {
+		measuredAt = types.FormatTimestamp(upload.MeasuredAt)
+	}
+
+	return append(record,
+		upload.Bucket,
+		upload.Key,
+		upload.UploadID,
+		types.FormatTimestamp(upload.Initiated),
+		strconv.Itoa(ageDays),
+		strconv.FormatInt(upload.Size, 10),
+		measuredAt,
+		upload.StorageClass,
+		upload.Region,
+		upload.Initiator,
+		InitiatedByLabel(upload),
+		upload.Account,
+		upload.InitiatorName,
+		upload.Owner,
+		upload.OwnerID,
+		partsCountField(upload),
+		estimatedCostField(upload),
+	)
+}
+
+// partsCountField returns the parts_count CSV field, empty when the upload's
+// parts were never listed
+func partsCountField(upload types.MultipartUpload) string {
+	if upload.MeasuredAt.IsZero() && upload.PartsCount == 0 {
+		return ""
+	}
+	return strconv.Itoa(upload.PartsCount)
+}
+
+// estimatedCostField returns the estimated_monthly_cost CSV field, empty like
+// parts_count when the upload was never measured
+func estimatedCostField(upload types.MultipartUpload) string {
+	if partsCountField(upload) == "" {
+		return ""
+	}
+	return strconv.FormatFloat(upload.EstimatedMonthlyCost, 'f', 6, 64)
+}
+
+// createExportFile creates filename for writing, and its directory if needed
+func createExportFile(filename string) (*os.File, error) {
 	dir := filepath.Dir(filename)
 	if dir != "." {
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+			return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
 		}
 	}
 
 	file, err := os.Create(filename)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filename, err)
+		return nil, fmt.Errorf("failed to create file %s: %w", filename, err)
+	}
+	return file, nil
+}
+
+// gzipSuffix marks export files that are written gzip-compressed
+const gzipSuffix = ".gz"
+
+// IsCompressedFilename reports whether filename names a gzip-compressed file
+func IsCompressedFilename(filename string) bool {
+	return strings.HasSuffix(strings.ToLower(filename), gzipSuffix)
+}
+
+// exportWriter writes an export file, gzip-compressed when its name ends in .gz
+type exportWriter struct {
+	io.Writer
+	file *os.File
+	gzip *gzip.Writer
+}
+
+// openExportWriter creates filename for writing, compressing what is written
+// when the name ends in .gz
+func openExportWriter(filename string) (*exportWriter, error) {
+	file, err := createExportFile(filename)
+	if err != nil {
+		return nil, err
 	}
-	defer file.Close()
+
+	w := &exportWriter{Writer: file, file: file}
+	if IsCompressedFilename(filename) {
+		w.gzip = gzip.NewWriter(file)
+		w.Writer = w.gzip
+	}
+	return w, nil
+}
+
+// Close ends the gzip stream, so the archive is complete even when the export
+// stopped early, and closes the file
+func (w *exportWriter) Close() error {
+	var err error
+	if w.gzip != nil {
+		err = w.gzip.Close()
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// closeExport closes an export writer, reporting the close error through err
+// when the export itself succeeded
+func closeExport(w *exportWriter, filename string, err *error) {
+	if closeErr := w.Close(); closeErr != nil && *err == nil {
+		*err = fmt.Errorf("failed to write %s: %w", filename, closeErr)
+	}
+}
+
+// ExportToCSV exports uploads to CSV format
+func (e *ExportService) ExportToCSV(ctx context.Context, uploads []types.MultipartUpload, filename string) (err error) {
+	file, err := openExportWriter(filename)
+	if err != nil {
+		return err
+	}
+	defer closeExport(file, filename, &err)
 
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
 	// Write CSV header
-	header := []string{
-		"bucket",
-		"key",
-		"upload_id",
-		"initiated",
-		"age_days",
-		"size",
-		"storage_class",
-		"region",
-	}
-	if err := writer.Write(header); err != nil {
+	if err := writer.Write(uploadCSVHeader); err != nil {
 		return fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
 	// Write upload data
+	record := make([]string, 0, len(uploadCSVHeader))
 	for _, upload := range uploads {
-		ageDays := int(time.Since(upload.Initiated).Hours() / 24)
-		
-		record := []string{
-			upload.Bucket,
-			upload.Key,
-			upload.UploadID,
-			upload.Initiated.Format("2006-01-02T15:04:05Z"),
-			strconv.Itoa(ageDays),
-			strconv.FormatInt(upload.Size, 10),
-			upload.StorageClass,
-			upload.Region,
-		}
-		
+		record = appendUploadCSVRecord(record[:0], upload)
 		if err := writer.Write(record); err != nil {
 			return fmt.Errorf("failed to write CSV record: %w", err)
 		}
@@ -81,28 +206,22 @@ func (e *ExportService) ExportToCSV(ctx context.Context, uploads []types.Multipa
 }
 
 // ExportToJSON exports uploads to JSON format
-func (e *ExportService) ExportToJSON(ctx context.Context, uploads []types.MultipartUpload, filename string) error {
-	// Ensure directory exists
-	dir := filepath.Dir(filename)
-	if dir != "." {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", dir, err)
-		}
-	}
-
-	file, err := os.Create(filename)
+func (e *ExportService) ExportToJSON(ctx context.Context, uploads []types.MultipartUpload, filename string) (err error) {
+	file, err := openExportWriter(filename)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filename, err)
+		return err
 	}
-	defer file.Close()
+	defer closeExport(file, filename, &err)
 
 	// Create export data structure
 	exportData := struct {
-		ExportedAt time.Time                `json:"exported_at"`
+		SchemaVersion int                   `json:"schema_version"`
+		ExportedAt string                   `json:"exported_at"`
 		TotalCount int                      `json:"total_count"`
 		Uploads    []types.MultipartUpload  `json:"uploads"`
 	}{
-		ExportedAt: time.Now(),
+		SchemaVersion: types.SchemaVersion,
+		ExportedAt: types.FormatTimestamp(time.Now()),
 		TotalCount: len(uploads),
 		Uploads:    uploads,
 	}
@@ -117,8 +236,162 @@ func (e *ExportService) ExportToJSON(ctx context.Context, uploads []types.Multip
 	return nil
 }
 
-// GenerateExportFilename generates a filename for export results
-func (e *ExportService) GenerateExportFilename(command string, format string) string {
+// ExportToXLSX exports uploads to an Excel workbook with an "Uploads" sheet
+// holding the CSV columns as typed cells, and "By Bucket" and "By Region"
+// summary sheets with counts, sizes and, given a cost calculator, estimated
+// monthly costs
+func (e *ExportService) ExportToXLSX(ctx context.Context, uploads []types.MultipartUpload, filename string) (err error) {
+	if err := checkXLSXRows(len(uploads)); err != nil {
+		return err
+	}
+	sheets := []xlsxSheet{uploadsSheet(uploads)}
+	summaries, err := e.summarySheets(ctx, uploads)
+	if err != nil {
+		return err
+	}
+	sheets = append(sheets, summaries...)
+
+	file, err := openExportWriter(filename)
+	if err != nil {
+		return err
+	}
+	defer closeExport(file, filename, &err)
+
+	return writeXLSX(file, sheets)
+}
+
+// checkXLSXRows refuses more uploads than the "Uploads" sheet has rows for,
+// which spreadsheets would cut off or refuse to open
+func checkXLSXRows(uploads int) error {
+	if uploads+1 > xlsxMaxRows {
+		return fmt.Errorf("%d uploads do not fit in the %d rows of a worksheet; use --max-rows %d or fewer to cut the export into several workbooks", uploads, xlsxMaxRows, xlsxMaxRows-1)
+	}
+	return nil
+}
+
+// uploadsSheet lists every upload in uploadCSVHeader order
+func uploadsSheet(uploads []types.MultipartUpload) xlsxSheet {
+	sheet := xlsxSheet{name: "Uploads", header: uploadCSVHeader, rows: make([][]xlsxCell, 0, len(uploads))}
+	for _, upload := range uploads {
+		sheet.rows = append(sheet.rows, []xlsxCell{
+			xlsxString(upload.Bucket),
+			xlsxString(upload.Key),
+			xlsxString(upload.UploadID),
+			xlsxTime(upload.Initiated),
+			xlsxInt(int64(time.Since(upload.Initiated).Hours() / 24)),
+			xlsxInt(upload.Size),
+			xlsxTime(upload.MeasuredAt),
+			xlsxString(upload.StorageClass),
+			xlsxString(upload.Region),
+			xlsxString(upload.Initiator),
+			xlsxString(InitiatedByLabel(upload)),
+			xlsxString(upload.Account),
+			xlsxString(upload.InitiatorName),
+			xlsxString(upload.Owner),
+			xlsxString(upload.OwnerID),
+			xlsxPartsCount(upload),
+			xlsxEstimatedCost(upload),
+		})
+	}
+	return sheet
+}
+
+// xlsxPartsCount returns the parts count cell, empty like the CSV field when
+// the upload's parts were never listed
+func xlsxPartsCount(upload types.MultipartUpload) xlsxCell {
+	if partsCountField(upload) == "" {
+		return xlsxString("")
+	}
+	return xlsxInt(int64(upload.PartsCount))
+}
+
+// xlsxEstimatedCost returns the estimated monthly cost cell, empty like the
+// CSV field when the upload was never measured
+func xlsxEstimatedCost(upload types.MultipartUpload) xlsxCell {
+	if estimatedCostField(upload) == "" {
+		return xlsxString("")
+	}
+	return xlsxFloat(upload.EstimatedMonthlyCost)
+}
+
+// uploadGroup totals the uploads sharing a bucket or region
+type uploadGroup struct {
+	name    string
+	uploads []types.MultipartUpload
+	size    int64
+}
+
+// groupUploads groups uploads by key, largest total size first
+func groupUploads(uploads []types.MultipartUpload, key func(types.MultipartUpload) string) []*uploadGroup {
+	byName := make(map[string]*uploadGroup)
+	var groups []*uploadGroup
+	for _, upload := range uploads {
+		name := key(upload)
+		group, ok := byName[name]
+		if !ok {
+			group = &uploadGroup{name: name}
+			byName[name] = group
+			groups = append(groups, group)
+		}
+		group.uploads = append(group.uploads, upload)
+		group.size += upload.Size
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].size != groups[j].size {
+			return groups[i].size > groups[j].size
+		}
+		return groups[i].name < groups[j].name
+	})
+	return groups
+}
+
+// summarySheets builds the "By Bucket" and "By Region" sheets
+func (e *ExportService) summarySheets(ctx context.Context, uploads []types.MultipartUpload) ([]xlsxSheet, error) {
+	byBucket := groupUploads(uploads, func(u types.MultipartUpload) string { return u.Bucket })
+	byRegion := groupUploads(uploads, func(u types.MultipartUpload) string { return u.Region })
+
+	sheets := []xlsxSheet{
+		{name: "By Bucket", header: []string{"bucket", "uploads", "size", "size_human"}},
+		{name: "By Region", header: []string{"region", "uploads", "size", "size_human"}},
+	}
+	for i, groups := range [][]*uploadGroup{byBucket, byRegion} {
+		for _, group := range groups {
+			sheets[i].rows = append(sheets[i].rows, []xlsxCell{
+				xlsxString(group.name),
+				xlsxInt(int64(len(group.uploads))),
+				xlsxInt(group.size),
+				xlsxString(units.FormatBytes(group.size)),
+			})
+		}
+	}
+	if e.costCalculator == nil {
+		return sheets, nil
+	}
+
+	// Costs are estimated per group so each row uses its own regions' prices
+	var currency string
+	for i, groups := range [][]*uploadGroup{byBucket, byRegion} {
+		for j, group := range groups {
+			breakdown, err := e.costCalculator.CalculateStorageCost(ctx, group.uploads)
+			if err != nil {
+				return nil, fmt.Errorf("failed to estimate costs for %s: %w", group.name, err)
+			}
+			currency = breakdown.Currency
+			sheets[i].rows[j] = append(sheets[i].rows[j], xlsxFloat(breakdown.TotalMonthlyCost))
+		}
+	}
+	if currency == "" {
+		currency = "USD"
+	}
+	for i := range sheets {
+		sheets[i].header = append(sheets[i].header, "estimated_monthly_cost_"+strings.ToLower(currency))
+	}
+	return sheets, nil
+}
+
+// GenerateExportFilename generates a filename for export results, ending in
+// .gz when compress is set
+func (e *ExportService) GenerateExportFilename(command string, format string, compress bool) string {
 	timestamp := time.Now().Format("20060102_1504")
 	
 	// Sanitize command name
@@ -127,48 +400,35 @@ func (e *ExportService) GenerateExportFilename(command string, format string) st
 	
 	// Ensure format is lowercase
 	format = strings.ToLower(format)
-	if format != "csv" && format != "json" {
+	if format != "csv" && format != "json" && format != "xlsx" {
 		format = "json" // Default to JSON
 	}
 	
-	return fmt.Sprintf("s3mpc_%s_export_%s.%s", sanitizedCommand, timestamp, format)
+	filename := fmt.Sprintf("s3mpc_%s_export_%s.%s", sanitizedCommand, timestamp, format)
+	if compress {
+		filename += gzipSuffix
+	}
+	return filename
 }
 
 // StreamExportToCSV exports large datasets to CSV with streaming
-func (e *ExportService) StreamExportToCSV(ctx context.Context, uploads <-chan types.MultipartUpload, filename string) error {
-	// Ensure directory exists
-	dir := filepath.Dir(filename)
-	if dir != "." {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", dir, err)
-		}
-	}
-
-	file, err := os.Create(filename)
+func (e *ExportService) StreamExportToCSV(ctx context.Context, uploads <-chan types.MultipartUpload, filename string) (err error) {
+	file, err := openExportWriter(filename)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filename, err)
+		return err
 	}
-	defer file.Close()
+	defer closeExport(file, filename, &err)
 
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
 	// Write CSV header
-	header := []string{
-		"bucket",
-		"key",
-		"upload_id",
-		"initiated",
-		"age_days",
-		"size",
-		"storage_class",
-		"region",
-	}
-	if err := writer.Write(header); err != nil {
+	if err := writer.Write(uploadCSVHeader); err != nil {
 		return fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
 	// Stream upload data
+	record := make([]string, 0, len(uploadCSVHeader))
 	for {
 		select {
 		case <-ctx.Done():
@@ -179,19 +439,7 @@ func (e *ExportService) StreamExportToCSV(ctx context.Context, uploads <-chan ty
 				return nil
 			}
 			
-			ageDays := int(time.Since(upload.Initiated).Hours() / 24)
-			
-			record := []string{
-				upload.Bucket,
-				upload.Key,
-				upload.UploadID,
-				upload.Initiated.Format("2006-01-02T15:04:05Z"),
-				strconv.Itoa(ageDays),
-				strconv.FormatInt(upload.Size, 10),
-				upload.StorageClass,
-				upload.Region,
-			}
-			
+			record = appendUploadCSVRecord(record[:0], upload)
 			if err := writer.Write(record); err != nil {
 				return fmt.Errorf("failed to write CSV record: %w", err)
 			}
@@ -202,83 +450,66 @@ func (e *ExportService) StreamExportToCSV(ctx context.Context, uploads <-chan ty
 	}
 }
 
-// StreamExportToJSON exports large datasets to JSON with streaming
-func (e *ExportService) StreamExportToJSON(ctx context.Context, uploads <-chan types.MultipartUpload, filename string) error {
-	// Ensure directory exists
-	dir := filepath.Dir(filename)
-	if dir != "." {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", dir, err)
-		}
-	}
-
-	file, err := os.Create(filename)
+// StreamExportToJSON exports large datasets to JSON with streaming. The file
+// has the layout of ExportToJSON with total_count last, since it is only known
+// at the end. A cancelled or failed export removes the partial file rather
+// than leave invalid JSON behind.
+func (e *ExportService) StreamExportToJSON(ctx context.Context, uploads <-chan types.MultipartUpload, filename string) (err error) {
+	file, err := openExportWriter(filename)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filename, err)
+		return err
 	}
-	defer file.Close()
+	defer func() {
+		closeExport(file, filename, &err)
+		if err != nil {
+			os.Remove(filename)
+		}
+	}()
 
-	// Write JSON structure manually for streaming
-	if _, err := file.WriteString("{\n"); err != nil {
+	out := bufio.NewWriter(file)
+	opening := fmt.Sprintf("{\n  \"schema_version\": %d,\n  \"exported_at\": %q,\n  \"uploads\": [", types.SchemaVersion, types.FormatTimestamp(time.Now()))
+	if _, err := out.WriteString(opening); err != nil {
 		return fmt.Errorf("failed to write JSON opening: %w", err)
 	}
-	
-	// Write metadata
-	exportedAt := time.Now().Format("2006-01-02T15:04:05Z")
-	if _, err := file.WriteString(fmt.Sprintf("  \"exported_at\": \"%s\",\n", exportedAt)); err != nil {
-		return fmt.Errorf("failed to write exported_at: %w", err)
-	}
-	
-	if _, err := file.WriteString("  \"uploads\": [\n"); err != nil {
-		return fmt.Errorf("failed to write uploads array opening: %w", err)
-	}
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("    ", "  ")
-	
-	first := true
 	count := 0
-	
-	// Stream upload data
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case upload, ok := <-uploads:
 			if !ok {
-				// Channel closed, finish the JSON structure
-				if _, err := file.WriteString("\n  ],\n"); err != nil {
-					return fmt.Errorf("failed to write uploads array closing: %w", err)
-				}
-				
-				if _, err := file.WriteString(fmt.Sprintf("  \"total_count\": %d\n", count)); err != nil {
-					return fmt.Errorf("failed to write total_count: %w", err)
+				// An empty array closes on the same line, like encoding/json writes it
+				closing := "]"
+				if count > 0 {
+					closing = "\n  ]"
 				}
-				
-				if _, err := file.WriteString("}\n"); err != nil {
+				closing += fmt.Sprintf(",\n  \"total_count\": %d\n}\n", count)
+				if _, err := out.WriteString(closing); err != nil {
 					return fmt.Errorf("failed to write JSON closing: %w", err)
 				}
-				
-				return nil
-			}
-			
-			if !first {
-				if _, err := file.WriteString(",\n"); err != nil {
-					return fmt.Errorf("failed to write JSON separator: %w", err)
+				if err := out.Flush(); err != nil {
+					return fmt.Errorf("failed to write JSON: %w", err)
 				}
+				return nil
 			}
-			first = false
-			count++
-			
-			// Encode the upload without newline
+
+			// Elements sit at the array's indentation, with their fields one level deeper
 			uploadJSON, err := json.MarshalIndent(upload, "    ", "  ")
 			if err != nil {
 				return fmt.Errorf("failed to marshal upload: %w", err)
 			}
-			
-			if _, err := file.Write(uploadJSON); err != nil {
+			separator := ",\n    "
+			if count == 0 {
+				separator = "\n    "
+			}
+			if _, err := out.WriteString(separator); err != nil {
+				return fmt.Errorf("failed to write JSON separator: %w", err)
+			}
+			if _, err := out.Write(uploadJSON); err != nil {
 				return fmt.Errorf("failed to write upload JSON: %w", err)
 			}
+			count++
 		}
 	}
-}
\ No newline at end of file
+}
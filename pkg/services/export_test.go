@@ -0,0 +1,685 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// roundTripUploads returns uploads with local-zone and sub-second timestamps.
+// Only the first upload has a measured size.
+func roundTripUploads() []types.MultipartUpload {
+	zone := time.FixedZone("PST", -8*3600)
+	return []types.MultipartUpload{
+		{Bucket: "b1", Key: "a/b.bin", UploadID: "u1", Initiated: time.Now().Add(-49 * time.Hour).In(zone), Size: 1024, PartsCount: 3, MeasuredAt: time.Now().Add(-32 * time.Minute).In(zone), StorageClass: "STANDARD", Region: "us-east-1", Account: "prod",
+			Initiator: "arn:aws:iam::123456789012:user/alice", InitiatorName: "alice", Owner: "ops", OwnerID: "75aa57f09aa0c8caeab4f8c24e99d10f"},
+		{Bucket: "b2", Key: "c,d.bin", UploadID: "u2", Initiated: time.Now().Add(-30 * 24 * time.Hour).In(zone), Size: 2048, StorageClass: "GLACIER", Region: "eu-west-1", Initiator: "s3.amazonaws.com", ServiceInitiated: true},
+	}
+}
+
+// csvColumn returns the values of a named column in a CSV file
+func csvColumn(t *testing.T, path, column string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("csv ReadAll() error = %v", err)
+	}
+	index := -1
+	for i, name := range records[0] {
+		if name == column {
+			index = i
+		}
+	}
+	if index < 0 {
+		t.Fatalf("column %s not found in %v", column, records[0])
+	}
+	var values []string
+	for _, record := range records[1:] {
+		values = append(values, record[index])
+	}
+	return values
+}
+
+func TestCSVExportRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	service := NewExportService()
+	first := filepath.Join(dir, "first.csv")
+	second := filepath.Join(dir, "second.csv")
+
+	if err := service.ExportToCSV(context.Background(), roundTripUploads(), first); err != nil {
+		t.Fatalf("ExportToCSV() error = %v", err)
+	}
+
+	file, err := os.Open(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ReadUploadsCSV(file)
+	file.Close()
+	if err != nil {
+		t.Fatalf("ReadUploadsCSV() error = %v", err)
+	}
+	if len(imported) != 2 || !imported[1].ServiceInitiated || imported[1].Key != "c,d.bin" || imported[0].Account != "prod" || imported[1].Account != "" ||
+		imported[0].InitiatorName != "alice" || imported[0].PartsCount != 3 || imported[0].Owner != "ops" || imported[0].OwnerID != "75aa57f09aa0c8caeab4f8c24e99d10f" {
+		t.Fatalf("ReadUploadsCSV() = %+v, want both uploads", imported)
+	}
+
+	if err := service.ExportToCSV(context.Background(), imported, second); err != nil {
+		t.Fatalf("ExportToCSV() error = %v", err)
+	}
+
+	original := csvColumn(t, first, "initiated")
+	roundTripped := csvColumn(t, second, "initiated")
+	if measuredAt := csvColumn(t, second, "measured_at"); measuredAt[0] == "" || measuredAt[0] != csvColumn(t, first, "measured_at")[0] || measuredAt[1] != "" {
+		t.Errorf("measured_at = %v after round trip, want the first upload's measurement only", measuredAt)
+	}
+	for i := range original {
+		if original[i] != roundTripped[i] {
+			t.Errorf("initiated[%d] = %s after round trip, want %s", i, roundTripped[i], original[i])
+		}
+		if _, err := time.Parse(time.RFC3339, original[i]); err != nil || original[i][len(original[i])-1] != 'Z' {
+			t.Errorf("initiated[%d] = %s, want RFC3339 UTC", i, original[i])
+		}
+	}
+
+	// Age recomputed from the imported timestamp matches the exported age_days
+	for i, value := range csvColumn(t, first, "age_days") {
+		exported, _ := strconv.Atoi(value)
+		recomputed := int(time.Since(imported[i].Initiated).Hours() / 24)
+		if diff := recomputed - exported; diff < -1 || diff > 1 {
+			t.Errorf("age_days[%d] recomputed as %d, exported %d", i, recomputed, exported)
+		}
+	}
+}
+
+func TestJSONExportRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	service := NewExportService()
+	first := filepath.Join(dir, "first.json")
+	second := filepath.Join(dir, "second.csv")
+	reference := filepath.Join(dir, "reference.csv")
+
+	uploads := roundTripUploads()
+	if err := service.ExportToJSON(context.Background(), uploads, first); err != nil {
+		t.Fatalf("ExportToJSON() error = %v", err)
+	}
+
+	file, err := os.Open(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ReadUploadsJSON(file)
+	file.Close()
+	if err != nil {
+		t.Fatalf("ReadUploadsJSON() error = %v", err)
+	}
+
+	if err := service.ExportToCSV(context.Background(), imported, second); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.ExportToCSV(context.Background(), uploads, reference); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, column := range []string{"initiated", "measured_at", "account"} {
+		want := csvColumn(t, reference, column)
+		got := csvColumn(t, second, column)
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s[%d] = %s after JSON round trip, want %s", column, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestReadUploadsCSVLegacyTimestamps(t *testing.T) {
+	input := "bucket,key,upload_id,initiated,age_days,size,storage_class,region,estimated_monthly_cost\n" +
+		"b,k,u,2024-03-05 14:30:15,1,10,STANDARD,us-east-1,0.000000\n"
+
+	uploads, err := ReadUploadsCSV(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatalf("ReadUploadsCSV() error = %v", err)
+	}
+	if len(uploads) != 1 || types.FormatTimestamp(uploads[0].Initiated) != "2024-03-05T14:30:15Z" || uploads[0].Size != 10 {
+		t.Errorf("ReadUploadsCSV() = %+v", uploads)
+	}
+}
+
+func TestExportsPreserveHostileKeys(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	uploads := roundTripUploads()
+	uploads[0].Key = hostileKey
+	uploads[1].Key = "quote\"comma,cr\rlf\n"
+
+	service := NewExportService()
+	for _, name := range []string{"export.csv", "export.json"} {
+		path := filepath.Join(dir, name)
+		var err error
+		if filepath.Ext(name) == ".csv" {
+			err = service.ExportToCSV(ctx, uploads, path)
+		} else {
+			err = service.ExportToJSON(ctx, uploads, path)
+		}
+		if err != nil {
+			t.Fatalf("export %s error = %v", name, err)
+		}
+
+		file, err := LoadUploadsFile(path)
+		if err != nil {
+			t.Fatalf("LoadUploadsFile(%s) error = %v", name, err)
+		}
+		if len(file.Uploads) != 2 || file.Uploads[0].Key != hostileKey || file.Uploads[1].Key != uploads[1].Key {
+			t.Errorf("%s did not keep the raw keys: %+v", name, file.Uploads)
+		}
+	}
+
+	// Saved dry-runs quote carriage returns as well as newlines
+	dryRun := &DryRunService{costCalculator: NewCostService()}
+	result, err := dryRun.SimulateDeletion(ctx, uploads, types.DeleteOptions{IncludeServiceInitiated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "dryrun.csv")
+	if err := dryRun.SaveDryRunResult(result, path); err != nil {
+		t.Fatalf("SaveDryRunResult() error = %v", err)
+	}
+	// The rows are sorted, so the keys are compared as a set
+	keys := csvColumn(t, path, "key")
+	sort.Strings(keys)
+	want := []string{hostileKey, uploads[1].Key}
+	sort.Strings(want)
+	if len(keys) != 2 || keys[0] != want[0] || keys[1] != want[1] {
+		t.Errorf("dry-run CSV keys = %q, want the raw keys", keys)
+	}
+}
+
+func TestPerUploadCostsInExports(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	const gb = 1 << 30
+	uploads := []types.MultipartUpload{
+		{Bucket: "b1", Key: "hot", UploadID: "u1", Initiated: time.Now().Add(-48 * time.Hour), Size: 2 * gb, PartsCount: 2, MeasuredAt: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "b2", Key: "cold", UploadID: "u2", Initiated: time.Now().Add(-48 * time.Hour), Size: gb, PartsCount: 1, MeasuredAt: time.Now(), StorageClass: "GLACIER", Region: "eu-west-1"},
+		{Bucket: "b3", Key: "unmeasured", UploadID: "u3", Initiated: time.Now().Add(-48 * time.Hour), StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+	uploads = EstimateUploadCosts(ctx, NewCostService(), uploads)
+
+	path := filepath.Join(dir, "export.csv")
+	if err := NewExportService().ExportToCSV(ctx, uploads, path); err != nil {
+		t.Fatalf("ExportToCSV() error = %v", err)
+	}
+	if costs := csvColumn(t, path, "estimated_monthly_cost"); strings.Join(costs, ",") != "0.046000,0.004500," {
+		t.Errorf("estimated_monthly_cost = %q, want regional prices and empty for the unmeasured upload", costs)
+	}
+
+	// Saved dry-runs price each row with the calculator, not a flat STANDARD rate
+	dryRun := &DryRunService{costCalculator: NewCostService()}
+	result, err := dryRun.SimulateDeletion(ctx, uploads[:2], types.DeleteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path = filepath.Join(dir, "dryrun.csv")
+	if err := dryRun.SaveDryRunResult(result, path); err != nil {
+		t.Fatalf("SaveDryRunResult() error = %v", err)
+	}
+	costs := csvColumn(t, path, "estimated_monthly_cost")
+	var total float64
+	for _, cost := range costs {
+		value, _ := strconv.ParseFloat(cost, 64)
+		total += value
+	}
+	if strings.Join(costs, ",") != "0.046000,0.004500" || math.Abs(total-result.EstimatedSavings) > 1e-6 {
+		t.Errorf("dry-run estimated_monthly_cost = %q, want rows adding up to savings %v", costs, result.EstimatedSavings)
+	}
+}
+
+// gunzipFile returns the decompressed contents of a gzip file
+func gunzipFile(t *testing.T, path string) []byte {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("%s is not gzip: %v", path, err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("%s is truncated: %v", path, err)
+	}
+	return data
+}
+
+func TestCompressedExportsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	service := NewExportService()
+	uploads := roundTripUploads()
+
+	stream := func(export func(context.Context, <-chan types.MultipartUpload, string) error) func(context.Context, []types.MultipartUpload, string) error {
+		return func(ctx context.Context, uploads []types.MultipartUpload, path string) error {
+			channel := make(chan types.MultipartUpload, len(uploads))
+			for _, upload := range uploads {
+				channel <- upload
+			}
+			close(channel)
+			return export(ctx, channel, path)
+		}
+	}
+	exports := map[string]func(context.Context, []types.MultipartUpload, string) error{
+		"export.csv.gz":    service.ExportToCSV,
+		"export.json.gz":   service.ExportToJSON,
+		"streamed.csv.gz":  stream(service.StreamExportToCSV),
+		"streamed.json.gz": stream(service.StreamExportToJSON),
+	}
+	for name, export := range exports {
+		path := filepath.Join(dir, name)
+		if err := export(ctx, uploads, path); err != nil {
+			t.Fatalf("export %s error = %v", name, err)
+		}
+
+		data := gunzipFile(t, path)
+		var imported []types.MultipartUpload
+		var err error
+		if strings.Contains(name, ".csv") {
+			imported, err = ReadUploadsCSV(bytes.NewReader(data))
+		} else {
+			imported, err = ReadUploadsJSON(bytes.NewReader(data))
+		}
+		if err != nil {
+			t.Fatalf("%s does not parse after decompression: %v", name, err)
+		}
+		if len(imported) != 2 || imported[1].Key != uploads[1].Key {
+			t.Errorf("%s = %+v, want both uploads", name, imported)
+		}
+
+		// Compressed exports can be fed back to --from-file
+		if file, err := LoadUploadsFile(path); err != nil || len(file.Uploads) != 2 {
+			t.Errorf("LoadUploadsFile(%s) = %v, %v, want both uploads", name, file, err)
+		}
+	}
+}
+
+func TestCancelledCompressedExportIsComplete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.csv.gz")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	uploads := make(chan types.MultipartUpload, 1)
+	uploads <- roundTripUploads()[0]
+	done := make(chan error)
+	go func() { done <- NewExportService().StreamExportToCSV(ctx, uploads, path) }()
+	for len(uploads) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("StreamExportToCSV() error = %v, want context.Canceled", err)
+	}
+	if records, err := csv.NewReader(bytes.NewReader(gunzipFile(t, path))).ReadAll(); err != nil || len(records) != 2 {
+		t.Errorf("cancelled export = %d records, %v; want the header and the upload written so far", len(records), err)
+	}
+}
+
+func TestGenerateExportFilenameCompressed(t *testing.T) {
+	service := NewExportService()
+	if name := service.GenerateExportFilename("export", "csv", true); !strings.HasSuffix(name, ".csv.gz") {
+		t.Errorf("GenerateExportFilename(compress) = %q, want a .csv.gz name", name)
+	}
+	if name := service.GenerateExportFilename("export", "json", false); !strings.HasSuffix(name, ".json") {
+		t.Errorf("GenerateExportFilename() = %q, want a .json name", name)
+	}
+}
+
+// readZipFile returns the contents of name in the zip archive at path
+func readZipFile(t *testing.T, path, name string) string {
+	t.Helper()
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("%s is not a zip archive: %v", path, err)
+	}
+	defer archive.Close()
+	file, err := archive.Open(name)
+	if err != nil {
+		t.Fatalf("%s has no %s: %v", path, name, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// xlsxRow is a worksheet row as parsed from sheet XML
+type xlsxRow struct {
+	Cells []struct {
+		Ref    string `xml:"r,attr"`
+		Style  int    `xml:"s,attr"`
+		Type   string `xml:"t,attr"`
+		Value  string `xml:"v"`
+		Inline string `xml:"is>t"`
+	} `xml:"c"`
+}
+
+// parseSheet parses the rows of a worksheet, failing on malformed XML
+func parseSheet(t *testing.T, data string) []xlsxRow {
+	t.Helper()
+	var sheet struct {
+		Pane struct {
+			State string `xml:"state,attr"`
+		} `xml:"sheetViews>sheetView>pane"`
+		Rows []xlsxRow `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal([]byte(data), &sheet); err != nil {
+		t.Fatalf("worksheet is not valid XML: %v", err)
+	}
+	if sheet.Pane.State != "frozen" {
+		t.Errorf("header row is not frozen")
+	}
+	return sheet.Rows
+}
+
+func TestXLSXExport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uploads.xlsx")
+	uploads := roundTripUploads()
+	uploads[0].Key = hostileKey
+	uploads = append(uploads, types.MultipartUpload{Bucket: "b1", Key: "x", UploadID: "u3", Initiated: time.Now(), Size: 4096, StorageClass: "STANDARD", Region: "us-east-1"})
+
+	if err := NewExportServiceWithCost(NewCostService()).ExportToXLSX(context.Background(), uploads, path); err != nil {
+		t.Fatalf("ExportToXLSX() error = %v", err)
+	}
+
+	workbook := readZipFile(t, path, "xl/workbook.xml")
+	for _, name := range []string{`name="Uploads"`, `name="By Bucket"`, `name="By Region"`} {
+		if !strings.Contains(workbook, name) {
+			t.Errorf("workbook has no sheet %s:\n%s", name, workbook)
+		}
+	}
+
+	rows := parseSheet(t, readZipFile(t, path, "xl/worksheets/sheet1.xml"))
+	if len(rows) != 4 || len(rows[0].Cells) != len(uploadCSVHeader) {
+		t.Fatalf("Uploads sheet has %d rows, want a header and 3 uploads", len(rows))
+	}
+	initiated := rows[1].Cells[3]
+	if initiated.Style != xlsxStyleDate || initiated.Type != "" {
+		t.Errorf("initiated cell = %+v, want a numeric date cell", initiated)
+	}
+	if serial, err := strconv.ParseFloat(initiated.Value, 64); err != nil || serial != xlsxSerialDate(uploads[0].Initiated) {
+		t.Errorf("initiated serial = %s, want %v", initiated.Value, xlsxSerialDate(uploads[0].Initiated))
+	}
+	if size := rows[1].Cells[5]; size.Style != xlsxStyleInteger || size.Value != "1024" {
+		t.Errorf("size cell = %+v, want the number 1024", size)
+	}
+	if key := rows[1].Cells[1].Inline; !strings.HasPrefix(key, "evil\n\r\t") || !strings.Contains(key, "_x001B_") {
+		t.Errorf("hostile key = %q, want control characters encoded as _xHHHH_", key)
+	}
+
+	// Buckets are summed with the largest first, with a cost column
+	buckets := parseSheet(t, readZipFile(t, path, "xl/worksheets/sheet2.xml"))
+	if len(buckets) != 3 || buckets[1].Cells[0].Inline != "b1" || buckets[1].Cells[1].Value != "2" || buckets[1].Cells[2].Value != "5120" {
+		t.Fatalf("By Bucket rows = %+v, want b1 first with 2 uploads and 5120 bytes", buckets)
+	}
+	if header := buckets[0].Cells; header[len(header)-1].Inline != "estimated_monthly_cost_usd" {
+		t.Errorf("By Bucket header ends with %q, want the cost column", header[len(header)-1].Inline)
+	}
+	if regions := parseSheet(t, readZipFile(t, path, "xl/worksheets/sheet3.xml")); len(regions) != 3 {
+		t.Errorf("By Region has %d rows, want a header and 2 regions", len(regions))
+	}
+}
+
+func TestXLSXRowLimit(t *testing.T) {
+	if err := checkXLSXRows(xlsxMaxRows - 1); err != nil {
+		t.Errorf("checkXLSXRows(%d) error = %v, want a full sheet allowed", xlsxMaxRows-1, err)
+	}
+	if err := checkXLSXRows(xlsxMaxRows); err == nil || !strings.Contains(err.Error(), "--max-rows 1048575") {
+		t.Errorf("checkXLSXRows(%d) error = %v, want --max-rows suggested", xlsxMaxRows, err)
+	}
+}
+
+func TestXLSXColumnNames(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(index); got != want {
+			t.Errorf("xlsxColumn(%d) = %s, want %s", index, got, want)
+		}
+	}
+}
+
+func TestXLSXEscape(t *testing.T) {
+	for input, want := range map[string]string{
+		"a<b&c":       "a&lt;b&amp;c",
+		"bell\x07":    "bell_x0007_",
+		"tmp_x0041_":  "tmp_x005F_x0041_",
+		"日本語/キー": "日本語/キー",
+	} {
+		if got := xlsxEscape(input); got != want {
+			t.Errorf("xlsxEscape(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+// streamUploads returns a closed channel holding n uploads
+func streamUploads(n int) <-chan types.MultipartUpload {
+	uploads := make(chan types.MultipartUpload, n)
+	for i := 0; i < n; i++ {
+		uploads <- types.MultipartUpload{Bucket: "b", Key: "k" + strconv.Itoa(i), UploadID: "u", Initiated: time.Now(), Size: int64(i), StorageClass: "STANDARD", Region: "us-east-1"}
+	}
+	close(uploads)
+	return uploads
+}
+
+func TestStreamExportToJSONIsValid(t *testing.T) {
+	dir := t.TempDir()
+	service := NewExportService()
+
+	for _, n := range []int{0, 1, 10000} {
+		path := filepath.Join(dir, "stream_"+strconv.Itoa(n)+".json")
+		if err := service.StreamExportToJSON(context.Background(), streamUploads(n), path); err != nil {
+			t.Fatalf("StreamExportToJSON(%d uploads) error = %v", n, err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var export struct {
+			SchemaVersion int                     `json:"schema_version"`
+			TotalCount    int                     `json:"total_count"`
+			Uploads       []types.MultipartUpload `json:"uploads"`
+		}
+		if err := json.Unmarshal(data, &export); err != nil {
+			t.Fatalf("%d-upload export is not valid JSON: %v\n%s", n, err, data)
+		}
+		if export.TotalCount != n || len(export.Uploads) != n || export.SchemaVersion != types.SchemaVersion {
+			t.Errorf("%d-upload export has total_count %d and %d uploads", n, export.TotalCount, len(export.Uploads))
+		}
+
+		// The layout matches what encoding/json would indent
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, data, "", "  "); err != nil {
+			t.Fatal(err)
+		}
+		if indented.String() != string(data) {
+			t.Errorf("%d-upload export is not consistently indented:\n%s", n, data)
+		}
+	}
+}
+
+func TestCancelledStreamExportToJSONRemovesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.json")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	uploads := make(chan types.MultipartUpload, 1)
+	uploads <- roundTripUploads()[0]
+	done := make(chan error)
+	go func() { done <- NewExportService().StreamExportToJSON(ctx, uploads, path) }()
+	for len(uploads) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("StreamExportToJSON() error = %v, want context.Canceled", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("cancelled export left %s behind (stat error %v)", path, err)
+	}
+}
+
+func TestExportSplitByBucketAndRows(t *testing.T) {
+	var uploads []types.MultipartUpload
+	for i, bucket := range []string{"logs", "index", "logs", "backups", "logs"} {
+		uploads = append(uploads, types.MultipartUpload{Bucket: bucket, Key: fmt.Sprintf("k%d", i), UploadID: "u", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"})
+	}
+	dir := t.TempDir()
+	service := &ExportService{}
+	split := func(opts types.ExportOptions) types.ExportManifest {
+		t.Helper()
+		manifest, err := service.ExportSplit(context.Background(), uploads, opts)
+		if err != nil {
+			t.Fatalf("ExportSplit(%+v) error = %v", opts, err)
+		}
+		for _, part := range manifest.Parts {
+			// Every part is a complete export with its own header
+			if part.Bytes == 0 || (opts.Format == "csv" && !IsCompressedFilename(part.File) && len(csvColumn(t, part.File, "key")) != part.Rows) {
+				t.Errorf("part %+v does not hold its rows", part)
+			}
+		}
+		return manifest
+	}
+	partNames := func(manifest types.ExportManifest) string {
+		var names []string
+		for _, part := range manifest.Parts {
+			names = append(names, fmt.Sprintf("%s:%s:%d", filepath.Base(part.File), part.Bucket, part.Rows))
+		}
+		return strings.Join(names, " ")
+	}
+
+	// Buckets in name order; the index keeps its name and the bucket called index gets a suffix
+	manifest := split(types.ExportOptions{Format: "csv", OutputFile: filepath.Join(dir, "out", "uploads.csv"), SplitBy: ExportSplitByBucket})
+	if want := "uploads_backups.csv:backups:1 uploads_index_2.csv:index:1 uploads_logs.csv:logs:3"; partNames(manifest) != want {
+		t.Errorf("parts = %s, want %s", partNames(manifest), want)
+	}
+	if manifest.Index != filepath.Join(dir, "out", "uploads_index.csv") {
+		t.Errorf("Index = %s, want uploads_index.csv next to the parts", manifest.Index)
+	}
+	if got := csvColumn(t, manifest.Index, "rows"); strings.Join(got, ",") != "1,1,3" {
+		t.Errorf("index rows = %v, want 1,1,3", got)
+	}
+
+	// Buckets with a file of their own are named after it, and still cut into chunks
+	manifest = split(types.ExportOptions{Format: "csv", OutputFile: filepath.Join(dir, "named.csv"), SplitBy: ExportSplitByBucket, MaxRows: 2,
+		BucketFiles: map[string]string{"logs": filepath.Join(dir, "logs", "uploads-logs.csv.gz")}})
+	if want := "named_backups_part001.csv:backups:1 named_index_part001.csv:index:1 uploads-logs_part001.csv.gz:logs:2 uploads-logs_part002.csv.gz:logs:1"; partNames(manifest) != want {
+		t.Errorf("parts = %s, want %s", partNames(manifest), want)
+	}
+
+	manifest = split(types.ExportOptions{Format: "json", OutputFile: filepath.Join(dir, "uploads.json"), SplitBy: ExportSplitByBucket, MaxRows: 2})
+	if want := "uploads_backups_part001.json:backups:1 uploads_index_part001.json:index:1 uploads_logs_part001.json:logs:2 uploads_logs_part002.json:logs:1"; partNames(manifest) != want {
+		t.Errorf("parts = %s, want %s", partNames(manifest), want)
+	}
+
+	// A size limit counts the header and the records before compression
+	recordSize, headerSize, _ := exportRecordSize("csv")
+	limit := headerSize + 2*recordSize(uploads[3]) // backups, the longest record
+	manifest = split(types.ExportOptions{Format: "csv", OutputFile: filepath.Join(dir, "sized.csv.gz"), MaxFileSize: limit})
+	if want := "sized_part001.csv.gz::2 sized_part002.csv.gz::2 sized_part003.csv.gz::1"; partNames(manifest) != want {
+		t.Errorf("parts = %s, want %s", partNames(manifest), want)
+	}
+	manifest = split(types.ExportOptions{Format: "csv", OutputFile: filepath.Join(dir, "tiny.csv"), MaxFileSize: 1})
+	if len(manifest.Parts) != len(uploads) {
+		t.Errorf("%d parts under a limit smaller than the header, want one per upload", len(manifest.Parts))
+	}
+
+	if _, err := service.ExportSplit(context.Background(), uploads, types.ExportOptions{Format: "xlsx", OutputFile: filepath.Join(dir, "book.xlsx"), MaxFileSize: limit}); err == nil {
+		t.Error("ExportSplit() of xlsx with MaxFileSize succeeded, want an error")
+	}
+}
+
+func TestExportWithParts(t *testing.T) {
+	dir := t.TempDir()
+	service := &ExportService{}
+	lastModified := time.Date(2024, 6, 10, 14, 30, 0, 0, time.UTC)
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.bin", UploadID: "u1", Initiated: lastModified.Add(-time.Hour), Size: 30, PartsCount: 2, Region: "us-east-1",
+			Parts: []types.UploadPart{{PartNumber: 1, Size: 10, ETag: `"e1"`, LastModified: lastModified}, {PartNumber: 2, Size: 20, ETag: `"e2"`, LastModified: lastModified}}},
+		{Bucket: "logs", Key: "b.bin", UploadID: "u2", Initiated: lastModified.Add(-time.Hour), Region: "us-east-1"},
+	}
+
+	// CSV exports get a second file with a row per part
+	files, err := service.ExportWithParts(context.Background(), uploads, "csv", filepath.Join(dir, "uploads.csv.gz"))
+	if err != nil {
+		t.Fatalf("ExportWithParts(csv) error = %v", err)
+	}
+	partsFile := filepath.Join(dir, "uploads_parts.csv.gz")
+	if len(files) != 2 || files[1] != partsFile {
+		t.Fatalf("ExportWithParts(csv) files = %v, want the export and %s", files, partsFile)
+	}
+	file, err := os.Open(partsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(gz).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"upload_id", "part_number", "size", "etag", "last_modified"},
+		{"u1", "1", "10", `"e1"`, types.FormatTimestamp(lastModified)},
+		{"u1", "2", "20", `"e2"`, types.FormatTimestamp(lastModified)},
+	}
+	if fmt.Sprint(records) != fmt.Sprint(want) {
+		t.Errorf("parts CSV = %q, want %q", records, want)
+	}
+
+	// JSON exports nest the parts in each upload
+	jsonFile := filepath.Join(dir, "uploads.json")
+	if _, err := service.ExportWithParts(context.Background(), uploads, "json", jsonFile); err != nil {
+		t.Fatalf("ExportWithParts(json) error = %v", err)
+	}
+	data, err := os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var export struct {
+		Uploads []types.MultipartUpload `json:"uploads"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		t.Fatalf("export is not JSON: %v", err)
+	}
+	if len(export.Uploads) != 2 || len(export.Uploads[0].Parts) != 2 || export.Uploads[0].Parts[1].ETag != `"e2"` || export.Uploads[1].Parts != nil {
+		t.Errorf("JSON uploads = %+v, want the parts nested in the first", export.Uploads)
+	}
+
+	if _, err := service.ExportWithParts(context.Background(), uploads, "xlsx", filepath.Join(dir, "uploads.xlsx")); err == nil {
+		t.Error("ExportWithParts(xlsx) succeeded, want an error")
+	}
+}
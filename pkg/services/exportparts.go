@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// partCSVHeader is the header of the parts file of a CSV export
+var partCSVHeader = []string{"upload_id", "part_number", "size", "etag", "last_modified"}
+
+// PartsFilename returns the name of the parts file of a CSV export, e.g.
+// "uploads_parts.csv.gz" for "uploads.csv.gz"
+func PartsFilename(filename string) string {
+	stem, ext := splitExportFilename(filename)
+	if ext == "" {
+		ext = ".csv"
+	}
+	return stem + "_parts" + ext
+}
+
+// ExportWithParts exports uploads whose Parts were listed, streaming them
+// so that no part is held twice in memory. JSON exports nest each upload's
+// parts in it; CSV exports write them to a second file named by
+// PartsFilename, one row per part. It returns the files written.
+func (e *ExportService) ExportWithParts(ctx context.Context, uploads []types.MultipartUpload, format string, filename string) ([]string, error) {
+	stream := map[string]func(context.Context, <-chan types.MultipartUpload, string) error{
+		"json": e.StreamExportToJSON,
+		"csv":  e.StreamExportToCSV,
+	}[format]
+	if stream == nil {
+		return nil, fmt.Errorf("parts cannot be exported as %s (supported: csv, json)", format)
+	}
+
+	feedCtx, cancel := context.WithCancel(ctx)
+	defer cancel() // stops the feed if the writer gives up early
+	if err := stream(feedCtx, feedUploads(feedCtx, uploads), filename); err != nil {
+		return nil, err
+	}
+	// A feed cut short by cancellation ends the stream as if it were complete
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if format == "json" {
+		return []string{filename}, nil
+	}
+	partsFile := PartsFilename(filename)
+	if err := writePartsCSV(ctx, uploads, partsFile); err != nil {
+		return nil, err
+	}
+	return []string{filename, partsFile}, nil
+}
+
+// feedUploads sends uploads on a channel for the streaming writers until
+// they are all sent or ctx ends
+func feedUploads(ctx context.Context, uploads []types.MultipartUpload) <-chan types.MultipartUpload {
+	feed := make(chan types.MultipartUpload)
+	go func() {
+		defer close(feed)
+		for _, upload := range uploads {
+			select {
+			case feed <- upload:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return feed
+}
+
+// writePartsCSV writes one row for every part of uploads to filename
+func writePartsCSV(ctx context.Context, uploads []types.MultipartUpload, filename string) (err error) {
+	file, err := openExportWriter(filename)
+	if err != nil {
+		return err
+	}
+	defer closeExport(file, filename, &err)
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(partCSVHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	record := make([]string, 0, len(partCSVHeader))
+	for _, upload := range uploads {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, part := range upload.Parts {
+			lastModified := ""
+			if !part.LastModified.IsZero() {
+				lastModified = types.FormatTimestamp(part.LastModified)
+			}
+			record = append(record[:0], upload.UploadID, strconv.Itoa(int(part.PartNumber)), strconv.FormatInt(part.Size, 10), part.ETag, lastModified)
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("failed to write CSV record: %w", err)
+			}
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
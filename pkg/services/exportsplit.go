@@ -0,0 +1,220 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// ExportSplitByBucket is the SplitBy value that writes one file per bucket
+const ExportSplitByBucket = "bucket"
+
+// exportIndexHeader is the header of the index file of a split export
+var exportIndexHeader = []string{"file", "bucket", "rows", "bytes"}
+
+// ExportSplit exports uploads in opts.Format to several files named after
+// opts.OutputFile: one per bucket with opts.SplitBy "bucket", each cut into
+// numbered chunks of at most opts.MaxRows uploads and opts.MaxFileSize bytes
+// before compression. A bucket in opts.BucketFiles is named after its file
+// there instead. Every file is a complete export with its own header.
+// An index CSV next to them lists the files with their row counts and sizes.
+func (e *ExportService) ExportSplit(ctx context.Context, uploads []types.MultipartUpload, opts types.ExportOptions) (types.ExportManifest, error) {
+	var manifest types.ExportManifest
+	if opts.SplitBy != "" && opts.SplitBy != ExportSplitByBucket {
+		return manifest, fmt.Errorf("unsupported split %q (supported: %s)", opts.SplitBy, ExportSplitByBucket)
+	}
+	if opts.MaxRows < 0 || opts.MaxFileSize < 0 {
+		return manifest, fmt.Errorf("the maximum rows and file size cannot be negative")
+	}
+	recordSize, headerSize, err := exportRecordSize(opts.Format)
+	if err != nil && opts.MaxFileSize > 0 {
+		return manifest, err
+	}
+
+	stem, ext := splitExportFilename(opts.OutputFile)
+	names := make(exportNames)
+	// The index is named first, so a bucket of the same name gets the suffix
+	manifest.Index = names.unique(stem+"_index") + ".csv"
+
+	groups := []*uploadGroup{{uploads: uploads}}
+	if opts.SplitBy == ExportSplitByBucket {
+		groups = groupUploads(uploads, func(u types.MultipartUpload) string { return u.Bucket })
+		sort.Slice(groups, func(i, j int) bool { return groups[i].name < groups[j].name })
+	}
+	chunked := opts.MaxRows > 0 || opts.MaxFileSize > 0
+	// The header counts toward the size; a limit it fills alone leaves one upload per file
+	var maxRecordBytes int64
+	if opts.MaxFileSize > 0 {
+		maxRecordBytes = max(opts.MaxFileSize-headerSize, 1)
+	}
+
+	for _, group := range groups {
+		chunks := [][]types.MultipartUpload{group.uploads}
+		if chunked {
+			chunks = chunkUploads(group.uploads, opts.MaxRows, maxRecordBytes, recordSize)
+		}
+		groupStem, groupExt := stem, ext
+		if file, ok := opts.BucketFiles[group.name]; ok && group.name != "" {
+			groupStem, groupExt = splitExportFilename(file)
+		} else if group.name != "" {
+			groupStem += "_" + sanitizeFilenamePart(group.name)
+		}
+		for i, chunk := range chunks {
+			name := groupStem
+			if chunked {
+				name += fmt.Sprintf("_part%03d", i+1)
+			}
+			filename := names.unique(name) + groupExt
+
+			if err := e.exportFile(ctx, opts.Format, chunk, filename); err != nil {
+				return manifest, err
+			}
+			info, err := os.Stat(filename)
+			if err != nil {
+				return manifest, fmt.Errorf("failed to read size of %s: %w", filename, err)
+			}
+			manifest.Parts = append(manifest.Parts, types.ExportPart{File: filename, Bucket: group.name, Rows: len(chunk), Bytes: info.Size()})
+		}
+	}
+
+	if err := writeExportIndex(manifest); err != nil {
+		return manifest, err
+	}
+	return manifest, nil
+}
+
+// exportFile writes uploads to filename in format
+func (e *ExportService) exportFile(ctx context.Context, format string, uploads []types.MultipartUpload, filename string) error {
+	switch format {
+	case "csv":
+		return e.ExportToCSV(ctx, uploads, filename)
+	case "json":
+		return e.ExportToJSON(ctx, uploads, filename)
+	case "xlsx":
+		return e.ExportToXLSX(ctx, uploads, filename)
+	}
+	return fmt.Errorf("invalid format: %q (must be csv, json or xlsx)", format)
+}
+
+// writeExportIndex writes the index CSV of a split export
+func writeExportIndex(manifest types.ExportManifest) (err error) {
+	file, err := openExportWriter(manifest.Index)
+	if err != nil {
+		return err
+	}
+	defer closeExport(file, manifest.Index, &err)
+
+	writer := csv.NewWriter(file)
+	writer.Write(exportIndexHeader)
+	for _, part := range manifest.Parts {
+		writer.Write([]string{part.File, part.Bucket, strconv.Itoa(part.Rows), strconv.FormatInt(part.Bytes, 10)})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", manifest.Index, err)
+	}
+	return nil
+}
+
+// jsonExportOverhead bounds the bytes of a JSON export around its uploads
+const jsonExportOverhead = 128
+
+// exportRecordSize returns how many bytes one upload takes in an export of
+// format before compression, and how many the rest of the file takes.
+// Workbooks are compressed as they are written, so their size cannot be limited.
+func exportRecordSize(format string) (func(types.MultipartUpload) int64, int64, error) {
+	switch format {
+	case "csv":
+		size := func(record []string) int64 {
+			var buf bytes.Buffer
+			writer := csv.NewWriter(&buf)
+			writer.Write(record)
+			writer.Flush()
+			return int64(buf.Len())
+		}
+		return func(upload types.MultipartUpload) int64 {
+			return size(appendUploadCSVRecord(nil, upload))
+		}, size(uploadCSVHeader), nil
+	case "json":
+		return func(upload types.MultipartUpload) int64 {
+			// Elements are indented two levels and separated by ",\n"
+			data, _ := json.MarshalIndent(upload, "    ", "  ")
+			return int64(len(data) + len(",\n    "))
+		}, jsonExportOverhead, nil
+	}
+	return nil, 0, fmt.Errorf("the file size of %s exports cannot be limited; limit the rows instead", format)
+}
+
+// chunkUploads cuts uploads into chunks of at most maxRows uploads whose
+// records add up to at most maxBytes; zero limits are not applied. An upload
+// larger than maxBytes gets a chunk of its own.
+func chunkUploads(uploads []types.MultipartUpload, maxRows int, maxBytes int64, recordSize func(types.MultipartUpload) int64) [][]types.MultipartUpload {
+	var chunks [][]types.MultipartUpload
+	var chunk []types.MultipartUpload
+	var chunkBytes int64
+	for _, upload := range uploads {
+		var size int64
+		if maxBytes > 0 {
+			size = recordSize(upload)
+		}
+		full := (maxRows > 0 && len(chunk) == maxRows) || (maxBytes > 0 && chunkBytes+size > maxBytes)
+		if len(chunk) > 0 && full {
+			chunks = append(chunks, chunk)
+			chunk, chunkBytes = nil, 0
+		}
+		chunk = append(chunk, upload)
+		chunkBytes += size
+	}
+	if len(chunk) > 0 || len(chunks) == 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// splitExportFilename splits an export filename into the part that names
+// its files and the extensions they share, e.g. "out/uploads" and ".csv.gz"
+func splitExportFilename(filename string) (stem, ext string) {
+	lower := strings.ToLower(filename)
+	for _, suffix := range []string{".csv", ".json", ".xlsx"} {
+		for _, candidate := range []string{suffix + gzipSuffix, suffix} {
+			if strings.HasSuffix(lower, candidate) {
+				cut := len(filename) - len(candidate)
+				return filename[:cut], filename[cut:]
+			}
+		}
+	}
+	return filename, ""
+}
+
+// sanitizeFilenamePart replaces what cannot safely appear in a file name
+// with underscores, such as the separators of a path
+func sanitizeFilenamePart(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// exportNames hands out file names, numbering the repeats of a name in the
+// order they are asked for
+type exportNames map[string]bool
+
+// unique returns name, or name with the lowest suffix _2, _3, ... not handed out yet
+func (n exportNames) unique(name string) string {
+	candidate := name
+	for i := 2; n[candidate]; i++ {
+		candidate = fmt.Sprintf("%s_%d", name, i)
+	}
+	n[candidate] = true
+	return candidate
+}
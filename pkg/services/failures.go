@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// FailureCategoryInterrupted is the error category of uploads that were not
+// deleted because the run was interrupted
+const FailureCategoryInterrupted = "interrupted"
+
+// uploadsField separates a marshalled FailureReport into the fields written
+// before the failed uploads and those written after them
+var uploadsField = []byte(`"uploads":[`)
+
+// FailureWriter writes a failure report while a delete runs. The file is
+// created on the first failure and every failure is written as it is recorded,
+// so a run that is killed still leaves the failures seen so far; LoadUploadsFile
+// reads such a truncated report.
+type FailureWriter struct {
+	filename string
+	report   types.FailureReport
+	now      func() time.Time
+
+	mutex  sync.Mutex
+	file   *os.File
+	failed int
+	err    error // first write error, returned by Close
+}
+
+// NewFailureWriter returns a writer for filename. The report holds the run
+// metadata; its Uploads and summary fields are filled in by the writer.
+func NewFailureWriter(filename string, report types.FailureReport) *FailureWriter {
+	report.SchemaVersion = types.SchemaVersion
+	report.Kind = types.FailureReportKind
+	if report.GeneratedAt.IsZero() {
+		report.GeneratedAt = time.Now().UTC()
+	}
+	return &FailureWriter{filename: filename, report: report, now: time.Now}
+}
+
+// Filename returns the path of the report
+func (w *FailureWriter) Filename() string {
+	return w.filename
+}
+
+// Failed returns the number of failures recorded
+func (w *FailureWriter) Failed() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.failed
+}
+
+// Record writes a failed upload to the report. It is safe for concurrent use.
+func (w *FailureWriter) Record(upload types.MultipartUpload, err error) error {
+	class := awsclient.ClassifyError(err)
+	record := types.FailedUpload{MultipartUpload: upload, UploadFailure: types.UploadFailure{
+		Error:         err.Error(),
+		ErrorCode:     class.Code,
+		ErrorCategory: string(class.Category),
+		Attempts:      awsclient.Attempts(err),
+		FailedAt:      w.now().UTC(),
+	}}
+	if interrupted(err) {
+		record.ErrorCategory = FailureCategoryInterrupted
+	}
+
+	data, marshalErr := json.Marshal(record)
+	if marshalErr != nil {
+		return fmt.Errorf("failed to encode failure for %s/%s: %w", upload.Bucket, upload.Key, marshalErr)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+	separator := []byte(",\n")
+	if w.file == nil {
+		if w.err = w.open(); w.err != nil {
+			return w.err
+		}
+		separator = nil
+	}
+	if _, w.err = w.file.Write(append(separator, data...)); w.err != nil {
+		w.err = fmt.Errorf("failed to write failure report %s: %w", w.filename, w.err)
+		return w.err
+	}
+	w.failed++
+	return nil
+}
+
+// open creates the report file and writes the run metadata
+func (w *FailureWriter) open() error {
+	header, _, err := w.marshalReport()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(w.filename)
+	if err != nil {
+		return fmt.Errorf("failed to create failure report %s: %w", w.filename, err)
+	}
+	if _, err := file.Write(append(header, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write failure report %s: %w", w.filename, err)
+	}
+	w.file = file
+	return nil
+}
+
+// interrupted reports whether err ended a deletion because the run was
+// interrupted or ran out of time, rather than because the call failed. An
+// attempt abandoned after --request-timeout is a failure of that call.
+func interrupted(err error) bool {
+	var requestTimeout *awsclient.RequestTimeoutError
+	if errors.As(err, &requestTimeout) {
+		return false
+	}
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Close completes the report with the run summary. Nothing is written when no
+// upload failed.
+func (w *FailureWriter) Close(interrupted bool) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	file := w.file
+	if file == nil {
+		return w.err
+	}
+	w.file = nil
+	if w.err != nil {
+		file.Close()
+		return w.err
+	}
+
+	w.report.FinishedAt = w.now().UTC()
+	w.report.Failed = w.failed
+	w.report.Interrupted = interrupted
+
+	_, trailer, err := w.marshalReport()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(append(append([]byte("\n"), trailer...), '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write failure report %s: %w", w.filename, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close failure report %s: %w", w.filename, err)
+	}
+	return nil
+}
+
+// marshalReport encodes the report without uploads and splits it around the
+// uploads list: the header ends with its opening bracket, the trailer starts
+// with its closing one. Field values are JSON-escaped, so the first match of
+// uploadsField is the field itself.
+func (w *FailureWriter) marshalReport() ([]byte, []byte, error) {
+	report := w.report
+	report.Uploads = []types.FailedUpload{}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode failure report: %w", err)
+	}
+	i := bytes.Index(data, uploadsField) + len(uploadsField)
+	return data[:i], data[i:], nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+
+	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestFailureReportIsReadableByFromFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "failures.json")
+	startedAt := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	writer := NewFailureWriter(filename, types.FailureReport{Command: "s3mpc delete --older-than=7d", Version: "test", GeneratedAt: startedAt})
+
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.tmp", UploadID: "1", Initiated: startedAt.Add(-72 * time.Hour), Region: "us-east-1"},
+		{Bucket: "media", Key: "b.tmp", UploadID: "2", Initiated: startedAt.Add(-96 * time.Hour), Region: "eu-west-1"},
+	}
+	denied := &awsclient.AttemptsError{Attempts: 1, Err: &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"}}
+	interrupted := fmt.Errorf("failed to abort multipart upload 2 in bucket media: %w", &awsclient.AttemptsError{Attempts: 0, Err: context.Canceled})
+	if err := writer.Record(uploads[0], denied); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each failure is on disk as soon as it is recorded
+	if _, err := os.Stat(filename); err != nil {
+		t.Fatalf("report not written after the first failure: %v", err)
+	}
+	if err := writer.Record(uploads[1], interrupted); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(true); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report types.FailureReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("report is not valid JSON: %v\n%s", err, data)
+	}
+	if report.Kind != types.FailureReportKind || report.Failed != 2 || !report.Interrupted || report.FinishedAt.IsZero() || report.Command != "s3mpc delete --older-than=7d" {
+		t.Errorf("report metadata = %+v", report)
+	}
+	if got := report.Uploads[0]; got.ErrorCode != "AccessDenied" || got.ErrorCategory != string(awsclient.CategoryPermission) || got.Attempts != 1 {
+		t.Errorf("first failure = %+v, want AccessDenied after 1 attempt", got)
+	}
+	if got := report.Uploads[1]; got.ErrorCategory != FailureCategoryInterrupted || got.Attempts != 0 {
+		t.Errorf("second failure = %+v, want an interrupted upload that was never attempted", got)
+	}
+
+	file, err := LoadUploadsFile(filename)
+	if err != nil {
+		t.Fatalf("LoadUploadsFile() error = %v", err)
+	}
+	if file.Kind != UploadsFileFailures || file.Incomplete || !file.GeneratedAt.Equal(startedAt) || len(file.Uploads) != 2 || !reflect.DeepEqual(file.Uploads[1], uploads[1]) {
+		t.Errorf("LoadUploadsFile() = %+v", file)
+	}
+}
+
+func TestTruncatedFailureReportKeepsCompleteUploads(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "failures.json")
+	writer := NewFailureWriter(filename, types.FailureReport{Command: "s3mpc delete"})
+	for i := 0; i < 3; i++ {
+		upload := types.MultipartUpload{Bucket: "logs", Key: fmt.Sprintf("%d.tmp", i), UploadID: fmt.Sprint(i), Initiated: time.Now().Add(-time.Hour)}
+		if err := writer.Record(upload, context.Canceled); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A killed run never writes the summary, and may stop mid-record
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := filepath.Join(dir, "truncated.json")
+	if err := os.WriteFile(truncated, data[:len(data)-10], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := LoadUploadsFile(truncated)
+	if err != nil {
+		t.Fatalf("LoadUploadsFile() error = %v", err)
+	}
+	if file.Kind != UploadsFileFailures || !file.Incomplete || len(file.Uploads) != 2 || file.Uploads[1].Key != "1.tmp" {
+		t.Errorf("LoadUploadsFile() = %+v, want the two complete uploads of an incomplete report", file)
+	}
+
+	// Other truncated JSON is still rejected
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte(`{"uploads":[{"bucket":"logs"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadUploadsFile(broken); err == nil {
+		t.Error("LoadUploadsFile() accepted truncated JSON that is not a failure report")
+	}
+}
+
+func TestFailureWriterWritesNothingWithoutFailures(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "failures.json")
+	if err := NewFailureWriter(filename, types.FailureReport{}).Close(false); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("report written although nothing failed: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultTimestampFormat is the layout of the timestamp in generated file names
+const DefaultTimestampFormat = "20060102_1504"
+
+// FilenamePlaceholders are the fields a filename template can use
+var FilenamePlaceholders = []string{"Command", "Timestamp", "Account", "Region", "Bucket", "Format", "Filter"}
+
+// FilenameData are the values a filename template is rendered with. Empty
+// values are rendered as "all", such as the bucket of a run over every bucket.
+type FilenameData struct {
+	Command   string
+	Timestamp string // set by Render
+	Account   string
+	Region    string
+	Bucket    string
+	Format    string // the extension, such as csv
+	Filter    string
+}
+
+// FilenameTemplate names the files a command writes, such as
+// "reports/{{.Account}}/{{.Timestamp}}/uploads-{{.Bucket}}.{{.Format}}"
+type FilenameTemplate struct {
+	tmpl            *template.Template
+	timestampFormat string
+}
+
+// ParseFilenameTemplate parses a filename template, rejecting placeholders
+// that are not in FilenamePlaceholders, and the layout its timestamp is
+// formatted with (DefaultTimestampFormat when empty)
+func ParseFilenameTemplate(text, timestampFormat string) (*FilenameTemplate, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("the template is empty")
+	}
+	if timestampFormat == "" {
+		timestampFormat = DefaultTimestampFormat
+	}
+	// A layout without date or time elements would give every run the same name
+	reference := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	if reference.Format(timestampFormat) == timestampFormat {
+		return nil, fmt.Errorf("timestamp format %q has no date or time elements, e.g. %s", timestampFormat, DefaultTimestampFormat)
+	}
+
+	tmpl, err := template.New("filename").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	t := &FilenameTemplate{tmpl: tmpl, timestampFormat: timestampFormat}
+	// Fields are resolved when the template runs, so unknown ones only fail then
+	if _, err := t.execute(FilenameData{}); err != nil {
+		return nil, fmt.Errorf("%w (placeholders: {{.%s}})", err, strings.Join(FilenamePlaceholders, "}}, {{."))
+	}
+	return t, nil
+}
+
+// Uses reports whether the template renders field, so that values which
+// cost an API call, such as the account, are only looked up when needed
+func (t *FilenameTemplate) Uses(field string) bool {
+	const marker = "\x00"
+	var data FilenameData
+	switch field {
+	case "Account":
+		data.Account = marker
+	case "Region":
+		data.Region = marker
+	case "Bucket":
+		data.Bucket = marker
+	case "Filter":
+		data.Filter = marker
+	default:
+		return true
+	}
+	name, _ := t.execute(data)
+	return strings.Contains(name, marker)
+}
+
+// Render returns the file name for data, with the timestamp of now. Values
+// are made safe for file names, so only the template itself adds
+// directories. The name ends in "." and the format unless the template
+// already ends that way.
+func (t *FilenameTemplate) Render(data FilenameData, now time.Time) (string, error) {
+	data.Timestamp = now.Format(t.timestampFormat)
+	for _, value := range []*string{&data.Command, &data.Timestamp, &data.Account, &data.Region, &data.Bucket, &data.Format, &data.Filter} {
+		if *value == "" {
+			*value = "all"
+		}
+		*value = sanitizeFilenamePart(*value)
+	}
+
+	name, err := t.execute(data)
+	if err != nil {
+		return "", err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || strings.HasSuffix(name, "/") {
+		return "", fmt.Errorf("the template renders %q, which is not a file name", name)
+	}
+	if !strings.HasSuffix(strings.ToLower(name), "."+strings.ToLower(data.Format)) {
+		name += "." + data.Format
+	}
+	return name, nil
+}
+
+func (t *FilenameTemplate) execute(data FilenameData) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
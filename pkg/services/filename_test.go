@@ -0,0 +1,55 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilenameTemplateRender(t *testing.T) {
+	names, err := ParseFilenameTemplate("reports/{{.Account}}/{{.Timestamp}}/uploads-{{.Bucket}}", "2006-01-02")
+	if err != nil {
+		t.Fatalf("ParseFilenameTemplate() error = %v", err)
+	}
+	now := time.Date(2024, 6, 10, 14, 30, 0, 0, time.UTC)
+
+	name, err := names.Render(FilenameData{Command: "export", Account: "123456789012", Bucket: "logs/2024", Format: "csv"}, now)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	// Values cannot add directories, and the extension is added
+	if want := "reports/123456789012/2024-06-10/uploads-logs_2024.csv"; name != want {
+		t.Errorf("Render() = %q, want %q", name, want)
+	}
+	if name, _ := names.Render(FilenameData{Format: "json"}, now); name != "reports/all/2024-06-10/uploads-all.json" {
+		t.Errorf("Render() without values = %q, want them rendered as all", name)
+	}
+
+	if !names.Uses("Account") || names.Uses("Filter") {
+		t.Error("Uses() should report the Account placeholder and not Filter")
+	}
+}
+
+func TestParseFilenameTemplateDefaults(t *testing.T) {
+	names, err := ParseFilenameTemplate("s3mpc_{{.Command}}_{{.Timestamp}}.{{.Format}}", "")
+	if err != nil {
+		t.Fatalf("ParseFilenameTemplate() error = %v", err)
+	}
+	now := time.Date(2024, 6, 10, 14, 30, 0, 0, time.UTC)
+	if name, _ := names.Render(FilenameData{Command: "export", Format: "csv"}, now); name != "s3mpc_export_20240610_1430.csv" {
+		t.Errorf("Render() = %q, want the default timestamp and no second extension", name)
+	}
+
+	for _, test := range []struct {
+		text, layout, err string
+	}{
+		{"", "", "empty"},
+		{"{{.Owner}}", "", "can't evaluate field Owner"},
+		{"{{.Bucket", "", "unclosed action"},
+		{"{{.Bucket}}", "today", "no date or time elements"},
+	} {
+		if _, err := ParseFilenameTemplate(test.text, test.layout); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("ParseFilenameTemplate(%q, %q) error = %v, want %s", test.text, test.layout, err, test.err)
+		}
+	}
+}
@@ -3,20 +3,38 @@ package services
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Garvitkul/s3mpc/internal/term"
+
 	"github.com/Garvitkul/s3mpc/pkg/interfaces"
 	"github.com/Garvitkul/s3mpc/pkg/types"
+	"github.com/Garvitkul/s3mpc/pkg/units"
 )
 
+// minTableColumnWidth is the narrowest a column is shrunk to when fitting a table to the terminal
+const minTableColumnWidth = 6
+
 // OutputFormatter implements the interfaces.OutputFormatter interface
-type OutputFormatter struct{}
+type OutputFormatter struct {
+	terminal term.Terminal
+	unicode  bool // draw histogram bars with block characters rather than "#"
+}
 
-// NewOutputFormatter creates a new OutputFormatter instance
+// NewOutputFormatter creates a new OutputFormatter instance that does not fit
+// tables to a terminal
 func NewOutputFormatter() interfaces.OutputFormatter {
-	return &OutputFormatter{}
+	return NewOutputFormatterWithTerminal(nil)
+}
+
+// NewOutputFormatterWithTerminal creates an OutputFormatter that fits tables to
+// the terminal's width at the time each table is formatted
+func NewOutputFormatterWithTerminal(terminal term.Terminal) interfaces.OutputFormatter {
+	return &OutputFormatter{terminal: terminal, unicode: term.SupportsUnicode()}
 }
 
 // FormatUploads formats uploads for human-readable console output
@@ -29,27 +47,7 @@ func (f *OutputFormatter) FormatUploads(uploads []types.MultipartUpload, showDet
 	
 	if showDetails {
 		// Detailed format with table
-		headers := []string{"Bucket", "Key", "Upload ID", "Initiated", "Age", "Size", "Storage Class", "Region"}
-		var rows [][]string
-		
-		for _, upload := range uploads {
-			age := time.Since(upload.Initiated)
-			ageStr := formatDuration(age)
-			sizeStr := FormatBytes(upload.Size)
-			
-			rows = append(rows, []string{
-				upload.Bucket,
-				truncateString(upload.Key, 40),
-				truncateString(upload.UploadID, 20),
-				upload.Initiated.Format("2006-01-02 15:04"),
-				ageStr,
-				sizeStr,
-				upload.StorageClass,
-				upload.Region,
-			})
-		}
-		
-		result.WriteString(f.FormatTable(headers, rows))
+		result.WriteString(f.formatUploadTable(uploads, interfaces.UploadTableOptions{}))
 	} else {
 		// Summary format
 		result.WriteString(fmt.Sprintf("Found %d incomplete multipart uploads:\n\n", len(uploads)))
@@ -57,10 +55,18 @@ func (f *OutputFormatter) FormatUploads(uploads []types.MultipartUpload, showDet
 		// Group by bucket for summary
 		bucketCounts := make(map[string]int)
 		bucketSizes := make(map[string]int64)
+		serviceInitiated := 0
+		superseded := 0
 		
 		for _, upload := range uploads {
 			bucketCounts[upload.Bucket]++
 			bucketSizes[upload.Bucket] += upload.Size
+			if upload.ServiceInitiated {
+				serviceInitiated++
+			}
+			if upload.Superseded {
+				superseded++
+			}
 		}
 		
 		// Sort buckets by name
@@ -73,19 +79,417 @@ func (f *OutputFormatter) FormatUploads(uploads []types.MultipartUpload, showDet
 		for _, bucket := range buckets {
 			count := bucketCounts[bucket]
 			size := bucketSizes[bucket]
-			result.WriteString(fmt.Sprintf("  %s: %d uploads (%s)\n", bucket, count, FormatBytes(size)))
+			result.WriteString(fmt.Sprintf("  %s: %d uploads (%s)\n", types.DisplayString(bucket), count, units.FormatBytes(size)))
+		}
+		
+		if serviceInitiated > 0 {
+			result.WriteString(fmt.Sprintf("\n  Service-initiated: %d uploads (excluded from deletion by default)\n", serviceInitiated))
+		}
+		if superseded > 0 {
+			result.WriteString(fmt.Sprintf("\n  Probably superseded: %d uploads (a newer object exists at the key)\n", superseded))
 		}
 	}
 	
 	return result.String()
 }
 
+// FormatUploadTable formats uploads as the detailed table, adding who
+// initiated and who owns each upload when showOwner is set
+func (f *OutputFormatter) FormatUploadTable(uploads []types.MultipartUpload, showOwner bool) string {
+	return f.FormatUploadColumns(uploads, interfaces.UploadTableOptions{ShowOwner: showOwner})
+}
+
+// FormatUploadColumns formats uploads as a table with the columns chosen in
+// options, or the detailed table when none are
+func (f *OutputFormatter) FormatUploadColumns(uploads []types.MultipartUpload, options interfaces.UploadTableOptions) string {
+	if len(uploads) == 0 {
+		return "No incomplete multipart uploads found."
+	}
+	return f.formatUploadTable(uploads, options)
+}
+
+// FormatUploadGroups formats each group as a heading with its count and size
+// followed by its table, then the totals across all groups
+func (f *OutputFormatter) FormatUploadGroups(groups []types.UploadGroup, options interfaces.UploadTableOptions) string {
+	if len(groups) == 0 {
+		return "No incomplete multipart uploads found."
+	}
+
+	var result strings.Builder
+	var totalCount int
+	var totalSize int64
+	for i, group := range groups {
+		if i > 0 {
+			result.WriteString("\n")
+		}
+		result.WriteString(fmt.Sprintf("%s: %s (%s)\n", types.DisplayString(group.Name), pluralUploads(group.Count), units.FormatBytes(group.Size)))
+		result.WriteString(f.formatUploadTable(group.Uploads, options))
+		totalCount += group.Count
+		totalSize += group.Size
+	}
+
+	groupNoun := "groups"
+	if len(groups) == 1 {
+		groupNoun = "group"
+	}
+	result.WriteString(fmt.Sprintf("\nTotal: %s (%s) in %d %s\n", pluralUploads(totalCount), units.FormatBytes(totalSize), len(groups), groupNoun))
+	return result.String()
+}
+
+// pluralUploads returns "1 upload" or "n uploads"
+func pluralUploads(count int) string {
+	if count == 1 {
+		return "1 upload"
+	}
+	return fmt.Sprintf("%d uploads", count)
+}
+
+// uploadColumn is a column that can be picked with list --columns
+type uploadColumn struct {
+	name   string
+	header string
+	value  func(upload types.MultipartUpload) string
+}
+
+// uploadColumns are the columns list --columns picks from
+var uploadColumns = []uploadColumn{
+	{"bucket", "Bucket", func(upload types.MultipartUpload) string { return upload.Bucket }},
+	{"key", "Key", func(upload types.MultipartUpload) string { return upload.Key }},
+	{"upload-id", "Upload ID", func(upload types.MultipartUpload) string { return upload.UploadID }},
+	{"initiated", "Initiated", func(upload types.MultipartUpload) string { return upload.Initiated.Format("2006-01-02 15:04") }},
+	{"age", "Age", func(upload types.MultipartUpload) string { return units.FormatDuration(time.Since(upload.Initiated)) }},
+	{"size", "Size", func(upload types.MultipartUpload) string { return units.FormatBytes(upload.Size) }},
+	{"storage-class", "Storage Class", func(upload types.MultipartUpload) string { return upload.StorageClass }},
+	{"region", "Region", func(upload types.MultipartUpload) string { return upload.Region }},
+	{"owner", "Owner", func(upload types.MultipartUpload) string { return firstNonEmpty(upload.Owner, upload.OwnerID) }},
+	{"parts", "Parts", formatPartsCount},
+	{"auto-abort", "Auto-Abort On", formatAbortDate},
+	{"cost", "Est. Cost/mo", formatEstimatedCost},
+}
+
+// ColumnsNeedMeasuring reports whether any of columns shows what only walking
+// an upload's parts finds: its size, parts, abort date or the cost of its size
+func ColumnsNeedMeasuring(columns []string) bool {
+	for _, name := range columns {
+		switch name {
+		case "size", "parts", "auto-abort", "cost":
+			return true
+		}
+	}
+	return false
+}
+
+// UploadColumnNames returns the names of the columns list --columns accepts
+func UploadColumnNames() []string {
+	names := make([]string, len(uploadColumns))
+	for i, column := range uploadColumns {
+		names[i] = column.name
+	}
+	return names
+}
+
+// ParseUploadColumns parses a comma-separated --columns value such as
+// bucket,key,age,size into column names, keeping their order
+func ParseUploadColumns(spec string) ([]string, error) {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := findUploadColumn(name); !ok {
+			return nil, fmt.Errorf("invalid column %q, valid columns: %s", name, strings.Join(UploadColumnNames(), ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("column %q is listed more than once", name)
+		}
+		seen[name] = true
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
+
+// findUploadColumn returns the column called name
+func findUploadColumn(name string) (uploadColumn, bool) {
+	for _, column := range uploadColumns {
+		if column.name == name {
+			return column, true
+		}
+	}
+	return uploadColumn{}, false
+}
+
+// formatUploadTable formats uploads with the columns in options, or the
+// detailed table when none are chosen
+func (f *OutputFormatter) formatUploadTable(uploads []types.MultipartUpload, options interfaces.UploadTableOptions) string {
+	if len(options.Columns) == 0 {
+		return f.formatDetailedTable(uploads, options)
+	}
+
+	headers := make([]string, len(options.Columns))
+	columns := make([]uploadColumn, len(options.Columns))
+	for i, name := range options.Columns {
+		column, ok := findUploadColumn(name)
+		if !ok {
+			column = uploadColumn{name: name, header: name, value: func(types.MultipartUpload) string { return "" }}
+		}
+		headers[i] = column.header
+		columns[i] = column
+	}
+
+	rows := make([][]string, len(uploads))
+	for i, upload := range uploads {
+		row := make([]string, len(columns))
+		for j, column := range columns {
+			row[j] = column.value(upload)
+		}
+		rows[i] = row
+	}
+
+	// Cells are only cut to fit the terminal, so long keys use whatever width is free
+	return f.formatTable(headers, rows, !options.Wide)
+}
+
+// formatDetailedTable formats the default table of uploads
+func (f *OutputFormatter) formatDetailedTable(uploads []types.MultipartUpload, options interfaces.UploadTableOptions) string {
+	headers := []string{"Bucket", "Key", "Upload ID", "Initiated", "Age", "Size", "Parts", "Storage Class", "Region", "Initiated By"}
+	multiAccount := hasAccounts(uploads)
+	if multiAccount {
+		headers = append([]string{"Account"}, headers...)
+	}
+	if options.ShowOwner {
+		headers = append(headers, "Initiator", "Owner")
+	}
+	superseded := hasSuperseded(uploads)
+	if superseded {
+		headers = append(headers, "Superseded")
+	}
+	autoAbort := hasAbortDate(uploads)
+	if autoAbort {
+		headers = append(headers, "Auto-Abort On")
+	}
+	estimatedCost := hasEstimatedCost(uploads)
+	if estimatedCost {
+		headers = append(headers, "Est. Cost/mo")
+	}
+	var rows [][]string
+	
+	for _, upload := range uploads {
+		age := time.Since(upload.Initiated)
+		ageStr := units.FormatDuration(age)
+		sizeStr := units.FormatBytes(upload.Size)
+		
+		var row []string
+		if multiAccount {
+			row = append(row, upload.Account)
+		}
+		row = append(row,
+			upload.Bucket,
+			types.DisplayString(upload.Key),
+			types.DisplayString(upload.UploadID),
+			upload.Initiated.Format("2006-01-02 15:04"),
+			ageStr,
+			sizeStr,
+			formatPartsCount(upload),
+			upload.StorageClass,
+			upload.Region,
+			InitiatedByLabel(upload),
+		)
+		if options.ShowOwner {
+			row = append(row,
+				types.DisplayString(firstNonEmpty(upload.InitiatorName, upload.Initiator)),
+				types.DisplayString(firstNonEmpty(upload.Owner, upload.OwnerID)),
+			)
+		}
+		if superseded {
+			supersededStr := ""
+			if upload.Superseded {
+				supersededStr = "yes"
+			}
+			row = append(row, supersededStr)
+		}
+		if autoAbort {
+			row = append(row, formatAbortDate(upload))
+		}
+		if estimatedCost {
+			row = append(row, formatEstimatedCost(upload))
+		}
+		rows = append(rows, row)
+	}
+	
+	// As with chosen columns, long keys and upload IDs are only cut to fit the terminal
+	return f.formatTable(headers, rows, !options.Wide)
+}
+
+// formatPartsCount returns the number of parts of a measured upload, or "-"
+// when its parts were never listed
+func formatPartsCount(upload types.MultipartUpload) string {
+	if upload.MeasuredAt.IsZero() && upload.PartsCount == 0 {
+		return "-"
+	}
+	return strconv.Itoa(upload.PartsCount)
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// hasAccounts reports whether uploads come from a multi-account run
+func hasAccounts(uploads []types.MultipartUpload) bool {
+	for _, upload := range uploads {
+		if upload.Account != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSuperseded reports whether any upload is marked superseded
+func hasSuperseded(uploads []types.MultipartUpload) bool {
+	for _, upload := range uploads {
+		if upload.Superseded {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAbortDate reports whether a lifecycle rule will abort any of uploads
+func hasAbortDate(uploads []types.MultipartUpload) bool {
+	for _, upload := range uploads {
+		if upload.AbortDate != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// formatAbortDate returns the day a lifecycle rule will abort an upload, or
+// "-" when no rule will
+func formatAbortDate(upload types.MultipartUpload) string {
+	if upload.AbortDate == nil {
+		return "-"
+	}
+	return upload.AbortDate.Format("2006-01-02")
+}
+
+// hasEstimatedCost reports whether any upload was priced
+func hasEstimatedCost(uploads []types.MultipartUpload) bool {
+	for _, upload := range uploads {
+		if upload.EstimatedMonthlyCost > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// formatEstimatedCost returns the estimated monthly cost of a measured upload,
+// to a hundredth of a cent as most uploads cost less than one, or "-" when
+// the upload was never measured
+func formatEstimatedCost(upload types.MultipartUpload) string {
+	if formatPartsCount(upload) == "-" {
+		return "-"
+	}
+	return strconv.FormatFloat(upload.EstimatedMonthlyCost, 'f', 4, 64)
+}
+
+// FormatAccountFailures lists the accounts of a multi-account run that could not be scanned
+func FormatAccountFailures(failures []types.AccountFailure) string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Failed accounts (%d):\n", len(failures)))
+	for _, failure := range failures {
+		result.WriteString(fmt.Sprintf("  %s: %s\n", types.DisplayString(failure.Account), types.DisplayString(failure.Error)))
+	}
+	return result.String()
+}
+
+// FormatSkippedBuckets lists the buckets that were not scanned because their
+// region could not be determined
+func FormatSkippedBuckets(skipped []types.SkippedBucket) string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Skipped buckets (%d):\n", len(skipped)))
+	for _, bucket := range skipped {
+		result.WriteString(fmt.Sprintf("  %s: %s\n", bucket.Name(), types.DisplayString(bucket.Error)))
+	}
+	return result.String()
+}
+
+// FormatBucketErrors lists the buckets whose uploads could not be listed
+func FormatBucketErrors(failed types.BucketErrors) string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Buckets with errors (%d):\n", len(failed)))
+	for _, bucket := range failed {
+		name := types.DisplayString(bucket.Bucket)
+		if bucket.Account != "" {
+			name = types.DisplayString(bucket.Account) + "/" + name
+		}
+		if bucket.Region != "" {
+			name += " (" + types.DisplayString(bucket.Region) + ")"
+		}
+		result.WriteString(fmt.Sprintf("  %s: %s\n", name, types.DisplayString(bucket.Err.Error())))
+	}
+	return result.String()
+}
+
+// FormatDoctorChecks lists each doctor check with its status, and the
+// remediation of those that did not pass
+func FormatDoctorChecks(checks []types.DoctorCheck) string {
+	var result strings.Builder
+	for _, check := range checks {
+		line := fmt.Sprintf("%-4s  %s", strings.ToUpper(check.Status), check.Name)
+		if check.Detail != "" {
+			line += ": " + types.DisplayString(check.Detail)
+		}
+		result.WriteString(line + "\n")
+		if check.Remediation != "" && check.Status != types.DoctorPass {
+			result.WriteString(fmt.Sprintf("      Hint: %s\n", types.DisplayString(check.Remediation)))
+		}
+	}
+	return result.String()
+}
+
 // FormatSizeReport formats size report for console output
 func (f *OutputFormatter) FormatSizeReport(report types.SizeReport) string {
 	var result strings.Builder
 	
 	result.WriteString(fmt.Sprintf("Total incomplete multipart uploads: %d\n", report.TotalCount))
-	result.WriteString(fmt.Sprintf("Total storage used: %s\n\n", FormatBytes(report.TotalSize)))
+	result.WriteString(fmt.Sprintf("Total storage used: %s\n", units.FormatBytes(report.TotalSize)))
+	if report.ServiceInitiatedCount > 0 {
+		result.WriteString(fmt.Sprintf("Service-initiated uploads: %d (%s)\n", report.ServiceInitiatedCount, units.FormatBytes(report.ServiceInitiatedSize)))
+	}
+	result.WriteString("\n")
+	
+	if len(report.ByAccount) > 0 {
+		result.WriteString("Breakdown by account:\n")
+		
+		// Sort accounts by size (descending)
+		type accountSize struct {
+			name string
+			size int64
+		}
+		
+		var accounts []accountSize
+		for account, size := range report.ByAccount {
+			accounts = append(accounts, accountSize{account, size})
+		}
+		
+		sort.Slice(accounts, func(i, j int) bool {
+			if accounts[i].size != accounts[j].size {
+				return accounts[i].size > accounts[j].size
+			}
+			return accounts[i].name < accounts[j].name
+		})
+		
+		for _, account := range accounts {
+			percentage := formatPercentage(float64(account.size), float64(report.TotalSize))
+			result.WriteString(fmt.Sprintf("  %s: %s (%s)\n", account.name, units.FormatBytes(account.size), percentage))
+		}
+		result.WriteString("\n")
+	}
 	
 	if len(report.ByBucket) > 0 {
 		result.WriteString("Breakdown by bucket:\n")
@@ -102,12 +506,15 @@ func (f *OutputFormatter) FormatSizeReport(report types.SizeReport) string {
 		}
 		
 		sort.Slice(buckets, func(i, j int) bool {
-			return buckets[i].size > buckets[j].size
+			if buckets[i].size != buckets[j].size {
+				return buckets[i].size > buckets[j].size
+			}
+			return buckets[i].name < buckets[j].name
 		})
 		
 		for _, bucket := range buckets {
-			percentage := float64(bucket.size) / float64(report.TotalSize) * 100
-			result.WriteString(fmt.Sprintf("  %s: %s (%.1f%%)\n", bucket.name, FormatBytes(bucket.size), percentage))
+			percentage := formatPercentage(float64(bucket.size), float64(report.TotalSize))
+			result.WriteString(fmt.Sprintf("  %s: %s (%s)\n", bucket.name, units.FormatBytes(bucket.size), percentage))
 		}
 		result.WriteString("\n")
 	}
@@ -127,16 +534,40 @@ func (f *OutputFormatter) FormatSizeReport(report types.SizeReport) string {
 		}
 		
 		sort.Slice(storageClasses, func(i, j int) bool {
-			return storageClasses[i].size > storageClasses[j].size
+			if storageClasses[i].size != storageClasses[j].size {
+				return storageClasses[i].size > storageClasses[j].size
+			}
+			return storageClasses[i].class < storageClasses[j].class
 		})
 		
 		for _, sc := range storageClasses {
-			percentage := float64(sc.size) / float64(report.TotalSize) * 100
-			result.WriteString(fmt.Sprintf("  %s: %s (%.1f%%)\n", sc.class, FormatBytes(sc.size), percentage))
+			percentage := formatPercentage(float64(sc.size), float64(report.TotalSize))
+			result.WriteString(fmt.Sprintf("  %s: %s (%s)\n", sc.class, units.FormatBytes(sc.size), percentage))
 		}
 		result.WriteString("\n")
 	}
 	
+	if report.Distribution != nil {
+		result.WriteString("Distribution by upload size:\n\n")
+		headers := []string{"Size Range", "Count", "Percentage", "Total Size", "Size Percentage"}
+		var rows [][]string
+		var bars []histogramRow
+		for _, bucket := range report.Distribution.Buckets {
+			rows = append(rows, []string{
+				bucket.Label,
+				fmt.Sprintf("%d", bucket.Count),
+				formatPercentage(float64(bucket.Count), float64(report.TotalCount)),
+				units.FormatBytes(bucket.TotalSize),
+				formatPercentage(float64(bucket.TotalSize), float64(report.TotalSize)),
+			})
+			bars = append(bars, histogramRow{label: bucket.Label, count: bucket.Count})
+		}
+		result.WriteString(f.FormatTable(headers, rows))
+		result.WriteString("\n")
+		result.WriteString(f.formatHistogram(bars))
+		result.WriteString("\n")
+	}
+	
 	if len(report.InaccessibleBuckets) > 0 {
 		result.WriteString("Inaccessible buckets:\n")
 		for _, bucket := range report.InaccessibleBuckets {
@@ -144,14 +575,72 @@ func (f *OutputFormatter) FormatSizeReport(report types.SizeReport) string {
 		}
 	}
 	
+	for _, bucket := range report.TrippedBuckets {
+		result.WriteString(FormatTrippedBucket(bucket))
+	}
+	
+	if len(report.ErrorSummary) > 0 {
+		result.WriteString("\nErrors by cause:\n")
+		result.WriteString(formatErrorSummaries(report.ErrorSummary, "upload"))
+	}
+	
+	if len(report.FailedAccounts) > 0 {
+		result.WriteString("\n")
+		result.WriteString(FormatAccountFailures(report.FailedAccounts))
+	}
+	
 	return result.String()
 }
 
+// formatPercentage formats part as a percentage of total, or "–" when the
+// total is zero (e.g. sizes were not hydrated or costs round to zero)
+func formatPercentage(part, total float64) string {
+	if total <= 0 || math.IsNaN(total) || math.IsInf(total, 0) {
+		return "–"
+	}
+	return fmt.Sprintf("%.1f%%", part/total*100)
+}
+
 // FormatCostBreakdown formats cost breakdown for console output
 func (f *OutputFormatter) FormatCostBreakdown(breakdown types.CostBreakdown) string {
 	var result strings.Builder
 	
-	result.WriteString(fmt.Sprintf("Total estimated monthly cost: $%.2f %s\n\n", breakdown.TotalMonthlyCost, breakdown.Currency))
+	result.WriteString(fmt.Sprintf("Total estimated monthly cost: %s\n", formatCurrency(breakdown.TotalMonthlyCost, breakdown.Currency)))
+	if breakdown.AnnualizedCost > 0 {
+		result.WriteString(fmt.Sprintf("Annualized cost: %s\n", formatCurrency(breakdown.AnnualizedCost, breakdown.Currency)))
+	}
+	if breakdown.AccruedCost > 0 {
+		result.WriteString(fmt.Sprintf("Accrued to date: %s\n", formatCurrency(breakdown.AccruedCost, breakdown.Currency)))
+	}
+	result.WriteString("\n")
+	
+	if len(breakdown.ByAccount) > 0 {
+		result.WriteString("Breakdown by account:\n")
+		
+		// Sort accounts by cost (descending)
+		type accountCost struct {
+			name string
+			cost float64
+		}
+		
+		var accounts []accountCost
+		for account, cost := range breakdown.ByAccount {
+			accounts = append(accounts, accountCost{account, cost})
+		}
+		
+		sort.Slice(accounts, func(i, j int) bool {
+			if accounts[i].cost != accounts[j].cost {
+				return accounts[i].cost > accounts[j].cost
+			}
+			return accounts[i].name < accounts[j].name
+		})
+		
+		for _, account := range accounts {
+			percentage := formatPercentage(account.cost, breakdown.TotalMonthlyCost)
+			result.WriteString(fmt.Sprintf("  %s: %s (%s)\n", account.name, formatAmount(account.cost, breakdown.Currency), percentage))
+		}
+		result.WriteString("\n")
+	}
 	
 	if len(breakdown.ByRegion) > 0 {
 		result.WriteString("Breakdown by region:\n")
@@ -168,12 +657,15 @@ func (f *OutputFormatter) FormatCostBreakdown(breakdown types.CostBreakdown) str
 		}
 		
 		sort.Slice(regions, func(i, j int) bool {
-			return regions[i].cost > regions[j].cost
+			if regions[i].cost != regions[j].cost {
+				return regions[i].cost > regions[j].cost
+			}
+			return regions[i].region < regions[j].region
 		})
 		
 		for _, region := range regions {
-			percentage := region.cost / breakdown.TotalMonthlyCost * 100
-			result.WriteString(fmt.Sprintf("  %s: $%.2f (%.1f%%)\n", region.region, region.cost, percentage))
+			percentage := formatPercentage(region.cost, breakdown.TotalMonthlyCost)
+			result.WriteString(fmt.Sprintf("  %s: %s (%s)\n", region.region, formatAmount(region.cost, breakdown.Currency), percentage))
 		}
 		result.WriteString("\n")
 	}
@@ -193,15 +685,39 @@ func (f *OutputFormatter) FormatCostBreakdown(breakdown types.CostBreakdown) str
 		}
 		
 		sort.Slice(storageClasses, func(i, j int) bool {
-			return storageClasses[i].cost > storageClasses[j].cost
+			if storageClasses[i].cost != storageClasses[j].cost {
+				return storageClasses[i].cost > storageClasses[j].cost
+			}
+			return storageClasses[i].class < storageClasses[j].class
 		})
 		
 		for _, sc := range storageClasses {
-			percentage := sc.cost / breakdown.TotalMonthlyCost * 100
-			result.WriteString(fmt.Sprintf("  %s: $%.2f (%.1f%%)\n", sc.class, sc.cost, percentage))
+			percentage := formatPercentage(sc.cost, breakdown.TotalMonthlyCost)
+			result.WriteString(fmt.Sprintf("  %s: %s (%s)\n", sc.class, formatAmount(sc.cost, breakdown.Currency), percentage))
 		}
 	}
 	
+	// Flag the regions and classes that had no price of their own, so an
+	// estimate is not mistaken for a real one
+	if len(breakdown.PricingWarnings) > 0 {
+		result.WriteString(fmt.Sprintf("\n⚠ estimated using default pricing: %s\n", strings.Join(breakdown.PricingWarnings, ", ")))
+	}
+	
+	if len(breakdown.FailedAccounts) > 0 {
+		result.WriteString("\n")
+		result.WriteString(FormatAccountFailures(breakdown.FailedAccounts))
+	}
+	
+	if len(breakdown.SkippedBuckets) > 0 {
+		result.WriteString("\n")
+		result.WriteString(FormatSkippedBuckets(breakdown.SkippedBuckets))
+	}
+	
+	if len(breakdown.BucketErrors) > 0 {
+		result.WriteString("\n")
+		result.WriteString(FormatBucketErrors(breakdown.BucketErrors))
+	}
+	
 	return result.String()
 }
 
@@ -227,20 +743,36 @@ func (f *OutputFormatter) FormatAgeDistribution(distribution types.AgeDistributi
 	var rows [][]string
 	
 	for _, bucket := range distribution.Buckets {
-		countPercentage := float64(bucket.Count) / float64(totalCount) * 100
-		sizePercentage := float64(bucket.TotalSize) / float64(totalSize) * 100
-		
 		rows = append(rows, []string{
 			bucket.Label,
 			fmt.Sprintf("%d", bucket.Count),
-			fmt.Sprintf("%.1f%%", countPercentage),
-			FormatBytes(bucket.TotalSize),
-			fmt.Sprintf("%.1f%%", sizePercentage),
+			formatPercentage(float64(bucket.Count), float64(totalCount)),
+			units.FormatBytes(bucket.TotalSize),
+			formatPercentage(float64(bucket.TotalSize), float64(totalSize)),
 		})
 	}
 	
 	result.WriteString(f.FormatTable(headers, rows))
-	result.WriteString(fmt.Sprintf("\nTotal: %d uploads, %s\n", totalCount, FormatBytes(totalSize)))
+	result.WriteString("\n")
+	var bars []histogramRow
+	for _, bucket := range distribution.Buckets {
+		bars = append(bars, histogramRow{label: bucket.Label, count: bucket.Count})
+	}
+	result.WriteString(f.formatHistogram(bars))
+	result.WriteString(fmt.Sprintf("\nTotal: %d uploads, %s\n", totalCount, units.FormatBytes(totalSize)))
+	
+	if percentiles := distribution.Percentiles; percentiles != nil {
+		result.WriteString("\n")
+		headers := []string{"Statistic", "Age", "Size At Least This Old"}
+		var rows [][]string
+		for _, stat := range []struct {
+			name       string
+			percentile types.AgePercentile
+		}{{"Min", percentiles.Min}, {"Median", percentiles.Median}, {"P90", percentiles.P90}, {"Max", percentiles.Max}} {
+			rows = append(rows, []string{stat.name, units.FormatDuration(stat.percentile.Age), units.FormatBytes(stat.percentile.Size)})
+		}
+		result.WriteString(f.FormatTable(headers, rows))
+	}
 	
 	// Highlight uploads older than 7 days
 	var oldUploads int
@@ -255,13 +787,188 @@ func (f *OutputFormatter) FormatAgeDistribution(distribution types.AgeDistributi
 	}
 	
 	if oldUploads > 0 {
-		result.WriteString(fmt.Sprintf("\n⚠️  %d uploads (%.1f%%) are older than 7 days, consuming %s\n", 
-			oldUploads, float64(oldUploads)/float64(totalCount)*100, FormatBytes(oldSize)))
+		result.WriteString(fmt.Sprintf("\n⚠️  %d uploads (%s) are older than 7 days, consuming %s\n", 
+			oldUploads, formatPercentage(float64(oldUploads), float64(totalCount)), units.FormatBytes(oldSize)))
+	}
+	
+	return result.String()
+}
+
+// FormatStatsReport formats a combined size, cost and age report
+func (f *OutputFormatter) FormatStatsReport(report types.StatsReport) string {
+	var result strings.Builder
+	
+	size := report.Size
+	size.ByBucket = nil
+	result.WriteString(f.FormatSizeReport(size))
+	
+	if len(report.ByBucket) > 0 {
+		result.WriteString("Breakdown by bucket:\n\n")
+		headers := []string{"Bucket", "Uploads", "Size", "Monthly Cost"}
+		var rows [][]string
+		for _, bucket := range report.ByBucket {
+			rows = append(rows, []string{
+				bucket.Bucket,
+				fmt.Sprintf("%d", bucket.Count),
+				units.FormatBytes(bucket.Size),
+				formatAmount(bucket.MonthlyCost, report.Cost.Currency),
+			})
+		}
+		result.WriteString(f.FormatTable(headers, rows))
+		result.WriteString("\n")
+	}
+	
+	result.WriteString(f.FormatCostBreakdown(report.Cost))
+	result.WriteString("\n")
+	result.WriteString(f.FormatAgeDistribution(report.Age))
+	
+	if len(report.InaccessibleBuckets) > 0 {
+		result.WriteString("\nInaccessible buckets:\n")
+		for _, bucket := range report.InaccessibleBuckets {
+			result.WriteString(fmt.Sprintf("  %s\n", bucket))
+		}
 	}
 	
 	return result.String()
 }
 
+// FormatLifecycleStatus formats a lifecycle rule audit as a table
+func (f *OutputFormatter) FormatLifecycleStatus(statuses []types.LifecycleStatus) string {
+	if len(statuses) == 0 {
+		return "No buckets found.\n"
+	}
+	
+	headers := []string{"Bucket", "Region", "Abort Rule", "Days", "Enabled", "Uploads"}
+	var rows [][]string
+	var errored, unlisted []types.LifecycleStatus
+	missing := 0
+	for _, status := range statuses {
+		rule, days, enabled := "none", "-", "-"
+		switch {
+		case status.Error != "":
+			rule = "error"
+			errored = append(errored, status)
+		case status.HasAbortRule:
+			rule = status.RuleID
+			if rule == "" {
+				rule = "yes"
+			}
+			if status.Prefix != "" {
+				rule += fmt.Sprintf(" (prefix %s)", status.Prefix)
+			}
+			days = fmt.Sprintf("%d", status.DaysAfterInitiation)
+			enabled = "no"
+			if status.RuleEnabled {
+				enabled = "yes"
+			}
+		case status.HasConfiguration:
+			rule = "none (other rules only)"
+		}
+		if status.Error == "" && !status.Covered() {
+			missing++
+		}
+		uploads := "unknown"
+		if status.UploadCount != nil {
+			uploads = fmt.Sprintf("%d", *status.UploadCount)
+		} else if status.ListError != "" {
+			unlisted = append(unlisted, status)
+		}
+		
+		rows = append(rows, []string{
+			status.Bucket,
+			status.Region,
+			rule,
+			days,
+			enabled,
+			uploads,
+		})
+	}
+	
+	var result strings.Builder
+	result.WriteString(f.FormatTable(headers, rows))
+	result.WriteString(fmt.Sprintf("\n%d of %d buckets lack an enabled rule covering the whole bucket\n", missing, len(statuses)-len(errored)))
+	
+	if len(errored) > 0 {
+		result.WriteString("\nCould not read lifecycle configuration:\n")
+		for _, status := range errored {
+			result.WriteString(fmt.Sprintf("  %s: %s\n", status.Bucket, status.Error))
+		}
+	}
+	
+	if len(unlisted) > 0 {
+		result.WriteString("\nCould not list uploads (count unknown):\n")
+		for _, status := range unlisted {
+			result.WriteString(fmt.Sprintf("  %s: %s\n", status.Bucket, status.ListError))
+		}
+	}
+	
+	return result.String()
+}
+
+// FormatLifecyclePlan formats the rule that would be written and what happens to each bucket
+func (f *OutputFormatter) FormatLifecyclePlan(changes []types.LifecycleChange) string {
+	if len(changes) == 0 {
+		return "No buckets selected.\n"
+	}
+	
+	var result strings.Builder
+	
+	// Every change writes the same rule, so it is shown once
+	if ruleJSON, err := f.FormatJSON(changes[0].Rule); err == nil {
+		result.WriteString("Lifecycle rule to write:\n")
+		result.WriteString(ruleJSON)
+		result.WriteString("\n\n")
+	}
+	
+	result.WriteString("Buckets:\n")
+	for _, change := range changes {
+		var action string
+		switch {
+		case change.Error != "":
+			action = fmt.Sprintf("error: %s", change.Error)
+		case change.Skipped != "":
+			action = fmt.Sprintf("skip, %s", change.Skipped)
+		case change.Replaces:
+			action = fmt.Sprintf("update the s3mpc rule, keeping %d other rules", change.ExistingRules)
+		default:
+			action = fmt.Sprintf("add the rule, keeping %d existing rules", change.ExistingRules)
+		}
+		result.WriteString(fmt.Sprintf("  %s (%s): %s\n", change.Bucket, change.Region, action))
+	}
+	
+	return result.String()
+}
+
+// FormatLifecycleApplyResult formats the per-bucket outcome of writing lifecycle rules
+func (f *OutputFormatter) FormatLifecycleApplyResult(result types.LifecycleApplyResult) string {
+	var output strings.Builder
+	
+	skipped := len(result.Changes) - result.Applied - result.Failed
+	output.WriteString("Lifecycle update completed:\n")
+	output.WriteString(fmt.Sprintf("  Buckets updated: %d\n", result.Applied))
+	output.WriteString(fmt.Sprintf("  Buckets skipped: %d\n", skipped))
+	output.WriteString(fmt.Sprintf("  Failed updates: %d\n", result.Failed))
+	
+	output.WriteString("\n")
+	for _, change := range result.Changes {
+		switch {
+		case change.Applied:
+			output.WriteString(fmt.Sprintf("  %s: updated (%d other rules kept)\n", change.Bucket, change.ExistingRules))
+		case change.Error != "":
+			output.WriteString(fmt.Sprintf("  %s: failed: %s\n", change.Bucket, change.Error))
+		case change.Skipped != "":
+			output.WriteString(fmt.Sprintf("  %s: skipped, %s\n", change.Bucket, change.Skipped))
+		}
+	}
+	
+	if len(result.ErrorSummary) > 0 {
+		output.WriteString("\nErrors by cause:\n")
+		output.WriteString(formatErrorSummaries(result.ErrorSummary, "bucket"))
+	}
+	
+	return output.String()
+}
+
 // FormatJSON formats any data structure as JSON
 func (f *OutputFormatter) FormatJSON(data interface{}) (string, error) {
 	jsonData, err := json.MarshalIndent(data, "", "  ")
@@ -271,29 +978,70 @@ func (f *OutputFormatter) FormatJSON(data interface{}) (string, error) {
 	return string(jsonData), nil
 }
 
+// escapeRows returns rows with each cell passed through types.DisplayString,
+// copying only the rows that change
+func escapeRows(rows [][]string) [][]string {
+	var escaped [][]string
+	for i, row := range rows {
+		copied := false
+		for j, cell := range row {
+			display := types.DisplayString(cell)
+			if display == cell {
+				continue
+			}
+			if escaped == nil {
+				escaped = append([][]string(nil), rows...)
+			}
+			if !copied {
+				escaped[i] = append([]string(nil), row...)
+				copied = true
+			}
+			escaped[i][j] = display
+		}
+	}
+	if escaped == nil {
+		return rows
+	}
+	return escaped
+}
+
 // FormatTable formats data as a table with headers and rows
 func (f *OutputFormatter) FormatTable(headers []string, rows [][]string) string {
+	return f.formatTable(headers, rows, true)
+}
+
+// formatTable formats a table, shrinking its widest columns to the terminal
+// width when fit is set
+func (f *OutputFormatter) formatTable(headers []string, rows [][]string, fit bool) string {
 	if len(headers) == 0 || len(rows) == 0 {
 		return ""
 	}
 	
+	// Hostile keys must not break rows or change terminal state
+	rows = escapeRows(rows)
+	
 	// Calculate column widths
 	colWidths := make([]int, len(headers))
 	
 	// Initialize with header widths
 	for i, header := range headers {
-		colWidths[i] = len(header)
+		colWidths[i] = displayWidth(header)
 	}
 	
 	// Update with row data widths
 	for _, row := range rows {
 		for i, cell := range row {
-			if i < len(colWidths) && len(cell) > colWidths[i] {
-				colWidths[i] = len(cell)
+			if i < len(colWidths) {
+				if width := displayWidth(cell); width > colWidths[i] {
+					colWidths[i] = width
+				}
 			}
 		}
 	}
 	
+	// Shrink the widest columns until the table fits the current terminal width
+	truncated := fit && f.fitColumnWidths(colWidths)
+	
 	var result strings.Builder
 	
 	// Format header
@@ -301,7 +1049,10 @@ func (f *OutputFormatter) FormatTable(headers []string, rows [][]string) string
 		if i > 0 {
 			result.WriteString("  ")
 		}
-		result.WriteString(fmt.Sprintf("%-*s", colWidths[i], header))
+		if truncated {
+			header = truncateString(header, colWidths[i])
+		}
+		writePadded(&result, header, colWidths[i])
 	}
 	result.WriteString("\n")
 	
@@ -321,7 +1072,10 @@ func (f *OutputFormatter) FormatTable(headers []string, rows [][]string) string
 				result.WriteString("  ")
 			}
 			if i < len(colWidths) {
-				result.WriteString(fmt.Sprintf("%-*s", colWidths[i], cell))
+				if truncated {
+					cell = truncateString(cell, colWidths[i])
+				}
+				writePadded(&result, cell, colWidths[i])
 			} else {
 				result.WriteString(cell)
 			}
@@ -332,51 +1086,95 @@ func (f *OutputFormatter) FormatTable(headers []string, rows [][]string) string
 	return result.String()
 }
 
-// Helper functions
-
-// formatDuration formats a duration for display
-func formatDuration(duration time.Duration) string {
-	days := int(duration.Hours() / 24)
-	if days > 0 {
-		return fmt.Sprintf("%dd", days)
+// fitColumnWidths narrows colWidths in place to fit the terminal and reports whether any changed
+func (f *OutputFormatter) fitColumnWidths(colWidths []int) bool {
+	if f.terminal == nil {
+		return false
+	}
+	width := f.terminal.Width()
+	if width <= 0 {
+		return false
 	}
 	
-	hours := int(duration.Hours())
-	if hours > 0 {
-		return fmt.Sprintf("%dh", hours)
+	total := 2 * (len(colWidths) - 1)
+	for _, w := range colWidths {
+		total += w
 	}
 	
-	minutes := int(duration.Minutes())
-	if minutes > 0 {
-		return fmt.Sprintf("%dm", minutes)
+	changed := false
+	for total > width {
+		widest := 0
+		for i, w := range colWidths {
+			if w > colWidths[widest] {
+				widest = i
+			}
+		}
+		if colWidths[widest] <= minTableColumnWidth {
+			break
+		}
+		colWidths[widest]--
+		total--
+		changed = true
 	}
 	
-	return fmt.Sprintf("%ds", int(duration.Seconds()))
+	return changed
+}
+
+// Helper functions
+
+
+// formatMeasurementAge describes how long ago sizes were measured, such as
+// "32 minutes ago"
+func formatMeasurementAge(age time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return plural(int(age.Minutes()), "minute")
+	case age < 24*time.Hour:
+		return plural(int(age.Hours()), "hour")
+	default:
+		return plural(int(age.Hours()/24), "day")
+	}
 }
 
 
 
-// FormatBytes formats bytes into human-readable format
-func FormatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
+// formatAmount formats a monetary amount, using a $ prefix for USD
+func formatAmount(amount float64, currency string) string {
+	if currency == "" || currency == "USD" {
+		return fmt.Sprintf("$%.2f", amount)
 	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+	return fmt.Sprintf("%.2f", amount)
+}
+
+// formatCurrency formats a monetary amount followed by its currency code
+func formatCurrency(amount float64, currency string) string {
+	if currency == "" {
+		currency = "USD"
 	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	return fmt.Sprintf("%s %s", formatAmount(amount, currency), currency)
 }
 
-// truncateString truncates a string to a maximum length
-func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+
+// FormatTrippedBucket says how many uploads in a bucket were not measured after
+// repeated failures, e.g. "Skipped 39,874 uploads in bucket logs after
+// repeated AccessDenied"
+func FormatTrippedBucket(bucket types.TrippedBucket) string {
+	cause := bucket.ErrorCode
+	if cause == "" {
+		cause = "failures"
 	}
-	if maxLen <= 3 {
-		return s[:maxLen]
+	noun := "uploads"
+	if bucket.SkippedUploads == 1 {
+		noun = "upload"
 	}
-	return s[:maxLen-3] + "..."
-}
\ No newline at end of file
+	return fmt.Sprintf("Skipped %s %s in bucket %s after repeated %s\n", formatCount(bucket.SkippedUploads), noun, types.DisplayString(bucket.Bucket), cause)
+}
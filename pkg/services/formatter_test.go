@@ -1,56 +1,17 @@
 package services
 
 import (
+	"bytes"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	"github.com/Garvitkul/s3mpc/internal/term"
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
 	"github.com/Garvitkul/s3mpc/pkg/types"
 )
 
-func TestFormatBytes(t *testing.T) {
-	tests := []struct {
-		name     string
-		bytes    int64
-		expected string
-	}{
-		{
-			name:     "zero bytes",
-			bytes:    0,
-			expected: "0 B",
-		},
-		{
-			name:     "bytes",
-			bytes:    512,
-			expected: "512 B",
-		},
-		{
-			name:     "kilobytes",
-			bytes:    1536, // 1.5 KB
-			expected: "1.5 KB",
-		},
-		{
-			name:     "megabytes",
-			bytes:    1572864, // 1.5 MB
-			expected: "1.5 MB",
-		},
-		{
-			name:     "gigabytes",
-			bytes:    1610612736, // 1.5 GB
-			expected: "1.5 GB",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := FormatBytes(tt.bytes)
-			if result != tt.expected {
-				t.Errorf("FormatBytes(%d) = %s, expected %s", tt.bytes, result, tt.expected)
-			}
-		})
-	}
-}
-
 func TestFormatUploads(t *testing.T) {
 	formatter := NewOutputFormatter()
 
@@ -82,6 +43,70 @@ func TestFormatUploads(t *testing.T) {
 	}
 }
 
+func TestFormatUploadTableShowOwner(t *testing.T) {
+	formatter := NewOutputFormatterWithTerminal(term.NewFake(0))
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.bin", UploadID: "u1", Initiated: time.Now().Add(-time.Hour), StorageClass: "STANDARD", Region: "us-east-1",
+			Initiator: "arn:aws:iam::123456789012:user/alice", InitiatorName: "alice", OwnerID: "75aa57f09aa0c8caeab4f8c24e99d10f"},
+	}
+
+	if output := formatter.FormatUploadTable(uploads, false); strings.Contains(output, "Owner") {
+		t.Errorf("FormatUploadTable() without showOwner = %q, want no owner column", output)
+	}
+	output := formatter.FormatUploadTable(uploads, true)
+	for _, want := range []string{"Initiator", "Owner", "alice", "75aa57f09aa0c8caeab4f8c24e99d10f"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("FormatUploadTable() = %q, want %q (the owner ID stands in for a missing name)", output, want)
+		}
+	}
+}
+
+func TestFormatUploadColumns(t *testing.T) {
+	longKey := "backups/" + strings.Repeat("nested/", 30) + "archive.tar"
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: longKey, UploadID: "u1", Initiated: time.Now().Add(-48 * time.Hour), Size: 2048},
+	}
+	options := interfaces.UploadTableOptions{Columns: []string{"size", "bucket", "key"}}
+
+	output := NewOutputFormatterWithTerminal(term.NewFake(0)).FormatUploadColumns(uploads, options)
+	header := strings.Fields(strings.SplitN(output, "\n", 2)[0])
+	if strings.Join(header, " ") != "Size Bucket Key" {
+		t.Errorf("header = %q, want the chosen columns in order", header)
+	}
+	if !strings.Contains(output, longKey) || strings.Contains(output, "Upload ID") {
+		t.Errorf("FormatUploadColumns() = %q, want the full key and no other columns", output)
+	}
+
+	narrow := NewOutputFormatterWithTerminal(term.NewFake(80))
+	if output := narrow.FormatUploadColumns(uploads, options); strings.Contains(output, longKey) {
+		t.Errorf("FormatUploadColumns() on an 80 column terminal = %q, want the key truncated", output)
+	}
+	options.Wide = true
+	if output := narrow.FormatUploadColumns(uploads, options); !strings.Contains(output, longKey) {
+		t.Errorf("FormatUploadColumns() with Wide = %q, want the full key", output)
+	}
+	if output := narrow.FormatUploadColumns(uploads, interfaces.UploadTableOptions{Wide: true}); !strings.Contains(output, longKey) {
+		t.Errorf("detailed table with Wide = %q, want the full key", output)
+	}
+}
+
+func TestParseUploadColumns(t *testing.T) {
+	columns, err := ParseUploadColumns("bucket, Key,age,size")
+	if err != nil || strings.Join(columns, ",") != "bucket,key,age,size" {
+		t.Errorf("ParseUploadColumns() = %v, %v, want bucket,key,age,size", columns, err)
+	}
+
+	_, err = ParseUploadColumns("bucket,etag")
+	if err == nil || !strings.Contains(err.Error(), "upload-id") {
+		t.Errorf("ParseUploadColumns(\"bucket,etag\") error = %v, want one listing the valid columns", err)
+	}
+	for _, invalid := range []string{"", "key,key", "bucket,"} {
+		if _, err := ParseUploadColumns(invalid); err == nil {
+			t.Errorf("ParseUploadColumns(%q) succeeded, want an error", invalid)
+		}
+	}
+}
+
 func TestFormatSizeReport(t *testing.T) {
 	formatter := NewOutputFormatter()
 
@@ -106,6 +131,52 @@ func TestFormatSizeReport(t *testing.T) {
 	}
 }
 
+func TestFormattersWithZeroTotals(t *testing.T) {
+	formatter := NewOutputFormatter()
+
+	outputs := map[string]string{
+		"size": formatter.FormatSizeReport(types.SizeReport{
+			TotalCount:     2,
+			ByBucket:       map[string]int64{"bucket1": 0, "bucket2": 0},
+			ByStorageClass: map[string]int64{"STANDARD": 0},
+		}),
+		"cost": formatter.FormatCostBreakdown(types.CostBreakdown{
+			ByRegion:       map[string]float64{"us-east-1": 0},
+			ByStorageClass: map[string]float64{"STANDARD": 0},
+			Currency:       "USD",
+		}),
+		"age": formatter.FormatAgeDistribution(types.AgeDistribution{Buckets: []types.AgeBucket{
+			{Label: "< 1 day", MaxAge: 24 * time.Hour},
+			{Label: "> 30 days", MinAge: 30 * 24 * time.Hour},
+		}}),
+	}
+
+	for name, output := range outputs {
+		for _, bad := range []string{"NaN", "Inf", "%!"} {
+			if strings.Contains(output, bad) {
+				t.Errorf("%s output with zero totals contains %q:\n%s", name, bad, output)
+			}
+		}
+		if !strings.Contains(output, "–") {
+			t.Errorf("%s output with zero totals does not mark percentages as unavailable:\n%s", name, output)
+		}
+	}
+
+	// Sizes may be zero while counts are not, so the count share is still shown
+	age := formatter.FormatAgeDistribution(types.AgeDistribution{Buckets: []types.AgeBucket{
+		{Label: "> 30 days", MinAge: 30 * 24 * time.Hour, Count: 4},
+	}})
+	if !strings.Contains(age, "100.0%") || !strings.Contains(age, "4 uploads (100.0%) are older than 7 days") {
+		t.Errorf("age output with zero sizes lost the count percentage:\n%s", age)
+	}
+
+	var progress bytes.Buffer
+	NewConsoleProgressReporterWithTerminal(&progress, false, nil).ReportProgress(DeletionProgress{StartTime: time.Now()})
+	if strings.Contains(progress.String(), "NaN") || !strings.Contains(progress.String(), "0/0 (–)") {
+		t.Errorf("progress with no uploads = %q, want 0/0 (–)", progress.String())
+	}
+}
+
 func TestFormatJSON(t *testing.T) {
 	formatter := NewOutputFormatter()
 
@@ -121,4 +192,144 @@ func TestFormatJSON(t *testing.T) {
 	if !strings.Contains(result, "test") || !strings.Contains(result, "value") {
 		t.Errorf("Expected JSON content in output, got: %s", result)
 	}
-}
\ No newline at end of file
+}
+
+func TestFormatTableWideCharacters(t *testing.T) {
+	headers := []string{"Bucket", "Key", "Size"}
+	rows := [][]string{
+		{"logs", "plain.bin", "1.0 KB"},
+		{"日本語バケット", "報告/第一四半期.csv", "2.0 KB"},
+		{"emoji", "🚀 launch/résumé.bin", "3.0 KB"},
+	}
+
+	// Every line is as wide as the separator, and untruncated cells start at
+	// the same display column as their header
+	assertAligned := func(t *testing.T, table string, rows [][]string) {
+		t.Helper()
+		if !utf8.ValidString(table) {
+			t.Fatalf("table is not valid UTF-8: %q", table)
+		}
+		lines := strings.Split(strings.TrimRight(table, "\n"), "\n")
+		separator := lines[1]
+		var starts []int
+		for i := range separator {
+			if separator[i] == '-' && (i == 0 || separator[i-1] == ' ') {
+				starts = append(starts, i)
+			}
+		}
+		for i, line := range lines {
+			if displayWidth(line) != len(separator) {
+				t.Errorf("line %q is %d columns wide, want %d", line, displayWidth(line), len(separator))
+			}
+			if i < 2 || rows == nil {
+				continue
+			}
+			for j, cell := range rows[i-2] {
+				if index := strings.Index(line, cell); index < 0 || displayWidth(line[:index]) != starts[j] {
+					t.Errorf("cell %q in %q does not start at column %d", cell, line, starts[j])
+				}
+			}
+		}
+	}
+
+	formatter := &OutputFormatter{}
+	assertAligned(t, formatter.FormatTable(headers, rows), rows)
+
+	// Narrow terminals truncate wide characters without splitting runes
+	narrow := NewOutputFormatterWithTerminal(term.NewFake(30))
+	table := narrow.FormatTable(headers, rows)
+	assertAligned(t, table, nil)
+	for _, line := range strings.Split(strings.TrimRight(table, "\n"), "\n") {
+		if width := displayWidth(line); width > 30 {
+			t.Errorf("line %q is %d columns wide, want at most 30", line, width)
+		}
+	}
+
+	uploads := []types.MultipartUpload{
+		{Bucket: "桶", Key: strings.Repeat("データ", 20), UploadID: "u1", Initiated: time.Now()},
+		{Bucket: "b", Key: strings.Repeat("🙂", 30), UploadID: "u2", Initiated: time.Now()},
+	}
+	output := formatter.FormatUploads(uploads, true)
+	assertAligned(t, output, nil)
+	if !strings.Contains(output, uploads[0].Key) || !strings.Contains(output, uploads[1].Key) {
+		t.Errorf("FormatUploads() = %q, want whole keys without a terminal to fit", output)
+	}
+
+	// Long keys are only cut to fit the terminal
+	output = NewOutputFormatterWithTerminal(term.NewFake(120)).FormatUploads(uploads, true)
+	assertAligned(t, output, nil)
+	if !strings.Contains(output, "...") {
+		t.Errorf("FormatUploads() = %q, want long keys truncated", output)
+	}
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if width := displayWidth(line); width > 120 {
+			t.Errorf("line %q is %d columns wide, want at most 120", line, width)
+		}
+	}
+}
+
+func TestTruncateStringKeepsValidUTF8(t *testing.T) {
+	tests := []struct {
+		input  string
+		maxLen int
+		want   string
+	}{
+		{"plain", 10, "plain"},
+		{"abcdefghij", 8, "abcde..."},
+		{"日本語のキー", 8, "日本..."},
+		{"日本語のキー", 7, "日本..."},
+		{"日本語", 3, "日"},
+		{"🚀🚀🚀🚀", 6, "🚀..."},
+		{"e\u0301e\u0301e\u0301e\u0301", 3, "e\u0301e\u0301e\u0301"},
+	}
+	for _, tt := range tests {
+		got := truncateString(tt.input, tt.maxLen)
+		if got != tt.want {
+			t.Errorf("truncateString(%q, %d) = %q, want %q", tt.input, tt.maxLen, got, tt.want)
+		}
+		if !utf8.ValidString(got) || displayWidth(got) > tt.maxLen {
+			t.Errorf("truncateString(%q, %d) = %q, want valid UTF-8 within %d columns", tt.input, tt.maxLen, got, tt.maxLen)
+		}
+	}
+}
+
+// hostileKey is a ~1,000 byte key full of line breaks, terminal escape
+// sequences and a bidi override
+var hostileKey = strings.Repeat("evil\n\r\t\x1b[2J\x1b]0;pwned\x07\u202e", 40)
+
+// assertTerminalSafe fails if output contains anything but printable text and
+// line breaks, or more lines than want
+func assertTerminalSafe(t *testing.T, output string, want int) {
+	t.Helper()
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > want {
+		t.Errorf("output has %d lines, want at most %d:\n%s", len(lines), want, output)
+	}
+	for _, line := range lines {
+		if escaped := types.DisplayString(line); escaped != line {
+			t.Errorf("line contains control characters: %q", line)
+		}
+	}
+}
+
+func TestFormatUploadsEscapesHostileKeys(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: hostileKey, UploadID: "u1\x1b[31m", Initiated: time.Now()},
+		{Bucket: "logs", Key: "plain.bin", UploadID: "u2", Initiated: time.Now()},
+	}
+	formatter := &OutputFormatter{}
+
+	output := formatter.FormatUploads(uploads, true)
+	assertTerminalSafe(t, output, 4)
+	if !strings.Contains(output, `evil\n\r\t\x1b[2J`) {
+		t.Errorf("FormatUploads() = %q, want the key's control characters as visible escapes", output)
+	}
+
+	rows := [][]string{{hostileKey, "1 KB"}}
+	assertTerminalSafe(t, formatter.FormatTable([]string{"Key", "Size"}, rows), 3)
+	if rows[0][0] != hostileKey {
+		t.Error("FormatTable() modified the caller's rows")
+	}
+
+	assertTerminalSafe(t, formatter.FormatUploads([]types.MultipartUpload{{Bucket: "logs\nforged: 0 uploads", Key: "k"}}, false), 3)
+}
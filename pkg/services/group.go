@@ -0,0 +1,76 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// noPrefixGroup names the group of keys with no directory for prefix:N grouping
+const noPrefixGroup = "(no prefix)"
+
+// GroupByKey returns the function naming the group of an upload for a
+// --group-by value: bucket, region, storage-class or prefix:N, where N is the
+// number of leading path segments of the key
+func GroupByKey(groupBy string) (func(types.MultipartUpload) string, error) {
+	switch groupBy {
+	case "bucket":
+		return func(upload types.MultipartUpload) string { return upload.Bucket }, nil
+	case "region":
+		return func(upload types.MultipartUpload) string { return upload.Region }, nil
+	case "storage-class":
+		return func(upload types.MultipartUpload) string { return upload.StorageClass }, nil
+	}
+
+	if depth, ok := strings.CutPrefix(groupBy, "prefix:"); ok {
+		segments, err := strconv.Atoi(depth)
+		if err != nil || segments < 1 {
+			return nil, fmt.Errorf("invalid --group-by %q, prefix needs a number of path segments such as prefix:2", groupBy)
+		}
+		return func(upload types.MultipartUpload) string { return keyPrefix(upload.Key, segments) }, nil
+	}
+
+	return nil, fmt.Errorf("invalid --group-by %q, supported: bucket, region, storage-class, prefix:N", groupBy)
+}
+
+// keyPrefix returns the first segments directories of key with a trailing
+// slash, fewer when the key is not that deep; the object name is never part
+// of the prefix
+func keyPrefix(key string, segments int) string {
+	end := 0
+	for i := 0; i < segments; i++ {
+		next := strings.IndexByte(key[end:], '/')
+		if next < 0 {
+			break
+		}
+		end += next + 1
+	}
+	if end == 0 {
+		return noPrefixGroup
+	}
+	return key[:end]
+}
+
+// GroupUploads groups uploads by the --group-by value, largest total size
+// first. Uploads keep their order within each group, so sorting before
+// grouping sorts each group.
+func GroupUploads(uploads []types.MultipartUpload, groupBy string) ([]types.UploadGroup, error) {
+	key, err := GroupByKey(groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := groupUploads(uploads, key)
+	groups := make([]types.UploadGroup, len(grouped))
+	for i, group := range grouped {
+		groups[i] = types.UploadGroup{
+			Name:    group.name,
+			Count:   len(group.uploads),
+			Size:    group.size,
+			Uploads: group.uploads,
+		}
+	}
+	return groups, nil
+}
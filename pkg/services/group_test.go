@@ -0,0 +1,85 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/internal/term"
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	tests := []struct {
+		key      string
+		segments int
+		want     string
+	}{
+		{"logs/2024/05/a.gz", 2, "logs/2024/"},
+		{"logs/2024/05/a.gz", 1, "logs/"},
+		{"logs/a.gz", 3, "logs/"},
+		{"a.gz", 2, noPrefixGroup},
+		{"/leading", 1, "/"},
+	}
+	for _, test := range tests {
+		if got := keyPrefix(test.key, test.segments); got != test.want {
+			t.Errorf("keyPrefix(%q, %d) = %q, want %q", test.key, test.segments, got, test.want)
+		}
+	}
+}
+
+func TestGroupUploads(t *testing.T) {
+	initiated := time.Now().Add(-time.Hour)
+	uploads := []types.MultipartUpload{
+		{Bucket: "small", Key: "logs/a", Size: 10, Initiated: initiated, Region: "us-east-1"},
+		{Bucket: "big", Key: "data/b", Size: 300, Initiated: initiated, Region: "eu-west-1"},
+		{Bucket: "small", Key: "logs/c", Size: 20, Initiated: initiated, Region: "us-east-1"},
+	}
+
+	groups, err := GroupUploads(uploads, "bucket")
+	if err != nil {
+		t.Fatalf("GroupUploads() error = %v", err)
+	}
+	if len(groups) != 2 || groups[0].Name != "big" || groups[1].Name != "small" {
+		t.Fatalf("groups = %+v, want big then small", groups)
+	}
+	small := groups[1]
+	if small.Count != 2 || small.Size != 30 || small.Uploads[0].Key != "logs/a" || small.Uploads[1].Key != "logs/c" {
+		t.Errorf("small group = %+v, want both uploads in order with their subtotals", small)
+	}
+
+	if groups, _ := GroupUploads(uploads, "prefix:1"); len(groups) != 2 || groups[0].Name != "data/" || groups[1].Name != "logs/" {
+		t.Errorf("prefix:1 groups = %+v, want data/ and logs/", groups)
+	}
+	if groups, _ := GroupUploads(nil, "region"); groups == nil || len(groups) != 0 {
+		t.Errorf("GroupUploads(nil) = %#v, want no groups", groups)
+	}
+
+	for _, invalid := range []string{"owner", "prefix:", "prefix:0", "prefix:x"} {
+		if _, err := GroupUploads(uploads, invalid); err == nil {
+			t.Errorf("GroupUploads(%q) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestFormatUploadGroups(t *testing.T) {
+	formatter := NewOutputFormatterWithTerminal(term.NewFake(0))
+	initiated := time.Now().Add(-time.Hour)
+	groups, err := GroupUploads([]types.MultipartUpload{
+		{Bucket: "logs", Key: "a", UploadID: "1", Size: 1024, Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "media", Key: "b", UploadID: "2", Size: 2048, Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "media", Key: "c", UploadID: "3", Size: 2048, Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"},
+	}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := formatter.FormatUploadGroups(groups, interfaces.UploadTableOptions{})
+	media := strings.Index(output, "media: 2 uploads (4.0 KB)")
+	logs := strings.Index(output, "logs: 1 upload (1.0 KB)")
+	total := strings.Index(output, "Total: 3 uploads (5.0 KB) in 2 groups")
+	if media < 0 || logs < media || total < logs {
+		t.Errorf("FormatUploadGroups() = %q, want each group's subtotal, largest first, then the totals", output)
+	}
+}
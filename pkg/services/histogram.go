@@ -0,0 +1,86 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// defaultHistogramBarWidth is the bar width when the output is not a terminal
+	defaultHistogramBarWidth = 30
+	// minHistogramBarWidth and maxHistogramBarWidth bound bars fitted to the terminal
+	minHistogramBarWidth = 10
+	maxHistogramBarWidth = 60
+)
+
+// histogramRow is one labelled bar of a histogram
+type histogramRow struct {
+	label string
+	count int
+}
+
+// formatHistogram draws a bar per row, scaled so the largest count fills the
+// bar width, followed by the count and its share of all counts, e.g.
+// "1 week  ████████░░ 1,234 (43.0%)". Bars use "#" and "." without Unicode.
+func (f *OutputFormatter) formatHistogram(rows []histogramRow) string {
+	var total, largest, labelWidth, countWidth int
+	for _, row := range rows {
+		total += row.count
+		if row.count > largest {
+			largest = row.count
+		}
+		if width := displayWidth(row.label); width > labelWidth {
+			labelWidth = width
+		}
+	}
+	counts := make([]string, len(rows))
+	for i, row := range rows {
+		counts[i] = fmt.Sprintf("%s (%s)", formatCount(row.count), formatPercentage(float64(row.count), float64(total)))
+		if width := displayWidth(counts[i]); width > countWidth {
+			countWidth = width
+		}
+	}
+
+	barWidth := f.histogramBarWidth(labelWidth + 2 + 1 + countWidth)
+	filled, empty := "█", "░"
+	if !f.unicode {
+		filled, empty = "#", "."
+	}
+
+	var result strings.Builder
+	for i, row := range rows {
+		bar := 0
+		if largest > 0 {
+			bar = (row.count*barWidth + largest/2) / largest
+		}
+		// Any upload at all gets a visible bar
+		if bar == 0 && row.count > 0 {
+			bar = 1
+		}
+		result.WriteString("  ")
+		writePadded(&result, row.label, labelWidth)
+		result.WriteString("  ")
+		result.WriteString(strings.Repeat(filled, bar))
+		result.WriteString(strings.Repeat(empty, barWidth-bar))
+		result.WriteString(" ")
+		result.WriteString(counts[i])
+		result.WriteString("\n")
+	}
+	return result.String()
+}
+
+// histogramBarWidth returns the bar width that fits the terminal beside
+// reserved columns of labels and counts
+func (f *OutputFormatter) histogramBarWidth(reserved int) int {
+	if f.terminal == nil || f.terminal.Width() <= 0 {
+		return defaultHistogramBarWidth
+	}
+	width := f.terminal.Width() - 2 - reserved
+	if width < minHistogramBarWidth {
+		return minHistogramBarWidth
+	}
+	if width > maxHistogramBarWidth {
+		return maxHistogramBarWidth
+	}
+	return width
+}
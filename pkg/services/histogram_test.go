@@ -0,0 +1,75 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Garvitkul/s3mpc/internal/term"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestFormatHistogramScalesToLargestCount(t *testing.T) {
+	formatter := &OutputFormatter{terminal: term.NewFake(0), unicode: true}
+	out := formatter.formatHistogram([]histogramRow{
+		{label: "1 day", count: 1234},
+		{label: "1 week", count: 617},
+		{label: "1 year+", count: 0},
+	})
+
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), out)
+	}
+	full := strings.Repeat("█", defaultHistogramBarWidth)
+	if !strings.Contains(lines[0], "1 day    "+full+" 1,234 (66.7%)") {
+		t.Errorf("largest row = %q, want a full bar", lines[0])
+	}
+	if got := strings.Count(lines[1], "█"); got != defaultHistogramBarWidth/2 {
+		t.Errorf("half-size row has %d blocks, want %d", got, defaultHistogramBarWidth/2)
+	}
+	if strings.Contains(lines[2], "█") || !strings.Contains(lines[2], "0 (0.0%)") {
+		t.Errorf("empty row = %q, want no blocks", lines[2])
+	}
+}
+
+func TestFormatHistogramFitsTerminalAndFallsBackToASCII(t *testing.T) {
+	formatter := &OutputFormatter{terminal: term.NewFake(40)}
+	out := formatter.formatHistogram([]histogramRow{{label: "< 10 MB", count: 3}, {label: "10 GB+", count: 1}})
+
+	if strings.ContainsAny(out, "█░") || !strings.Contains(out, "#") {
+		t.Errorf("ASCII histogram uses block characters:\n%s", out)
+	}
+	for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+		if width := displayWidth(line); width > 40 {
+			t.Errorf("line %q is %d columns, wider than the 40 column terminal", line, width)
+		}
+	}
+}
+
+func TestSizeDistribution(t *testing.T) {
+	const mb = 1024 * 1024
+	uploads := []types.MultipartUpload{
+		{Size: 5 * mb},
+		{Size: 10 * mb},
+		{Size: 500 * mb},
+		{Size: 20 * 1024 * mb},
+	}
+	dist := NewSizeService(nil).CalculateSizeDistribution(uploads)
+
+	wantCounts := []int{1, 1, 1, 0, 1}
+	if len(dist.Buckets) != len(wantCounts) {
+		t.Fatalf("got %d buckets, want %d", len(dist.Buckets), len(wantCounts))
+	}
+	for i, want := range wantCounts {
+		if dist.Buckets[i].Count != want {
+			t.Errorf("%s count = %d, want %d", dist.Buckets[i].Label, dist.Buckets[i].Count, want)
+		}
+	}
+
+	// JSON carries the ranges but never the bars
+	formatter := &OutputFormatter{unicode: true}
+	json, err := formatter.FormatJSON(types.SizeReport{TotalCount: 4, Distribution: &dist})
+	if err != nil || !strings.Contains(json, `"10 MB - 100 MB"`) || strings.ContainsAny(json, "█#") {
+		t.Errorf("FormatJSON() = %s, %v, want ranges without bars", json, err)
+	}
+}
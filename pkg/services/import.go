@@ -0,0 +1,370 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// ReadUploadsCSV reads uploads from a CSV file written by an export or a saved
+// dry-run. Columns are matched by header name, so extra columns are ignored.
+func ReadUploadsCSV(r io.Reader) ([]types.MultipartUpload, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"bucket", "key", "upload_id", "initiated"} {
+		if _, exists := columns[required]; !exists {
+			return nil, fmt.Errorf("CSV is missing required column '%s'", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, exists := columns[name]; exists && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var uploads []types.MultipartUpload
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV line %d: %w", line, err)
+		}
+
+		initiated, err := types.ParseTimestamp(field(record, "initiated"))
+		if err != nil {
+			return nil, fmt.Errorf("CSV line %d: %w", line, err)
+		}
+
+		upload := types.MultipartUpload{
+			Bucket:        field(record, "bucket"),
+			Key:           field(record, "key"),
+			UploadID:      field(record, "upload_id"),
+			Initiated:     initiated,
+			StorageClass:  field(record, "storage_class"),
+			Region:        field(record, "region"),
+			Initiator:     field(record, "initiator"),
+			InitiatorName: field(record, "initiator_name"),
+			Owner:         field(record, "owner"),
+			OwnerID:       field(record, "owner_id"),
+			Account:       field(record, "account"),
+		}
+
+		if size := field(record, "size"); size != "" {
+			upload.Size, err = strconv.ParseInt(size, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("CSV line %d: invalid size %q", line, size)
+			}
+		}
+		if partsCount := field(record, "parts_count"); partsCount != "" {
+			upload.PartsCount, err = strconv.Atoi(partsCount)
+			if err != nil {
+				return nil, fmt.Errorf("CSV line %d: invalid parts_count %q", line, partsCount)
+			}
+		}
+		if measuredAt := field(record, "measured_at"); measuredAt != "" {
+			upload.MeasuredAt, err = types.ParseTimestamp(measuredAt)
+			if err != nil {
+				return nil, fmt.Errorf("CSV line %d: %w", line, err)
+			}
+		}
+		if initiatedBy := field(record, "initiated_by"); initiatedBy != "" {
+			upload.ServiceInitiated = initiatedBy == "service"
+		} else if serviceInitiated := field(record, "service_initiated"); serviceInitiated != "" {
+			upload.ServiceInitiated, _ = strconv.ParseBool(serviceInitiated)
+		}
+
+		uploads = append(uploads, upload)
+	}
+
+	return uploads, nil
+}
+
+// ReadUploadsJSON reads uploads from a JSON export, a saved dry-run result, or
+// a plain array of uploads
+func ReadUploadsJSON(r io.Reader) ([]types.MultipartUpload, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON: %w", err)
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var uploads []types.MultipartUpload
+		if err := json.Unmarshal(trimmed, &uploads); err != nil {
+			return nil, fmt.Errorf("failed to decode uploads: %w", err)
+		}
+		return uploads, nil
+	}
+
+	var document struct {
+		Uploads []types.MultipartUpload `json:"uploads"`
+	}
+	if err := json.Unmarshal(trimmed, &document); err != nil {
+		return nil, fmt.Errorf("failed to decode uploads: %w", err)
+	}
+
+	return document.Uploads, nil
+}
+
+// Upload file kinds recognized by LoadUploadsFile
+const (
+	UploadsFileExport    = "export"
+	UploadsFileDryRun    = "dry-run report"
+	UploadsFileInventory = "inventory"
+	UploadsFileFailures  = "failure report"
+	UploadsFileDeletions = "deletion report"
+)
+
+// UploadsFile is a set of uploads loaded from a file written by an earlier run
+type UploadsFile struct {
+	Kind        string
+	Uploads     []types.MultipartUpload
+	GeneratedAt time.Time // when the data was collected
+	Incomplete  bool      // the file was cut short, e.g. a failure report from a killed run
+}
+
+// uploadsFileProbe holds the top-level fields used to detect a JSON file's schema
+type uploadsFileProbe struct {
+	SchemaVersion int              `json:"schema_version"`
+	Kind          string           `json:"kind"`
+	ExportedAt    string           `json:"exported_at"`
+	GeneratedAt   string           `json:"generated_at"`
+	Command       *string          `json:"command"`
+	TotalUploads  *int             `json:"total_uploads"`
+	Uploads       *json.RawMessage `json:"uploads"`
+	Outcomes      *json.RawMessage `json:"outcomes"`
+}
+
+// LoadUploadsFile loads uploads from an export (CSV or JSON), a saved dry-run
+// report, a failure report, the failed uploads of a JSON deletion report, or an
+// inventory (a JSON array of uploads), detecting the format from its contents. Files with a schema version newer than types.SchemaVersion are refused.
+func LoadUploadsFile(filename string) (*UploadsFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	if data, err = gunzipIfCompressed(data); err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", filename, err)
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("%s is empty", filename)
+	}
+
+	// Files without a timestamp are dated by their modification time
+	var modTime time.Time
+	if info, err := os.Stat(filename); err == nil {
+		modTime = info.ModTime().UTC()
+	}
+
+	switch trimmed[0] {
+	case '[':
+		uploads, err := ReadUploadsJSON(bytes.NewReader(trimmed))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load inventory %s: %w", filename, err)
+		}
+		return &UploadsFile{Kind: UploadsFileInventory, Uploads: uploads, GeneratedAt: modTime}, nil
+	case '{':
+		return loadUploadsJSONDocument(filename, trimmed, modTime)
+	default:
+		uploads, err := ReadUploadsCSV(bytes.NewReader(trimmed))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CSV export %s: %w", filename, err)
+		}
+		return &UploadsFile{Kind: UploadsFileExport, Uploads: uploads, GeneratedAt: modTime}, nil
+	}
+}
+
+// gunzipIfCompressed decompresses data that starts with the gzip magic number,
+// such as a compressed export, and returns other data unchanged
+func gunzipIfCompressed(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// loadUploadsJSONDocument detects whether a JSON object is an export or a dry-run report
+func loadUploadsJSONDocument(filename string, data []byte, modTime time.Time) (*UploadsFile, error) {
+	var probe uploadsFileProbe
+	if err := json.Unmarshal(data, &probe); err != nil {
+		// A failure report is written as uploads fail, so a killed run leaves
+		// it truncated; keep the uploads that were written in full
+		if report, ok := readPartialFailureReport(data); ok {
+			if err := checkSchemaVersion(filename, report.SchemaVersion); err != nil {
+				return nil, err
+			}
+			return failureReportFile(report, modTime, true), nil
+		}
+		return nil, fmt.Errorf("failed to decode %s: %w", filename, err)
+	}
+
+	if err := checkSchemaVersion(filename, probe.SchemaVersion); err != nil {
+		return nil, err
+	}
+	if probe.Kind == types.DeletionReportKind && probe.Outcomes != nil {
+		var report types.DeletionReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, fmt.Errorf("failed to decode deletion report %s: %w", filename, err)
+		}
+		return deletionReportFile(&report, modTime), nil
+	}
+	if probe.Uploads == nil {
+		return nil, fmt.Errorf("%s is not an s3mpc export, dry-run report or inventory: no uploads list", filename)
+	}
+
+	file := &UploadsFile{GeneratedAt: modTime}
+
+	switch {
+	case probe.Kind == types.FailureReportKind:
+		var report types.FailureReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, fmt.Errorf("failed to decode failure report %s: %w", filename, err)
+		}
+		return failureReportFile(&report, modTime, false), nil
+	case probe.Command != nil && probe.TotalUploads != nil:
+		var result types.DryRunResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode dry-run report %s: %w", filename, err)
+		}
+		file.Kind = UploadsFileDryRun
+		file.Uploads = result.Uploads
+		if !result.GeneratedAt.IsZero() {
+			file.GeneratedAt = result.GeneratedAt
+		}
+	default:
+		uploads, err := ReadUploadsJSON(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", filename, err)
+		}
+		file.Kind = UploadsFileInventory
+		file.Uploads = uploads
+		if probe.ExportedAt != "" {
+			file.Kind = UploadsFileExport
+			if exportedAt, err := types.ParseTimestamp(probe.ExportedAt); err == nil {
+				file.GeneratedAt = exportedAt
+			}
+		}
+	}
+
+	return file, nil
+}
+
+// checkSchemaVersion refuses files written by a newer version of s3mpc
+func checkSchemaVersion(filename string, version int) error {
+	if version > types.SchemaVersion {
+		return fmt.Errorf("%s uses schema version %d, but this version of s3mpc only understands up to %d; upgrade s3mpc to read it",
+			filename, version, types.SchemaVersion)
+	}
+	return nil
+}
+
+// failureReportFile returns the uploads of a failure report
+func failureReportFile(report *types.FailureReport, modTime time.Time, incomplete bool) *UploadsFile {
+	file := &UploadsFile{Kind: UploadsFileFailures, GeneratedAt: modTime, Incomplete: incomplete}
+	if !report.GeneratedAt.IsZero() {
+		file.GeneratedAt = report.GeneratedAt
+	}
+	for _, failed := range report.Uploads {
+		file.Uploads = append(file.Uploads, failed.MultipartUpload)
+	}
+	return file
+}
+
+// deletionReportFile returns the uploads a deletion report records as failed
+func deletionReportFile(report *types.DeletionReport, modTime time.Time) *UploadsFile {
+	file := &UploadsFile{Kind: UploadsFileDeletions, GeneratedAt: modTime}
+	if !report.StartedAt.IsZero() {
+		file.GeneratedAt = report.StartedAt
+	}
+	for _, outcome := range report.Outcomes {
+		if !outcome.Success {
+			file.Uploads = append(file.Uploads, outcome.MultipartUpload)
+		}
+	}
+	return file
+}
+
+// readPartialFailureReport decodes a failure report that ends early, keeping
+// every upload that was written in full. It reports false if data does not
+// start like a failure report.
+func readPartialFailureReport(data []byte) (*types.FailureReport, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, false
+	}
+
+	fields := make(map[string]json.RawMessage)
+	var uploads []types.FailedUpload
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		name, _ := token.(string)
+
+		if name != "uploads" {
+			var value json.RawMessage
+			if err := decoder.Decode(&value); err != nil {
+				break
+			}
+			fields[name] = value
+			continue
+		}
+
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			break
+		}
+		for decoder.More() {
+			var upload types.FailedUpload
+			if err := decoder.Decode(&upload); err != nil {
+				break
+			}
+			uploads = append(uploads, upload)
+		}
+		break
+	}
+
+	var kind string
+	if err := json.Unmarshal(fields["kind"], &kind); err != nil || kind != types.FailureReportKind {
+		return nil, false
+	}
+
+	var report types.FailureReport
+	if raw, err := json.Marshal(fields); err == nil {
+		json.Unmarshal(raw, &report)
+	}
+	report.Uploads = uploads
+	return &report, true
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestLoadUploadsFileFormats(t *testing.T) {
+	dir := t.TempDir()
+	uploads := roundTripUploads()
+	ctx := context.Background()
+
+	exportService := &ExportService{}
+	exportJSON := filepath.Join(dir, "export.json")
+	if err := exportService.ExportToJSON(ctx, uploads, exportJSON); err != nil {
+		t.Fatal(err)
+	}
+	exportCSV := filepath.Join(dir, "export.csv")
+	if err := exportService.ExportToCSV(ctx, uploads, exportCSV); err != nil {
+		t.Fatal(err)
+	}
+
+	generatedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	dryRun := NewDryRunService(NewCostService())
+	result, err := dryRun.SimulateDeletion(ctx, uploads, types.DeleteOptions{IncludeServiceInitiated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result.GeneratedAt = generatedAt
+	dryRunJSON := filepath.Join(dir, "dryrun.json")
+	if err := dryRun.SaveDryRunResult(result, dryRunJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	inventory := filepath.Join(dir, "inventory.json")
+	writeFile(t, inventory, `[{"bucket":"b1","key":"k","upload_id":"u","initiated":"2024-04-01T00:00:00Z","size":10,"storage_class":"STANDARD","region":"us-east-1"}]`)
+
+	tests := []struct {
+		file  string
+		kind  string
+		count int
+	}{
+		{exportJSON, UploadsFileExport, 2},
+		{exportCSV, UploadsFileExport, 2},
+		{dryRunJSON, UploadsFileDryRun, 2},
+		{inventory, UploadsFileInventory, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.file), func(t *testing.T) {
+			loaded, err := LoadUploadsFile(tt.file)
+			if err != nil {
+				t.Fatalf("LoadUploadsFile() error = %v", err)
+			}
+			if loaded.Kind != tt.kind {
+				t.Errorf("Kind = %q, want %q", loaded.Kind, tt.kind)
+			}
+			if len(loaded.Uploads) != tt.count {
+				t.Fatalf("loaded %d uploads, want %d", len(loaded.Uploads), tt.count)
+			}
+			if loaded.Uploads[0].Size == 0 {
+				t.Errorf("upload size was not loaded: %+v", loaded.Uploads[0])
+			}
+			if loaded.GeneratedAt.IsZero() {
+				t.Error("GeneratedAt is zero")
+			}
+		})
+	}
+
+	if loaded, _ := LoadUploadsFile(dryRunJSON); !loaded.GeneratedAt.Equal(generatedAt) {
+		t.Errorf("dry-run GeneratedAt = %v, want %v from the report", loaded.GeneratedAt, generatedAt)
+	}
+}
+
+func TestLoadUploadsFileRejectsUnknownSchemas(t *testing.T) {
+	dir := t.TempDir()
+
+	newer := filepath.Join(dir, "newer.json")
+	writeFile(t, newer, `{"schema_version": 99, "exported_at": "2024-05-01T00:00:00Z", "uploads": []}`)
+	if _, err := LoadUploadsFile(newer); err == nil || !strings.Contains(err.Error(), "schema version 99") {
+		t.Errorf("LoadUploadsFile(newer schema) error = %v, want schema version error", err)
+	}
+
+	unrelated := filepath.Join(dir, "unrelated.json")
+	writeFile(t, unrelated, `{"total_size": 10}`)
+	if _, err := LoadUploadsFile(unrelated); err == nil {
+		t.Error("LoadUploadsFile() accepted a file without an uploads list")
+	}
+}
+
+// writeFile writes content to path, failing the test on error
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
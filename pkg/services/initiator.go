@@ -0,0 +1,78 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// InitiatorRule identifies service-initiated uploads by their Initiator ARN
+type InitiatorRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// DefaultInitiatorRules match initiators that belong to AWS services rather than users
+var DefaultInitiatorRules = []InitiatorRule{
+	{Name: "service-principal", Pattern: regexp.MustCompile(`(?i)^[a-z0-9.-]+\.amazonaws\.com(\.cn)?$`)},
+	{Name: "service-linked-role", Pattern: regexp.MustCompile(`(?i):role/aws-service-role/`)},
+	{Name: "service-linked-role-session", Pattern: regexp.MustCompile(`(?i):assumed-role/AWSServiceRoleFor`)},
+	{Name: "aws-owned-account", Pattern: regexp.MustCompile(`(?i)^arn:aws[a-z-]*:(iam|sts)::aws:`)},
+	{Name: "s3-replication", Pattern: regexp.MustCompile(`(?i):assumed-role/[^/]*s3-?(batch-)?replication`)},
+	{Name: "snow-transfer", Pattern: regexp.MustCompile(`(?i):assumed-role/[^/]*(snowball|snowcone|snowmobile)`)},
+}
+
+// InitiatorClassifier classifies uploads as service-initiated or user-initiated
+type InitiatorClassifier struct {
+	rules []InitiatorRule
+}
+
+// NewInitiatorClassifier creates a classifier using the default rules plus any
+// additional user-supplied regular expressions
+func NewInitiatorClassifier(additionalPatterns []string) (*InitiatorClassifier, error) {
+	rules := make([]InitiatorRule, len(DefaultInitiatorRules), len(DefaultInitiatorRules)+len(additionalPatterns))
+	copy(rules, DefaultInitiatorRules)
+
+	for i, pattern := range additionalPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service initiator pattern %q: %w", pattern, err)
+		}
+		rules = append(rules, InitiatorRule{Name: fmt.Sprintf("custom-%d", i+1), Pattern: re})
+	}
+
+	return &InitiatorClassifier{rules: rules}, nil
+}
+
+// Classify returns whether the initiator belongs to an AWS service, and the name of the matching rule
+func (c *InitiatorClassifier) Classify(initiator string) (bool, string) {
+	if initiator == "" {
+		return false, ""
+	}
+	for _, rule := range c.rules {
+		if rule.Pattern.MatchString(initiator) {
+			return true, rule.Name
+		}
+	}
+	return false, ""
+}
+
+// Apply marks each upload's ServiceInitiated flag from its Initiator
+func (c *InitiatorClassifier) Apply(upload *types.MultipartUpload) {
+	upload.ServiceInitiated, _ = c.Classify(upload.Initiator)
+}
+
+// defaultInitiatorClassifier returns a classifier with only the default rules
+func defaultInitiatorClassifier() *InitiatorClassifier {
+	classifier, _ := NewInitiatorClassifier(nil)
+	return classifier
+}
+
+// InitiatedByLabel returns "service" or "user" for display and filtering
+func InitiatedByLabel(upload types.MultipartUpload) string {
+	if upload.ServiceInitiated {
+		return "service"
+	}
+	return "user"
+}
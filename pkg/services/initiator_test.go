@@ -0,0 +1,63 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestInitiatorClassifier(t *testing.T) {
+	classifier, err := NewInitiatorClassifier([]string{`:assumed-role/datasync-`})
+	if err != nil {
+		t.Fatalf("NewInitiatorClassifier() error = %v", err)
+	}
+
+	tests := []struct {
+		initiator string
+		want      bool
+		wantRule  string
+	}{
+		{"s3.amazonaws.com", true, "service-principal"},
+		{"arn:aws:iam::123456789012:role/aws-service-role/backup.amazonaws.com/AWSServiceRoleForBackup", true, "service-linked-role"},
+		{"arn:aws:sts::123456789012:assumed-role/AWSServiceRoleForAmazonS3Replication/s3-session", true, "service-linked-role-session"},
+		{"arn:aws:sts::aws:assumed-role/internal-transfer/session", true, "aws-owned-account"},
+		{"arn:aws:sts::123456789012:assumed-role/my-s3-replication-role/s3replication", true, "s3-replication"},
+		{"arn:aws:sts::123456789012:assumed-role/SnowballImportRole/job-1", true, "snow-transfer"},
+		{"arn:aws:sts::123456789012:assumed-role/datasync-task/session", true, "custom-1"},
+		{"arn:aws:iam::123456789012:user/alice", false, ""},
+		{"arn:aws:sts::123456789012:assumed-role/deploy/ci", false, ""},
+		{"", false, ""},
+	}
+
+	for _, tt := range tests {
+		got, rule := classifier.Classify(tt.initiator)
+		if got != tt.want || rule != tt.wantRule {
+			t.Errorf("Classify(%q) = %v, %q, want %v, %q", tt.initiator, got, rule, tt.want, tt.wantRule)
+		}
+	}
+}
+
+func TestNewInitiatorClassifierInvalidPattern(t *testing.T) {
+	if _, err := NewInitiatorClassifier([]string{"("}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestFilterUploadsForDeletionExcludesServiceInitiated(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "b", Key: "user", UploadID: "1", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "b", Key: "replica", UploadID: "2", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1", ServiceInitiated: true},
+	}
+	service := &UploadService{}
+
+	filtered, excluded := service.filterUploadsForDeletion(uploads, types.DeleteOptions{})
+	if len(filtered) != 1 || filtered[0].Key != "user" || excluded != 1 {
+		t.Errorf("default filter = %d uploads, %d excluded, want 1 user upload and 1 excluded", len(filtered), excluded)
+	}
+
+	filtered, excluded = service.filterUploadsForDeletion(uploads, types.DeleteOptions{IncludeServiceInitiated: true})
+	if len(filtered) != 2 || excluded != 0 {
+		t.Errorf("with IncludeServiceInitiated = %d uploads, %d excluded, want 2 and 0", len(filtered), excluded)
+	}
+}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// noLifecycleConfigurationCode is returned for buckets without any lifecycle rules
+const noLifecycleConfigurationCode = "NoSuchLifecycleConfiguration"
+
+// AbortRuleID identifies the lifecycle rule written by "lifecycle apply"
+const AbortRuleID = "s3mpc-abort-incomplete-uploads"
+
+// S3LifecycleClientInterface defines the S3 operations needed by LifecycleService
+type S3LifecycleClientInterface interface {
+	GetBucketLifecycleConfiguration(ctx context.Context, bucket string) (*s3.GetBucketLifecycleConfigurationOutput, error)
+	PutBucketLifecycleConfiguration(ctx context.Context, input *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error)
+}
+
+// LifecycleClientFactory creates a lifecycle client for a region
+type LifecycleClientFactory func(ctx context.Context, region string) (S3LifecycleClientInterface, error)
+
+// LifecycleService implements the interfaces.LifecycleService interface
+type LifecycleService struct {
+	bucketService interfaces.BucketService
+	uploadService interfaces.UploadService
+	concurrency   int
+	newClient     LifecycleClientFactory
+	clients       map[string]S3LifecycleClientInterface
+	clientMutex   sync.Mutex
+}
+
+// NewLifecycleService creates a new LifecycleService using regional clients of
+// client, which share its credentials, rate limit and retry settings
+func NewLifecycleService(bucketService interfaces.BucketService, uploadService interfaces.UploadService, concurrency int, client *awsclient.S3Client) interfaces.LifecycleService {
+	return NewLifecycleServiceWithClients(bucketService, uploadService, concurrency, func(ctx context.Context, region string) (S3LifecycleClientInterface, error) {
+		return client.ForRegion(region), nil
+	})
+}
+
+// NewLifecycleServiceWithClients creates a new LifecycleService with a custom client factory
+func NewLifecycleServiceWithClients(bucketService interfaces.BucketService, uploadService interfaces.UploadService, concurrency int, newClient LifecycleClientFactory) *LifecycleService {
+	if concurrency <= 0 {
+		concurrency = 10
+	}
+	return &LifecycleService{
+		bucketService: bucketService,
+		uploadService: uploadService,
+		concurrency:   concurrency,
+		newClient:     newClient,
+		clients:       make(map[string]S3LifecycleClientInterface),
+	}
+}
+
+// CheckBuckets reports the abort-incomplete-uploads rule and upload count of
+// each bucket, sorted by bucket name. A bucket whose configuration cannot be
+// read is reported with Error set, and one whose uploads cannot be listed with
+// ListError set and no count, rather than failing the whole check.
+func (s *LifecycleService) CheckBuckets(ctx context.Context, opts types.ListOptions) ([]types.LifecycleStatus, error) {
+	buckets, skipped, err := s.listBuckets(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]types.LifecycleStatus, len(buckets), len(buckets)+len(skipped))
+	s.forEachBucket(len(buckets), func(i int) {
+		statuses[i] = s.checkBucket(ctx, buckets[i])
+		s.countUploads(ctx, &statuses[i])
+	})
+	for _, bucket := range skipped {
+		statuses = append(statuses, types.LifecycleStatus{Bucket: bucket.Bucket, Error: bucket.Error})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Bucket < statuses[j].Bucket
+	})
+
+	return statuses, nil
+}
+
+// listBuckets returns the bucket named in opts, or all buckets in opts.Region
+// and the buckets whose region could not be determined
+func (s *LifecycleService) listBuckets(ctx context.Context, opts types.ListOptions) ([]types.Bucket, []types.SkippedBucket, error) {
+	if opts.BucketName != "" {
+		region, err := s.bucketService.GetBucketRegion(ctx, opts.BucketName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get region for bucket %s: %w", opts.BucketName, err)
+		}
+		return []types.Bucket{{Name: opts.BucketName, Region: region}}, nil, nil
+	}
+
+	buckets, skipped, err := s.bucketService.ListBuckets(ctx, opts.Region)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list buckets: %w", err)
+	}
+	return buckets, skipped, nil
+}
+
+// checkBucket reads the lifecycle configuration of one bucket
+func (s *LifecycleService) checkBucket(ctx context.Context, bucket types.Bucket) types.LifecycleStatus {
+	status := types.LifecycleStatus{Bucket: bucket.Name, Region: bucket.Region}
+
+	client, err := s.getClient(ctx, bucket.Region)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	rules, _, hasConfiguration, err := readLifecycleRules(ctx, client, bucket.Name)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	status.Access.GetLifecycle = true
+	status.HasConfiguration = hasConfiguration
+	applyAbortRule(&status, rules)
+	return status
+}
+
+// countUploads lists the uploads of one bucket. Listing needs a different
+// permission than reading lifecycle rules, so a failure only leaves the count unknown.
+func (s *LifecycleService) countUploads(ctx context.Context, status *types.LifecycleStatus) {
+	uploads, err := s.uploadService.ListUploads(ctx, types.ListOptions{BucketName: status.Bucket})
+	if err != nil {
+		status.ListError = err.Error()
+		return
+	}
+	count := len(uploads)
+	status.UploadCount = &count
+	status.Access.ListUploads = true
+}
+
+// readLifecycleRules returns the lifecycle rules of a bucket and the minimum
+// object size its transitions apply to. A bucket without a lifecycle
+// configuration has no rules and is not an error.
+func readLifecycleRules(ctx context.Context, client S3LifecycleClientInterface, bucket string) ([]s3types.LifecycleRule, s3types.TransitionDefaultMinimumObjectSize, bool, error) {
+	output, err := client.GetBucketLifecycleConfiguration(ctx, bucket)
+	if err != nil {
+		if awsclient.ErrorCode(err) == noLifecycleConfigurationCode {
+			return nil, "", false, nil
+		}
+		return nil, "", false, err
+	}
+	return output.Rules, output.TransitionDefaultMinimumObjectSize, true, nil
+}
+
+// PlanAbortRule works out, for each selected bucket, whether the s3mpc abort
+// rule would be added, would update an earlier s3mpc rule, or is not needed
+// because an enabled rule already covers the whole bucket
+func (s *LifecycleService) PlanAbortRule(ctx context.Context, opts types.LifecycleApplyOptions) ([]types.LifecycleChange, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid lifecycle options: %w", err)
+	}
+
+	buckets, skipped, err := s.listBuckets(ctx, types.ListOptions{BucketName: opts.BucketName, Region: opts.Region})
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]types.LifecycleChange, len(buckets), len(buckets)+len(skipped))
+	s.forEachBucket(len(buckets), func(i int) {
+		changes[i] = s.planBucket(ctx, buckets[i], opts.Days)
+	})
+	for _, bucket := range skipped {
+		changes = append(changes, types.LifecycleChange{Bucket: bucket.Bucket, Rule: newAbortLifecycleRule(opts.Days), Error: bucket.Error})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Bucket < changes[j].Bucket
+	})
+
+	return changes, nil
+}
+
+// planBucket compares the current rules of a bucket with the abort rule
+func (s *LifecycleService) planBucket(ctx context.Context, bucket types.Bucket, days int32) types.LifecycleChange {
+	change := types.LifecycleChange{Bucket: bucket.Name, Region: bucket.Region, Rule: newAbortLifecycleRule(days)}
+
+	client, err := s.getClient(ctx, bucket.Region)
+	if err != nil {
+		change.Error = err.Error()
+		return change
+	}
+
+	rules, _, _, err := readLifecycleRules(ctx, client, bucket.Name)
+	if err != nil {
+		change.Error = err.Error()
+		return change
+	}
+
+	var others []s3types.LifecycleRule
+	for _, rule := range rules {
+		if aws.ToString(rule.ID) != AbortRuleID {
+			others = append(others, rule)
+			continue
+		}
+		change.Replaces = true
+		if rule.Status == s3types.ExpirationStatusEnabled && lifecycleRulePrefix(rule) == "" &&
+			rule.AbortIncompleteMultipartUpload != nil && aws.ToInt32(rule.AbortIncompleteMultipartUpload.DaysAfterInitiation) == days {
+			change.Skipped = fmt.Sprintf("s3mpc rule already aborts uploads after %d days", days)
+		}
+	}
+	change.ExistingRules = len(others)
+
+	if change.Skipped == "" && !change.Replaces {
+		status := types.LifecycleStatus{}
+		applyAbortRule(&status, others)
+		if status.Covered() {
+			change.Skipped = fmt.Sprintf("already covered by rule %q (%d days)", status.RuleID, status.DaysAfterInitiation)
+		}
+	}
+
+	return change
+}
+
+// ApplyAbortRule writes each pending change. The bucket's rules are read again
+// just before writing, so rules added since the plan are kept as well.
+func (s *LifecycleService) ApplyAbortRule(ctx context.Context, changes []types.LifecycleChange) (*types.LifecycleApplyResult, error) {
+	result := &types.LifecycleApplyResult{Changes: make([]types.LifecycleChange, len(changes))}
+	copy(result.Changes, changes)
+
+	s.forEachBucket(len(result.Changes), func(i int) {
+		change := &result.Changes[i]
+		if !change.Pending() {
+			return
+		}
+		if err := s.applyBucket(ctx, change); err != nil {
+			change.Error = err.Error()
+			return
+		}
+		change.Applied = true
+	})
+
+	var bucketErrors []bucketError
+	for _, change := range result.Changes {
+		switch {
+		case change.Applied:
+			result.Applied++
+		case change.Error != "":
+			result.Failed++
+			bucketErrors = append(bucketErrors, bucketError{bucket: change.Bucket, err: errors.New(change.Error)})
+		}
+	}
+	if len(bucketErrors) > 0 {
+		result.ErrorSummary = summarizeErrors(bucketErrors)
+	}
+
+	return result, nil
+}
+
+// applyBucket merges the abort rule into the current rules of a bucket and writes them
+func (s *LifecycleService) applyBucket(ctx context.Context, change *types.LifecycleChange) error {
+	client, err := s.getClient(ctx, change.Region)
+	if err != nil {
+		return err
+	}
+
+	rules, minimumSize, _, err := readLifecycleRules(ctx, client, change.Bucket)
+	if err != nil {
+		return fmt.Errorf("failed to read lifecycle configuration: %w", err)
+	}
+
+	// The minimum size is written back too: left out, S3 resets it to the
+	// 128 KB default, changing what the bucket's transition rules apply to
+	merged := mergeAbortRule(rules, change.Rule)
+	_, err = client.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                             aws.String(change.Bucket),
+		LifecycleConfiguration:             &s3types.BucketLifecycleConfiguration{Rules: merged},
+		TransitionDefaultMinimumObjectSize: minimumSize,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write lifecycle configuration: %w", err)
+	}
+
+	change.ExistingRules = len(merged) - 1
+	return nil
+}
+
+// newAbortLifecycleRule returns the whole-bucket rule aborting uploads after days
+func newAbortLifecycleRule(days int32) types.AbortLifecycleRule {
+	return types.AbortLifecycleRule{
+		ID:                             AbortRuleID,
+		Status:                         string(s3types.ExpirationStatusEnabled),
+		Filter:                         types.LifecycleRuleFilter{Prefix: ""},
+		AbortIncompleteMultipartUpload: types.AbortIncompleteUploadAction{DaysAfterInitiation: days},
+	}
+}
+
+// mergeAbortRule returns rules with the abort rule added, replacing an earlier
+// s3mpc rule. All other rules are kept unchanged and in order.
+func mergeAbortRule(rules []s3types.LifecycleRule, rule types.AbortLifecycleRule) []s3types.LifecycleRule {
+	merged := make([]s3types.LifecycleRule, 0, len(rules)+1)
+	for _, existing := range rules {
+		if aws.ToString(existing.ID) != rule.ID {
+			merged = append(merged, existing)
+		}
+	}
+
+	return append(merged, s3types.LifecycleRule{
+		ID:     aws.String(rule.ID),
+		Status: s3types.ExpirationStatus(rule.Status),
+		Filter: &s3types.LifecycleRuleFilter{Prefix: aws.String(rule.Filter.Prefix)},
+		AbortIncompleteMultipartUpload: &s3types.AbortIncompleteMultipartUpload{
+			DaysAfterInitiation: aws.Int32(rule.AbortIncompleteMultipartUpload.DaysAfterInitiation),
+		},
+	})
+}
+
+// forEachBucket runs fn for indexes 0..n-1 with at most s.concurrency running at once
+func (s *LifecycleService) forEachBucket(n int, fn func(i int)) {
+	semaphore := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// applyAbortRule records the most effective AbortIncompleteMultipartUpload rule:
+// enabled before disabled, whole-bucket before prefix-scoped, then the fewest days
+func applyAbortRule(status *types.LifecycleStatus, rules []s3types.LifecycleRule) {
+	rank := func(enabled bool, prefix string) int {
+		r := 0
+		if !enabled {
+			r += 2
+		}
+		if prefix != "" {
+			r++
+		}
+		return r
+	}
+
+	for _, rule := range rules {
+		if rule.AbortIncompleteMultipartUpload == nil {
+			continue
+		}
+
+		enabled := rule.Status == s3types.ExpirationStatusEnabled
+		prefix := lifecycleRulePrefix(rule)
+		days := aws.ToInt32(rule.AbortIncompleteMultipartUpload.DaysAfterInitiation)
+
+		if status.HasAbortRule {
+			current, candidate := rank(status.RuleEnabled, status.Prefix), rank(enabled, prefix)
+			if candidate > current || (candidate == current && days >= status.DaysAfterInitiation) {
+				continue
+			}
+		}
+
+		status.HasAbortRule = true
+		status.RuleID = aws.ToString(rule.ID)
+		status.RuleEnabled = enabled
+		status.Prefix = prefix
+		status.DaysAfterInitiation = days
+	}
+}
+
+// lifecycleRulePrefix returns the key prefix a rule is limited to, or "" for the whole bucket
+func lifecycleRulePrefix(rule s3types.LifecycleRule) string {
+	if filter := rule.Filter; filter != nil {
+		if filter.And != nil {
+			return aws.ToString(filter.And.Prefix)
+		}
+		return aws.ToString(filter.Prefix)
+	}
+	return aws.ToString(rule.Prefix)
+}
+
+// getClient returns the lifecycle client for a region, creating it if needed
+func (s *LifecycleService) getClient(ctx context.Context, region string) (S3LifecycleClientInterface, error) {
+	s.clientMutex.Lock()
+	defer s.clientMutex.Unlock()
+
+	if client, exists := s.clients[region]; exists {
+		return client, nil
+	}
+
+	client, err := s.newClient(ctx, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client for region %s: %w", region, err)
+	}
+	s.clients[region] = client
+	return client, nil
+}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// bucketListService returns a fixed list of buckets
+type bucketListService struct {
+	staticBucketService
+	buckets []types.Bucket
+}
+
+func (b *bucketListService) ListBuckets(ctx context.Context, region string) ([]types.Bucket, []types.SkippedBucket, error) {
+	return b.buckets, nil, nil
+}
+
+// mockLifecycleClient returns canned lifecycle rules and transition minimum
+// sizes or errors per bucket and records written configurations
+type mockLifecycleClient struct {
+	rules        map[string][]s3types.LifecycleRule
+	minimumSizes map[string]s3types.TransitionDefaultMinimumObjectSize
+	errors       map[string]error
+	putErrors    map[string]error
+	mutex        sync.Mutex
+	puts         map[string][]s3types.LifecycleRule
+	putSizes     map[string]s3types.TransitionDefaultMinimumObjectSize
+}
+
+func (m *mockLifecycleClient) GetBucketLifecycleConfiguration(ctx context.Context, bucket string) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	if err, exists := m.errors[bucket]; exists {
+		return nil, err
+	}
+	return &s3.GetBucketLifecycleConfigurationOutput{Rules: m.rules[bucket], TransitionDefaultMinimumObjectSize: m.minimumSizes[bucket]}, nil
+}
+
+func (m *mockLifecycleClient) PutBucketLifecycleConfiguration(ctx context.Context, input *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	bucket := aws.ToString(input.Bucket)
+	if err, exists := m.putErrors[bucket]; exists {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.puts == nil {
+		m.puts = make(map[string][]s3types.LifecycleRule)
+		m.putSizes = make(map[string]s3types.TransitionDefaultMinimumObjectSize)
+	}
+	m.puts[bucket] = input.LifecycleConfiguration.Rules
+	m.putSizes[bucket] = input.TransitionDefaultMinimumObjectSize
+	return &s3.PutBucketLifecycleConfigurationOutput{}, nil
+}
+
+func abortRule(id string, days int32, status s3types.ExpirationStatus, prefix string) s3types.LifecycleRule {
+	return s3types.LifecycleRule{
+		ID:                             aws.String(id),
+		Status:                         status,
+		Filter:                         &s3types.LifecycleRuleFilter{Prefix: aws.String(prefix)},
+		AbortIncompleteMultipartUpload: &s3types.AbortIncompleteMultipartUpload{DaysAfterInitiation: aws.Int32(days)},
+	}
+}
+
+func TestCheckBucketsReportsAbortRules(t *testing.T) {
+	noConfiguration := fmt.Errorf("operation failed after 3 retries: %w", &smithy.GenericAPIError{Code: "NoSuchLifecycleConfiguration", Message: "The lifecycle configuration does not exist"})
+	client := &mockLifecycleClient{
+		rules: map[string][]s3types.LifecycleRule{
+			"covered": {
+				{ID: aws.String("expire-logs"), Status: s3types.ExpirationStatusEnabled, Expiration: &s3types.LifecycleExpiration{Days: aws.Int32(90)}},
+				abortRule("abort-scoped", 3, s3types.ExpirationStatusEnabled, "tmp/"),
+				abortRule("abort-all", 7, s3types.ExpirationStatusEnabled, ""),
+			},
+			"disabled":   {abortRule("abort-off", 1, s3types.ExpirationStatusDisabled, "")},
+			"transition": {{ID: aws.String("to-glacier"), Status: s3types.ExpirationStatusEnabled, Transitions: []s3types.Transition{{Days: aws.Int32(30)}}}},
+		},
+		errors: map[string]error{
+			"bare":   noConfiguration,
+			"locked": errors.New("operation error S3: GetBucketLifecycleConfiguration, https response error StatusCode: 403, RequestID: 1, HostID: x, api error AccessDenied: Access Denied"),
+		},
+	}
+
+	var regions []string
+	bucketService := &bucketListService{}
+	for _, name := range []string{"transition", "locked", "disabled", "covered", "bare"} {
+		bucketService.buckets = append(bucketService.buckets, types.Bucket{Name: name, Region: "us-west-2"})
+	}
+	uploadService := &countingUploadService{uploads: []types.MultipartUpload{{Bucket: "bare"}, {Bucket: "bare"}, {Bucket: "covered"}}}
+
+	service := NewLifecycleServiceWithClients(bucketService, uploadService, 2, func(ctx context.Context, region string) (S3LifecycleClientInterface, error) {
+		regions = append(regions, region)
+		return client, nil
+	})
+
+	statuses, err := service.CheckBuckets(context.Background(), types.ListOptions{})
+	if err != nil {
+		t.Fatalf("CheckBuckets() error = %v", err)
+	}
+	if len(regions) != 1 {
+		t.Errorf("created %d clients for one region, want 1", len(regions))
+	}
+
+	byBucket := make(map[string]types.LifecycleStatus)
+	var order []string
+	for _, status := range statuses {
+		byBucket[status.Bucket] = status
+		order = append(order, status.Bucket)
+	}
+	if strings.Join(order, ",") != "bare,covered,disabled,locked,transition" {
+		t.Errorf("buckets in order %v, want sorted by name", order)
+	}
+
+	if bare := byBucket["bare"]; bare.HasConfiguration || bare.HasAbortRule || bare.Error != "" || bare.UploadCount == nil || *bare.UploadCount != 2 {
+		t.Errorf("bare = %+v, want no configuration, no error and 2 uploads", bare)
+	}
+	if covered := byBucket["covered"]; !covered.Covered() || covered.RuleID != "abort-all" || covered.DaysAfterInitiation != 7 || covered.UploadCount == nil || *covered.UploadCount != 1 {
+		t.Errorf("covered = %+v, want the whole-bucket rule abort-all after 7 days", covered)
+	}
+	if disabled := byBucket["disabled"]; !disabled.HasAbortRule || disabled.RuleEnabled || disabled.Covered() {
+		t.Errorf("disabled = %+v, want a disabled rule that does not cover the bucket", disabled)
+	}
+	if transition := byBucket["transition"]; !transition.HasConfiguration || transition.HasAbortRule {
+		t.Errorf("transition = %+v, want a configuration without an abort rule", transition)
+	}
+	if locked := byBucket["locked"]; !strings.Contains(locked.Error, "AccessDenied") {
+		t.Errorf("locked = %+v, want the AccessDenied error", locked)
+	}
+
+	output := NewOutputFormatter().FormatLifecycleStatus(statuses)
+	for _, want := range []string{"none (other rules only)", "abort-all", "3 of 4 buckets lack", "locked: operation error"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("FormatLifecycleStatus() missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestApplyAbortRuleKeepsExistingRules(t *testing.T) {
+	expireLogs := s3types.LifecycleRule{
+		ID:         aws.String("expire-logs"),
+		Status:     s3types.ExpirationStatusEnabled,
+		Filter:     &s3types.LifecycleRuleFilter{Prefix: aws.String("logs/")},
+		Expiration: &s3types.LifecycleExpiration{Days: aws.Int32(90)},
+	}
+	toGlacier := s3types.LifecycleRule{
+		ID:          aws.String("to-glacier"),
+		Status:      s3types.ExpirationStatusEnabled,
+		Prefix:      aws.String("archive/"),
+		Transitions: []s3types.Transition{{Days: aws.Int32(30), StorageClass: s3types.TransitionStorageClassGlacier}},
+	}
+	client := &mockLifecycleClient{
+		rules: map[string][]s3types.LifecycleRule{
+			"rules":   {expireLogs, toGlacier},
+			"stale":   {expireLogs, abortRule(AbortRuleID, 30, s3types.ExpirationStatusEnabled, "")},
+			"current": {abortRule(AbortRuleID, 5, s3types.ExpirationStatusEnabled, "")},
+			"covered": {abortRule("team-rule", 2, s3types.ExpirationStatusEnabled, "")},
+		},
+		errors: map[string]error{
+			"empty":  &smithy.GenericAPIError{Code: "NoSuchLifecycleConfiguration"},
+			"locked": errors.New("operation error S3: GetBucketLifecycleConfiguration, https response error StatusCode: 403, RequestID: 1, HostID: x, api error AccessDenied: Access Denied"),
+		},
+		putErrors: map[string]error{
+			"stale": errors.New("operation error S3: PutBucketLifecycleConfiguration, https response error StatusCode: 403, RequestID: 2, HostID: y, api error AccessDenied: Access Denied"),
+		},
+	}
+
+	bucketService := &bucketListService{}
+	for _, name := range []string{"rules", "stale", "current", "covered", "empty", "locked"} {
+		bucketService.buckets = append(bucketService.buckets, types.Bucket{Name: name, Region: "eu-west-1"})
+	}
+	service := NewLifecycleServiceWithClients(bucketService, &countingUploadService{}, 4, func(ctx context.Context, region string) (S3LifecycleClientInterface, error) {
+		return client, nil
+	})
+
+	if _, err := service.PlanAbortRule(context.Background(), types.LifecycleApplyOptions{Days: 5}); err == nil {
+		t.Error("PlanAbortRule() without a bucket or --all-missing succeeded, want a validation error")
+	}
+
+	changes, err := service.PlanAbortRule(context.Background(), types.LifecycleApplyOptions{Days: 5, AllMissing: true})
+	if err != nil {
+		t.Fatalf("PlanAbortRule() error = %v", err)
+	}
+	planned := make(map[string]types.LifecycleChange)
+	for _, change := range changes {
+		planned[change.Bucket] = change
+	}
+	if !planned["rules"].Pending() || planned["rules"].ExistingRules != 2 {
+		t.Errorf("rules = %+v, want a pending change keeping 2 rules", planned["rules"])
+	}
+	if !planned["stale"].Replaces || !planned["stale"].Pending() {
+		t.Errorf("stale = %+v, want a pending update of the s3mpc rule", planned["stale"])
+	}
+	if planned["current"].Skipped == "" || planned["covered"].Skipped == "" {
+		t.Errorf("current = %+v, covered = %+v, want both skipped", planned["current"], planned["covered"])
+	}
+	if planned["locked"].Error == "" {
+		t.Errorf("locked = %+v, want the read error", planned["locked"])
+	}
+	if len(client.puts) != 0 {
+		t.Fatal("PlanAbortRule() wrote lifecycle configurations")
+	}
+
+	plan := NewOutputFormatter().FormatLifecyclePlan(changes)
+	for _, want := range []string{`"ID": "` + AbortRuleID + `"`, `"DaysAfterInitiation": 5`, `"Prefix": ""`, "rules (eu-west-1): add the rule, keeping 2 existing rules"} {
+		if !strings.Contains(plan, want) {
+			t.Errorf("FormatLifecyclePlan() missing %q:\n%s", want, plan)
+		}
+	}
+
+	result, err := service.ApplyAbortRule(context.Background(), changes)
+	if err != nil {
+		t.Fatalf("ApplyAbortRule() error = %v", err)
+	}
+	if result.Applied != 2 || result.Failed != 2 {
+		t.Errorf("Applied = %d, Failed = %d, want 2 and 2", result.Applied, result.Failed)
+	}
+	// The read and write failures need different permissions, so they are separate causes
+	if len(result.ErrorSummary) != 2 || result.ErrorSummary[0].Code != "AccessDenied" || result.ErrorSummary[1].Code != "AccessDenied" {
+		t.Errorf("ErrorSummary = %+v, want two AccessDenied causes", result.ErrorSummary)
+	}
+
+	written := client.puts["rules"]
+	if len(written) != 3 {
+		t.Fatalf("wrote %d rules to bucket rules, want 3", len(written))
+	}
+	if written[0].Expiration == nil || aws.ToInt32(written[0].Expiration.Days) != 90 || lifecycleRulePrefix(written[0]) != "logs/" {
+		t.Errorf("expiration rule changed: %+v", written[0])
+	}
+	if len(written[1].Transitions) != 1 || aws.ToString(written[1].Prefix) != "archive/" {
+		t.Errorf("transition rule changed: %+v", written[1])
+	}
+	if aws.ToString(written[2].ID) != AbortRuleID || aws.ToInt32(written[2].AbortIncompleteMultipartUpload.DaysAfterInitiation) != 5 {
+		t.Errorf("abort rule = %+v, want %s after 5 days", written[2], AbortRuleID)
+	}
+
+	if empty := client.puts["empty"]; len(empty) != 1 {
+		t.Errorf("wrote %d rules to a bucket without configuration, want 1", len(empty))
+	}
+	if _, written := client.puts["covered"]; written {
+		t.Error("wrote to a bucket already covered by another rule")
+	}
+
+	report := NewOutputFormatter().FormatLifecycleApplyResult(*result)
+	for _, want := range []string{"Buckets updated: 2", "Failed updates: 2", "rules: updated (2 other rules kept)", "Hint: Grant s3:PutBucketLifecycleConfiguration"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("FormatLifecycleApplyResult() missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestApplyAbortRuleKeepsTransitionMinimumSize(t *testing.T) {
+	client := &mockLifecycleClient{
+		rules: map[string][]s3types.LifecycleRule{
+			"archive": {{
+				ID:          aws.String("to-glacier"),
+				Status:      s3types.ExpirationStatusEnabled,
+				Filter:      &s3types.LifecycleRuleFilter{Prefix: aws.String("")},
+				Transitions: []s3types.Transition{{Days: aws.Int32(30), StorageClass: s3types.TransitionStorageClassGlacier}},
+			}},
+		},
+		minimumSizes: map[string]s3types.TransitionDefaultMinimumObjectSize{
+			"archive": s3types.TransitionDefaultMinimumObjectSizeVariesByStorageClass,
+		},
+	}
+	bucketService := &bucketListService{buckets: []types.Bucket{{Name: "archive", Region: "us-east-1"}}}
+	service := NewLifecycleServiceWithClients(bucketService, &countingUploadService{}, 1, func(ctx context.Context, region string) (S3LifecycleClientInterface, error) {
+		return client, nil
+	})
+
+	changes, err := service.PlanAbortRule(context.Background(), types.LifecycleApplyOptions{Days: 7, BucketName: "archive"})
+	if err != nil {
+		t.Fatalf("PlanAbortRule() error = %v", err)
+	}
+	result, err := service.ApplyAbortRule(context.Background(), changes)
+	if err != nil || result.Applied != 1 {
+		t.Fatalf("ApplyAbortRule() = %+v, %v, want the rule applied", result, err)
+	}
+	if got := client.putSizes["archive"]; got != s3types.TransitionDefaultMinimumObjectSizeVariesByStorageClass {
+		t.Errorf("wrote transition minimum size %q, want the bucket's varies_by_storage_class kept", got)
+	}
+}
+
+func TestLifecycleWithPartialPermissions(t *testing.T) {
+	accessDenied := func(operation string) error {
+		return fmt.Errorf("operation error S3: %s, https response error StatusCode: 403, RequestID: 1, HostID: x, api error AccessDenied: Access Denied", operation)
+	}
+
+	// Each bucket name is the permission matrix row it exercises:
+	// list uploads, read lifecycle (ok, none or denied) and write lifecycle
+	type permissions struct {
+		list, put bool
+		read      string
+	}
+	matrix := map[string]permissions{
+		"list-read-put":   {list: true, read: "ok", put: true},
+		"list-none-put":   {list: true, read: "none", put: true},
+		"list-read-deny":  {list: true, read: "ok"},
+		"list-deny":       {list: true, read: "denied"},
+		"nolist-read-put": {read: "ok", put: true},
+		"nolist-none-put": {read: "none", put: true},
+		"nolist-read":     {read: "ok"},
+		"nolist-deny":     {read: "denied"},
+	}
+
+	client := &mockLifecycleClient{rules: map[string][]s3types.LifecycleRule{}, errors: map[string]error{}, putErrors: map[string]error{}}
+	bucketService := &bucketListService{}
+	uploadService := &countingUploadService{denyList: map[string]bool{}}
+	for name, perms := range matrix {
+		bucketService.buckets = append(bucketService.buckets, types.Bucket{Name: name, Region: "us-east-1"})
+		uploadService.uploads = append(uploadService.uploads, types.MultipartUpload{Bucket: name})
+		if !perms.list {
+			uploadService.denyList[name] = true
+		}
+		switch perms.read {
+		case "ok":
+			client.rules[name] = []s3types.LifecycleRule{{ID: aws.String("expire-logs"), Status: s3types.ExpirationStatusEnabled, Expiration: &s3types.LifecycleExpiration{Days: aws.Int32(90)}}}
+		case "none":
+			client.errors[name] = &smithy.GenericAPIError{Code: "NoSuchLifecycleConfiguration"}
+		case "denied":
+			client.errors[name] = accessDenied("GetBucketLifecycleConfiguration")
+		}
+		if !perms.put {
+			client.putErrors[name] = accessDenied("PutBucketLifecycleConfiguration")
+		}
+	}
+
+	service := NewLifecycleServiceWithClients(bucketService, uploadService, 3, func(ctx context.Context, region string) (S3LifecycleClientInterface, error) {
+		return client, nil
+	})
+
+	statuses, err := service.CheckBuckets(context.Background(), types.ListOptions{})
+	if err != nil {
+		t.Fatalf("CheckBuckets() error = %v, want per-bucket errors only", err)
+	}
+	if len(statuses) != len(matrix) {
+		t.Fatalf("CheckBuckets() returned %d buckets, want %d", len(statuses), len(matrix))
+	}
+	for _, status := range statuses {
+		perms := matrix[status.Bucket]
+		if status.Access.ListUploads != perms.list || status.Access.GetLifecycle != (perms.read != "denied") {
+			t.Errorf("%s: Access = %+v, want list %v and read %v", status.Bucket, status.Access, perms.list, perms.read != "denied")
+		}
+		if perms.list {
+			if status.UploadCount == nil || *status.UploadCount != 1 || status.ListError != "" {
+				t.Errorf("%s: UploadCount = %v, ListError = %q, want 1 upload", status.Bucket, status.UploadCount, status.ListError)
+			}
+		} else if status.UploadCount != nil || status.ListError == "" {
+			t.Errorf("%s: UploadCount = %v, ListError = %q, want an unknown count and the list error", status.Bucket, status.UploadCount, status.ListError)
+		}
+		if (perms.read == "denied") != (status.Error != "") {
+			t.Errorf("%s: Error = %q, want an error only when lifecycle reads are denied", status.Bucket, status.Error)
+		}
+		if perms.read != "denied" && status.HasAbortRule {
+			t.Errorf("%s: HasAbortRule = true, want the missing rule reported", status.Bucket)
+		}
+	}
+
+	output := NewOutputFormatter().FormatLifecycleStatus(statuses)
+	for _, want := range []string{"unknown", "Could not list uploads (count unknown):", "nolist-read: access denied listing uploads", "6 of 6 buckets lack"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("FormatLifecycleStatus() missing %q:\n%s", want, output)
+		}
+	}
+
+	listCalls := atomic.LoadInt64(&uploadService.listCalls)
+	changes, err := service.PlanAbortRule(context.Background(), types.LifecycleApplyOptions{Days: 7, AllMissing: true})
+	if err != nil {
+		t.Fatalf("PlanAbortRule() error = %v", err)
+	}
+	if calls := atomic.LoadInt64(&uploadService.listCalls); calls != listCalls {
+		t.Errorf("PlanAbortRule() listed uploads %d times, want 0", calls-listCalls)
+	}
+	for _, change := range changes {
+		// Rules cannot be merged without reading them first
+		if perms := matrix[change.Bucket]; (perms.read == "denied") != (change.Error != "") {
+			t.Errorf("%s: plan Error = %q, want an error only when lifecycle reads are denied", change.Bucket, change.Error)
+		}
+	}
+
+	result, err := service.ApplyAbortRule(context.Background(), changes)
+	if err != nil {
+		t.Fatalf("ApplyAbortRule() error = %v", err)
+	}
+	if result.Applied != 4 || result.Failed != 4 {
+		t.Errorf("Applied = %d, Failed = %d, want 4 and 4", result.Applied, result.Failed)
+	}
+	for name, perms := range matrix {
+		_, written := client.puts[name]
+		if want := perms.read != "denied" && perms.put; written != want {
+			t.Errorf("%s: written = %v, want %v", name, written, want)
+		}
+	}
+}
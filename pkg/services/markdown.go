@@ -0,0 +1,409 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+	"github.com/Garvitkul/s3mpc/pkg/units"
+)
+
+// markdownCodeColumns are the upload columns shown as code, so that
+// underscores and asterisks in keys are not read as emphasis
+var markdownCodeColumns = map[string]bool{"bucket": true, "key": true, "upload-id": true}
+
+// markdownNumber matches cells that are right-aligned: counts, sizes, amounts,
+// percentages and ages such as 12, 1.5 GB, $0.42, 45.0% and 7d
+var markdownNumber = regexp.MustCompile(`^[-+]?\$?[0-9][0-9,]*(\.[0-9]+)?( ?([A-Za-z]{1,3}|%))?$`)
+
+// markdownTotal is the label of the totals row of markdown tables
+const markdownTotal = "**Total**"
+
+// FormatMarkdownTable formats rows as a GitHub-flavored markdown table. Columns
+// whose cells are all numbers, sizes, amounts or percentages are right-aligned.
+func (f *OutputFormatter) FormatMarkdownTable(headers []string, rows [][]string) string {
+	var result strings.Builder
+
+	cells := make([]string, len(headers))
+	for i, header := range headers {
+		cells[i] = markdownCell(header)
+	}
+	result.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+
+	for i := range headers {
+		cells[i] = "---"
+		if markdownNumericColumn(rows, i) {
+			cells[i] = "---:"
+		}
+	}
+	result.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+
+	for _, row := range rows {
+		for i := range headers {
+			cells[i] = ""
+			if i < len(row) {
+				cells[i] = markdownCell(row[i])
+			}
+		}
+		result.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	return result.String()
+}
+
+// markdownNumericColumn reports whether every filled cell of column i is a
+// number; a column with no filled cells is not
+func markdownNumericColumn(rows [][]string, i int) bool {
+	numeric := false
+	for _, row := range rows {
+		if i >= len(row) || row[i] == "" || row[i] == "–" || row[i] == "-" {
+			continue
+		}
+		if !markdownNumber.MatchString(row[i]) {
+			return false
+		}
+		numeric = true
+	}
+	return numeric
+}
+
+// markdownCell escapes the pipes and line breaks that would end a table cell
+func markdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(value)
+}
+
+// markdownCode formats value as inline code, fencing it with two backticks
+// when it contains one
+func markdownCode(value string) string {
+	if value == "" {
+		return ""
+	}
+	value = types.DisplayString(value)
+	if strings.Contains(value, "`") {
+		return "`` " + value + " ``"
+	}
+	return "`" + value + "`"
+}
+
+// markdownHeader starts a markdown report with its title and, when known, the
+// command line that produced it
+func markdownHeader(title, command string) string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("## %s\n\n", title))
+	if command != "" {
+		result.WriteString(fmt.Sprintf("```sh\n%s\n```\n\n", command))
+	}
+	return result.String()
+}
+
+// markdownSection formats a titled table
+func (f *OutputFormatter) markdownSection(title string, headers []string, rows [][]string) string {
+	return fmt.Sprintf("### %s\n\n%s\n", title, f.FormatMarkdownTable(headers, rows))
+}
+
+// markdownList formats names as a bullet list of code spans
+func markdownList(title string, names []string) string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("### %s\n\n", title))
+	for _, name := range names {
+		result.WriteString(fmt.Sprintf("- %s\n", markdownCode(name)))
+	}
+	result.WriteString("\n")
+	return result.String()
+}
+
+// sizeRows returns one row per name with its size and share of total, largest
+// first, followed by a totals row
+func sizeRows(sizes map[string]int64, total int64, code bool) [][]string {
+	names := sortedKeysByValue(sizes)
+	rows := make([][]string, 0, len(names)+1)
+	for _, name := range names {
+		label := name
+		if code {
+			label = markdownCode(name)
+		}
+		rows = append(rows, []string{label, units.FormatBytes(sizes[name]), formatPercentage(float64(sizes[name]), float64(total))})
+	}
+	return append(rows, []string{markdownTotal, units.FormatBytes(total), formatPercentage(float64(total), float64(total))})
+}
+
+// costRows returns one row per name with its cost and share of total, most
+// expensive first, followed by a totals row
+func costRows(costs map[string]float64, total float64, currency string) [][]string {
+	names := sortedKeysByValue(costs)
+	rows := make([][]string, 0, len(names)+1)
+	for _, name := range names {
+		rows = append(rows, []string{name, formatAmount(costs[name], currency), formatPercentage(costs[name], total)})
+	}
+	return append(rows, []string{markdownTotal, formatAmount(total, currency), formatPercentage(total, total)})
+}
+
+// sortedKeysByValue returns the keys of values, largest value first and by
+// name among equal values
+func sortedKeysByValue[V int | int64 | float64](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if values[keys[i]] != values[keys[j]] {
+			return values[keys[i]] > values[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// accountColumn leads the default markdown table of a multi-account run
+var accountColumn = uploadColumn{"account", "Account", func(upload types.MultipartUpload) string { return upload.Account }}
+
+// markdownColumn returns the column called name, including accountColumn
+func markdownColumn(name string) (uploadColumn, bool) {
+	if name == accountColumn.name {
+		return accountColumn, true
+	}
+	return findUploadColumn(name)
+}
+
+// FormatUploadsMarkdown formats uploads as a markdown table with the columns
+// in options, or the default list columns, and a totals row. The sizes in the
+// default columns are those of measured uploads.
+func (f *OutputFormatter) FormatUploadsMarkdown(uploads []types.MultipartUpload, options interfaces.UploadTableOptions, command string) string {
+	columns := options.Columns
+	if len(columns) == 0 {
+		columns = []string{"bucket", "key", "size", "age", "storage-class", "region"}
+		if hasAccounts(uploads) {
+			columns = append([]string{accountColumn.name}, columns...)
+		}
+		if options.ShowOwner {
+			columns = append(columns, "owner")
+		}
+	}
+
+	var result strings.Builder
+	result.WriteString(markdownHeader("Incomplete multipart uploads", command))
+	if len(uploads) == 0 {
+		result.WriteString("No incomplete multipart uploads found.\n")
+		return result.String()
+	}
+
+	headers := make([]string, len(columns))
+	for i, name := range columns {
+		headers[i] = name
+		if column, ok := markdownColumn(name); ok {
+			headers[i] = column.header
+		}
+	}
+
+	var totalSize int64
+	var totalCost float64
+	rows := make([][]string, 0, len(uploads)+1)
+	for _, upload := range uploads {
+		totalSize += upload.Size
+		totalCost += upload.EstimatedMonthlyCost
+		row := make([]string, len(columns))
+		for i, name := range columns {
+			column, ok := markdownColumn(name)
+			switch {
+			case !ok:
+			case markdownCodeColumns[name]:
+				row[i] = markdownCode(column.value(upload))
+			default:
+				row[i] = column.value(upload)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	// Sizes and costs add up and the label goes in the first column that does not
+	total := make([]string, len(columns))
+	labeled := false
+	for i, name := range columns {
+		switch {
+		case name == "size":
+			total[i] = units.FormatBytes(totalSize)
+		case name == "cost":
+			total[i] = fmt.Sprintf("%.4f", totalCost)
+		case !labeled:
+			total[i] = fmt.Sprintf("%s (%s)", markdownTotal, pluralUploads(len(uploads)))
+			labeled = true
+		}
+	}
+	rows = append(rows, total)
+
+	result.WriteString(f.FormatMarkdownTable(headers, rows))
+	return result.String()
+}
+
+// FormatSizeReportMarkdown formats a size report as markdown, with a table for
+// each breakdown in the report
+func (f *OutputFormatter) FormatSizeReportMarkdown(report types.SizeReport, command string) string {
+	var result strings.Builder
+	result.WriteString(markdownHeader("Storage used by incomplete multipart uploads", command))
+	result.WriteString(fmt.Sprintf("%s using **%s**.\n\n", pluralUploads(report.TotalCount), units.FormatBytes(report.TotalSize)))
+	if report.ServiceInitiatedCount > 0 {
+		result.WriteString(fmt.Sprintf("Service-initiated: %s (%s).\n\n", pluralUploads(report.ServiceInitiatedCount), units.FormatBytes(report.ServiceInitiatedSize)))
+	}
+
+	if len(report.ByAccount) > 0 {
+		result.WriteString(f.markdownSection("By account", []string{"Account", "Size", "Share"}, sizeRows(report.ByAccount, report.TotalSize, false)))
+	}
+	if len(report.ByBucket) > 0 {
+		result.WriteString(f.markdownSection("By bucket", []string{"Bucket", "Size", "Share"}, sizeRows(report.ByBucket, report.TotalSize, true)))
+	}
+	if len(report.ByStorageClass) > 0 {
+		result.WriteString(f.markdownSection("By storage class", []string{"Storage Class", "Size", "Share"}, sizeRows(report.ByStorageClass, report.TotalSize, false)))
+	}
+
+	if report.Distribution != nil {
+		var rows [][]string
+		for _, bucket := range report.Distribution.Buckets {
+			rows = append(rows, []string{
+				bucket.Label,
+				fmt.Sprintf("%d", bucket.Count),
+				formatPercentage(float64(bucket.Count), float64(report.TotalCount)),
+				units.FormatBytes(bucket.TotalSize),
+				formatPercentage(float64(bucket.TotalSize), float64(report.TotalSize)),
+			})
+		}
+		rows = append(rows, []string{markdownTotal, fmt.Sprintf("%d", report.TotalCount), "", units.FormatBytes(report.TotalSize), ""})
+		result.WriteString(f.markdownSection("By upload size", []string{"Size Range", "Uploads", "Share", "Size", "Size Share"}, rows))
+	}
+
+	if len(report.InaccessibleBuckets) > 0 {
+		result.WriteString(markdownList("Inaccessible buckets", report.InaccessibleBuckets))
+	}
+	for _, bucket := range report.TrippedBuckets {
+		result.WriteString(FormatTrippedBucket(bucket) + "\n")
+	}
+	return strings.TrimSuffix(result.String(), "\n")
+}
+
+// FormatCostBreakdownMarkdown formats a cost breakdown as markdown, with a
+// table for each breakdown in it
+func (f *OutputFormatter) FormatCostBreakdownMarkdown(breakdown types.CostBreakdown, command string) string {
+	var result strings.Builder
+	result.WriteString(markdownHeader("Estimated cost of incomplete multipart uploads", command))
+	result.WriteString(fmt.Sprintf("- Monthly: **%s**\n", formatCurrency(breakdown.TotalMonthlyCost, breakdown.Currency)))
+	if breakdown.AnnualizedCost > 0 {
+		result.WriteString(fmt.Sprintf("- Annualized: %s\n", formatCurrency(breakdown.AnnualizedCost, breakdown.Currency)))
+	}
+	if breakdown.AccruedCost > 0 {
+		result.WriteString(fmt.Sprintf("- Accrued to date: %s\n", formatCurrency(breakdown.AccruedCost, breakdown.Currency)))
+	}
+	result.WriteString("\n")
+
+	headers := func(first string) []string { return []string{first, "Monthly Cost", "Share"} }
+	if len(breakdown.ByAccount) > 0 {
+		result.WriteString(f.markdownSection("By account", headers("Account"), costRows(breakdown.ByAccount, breakdown.TotalMonthlyCost, breakdown.Currency)))
+	}
+	if len(breakdown.ByRegion) > 0 {
+		result.WriteString(f.markdownSection("By region", headers("Region"), costRows(breakdown.ByRegion, breakdown.TotalMonthlyCost, breakdown.Currency)))
+	}
+	if len(breakdown.ByStorageClass) > 0 {
+		result.WriteString(f.markdownSection("By storage class", headers("Storage Class"), costRows(breakdown.ByStorageClass, breakdown.TotalMonthlyCost, breakdown.Currency)))
+	}
+
+	if len(breakdown.PricingWarnings) > 0 {
+		result.WriteString(fmt.Sprintf("Estimated using default pricing: %s\n\n", strings.Join(breakdown.PricingWarnings, ", ")))
+	}
+	return strings.TrimSuffix(result.String(), "\n")
+}
+
+// FormatAgeDistributionMarkdown formats an age distribution as markdown, with
+// the age percentiles when known
+func (f *OutputFormatter) FormatAgeDistributionMarkdown(distribution types.AgeDistribution, command string) string {
+	var result strings.Builder
+	result.WriteString(markdownHeader("Age of incomplete multipart uploads", command))
+
+	var totalCount int
+	var totalSize int64
+	for _, bucket := range distribution.Buckets {
+		totalCount += bucket.Count
+		totalSize += bucket.TotalSize
+	}
+
+	var rows [][]string
+	for _, bucket := range distribution.Buckets {
+		rows = append(rows, []string{
+			bucket.Label,
+			fmt.Sprintf("%d", bucket.Count),
+			formatPercentage(float64(bucket.Count), float64(totalCount)),
+			units.FormatBytes(bucket.TotalSize),
+			formatPercentage(float64(bucket.TotalSize), float64(totalSize)),
+		})
+	}
+	rows = append(rows, []string{markdownTotal, fmt.Sprintf("%d", totalCount), "", units.FormatBytes(totalSize), ""})
+	result.WriteString(f.FormatMarkdownTable([]string{"Age Range", "Uploads", "Share", "Size", "Size Share"}, rows))
+
+	if percentiles := distribution.Percentiles; percentiles != nil {
+		var rows [][]string
+		for _, stat := range []struct {
+			name       string
+			percentile types.AgePercentile
+		}{{"Min", percentiles.Min}, {"Median", percentiles.Median}, {"P90", percentiles.P90}, {"Max", percentiles.Max}} {
+			rows = append(rows, []string{stat.name, units.FormatDuration(stat.percentile.Age), units.FormatBytes(stat.percentile.Size)})
+		}
+		result.WriteString("\n")
+		result.WriteString(f.FormatMarkdownTable([]string{"Statistic", "Age", "Size At Least This Old"}, rows))
+	}
+	return result.String()
+}
+
+// FormatDryRunMarkdown formats the summary of a delete dry run as markdown,
+// with what would be deleted per bucket, region and storage class
+func (f *OutputFormatter) FormatDryRunMarkdown(result types.DryRunResult, command string) string {
+	var output strings.Builder
+	output.WriteString(markdownHeader("Dry run: uploads that would be deleted", command))
+
+	output.WriteString(fmt.Sprintf("- Uploads: **%d**\n", result.TotalUploads))
+	if result.MatchingUploads > result.TotalUploads {
+		output.WriteString(fmt.Sprintf("- %s\n", maxDeletesSummary(result.MatchingUploads, result.TotalUploads)))
+	}
+	output.WriteString(fmt.Sprintf("- Storage freed: **%s**\n", units.FormatBytes(result.TotalSize)))
+	output.WriteString(fmt.Sprintf("- Estimated monthly savings: **%s**\n", formatCurrency(result.EstimatedSavings, result.Currency)))
+	output.WriteString(fmt.Sprintf("- Already wasted (accrued since initiated): %s\n", formatCurrency(result.AlreadyWasted, result.Currency)))
+	if result.ExcludedServiceInitiated > 0 {
+		output.WriteString(fmt.Sprintf("- Service-initiated uploads excluded: %d\n", result.ExcludedServiceInitiated))
+	}
+	if result.SupersededUploads > 0 {
+		output.WriteString(fmt.Sprintf("- Probably superseded: %s (%s)\n", pluralUploads(result.SupersededUploads), units.FormatBytes(result.SupersededSize)))
+	}
+	if result.AccessByBucket != nil {
+		denied := deniedBuckets(result)
+		output.WriteString(fmt.Sprintf("- Deletable buckets (verified): %d of %d\n", len(result.AccessByBucket)-len(denied), len(result.UploadsByBucket)))
+		for _, bucket := range denied {
+			output.WriteString(fmt.Sprintf("  - %s: `s3:AbortMultipartUpload` denied\n", markdownCode(bucket)))
+		}
+	}
+	if result.Filters != "" {
+		output.WriteString(fmt.Sprintf("- Filters: %s\n", markdownCode(result.Filters)))
+	}
+	output.WriteString("\n")
+
+	breakdown := func(title, first string, counts map[string]int, sizes map[string]int64, savings map[string]float64, code bool) {
+		var rows [][]string
+		for _, name := range sortedKeysByValue(sizes) {
+			label := name
+			if code {
+				label = markdownCode(name)
+			}
+			rows = append(rows, []string{label, fmt.Sprintf("%d", counts[name]), units.FormatBytes(sizes[name]), formatAmount(savings[name], result.Currency)})
+		}
+		rows = append(rows, []string{markdownTotal, fmt.Sprintf("%d", result.TotalUploads), units.FormatBytes(result.TotalSize), formatAmount(result.EstimatedSavings, result.Currency)})
+		output.WriteString(f.markdownSection(title, []string{first, "Uploads", "Size", "Savings/Month"}, rows))
+	}
+	if len(result.UploadsByBucket) > 0 {
+		breakdown("By bucket", "Bucket", result.UploadsByBucket, result.SizeByBucket, result.SavingsByBucket, true)
+	}
+	if len(result.UploadsByRegion) > 1 {
+		breakdown("By region", "Region", result.UploadsByRegion, result.SizeByRegion, result.SavingsByRegion, false)
+	}
+	if len(result.UploadsByStorageClass) > 1 {
+		breakdown("By storage class", "Storage Class", result.UploadsByStorageClass, result.SizeByStorageClass, result.SavingsByStorageClass, false)
+	}
+	return strings.TrimSuffix(output.String(), "\n")
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata/markdown")
+
+// assertGolden compares got with testdata/markdown/name, rewriting the file
+// instead with -update
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "markdown", name)
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run go test -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s does not match the golden file (run go test -update after checking the change)\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+func TestMarkdownReportsMatchGoldenFiles(t *testing.T) {
+	formatter := &OutputFormatter{}
+	initiated := time.Now().Add(-72*time.Hour - time.Minute)
+
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "app/__tmp__/2024_05_01.gz", UploadID: "u1", Initiated: initiated, Size: 3 << 30, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "media", Key: "video|clip`1`.mp4", UploadID: "u2", Initiated: initiated, Size: 512 << 20, StorageClass: "STANDARD_IA", Region: "eu-west-1", Owner: "alice"},
+		{Bucket: "media", Key: "*draft*.mov", UploadID: "u3", Initiated: initiated, Size: 900, StorageClass: "STANDARD", Region: "eu-west-1"},
+	}
+	assertGolden(t, "list.md", formatter.FormatUploadsMarkdown(uploads, interfaces.UploadTableOptions{}, "s3mpc list --output=markdown"))
+	assertGolden(t, "list_columns.md", formatter.FormatUploadsMarkdown(uploads, interfaces.UploadTableOptions{Columns: []string{"size", "key", "upload-id"}}, ""))
+
+	size := types.SizeReport{
+		TotalSize:           3<<30 + 512<<20 + 900,
+		TotalCount:          3,
+		ByBucket:            map[string]int64{"logs": 3 << 30, "media": 512<<20 + 900},
+		ByStorageClass:      map[string]int64{"STANDARD": 3<<30 + 900, "STANDARD_IA": 512 << 20},
+		InaccessibleBuckets: []string{"locked_bucket"},
+	}
+	assertGolden(t, "size.md", formatter.FormatSizeReportMarkdown(size, "s3mpc size --bucket=true --output=markdown"))
+
+	cost := types.CostBreakdown{
+		TotalMonthlyCost: 0.0809,
+		Currency:         "USD",
+		AnnualizedCost:   0.9708,
+		ByRegion:         map[string]float64{"us-east-1": 0.069, "eu-west-1": 0.0119},
+		ByStorageClass:   map[string]float64{"STANDARD": 0.0746, "STANDARD_IA": 0.0063},
+		PricingWarnings:  []string{"eu-west-1/STANDARD_IA"},
+	}
+	assertGolden(t, "cost.md", formatter.FormatCostBreakdownMarkdown(cost, "s3mpc cost --output=markdown --storage-class=true"))
+
+	age := types.AgeDistribution{
+		Buckets: []types.AgeBucket{
+			{Label: "< 1 day", Count: 0},
+			{Label: "1-7 days", Count: 3, TotalSize: 3<<30 + 512<<20 + 900},
+			{Label: "> 7 days", Count: 0},
+		},
+		Percentiles: &types.AgePercentiles{
+			Min:    types.AgePercentile{Age: 72 * time.Hour, Size: 3<<30 + 512<<20 + 900},
+			Median: types.AgePercentile{Age: 72 * time.Hour, Size: 3<<30 + 512<<20 + 900},
+			P90:    types.AgePercentile{Age: 72 * time.Hour, Size: 3<<30 + 512<<20 + 900},
+			Max:    types.AgePercentile{Age: 72 * time.Hour, Size: 3<<30 + 512<<20 + 900},
+		},
+	}
+	assertGolden(t, "age.md", formatter.FormatAgeDistributionMarkdown(age, "s3mpc age --output=markdown"))
+
+	dryRun := types.DryRunResult{
+		TotalUploads:          3,
+		TotalSize:             3<<30 + 512<<20 + 900,
+		EstimatedSavings:      0.0809,
+		AlreadyWasted:         0.0081,
+		Currency:              "USD",
+		UploadsByBucket:       map[string]int{"logs": 1, "media": 2},
+		SizeByBucket:          map[string]int64{"logs": 3 << 30, "media": 512<<20 + 900},
+		SavingsByBucket:       map[string]float64{"logs": 0.069, "media": 0.0119},
+		UploadsByRegion:       map[string]int{"us-east-1": 1, "eu-west-1": 2},
+		SizeByRegion:          map[string]int64{"us-east-1": 3 << 30, "eu-west-1": 512<<20 + 900},
+		SavingsByRegion:       map[string]float64{"us-east-1": 0.069, "eu-west-1": 0.0119},
+		UploadsByStorageClass: map[string]int{"STANDARD": 2},
+		SizeByStorageClass:    map[string]int64{"STANDARD": 3<<30 + 900},
+		SavingsByStorageClass: map[string]float64{"STANDARD": 0.0746},
+		Filters:               "age>2d",
+	}
+	assertGolden(t, "dryrun.md", formatter.FormatDryRunMarkdown(dryRun, "s3mpc delete --dry-run=true --older-than=2d --output=markdown"))
+}
+
+func TestFormatMarkdownTable(t *testing.T) {
+	formatter := &OutputFormatter{}
+	got := formatter.FormatMarkdownTable(
+		[]string{"Name", "Count", "Size", "Share", "Note"},
+		[][]string{
+			{"a|b", "1,200", "1.5 GB", "45.0%", "line\nbreak"},
+			{"c", "3", "512 B", "–", "2024-05-01"},
+			{"short"},
+		},
+	)
+	want := strings.Join([]string{
+		`| Name | Count | Size | Share | Note |`,
+		`| --- | ---: | ---: | ---: | --- |`,
+		`| a\|b | 1,200 | 1.5 GB | 45.0% | line break |`,
+		`| c | 3 | 512 B | – | 2024-05-01 |`,
+		`| short |  |  |  |  |`,
+	}, "\n") + "\n"
+	if got != want {
+		t.Errorf("FormatMarkdownTable() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUploadsMarkdownShowsAccounts(t *testing.T) {
+	formatter := &OutputFormatter{}
+	uploads := []types.MultipartUpload{
+		{Account: "prod", Bucket: "logs", Key: "a.gz", UploadID: "u1", Initiated: time.Now().Add(-time.Hour), Size: 1 << 20, StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+	got := formatter.FormatUploadsMarkdown(uploads, interfaces.UploadTableOptions{}, "")
+	for _, want := range []string{"| Account | Bucket | Key | Size |", "| prod | `logs` | `a.gz` | 1.0 MB |", "| **Total** (1 upload) |  |  | 1.0 MB |"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatUploadsMarkdown() = %q, want it to contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// MetricsFormatter implements the interfaces.MetricsFormatter interface
+type MetricsFormatter struct{}
+
+// NewMetricsFormatter creates a new MetricsFormatter instance
+func NewMetricsFormatter() interfaces.MetricsFormatter {
+	return &MetricsFormatter{}
+}
+
+// FormatOpenMetrics renders a metrics report in the OpenMetrics text format,
+// suitable for node_exporter's textfile collector
+func (f *MetricsFormatter) FormatOpenMetrics(report types.MetricsReport) string {
+	var result strings.Builder
+
+	writeFamily := func(name, help, unit string) {
+		fmt.Fprintf(&result, "# TYPE %s gauge\n", name)
+		if unit != "" {
+			fmt.Fprintf(&result, "# UNIT %s %s\n", name, unit)
+		}
+		fmt.Fprintf(&result, "# HELP %s %s\n", name, help)
+	}
+	seriesLabels := func(series types.UploadSeries) string {
+		return formatMetricLabels("bucket", series.Bucket, "region", series.Region, "storage_class", series.StorageClass)
+	}
+
+	writeFamily("s3mpc_incomplete_uploads_total", "Number of incomplete multipart uploads.", "")
+	for _, series := range report.Series {
+		fmt.Fprintf(&result, "s3mpc_incomplete_uploads_total%s %d\n", seriesLabels(series), series.Count)
+	}
+
+	writeFamily("s3mpc_incomplete_bytes", "Bytes stored in parts of incomplete multipart uploads.", "bytes")
+	for _, series := range report.Series {
+		fmt.Fprintf(&result, "s3mpc_incomplete_bytes%s %d\n", seriesLabels(series), series.Size)
+	}
+
+	// Costs in another currency would be mislabeled, so they are left out
+	if report.Currency == "" || report.Currency == "USD" {
+		writeFamily("s3mpc_incomplete_monthly_cost_usd", "Estimated monthly storage cost of incomplete multipart uploads in USD.", "")
+		for _, series := range report.Series {
+			fmt.Fprintf(&result, "s3mpc_incomplete_monthly_cost_usd%s %s\n", seriesLabels(series), formatMetricValue(series.MonthlyCost))
+		}
+	}
+
+	writeFamily("s3mpc_oldest_upload_age_seconds", "Age of the oldest incomplete multipart upload in the bucket.", "seconds")
+	for _, oldest := range report.OldestUploads {
+		age := report.GeneratedAt.Sub(oldest.Initiated).Seconds()
+		if age < 0 {
+			age = 0
+		}
+		fmt.Fprintf(&result, "s3mpc_oldest_upload_age_seconds%s %s\n", formatMetricLabels("bucket", oldest.Bucket), formatMetricValue(age))
+	}
+
+	result.WriteString("# EOF\n")
+	return result.String()
+}
+
+// formatMetricLabels renders name/value pairs as an OpenMetrics label set
+func formatMetricLabels(pairs ...string) string {
+	labels := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, pairs[i], escapeLabelValue(pairs[i+1])))
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+// labelValueEscaper escapes the characters the OpenMetrics spec requires in label values
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabelValue escapes backslashes, double quotes and line feeds
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+// formatMetricValue formats a float in the shortest form that round-trips
+func formatMetricValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
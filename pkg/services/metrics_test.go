@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestFormatOpenMetrics(t *testing.T) {
+	now := time.Date(2024, 6, 12, 12, 0, 0, 0, time.UTC)
+	report := types.MetricsReport{
+		GeneratedAt: now,
+		Currency:    "USD",
+		Series: []types.UploadSeries{
+			{Bucket: "logs", Region: "us-east-1", StorageClass: "STANDARD", Count: 2, Size: 3 << 20, MonthlyCost: 0.25},
+			{Bucket: `odd"bucket\name`, Region: "eu-west-1", StorageClass: "line\nbreak", Count: 1, Size: 1024, MonthlyCost: 0},
+		},
+		OldestUploads: []types.BucketOldest{
+			{Bucket: "logs", Initiated: now.Add(-90 * time.Minute)},
+			{Bucket: "clock-skew", Initiated: now.Add(time.Minute)},
+		},
+	}
+
+	got := NewMetricsFormatter().FormatOpenMetrics(report)
+	want := `# TYPE s3mpc_incomplete_uploads_total gauge
+# HELP s3mpc_incomplete_uploads_total Number of incomplete multipart uploads.
+s3mpc_incomplete_uploads_total{bucket="logs",region="us-east-1",storage_class="STANDARD"} 2
+s3mpc_incomplete_uploads_total{bucket="odd\"bucket\\name",region="eu-west-1",storage_class="line\nbreak"} 1
+# TYPE s3mpc_incomplete_bytes gauge
+# UNIT s3mpc_incomplete_bytes bytes
+# HELP s3mpc_incomplete_bytes Bytes stored in parts of incomplete multipart uploads.
+s3mpc_incomplete_bytes{bucket="logs",region="us-east-1",storage_class="STANDARD"} 3145728
+s3mpc_incomplete_bytes{bucket="odd\"bucket\\name",region="eu-west-1",storage_class="line\nbreak"} 1024
+# TYPE s3mpc_incomplete_monthly_cost_usd gauge
+# HELP s3mpc_incomplete_monthly_cost_usd Estimated monthly storage cost of incomplete multipart uploads in USD.
+s3mpc_incomplete_monthly_cost_usd{bucket="logs",region="us-east-1",storage_class="STANDARD"} 0.25
+s3mpc_incomplete_monthly_cost_usd{bucket="odd\"bucket\\name",region="eu-west-1",storage_class="line\nbreak"} 0
+# TYPE s3mpc_oldest_upload_age_seconds gauge
+# UNIT s3mpc_oldest_upload_age_seconds seconds
+# HELP s3mpc_oldest_upload_age_seconds Age of the oldest incomplete multipart upload in the bucket.
+s3mpc_oldest_upload_age_seconds{bucket="logs"} 5400
+s3mpc_oldest_upload_age_seconds{bucket="clock-skew"} 0
+# EOF
+`
+	if got != want {
+		t.Errorf("FormatOpenMetrics() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Costs in another currency must not be reported under the _usd name
+	report.Currency = "EUR"
+	if got := NewMetricsFormatter().FormatOpenMetrics(report); strings.Contains(got, "monthly_cost_usd") {
+		t.Errorf("FormatOpenMetrics() reported EUR costs as USD:\n%s", got)
+	}
+}
+
+func TestMetricsForUploadsGroupsSeries(t *testing.T) {
+	now := time.Now()
+	uploads := []types.MultipartUpload{
+		{Bucket: "media", Key: "a", Initiated: now.Add(-time.Hour), Size: 100, StorageClass: "STANDARD", Region: "eu-west-1"},
+		{Bucket: "logs", Key: "b", Initiated: now.Add(-48 * time.Hour), Size: 200, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "c", Initiated: now.Add(-time.Hour), Size: 300, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "d", Initiated: now.Add(-time.Hour), Size: 400, StorageClass: "GLACIER", Region: "us-east-1"},
+	}
+	service := NewReportService(&countingUploadService{}, NewSizeService(&countingUploadService{}), NewCostService(), NewAgeService())
+
+	report, err := service.MetricsForUploads(context.Background(), uploads, nil)
+	if err != nil {
+		t.Fatalf("MetricsForUploads() error = %v", err)
+	}
+
+	var series []string
+	for _, s := range report.Series {
+		series = append(series, fmt.Sprintf("%s/%s/%s=%d:%d", s.Bucket, s.Region, s.StorageClass, s.Count, s.Size))
+	}
+	if got, want := strings.Join(series, " "), "logs/us-east-1/GLACIER=1:400 logs/us-east-1/STANDARD=2:500 media/eu-west-1/STANDARD=1:100"; got != want {
+		t.Errorf("series = %s, want %s", got, want)
+	}
+
+	if len(report.OldestUploads) != 2 || report.OldestUploads[0].Bucket != "logs" || !report.OldestUploads[0].Initiated.Equal(uploads[1].Initiated) {
+		t.Errorf("OldestUploads = %+v, want logs initiated at %v first", report.OldestUploads, uploads[1].Initiated)
+	}
+	if report.InaccessibleBuckets == nil {
+		t.Error("InaccessibleBuckets is nil, want an empty list")
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// objectKey identifies a completed object; retried uploads often share one
+type objectKey struct {
+	bucket string
+	key    string
+}
+
+// objectState is what HeadObject reported for an object key
+type objectState struct {
+	lastModified time.Time
+	exists       bool
+	err          error
+}
+
+// ObjectCheckService implements the interfaces.ObjectCheckService interface.
+// Each object key is read with HeadObject at most once per run, through the
+// upload service's regional clients, so the calls share their rate limits.
+type ObjectCheckService struct {
+	uploadService interfaces.UploadService
+	concurrency   int
+	objects       map[objectKey]objectState
+	mutex         sync.Mutex
+	headCalls     int64
+	cacheHits     int64
+}
+
+// NewObjectCheckService creates an ObjectCheckService reading at most
+// concurrency objects at a time
+func NewObjectCheckService(uploadService interfaces.UploadService, concurrency int) *ObjectCheckService {
+	if concurrency <= 0 {
+		concurrency = 10
+	}
+	return &ObjectCheckService{
+		uploadService: uploadService,
+		concurrency:   concurrency,
+		objects:       make(map[objectKey]objectState),
+	}
+}
+
+// MarkSuperseded sets Superseded on each upload whose key holds a completed
+// object last modified after the upload was initiated: the application retried
+// and succeeded, so the upload is almost certainly safe to abort. The buckets
+// whose objects could not be read are returned, and their uploads left unmarked.
+func (s *ObjectCheckService) MarkSuperseded(ctx context.Context, uploads []types.MultipartUpload) ([]types.MultipartUpload, []string, error) {
+	// Read each key once, however many uploads target it
+	s.mutex.Lock()
+	pending := make(map[objectKey]types.MultipartUpload)
+	for _, upload := range uploads {
+		key := objectKey{bucket: upload.Bucket, key: upload.Key}
+		if _, checked := s.objects[key]; checked {
+			s.cacheHits++
+			continue
+		}
+		if _, queued := pending[key]; !queued {
+			pending[key] = upload
+		}
+	}
+	s.mutex.Unlock()
+
+	semaphore := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+	for key, upload := range pending {
+		wg.Add(1)
+		go func(key objectKey, upload types.MultipartUpload) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			lastModified, exists, err := s.uploadService.GetObjectLastModified(ctx, upload)
+			atomic.AddInt64(&s.headCalls, 1)
+			if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+				return // an interrupted read says nothing about the object
+			}
+
+			s.mutex.Lock()
+			s.objects[key] = objectState{lastModified: lastModified, exists: exists, err: err}
+			s.mutex.Unlock()
+		}(key, upload)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	marked := make([]types.MultipartUpload, len(uploads))
+	var uncheckedBuckets []string
+	unchecked := make(map[string]bool)
+	for i, upload := range uploads {
+		state := s.objects[objectKey{bucket: upload.Bucket, key: upload.Key}]
+		if state.err != nil && !unchecked[upload.Bucket] {
+			unchecked[upload.Bucket] = true
+			uncheckedBuckets = append(uncheckedBuckets, upload.Bucket)
+		}
+		upload.Superseded = state.exists && state.lastModified.After(upload.Initiated)
+		marked[i] = upload
+	}
+	return marked, uncheckedBuckets, nil
+}
+
+// GetCacheStats returns the number of HeadObject calls made and avoided
+func (s *ObjectCheckService) GetCacheStats() map[string]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return map[string]interface{}{
+		"head_object_calls": atomic.LoadInt64(&s.headCalls),
+		"cached_objects":    len(s.objects),
+		"cache_hits":        s.cacheHits,
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// objectsClient answers HeadObject from a fixed set of objects, denying
+// access to the buckets in denied
+type objectsClient struct {
+	mockPartsClient
+	objects map[string]time.Time // bucket/key -> last modified
+	denied  map[string]bool
+	calls   int64
+}
+
+func (c *objectsClient) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	atomic.AddInt64(&c.calls, 1)
+	bucket := aws.ToString(input.Bucket)
+	if c.denied[bucket] {
+		return nil, &smithy.GenericAPIError{Code: "Forbidden", Message: "Forbidden"}
+	}
+	lastModified, exists := c.objects[bucket+"/"+aws.ToString(input.Key)]
+	if !exists {
+		return c.mockPartsClient.HeadObject(ctx, input)
+	}
+	return &s3.HeadObjectOutput{LastModified: aws.Time(lastModified)}, nil
+}
+
+func TestMarkSuperseded(t *testing.T) {
+	initiated := time.Now().Add(-48 * time.Hour)
+	client := &objectsClient{
+		objects: map[string]time.Time{
+			"data/retried.bin": initiated.Add(time.Hour),
+			"data/older.bin":   initiated.Add(-time.Hour),
+		},
+		denied: map[string]bool{"locked": true},
+	}
+	uploadService := &UploadService{
+		client:          failingClient{},
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": client},
+	}
+	upload := func(bucket, key, id string) types.MultipartUpload {
+		return types.MultipartUpload{Bucket: bucket, Key: key, UploadID: id, Initiated: initiated, StorageClass: "STANDARD", Region: "us-east-1"}
+	}
+	uploads := []types.MultipartUpload{
+		upload("data", "retried.bin", "u1"),
+		upload("data", "retried.bin", "u2"), // same key, read once
+		upload("data", "older.bin", "u3"),   // the object predates the upload
+		upload("data", "missing.bin", "u4"),
+		upload("locked", "secret.bin", "u5"),
+	}
+
+	service := NewObjectCheckService(uploadService, 2)
+	ctx := context.Background()
+	marked, unchecked, err := service.MarkSuperseded(ctx, uploads)
+	if err != nil {
+		t.Fatalf("MarkSuperseded() error = %v", err)
+	}
+
+	want := []bool{true, true, false, false, false}
+	for i, upload := range marked {
+		if upload.UploadID != uploads[i].UploadID || upload.Superseded != want[i] {
+			t.Errorf("upload %s superseded = %v, want %v", upload.UploadID, upload.Superseded, want[i])
+		}
+	}
+	if len(unchecked) != 1 || unchecked[0] != "locked" {
+		t.Errorf("unchecked buckets = %v, want [locked]", unchecked)
+	}
+	if calls := atomic.LoadInt64(&client.calls); calls != 4 {
+		t.Errorf("HeadObject called %d times, want once per key", calls)
+	}
+
+	// Later checks in the same run are answered from the cache
+	if _, _, err := service.MarkSuperseded(ctx, uploads[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if calls := atomic.LoadInt64(&client.calls); calls != 4 {
+		t.Errorf("HeadObject called %d times after a repeated check, want no new calls", calls)
+	}
+	if stats := service.GetCacheStats(); stats["head_object_calls"] != int64(4) || stats["cache_hits"] != int64(2) {
+		t.Errorf("GetCacheStats() = %v", stats)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, _, err := NewObjectCheckService(uploadService, 2).MarkSuperseded(canceled, uploads); err == nil {
+		t.Error("MarkSuperseded() with a canceled context succeeded, want an error")
+	}
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	pricingtypes "github.com/aws/aws-sdk-go-v2/service/pricing/types"
+)
+
+// PricingSource provides storage prices in USD per GB per month
+type PricingSource interface {
+	// GetPrice returns the price for a region and (normalized) storage class
+	GetPrice(ctx context.Context, region, storageClass string) (float64, error)
+}
+
+// PricingAPIClient defines the Pricing API operations needed by PricingAPISource
+type PricingAPIClient interface {
+	GetProducts(ctx context.Context, params *pricing.GetProductsInput, optFns ...func(*pricing.Options)) (*pricing.GetProductsOutput, error)
+}
+
+// DefaultPricingCacheTTL is how long live prices are reused before querying the API again
+const DefaultPricingCacheTTL = 24 * time.Hour
+
+// s3VolumeTypes maps storage classes to the Pricing API volumeType attribute
+var s3VolumeTypes = map[string]string{
+	"STANDARD":            "Standard",
+	"STANDARD_IA":         "Standard - Infrequent Access",
+	"ONEZONE_IA":          "One Zone - Infrequent Access",
+	"REDUCED_REDUNDANCY":  "Reduced Redundancy",
+	"GLACIER":             "Amazon Glacier",
+	"GLACIER_IR":          "Glacier Instant Retrieval",
+	"DEEP_ARCHIVE":        "Glacier Deep Archive",
+	"INTELLIGENT_TIERING": "Intelligent-Tiering Frequent Access",
+}
+
+// pricingCacheEntry is a cached price with the time it was fetched
+type pricingCacheEntry struct {
+	Price     float64   `json:"price"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// pricingCacheFile is the on-disk layout of the pricing cache
+type pricingCacheFile struct {
+	Prices map[string]map[string]pricingCacheEntry `json:"prices"` // region -> storage class -> entry
+}
+
+// PricingAPISource implements PricingSource using the AWS Pricing API with a file cache
+type PricingAPISource struct {
+	client    PricingAPIClient
+	cachePath string
+	ttl       time.Duration
+	cache     pricingCacheFile
+	failures  map[string]error // region/storage class -> the error fetching it, kept for the run
+	mutex     sync.Mutex
+	loaded    bool
+}
+
+// NewPricingAPISource creates a new PricingAPISource. An empty cachePath uses
+// ~/.s3mpc/pricing.json and a zero ttl uses DefaultPricingCacheTTL.
+func NewPricingAPISource(client PricingAPIClient, cachePath string, ttl time.Duration) *PricingAPISource {
+	if cachePath == "" {
+		cachePath = DefaultPricingCachePath()
+	}
+	if ttl <= 0 {
+		ttl = DefaultPricingCacheTTL
+	}
+	return &PricingAPISource{
+		client:    client,
+		cachePath: cachePath,
+		ttl:       ttl,
+		cache:     pricingCacheFile{Prices: make(map[string]map[string]pricingCacheEntry)},
+		failures:  make(map[string]error),
+	}
+}
+
+// DefaultPricingCachePath returns the default location of the pricing cache
+func DefaultPricingCachePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".s3mpc", "pricing.json")
+	}
+	return filepath.Join(home, ".s3mpc", "pricing.json")
+}
+
+// GetPrice returns the live price for a region and storage class, using the
+// cache when fresh. A price that could not be fetched is not asked for again
+// in the same run; the error is returned instead.
+func (p *PricingAPISource) GetPrice(ctx context.Context, region, storageClass string) (float64, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.loadCache()
+
+	if entry, exists := p.cache.Prices[region][storageClass]; exists && time.Since(entry.FetchedAt) < p.ttl {
+		return entry.Price, nil
+	}
+	failureKey := region + "/" + storageClass
+	if err, failed := p.failures[failureKey]; failed {
+		return 0, err
+	}
+
+	price, err := p.fetchPrice(ctx, region, storageClass)
+	if err != nil {
+		// A cancelled run says nothing about the price
+		if ctx.Err() == nil {
+			p.failures[failureKey] = err
+		}
+		return 0, err
+	}
+
+	if p.cache.Prices[region] == nil {
+		p.cache.Prices[region] = make(map[string]pricingCacheEntry)
+	}
+	p.cache.Prices[region][storageClass] = pricingCacheEntry{Price: price, FetchedAt: time.Now()}
+
+	// A cache write failure only costs us an extra API call next time
+	_ = p.saveCache()
+
+	return price, nil
+}
+
+// fetchPrice queries GetProducts for the first-tier storage price
+func (p *PricingAPISource) fetchPrice(ctx context.Context, region, storageClass string) (float64, error) {
+	volumeType, exists := s3VolumeTypes[storageClass]
+	if !exists {
+		return 0, fmt.Errorf("no pricing API volume type for storage class: %s", storageClass)
+	}
+
+	input := &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonS3"),
+		Filters: []pricingtypes.Filter{
+			{Field: aws.String("regionCode"), Type: pricingtypes.FilterTypeTermMatch, Value: aws.String(region)},
+			{Field: aws.String("productFamily"), Type: pricingtypes.FilterTypeTermMatch, Value: aws.String("Storage")},
+			{Field: aws.String("volumeType"), Type: pricingtypes.FilterTypeTermMatch, Value: aws.String(volumeType)},
+		},
+		FormatVersion: aws.String("aws_v1"),
+	}
+
+	output, err := p.client.GetProducts(ctx, input)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query pricing API for %s/%s: %w", region, storageClass, err)
+	}
+
+	for _, item := range output.PriceList {
+		if price, ok := parseFirstTierPrice(item); ok {
+			return price, nil
+		}
+	}
+
+	return 0, fmt.Errorf("pricing API returned no storage price for %s/%s", region, storageClass)
+}
+
+// priceListItem is the subset of a Pricing API price list document we read
+type priceListItem struct {
+	Terms struct {
+		OnDemand map[string]struct {
+			PriceDimensions map[string]struct {
+				Unit         string            `json:"unit"`
+				BeginRange   string            `json:"beginRange"`
+				PricePerUnit map[string]string `json:"pricePerUnit"`
+			} `json:"priceDimensions"`
+		} `json:"OnDemand"`
+	} `json:"terms"`
+}
+
+// parseFirstTierPrice extracts the USD GB-month price of the first volume tier
+func parseFirstTierPrice(document string) (float64, bool) {
+	var item priceListItem
+	if err := json.Unmarshal([]byte(document), &item); err != nil {
+		return 0, false
+	}
+
+	for _, term := range item.Terms.OnDemand {
+		for _, dimension := range term.PriceDimensions {
+			if dimension.Unit != "GB-Mo" || (dimension.BeginRange != "" && dimension.BeginRange != "0") {
+				continue
+			}
+			price, err := strconv.ParseFloat(dimension.PricePerUnit["USD"], 64)
+			if err != nil || price < 0 {
+				continue
+			}
+			return price, true
+		}
+	}
+
+	return 0, false
+}
+
+// loadCache reads the cache file once; a missing or corrupt file starts an empty cache
+func (p *PricingAPISource) loadCache() {
+	if p.loaded {
+		return
+	}
+	p.loaded = true
+
+	data, err := os.ReadFile(p.cachePath)
+	if err != nil {
+		return
+	}
+
+	var cache pricingCacheFile
+	if err := json.Unmarshal(data, &cache); err != nil || cache.Prices == nil {
+		return
+	}
+	p.cache = cache
+}
+
+// saveCache writes the cache file atomically
+func (p *PricingAPISource) saveCache() error {
+	if err := os.MkdirAll(filepath.Dir(p.cachePath), 0755); err != nil {
+		return fmt.Errorf("failed to create pricing cache directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(p.cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pricing cache: %w", err)
+	}
+
+	tmpPath := p.cachePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write pricing cache: %w", err)
+	}
+
+	return os.Rename(tmpPath, p.cachePath)
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExecCostProviderScheme prefixes --cost-provider values that name a program to run
+const ExecCostProviderScheme = "exec:"
+
+// DefaultCostProviderTimeout is how long a cost provider program may run for one price
+const DefaultCostProviderTimeout = 10 * time.Second
+
+// CostProviderSchemaVersion is the version of the cost provider request and response
+const CostProviderSchemaVersion = 1
+
+// maxCostProviderOutput is the most output read from a cost provider; a price is a few bytes
+const maxCostProviderOutput = 64 * 1024
+
+// CostProviderRequest is written as JSON to the standard input of a cost
+// provider program. GB is the amount of storage to price; s3mpc asks for
+// 1 GB-month and scales the price by the size of each upload.
+type CostProviderRequest struct {
+	SchemaVersion int     `json:"schema_version"`
+	Region        string  `json:"region"`
+	StorageClass  string  `json:"storage_class"`
+	GB            float64 `json:"gb"`
+}
+
+// CostProviderResponse is read as JSON from the standard output of a cost
+// provider program. Currency, if given, must match the currency of the run.
+type CostProviderResponse struct {
+	SchemaVersion   int      `json:"schema_version"`
+	PricePerGBMonth *float64 `json:"price_per_gb_month"`
+	Currency        string   `json:"currency,omitempty"`
+}
+
+// execPrice is the outcome of one cost provider call, remembered for the rest of the run
+type execPrice struct {
+	price float64
+	err   error
+}
+
+// ExecPricingSource implements PricingSource by running an external program
+// once per region and storage class. Failures are remembered too, so a broken
+// provider is reported once per region and storage class, not once per upload.
+type ExecPricingSource struct {
+	path     string
+	currency string
+	timeout  time.Duration
+	warnings io.Writer
+	prices   map[string]execPrice // region/storage class -> outcome
+	mutex    sync.Mutex
+}
+
+// ParseCostProvider returns the pricing source named by a --cost-provider
+// value such as exec:/usr/local/bin/prices, with prices in currency
+func ParseCostProvider(value, currency string) (*ExecPricingSource, error) {
+	if !strings.HasPrefix(value, ExecCostProviderScheme) {
+		return nil, fmt.Errorf("unsupported cost provider %q, expected exec:/path/to/program", value)
+	}
+	path := strings.TrimPrefix(value, ExecCostProviderScheme)
+	if path == "" {
+		return nil, fmt.Errorf("cost provider %q names no program", value)
+	}
+	if _, err := exec.LookPath(path); err != nil {
+		return nil, fmt.Errorf("cost provider %s: %w", path, err)
+	}
+	return NewExecPricingSource(path, currency, DefaultCostProviderTimeout), nil
+}
+
+// NewExecPricingSource creates a source that runs path for each price, giving
+// it timeout to answer. An empty currency means USD.
+func NewExecPricingSource(path, currency string, timeout time.Duration) *ExecPricingSource {
+	if currency == "" {
+		currency = "USD"
+	}
+	return &ExecPricingSource{
+		path:     path,
+		currency: currency,
+		timeout:  timeout,
+		prices:   make(map[string]execPrice),
+	}
+}
+
+// SetWarningWriter sets where fallbacks to other prices are reported; nil discards them
+func (e *ExecPricingSource) SetWarningWriter(w io.Writer) {
+	e.warnings = w
+}
+
+// GetPrice returns the provider's price for a region and storage class
+func (e *ExecPricingSource) GetPrice(ctx context.Context, region, storageClass string) (float64, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	key := region + "/" + storageClass
+	if result, exists := e.prices[key]; exists {
+		return result.price, result.err
+	}
+
+	price, err := e.run(ctx, region, storageClass)
+	if err != nil {
+		err = fmt.Errorf("cost provider %s failed for %s: %w", e.path, key, err)
+		if e.warnings != nil {
+			fmt.Fprintf(e.warnings, "Warning: %v; using built-in prices for %s\n", err, key)
+		}
+	}
+	e.prices[key] = execPrice{price: price, err: err}
+	return price, err
+}
+
+// run runs the provider program once and validates its response
+func (e *ExecPricingSource) run(ctx context.Context, region, storageClass string) (float64, error) {
+	request, err := json.Marshal(CostProviderRequest{
+		SchemaVersion: CostProviderSchemaVersion,
+		Region:        region,
+		StorageClass:  storageClass,
+		GB:            1,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	var stdout, stderr limitedBuffer
+	cmd := exec.CommandContext(ctx, e.path)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second // do not wait on children that keep the output open
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return 0, fmt.Errorf("no response within %s", e.timeout)
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return 0, fmt.Errorf("%w: %s", err, message)
+		}
+		return 0, err
+	}
+	if stdout.truncated {
+		return 0, fmt.Errorf("response is larger than %d bytes", maxCostProviderOutput)
+	}
+
+	return e.parseResponse(stdout.Bytes())
+}
+
+// parseResponse validates a provider response and returns its price
+func (e *ExecPricingSource) parseResponse(data []byte) (float64, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var response CostProviderResponse
+	if err := decoder.Decode(&response); err != nil {
+		return 0, fmt.Errorf("invalid response %q: %w", truncateString(string(data), 80), err)
+	}
+	if response.SchemaVersion > CostProviderSchemaVersion {
+		return 0, fmt.Errorf("response has schema_version %d, this version of s3mpc reads up to %d", response.SchemaVersion, CostProviderSchemaVersion)
+	}
+	if response.PricePerGBMonth == nil {
+		return 0, fmt.Errorf("response has no price_per_gb_month")
+	}
+
+	price := *response.PricePerGBMonth
+	if price < 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+		return 0, fmt.Errorf("price_per_gb_month must be a non-negative number, got %v", price)
+	}
+	if response.Currency != "" && !strings.EqualFold(response.Currency, e.currency) {
+		return 0, fmt.Errorf("price is in %s, expected %s", strings.ToUpper(response.Currency), e.currency)
+	}
+	return price, nil
+}
+
+// limitedBuffer keeps the first maxCostProviderOutput bytes written to it
+type limitedBuffer struct {
+	bytes.Buffer
+	truncated bool
+}
+
+// Write stores what fits and discards the rest, so a chatty program cannot exhaust memory
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxCostProviderOutput - b.Len(); len(p) > room {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// fakeCostProvider returns a source running testdata/cost_provider.sh and the file it logs requests to
+func fakeCostProvider(t *testing.T) (*ExecPricingSource, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake cost provider is a shell script")
+	}
+	script, err := filepath.Abs(filepath.Join("testdata", "cost_provider.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := filepath.Join(t.TempDir(), "requests.log")
+	t.Setenv("COST_PROVIDER_LOG", log)
+	return NewExecPricingSource(script, "USD", 500*time.Millisecond), log
+}
+
+func TestExecPricingSourceValidatesResponses(t *testing.T) {
+	source, log := fakeCostProvider(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		storageClass string
+		want         float64
+		wantErr      string
+	}{
+		{"STANDARD", 0.0195, ""},
+		{"GLACIER", 0, "no response within 500ms"},
+		{"DEEP_ARCHIVE", 0, "exit status 3: rate service unavailable"},
+		{"ONEZONE_IA", 0, `invalid response "price: cheap`},
+		{"STANDARD_IA", 0, "price_per_gb_month must be a non-negative number, got -1"},
+		{"GLACIER_IR", 0, "price is in EUR, expected USD"},
+	}
+	for _, tt := range tests {
+		price, err := source.GetPrice(ctx, "eu-west-1", tt.storageClass)
+		if tt.wantErr == "" {
+			if err != nil || price != tt.want {
+				t.Errorf("GetPrice(%s) = %v, %v, want %v", tt.storageClass, price, err, tt.want)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), "eu-west-1/"+tt.storageClass) {
+			t.Errorf("GetPrice(%s) error = %v, want %q", tt.storageClass, err, tt.wantErr)
+		}
+	}
+
+	// Each region and storage class is asked for once per run, even when it failed
+	for _, storageClass := range []string{"STANDARD", "DEEP_ARCHIVE"} {
+		if _, err := source.GetPrice(ctx, "eu-west-1", storageClass); (err == nil) != (storageClass == "STANDARD") {
+			t.Errorf("cached GetPrice(%s) error = %v", storageClass, err)
+		}
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(requests) != len(tests) {
+		t.Errorf("provider ran %d times, want %d", len(requests), len(tests))
+	}
+	if requests[0] != `{"schema_version":1,"region":"eu-west-1","storage_class":"STANDARD","gb":1}` {
+		t.Errorf("request = %s", requests[0])
+	}
+}
+
+func TestCostServiceFallsBackWhenCostProviderFails(t *testing.T) {
+	source, _ := fakeCostProvider(t)
+	var warnings strings.Builder
+	source.SetWarningWriter(&warnings)
+
+	const gb = 1024 * 1024 * 1024
+	uploads := []types.MultipartUpload{
+		{Size: 10 * gb, Region: "us-east-1", StorageClass: "STANDARD", Initiated: time.Now()},
+		{Size: 10 * gb, Region: "us-east-1", StorageClass: "DEEP_ARCHIVE", Initiated: time.Now()},
+		{Size: 10 * gb, Region: "us-east-1", StorageClass: "DEEP_ARCHIVE", Initiated: time.Now()},
+	}
+	breakdown, err := NewCostServiceWithSource(source).CalculateStorageCost(context.Background(), uploads)
+	if err != nil {
+		t.Fatalf("CalculateStorageCost() error = %v", err)
+	}
+
+	builtIn, _ := NewCostService().GetRegionalPricing(context.Background(), "us-east-1", "DEEP_ARCHIVE")
+	if want := 10*0.0195 + 20*builtIn; math.Abs(breakdown.TotalMonthlyCost-want) > 1e-9 {
+		t.Errorf("TotalMonthlyCost = %v, want provider prices for STANDARD and built-in prices for DEEP_ARCHIVE (%v)", breakdown.TotalMonthlyCost, want)
+	}
+	if strings.Count(warnings.String(), "Warning:") != 1 || !strings.Contains(warnings.String(), "using built-in prices for us-east-1/DEEP_ARCHIVE") {
+		t.Errorf("warnings = %q, want one fallback warning", warnings.String())
+	}
+}
+
+func TestParseCostProvider(t *testing.T) {
+	for value, want := range map[string]string{
+		"http://prices.internal": "unsupported cost provider",
+		"exec:":                  "names no program",
+		"exec:/does/not/exist":   "cost provider /does/not/exist",
+	} {
+		if _, err := ParseCostProvider(value, "USD"); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseCostProvider(%q) error = %v, want %q", value, err, want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPricingRegion is the pricing file region entry used when a region has no entry of its own
+const DefaultPricingRegion = "default"
+
+// FilePricingSource implements PricingSource with prices loaded from a local YAML file.
+//
+// The file format is:
+//
+//	currency: EUR
+//	regions:
+//	  default:
+//	    STANDARD: 0.020
+//	  us-east-1:
+//	    STANDARD: 0.018
+//	    MINIO_HOT: 0.010   # custom storage classes are accepted as-is
+type FilePricingSource struct {
+	path     string
+	currency string
+	prices   map[string]map[string]float64 // region -> storage class -> price per GB per month
+}
+
+// LoadPricingFile loads and validates a pricing override file
+func LoadPricingFile(path string) (*FilePricingSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing file %s: %w", path, err)
+	}
+	return ParsePricingFile(path, data)
+}
+
+// ParsePricingFile parses pricing file contents; path is only used in error messages
+func ParsePricingFile(path string, data []byte) (*FilePricingSource, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing file %s: %w", path, err)
+	}
+
+	source := &FilePricingSource{
+		path:     path,
+		currency: "USD",
+		prices:   make(map[string]map[string]float64),
+	}
+
+	if len(root.Content) == 0 {
+		return nil, fmt.Errorf("%s: pricing file is empty", path)
+	}
+
+	document := root.Content[0]
+	if document.Kind != yaml.MappingNode {
+		return nil, pricingFileError(path, document, "expected a mapping with 'currency' and 'regions' keys")
+	}
+
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		key, value := document.Content[i], document.Content[i+1]
+
+		switch key.Value {
+		case "currency":
+			currency := strings.ToUpper(strings.TrimSpace(value.Value))
+			if value.Kind != yaml.ScalarNode || len(currency) != 3 {
+				return nil, pricingFileError(path, value, "currency must be a three-letter code such as USD or EUR")
+			}
+			source.currency = currency
+		case "regions":
+			if err := source.parseRegions(value); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, pricingFileError(path, key, fmt.Sprintf("unknown key '%s', expected 'currency' or 'regions'", key.Value))
+		}
+	}
+
+	if len(source.prices) == 0 {
+		return nil, fmt.Errorf("%s: pricing file defines no regions", path)
+	}
+
+	return source, nil
+}
+
+// parseRegions parses the region -> storage class -> price mapping
+func (f *FilePricingSource) parseRegions(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return pricingFileError(f.path, node, "'regions' must map region names to storage class prices")
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		regionNode, classesNode := node.Content[i], node.Content[i+1]
+		region := strings.ToLower(strings.TrimSpace(regionNode.Value))
+		if region == "" {
+			return pricingFileError(f.path, regionNode, "region name cannot be empty")
+		}
+		if classesNode.Kind != yaml.MappingNode {
+			return pricingFileError(f.path, classesNode, fmt.Sprintf("region '%s' must map storage classes to prices", region))
+		}
+
+		classes := make(map[string]float64)
+		for j := 0; j+1 < len(classesNode.Content); j += 2 {
+			classNode, priceNode := classesNode.Content[j], classesNode.Content[j+1]
+			storageClass := strings.ToUpper(strings.TrimSpace(classNode.Value))
+			if storageClass == "" {
+				return pricingFileError(f.path, classNode, fmt.Sprintf("empty storage class in region '%s'", region))
+			}
+
+			price, err := strconv.ParseFloat(priceNode.Value, 64)
+			if priceNode.Kind != yaml.ScalarNode || err != nil {
+				return pricingFileError(f.path, priceNode, fmt.Sprintf("price for %s/%s must be a number, got '%s'", region, storageClass, priceNode.Value))
+			}
+			if price < 0 {
+				return pricingFileError(f.path, priceNode, fmt.Sprintf("price for %s/%s cannot be negative: %v", region, storageClass, price))
+			}
+
+			classes[storageClass] = price
+		}
+
+		f.prices[region] = classes
+	}
+
+	return nil
+}
+
+// pricingFileError formats a validation error with file and line context
+func pricingFileError(path string, node *yaml.Node, message string) error {
+	return fmt.Errorf("%s:%d:%d: %s", path, node.Line, node.Column, message)
+}
+
+// GetPrice returns the file price for a region and storage class, falling back
+// to the 'default' region entry
+func (f *FilePricingSource) GetPrice(ctx context.Context, region, storageClass string) (float64, error) {
+	storageClass = strings.ToUpper(storageClass)
+
+	if price, exists := f.prices[strings.ToLower(region)][storageClass]; exists {
+		return price, nil
+	}
+	if price, exists := f.prices[DefaultPricingRegion][storageClass]; exists {
+		return price, nil
+	}
+
+	return 0, fmt.Errorf("pricing file %s has no price for storage class %s in region %s", f.path, storageClass, region)
+}
+
+// Currency returns the currency of the prices in the file
+func (f *FilePricingSource) Currency() string {
+	return f.currency
+}
+
+// PricingSourceChain tries each source in order and returns the first price found
+type PricingSourceChain []PricingSource
+
+// GetPrice returns the first successful price from the chain
+func (c PricingSourceChain) GetPrice(ctx context.Context, region, storageClass string) (float64, error) {
+	var lastErr error
+	for _, source := range c {
+		price, err := source.GetPrice(ctx, region, storageClass)
+		if err == nil {
+			return price, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no pricing sources configured")
+	}
+	return 0, lastErr
+}
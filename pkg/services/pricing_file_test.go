@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+const samplePricingFile = `currency: eur
+regions:
+  default:
+    STANDARD: 0.020
+  us-east-1:
+    STANDARD: 0.018
+    minio_hot: 0.010
+`
+
+func TestParsePricingFile(t *testing.T) {
+	source, err := ParsePricingFile("prices.yaml", []byte(samplePricingFile))
+	if err != nil {
+		t.Fatalf("ParsePricingFile() error = %v", err)
+	}
+
+	if source.Currency() != "EUR" {
+		t.Errorf("Currency() = %s, want EUR", source.Currency())
+	}
+
+	tests := []struct {
+		region       string
+		storageClass string
+		want         float64
+		wantErr      bool
+	}{
+		{"us-east-1", "STANDARD", 0.018, false},
+		{"us-east-1", "MINIO_HOT", 0.010, false},
+		{"eu-north-1", "STANDARD", 0.020, false},
+		{"eu-north-1", "GLACIER", 0, true},
+	}
+
+	for _, tt := range tests {
+		price, err := source.GetPrice(context.Background(), tt.region, tt.storageClass)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("GetPrice(%s, %s) error = %v, wantErr %v", tt.region, tt.storageClass, err, tt.wantErr)
+			continue
+		}
+		if price != tt.want {
+			t.Errorf("GetPrice(%s, %s) = %v, want %v", tt.region, tt.storageClass, price, tt.want)
+		}
+	}
+}
+
+func TestParsePricingFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantMsg string
+	}{
+		{
+			name:    "negative price",
+			content: "regions:\n  us-east-1:\n    STANDARD: -0.5\n",
+			wantMsg: "prices.yaml:3:15: price for us-east-1/STANDARD cannot be negative",
+		},
+		{
+			name:    "non-numeric price",
+			content: "regions:\n  us-east-1:\n    STANDARD: cheap\n",
+			wantMsg: "prices.yaml:3:15: price for us-east-1/STANDARD must be a number",
+		},
+		{
+			name:    "malformed yaml",
+			content: "regions:\n  us-east-1: [\n",
+			wantMsg: "line",
+		},
+		{
+			name:    "unknown key",
+			content: "curency: USD\nregions:\n  default:\n    STANDARD: 0.02\n",
+			wantMsg: "prices.yaml:1:1: unknown key 'curency'",
+		},
+		{
+			name:    "invalid currency",
+			content: "currency: dollars\nregions:\n  default:\n    STANDARD: 0.02\n",
+			wantMsg: "prices.yaml:1:11: currency must be a three-letter code",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePricingFile("prices.yaml", []byte(tt.content))
+			if err == nil {
+				t.Fatal("ParsePricingFile() expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("ParsePricingFile() error = %q, want it to contain %q", err.Error(), tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestCostServiceWithPricingFile(t *testing.T) {
+	source, err := ParsePricingFile("prices.yaml", []byte(samplePricingFile))
+	if err != nil {
+		t.Fatalf("ParsePricingFile() error = %v", err)
+	}
+	service := NewCostServiceWithOptions(source, source.Currency())
+
+	uploads := []types.MultipartUpload{
+		{Bucket: "b", Key: "k1", UploadID: "u1", Size: 1024 * 1024 * 1024, StorageClass: "MINIO_HOT", Region: "us-east-1"},
+		{Bucket: "b", Key: "k2", UploadID: "u2", Size: 1024 * 1024 * 1024, StorageClass: "STANDARD", Region: "eu-west-1"},
+	}
+
+	breakdown, err := service.CalculateStorageCost(context.Background(), uploads)
+	if err != nil {
+		t.Fatalf("CalculateStorageCost() error = %v", err)
+	}
+	if breakdown.Currency != "EUR" {
+		t.Errorf("Currency = %s, want EUR", breakdown.Currency)
+	}
+	if got, want := breakdown.TotalMonthlyCost, 0.030; got < want-1e-9 || got > want+1e-9 {
+		t.Errorf("TotalMonthlyCost = %v, want %v", got, want)
+	}
+}
+
+func TestCostServiceRefusesUSDPricesForOtherCurrencies(t *testing.T) {
+	source, err := ParsePricingFile("prices.yaml", []byte(samplePricingFile))
+	if err != nil {
+		t.Fatalf("ParsePricingFile() error = %v", err)
+	}
+	service := NewCostServiceWithOptions(source, source.Currency())
+
+	// The file has no GLACIER price, and the built-in one is in USD
+	uploads := []types.MultipartUpload{
+		{Bucket: "b", Key: "k1", UploadID: "u1", Size: 1024 * 1024 * 1024, StorageClass: "GLACIER", Region: "us-east-1"},
+	}
+	if _, err := service.GetRegionalPricing(context.Background(), "us-east-1", "GLACIER"); err == nil || !strings.Contains(err.Error(), "not EUR") {
+		t.Errorf("GetRegionalPricing() error = %v, want the USD prices refused", err)
+	}
+	if _, err := service.CalculateStorageCost(context.Background(), uploads); err == nil {
+		t.Error("CalculateStorageCost() succeeded, want the missing EUR price reported")
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+)
+
+// mockPricingClient returns canned GetProducts responses
+type mockPricingClient struct {
+	priceList []string
+	err       error
+	calls     int
+}
+
+func (m *mockPricingClient) GetProducts(ctx context.Context, params *pricing.GetProductsInput, optFns ...func(*pricing.Options)) (*pricing.GetProductsOutput, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &pricing.GetProductsOutput{PriceList: m.priceList}, nil
+}
+
+const standardPriceDocument = `{
+  "product": {"attributes": {"regionCode": "eu-north-1", "volumeType": "Standard"}},
+  "terms": {"OnDemand": {"ABC.JRTCKXETXF": {"priceDimensions": {
+    "ABC.JRTCKXETXF.PGHJ3S3EYE": {"unit": "GB-Mo", "beginRange": "51200", "pricePerUnit": {"USD": "0.0210"}},
+    "ABC.JRTCKXETXF.D42MF2PVJS": {"unit": "GB-Mo", "beginRange": "0", "pricePerUnit": {"USD": "0.0230"}}
+  }}}}
+}`
+
+func TestPricingAPISourceGetPrice(t *testing.T) {
+	client := &mockPricingClient{priceList: []string{standardPriceDocument}}
+	cachePath := filepath.Join(t.TempDir(), "pricing.json")
+	source := NewPricingAPISource(client, cachePath, time.Hour)
+
+	price, err := source.GetPrice(context.Background(), "eu-north-1", "STANDARD")
+	if err != nil {
+		t.Fatalf("GetPrice() error = %v", err)
+	}
+	if price != 0.023 {
+		t.Errorf("GetPrice() = %v, want 0.023", price)
+	}
+
+	// Second lookup is served from memory
+	if _, err := source.GetPrice(context.Background(), "eu-north-1", "STANDARD"); err != nil {
+		t.Fatalf("GetPrice() error = %v", err)
+	}
+	if client.calls != 1 {
+		t.Errorf("expected 1 API call, got %d", client.calls)
+	}
+
+	// A new source reads the persisted cache instead of calling the API
+	otherClient := &mockPricingClient{err: errors.New("should not be called")}
+	cached := NewPricingAPISource(otherClient, cachePath, time.Hour)
+	price, err = cached.GetPrice(context.Background(), "eu-north-1", "STANDARD")
+	if err != nil || price != 0.023 {
+		t.Errorf("cached GetPrice() = %v, %v, want 0.023", price, err)
+	}
+	if otherClient.calls != 0 {
+		t.Errorf("expected cached lookup, got %d API calls", otherClient.calls)
+	}
+}
+
+func TestCostServiceFallsBackWhenPricingAPIFails(t *testing.T) {
+	client := &mockPricingClient{err: errors.New("AccessDeniedException: not authorized to perform pricing:GetProducts")}
+	source := NewPricingAPISource(client, filepath.Join(t.TempDir(), "pricing.json"), time.Hour)
+	service := NewCostServiceWithSource(source)
+
+	price, err := service.GetRegionalPricing(context.Background(), "us-east-1", "STANDARD")
+	if err != nil {
+		t.Fatalf("GetRegionalPricing() error = %v", err)
+	}
+	if price != 0.023 {
+		t.Errorf("GetRegionalPricing() = %v, want embedded price 0.023", price)
+	}
+	if client.calls != 1 {
+		t.Errorf("expected the API to be tried once, got %d calls", client.calls)
+	}
+
+	// The failure is remembered for the region and class, so pricing every
+	// upload does not call the API again; other classes are still tried
+	for i := 0; i < 3; i++ {
+		if _, err := service.GetRegionalPricing(context.Background(), "us-east-1", "STANDARD"); err != nil {
+			t.Fatalf("GetRegionalPricing() error = %v", err)
+		}
+	}
+	if client.calls != 1 {
+		t.Errorf("expected the failed price not to be asked for again, got %d calls", client.calls)
+	}
+	if _, err := service.GetRegionalPricing(context.Background(), "us-east-1", "GLACIER"); err != nil {
+		t.Fatalf("GetRegionalPricing() error = %v", err)
+	}
+	if client.calls != 2 {
+		t.Errorf("expected another storage class to be tried, got %d calls", client.calls)
+	}
+}
+
+func TestCostServiceUsesLivePrice(t *testing.T) {
+	client := &mockPricingClient{priceList: []string{standardPriceDocument}}
+	source := NewPricingAPISource(client, filepath.Join(t.TempDir(), "pricing.json"), time.Hour)
+	service := NewCostServiceWithSource(source)
+
+	// eu-north-1 is not in the embedded table, so this price must come from the API
+	price, err := service.GetRegionalPricing(context.Background(), "eu-north-1", "STANDARD")
+	if err != nil {
+		t.Fatalf("GetRegionalPricing() error = %v", err)
+	}
+	if price != 0.023 {
+		t.Errorf("GetRegionalPricing() = %v, want 0.023", price)
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// rateWindow is how far back the rolling progress rate looks
+const rateWindow = 10 * time.Second
+
+// plainProgressInterval is how often progress is logged when the output is not a terminal
+const plainProgressInterval = 30 * time.Second
+
+// deletingPhase is the phase shown for progress that does not name one
+const deletingPhase = "Deleting"
+
+// sizingPhase is the phase shown while upload sizes are fetched
+const sizingPhase = "Calculating upload sizes"
+
+// progressState holds the counters of a running operation. Workers update it
+// concurrently while a single reporter goroutine takes snapshots of it.
+type progressState struct {
+	phase         string
+	total         int
+	startTime     time.Time
+	processed     int64
+	succeeded     int64
+	failed        int64
+	currentBucket atomic.Value // string
+}
+
+// newProgressState creates the progress state of an operation over total items
+func newProgressState(phase string, total int, startTime time.Time) *progressState {
+	return &progressState{phase: phase, total: total, startTime: startTime}
+}
+
+// begin records that a worker started on an item in bucket
+func (p *progressState) begin(bucket string) {
+	p.currentBucket.Store(bucket)
+}
+
+// finish counts an item as done, failed when err is not nil. Processed is
+// incremented last so a snapshot never shows more processed than finished.
+func (p *progressState) finish(err error) {
+	if err != nil {
+		atomic.AddInt64(&p.failed, 1)
+	} else {
+		atomic.AddInt64(&p.succeeded, 1)
+	}
+	atomic.AddInt64(&p.processed, 1)
+}
+
+// snapshot returns the current progress
+func (p *progressState) snapshot() DeletionProgress {
+	processed := atomic.LoadInt64(&p.processed)
+	bucket, _ := p.currentBucket.Load().(string)
+	return DeletionProgress{
+		Phase:             p.phase,
+		TotalUploads:      p.total,
+		ProcessedUploads:  processed,
+		SuccessfulDeletes: atomic.LoadInt64(&p.succeeded),
+		FailedDeletes:     atomic.LoadInt64(&p.failed),
+		CurrentBucket:     bucket,
+		StartTime:         p.startTime,
+	}
+}
+
+// reportEvery reports a snapshot of state every interval until stop is closed
+// or ctx is done. The returned channel is closed once it stops reporting, so
+// a final report made after waiting on it cannot be overtaken by a tick.
+func reportEvery(ctx context.Context, reporter ProgressReporter, state *progressState, interval time.Duration, stop <-chan struct{}) <-chan struct{} {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				reporter.ReportProgress(state.snapshot())
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return stopped
+}
+
+// progressSample is the processed count at a point in time
+type progressSample struct {
+	at        time.Time
+	processed int64
+}
+
+// progressRate computes a rolling per-second rate from recent progress samples
+type progressRate struct {
+	samples []progressSample
+}
+
+// add records the processed count at a time, keeping only the samples within
+// rateWindow plus the newest one before it, which anchors the window
+func (r *progressRate) add(at time.Time, processed int64) {
+	if n := len(r.samples); n > 0 && processed < r.samples[n-1].processed {
+		r.samples = r.samples[:0] // a new run started
+	}
+	r.samples = append(r.samples, progressSample{at: at, processed: processed})
+
+	cut := 0
+	for cut < len(r.samples)-1 && at.Sub(r.samples[cut+1].at) >= rateWindow {
+		cut++
+	}
+	r.samples = r.samples[cut:]
+}
+
+// reset forgets all samples
+func (r *progressRate) reset() {
+	r.samples = r.samples[:0]
+}
+
+// perSecond returns the rate over the window, or 0 before there are two samples
+func (r *progressRate) perSecond() float64 {
+	if len(r.samples) < 2 {
+		return 0
+	}
+	first, last := r.samples[0], r.samples[len(r.samples)-1]
+	span := last.at.Sub(first.at).Seconds()
+	if span <= 0 {
+		return 0
+	}
+	return float64(last.processed-first.processed) / span
+}
+
+// estimateRemaining returns how long the remaining items take at rate per
+// second, and false when the rate is too low to tell
+func estimateRemaining(remaining int64, rate float64) (time.Duration, bool) {
+	if remaining <= 0 {
+		return 0, true
+	}
+	if rate <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(remaining) / rate * float64(time.Second)), true
+}
+
+// formatETA formats an estimated time remaining, or "?" when it is unknown
+func formatETA(remaining time.Duration, known bool) string {
+	if !known {
+		return "?"
+	}
+	return remaining.Round(time.Second).String()
+}
+
+// progressBarWidth returns the bar width for a terminal width: a quarter of
+// the line, but never narrower than 10 or wider than 40 columns
+func progressBarWidth(terminalWidth int) int {
+	width := terminalWidth / 4
+	if width < 10 {
+		return 10
+	}
+	if width > 40 {
+		return 40
+	}
+	return width
+}
+
+// progressBar draws a bar of width columns filled in proportion to done/total
+func progressBar(done int64, total int, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = int(int64(width) * done / int64(total))
+	}
+	if filled > width {
+		filled = width
+	}
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
+	pkgtypes "github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// ProgressEvent is one line of JSONProgressReporter output. Type is
+// "progress" for each tick, "error" for each failed upload and "result" once
+// a phase completes; Phase is "delete" or "size".
+type ProgressEvent struct {
+	Type           string   `json:"type"`
+	Phase          string   `json:"phase"`
+	Processed      int64    `json:"processed"`
+	Total          int      `json:"total"`
+	Succeeded      int64    `json:"succeeded"`
+	Failed         int64    `json:"failed"`
+	ElapsedSeconds float64  `json:"elapsed_s"`
+	RatePerSecond  float64  `json:"rate_per_s,omitempty"`
+	ETASeconds     *float64 `json:"eta_s,omitempty"`
+	StorageFreed   int64    `json:"storage_freed,omitempty"`
+
+	// Set on error events
+	Bucket    string `json:"bucket,omitempty"`
+	Key       string `json:"key,omitempty"`
+	UploadID  string `json:"upload_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+	Time      string `json:"time,omitempty"`
+}
+
+// JSONProgressReporter implements ProgressReporter by writing one JSON object
+// per line, for CI pipelines that parse progress. It writes every event,
+// including under --quiet, since it is only used when asked for.
+type JSONProgressReporter struct {
+	writer  io.Writer
+	now     func() time.Time
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	rate    progressRate
+	start   time.Time
+}
+
+// NewJSONProgressReporter creates a JSON progress reporter writing to writer,
+// normally stderr so stdout stays clean for command output; a nil writer
+// discards the events
+func NewJSONProgressReporter(writer io.Writer) *JSONProgressReporter {
+	if writer == nil {
+		writer = io.Discard
+	}
+	return &JSONProgressReporter{
+		writer:  writer,
+		now:     time.Now,
+		encoder: json.NewEncoder(writer),
+	}
+}
+
+// progressEventPhase returns the phase name used in progress events
+func progressEventPhase(phase string) string {
+	switch phase {
+	case "", deletingPhase:
+		return "delete"
+	case sizingPhase:
+		return "size"
+	}
+	return phase
+}
+
+// ReportProgress writes a progress event
+func (r *JSONProgressReporter) ReportProgress(progress DeletionProgress) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.now()
+	if !r.start.Equal(progress.StartTime) {
+		r.rate.reset()
+		r.start = progress.StartTime
+	}
+	r.rate.add(now, progress.ProcessedUploads)
+
+	event := ProgressEvent{
+		Type:           "progress",
+		Phase:          progressEventPhase(progress.Phase),
+		Processed:      progress.ProcessedUploads,
+		Total:          progress.TotalUploads,
+		Succeeded:      progress.SuccessfulDeletes,
+		Failed:         progress.FailedDeletes,
+		ElapsedSeconds: now.Sub(progress.StartTime).Seconds(),
+		RatePerSecond:  r.rate.perSecond(),
+	}
+	if eta, known := estimateRemaining(int64(progress.TotalUploads)-progress.ProcessedUploads, event.RatePerSecond); known {
+		seconds := eta.Seconds()
+		event.ETASeconds = &seconds
+	}
+	r.write(event)
+}
+
+// ReportError writes an error event for a failed upload
+func (r *JSONProgressReporter) ReportError(err DeletionError) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	at := err.Time
+	if at.IsZero() {
+		at = r.now()
+	}
+	r.write(ProgressEvent{
+		Type:      "error",
+		Phase:     progressEventPhase(""),
+		Bucket:    err.Upload.Bucket,
+		Key:       err.Upload.Key,
+		UploadID:  err.Upload.UploadID,
+		Error:     err.Error.Error(),
+		ErrorCode: awsclient.ClassifyError(err.Error).Code,
+		Time:      pkgtypes.FormatTimestamp(at),
+	})
+}
+
+// ReportCompletion writes the result event
+func (r *JSONProgressReporter) ReportCompletion(result DeletionResult) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.write(ProgressEvent{
+		Type:           "result",
+		Phase:          progressEventPhase(result.Phase),
+		Processed:      int64(result.TotalProcessed),
+		Total:          result.TotalProcessed,
+		Succeeded:      int64(result.SuccessfulDeletes),
+		Failed:         int64(result.FailedDeletes),
+		ElapsedSeconds: result.Duration.Seconds(),
+		StorageFreed:   result.StorageFreed,
+	})
+	r.rate.reset()
+	r.start = time.Time{}
+}
+
+// write encodes event on its own line; callers hold r.mutex. Progress is best
+// effort, so a failed write does not stop the run.
+func (r *JSONProgressReporter) write(event ProgressEvent) {
+	_ = r.encoder.Encode(event)
+}
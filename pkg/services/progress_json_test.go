@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// decodeProgressEvents decodes a stream of JSON progress events, one per line
+func decodeProgressEvents(t *testing.T, output []byte) []ProgressEvent {
+	t.Helper()
+	var events []ProgressEvent
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var event ProgressEvent
+		if err := decoder.Decode(&event); err == io.EOF {
+			return events
+		} else if err != nil {
+			t.Fatalf("progress output is not a JSON stream: %v\n%s", err, output)
+		}
+		events = append(events, event)
+	}
+}
+
+// failingAbortClient fails to abort the upload with key "locked"
+type failingAbortClient struct {
+	mockAbortClient
+}
+
+func (m *failingAbortClient) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	if *input.Key == "locked" {
+		return nil, errors.New("api error AccessDenied: Access Denied")
+	}
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func TestJSONProgressReporterEmitsEventStream(t *testing.T) {
+	var output bytes.Buffer
+	client := &failingAbortClient{}
+	service := &UploadService{
+		client:           client,
+		concurrency:      1,
+		regionalClients:  map[string]S3UploadClientInterface{"us-east-1": client},
+		progressReporter: NewJSONProgressReporter(&output),
+	}
+
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a", UploadID: "u1", Size: 100, Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "locked", UploadID: "u2", Size: 200, Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+	if err := service.deleteUploadsWithProgress(context.Background(), uploads, types.DeleteOptions{}); err == nil {
+		t.Fatal("deleteUploadsWithProgress() error = nil, want the failed deletion")
+	}
+
+	events := decodeProgressEvents(t, output.Bytes())
+	if len(events) < 3 {
+		t.Fatalf("got %d events, want an error, progress and result:\n%s", len(events), output.String())
+	}
+
+	var errorEvents int
+	for _, event := range events[:len(events)-1] {
+		if event.Phase != "delete" {
+			t.Errorf("event phase = %q, want delete", event.Phase)
+		}
+		if event.Type == "error" {
+			errorEvents++
+			if event.Key != "locked" || event.UploadID != "u2" || event.ErrorCode != "AccessDenied" {
+				t.Errorf("error event = %+v, want the locked upload with code AccessDenied", event)
+			}
+		}
+	}
+	if errorEvents != 1 {
+		t.Errorf("got %d error events, want 1", errorEvents)
+	}
+
+	progress := events[len(events)-2]
+	if progress.Type != "progress" || progress.Processed != 2 || progress.Total != 2 || progress.Failed != 1 {
+		t.Errorf("final progress event = %+v, want 2/2 with 1 failure", progress)
+	}
+
+	result := events[len(events)-1]
+	if result.Type != "result" || result.Succeeded != 1 || result.Failed != 1 || result.StorageFreed != 100 {
+		t.Errorf("result event = %+v, want 1 succeeded, 1 failed, 100 bytes freed", result)
+	}
+}
+
+func TestJSONProgressReporterReportsSizeHydration(t *testing.T) {
+	var output bytes.Buffer
+	uploadService := &UploadService{
+		client:          &mockAbortClient{},
+		concurrency:     1,
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": &mockAbortClient{}},
+	}
+	sizeService := NewSizeServiceWithCache(uploadService, 1, nil, nil)
+	sizeService.SetProgressReporter(NewJSONProgressReporter(&output))
+
+	uploads := []types.MultipartUpload{{Bucket: "logs", Key: "a", UploadID: "u1", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"}}
+	if _, _, err := sizeService.HydrateUploadSizes(context.Background(), uploads); err != nil {
+		t.Fatalf("HydrateUploadSizes() error = %v", err)
+	}
+
+	events := decodeProgressEvents(t, output.Bytes())
+	if len(events) == 0 {
+		t.Fatal("no progress events for size hydration")
+	}
+	for _, event := range events {
+		if event.Phase != "size" {
+			t.Errorf("event phase = %q, want size", event.Phase)
+		}
+	}
+	if result := events[len(events)-1]; result.Type != "result" || result.Processed != 1 || result.Failed != 0 {
+		t.Errorf("last event = %+v, want a result for 1 upload", result)
+	}
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/Garvitkul/s3mpc/internal/term"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes and reads
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
+// lastProgressLine returns the text drawn after the final line clear
+func lastProgressLine(output string) string {
+	output = strings.SplitN(output, "\n", 2)[0]
+	parts := strings.Split(output, "\r\033[K")
+	return parts[len(parts)-1]
+}
+
+// mockAbortClient implements S3UploadClientInterface, running onAbort for each abort
+type mockAbortClient struct {
+	onAbort func()
+}
+
+func (m *mockAbortClient) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (*s3.ListMultipartUploadsOutput, error) {
+	return &s3.ListMultipartUploadsOutput{}, nil
+}
+
+func (m *mockAbortClient) ListParts(ctx context.Context, input *s3.ListPartsInput) (*s3.ListPartsOutput, error) {
+	return &s3.ListPartsOutput{}, nil
+}
+
+func (m *mockAbortClient) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	if m.onAbort != nil {
+		m.onAbort()
+	}
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (m *mockAbortClient) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	return nil, &s3types.NotFound{}
+}
+
+func TestProgressReporterRerendersOnResize(t *testing.T) {
+	fake := term.NewFake(200)
+	output := &syncBuffer{}
+	reporter := NewConsoleProgressReporterWithTerminal(output, false, fake)
+
+	reporter.ReportProgress(DeletionProgress{TotalUploads: 10, ProcessedUploads: 3, CurrentBucket: "a-bucket-with-a-long-name", StartTime: time.Now()})
+	if line := lastProgressLine(output.String()); !strings.Contains(line, "a-bucket-with-a-long-name") {
+		t.Fatalf("progress line = %q, want full line at width 200", line)
+	}
+
+	fake.SetWidth(40)
+
+	deadline := time.After(time.Second)
+	for {
+		line := lastProgressLine(output.String())
+		if len(line) <= 39 && strings.Count(output.String(), "\r\033[K") >= 2 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("progress line not re-rendered after resize: %q", line)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	reporter.ReportCompletion(DeletionResult{TotalProcessed: 10})
+}
+
+func TestDeletionRunAdaptsToResize(t *testing.T) {
+	fake := term.NewFake(200)
+	output := &syncBuffer{}
+
+	var once sync.Once
+	client := &mockAbortClient{onAbort: func() { once.Do(func() { fake.SetWidth(30) }) }}
+	service := &UploadService{
+		client:           client,
+		concurrency:      1,
+		regionalClients:  map[string]S3UploadClientInterface{"us-east-1": client},
+		progressReporter: NewConsoleProgressReporterWithTerminal(output, false, fake),
+	}
+
+	uploads := []types.MultipartUpload{
+		{Bucket: "bucket-one", Key: "k1", UploadID: "u1", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "bucket-two", Key: "k2", UploadID: "u2", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+	if err := service.deleteUploadsWithProgress(context.Background(), uploads, types.DeleteOptions{}); err != nil {
+		t.Fatalf("deleteUploadsWithProgress() error = %v", err)
+	}
+
+	if line := lastProgressLine(output.String()); len(line) > 29 {
+		t.Errorf("final progress line is %d columns after resize to 30: %q", len(line), line)
+	}
+}
+
+func TestProgressLineClampsWideBucketNames(t *testing.T) {
+	output := &syncBuffer{}
+	reporter := NewConsoleProgressReporterWithTerminal(output, false, term.NewFake(60))
+
+	reporter.ReportProgress(DeletionProgress{TotalUploads: 10, ProcessedUploads: 3, CurrentBucket: "本番環境のアップロード用バケット🚀", StartTime: time.Now()})
+	line := lastProgressLine(output.String())
+	if !utf8.ValidString(line) {
+		t.Errorf("progress line is not valid UTF-8: %q", line)
+	}
+	if width := displayWidth(line); width > 59 {
+		t.Errorf("progress line is %d columns at width 60: %q", width, line)
+	}
+
+	reporter.ReportCompletion(DeletionResult{TotalProcessed: 10})
+}
+
+func TestFormatTableFitsTerminalWidth(t *testing.T) {
+	fake := term.NewFake(200)
+	formatter := NewOutputFormatterWithTerminal(fake)
+	headers := []string{"Bucket", "Key"}
+	rows := [][]string{{"bucket", strings.Repeat("k", 60)}}
+
+	if wide := formatter.FormatTable(headers, rows); !strings.Contains(wide, strings.Repeat("k", 60)) {
+		t.Errorf("table at width 200 truncated the key:\n%s", wide)
+	}
+
+	fake.SetWidth(40)
+	for _, line := range strings.Split(strings.TrimRight(formatter.FormatTable(headers, rows), "\n"), "\n") {
+		if len(line) > 40 {
+			t.Errorf("table line is %d columns after resize to 40: %q", len(line), line)
+		}
+	}
+}
+
+func TestProgressRateUsesRecentWindow(t *testing.T) {
+	var rate progressRate
+	start := time.Now()
+
+	// A slow start followed by a fast stretch is reported at the recent rate
+	rate.add(start, 0)
+	rate.add(start.Add(20*time.Second), 10)
+	rate.add(start.Add(25*time.Second), 60)
+	rate.add(start.Add(30*time.Second), 110)
+	if got := rate.perSecond(); got != 10 {
+		t.Errorf("perSecond() = %v, want 10 over the last %v", got, rateWindow)
+	}
+
+	if eta, known := estimateRemaining(50, 10); !known || eta != 5*time.Second {
+		t.Errorf("estimateRemaining(50, 10) = %v, %v, want 5s", eta, known)
+	}
+	if _, known := estimateRemaining(50, 0); known {
+		t.Error("estimateRemaining() with no rate is known, want unknown")
+	}
+}
+
+func TestProgressBarShowsRateAndETA(t *testing.T) {
+	output := &syncBuffer{}
+	reporter := NewConsoleProgressReporterWithTerminal(output, false, term.NewFake(120))
+	start := time.Now()
+	now := start
+	reporter.now = func() time.Time { return now }
+
+	reporter.ReportProgress(DeletionProgress{TotalUploads: 100, ProcessedUploads: 0, StartTime: start})
+	now = start.Add(5 * time.Second)
+	reporter.ReportProgress(DeletionProgress{TotalUploads: 100, ProcessedUploads: 50, StartTime: start})
+
+	line := lastProgressLine(output.String())
+	if !strings.Contains(line, "["+strings.Repeat("#", 15)+strings.Repeat("-", 15)+"]") {
+		t.Errorf("progress line = %q, want a half-filled 30-column bar", line)
+	}
+	if !strings.Contains(line, "10.0/s") || !strings.Contains(line, "ETA: 5s") {
+		t.Errorf("progress line = %q, want rate 10.0/s and ETA 5s", line)
+	}
+}
+
+func TestProgressErrorsPrintAboveBar(t *testing.T) {
+	output := &syncBuffer{}
+	reporter := NewConsoleProgressReporterWithTerminal(output, false, term.NewFake(200))
+	start := time.Now()
+
+	reporter.ReportProgress(DeletionProgress{TotalUploads: 2, ProcessedUploads: 1, StartTime: start})
+	failure := DeletionError{Upload: types.MultipartUpload{Bucket: "logs", Key: "k"}, Error: errors.New("AccessDenied")}
+	reporter.ReportError(failure)
+
+	lines := strings.Split(output.String(), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "Failed: logs/k: AccessDenied") {
+		t.Fatalf("output = %q, want the error on its own line", output.String())
+	}
+	if !strings.HasPrefix(lines[1], "\r\033[K") || !strings.Contains(lines[1], "1/2") {
+		t.Errorf("progress line after error = %q, want it redrawn below", lines[1])
+	}
+
+	reporter.ReportCompletion(DeletionResult{TotalProcessed: 2, FailedDeletes: 1, Errors: []DeletionError{failure}})
+	if strings.Contains(output.String(), "Errors encountered") {
+		t.Errorf("completion listed errors already shown:\n%s", output.String())
+	}
+}
+
+func TestPlainProgressLogsPeriodically(t *testing.T) {
+	output := &syncBuffer{}
+	reporter := NewConsoleProgressReporterWithTerminal(output, false, nil)
+	start := time.Now()
+	now := start
+	reporter.now = func() time.Time { return now }
+
+	for i := int64(1); i <= 59; i++ {
+		now = start.Add(time.Duration(i) * time.Second)
+		reporter.ReportProgress(DeletionProgress{TotalUploads: 100, ProcessedUploads: i, StartTime: start})
+	}
+	reporter.ReportProgress(DeletionProgress{TotalUploads: 100, ProcessedUploads: 100, StartTime: start})
+
+	got := output.String()
+	if strings.Contains(got, "\r") {
+		t.Errorf("plain progress used carriage returns: %q", got)
+	}
+	// The first report, one after 30s, and the final one
+	if lines := strings.Count(got, "\n"); lines != 3 {
+		t.Errorf("plain progress wrote %d lines, want 3:\n%s", lines, got)
+	}
+}
+
+// recordingReporter records every progress report
+type recordingReporter struct {
+	mutex     sync.Mutex
+	reports   []DeletionProgress
+	completed int
+}
+
+func (r *recordingReporter) ReportProgress(progress DeletionProgress) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.reports = append(r.reports, progress)
+}
+
+func (r *recordingReporter) ReportError(err DeletionError) {}
+
+func (r *recordingReporter) ReportCompletion(result DeletionResult) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.completed++
+}
+
+// TestDeletionProgressIsRaceFree reports progress while many workers delete;
+// run with -race (make test-race) to check the progress state is shared safely
+func TestDeletionProgressIsRaceFree(t *testing.T) {
+	reporter := &recordingReporter{}
+	client := &mockAbortClient{onAbort: func() { time.Sleep(100 * time.Microsecond) }}
+	service := &UploadService{
+		client:           client,
+		concurrency:      8,
+		regionalClients:  map[string]S3UploadClientInterface{"us-east-1": client},
+		progressReporter: reporter,
+		progressInterval: time.Millisecond,
+	}
+
+	names := []string{"alpha", "bravo-bucket", "charlie-logs"}
+	buckets := map[string]bool{}
+	var uploads []types.MultipartUpload
+	for i := 0; i < 300; i++ {
+		buckets[names[i%len(names)]] = true
+		uploads = append(uploads, types.MultipartUpload{Bucket: names[i%len(names)], Key: "k", UploadID: "u", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"})
+	}
+
+	if err := service.deleteUploadsWithProgress(context.Background(), uploads, types.DeleteOptions{}); err != nil {
+		t.Fatalf("deleteUploadsWithProgress() error = %v", err)
+	}
+
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	if reporter.completed != 1 {
+		t.Errorf("completion reported %d times, want once", reporter.completed)
+	}
+	for _, report := range reporter.reports {
+		if report.CurrentBucket != "" && !buckets[report.CurrentBucket] {
+			t.Errorf("CurrentBucket = %q, want one of the deleted buckets", report.CurrentBucket)
+		}
+		if report.SuccessfulDeletes+report.FailedDeletes < report.ProcessedUploads {
+			t.Errorf("report %+v counts more processed than finished", report)
+		}
+	}
+	if final := reporter.reports[len(reporter.reports)-1]; final.ProcessedUploads != int64(len(uploads)) {
+		t.Errorf("final report processed %d, want %d", final.ProcessedUploads, len(uploads))
+	}
+}
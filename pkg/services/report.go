@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// ReportService implements the interfaces.ReportService interface
+type ReportService struct {
+	uploadService  interfaces.UploadService
+	sizeService    interfaces.SizeService
+	costCalculator interfaces.CostCalculator
+	ageService     interfaces.AgeService
+}
+
+// NewReportService creates a new ReportService instance
+func NewReportService(uploadService interfaces.UploadService, sizeService interfaces.SizeService, costCalculator interfaces.CostCalculator, ageService interfaces.AgeService) interfaces.ReportService {
+	return &ReportService{
+		uploadService:  uploadService,
+		sizeService:    sizeService,
+		costCalculator: costCalculator,
+		ageService:     ageService,
+	}
+}
+
+// GenerateStats lists uploads and fetches their sizes once, then analyzes the
+// same uploads for size, cost and age. When some buckets could not be listed,
+// the stats of the others are returned with their types.BucketErrors.
+func (r *ReportService) GenerateStats(ctx context.Context, opts types.ListOptions) (*types.StatsReport, error) {
+	uploads, inaccessibleBuckets, failed, err := r.scan(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := r.StatsForUploads(ctx, uploads, inaccessibleBuckets)
+	if err != nil {
+		return nil, err
+	}
+	return report, partialResult(failed)
+}
+
+// GenerateMetrics scans uploads the same way as GenerateStats and totals them
+// for monitoring, returning partial results the same way too
+func (r *ReportService) GenerateMetrics(ctx context.Context, opts types.ListOptions) (*types.MetricsReport, error) {
+	uploads, inaccessibleBuckets, failed, err := r.scan(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := r.MetricsForUploads(ctx, uploads, inaccessibleBuckets)
+	if err != nil {
+		return nil, err
+	}
+	return report, partialResult(failed)
+}
+
+// scan lists uploads once and fetches their sizes. The buckets that could not
+// be listed are returned apart, since the uploads of the others are complete.
+func (r *ReportService) scan(ctx context.Context, opts types.ListOptions) ([]types.MultipartUpload, []string, types.BucketErrors, error) {
+	uploads, failed, err := listPartially(ctx, r.uploadService, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	uploads, inaccessibleBuckets, err := r.sizeService.HydrateUploadSizes(ctx, uploads)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to calculate upload sizes: %w", err)
+	}
+
+	return uploads, withSkippedBuckets(inaccessibleBuckets, r.uploadService.SkippedBuckets()), failed, nil
+}
+
+// listPartially lists uploads, returning the buckets that could not be
+// listed apart from any other error
+func listPartially(ctx context.Context, uploadService interfaces.UploadService, opts types.ListOptions) ([]types.MultipartUpload, types.BucketErrors, error) {
+	uploads, err := uploadService.ListUploads(ctx, opts)
+	var failed types.BucketErrors
+	if err != nil && !errors.As(err, &failed) {
+		return nil, nil, fmt.Errorf("failed to list uploads: %w", err)
+	}
+	return uploads, failed, nil
+}
+
+// partialResult returns failed as the error of a result that leaves out the
+// uploads of those buckets, or nil when every bucket was listed
+func partialResult(failed types.BucketErrors) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	return failed
+}
+
+// StatsForUploads analyzes uploads whose sizes are already known
+func (r *ReportService) StatsForUploads(ctx context.Context, uploads []types.MultipartUpload, inaccessibleBuckets []string) (*types.StatsReport, error) {
+	sizeReport, err := r.sizeService.ReportForUploads(uploads)
+	if err != nil {
+		return nil, err
+	}
+	// Inaccessible buckets are reported once, at the top level, and the age
+	// distribution stands in for a size histogram
+	sizeReport.InaccessibleBuckets = nil
+	sizeReport.Distribution = nil
+
+	cost, err := r.costCalculator.CalculateStorageCost(ctx, uploads)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate costs: %w", err)
+	}
+
+	age, err := r.ageService.CalculateAgeDistribution(ctx, uploads)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate age distribution: %w", err)
+	}
+
+	byBucket, err := r.bucketStats(ctx, uploads)
+	if err != nil {
+		return nil, err
+	}
+
+	if inaccessibleBuckets == nil {
+		inaccessibleBuckets = []string{}
+	}
+
+	return &types.StatsReport{
+		Size:                *sizeReport,
+		Cost:                cost,
+		Age:                 age,
+		ByBucket:            byBucket,
+		InaccessibleBuckets: inaccessibleBuckets,
+	}, nil
+}
+
+// bucketStats groups uploads by bucket, sorted by size (descending)
+func (r *ReportService) bucketStats(ctx context.Context, uploads []types.MultipartUpload) ([]types.BucketStats, error) {
+	grouped := make(map[string][]types.MultipartUpload)
+	for _, upload := range uploads {
+		grouped[upload.Bucket] = append(grouped[upload.Bucket], upload)
+	}
+
+	stats := make([]types.BucketStats, 0, len(grouped))
+	for bucket, bucketUploads := range grouped {
+		cost, err := r.costCalculator.CalculateStorageCost(ctx, bucketUploads)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate costs for bucket %s: %w", bucket, err)
+		}
+
+		entry := types.BucketStats{Bucket: bucket, Count: len(bucketUploads), MonthlyCost: cost.TotalMonthlyCost}
+		for _, upload := range bucketUploads {
+			entry.Size += upload.Size
+		}
+		stats = append(stats, entry)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Size != stats[j].Size {
+			return stats[i].Size > stats[j].Size
+		}
+		return stats[i].Bucket < stats[j].Bucket
+	})
+
+	return stats, nil
+}
+
+// MetricsForUploads totals uploads by bucket, region and storage class and
+// records the oldest upload of each bucket
+func (r *ReportService) MetricsForUploads(ctx context.Context, uploads []types.MultipartUpload, inaccessibleBuckets []string) (*types.MetricsReport, error) {
+	type seriesKey struct {
+		bucket, region, storageClass string
+	}
+
+	grouped := make(map[seriesKey][]types.MultipartUpload)
+	oldest := make(map[string]time.Time)
+	for _, upload := range uploads {
+		key := seriesKey{upload.Bucket, upload.Region, upload.StorageClass}
+		grouped[key] = append(grouped[key], upload)
+
+		if initiated, ok := oldest[upload.Bucket]; !ok || upload.Initiated.Before(initiated) {
+			oldest[upload.Bucket] = upload.Initiated
+		}
+	}
+
+	report := &types.MetricsReport{
+		GeneratedAt:         time.Now(),
+		Series:              make([]types.UploadSeries, 0, len(grouped)),
+		OldestUploads:       make([]types.BucketOldest, 0, len(oldest)),
+		InaccessibleBuckets: inaccessibleBuckets,
+	}
+	if report.InaccessibleBuckets == nil {
+		report.InaccessibleBuckets = []string{}
+	}
+
+	for key, seriesUploads := range grouped {
+		cost, err := r.costCalculator.CalculateStorageCost(ctx, seriesUploads)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate costs for bucket %s: %w", key.bucket, err)
+		}
+		report.Currency = cost.Currency
+
+		series := types.UploadSeries{
+			Bucket:       key.bucket,
+			Region:       key.region,
+			StorageClass: key.storageClass,
+			Count:        len(seriesUploads),
+			MonthlyCost:  cost.TotalMonthlyCost,
+		}
+		for _, upload := range seriesUploads {
+			series.Size += upload.Size
+		}
+		report.Series = append(report.Series, series)
+	}
+
+	for bucket, initiated := range oldest {
+		report.OldestUploads = append(report.OldestUploads, types.BucketOldest{Bucket: bucket, Initiated: initiated})
+	}
+
+	sort.Slice(report.Series, func(i, j int) bool {
+		a, b := report.Series[i], report.Series[j]
+		if a.Bucket != b.Bucket {
+			return a.Bucket < b.Bucket
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.StorageClass < b.StorageClass
+	})
+	sort.Slice(report.OldestUploads, func(i, j int) bool {
+		return report.OldestUploads[i].Bucket < report.OldestUploads[j].Bucket
+	})
+
+	return report, nil
+}
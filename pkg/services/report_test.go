@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestGenerateStatsScansOnce(t *testing.T) {
+	now := time.Now()
+	uploadService := &countingUploadService{
+		uploads: []types.MultipartUpload{
+			{Bucket: "logs", Key: "a", UploadID: "1", Initiated: now.Add(-2 * time.Hour), StorageClass: "STANDARD", Region: "us-east-1"},
+			{Bucket: "logs", Key: "bb", UploadID: "2", Initiated: now.Add(-10 * 24 * time.Hour), StorageClass: "STANDARD", Region: "us-east-1"},
+			{Bucket: "media", Key: "ccc", UploadID: "3", Initiated: now.Add(-40 * 24 * time.Hour), StorageClass: "STANDARD_IA", Region: "eu-west-1"},
+			{Bucket: "locked", Key: "d", UploadID: "4", Initiated: now, StorageClass: "STANDARD", Region: "us-east-1"},
+			{Bucket: "locked", Key: "e", UploadID: "5", Initiated: now, StorageClass: "STANDARD", Region: "us-east-1"},
+		},
+		failBuckets: map[string]bool{"locked": true},
+	}
+	sizeService := NewSizeServiceWithCache(uploadService, 2, nil, nil)
+	service := NewReportService(uploadService, sizeService, NewCostService(), NewAgeService())
+
+	report, err := service.GenerateStats(context.Background(), types.ListOptions{})
+	if err != nil {
+		t.Fatalf("GenerateStats() error = %v", err)
+	}
+
+	if uploadService.listCalls != 1 || uploadService.sizeCalls != 5 {
+		t.Errorf("ListUploads called %d times and GetUploadSize %d times, want 1 and 5", uploadService.listCalls, uploadService.sizeCalls)
+	}
+
+	if report.Size.TotalCount != 3 || report.Size.TotalSize != 6*1024 {
+		t.Errorf("size = %d uploads, %d bytes, want 3 uploads, %d bytes", report.Size.TotalCount, report.Size.TotalSize, 6*1024)
+	}
+	if report.Cost.TotalMonthlyCost <= 0 {
+		t.Errorf("TotalMonthlyCost = %v, want > 0", report.Cost.TotalMonthlyCost)
+	}
+
+	var aged int
+	for _, bucket := range report.Age.Buckets {
+		aged += bucket.Count
+	}
+	if aged != 3 {
+		t.Errorf("age distribution counts %d uploads, want 3", aged)
+	}
+
+	if len(report.ByBucket) != 2 || report.ByBucket[0].Bucket != "logs" || report.ByBucket[0].Count != 2 {
+		t.Errorf("ByBucket = %+v, want logs (2 uploads) first", report.ByBucket)
+	}
+
+	if len(report.InaccessibleBuckets) != 1 || report.InaccessibleBuckets[0] != "locked" {
+		t.Errorf("InaccessibleBuckets = %v, want [locked]", report.InaccessibleBuckets)
+	}
+	if len(report.Size.InaccessibleBuckets) != 0 {
+		t.Errorf("size report repeats inaccessible buckets: %v", report.Size.InaccessibleBuckets)
+	}
+}
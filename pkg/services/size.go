@@ -3,57 +3,136 @@ package services
 import (
 	"context"
 	"fmt"
+	"io"
 	"sort"
 	"sync"
+	"time"
 
+	"github.com/Garvitkul/s3mpc/internal/logging"
 	"github.com/Garvitkul/s3mpc/pkg/interfaces"
 	"github.com/Garvitkul/s3mpc/pkg/types"
+	"github.com/Garvitkul/s3mpc/pkg/units"
 )
 
 // SizeService handles size calculation and reporting operations
 type SizeService struct {
 	uploadService interfaces.UploadService
 	concurrency   int
+	cache         *SizeCache // nil disables caching
+	progress      ProgressReporter // nil disables progress output
+	// maxBucketFailures is how many consecutive non-retryable failures stop
+	// the measuring of a bucket's uploads; zero never stops
+	maxBucketFailures int
+	logger        *logging.Logger // receives diagnostics; never nil once constructed
+
+	trippedMutex  sync.Mutex
+	tripped       []types.TrippedBucket
 }
 
 // NewSizeService creates a new SizeService instance
 func NewSizeService(uploadService interfaces.UploadService) *SizeService {
-	return &SizeService{
-		uploadService: uploadService,
-		concurrency:   10, // Default concurrency
-	}
+	return NewSizeServiceWithConcurrency(uploadService, 10)
 }
 
 // NewSizeServiceWithConcurrency creates a new SizeService instance with custom concurrency
 func NewSizeServiceWithConcurrency(uploadService interfaces.UploadService, concurrency int) *SizeService {
+	return NewSizeServiceWithCache(uploadService, concurrency, nil, nil)
+}
+
+// NewSizeServiceWithCache creates a new SizeService that reuses sizes from
+// cache and reports diagnostics to logger; a nil logger logs nothing
+func NewSizeServiceWithCache(uploadService interfaces.UploadService, concurrency int, cache *SizeCache, logger *logging.Logger) *SizeService {
+	if logger == nil {
+		logger = logging.NewNopLogger()
+	}
 	return &SizeService{
 		uploadService: uploadService,
 		concurrency:   concurrency,
+		cache:         cache,
+		maxBucketFailures: DefaultMaxBucketFailures,
+		logger:        logger,
+	}
+}
+
+// SetMaxBucketFailures sets how many consecutive failures that retrying cannot
+// fix, such as AccessDenied, stop the measuring of a bucket's remaining
+// uploads; zero never stops
+func (s *SizeService) SetMaxBucketFailures(limit int) {
+	if limit >= 0 {
+		s.maxBucketFailures = limit
+	}
+}
+
+// log returns the logger of the service
+func (s *SizeService) log() *logging.Logger {
+	if s.logger == nil {
+		return logging.NewNopLogger()
 	}
+	return s.logger
 }
 
-// CalculateTotalSize calculates the total size of all incomplete multipart uploads
+// TrippedBuckets returns the buckets whose remaining uploads the most recent
+// measuring skipped after repeated failures
+func (s *SizeService) TrippedBuckets() []types.TrippedBucket {
+	s.trippedMutex.Lock()
+	defer s.trippedMutex.Unlock()
+	
+	return s.tripped
+}
+
+// SetProgressWriter enables a progress line on w while upload sizes are fetched
+func (s *SizeService) SetProgressWriter(w io.Writer) {
+	s.progress = NewConsoleProgressReporter(w, false)
+}
+
+// SetProgressReporter reports progress to reporter while upload sizes are fetched
+func (s *SizeService) SetProgressReporter(reporter ProgressReporter) {
+	s.progress = reporter
+}
+
+// CalculateTotalSize calculates the total size of all incomplete multipart
+// uploads. When some buckets could not be listed, the report of the others
+// is returned with their types.BucketErrors.
 func (s *SizeService) CalculateTotalSize(ctx context.Context, opts types.ListOptions) (*types.SizeReport, error) {
 	// Get all uploads
-	uploads, err := s.uploadService.ListUploads(ctx, opts)
+	uploads, failed, err := listPartially(ctx, s.uploadService, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list uploads: %w", err)
+		return nil, err
 	}
 
 	// Calculate sizes for all uploads concurrently
-	uploadsWithSizes, inaccessibleBuckets, err := s.calculateUploadSizes(ctx, uploads)
+	uploadsWithSizes, inaccessibleBuckets, errorSummary, err := s.calculateUploadSizes(ctx, uploads, 0, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate upload sizes: %w", err)
 	}
 
 	// Generate size report
+	inaccessibleBuckets = withSkippedBuckets(inaccessibleBuckets, s.uploadService.SkippedBuckets())
 	report := s.generateSizeReport(uploadsWithSizes, inaccessibleBuckets)
+	report.ErrorSummary = errorSummary
+	report.TrippedBuckets = s.TrippedBuckets()
 	
 	if err := report.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid size report: %w", err)
 	}
 
-	return report, nil
+	return report, partialResult(failed)
+}
+
+// withSkippedBuckets adds the buckets a listing could not scan to the buckets
+// whose uploads could not be measured, once each
+func withSkippedBuckets(inaccessibleBuckets []string, skipped []types.SkippedBucket) []string {
+	seen := make(map[string]bool, len(inaccessibleBuckets))
+	for _, bucket := range inaccessibleBuckets {
+		seen[bucket] = true
+	}
+	for _, bucket := range skipped {
+		if !seen[bucket.Bucket] {
+			inaccessibleBuckets = append(inaccessibleBuckets, bucket.Bucket)
+			seen[bucket.Bucket] = true
+		}
+	}
+	return inaccessibleBuckets
 }
 
 // CalculateBucketSizes calculates sizes grouped by bucket
@@ -67,10 +146,67 @@ func (s *SizeService) CalculateBucketSizes(ctx context.Context, opts types.ListO
 	return report, nil
 }
 
-// calculateUploadSizes calculates sizes for all uploads concurrently
-func (s *SizeService) calculateUploadSizes(ctx context.Context, uploads []types.MultipartUpload) ([]types.MultipartUpload, []string, error) {
+// HydrateUploadSizes fills in Size, PartsCount and MeasuredAt for each upload, returning
+// uploads whose size could be determined and the buckets whose parts could not be listed
+func (s *SizeService) HydrateUploadSizes(ctx context.Context, uploads []types.MultipartUpload) ([]types.MultipartUpload, []string, error) {
+	uploads, inaccessibleBuckets, _, err := s.calculateUploadSizes(ctx, uploads, 0, false)
+	return uploads, inaccessibleBuckets, err
+}
+
+// HydrateUploadParts fills in Parts as well as what HydrateUploadSizes does,
+// listing each upload's parts once. The cache holds no parts, so every upload
+// is measured, and the fresh sizes are cached.
+func (s *SizeService) HydrateUploadParts(ctx context.Context, uploads []types.MultipartUpload) ([]types.MultipartUpload, []string, error) {
+	uploads, inaccessibleBuckets, _, err := s.calculateUploadSizes(ctx, uploads, 0, true)
+	return uploads, inaccessibleBuckets, err
+}
+
+// EstimateParts totals the parts counts of the uploads that were measured or
+// are cached, without listing any parts, and returns how many uploads that
+// covers, so a caller can tell how many parts a walk would find beforehand
+func (s *SizeService) EstimateParts(uploads []types.MultipartUpload) (int, int) {
+	parts, known := 0, 0
+	for _, upload := range uploads {
+		if !upload.MeasuredAt.IsZero() || upload.PartsCount > 0 {
+			parts += upload.PartsCount
+			known++
+		} else if s.cache != nil {
+			if detail, _, cached := s.cache.LookupDetail(upload); cached && detail.PartsCount > 0 {
+				parts += detail.PartsCount
+				known++
+			}
+		}
+	}
+	return parts, known
+}
+
+// RemeasureStaleSizes measures again every upload whose size was measured more
+// than olderThan ago or never, bypassing cached sizes, and returns the uploads
+// with fresh sizes. Uploads that can no longer be measured are dropped.
+func (s *SizeService) RemeasureStaleSizes(ctx context.Context, uploads []types.MultipartUpload, olderThan time.Duration) ([]types.MultipartUpload, error) {
+	uploads, _, _, err := s.calculateUploadSizes(ctx, uploads, olderThan, false)
+	return uploads, err
+}
+
+// ReportForUploads builds a size report from uploads whose sizes are already
+// known, such as uploads loaded from a file
+func (s *SizeService) ReportForUploads(uploads []types.MultipartUpload) (*types.SizeReport, error) {
+	report := s.generateSizeReport(uploads, nil)
+	if err := report.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid size report: %w", err)
+	}
+	return report, nil
+}
+
+// calculateUploadSizes calculates sizes for all uploads concurrently, returning
+// the buckets whose parts could not be listed and why. With a positive
+// remeasureOlderThan, every upload measured longer ago than that (or never) is
+// measured again; otherwise cached sizes are reused unless withParts asks
+// for every upload's parts. Cached sizes in the buckets of uploads that are
+// not among them are pruned, as they were completed or aborted.
+func (s *SizeService) calculateUploadSizes(ctx context.Context, uploads []types.MultipartUpload, remeasureOlderThan time.Duration, withParts bool) ([]types.MultipartUpload, []string, []types.ErrorSummary, error) {
 	if len(uploads) == 0 {
-		return uploads, nil, nil
+		return uploads, nil, nil, nil
 	}
 
 	type uploadResult struct {
@@ -81,8 +217,24 @@ func (s *SizeService) calculateUploadSizes(ctx context.Context, uploads []types.
 
 	resultChan := make(chan uploadResult, len(uploads))
 	semaphore := make(chan struct{}, s.concurrency)
+	breaker := newBucketBreaker(s.maxBucketFailures)
 
 	var wg sync.WaitGroup
+	progress := newProgressState(sizingPhase, len(uploads), time.Now())
+	timings := newSizingTimings()
+
+	// Fetching sizes can take minutes on large accounts, so report every second
+	stopProgress := make(chan struct{})
+	var progressStopped <-chan struct{}
+	if s.progress != nil {
+		progressStopped = reportEvery(ctx, s.progress, progress, time.Second, stopProgress)
+	}
+
+	// Count each upload as done before handing over its result
+	send := func(result uploadResult) {
+		progress.finish(result.err)
+		resultChan <- result
+	}
 
 	// Calculate size for each upload concurrently
 	for _, upload := range uploads {
@@ -93,21 +245,69 @@ func (s *SizeService) calculateUploadSizes(ctx context.Context, uploads []types.
 			// Acquire semaphore
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
+			progress.begin(u.Bucket)
+			began, measured := time.Now(), false
+			defer func() { timings.record(u.Bucket, began, measured) }()
+
+			if remeasureOlderThan > 0 {
+				if !u.MeasuredAt.IsZero() && time.Since(u.MeasuredAt) <= remeasureOlderThan {
+					send(uploadResult{upload: u})
+					return
+				}
+			} else if s.cache != nil && !withParts {
+				// Entries cached before parts were counted or abort dates
+				// looked up are measured again
+				if detail, measuredAt, cached := s.cache.LookupDetail(u); cached && (detail.PartsCount > 0 || detail.Size == 0) && detail.AbortChecked {
+					u.Size = detail.Size
+					u.PartsCount = detail.PartsCount
+					u.AbortDate, u.AbortRuleID = detail.AbortDate, detail.AbortRuleID
+					u.MeasuredAt = measuredAt
+					send(uploadResult{upload: u})
+					return
+				}
+			}
 
-			size, err := s.uploadService.GetUploadSize(ctx, u)
+			// A bucket that keeps refusing calls is not called for the rest
+			if !breaker.allow(u.Bucket) {
+				send(uploadResult{upload: u, err: errBucketTripped, inaccessibleBucket: u.Bucket})
+				return
+			}
+
+			measuredAt := time.Now().UTC()
+			measured = true
+			measure := s.uploadService.GetUploadDetail
+			if withParts {
+				measure = s.uploadService.GetUploadDetailWithParts
+			}
+			detail, err := measure(ctx, u)
 			if err != nil {
+				if breaker.failure(u.Bucket, err) {
+					s.log().Debug("Stopped measuring uploads in bucket after repeated failures", map[string]interface{}{
+						"bucket":   u.Bucket,
+						"failures": s.maxBucketFailures,
+						"error":    err,
+					})
+				}
 				// Check if this is an access denied error for the bucket
-				resultChan <- uploadResult{
+				send(uploadResult{
 					upload:             u,
 					err:                err,
 					inaccessibleBucket: u.Bucket,
-				}
+				})
 				return
 			}
+			breaker.success(u.Bucket)
 
 			// Update upload with calculated size
-			u.Size = size
-			resultChan <- uploadResult{upload: u}
+			u.Size = detail.Size
+			u.PartsCount = detail.PartsCount
+			u.AbortDate, u.AbortRuleID = detail.AbortDate, detail.AbortRuleID
+			u.Parts = detail.Parts
+			u.MeasuredAt = measuredAt
+			if s.cache != nil {
+				s.cache.Set(u, detail.Size)
+			}
+			send(uploadResult{upload: u})
 		}(upload)
 	}
 
@@ -120,13 +320,16 @@ func (s *SizeService) calculateUploadSizes(ctx context.Context, uploads []types.
 	// Collect results
 	var uploadsWithSizes []types.MultipartUpload
 	var inaccessibleBuckets []string
-	var errors []error
+	var errors []bucketError
 
 	bucketErrorMap := make(map[string]bool) // Track which buckets had errors
 
 	for result := range resultChan {
 		if result.err != nil {
-			errors = append(errors, result.err)
+			// Skipped uploads are counted with their bucket, not listed as errors
+			if result.err != errBucketTripped {
+				errors = append(errors, bucketError{bucket: result.upload.Bucket, err: result.err})
+			}
 			if result.inaccessibleBucket != "" && !bucketErrorMap[result.inaccessibleBucket] {
 				inaccessibleBuckets = append(inaccessibleBuckets, result.inaccessibleBucket)
 				bucketErrorMap[result.inaccessibleBucket] = true
@@ -136,12 +339,121 @@ func (s *SizeService) calculateUploadSizes(ctx context.Context, uploads []types.
 		uploadsWithSizes = append(uploadsWithSizes, result.upload)
 	}
 
-	// Return partial results even if some uploads failed
-	return uploadsWithSizes, inaccessibleBuckets, nil
+	close(stopProgress)
+	if s.progress != nil {
+		<-progressStopped
+		final := progress.snapshot()
+		s.progress.ReportProgress(final)
+		s.progress.ReportCompletion(DeletionResult{
+			Phase:             sizingPhase,
+			TotalProcessed:    len(uploads),
+			SuccessfulDeletes: len(uploadsWithSizes),
+			FailedDeletes:     int(final.FailedDeletes),
+			Duration:          time.Since(progress.startTime),
+		})
+	}
+
+	// A cache write failure only costs us extra ListParts calls next time
+	if s.cache != nil {
+		s.cache.Prune(uploads)
+		_ = s.cache.Save()
+	}
+
+	timings.log(s.log(), progress.startTime)
+	tripped := breaker.tripped()
+	for _, bucket := range tripped {
+		s.log().Debug("Skipped uploads in bucket after repeated failures", map[string]interface{}{
+			"bucket":          bucket.Bucket,
+			"error_code":      bucket.ErrorCode,
+			"skipped_uploads": bucket.SkippedUploads,
+		})
+	}
+	s.trippedMutex.Lock()
+	s.tripped = tripped
+	s.trippedMutex.Unlock()
+
+	// Return partial results even if some uploads failed; buckets fail in
+	// whatever order the workers reach them, so they are sorted by name
+	sort.Strings(inaccessibleBuckets)
+	return uploadsWithSizes, inaccessibleBuckets, summarizeErrors(errors), nil
+}
+
+// sizingTimings records how long measuring each bucket's uploads took, for
+// the debug log
+type sizingTimings struct {
+	mutex   sync.Mutex
+	buckets map[string]*bucketSizing
+	order   []string
+}
+
+// bucketSizing is the measuring of one bucket's uploads
+type bucketSizing struct {
+	uploads  int
+	measured int // uploads whose parts were listed, rather than cached or skipped
+	start    time.Time
+	end      time.Time
+}
+
+func newSizingTimings() *sizingTimings {
+	return &sizingTimings{buckets: make(map[string]*bucketSizing)}
+}
+
+// record adds an upload of bucket that was handled from began until now
+func (t *sizingTimings) record(bucket string, began time.Time, measured bool) {
+	now := time.Now()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	sizing, exists := t.buckets[bucket]
+	if !exists {
+		sizing = &bucketSizing{start: began}
+		t.buckets[bucket] = sizing
+		t.order = append(t.order, bucket)
+	}
+	sizing.uploads++
+	if measured {
+		sizing.measured++
+	}
+	if began.Before(sizing.start) {
+		sizing.start = began
+	}
+	if now.After(sizing.end) {
+		sizing.end = now
+	}
+}
+
+// log writes each bucket's measuring and the total at debug level
+func (t *sizingTimings) log(logger *logging.Logger, start time.Time) {
+	if !logger.IsEnabled(logging.LevelDebug) {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	uploads, measured := 0, 0
+	for _, bucket := range t.order {
+		sizing := t.buckets[bucket]
+		uploads += sizing.uploads
+		measured += sizing.measured
+		logger.Debug("Measured upload sizes in bucket", map[string]interface{}{
+			"bucket":   bucket,
+			"uploads":  sizing.uploads,
+			"measured": sizing.measured,
+			"duration": sizing.end.Sub(sizing.start).Round(time.Millisecond),
+		})
+	}
+	logger.Debug("Measured upload sizes", map[string]interface{}{
+		"buckets":  len(t.order),
+		"uploads":  uploads,
+		"measured": measured,
+		"duration": time.Since(start).Round(time.Millisecond),
+	})
 }
 
 // generateSizeReport creates a comprehensive size report from uploads
 func (s *SizeService) generateSizeReport(uploads []types.MultipartUpload, inaccessibleBuckets []string) *types.SizeReport {
+	inaccessibleBuckets = append([]string(nil), inaccessibleBuckets...)
+	sort.Strings(inaccessibleBuckets)
 	report := &types.SizeReport{
 		ByStorageClass:      make(map[string]int64),
 		ByBucket:            make(map[string]int64),
@@ -158,11 +470,55 @@ func (s *SizeService) generateSizeReport(uploads []types.MultipartUpload, inacce
 
 		// Aggregate by bucket
 		report.ByBucket[upload.Bucket] += upload.Size
+
+		// Multi-account runs also total each account
+		if upload.Account != "" {
+			if report.ByAccount == nil {
+				report.ByAccount = make(map[string]int64)
+			}
+			report.ByAccount[upload.Account] += upload.Size
+		}
+
+		// Service-initiated uploads are counted separately as well
+		if upload.ServiceInitiated {
+			report.ServiceInitiatedCount++
+			report.ServiceInitiatedSize += upload.Size
+		}
 	}
 
+	distribution := s.CalculateSizeDistribution(uploads)
+	report.Distribution = &distribution
+	report.TagsByBucket = types.TagsByBucket(uploads)
+
 	return report
 }
 
+// CalculateSizeDistribution counts uploads by size: under 10 MB, 10 MB to
+// 100 MB, 100 MB to 1 GB, 1 GB to 10 GB and over 10 GB
+func (s *SizeService) CalculateSizeDistribution(uploads []types.MultipartUpload) types.SizeDistribution {
+	const mb, gb = int64(1024 * 1024), int64(1024 * 1024 * 1024)
+	buckets := []types.SizeBucket{
+		{Label: "< 10 MB", MinSize: 0, MaxSize: 10 * mb},
+		{Label: "10 MB - 100 MB", MinSize: 10 * mb, MaxSize: 100 * mb},
+		{Label: "100 MB - 1 GB", MinSize: 100 * mb, MaxSize: gb},
+		{Label: "1 GB - 10 GB", MinSize: gb, MaxSize: 10 * gb},
+		{Label: "10 GB+", MinSize: 10 * gb, MaxSize: 0}, // MaxSize 0 means no upper limit
+	}
+
+	for _, upload := range uploads {
+		for i := range buckets {
+			bucket := &buckets[i]
+			if upload.Size >= bucket.MinSize && (bucket.MaxSize == 0 || upload.Size < bucket.MaxSize) {
+				bucket.Count++
+				bucket.TotalSize += upload.Size
+				break
+			}
+		}
+	}
+
+	return types.SizeDistribution{Buckets: buckets}
+}
+
 // GetSortedBucketSizes returns bucket sizes sorted by size in descending order
 func (s *SizeService) GetSortedBucketSizes(report *types.SizeReport) []interfaces.BucketSize {
 	var bucketSizes []interfaces.BucketSize
@@ -176,7 +532,10 @@ func (s *SizeService) GetSortedBucketSizes(report *types.SizeReport) []interface
 
 	// Sort by size in descending order
 	sort.Slice(bucketSizes, func(i, j int) bool {
-		return bucketSizes[i].Size > bucketSizes[j].Size
+		if bucketSizes[i].Size != bucketSizes[j].Size {
+			return bucketSizes[i].Size > bucketSizes[j].Size
+		}
+		return bucketSizes[i].Bucket < bucketSizes[j].Bucket
 	})
 
 	return bucketSizes
@@ -184,81 +543,7 @@ func (s *SizeService) GetSortedBucketSizes(report *types.SizeReport) []interface
 
 
 
-// FormatSize formats a size in bytes to human-readable format
-func FormatSize(bytes int64) string {
-	if bytes == 0 {
-		return "0 B"
-	}
-
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-
-	units := []string{"KB", "MB", "GB", "TB", "PB"}
-	if exp >= len(units) {
-		// For extremely large sizes, use the largest unit
-		exp = len(units) - 1
-		div = int64(1)
-		for i := 0; i <= exp; i++ {
-			div *= unit
-		}
-	}
-
-	return fmt.Sprintf("%.1f %s", float64(bytes)/float64(div), units[exp])
-}
-
-// ParseSize parses a human-readable size string to bytes
-func ParseSize(sizeStr string) (int64, error) {
-	if sizeStr == "" {
-		return 0, fmt.Errorf("size string cannot be empty")
-	}
-
-	var value float64
-	var unit string
-
-	// Parse the size string
-	n, err := fmt.Sscanf(sizeStr, "%f %s", &value, &unit)
-	if err != nil || n != 2 {
-		// Try parsing without space
-		n, err = fmt.Sscanf(sizeStr, "%f%s", &value, &unit)
-		if err != nil || n != 2 {
-			return 0, fmt.Errorf("invalid size format: %s", sizeStr)
-		}
-	}
-
-	if value < 0 {
-		return 0, fmt.Errorf("size cannot be negative: %s", sizeStr)
-	}
 
-	// Convert unit to bytes
-	multiplier := int64(1)
-	switch unit {
-	case "B", "b":
-		multiplier = 1
-	case "KB", "kb", "K", "k":
-		multiplier = 1024
-	case "MB", "mb", "M", "m":
-		multiplier = 1024 * 1024
-	case "GB", "gb", "G", "g":
-		multiplier = 1024 * 1024 * 1024
-	case "TB", "tb", "T", "t":
-		multiplier = 1024 * 1024 * 1024 * 1024
-	case "PB", "pb", "P", "p":
-		multiplier = 1024 * 1024 * 1024 * 1024 * 1024
-	default:
-		return 0, fmt.Errorf("unknown size unit: %s", unit)
-	}
-
-	bytes := int64(value * float64(multiplier))
-	return bytes, nil
-}
 
 // GetStorageClassBreakdown returns a formatted breakdown by storage class
 func (s *SizeService) GetStorageClassBreakdown(report *types.SizeReport) []interfaces.StorageClassSize {
@@ -268,13 +553,16 @@ func (s *SizeService) GetStorageClassBreakdown(report *types.SizeReport) []inter
 		breakdown = append(breakdown, interfaces.StorageClassSize{
 			StorageClass: storageClass,
 			Size:         size,
-			Formatted:    FormatSize(size),
+			Formatted:    units.FormatBytes(size),
 		})
 	}
 
 	// Sort by size in descending order
 	sort.Slice(breakdown, func(i, j int) bool {
-		return breakdown[i].Size > breakdown[j].Size
+		if breakdown[i].Size != breakdown[j].Size {
+			return breakdown[i].Size > breakdown[j].Size
+		}
+		return breakdown[i].StorageClass < breakdown[j].StorageClass
 	})
 
 	return breakdown
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/Garvitkul/s3mpc/internal/logging"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// mockPartsClient implements S3UploadClientInterface for one bucket, returning
+// its parts in pages of one
+type mockPartsClient struct {
+	uploads []s3types.MultipartUpload
+	parts   []int64
+}
+
+func (m *mockPartsClient) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (*s3.ListMultipartUploadsOutput, error) {
+	return &s3.ListMultipartUploadsOutput{Uploads: m.uploads}, nil
+}
+
+func (m *mockPartsClient) ListParts(ctx context.Context, input *s3.ListPartsInput) (*s3.ListPartsOutput, error) {
+	index := 0
+	if input.PartNumberMarker != nil {
+		index = len(*input.PartNumberMarker)
+	}
+	output := &s3.ListPartsOutput{
+		Parts:       []s3types.Part{{PartNumber: aws.Int32(int32(index + 1)), Size: aws.Int64(m.parts[index])}},
+		IsTruncated: aws.Bool(index+1 < len(m.parts)),
+	}
+	if index+1 < len(m.parts) {
+		output.NextPartNumberMarker = aws.String(strings.Repeat("x", index+1))
+	}
+	return output, nil
+}
+
+func (m *mockPartsClient) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (m *mockPartsClient) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	return nil, &s3types.NotFound{}
+}
+
+// failingClient implements S3UploadClientInterface, failing every call as a
+// client for the wrong region would
+type failingClient struct{}
+
+func (failingClient) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (*s3.ListMultipartUploadsOutput, error) {
+	return nil, errors.New("PermanentRedirect")
+}
+
+func (failingClient) ListParts(ctx context.Context, input *s3.ListPartsInput) (*s3.ListPartsOutput, error) {
+	return nil, errors.New("PermanentRedirect")
+}
+
+func (failingClient) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	return nil, errors.New("PermanentRedirect")
+}
+
+func (failingClient) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	return nil, errors.New("PermanentRedirect")
+}
+
+// staticBucketService implements interfaces.BucketService with one bucket
+type staticBucketService struct {
+	bucket types.Bucket
+}
+
+func (b *staticBucketService) ListBuckets(ctx context.Context, region string) ([]types.Bucket, []types.SkippedBucket, error) {
+	return []types.Bucket{b.bucket}, nil, nil
+}
+
+func (b *staticBucketService) GetBucketRegion(ctx context.Context, bucketName string) (string, error) {
+	return b.bucket.Region, nil
+}
+
+func (b *staticBucketService) ListBucketsInRegion(ctx context.Context, region string) ([]types.Bucket, []types.SkippedBucket, error) {
+	return []types.Bucket{b.bucket}, nil, nil
+}
+
+func (b *staticBucketService) ListDirectoryBuckets(ctx context.Context, region string) ([]types.Bucket, error) {
+	return nil, nil
+}
+
+func (b *staticBucketService) GetBucketTags(ctx context.Context, bucketName, region string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (b *staticBucketService) SelectBucketsByTags(ctx context.Context, buckets []types.Bucket, filter *types.BucketTagFilter) ([]types.Bucket, []types.SkippedBucket, error) {
+	return buckets, nil, nil
+}
+
+func (b *staticBucketService) ClearRegionCache() {}
+
+func (b *staticBucketService) Invalidate() {}
+
+func (b *staticBucketService) GetCacheStats() map[string]interface{} {
+	return nil
+}
+
+func TestCostOfHydratedUploadsIsNonZero(t *testing.T) {
+	const mb = 1024 * 1024
+	regional := &mockPartsClient{
+		uploads: []s3types.MultipartUpload{{
+			Key:          aws.String("backups/db.tar"),
+			UploadId:     aws.String("upload-1"),
+			Initiated:    aws.Time(time.Now().Add(-48 * time.Hour)),
+			StorageClass: s3types.StorageClassStandard,
+		}},
+		parts: []int64{512 * mb, 512 * mb, 256 * mb},
+	}
+	uploadService := &UploadService{
+		client:          failingClient{},
+		bucketService:   &staticBucketService{bucket: types.Bucket{Name: "archive", Region: "eu-west-1"}},
+		concurrency:     2,
+		regionalClients: map[string]S3UploadClientInterface{"eu-west-1": regional},
+	}
+	sizeService := NewSizeServiceWithCache(uploadService, 2, nil, nil)
+	progress := &syncBuffer{}
+	sizeService.SetProgressWriter(progress)
+
+	ctx := context.Background()
+	uploads, err := uploadService.ListUploads(ctx, types.ListOptions{})
+	if err != nil {
+		t.Fatalf("ListUploads() error = %v", err)
+	}
+	if len(uploads) != 1 || uploads[0].Size != 0 {
+		t.Fatalf("ListUploads() = %+v, want one unsized upload", uploads)
+	}
+
+	uploads, inaccessible, err := sizeService.HydrateUploadSizes(ctx, uploads)
+	if err != nil {
+		t.Fatalf("HydrateUploadSizes() error = %v", err)
+	}
+	if len(inaccessible) != 0 {
+		t.Fatalf("inaccessible buckets = %v, want sizes listed through the regional client", inaccessible)
+	}
+	if len(uploads) != 1 || uploads[0].Size != 1280*mb {
+		t.Fatalf("hydrated uploads = %+v, want size %d", uploads, 1280*mb)
+	}
+	if !strings.Contains(progress.String(), "Calculating upload sizes: 1/1") {
+		t.Errorf("progress output = %q, want a sizing progress line", progress.String())
+	}
+
+	breakdown, err := NewCostService().CalculateStorageCost(ctx, uploads)
+	if err != nil {
+		t.Fatalf("CalculateStorageCost() error = %v", err)
+	}
+	if breakdown.TotalMonthlyCost <= 0 {
+		t.Errorf("TotalMonthlyCost = %v, want a non-zero cost for 1.25GB of parts", breakdown.TotalMonthlyCost)
+	}
+}
+
+func TestScanLogsDebugEventsPerBucket(t *testing.T) {
+	regional := &mockPartsClient{
+		uploads: []s3types.MultipartUpload{
+			{Key: aws.String("a.tar"), UploadId: aws.String("upload-1"), Initiated: aws.Time(time.Now())},
+			{Key: aws.String("b.tar"), UploadId: aws.String("upload-2"), Initiated: aws.Time(time.Now())},
+		},
+		parts: []int64{1024, 1024},
+	}
+	logs := &syncBuffer{}
+	logger := logging.NewJSONLogger(logs, logging.LevelDebug)
+	uploadService := &UploadService{
+		client:          failingClient{},
+		bucketService:   &staticBucketService{bucket: types.Bucket{Name: "archive", Region: "eu-west-1"}},
+		concurrency:     2,
+		regionalClients: map[string]S3UploadClientInterface{"eu-west-1": regional},
+		logger:          logger,
+	}
+
+	ctx := context.Background()
+	uploads, err := uploadService.ListUploads(ctx, types.ListOptions{})
+	if err != nil {
+		t.Fatalf("ListUploads() error = %v", err)
+	}
+	if _, _, err := NewSizeServiceWithCache(uploadService, 2, nil, logger).HydrateUploadSizes(ctx, uploads); err != nil {
+		t.Fatalf("HydrateUploadSizes() error = %v", err)
+	}
+
+	events := make(map[string]map[string]interface{})
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry struct {
+			Msg    string                 `json:"msg"`
+			Fields map[string]interface{} `json:"fields"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line is not valid JSON: %v\n%s", err, line)
+		}
+		events[entry.Msg] = entry.Fields
+	}
+
+	want := map[string]map[string]interface{}{
+		"Listing uploads in bucket":       {"bucket": "archive", "region": "eu-west-1"},
+		"Listed page of uploads":          {"bucket": "archive", "region": "eu-west-1", "page": float64(1), "uploads": float64(2)},
+		"Listed uploads in bucket":        {"bucket": "archive", "pages": float64(1), "uploads": float64(2)},
+		"Measured upload sizes in bucket": {"bucket": "archive", "uploads": float64(2), "measured": float64(2)},
+		"Measured upload sizes":           {"buckets": float64(1), "uploads": float64(2), "measured": float64(2)},
+	}
+	for msg, fields := range want {
+		got, logged := events[msg]
+		if !logged {
+			t.Errorf("%q was not logged; got:\n%s", msg, logs.String())
+			continue
+		}
+		for name, value := range fields {
+			if got[name] != value {
+				t.Errorf("%q %s = %v, want %v", msg, name, got[name], value)
+			}
+		}
+	}
+	for _, msg := range []string{"Listed page of uploads", "Listed uploads in bucket", "Measured upload sizes"} {
+		if _, timed := events[msg]["elapsed"]; !timed {
+			if _, timed := events[msg]["duration"]; !timed {
+				t.Errorf("%q has no elapsed time or duration", msg)
+			}
+		}
+	}
+}
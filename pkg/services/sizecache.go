@@ -0,0 +1,246 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// SizeStalenessWarning is the measurement age past which delete summaries warn
+// that sizes may be stale, since cached sizes are never expired
+const SizeStalenessWarning = 15 * time.Minute
+
+// sizeCacheKey identifies an upload in the size cache
+type sizeCacheKey struct {
+	Bucket   string
+	Key      string
+	UploadID string
+}
+
+// sizeCacheEntry is a persisted upload size
+type sizeCacheEntry struct {
+	Bucket       string     `json:"bucket"`
+	Key          string     `json:"key"`
+	UploadID     string     `json:"upload_id"`
+	Size         int64      `json:"size"`
+	PartsCount   int        `json:"parts_count,omitempty"` // missing from entries written before parts were counted
+	AbortDate    *time.Time `json:"abort_date,omitempty"`
+	AbortRuleID  string     `json:"abort_rule_id,omitempty"`
+	AbortChecked bool       `json:"abort_checked,omitempty"` // missing from entries written before abort dates were looked up
+	MeasuredAt   time.Time  `json:"measured_at"`
+}
+
+// sizeCacheFile is the on-disk layout of the size cache
+type sizeCacheFile struct {
+	Entries []sizeCacheEntry `json:"entries"`
+}
+
+// SizeCache remembers upload sizes so ListParts is walked once per upload.
+// Parts of an incomplete upload are only ever added, so a cached size is at
+// worst slightly low; entries are never expired by age, but those of uploads
+// that were deleted or no longer listed are removed.
+type SizeCache struct {
+	path    string // empty for an in-memory cache
+	entries map[sizeCacheKey]sizeCacheEntry
+	mutex   sync.RWMutex
+	loaded  bool
+	dirty   bool
+}
+
+// NewSizeCache creates a size cache persisted at path, or in memory only when path is empty
+func NewSizeCache(path string) *SizeCache {
+	return &SizeCache{
+		path:    path,
+		entries: make(map[sizeCacheKey]sizeCacheEntry),
+	}
+}
+
+// DefaultSizeCachePath returns the default location of the persisted size cache
+func DefaultSizeCachePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".s3mpc", "cache.db")
+	}
+	return filepath.Join(home, ".s3mpc", "cache.db")
+}
+
+// Get returns the cached size of an upload
+func (c *SizeCache) Get(upload types.MultipartUpload) (int64, bool) {
+	size, _, exists := c.Lookup(upload)
+	return size, exists
+}
+
+// Lookup returns the cached size of an upload and when it was measured
+func (c *SizeCache) Lookup(upload types.MultipartUpload) (int64, time.Time, bool) {
+	detail, measuredAt, exists := c.LookupDetail(upload)
+	return detail.Size, measuredAt, exists
+}
+
+// LookupDetail returns the cached size, parts count and abort date of an
+// upload and when they were measured
+func (c *SizeCache) LookupDetail(upload types.MultipartUpload) (types.UploadDetail, time.Time, bool) {
+	c.load()
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	entry, exists := c.entries[sizeCacheKey{upload.Bucket, upload.Key, upload.UploadID}]
+	return types.UploadDetail{Size: entry.Size, PartsCount: entry.PartsCount, AbortDate: entry.AbortDate, AbortRuleID: entry.AbortRuleID, AbortChecked: entry.AbortChecked}, entry.MeasuredAt, exists
+}
+
+// Set stores the size of an upload, with its PartsCount and abort date,
+// measured at upload.MeasuredAt or now if unset
+func (c *SizeCache) Set(upload types.MultipartUpload, size int64) {
+	c.load()
+
+	measuredAt := upload.MeasuredAt
+	if measuredAt.IsZero() {
+		measuredAt = time.Now()
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries[sizeCacheKey{upload.Bucket, upload.Key, upload.UploadID}] = sizeCacheEntry{
+		Bucket:       upload.Bucket,
+		Key:          upload.Key,
+		UploadID:     upload.UploadID,
+		Size:         size,
+		PartsCount:   upload.PartsCount,
+		AbortDate:    upload.AbortDate,
+		AbortRuleID:  upload.AbortRuleID,
+		AbortChecked: true,
+		MeasuredAt:   measuredAt.UTC(),
+	}
+	c.dirty = true
+}
+
+// Delete removes the cached size of an upload, once it has been aborted
+func (c *SizeCache) Delete(upload types.MultipartUpload) {
+	c.load()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := sizeCacheKey{upload.Bucket, upload.Key, upload.UploadID}
+	if _, exists := c.entries[key]; exists {
+		delete(c.entries, key)
+		c.dirty = true
+	}
+}
+
+// Prune removes the cached sizes of uploads that are no longer listed: the
+// entries of the buckets of uploads that are not in uploads. Other buckets
+// may not have been listed, so their entries are kept. An upload a listing
+// left out by prefix is measured again the next time it is listed.
+func (c *SizeCache) Prune(uploads []types.MultipartUpload) {
+	c.load()
+
+	listed := make(map[sizeCacheKey]bool, len(uploads))
+	buckets := make(map[string]bool)
+	for _, upload := range uploads {
+		listed[sizeCacheKey{upload.Bucket, upload.Key, upload.UploadID}] = true
+		buckets[upload.Bucket] = true
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key := range c.entries {
+		if buckets[key.Bucket] && !listed[key] {
+			delete(c.entries, key)
+			c.dirty = true
+		}
+	}
+}
+
+// Save writes the cache file atomically if anything changed
+func (c *SizeCache) Save() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.path == "" || !c.dirty {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create size cache directory: %w", err)
+	}
+
+	file := sizeCacheFile{Entries: make([]sizeCacheEntry, 0, len(c.entries))}
+	for _, entry := range c.entries {
+		file.Entries = append(file.Entries, entry)
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to encode size cache: %w", err)
+	}
+
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write size cache: %w", err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return fmt.Errorf("failed to write size cache: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// Clear removes all cached sizes, including the cache file
+func (c *SizeCache) Clear() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = make(map[sizeCacheKey]sizeCacheEntry)
+	c.loaded = true
+	c.dirty = false
+
+	if c.path == "" {
+		return nil
+	}
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove size cache %s: %w", c.path, err)
+	}
+	return nil
+}
+
+// Path returns the cache file location, or "" for an in-memory cache
+func (c *SizeCache) Path() string {
+	return c.path
+}
+
+// load reads the cache file once; a missing or corrupt file starts an empty cache
+func (c *SizeCache) load() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+
+	if c.path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return
+	}
+
+	var file sizeCacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return
+	}
+	for _, entry := range file.Entries {
+		c.entries[sizeCacheKey{entry.Bucket, entry.Key, entry.UploadID}] = entry
+	}
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// countingUploadService implements interfaces.UploadService with fixed sizes
+type countingUploadService struct {
+	uploads     []types.MultipartUpload
+	failBuckets map[string]bool // buckets whose parts cannot be listed
+	denyList    map[string]bool // buckets whose uploads cannot be listed
+	listCalls   int64
+	sizeCalls   int64
+}
+
+func (m *countingUploadService) ListUploads(ctx context.Context, opts types.ListOptions) ([]types.MultipartUpload, error) {
+	atomic.AddInt64(&m.listCalls, 1)
+	if opts.BucketName == "" {
+		return m.uploads, nil
+	}
+	if m.denyList[opts.BucketName] {
+		return nil, fmt.Errorf("access denied listing uploads in %s", opts.BucketName)
+	}
+	var uploads []types.MultipartUpload
+	for _, upload := range m.uploads {
+		if upload.Bucket == opts.BucketName {
+			uploads = append(uploads, upload)
+		}
+	}
+	return uploads, nil
+}
+
+func (m *countingUploadService) WalkUploads(ctx context.Context, opts types.ListOptions, fn func(types.MultipartUpload) error) error {
+	atomic.AddInt64(&m.listCalls, 1)
+	for _, upload := range m.uploads {
+		if err := fn(upload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *countingUploadService) GetObjectLastModified(ctx context.Context, upload types.MultipartUpload) (time.Time, bool, error) {
+	return time.Time{}, false, nil
+}
+
+func (m *countingUploadService) SkippedBuckets() []types.SkippedBucket {
+	return nil
+}
+
+func (m *countingUploadService) DeleteUpload(ctx context.Context, upload types.MultipartUpload) error {
+	return nil
+}
+
+func (m *countingUploadService) GetUploadSize(ctx context.Context, upload types.MultipartUpload) (int64, error) {
+	detail, err := m.GetUploadDetail(ctx, upload)
+	return detail.Size, err
+}
+
+func (m *countingUploadService) GetUploadDetail(ctx context.Context, upload types.MultipartUpload) (types.UploadDetail, error) {
+	atomic.AddInt64(&m.sizeCalls, 1)
+	if m.failBuckets[upload.Bucket] {
+		return types.UploadDetail{}, fmt.Errorf("access denied listing parts in %s", upload.Bucket)
+	}
+	return types.UploadDetail{Size: int64(len(upload.Key)) * 1024, PartsCount: len(upload.Key)}, nil
+}
+
+func (m *countingUploadService) GetUploadDetailWithParts(ctx context.Context, upload types.MultipartUpload) (types.UploadDetail, error) {
+	detail, err := m.GetUploadDetail(ctx, upload)
+	for i := 0; i < detail.PartsCount; i++ {
+		detail.Parts = append(detail.Parts, types.UploadPart{PartNumber: int32(i + 1), Size: 1024})
+	}
+	return detail, err
+}
+
+func (m *countingUploadService) DeleteUploads(ctx context.Context, uploads []types.MultipartUpload, opts types.DeleteOptions) error {
+	return nil
+}
+
+func (m *countingUploadService) FindRemainingUploads(ctx context.Context, uploads []types.MultipartUpload) ([]types.MultipartUpload, []types.MultipartUpload, error) {
+	return uploads, nil, nil
+}
+
+func TestSizeCachePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	upload := types.MultipartUpload{Bucket: "b", Key: "k", UploadID: "u"}
+
+	cache := NewSizeCache(path)
+	cache.Set(upload, 42)
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reloaded := NewSizeCache(path)
+	if size, ok := reloaded.Get(upload); !ok || size != 42 {
+		t.Errorf("Get() = %d, %v after reload, want 42, true", size, ok)
+	}
+	if _, ok := reloaded.Get(types.MultipartUpload{Bucket: "b", Key: "k", UploadID: "other"}); ok {
+		t.Error("Get() found an entry for a different upload ID")
+	}
+
+	if err := reloaded.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("cache file still exists after Clear(): %v", err)
+	}
+	if _, ok := NewSizeCache(path).Get(upload); ok {
+		t.Error("Get() found an entry after Clear()")
+	}
+}
+
+func TestSizeCacheForgetsGoneUploads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	kept := types.MultipartUpload{Bucket: "b", Key: "kept", UploadID: "1"}
+	completed := types.MultipartUpload{Bucket: "b", Key: "completed", UploadID: "2"}
+	aborted := types.MultipartUpload{Bucket: "b", Key: "aborted", UploadID: "3"}
+	unlisted := types.MultipartUpload{Bucket: "other", Key: "k", UploadID: "4"}
+
+	cache := NewSizeCache(path)
+	for _, upload := range []types.MultipartUpload{kept, completed, aborted, unlisted} {
+		cache.Set(upload, 42)
+	}
+	cache.Delete(aborted)
+	// Only bucket b was listed, so the entry of the other bucket stays
+	cache.Prune([]types.MultipartUpload{kept, aborted})
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reloaded := NewSizeCache(path)
+	for _, tt := range []struct {
+		upload types.MultipartUpload
+		want   bool
+	}{{kept, true}, {completed, false}, {aborted, false}, {unlisted, true}} {
+		if _, ok := reloaded.Get(tt.upload); ok != tt.want {
+			t.Errorf("Get(%s) found = %v, want %v", tt.upload.Key, ok, tt.want)
+		}
+	}
+}
+
+func TestSizeServiceReusesCachedSizes(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "b", Key: "one", UploadID: "1", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "b", Key: "three", UploadID: "3", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+	uploadService := &countingUploadService{uploads: uploads}
+	cache := NewSizeCache(filepath.Join(t.TempDir(), "cache.db"))
+	service := NewSizeServiceWithCache(uploadService, 2, cache, nil)
+
+	report, err := service.CalculateTotalSize(context.Background(), types.ListOptions{})
+	if err != nil {
+		t.Fatalf("CalculateTotalSize() error = %v", err)
+	}
+	if report.TotalSize != 8*1024 {
+		t.Errorf("TotalSize = %d, want %d", report.TotalSize, 8*1024)
+	}
+
+	// A second command in the same process, such as cost, reuses the sizes
+	hydrated, _, err := service.HydrateUploadSizes(context.Background(), uploads)
+	if err != nil {
+		t.Fatalf("HydrateUploadSizes() error = %v", err)
+	}
+	if len(hydrated) != 2 || hydrated[0].Size+hydrated[1].Size != 8*1024 || hydrated[0].PartsCount+hydrated[1].PartsCount != 8 {
+		t.Errorf("HydrateUploadSizes() = %+v, want cached sizes and parts counts", hydrated)
+	}
+	if uploadService.sizeCalls != 2 {
+		t.Errorf("GetUploadSize called %d times, want 2", uploadService.sizeCalls)
+	}
+
+	// Without a cache every call walks the parts again
+	uncached := NewSizeServiceWithCache(uploadService, 2, nil, nil)
+	if _, _, err := uncached.HydrateUploadSizes(context.Background(), uploads); err != nil {
+		t.Fatal(err)
+	}
+	if uploadService.sizeCalls != 4 {
+		t.Errorf("GetUploadSize called %d times without cache, want 4", uploadService.sizeCalls)
+	}
+
+	// The cache holds no parts, so listing them walks every upload once
+	withParts, _, err := service.HydrateUploadParts(context.Background(), uploads)
+	if err != nil {
+		t.Fatalf("HydrateUploadParts() error = %v", err)
+	}
+	if uploadService.sizeCalls != 6 || len(withParts) != 2 || len(withParts[0].Parts)+len(withParts[1].Parts) != 8 {
+		t.Errorf("HydrateUploadParts() = %+v after %d walks, want every part from 6", withParts, uploadService.sizeCalls)
+	}
+}
+
+func TestSizeServiceRecordsMeasuredAt(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "b", Key: "cached", UploadID: "1", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "b", Key: "fresh", UploadID: "2", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+	uploadService := &countingUploadService{uploads: uploads}
+	cache := NewSizeCache(filepath.Join(t.TempDir(), "cache.db"))
+	service := NewSizeServiceWithCache(uploadService, 2, cache, nil)
+
+	// A size cached by an earlier run keeps the time it was measured
+	measuredEarlier := time.Now().Add(-32 * time.Minute).UTC().Truncate(time.Second)
+	earlier := uploads[0]
+	earlier.MeasuredAt = measuredEarlier
+	earlier.PartsCount = 1
+	cache.Set(earlier, 1024)
+
+	before := time.Now()
+	hydrated, _, err := service.HydrateUploadSizes(context.Background(), uploads)
+	if err != nil {
+		t.Fatalf("HydrateUploadSizes() error = %v", err)
+	}
+
+	measured := make(map[string]types.MultipartUpload)
+	for _, upload := range hydrated {
+		measured[upload.Key] = upload
+	}
+	if got := measured["cached"].MeasuredAt; !got.Equal(measuredEarlier) {
+		t.Errorf("cache hit MeasuredAt = %v, want %v", got, measuredEarlier)
+	}
+	if got := measured["fresh"].MeasuredAt; got.Before(before.Add(-time.Second)) || got.After(time.Now()) {
+		t.Errorf("fresh measurement MeasuredAt = %v, want about now", got)
+	}
+	if uploadService.sizeCalls != 1 {
+		t.Errorf("GetUploadSize called %d times, want 1", uploadService.sizeCalls)
+	}
+	if oldest := types.OldestMeasurement(hydrated); !oldest.Equal(measuredEarlier) {
+		t.Errorf("OldestMeasurement() = %v, want %v", oldest, measuredEarlier)
+	}
+
+	// Remeasuring only refetches the stale size, bypassing the cache
+	remeasured, err := service.RemeasureStaleSizes(context.Background(), hydrated, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("RemeasureStaleSizes() error = %v", err)
+	}
+	if uploadService.sizeCalls != 2 {
+		t.Errorf("GetUploadSize called %d times after remeasuring, want 2", uploadService.sizeCalls)
+	}
+	for _, upload := range remeasured {
+		if time.Since(upload.MeasuredAt) > time.Minute {
+			t.Errorf("%s MeasuredAt = %v after remeasuring, want a fresh measurement", upload.Key, upload.MeasuredAt)
+		}
+		if want := int64(len(upload.Key)) * 1024; upload.Size != want {
+			t.Errorf("%s Size = %d after remeasuring, want %d", upload.Key, upload.Size, want)
+		}
+	}
+	if size, measuredAt, _ := cache.Lookup(uploads[0]); size != 6*1024 || measuredAt.Equal(measuredEarlier) {
+		t.Errorf("cache Lookup() = %d, %v, want the remeasured size", size, measuredAt)
+	}
+}
+
+func TestSizeServiceRemeasuresEntriesWithoutPartsCount(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "b", Key: "legacy", UploadID: "1", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+	uploadService := &countingUploadService{uploads: uploads}
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	// Cache files from before parts were counted have sizes only
+	legacy := `{"entries":[{"bucket":"b","key":"legacy","upload_id":"1","size":1,"measured_at":"2024-05-01T12:00:00Z"}]}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache := NewSizeCache(path)
+	service := NewSizeServiceWithCache(uploadService, 1, cache, nil)
+
+	hydrated, _, err := service.HydrateUploadSizes(context.Background(), uploads)
+	if err != nil {
+		t.Fatalf("HydrateUploadSizes() error = %v", err)
+	}
+	if uploadService.sizeCalls != 1 || len(hydrated) != 1 || hydrated[0].PartsCount != 6 || hydrated[0].Size != 6*1024 {
+		t.Errorf("HydrateUploadSizes() = %+v after %d measurements, want the legacy entry measured again", hydrated, uploadService.sizeCalls)
+	}
+	if detail, _, _ := cache.LookupDetail(uploads[0]); detail.PartsCount != 6 {
+		t.Errorf("cache LookupDetail() = %+v, want the parts count cached", detail)
+	}
+}
+
+func TestSizeServiceRemeasuresEntriesWithoutAbortCheck(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "b", Key: "legacy", UploadID: "1", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+	uploadService := &countingUploadService{uploads: uploads}
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	// Without abort_checked, a missing abort date may only mean it was never looked up
+	legacy := `{"entries":[{"bucket":"b","key":"legacy","upload_id":"1","size":6144,"parts_count":6,"measured_at":"2024-05-01T12:00:00Z"}]}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache := NewSizeCache(path)
+	service := NewSizeServiceWithCache(uploadService, 1, cache, nil)
+
+	if _, _, err := service.HydrateUploadSizes(context.Background(), uploads); err != nil {
+		t.Fatalf("HydrateUploadSizes() error = %v", err)
+	}
+	if uploadService.sizeCalls != 1 {
+		t.Errorf("measured %d times, want the entry without an abort check measured again", uploadService.sizeCalls)
+	}
+	if detail, _, _ := cache.LookupDetail(uploads[0]); !detail.AbortChecked {
+		t.Errorf("cache LookupDetail() = %+v, want the abort date recorded as checked", detail)
+	}
+
+	// Once checked, the entry is reused
+	if _, _, err := service.HydrateUploadSizes(context.Background(), uploads); err != nil {
+		t.Fatalf("HydrateUploadSizes() error = %v", err)
+	}
+	if uploadService.sizeCalls != 1 {
+		t.Errorf("measured %d times, want the checked entry reused", uploadService.sizeCalls)
+	}
+}
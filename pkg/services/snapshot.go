@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+	"github.com/Garvitkul/s3mpc/pkg/units"
+)
+
+// GenerateSnapshot scans uploads the same way as GenerateStats and totals them
+// by bucket, returning partial results the same way too. The buckets that
+// could not be listed count as inaccessible, so a diff does not take their
+// uploads for cleaned up.
+func (r *ReportService) GenerateSnapshot(ctx context.Context, opts types.ListOptions) (*types.Snapshot, error) {
+	uploads, inaccessibleBuckets, failed, err := r.scan(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, bucket := range failed {
+		inaccessibleBuckets = append(inaccessibleBuckets, bucket.Bucket)
+	}
+
+	return r.SnapshotForUploads(uploads, inaccessibleBuckets), partialResult(failed)
+}
+
+// SnapshotForUploads totals uploads by bucket, taken now. The caller fills in
+// the version, profile and region.
+func (r *ReportService) SnapshotForUploads(uploads []types.MultipartUpload, inaccessibleBuckets []string) *types.Snapshot {
+	byBucket := make(map[string]*types.SnapshotBucket)
+	snapshot := &types.Snapshot{
+		SchemaVersion:       types.SchemaVersion,
+		Kind:                types.SnapshotKind,
+		TakenAt:             time.Now().UTC(),
+		Buckets:             []types.SnapshotBucket{},
+		InaccessibleBuckets: inaccessibleBuckets,
+	}
+	for _, upload := range uploads {
+		bucket, ok := byBucket[upload.Bucket]
+		if !ok {
+			bucket = &types.SnapshotBucket{Bucket: upload.Bucket}
+			byBucket[upload.Bucket] = bucket
+		}
+		bucket.Count++
+		bucket.Size += upload.Size
+		snapshot.TotalCount++
+		snapshot.TotalSize += upload.Size
+	}
+
+	for _, bucket := range byBucket {
+		snapshot.Buckets = append(snapshot.Buckets, *bucket)
+	}
+	sort.Slice(snapshot.Buckets, func(i, j int) bool {
+		return snapshot.Buckets[i].Bucket < snapshot.Buckets[j].Bucket
+	})
+	return snapshot
+}
+
+// LoadSnapshot reads a snapshot written by snapshot save, refusing files
+// written by a newer version of s3mpc and files whose totals do not add up
+func LoadSnapshot(filename string) (*types.Snapshot, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	if data, err = gunzipIfCompressed(data); err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", filename, err)
+	}
+
+	var snapshot types.Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %s: %w", filename, err)
+	}
+	if err := checkSchemaVersion(filename, snapshot.SchemaVersion); err != nil {
+		return nil, err
+	}
+	if err := snapshot.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", filename, err)
+	}
+	return &snapshot, nil
+}
+
+// DiffSnapshots compares an older snapshot with a newer one. Buckets the newer
+// scan could not read are not counted as cleaned. At most top growing buckets
+// are kept; top <= 0 keeps them all.
+func DiffSnapshots(from, to types.Snapshot, top int) types.SnapshotDiff {
+	diff := types.SnapshotDiff{
+		From:           from.TakenAt,
+		To:             to.TakenAt,
+		FromCount:      from.TotalCount,
+		ToCount:        to.TotalCount,
+		FromSize:       from.TotalSize,
+		ToSize:         to.TotalSize,
+		CountChange:    to.TotalCount - from.TotalCount,
+		SizeChange:     to.TotalSize - from.TotalSize,
+		NewBuckets:     []types.SnapshotBucketChange{},
+		CleanedBuckets: []types.SnapshotBucketChange{},
+		TopGrowing:     []types.SnapshotBucketChange{},
+	}
+
+	change := func(bucket string, before, after types.SnapshotBucket) types.SnapshotBucketChange {
+		return types.SnapshotBucketChange{
+			Bucket:      bucket,
+			FromCount:   before.Count,
+			ToCount:     after.Count,
+			FromSize:    before.Size,
+			ToSize:      after.Size,
+			SizeChange:  after.Size - before.Size,
+			CountChange: after.Count - before.Count,
+		}
+	}
+
+	before := make(map[string]types.SnapshotBucket, len(from.Buckets))
+	for _, bucket := range from.Buckets {
+		before[bucket.Bucket] = bucket
+	}
+	unreadable := make(map[string]bool, len(to.InaccessibleBuckets))
+	for _, bucket := range to.InaccessibleBuckets {
+		unreadable[bucket] = true
+	}
+
+	for _, bucket := range to.Buckets {
+		old, existed := before[bucket.Bucket]
+		delete(before, bucket.Bucket)
+		switch {
+		case !existed || old.Count == 0:
+			diff.NewBuckets = append(diff.NewBuckets, change(bucket.Bucket, old, bucket))
+		case bucket.Size > old.Size || (bucket.Size == old.Size && bucket.Count > old.Count):
+			diff.TopGrowing = append(diff.TopGrowing, change(bucket.Bucket, old, bucket))
+		}
+	}
+	// What is left had uploads before and has none now
+	for _, bucket := range from.Buckets {
+		if _, gone := before[bucket.Bucket]; gone && bucket.Count > 0 && !unreadable[bucket.Bucket] {
+			diff.CleanedBuckets = append(diff.CleanedBuckets, change(bucket.Bucket, bucket, types.SnapshotBucket{}))
+		}
+	}
+
+	sort.Slice(diff.NewBuckets, func(i, j int) bool {
+		return diff.NewBuckets[i].ToSize > diff.NewBuckets[j].ToSize
+	})
+	sort.Slice(diff.CleanedBuckets, func(i, j int) bool {
+		return diff.CleanedBuckets[i].FromSize > diff.CleanedBuckets[j].FromSize
+	})
+	sort.SliceStable(diff.TopGrowing, func(i, j int) bool {
+		a, b := diff.TopGrowing[i], diff.TopGrowing[j]
+		if a.SizeChange != b.SizeChange {
+			return a.SizeChange > b.SizeChange
+		}
+		return a.CountChange > b.CountChange
+	})
+	if top > 0 && len(diff.TopGrowing) > top {
+		diff.TopGrowing = diff.TopGrowing[:top]
+	}
+	return diff
+}
+
+// FormatSnapshotDiff summarizes the net change, then lists new, cleaned and
+// growing buckets
+func FormatSnapshotDiff(diff types.SnapshotDiff) string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Comparing %s with %s (%s apart)\n\n",
+		diff.From.Local().Format("2006-01-02 15:04"), diff.To.Local().Format("2006-01-02 15:04"), units.FormatDuration(diff.To.Sub(diff.From))))
+	result.WriteString(fmt.Sprintf("Uploads: %s -> %s (%s)\n", formatCount(diff.FromCount), formatCount(diff.ToCount), signedCount(diff.CountChange)))
+	result.WriteString(fmt.Sprintf("Size:    %s -> %s (%s)\n", units.FormatBytes(diff.FromSize), units.FormatBytes(diff.ToSize), signedBytes(diff.SizeChange)))
+
+	writeBuckets := func(title string, changes []types.SnapshotBucketChange) {
+		result.WriteString(fmt.Sprintf("\n%s: %d\n", title, len(changes)))
+		for _, change := range changes {
+			result.WriteString(fmt.Sprintf("  %s: %s uploads, %s -> %s uploads, %s (%s)\n",
+				types.DisplayString(change.Bucket),
+				formatCount(change.FromCount), units.FormatBytes(change.FromSize),
+				formatCount(change.ToCount), units.FormatBytes(change.ToSize),
+				signedBytes(change.SizeChange)))
+		}
+	}
+	writeBuckets("New buckets with uploads", diff.NewBuckets)
+	writeBuckets("Cleaned buckets", diff.CleanedBuckets)
+	writeBuckets("Top growing buckets", diff.TopGrowing)
+	return result.String()
+}
+
+// signedCount formats a change in count with its sign, e.g. +12 or -3
+func signedCount(change int) string {
+	if change < 0 {
+		return "-" + formatCount(-change)
+	}
+	return "+" + formatCount(change)
+}
+
+// signedBytes formats a change in size with its sign, e.g. +1.5 GB
+func signedBytes(change int64) string {
+	if change < 0 {
+		return "-" + units.FormatBytes(-change)
+	}
+	return "+" + units.FormatBytes(change)
+}
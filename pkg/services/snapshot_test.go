@@ -0,0 +1,131 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// snapshotOf builds a valid snapshot taken at takenAt from buckets
+func snapshotOf(takenAt time.Time, buckets ...types.SnapshotBucket) types.Snapshot {
+	snapshot := types.Snapshot{SchemaVersion: types.SchemaVersion, Kind: types.SnapshotKind, TakenAt: takenAt, Buckets: buckets}
+	for _, bucket := range buckets {
+		snapshot.TotalCount += bucket.Count
+		snapshot.TotalSize += bucket.Size
+	}
+	return snapshot
+}
+
+func TestSnapshotForUploads(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "media", Size: 300},
+		{Bucket: "logs", Size: 100},
+		{Bucket: "media", Size: 200},
+	}
+	snapshot := (&ReportService{}).SnapshotForUploads(uploads, nil)
+
+	if err := snapshot.Validate(); err != nil {
+		t.Fatalf("SnapshotForUploads() is invalid: %v", err)
+	}
+	want := []types.SnapshotBucket{{Bucket: "logs", Count: 1, Size: 100}, {Bucket: "media", Count: 2, Size: 500}}
+	if len(snapshot.Buckets) != len(want) || snapshot.Buckets[0] != want[0] || snapshot.Buckets[1] != want[1] {
+		t.Errorf("Buckets = %+v, want %+v", snapshot.Buckets, want)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	week := 7 * 24 * time.Hour
+	from := snapshotOf(time.Now().Add(-week),
+		types.SnapshotBucket{Bucket: "backups", Count: 2, Size: 1000},
+		types.SnapshotBucket{Bucket: "logs", Count: 5, Size: 500},
+		types.SnapshotBucket{Bucket: "media", Count: 1, Size: 100},
+		types.SnapshotBucket{Bucket: "private", Count: 1, Size: 50},
+		types.SnapshotBucket{Bucket: "tmp", Count: 3, Size: 300},
+	)
+	to := snapshotOf(time.Now(),
+		types.SnapshotBucket{Bucket: "backups", Count: 2, Size: 1000},
+		types.SnapshotBucket{Bucket: "logs", Count: 6, Size: 900},
+		types.SnapshotBucket{Bucket: "media", Count: 4, Size: 2000},
+		types.SnapshotBucket{Bucket: "uploads", Count: 1, Size: 700},
+	)
+	to.InaccessibleBuckets = []string{"private"}
+
+	diff := DiffSnapshots(from, to, 1)
+	if diff.SizeChange != 4600-1950 || diff.CountChange != 13-12 {
+		t.Errorf("net change = %d uploads, %d bytes, want 1, 2650", diff.CountChange, diff.SizeChange)
+	}
+	if len(diff.NewBuckets) != 1 || diff.NewBuckets[0].Bucket != "uploads" {
+		t.Errorf("NewBuckets = %+v, want uploads", diff.NewBuckets)
+	}
+	// private could not be read, so it is not known to be clean
+	if len(diff.CleanedBuckets) != 1 || diff.CleanedBuckets[0].Bucket != "tmp" || diff.CleanedBuckets[0].SizeChange != -300 {
+		t.Errorf("CleanedBuckets = %+v, want tmp", diff.CleanedBuckets)
+	}
+	if len(diff.TopGrowing) != 1 || diff.TopGrowing[0].Bucket != "media" {
+		t.Errorf("TopGrowing = %+v, want only media with --top 1", diff.TopGrowing)
+	}
+
+	out := FormatSnapshotDiff(diff)
+	for _, want := range []string{"Uploads: 12 -> 13 (+1)", "New buckets with uploads: 1", "tmp: 3 uploads", "Top growing buckets: 1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("FormatSnapshotDiff() missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSnapshotValidate(t *testing.T) {
+	valid := snapshotOf(time.Now(), types.SnapshotBucket{Bucket: "logs", Count: 1, Size: 10})
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	tests := map[string]func(s *types.Snapshot){
+		"wrong kind":   func(s *types.Snapshot) { s.Kind = types.DeletionReportKind },
+		"newer schema": func(s *types.Snapshot) { s.SchemaVersion = types.SchemaVersion + 1 },
+		"no timestamp": func(s *types.Snapshot) { s.TakenAt = time.Time{} },
+		"totals off":   func(s *types.Snapshot) { s.TotalSize++ },
+		"duplicate bucket": func(s *types.Snapshot) {
+			s.Buckets = append(s.Buckets, s.Buckets[0])
+			s.TotalCount++
+			s.TotalSize += 10
+		},
+		"negative size": func(s *types.Snapshot) { s.Buckets[0].Size = -10; s.TotalSize = -10 },
+	}
+	for name, corrupt := range tests {
+		snapshot := snapshotOf(valid.TakenAt, valid.Buckets[0])
+		corrupt(&snapshot)
+		if err := snapshot.Validate(); err == nil {
+			t.Errorf("%s: Validate() succeeded, want an error", name)
+		}
+	}
+}
+
+func TestLoadSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	snapshot := snapshotOf(time.Now().UTC().Truncate(time.Second), types.SnapshotBucket{Bucket: "logs", Count: 1, Size: 10})
+	data, err := NewOutputFormatter().FormatJSON(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "snapshot.json")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSnapshot(path)
+	if err != nil || !loaded.TakenAt.Equal(snapshot.TakenAt) || loaded.TotalSize != 10 {
+		t.Fatalf("LoadSnapshot() = %+v, %v", loaded, err)
+	}
+
+	// An export is not a snapshot
+	if err := os.WriteFile(path, []byte(`{"schema_version": 1, "uploads": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSnapshot(path); err == nil || !strings.Contains(err.Error(), "not a snapshot") {
+		t.Errorf("LoadSnapshot(export) error = %v, want not a snapshot", err)
+	}
+}
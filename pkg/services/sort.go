@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Garvitkul/s3mpc/pkg/interfaces"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// SortFields are the fields accepted by --sort-by
+var SortFields = []string{"age", "initiated", "size", "cost", "bucket", "key", "region", "storage-class"}
+
+// sortDescendingByDefault lists the fields sorted largest first unless :asc is
+// given, so the oldest, largest and most expensive uploads come first
+var sortDescendingByDefault = map[string]bool{"age": true, "size": true, "cost": true}
+
+// UploadSort is a parsed --sort-by value
+type UploadSort struct {
+	Field      string
+	Descending bool
+}
+
+// ParseUploadSort parses a --sort-by value such as size, size:desc or
+// key:asc. descending is the --desc flag, which cannot be combined with :asc.
+func ParseUploadSort(value string, descending bool) (UploadSort, error) {
+	field, direction, hasDirection := strings.Cut(value, ":")
+	if !isSortField(field) {
+		return UploadSort{}, fmt.Errorf("invalid --sort-by field %q, supported: %s", field, strings.Join(SortFields, ", "))
+	}
+
+	order := UploadSort{Field: field, Descending: sortDescendingByDefault[field] || descending}
+	if hasDirection {
+		switch direction {
+		case "asc":
+			if descending {
+				return UploadSort{}, fmt.Errorf("--desc cannot be used with --sort-by %s", value)
+			}
+			order.Descending = false
+		case "desc":
+			order.Descending = true
+		default:
+			return UploadSort{}, fmt.Errorf("invalid --sort-by direction %q, supported: asc, desc", direction)
+		}
+	}
+	return order, nil
+}
+
+// isSortField reports whether field is one of SortFields
+func isSortField(field string) bool {
+	for _, name := range SortFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsSizes reports whether the order depends on measured upload sizes
+func (s UploadSort) NeedsSizes() bool {
+	return s.Field == "size" || s.Field == "cost"
+}
+
+// Less returns the ordering of uploads, breaking ties by bucket, key and
+// upload ID so pages of a listing are stable. monthlyCost prices an upload for
+// the cost field and is not used by the others.
+func (s UploadSort) Less(monthlyCost func(types.MultipartUpload) float64) func(a, b types.MultipartUpload) bool {
+	compare := s.compareField(monthlyCost)
+	return func(a, b types.MultipartUpload) bool {
+		if c := compare(a, b); c != 0 {
+			if s.Descending {
+				return c > 0
+			}
+			return c < 0
+		}
+		if a.Bucket != b.Bucket {
+			return a.Bucket < b.Bucket
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.UploadID < b.UploadID
+	}
+}
+
+// compareField compares two uploads by the sort field in ascending order
+func (s UploadSort) compareField(monthlyCost func(types.MultipartUpload) float64) func(a, b types.MultipartUpload) int {
+	switch s.Field {
+	case "age":
+		// An older upload has a larger age
+		return func(a, b types.MultipartUpload) int { return b.Initiated.Compare(a.Initiated) }
+	case "initiated":
+		return func(a, b types.MultipartUpload) int { return a.Initiated.Compare(b.Initiated) }
+	case "size":
+		return func(a, b types.MultipartUpload) int { return compareOrdered(a.Size, b.Size) }
+	case "cost":
+		return func(a, b types.MultipartUpload) int { return compareOrdered(monthlyCost(a), monthlyCost(b)) }
+	case "bucket":
+		return func(a, b types.MultipartUpload) int { return strings.Compare(a.Bucket, b.Bucket) }
+	case "key":
+		return func(a, b types.MultipartUpload) int { return strings.Compare(a.Key, b.Key) }
+	case "region":
+		return func(a, b types.MultipartUpload) int { return strings.Compare(a.Region, b.Region) }
+	case "storage-class":
+		return func(a, b types.MultipartUpload) int { return strings.Compare(a.StorageClass, b.StorageClass) }
+	}
+	return func(a, b types.MultipartUpload) int { return 0 }
+}
+
+// compareOrdered returns -1, 0 or 1 as a is less than, equal to or greater than b
+func compareOrdered[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// SortUploads returns a copy of uploads sorted by less
+func SortUploads(uploads []types.MultipartUpload, less func(a, b types.MultipartUpload) bool) []types.MultipartUpload {
+	sorted := make([]types.MultipartUpload, len(uploads))
+	copy(sorted, uploads)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// MonthlyCostFunc returns the estimated monthly cost of an upload as the cost
+// command computes it, looking up each region and storage class price once
+func MonthlyCostFunc(ctx context.Context, calculator interfaces.CostCalculator) func(types.MultipartUpload) float64 {
+	pricePerGB := make(map[[2]string]float64)
+	return func(upload types.MultipartUpload) float64 {
+		key := [2]string{upload.Region, upload.StorageClass}
+		price, known := pricePerGB[key]
+		if !known {
+			// Pricing one GB goes through the same fallbacks as the cost command
+			breakdown, err := calculator.CalculateStorageCost(ctx, []types.MultipartUpload{
+				{Size: 1 << 30, Region: upload.Region, StorageClass: upload.StorageClass},
+			})
+			if err == nil {
+				price = breakdown.TotalMonthlyCost
+			}
+			pricePerGB[key] = price
+		}
+		return float64(upload.Size) / (1 << 30) * price
+	}
+}
+
+// EstimateUploadCosts returns a copy of uploads with EstimatedMonthlyCost set
+// from their measured sizes, priced as MonthlyCostFunc prices them
+func EstimateUploadCosts(ctx context.Context, calculator interfaces.CostCalculator, uploads []types.MultipartUpload) []types.MultipartUpload {
+	monthlyCost := MonthlyCostFunc(ctx, calculator)
+	priced := make([]types.MultipartUpload, len(uploads))
+	for i, upload := range uploads {
+		upload.EstimatedMonthlyCost = monthlyCost(upload)
+		priced[i] = upload
+	}
+	return priced
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestParseUploadSort(t *testing.T) {
+	tests := []struct {
+		value      string
+		descending bool
+		want       UploadSort
+	}{
+		{"age", false, UploadSort{Field: "age", Descending: true}},
+		{"key", false, UploadSort{Field: "key"}},
+		{"key", true, UploadSort{Field: "key", Descending: true}},
+		{"size:asc", false, UploadSort{Field: "size"}},
+		{"region:desc", false, UploadSort{Field: "region", Descending: true}},
+		{"storage-class:desc", true, UploadSort{Field: "storage-class", Descending: true}},
+	}
+	for _, test := range tests {
+		got, err := ParseUploadSort(test.value, test.descending)
+		if err != nil || got != test.want {
+			t.Errorf("ParseUploadSort(%q, %v) = %+v, %v, want %+v", test.value, test.descending, got, err, test.want)
+		}
+	}
+
+	for _, invalid := range []string{"owner", "size:up", "", ":desc"} {
+		if _, err := ParseUploadSort(invalid, false); err == nil {
+			t.Errorf("ParseUploadSort(%q) succeeded, want an error", invalid)
+		}
+	}
+	if _, err := ParseUploadSort("size:asc", true); err == nil {
+		t.Error("ParseUploadSort(\"size:asc\", true) succeeded, want an error for the conflicting direction")
+	}
+}
+
+func TestSortUploadsBreaksTies(t *testing.T) {
+	initiated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	uploads := []types.MultipartUpload{
+		{Bucket: "b", Key: "x", UploadID: "2", Size: 10, Initiated: initiated},
+		{Bucket: "a", Key: "y", UploadID: "1", Size: 10, Initiated: initiated.Add(time.Hour)},
+		{Bucket: "b", Key: "x", UploadID: "1", Size: 10, Initiated: initiated},
+		{Bucket: "a", Key: "z", UploadID: "1", Size: 20, Initiated: initiated},
+	}
+
+	tests := []struct {
+		sortBy string
+		want   []string
+	}{
+		{"size", []string{"a/z/1", "a/y/1", "b/x/1", "b/x/2"}},
+		{"size:asc", []string{"a/y/1", "b/x/1", "b/x/2", "a/z/1"}},
+		{"age", []string{"a/z/1", "b/x/1", "b/x/2", "a/y/1"}},
+		{"key:desc", []string{"a/z/1", "a/y/1", "b/x/1", "b/x/2"}},
+	}
+	for _, test := range tests {
+		order, err := ParseUploadSort(test.sortBy, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sorted := SortUploads(uploads, order.Less(nil))
+		for i, upload := range sorted {
+			if got := upload.Bucket + "/" + upload.Key + "/" + upload.UploadID; got != test.want[i] {
+				t.Errorf("%s: position %d is %s, want %s", test.sortBy, i, got, test.want[i])
+			}
+		}
+	}
+	if uploads[0].UploadID != "2" {
+		t.Error("SortUploads() reordered its input")
+	}
+}
+
+func TestSortUploadsByCost(t *testing.T) {
+	const gb = 1024 * 1024 * 1024
+	uploads := []types.MultipartUpload{
+		{Bucket: "standard", Size: 10 * gb, Region: "us-east-1", StorageClass: "STANDARD"},
+		{Bucket: "glacier", Size: 20 * gb, Region: "us-east-1", StorageClass: "GLACIER"},
+		{Bucket: "large", Size: 30 * gb, Region: "us-east-1", StorageClass: "STANDARD"},
+	}
+
+	order, _ := ParseUploadSort("cost", false)
+	sorted := SortUploads(uploads, order.Less(MonthlyCostFunc(context.Background(), NewCostService())))
+	if sorted[0].Bucket != "large" || sorted[1].Bucket != "standard" || sorted[2].Bucket != "glacier" {
+		t.Errorf("cost order = %s, %s, %s, want large, standard, glacier", sorted[0].Bucket, sorted[1].Bucket, sorted[2].Bucket)
+	}
+}
@@ -0,0 +1,21 @@
+#!/bin/sh
+# Fake cost provider for pricing_exec_test.go. It answers by storage class and
+# appends each request to $COST_PROVIDER_LOG.
+request=$(cat)
+echo "$request" >> "$COST_PROVIDER_LOG"
+
+case "$request" in
+*'"storage_class":"STANDARD"'*)
+	echo '{"schema_version":1,"price_per_gb_month":0.0195,"currency":"usd"}' ;;
+*'"storage_class":"GLACIER"'*)
+	sleep 5 ;;
+*'"storage_class":"DEEP_ARCHIVE"'*)
+	echo "rate service unavailable" >&2
+	exit 3 ;;
+*'"storage_class":"ONEZONE_IA"'*)
+	echo 'price: cheap' ;;
+*'"storage_class":"STANDARD_IA"'*)
+	echo '{"price_per_gb_month":-1}' ;;
+*)
+	echo '{"price_per_gb_month":0.01,"currency":"EUR"}' ;;
+esac
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+// TopUploads keeps the k uploads that sort first using O(k) memory. Ties keep
+// the order in which uploads were pushed, so the result equals a stable sort
+// of every pushed upload truncated to k.
+type TopUploads struct {
+	k    int
+	heap topHeap
+	seq  int
+}
+
+// NewTopUploads creates a TopUploads keeping the first k uploads by less
+func NewTopUploads(k int, less func(a, b types.MultipartUpload) bool) *TopUploads {
+	return &TopUploads{k: k, heap: topHeap{less: less}}
+}
+
+// Push offers an upload, evicting the last kept upload if it sorts later
+func (t *TopUploads) Push(upload types.MultipartUpload) {
+	if t.k <= 0 {
+		return
+	}
+
+	entry := topEntry{upload: upload, seq: t.seq}
+	t.seq++
+
+	if len(t.heap.entries) < t.k {
+		heap.Push(&t.heap, entry)
+		return
+	}
+
+	// The root is the kept upload that sorts last; a later push only displaces
+	// it by sorting strictly earlier
+	if t.heap.less(entry.upload, t.heap.entries[0].upload) {
+		t.heap.entries[0] = entry
+		heap.Fix(&t.heap, 0)
+	}
+}
+
+// Sorted returns the kept uploads in sort order
+func (t *TopUploads) Sorted() []types.MultipartUpload {
+	entries := make([]topEntry, len(t.heap.entries))
+	copy(entries, t.heap.entries)
+	sort.Slice(entries, func(i, j int) bool {
+		return t.heap.before(entries[i], entries[j])
+	})
+
+	uploads := make([]types.MultipartUpload, len(entries))
+	for i, entry := range entries {
+		uploads[i] = entry.upload
+	}
+	return uploads
+}
+
+// topEntry is a kept upload and the order in which it was pushed
+type topEntry struct {
+	upload types.MultipartUpload
+	seq    int
+}
+
+// topHeap is a max-heap of entries: the root sorts last
+type topHeap struct {
+	entries []topEntry
+	less    func(a, b types.MultipartUpload) bool
+}
+
+// before orders entries by less, then by push order
+func (h *topHeap) before(a, b topEntry) bool {
+	if h.less(a.upload, b.upload) {
+		return true
+	}
+	if h.less(b.upload, a.upload) {
+		return false
+	}
+	return a.seq < b.seq
+}
+
+func (h *topHeap) Len() int           { return len(h.entries) }
+func (h *topHeap) Less(i, j int) bool { return h.before(h.entries[j], h.entries[i]) }
+func (h *topHeap) Swap(i, j int)      { h.entries[i], h.entries[j] = h.entries[j], h.entries[i] }
+func (h *topHeap) Push(x interface{}) { h.entries = append(h.entries, x.(topEntry)) }
+func (h *topHeap) Pop() interface{} {
+	last := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	return last
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestTopUploadsMatchesStableSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Few distinct values, so most comparisons are ties
+	uploads := make([]types.MultipartUpload, 500)
+	for i := range uploads {
+		uploads[i] = types.MultipartUpload{
+			Bucket:    fmt.Sprintf("bucket-%d", rng.Intn(5)),
+			Key:       fmt.Sprintf("key-%03d", i),
+			UploadID:  fmt.Sprintf("upload-%03d", i),
+			Initiated: base.Add(time.Duration(rng.Intn(20)) * time.Hour),
+			Size:      int64(rng.Intn(10)) << 20,
+		}
+	}
+
+	orders := map[string]func(a, b types.MultipartUpload) bool{
+		// Without a tie-break, ties fall back to push order
+		"bucket only": func(a, b types.MultipartUpload) bool { return a.Bucket < b.Bucket },
+	}
+	for _, sortBy := range []string{"age", "size", "bucket:desc"} {
+		order, err := ParseUploadSort(sortBy, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		orders[sortBy] = order.Less(nil)
+	}
+
+	for sortBy, less := range orders {
+		want := make([]types.MultipartUpload, len(uploads))
+		copy(want, uploads)
+		sort.SliceStable(want, func(i, j int) bool { return less(want[i], want[j]) })
+
+		for _, k := range []int{1, 7, 50, 499, 500, 1000} {
+			top := NewTopUploads(k, less)
+			for _, upload := range uploads {
+				top.Push(upload)
+			}
+			got := top.Sorted()
+
+			n := k
+			if n > len(want) {
+				n = len(want)
+			}
+			if len(got) != n {
+				t.Fatalf("%s k=%d: got %d uploads, want %d", sortBy, k, len(got), n)
+			}
+			for i := range got {
+				if got[i].UploadID != want[i].UploadID {
+					t.Fatalf("%s k=%d: position %d is %s, want %s", sortBy, k, i, got[i].UploadID, want[i].UploadID)
+				}
+			}
+		}
+	}
+}
+
+func TestWalkUploadsStopsEarly(t *testing.T) {
+	service, buckets := fanOutService(20, 50, 0)
+	service.bucketService = &bucketListService{buckets: buckets}
+	ctx := context.Background()
+
+	listed, err := service.ListUploads(ctx, types.ListOptions{})
+	if err != nil {
+		t.Fatalf("ListUploads() error = %v", err)
+	}
+
+	walked := 0
+	if err := service.WalkUploads(ctx, types.ListOptions{}, func(upload types.MultipartUpload) error {
+		walked++
+		return nil
+	}); err != nil {
+		t.Fatalf("WalkUploads() error = %v", err)
+	}
+	if walked != len(listed) || walked != 20*50 {
+		t.Errorf("WalkUploads() visited %d uploads, ListUploads returned %d, want %d", walked, len(listed), 20*50)
+	}
+
+	walked = 0
+	if err := service.WalkUploads(ctx, types.ListOptions{Offset: 30, MaxResults: 25}, func(upload types.MultipartUpload) error {
+		walked++
+		return nil
+	}); err != nil {
+		t.Fatalf("WalkUploads() with a limit error = %v", err)
+	}
+	if walked != 25 {
+		t.Errorf("WalkUploads() with offset 30 and limit 25 visited %d uploads, want 25", walked)
+	}
+
+	stop := errors.New("stop")
+	walked = 0
+	err = service.WalkUploads(ctx, types.ListOptions{}, func(upload types.MultipartUpload) error {
+		walked++
+		if walked == 10 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || walked != 10 {
+		t.Errorf("WalkUploads() = %v after %d uploads, want the callback error after 10", err, walked)
+	}
+}
@@ -1,11 +1,13 @@
 package services
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -13,9 +15,14 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/Garvitkul/s3mpc/internal/logging"
+	"github.com/Garvitkul/s3mpc/internal/prompt"
+	"github.com/Garvitkul/s3mpc/internal/term"
 	awsclient "github.com/Garvitkul/s3mpc/pkg/aws"
 	"github.com/Garvitkul/s3mpc/pkg/interfaces"
 	pkgtypes "github.com/Garvitkul/s3mpc/pkg/types"
+	"github.com/Garvitkul/s3mpc/pkg/units"
 )
 
 // S3UploadClientInterface defines the S3 operations needed by UploadService
@@ -23,10 +30,12 @@ type S3UploadClientInterface interface {
 	ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (*s3.ListMultipartUploadsOutput, error)
 	ListParts(ctx context.Context, input *s3.ListPartsInput) (*s3.ListPartsOutput, error)
 	AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error)
+	HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error)
 }
 
 // DeletionProgress represents progress information for deletion operations
 type DeletionProgress struct {
+	Phase            string // what is being processed, e.g. "Calculating upload sizes"; empty means deleting
 	TotalUploads     int
 	ProcessedUploads int64
 	SuccessfulDeletes int64
@@ -43,8 +52,42 @@ type DeletionError struct {
 	Time   time.Time
 }
 
+// ErrNoUploadsMatched is returned by DeleteUploads when the delete options
+// leave no upload to delete
+var ErrNoUploadsMatched = errors.New("no uploads match the specified criteria")
+
+// Most pages a listing follows, so that a listing which keeps answering
+// truncated pages cannot loop forever. S3 keeps at most 10000 parts per upload.
+const (
+	maxUploadPages = 100000
+	maxPartPages   = 10000
+)
+
+// DeletionFailuresError reports the uploads DeleteUploads could not delete.
+// It unwraps to the first failure, so a run where every deletion failed for
+// the same reason can be classified by it.
+type DeletionFailuresError struct {
+	Failed int
+	Total  int
+	Err    error
+}
+
+func (e *DeletionFailuresError) Error() string {
+	return fmt.Sprintf("failed to delete %d out of %d uploads", e.Failed, e.Total)
+}
+
+func (e *DeletionFailuresError) Unwrap() error {
+	return e.Err
+}
+
+// Partial reports whether some of the uploads were deleted
+func (e *DeletionFailuresError) Partial() bool {
+	return e.Failed < e.Total
+}
+
 // DeletionResult represents the result of a deletion operation
 type DeletionResult struct {
+	Phase             string // matches DeletionProgress.Phase
 	TotalProcessed    int
 	SuccessfulDeletes int
 	FailedDeletes     int
@@ -56,63 +99,265 @@ type DeletionResult struct {
 // ProgressReporter defines the interface for reporting deletion progress
 type ProgressReporter interface {
 	ReportProgress(progress DeletionProgress)
+	ReportError(err DeletionError)
 	ReportCompletion(result DeletionResult)
 }
 
-// ConsoleProgressReporter implements ProgressReporter for console output
+// ConsoleProgressReporter implements ProgressReporter for console output. On a
+// terminal it redraws a progress bar in place; otherwise it logs a plain
+// progress line every plainProgressInterval.
 type ConsoleProgressReporter struct {
-	writer io.Writer
-	quiet  bool
+	writer   io.Writer
+	errWriter io.Writer // receives deletion errors, which are reported even when quiet; defaults to writer
+	quiet    bool
+	terminal term.Terminal
+	now      func() time.Time
+	mutex    sync.Mutex
+	last     *DeletionProgress
+	rate     progressRate
+	lastPlain time.Time
+	lineOpen bool // a progress line is drawn without a trailing newline
+	streamed int  // errors already shown above the progress line
+	stopWatch chan struct{}
 }
 
-// NewConsoleProgressReporter creates a new console progress reporter
+// NewConsoleProgressReporter creates a new console progress reporter; a nil
+// writer discards the output
 func NewConsoleProgressReporter(writer io.Writer, quiet bool) *ConsoleProgressReporter {
 	if writer == nil {
-		writer = os.Stdout
+		writer = io.Discard
+	}
+	var terminal term.Terminal
+	if file, ok := writer.(*os.File); ok {
+		terminal = term.New(file)
+	}
+	return NewConsoleProgressReporterWithTerminal(writer, quiet, terminal)
+}
+
+// NewConsoleProgressReporterWithTerminal creates a console progress reporter that
+// fits its progress line to terminal and re-renders it when the terminal is resized
+func NewConsoleProgressReporterWithTerminal(writer io.Writer, quiet bool, terminal term.Terminal) *ConsoleProgressReporter {
+	if writer == nil {
+		writer = io.Discard
 	}
 	return &ConsoleProgressReporter{
-		writer: writer,
-		quiet:  quiet,
+		writer:   writer,
+		errWriter: writer,
+		quiet:    quiet,
+		terminal: terminal,
+		now:      time.Now,
+	}
+}
+
+// SetErrorWriter sets where deletion errors are written when quiet, such as
+// stderr while progress goes to stdout
+func (r *ConsoleProgressReporter) SetErrorWriter(w io.Writer) {
+	if w != nil {
+		r.errWriter = w
 	}
 }
 
+// interactive reports whether progress is redrawn in place on a terminal
+func (r *ConsoleProgressReporter) interactive() bool {
+	return r.terminal != nil && r.terminal.IsTerminal()
+}
+
 // ReportProgress reports deletion progress to console
 func (r *ConsoleProgressReporter) ReportProgress(progress DeletionProgress) {
 	if r.quiet {
 		return
 	}
 	
-	elapsed := time.Since(progress.StartTime)
-	percentage := float64(progress.ProcessedUploads) / float64(progress.TotalUploads) * 100
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	now := r.now()
+	if r.last == nil || r.last.Phase != progress.Phase || !r.last.StartTime.Equal(progress.StartTime) {
+		r.rate.reset()
+		r.lastPlain = time.Time{}
+	}
+	r.rate.add(now, progress.ProcessedUploads)
+	r.last = &progress
 	
-	fmt.Fprintf(r.writer, "\rProgress: %d/%d (%.1f%%) | Success: %d | Failed: %d | Current: %s | Elapsed: %v",
-		progress.ProcessedUploads, progress.TotalUploads, percentage,
-		progress.SuccessfulDeletes, progress.FailedDeletes,
-		progress.CurrentBucket, elapsed.Truncate(time.Second))
+	if !r.interactive() {
+		finished := progress.ProcessedUploads >= int64(progress.TotalUploads)
+		if r.lastPlain.IsZero() || finished || now.Sub(r.lastPlain) >= plainProgressInterval {
+			fmt.Fprintln(r.writer, r.progressLine(now, 0))
+			r.lastPlain = now
+		}
+		return
+	}
+	r.renderProgress()
+	r.watchResize()
 }
 
-// ReportCompletion reports deletion completion to console
+// ReportError shows a deletion error as it happens, above the progress line so
+// the line is redrawn intact below it
+func (r *ConsoleProgressReporter) ReportError(err DeletionError) {
+	if r.quiet {
+		return
+	}
+	
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	if r.lineOpen {
+		fmt.Fprint(r.writer, "\r\033[K")
+		r.lineOpen = false
+	}
+	fmt.Fprintf(r.writer, "Failed: %s\n", errorLine(err))
+	r.streamed++
+	if r.last != nil && r.interactive() {
+		r.renderProgress()
+	}
+}
+
+// progressLine formats the last progress, with a bar of barWidth columns when
+// barWidth is positive
+func (r *ConsoleProgressReporter) progressLine(now time.Time, barWidth int) string {
+	progress := *r.last
+	elapsed := now.Sub(progress.StartTime)
+	phase := progress.Phase
+	if phase == "" {
+		phase = deletingPhase
+	}
+	
+	rate := r.rate.perSecond()
+	if rate == 0 && elapsed >= time.Second {
+		rate = float64(progress.ProcessedUploads) / elapsed.Seconds()
+	}
+	eta := formatETA(estimateRemaining(int64(progress.TotalUploads)-progress.ProcessedUploads, rate))
+	
+	var line strings.Builder
+	fmt.Fprintf(&line, "%s: ", phase)
+	if barWidth > 0 {
+		fmt.Fprintf(&line, "%s ", progressBar(progress.ProcessedUploads, progress.TotalUploads, barWidth))
+	}
+	fmt.Fprintf(&line, "%d/%d (%s) | %.1f/s | ETA: %s | Success: %d | Failed: %d",
+		progress.ProcessedUploads, progress.TotalUploads,
+		formatPercentage(float64(progress.ProcessedUploads), float64(progress.TotalUploads)),
+		rate, eta, progress.SuccessfulDeletes, progress.FailedDeletes)
+	if progress.CurrentBucket != "" {
+		fmt.Fprintf(&line, " | Current: %s", pkgtypes.DisplayString(progress.CurrentBucket))
+	}
+	fmt.Fprintf(&line, " | Elapsed: %v", elapsed.Truncate(time.Second))
+	return line.String()
+}
+
+// renderProgress clears the current line and redraws the last progress at the current width
+func (r *ConsoleProgressReporter) renderProgress() {
+	width := r.terminal.Width()
+	line := r.progressLine(r.now(), progressBarWidth(width))
+	
+	// Stay one column short of the edge so the line never wraps
+	if width > 1 {
+		line = truncateString(line, width-1)
+	}
+	fmt.Fprintf(r.writer, "\r\033[K%s", line)
+	r.lineOpen = true
+}
+
+// watchResize starts re-rendering the progress line on terminal resizes; callers hold r.mutex
+func (r *ConsoleProgressReporter) watchResize() {
+	if !r.interactive() || r.stopWatch != nil {
+		return
+	}
+	
+	stop := make(chan struct{})
+	r.stopWatch = stop
+	resized := r.terminal.Resized()
+	
+	go func() {
+		for {
+			select {
+			case <-resized:
+				r.mutex.Lock()
+				if r.last != nil && r.stopWatch == stop {
+					r.renderProgress()
+				}
+				r.mutex.Unlock()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// finishLine stops redrawing the progress line and ends it; callers hold r.mutex
+func (r *ConsoleProgressReporter) finishLine() {
+	if r.stopWatch != nil {
+		close(r.stopWatch)
+		r.stopWatch = nil
+	}
+	if r.lineOpen {
+		fmt.Fprintln(r.writer)
+		r.lineOpen = false
+	}
+	r.last = nil
+}
+
+// ReportCompletion reports deletion completion to console. When quiet, only
+// errors are reported.
 func (r *ConsoleProgressReporter) ReportCompletion(result DeletionResult) {
 	if r.quiet {
+		if len(result.Errors) > 0 {
+			r.writeErrors(r.errWriter, result.Errors, false)
+		}
 		return
 	}
 	
-	fmt.Fprintf(r.writer, "\n\nDeletion completed:\n")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.finishLine()
+	streamed := r.streamed
+	r.streamed = 0
+	
+	if result.Phase != "" {
+		fmt.Fprintf(r.writer, "%s: done in %v (%d failed)\n", result.Phase, result.Duration.Truncate(time.Second), result.FailedDeletes)
+		return
+	}
+	
+	fmt.Fprintf(r.writer, "\nDeletion completed:\n")
 	fmt.Fprintf(r.writer, "  Total processed: %d\n", result.TotalProcessed)
 	fmt.Fprintf(r.writer, "  Successful deletions: %d\n", result.SuccessfulDeletes)
 	fmt.Fprintf(r.writer, "  Failed deletions: %d\n", result.FailedDeletes)
-	fmt.Fprintf(r.writer, "  Storage freed: %s\n", FormatBytes(result.StorageFreed))
+	fmt.Fprintf(r.writer, "  Storage freed: %s\n", units.FormatBytes(result.StorageFreed))
 	fmt.Fprintf(r.writer, "  Duration: %v\n", result.Duration.Truncate(time.Second))
 	
 	if len(result.Errors) > 0 {
-		fmt.Fprintf(r.writer, "\nErrors encountered:\n")
-		for i, err := range result.Errors {
-			if i >= 10 { // Limit error display
-				fmt.Fprintf(r.writer, "  ... and %d more errors\n", len(result.Errors)-10)
-				break
-			}
-			fmt.Fprintf(r.writer, "  %s/%s: %v\n", err.Upload.Bucket, err.Upload.Key, err.Error)
+		r.writeErrors(r.writer, result.Errors, streamed >= len(result.Errors))
+	}
+}
+
+// maxErrorKeyWidth is the widest a key is shown in the list of deletion errors
+const maxErrorKeyWidth = 80
+
+// errorLine formats a deletion error on one line
+func errorLine(err DeletionError) string {
+	return fmt.Sprintf("%s/%s: %s", pkgtypes.DisplayString(err.Upload.Bucket),
+		truncateString(pkgtypes.DisplayString(err.Upload.Key), maxErrorKeyWidth), pkgtypes.DisplayString(err.Error.Error()))
+}
+
+// writeErrors writes deletion errors grouped by cause, then the first few
+// individually unless they were already shown as they happened
+func (r *ConsoleProgressReporter) writeErrors(w io.Writer, deletionErrors []DeletionError, shown bool) {
+	bucketErrors := make([]bucketError, len(deletionErrors))
+	for i, err := range deletionErrors {
+		bucketErrors[i] = bucketError{bucket: err.Upload.Bucket, err: err.Error}
+	}
+	fmt.Fprintf(w, "\nErrors by cause:\n")
+	fmt.Fprint(w, formatErrorSummaries(summarizeErrors(bucketErrors), "upload"))
+	if shown {
+		return
+	}
+	
+	fmt.Fprintf(w, "\nErrors encountered:\n")
+	for i, err := range deletionErrors {
+		if i >= 10 { // Limit error display
+			fmt.Fprintf(w, "  ... and %d more errors\n", len(deletionErrors)-10)
+			break
 		}
+		fmt.Fprintf(w, "  %s\n", errorLine(err))
 	}
 }
 
@@ -123,51 +368,61 @@ type UploadService struct {
 	client        S3UploadClientInterface
 	bucketService interfaces.BucketService
 	dryRunService interfaces.DryRunService
+	// concurrency limits per-upload calls (deletes, part listings, object
+	// checks) and bucketConcurrency the buckets listed at once; zero
+	// bucketConcurrency follows concurrency
 	concurrency   int
+	bucketConcurrency int
+	// clientConfig configures the regional clients the service creates
+	// itself, when its client is not an *awsclient.S3Client
+	clientConfig  awsclient.ClientConfig
 	progressReporter ProgressReporter
-	confirmationReader io.Reader
+	prompter     *prompt.Prompter
 	outputWriter io.Writer
 	regionalClients map[string]S3UploadClientInterface
 	clientMutex     sync.RWMutex
+	initiatorClassifier *InitiatorClassifier
+	logger       *logging.Logger // receives diagnostics; never nil once constructed
+	// progressInterval is how often deletion progress is reported; zero means every second
+	progressInterval time.Duration
+	// accessProbeInterval is how long --verify-access waits between probes; zero means defaultAccessProbeInterval
+	accessProbeInterval time.Duration
+	
+	// skipped holds the buckets the most recent listing could not scan
+	skipped      []pkgtypes.SkippedBucket
+	skippedMutex sync.Mutex
 }
 
-// NewUploadService creates a new UploadService instance
+// NewUploadService creates a new UploadService instance. It writes nothing
+// and cannot ask for confirmation; use NewUploadServiceWithOptions to give it
+// an output writer and a prompter.
 func NewUploadService(client *awsclient.S3Client, bucketService interfaces.BucketService, dryRunService interfaces.DryRunService) interfaces.UploadService {
-	return &UploadService{
-		client:             client,
-		bucketService:      bucketService,
-		dryRunService:      dryRunService,
-		concurrency:        10, // Default concurrency
-		progressReporter:   NewConsoleProgressReporter(os.Stdout, false),
-		confirmationReader: os.Stdin,
-		outputWriter:       os.Stdout,
-		regionalClients:    make(map[string]S3UploadClientInterface),
-	}
+	return NewUploadServiceWithConcurrency(client, bucketService, dryRunService, 10)
 }
 
 // NewUploadServiceWithConcurrency creates a new UploadService instance with custom concurrency
 func NewUploadServiceWithConcurrency(client *awsclient.S3Client, bucketService interfaces.BucketService, dryRunService interfaces.DryRunService, concurrency int) interfaces.UploadService {
-	return &UploadService{
-		client:             client,
-		bucketService:      bucketService,
-		dryRunService:      dryRunService,
-		concurrency:        concurrency,
-		progressReporter:   NewConsoleProgressReporter(os.Stdout, false),
-		confirmationReader: os.Stdin,
-		outputWriter:       os.Stdout,
-	}
+	return NewUploadServiceWithOptions(client, bucketService, dryRunService, concurrency, nil, nil, nil, nil, nil)
 }
 
-// NewUploadServiceWithOptions creates a new UploadService instance with all options
-func NewUploadServiceWithOptions(client *awsclient.S3Client, bucketService interfaces.BucketService, dryRunService interfaces.DryRunService, concurrency int, progressReporter ProgressReporter, confirmationReader io.Reader, outputWriter io.Writer) interfaces.UploadService {
+// NewUploadServiceWithOptions creates a new UploadService instance with all
+// options. Nil options discard progress and output, decline confirmations as
+// if prompts were disabled, and log nothing.
+func NewUploadServiceWithOptions(client *awsclient.S3Client, bucketService interfaces.BucketService, dryRunService interfaces.DryRunService, concurrency int, progressReporter ProgressReporter, prompter *prompt.Prompter, outputWriter io.Writer, initiatorClassifier *InitiatorClassifier, logger *logging.Logger) interfaces.UploadService {
 	if progressReporter == nil {
-		progressReporter = NewConsoleProgressReporter(os.Stdout, false)
-	}
-	if confirmationReader == nil {
-		confirmationReader = os.Stdin
+		progressReporter = NewConsoleProgressReporter(io.Discard, false)
 	}
 	if outputWriter == nil {
-		outputWriter = os.Stdout
+		outputWriter = io.Discard
+	}
+	if prompter == nil {
+		prompter = prompt.New(nil, outputWriter, true)
+	}
+	if initiatorClassifier == nil {
+		initiatorClassifier = defaultInitiatorClassifier()
+	}
+	if logger == nil {
+		logger = logging.NewNopLogger()
 	}
 	
 	return &UploadService{
@@ -176,9 +431,40 @@ func NewUploadServiceWithOptions(client *awsclient.S3Client, bucketService inter
 		dryRunService:      dryRunService,
 		concurrency:        concurrency,
 		progressReporter:   progressReporter,
-		confirmationReader: confirmationReader,
+		prompter:           prompter,
 		outputWriter:       outputWriter,
+		regionalClients:    make(map[string]S3UploadClientInterface),
+		initiatorClassifier: initiatorClassifier,
+		logger:             logger,
+	}
+}
+
+// log returns the logger of the service
+func (s *UploadService) log() *logging.Logger {
+	if s.logger == nil {
+		return logging.NewNopLogger()
 	}
+	return s.logger
+}
+
+// SetBucketConcurrency sets how many buckets are listed at once, separately
+// from the per-upload concurrency; zero follows the per-upload concurrency
+func (s *UploadService) SetBucketConcurrency(concurrency int) {
+	s.bucketConcurrency = concurrency
+}
+
+// SetClientConfig sets the configuration of the regional clients created
+// when the service's client cannot make them itself; its region is replaced
+func (s *UploadService) SetClientConfig(cfg awsclient.ClientConfig) {
+	s.clientConfig = cfg
+}
+
+// bucketLimit returns how many buckets are listed at once
+func (s *UploadService) bucketLimit() int {
+	if s.bucketConcurrency > 0 {
+		return s.bucketConcurrency
+	}
+	return s.concurrency
 }
 
 // ListUploads retrieves all incomplete multipart uploads
@@ -187,51 +473,324 @@ func (s *UploadService) ListUploads(ctx context.Context, opts pkgtypes.ListOptio
 		return nil, fmt.Errorf("invalid list options: %w", err)
 	}
 
+	buckets, err := s.bucketsToList(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	// If a specific bucket is requested, list uploads for that bucket only
+	if opts.BucketName != "" {
+		return s.listUploadsForBucket(ctx, buckets[0], opts)
+	}
+
+	// Process buckets concurrently
+	return s.listUploadsForBuckets(ctx, buckets, opts)
+}
+
+// WalkUploads lists incomplete multipart uploads like ListUploads, but calls fn
+// for each upload as pages arrive instead of collecting them. fn is never
+// called concurrently; if it returns an error, listing stops and WalkUploads
+// returns that error.
+func (s *UploadService) WalkUploads(ctx context.Context, opts pkgtypes.ListOptions, fn func(pkgtypes.MultipartUpload) error) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("invalid list options: %w", err)
+	}
+
+	buckets, err := s.bucketsToList(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	// Offset and limit apply to the whole walk, so each bucket may need to
+	// supply all of them
+	bucketOpts := opts
+	if opts.MaxResults > 0 {
+		bucketOpts.MaxResults = opts.Offset + opts.MaxResults
+	}
+
+	walkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	skipped, emitted := 0, 0
+	var stopErr error
+	stopped := false
+	emit := func(page []pkgtypes.MultipartUpload) {
+		for _, upload := range page {
+			if stopped {
+				return
+			}
+			if skipped < opts.Offset {
+				skipped++
+				continue
+			}
+			if err := fn(upload); err != nil {
+				stopErr, stopped = err, true
+				cancel()
+				return
+			}
+			emitted++
+			if opts.MaxResults > 0 && emitted >= opts.MaxResults {
+				stopped = true
+				cancel()
+			}
+		}
+	}
+
+	if opts.BucketName != "" {
+		err := s.walkBucketPages(walkCtx, buckets[0], bucketOpts, func(page []pkgtypes.MultipartUpload) error {
+			emit(page)
+			if stopped {
+				return context.Canceled
+			}
+			return nil
+		})
+		if stopped {
+			return stopErr
+		}
+		return err
+	}
+
+	type bucketPage struct {
+		bucket  pkgtypes.Bucket
+		uploads []pkgtypes.MultipartUpload
+		err     error
+	}
+
+	pages := make(chan bucketPage, s.bucketLimit())
+	semaphore := make(chan struct{}, s.bucketLimit())
+
+	var wg sync.WaitGroup
+
+	for _, bucket := range buckets {
+		wg.Add(1)
+		go func(b pkgtypes.Bucket) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			err := s.walkBucketPages(walkCtx, b, bucketOpts, func(page []pkgtypes.MultipartUpload) error {
+				select {
+				case pages <- bucketPage{uploads: page}:
+					return nil
+				case <-walkCtx.Done():
+					return walkCtx.Err()
+				}
+			})
+			if err != nil {
+				pages <- bucketPage{bucket: b, err: err}
+			}
+		}(bucket)
+	}
+
+	go func() {
+		wg.Wait()
+		close(pages)
+	}()
+
+	// Keep draining after stopping so that no bucket goroutine blocks
+	var errors []pkgtypes.BucketError
+	for page := range pages {
+		if stopped {
+			continue
+		}
+		if page.err != nil {
+			errors = append(errors, failedBucket(page.bucket, page.err))
+			continue
+		}
+		emit(page.uploads)
+	}
+
+	if stopped {
+		return stopErr
+	}
+	if len(errors) > 0 {
+		return bucketListingError(ctx, errors)
+	}
+	return nil
+}
+
+// bucketsToList resolves the buckets a listing covers: the requested bucket,
+// or every bucket in the requested regions, with the directory buckets when
+// they were asked for, limited to the requested bucket tags
+func (s *UploadService) bucketsToList(ctx context.Context, opts pkgtypes.ListOptions) ([]pkgtypes.Bucket, error) {
 	if opts.BucketName != "" {
 		region, err := s.bucketService.GetBucketRegion(ctx, opts.BucketName)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get region for bucket %s: %w", opts.BucketName, err)
 		}
+		if opts.Region != "" && !sameRegion(region, opts.Region) {
+			return nil, fmt.Errorf("bucket %s is in %s, not in the requested region %s", opts.BucketName, region, opts.Region)
+		}
+		if len(opts.Regions) > 0 && !regionSet(opts.Regions)[awsclient.NormalizeRegion(region)] {
+			return nil, fmt.Errorf("bucket %s is in %s, which is not one of the requested regions (%s)", opts.BucketName, region, strings.Join(opts.Regions, ", "))
+		}
 		
-		bucket := pkgtypes.Bucket{
-			Name:   opts.BucketName,
-			Region: region,
+		s.recordSkipped(nil)
+		buckets := []pkgtypes.Bucket{{Name: opts.BucketName, Region: region}}
+		if pkgtypes.IsDirectoryBucketName(opts.BucketName) {
+			buckets[0].BucketType = pkgtypes.BucketTypeDirectory
+		}
+		if opts.BucketTags.IsEmpty() {
+			return buckets, nil
 		}
 		
-		return s.listUploadsForBucket(ctx, bucket, opts)
+		selected, skipped, err := s.bucketService.SelectBucketsByTags(ctx, buckets, opts.BucketTags)
+		if err != nil {
+			return nil, err
+		}
+		if len(skipped) > 0 {
+			return nil, errors.New(skipped[0].Error)
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("bucket %s does not have the requested bucket tags (%s)", opts.BucketName, opts.BucketTags)
+		}
+		return selected, nil
 	}
 
 	// Get all buckets (don't filter by region yet)
-	buckets, err := s.bucketService.ListBuckets(ctx, "")
+	buckets, skipped, err := s.bucketService.ListBuckets(ctx, "")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list buckets: %w", err)
 	}
 	
+	// A bucket without a known region could be in any of them, so it is
+	// reported whatever regions were requested
+	defer func() { s.recordSkipped(skipped) }()
+	
 	// Filter by region if specified
 	if opts.Region != "" {
 		var filteredBuckets []pkgtypes.Bucket
 		for _, bucket := range buckets {
-			if bucket.Region == opts.Region {
+			if sameRegion(bucket.Region, opts.Region) {
+				filteredBuckets = append(filteredBuckets, bucket)
+			}
+		}
+		buckets = filteredBuckets
+	}
+	
+	// Drop buckets outside the requested regions before any of them is listed
+	if len(opts.Regions) > 0 {
+		regions := regionSet(opts.Regions)
+		var filteredBuckets []pkgtypes.Bucket
+		for _, bucket := range buckets {
+			if regions[awsclient.NormalizeRegion(bucket.Region)] {
+				filteredBuckets = append(filteredBuckets, bucket)
+			}
+		}
+		buckets = filteredBuckets
+	}
+	
+	if opts.IncludeDirectoryBuckets {
+		directoryBuckets, directorySkipped, err := s.directoryBucketsToList(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, directoryBuckets...)
+		skipped = append(skipped, directorySkipped...)
+	}
+	
+	if len(opts.ExcludeBuckets) > 0 {
+		excluded := make(map[string]bool, len(opts.ExcludeBuckets))
+		for _, name := range opts.ExcludeBuckets {
+			excluded[name] = true
+		}
+		var filteredBuckets []pkgtypes.Bucket
+		for _, bucket := range buckets {
+			if !excluded[bucket.Name] {
 				filteredBuckets = append(filteredBuckets, bucket)
 			}
 		}
 		buckets = filteredBuckets
 	}
+	
+	// Tags are only looked up for the buckets left, and before any upload is listed
+	if !opts.BucketTags.IsEmpty() {
+		selected, tagSkipped, err := s.bucketService.SelectBucketsByTags(ctx, buckets, opts.BucketTags)
+		if err != nil {
+			return nil, err
+		}
+		buckets = selected
+		skipped = append(skipped, tagSkipped...)
+	}
 
-	// Process buckets concurrently
-	return s.listUploadsForBuckets(ctx, buckets, opts)
+	return buckets, nil
+}
+
+// directoryBucketsToList lists the directory buckets in the requested regions,
+// or in every region that offers them when no region was requested. A region
+// whose directory buckets cannot be listed is returned as skipped, so the
+// others are still scanned.
+func (s *UploadService) directoryBucketsToList(ctx context.Context, opts pkgtypes.ListOptions) ([]pkgtypes.Bucket, []pkgtypes.SkippedBucket, error) {
+	regions := opts.Regions
+	if len(regions) == 0 && opts.Region != "" {
+		regions = []string{opts.Region}
+	}
+	if len(regions) == 0 {
+		regions = awsclient.DirectoryBucketRegions
+	}
+
+	var buckets []pkgtypes.Bucket
+	var skipped []pkgtypes.SkippedBucket
+	for _, region := range regions {
+		if opts.Region != "" && !sameRegion(region, opts.Region) {
+			continue
+		}
+		directoryBuckets, err := s.bucketService.ListDirectoryBuckets(ctx, region)
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if err != nil {
+			skipped = append(skipped, pkgtypes.SkippedBucket{Region: region, Error: err.Error(), Lookup: pkgtypes.SkippedDirectoryBucketLookup})
+			continue
+		}
+		buckets = append(buckets, directoryBuckets...)
+	}
+	return buckets, skipped, nil
+}
+
+// SkippedBuckets returns the buckets the most recent listing left out because
+// their region could not be determined
+func (s *UploadService) SkippedBuckets() []pkgtypes.SkippedBucket {
+	s.skippedMutex.Lock()
+	defer s.skippedMutex.Unlock()
+	
+	return s.skipped
+}
+
+// recordSkipped remembers the buckets a listing could not scan
+func (s *UploadService) recordSkipped(skipped []pkgtypes.SkippedBucket) {
+	s.skippedMutex.Lock()
+	defer s.skippedMutex.Unlock()
+	
+	s.skipped = skipped
+}
+
+// regionSet returns the given regions as a set of normalized names
+func regionSet(regions []string) map[string]bool {
+	set := make(map[string]bool, len(regions))
+	for _, region := range regions {
+		set[awsclient.NormalizeRegion(region)] = true
+	}
+	return set
+}
+
+// sameRegion reports whether two region names are the same region, whatever
+// their case and surrounding whitespace
+func sameRegion(a, b string) bool {
+	return awsclient.NormalizeRegion(a) == awsclient.NormalizeRegion(b)
 }
 
 // listUploadsForBuckets processes multiple buckets concurrently
 func (s *UploadService) listUploadsForBuckets(ctx context.Context, buckets []pkgtypes.Bucket, opts pkgtypes.ListOptions) ([]pkgtypes.MultipartUpload, error) {
 	type bucketResult struct {
+		bucket  pkgtypes.Bucket
 		uploads []pkgtypes.MultipartUpload
 		err     error
 	}
 
 	resultChan := make(chan bucketResult, len(buckets))
-	semaphore := make(chan struct{}, s.concurrency)
+	semaphore := make(chan struct{}, s.bucketLimit())
 
 	var wg sync.WaitGroup
 
@@ -246,7 +805,7 @@ func (s *UploadService) listUploadsForBuckets(ctx context.Context, buckets []pkg
 			defer func() { <-semaphore }()
 
 			uploads, err := s.listUploadsForBucket(ctx, b, opts)
-			resultChan <- bucketResult{uploads: uploads, err: err}
+			resultChan <- bucketResult{bucket: b, uploads: uploads, err: err}
 		}(bucket)
 	}
 
@@ -258,11 +817,16 @@ func (s *UploadService) listUploadsForBuckets(ctx context.Context, buckets []pkg
 
 	// Collect results
 	var allUploads []pkgtypes.MultipartUpload
-	var errors []error
+	var errors []pkgtypes.BucketError
 
 	for result := range resultChan {
 		if result.err != nil {
-			errors = append(errors, result.err)
+			s.log().Debug("Failed to list uploads in bucket", map[string]interface{}{
+				"bucket": result.bucket.Name,
+				"region": result.bucket.Region,
+				"error":  result.err.Error(),
+			})
+			errors = append(errors, failedBucket(result.bucket, result.err))
 			continue
 		}
 		allUploads = append(allUploads, result.uploads...)
@@ -275,44 +839,82 @@ func (s *UploadService) listUploadsForBuckets(ctx context.Context, buckets []pkg
 
 	// Return partial results even if some buckets failed
 	if len(errors) > 0 {
-		// Log first few errors for debugging
-		for i, err := range errors {
-			if i >= 3 { // Limit to first 3 errors
-				break
-			}
-			fmt.Fprintf(os.Stderr, "Bucket access error %d: %v\n", i+1, err)
-		}
-		if len(errors) > 3 {
-			fmt.Fprintf(os.Stderr, "... and %d more errors\n", len(errors)-3)
-		}
-		return allUploads, fmt.Errorf("failed to list uploads for some buckets: %d errors occurred", len(errors))
+		return allUploads, bucketListingError(ctx, errors)
 	}
 
 	return allUploads, nil
 }
 
+// failedBucket records the error a bucket failed with
+func failedBucket(bucket pkgtypes.Bucket, err error) pkgtypes.BucketError {
+	return pkgtypes.BucketError{Bucket: bucket.Name, Region: bucket.Region, Err: err}
+}
+
+// bucketListingError returns the buckets that failed as BucketErrors, which
+// callers treat as partial results. A cancelled listing is returned as the
+// context's error instead, since its buckets did not fail on their own; one
+// that ran out of time still reports what it listed and the buckets it did
+// not finish.
+func bucketListingError(ctx context.Context, errors []pkgtypes.BucketError) error {
+	if err := ctx.Err(); err != nil && err != context.DeadlineExceeded {
+		return err
+	}
+	return pkgtypes.NewBucketErrors(errors)
+}
+
 // listUploadsForBucket lists uploads for a single bucket
 func (s *UploadService) listUploadsForBucket(ctx context.Context, bucket pkgtypes.Bucket, opts pkgtypes.ListOptions) ([]pkgtypes.MultipartUpload, error) {
 	var allUploads []pkgtypes.MultipartUpload
-	var keyMarker *string
-	var uploadIDMarker *string
+	err := s.walkBucketPages(ctx, bucket, opts, func(page []pkgtypes.MultipartUpload) error {
+		allUploads = append(allUploads, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return allUploads, nil
+}
+
+// walkBucketPages lists the uploads of a single bucket, calling fn with each
+// page. Listing stops at the first error from fn. Each page and the bucket's
+// total are logged at debug level.
+func (s *UploadService) walkBucketPages(ctx context.Context, bucket pkgtypes.Bucket, opts pkgtypes.ListOptions, fn func([]pkgtypes.MultipartUpload) error) error {
+	start := time.Now()
+	pages := 0
+	listed := 0
+	s.log().Debug("Listing uploads in bucket", map[string]interface{}{
+		"bucket": bucket.Name,
+		"region": bucket.Region,
+	})
+	var keyMarker, uploadIDMarker string
+
+	// Directory buckets only take prefixes that end in a slash, so the rest
+	// of the prefix is matched here
+	prefix := opts.KeyPrefix
+	if bucket.BucketType == pkgtypes.BucketTypeDirectory {
+		prefix = prefix[:strings.LastIndex(prefix, "/")+1]
+	}
 
 	for {
 		input := &s3.ListMultipartUploadsInput{
 			Bucket: aws.String(bucket.Name),
 		}
+		if prefix != "" {
+			input.Prefix = aws.String(prefix)
+		}
 
-		// Set pagination markers if available
-		if keyMarker != nil {
-			input.KeyMarker = keyMarker
+		// Set pagination markers if available; S3 rejects empty ones
+		if keyMarker != "" {
+			input.KeyMarker = aws.String(keyMarker)
 		}
-		if uploadIDMarker != nil {
-			input.UploadIdMarker = uploadIDMarker
+		if uploadIDMarker != "" {
+			input.UploadIdMarker = aws.String(uploadIDMarker)
 		}
 
 		// Set max keys for pagination
 		if opts.MaxResults > 0 {
-			remaining := opts.MaxResults - len(allUploads)
+			remaining := opts.MaxResults - listed
 			if remaining <= 0 {
 				break
 			}
@@ -322,22 +924,29 @@ func (s *UploadService) listUploadsForBucket(ctx context.Context, bucket pkgtype
 		// Use region-specific client for this bucket
 		regionalClient, err := s.getRegionalClient(ctx, bucket.Region)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create regional client for bucket %s: %w", bucket.Name, err)
+			return fmt.Errorf("failed to create regional client for bucket %s: %w", bucket.Name, err)
 		}
 		
 		output, err := regionalClient.ListMultipartUploads(ctx, input)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list multipart uploads for bucket %s: %w", bucket.Name, err)
+			return fmt.Errorf("failed to list multipart uploads for bucket %s: %w", bucket.Name, err)
 		}
 
 		// Convert AWS uploads to our types
+		page := make([]pkgtypes.MultipartUpload, 0, len(output.Uploads))
 		for _, upload := range output.Uploads {
 			if upload.Key == nil || upload.UploadId == nil || upload.Initiated == nil {
 				continue
 			}
+			if !strings.HasPrefix(*upload.Key, opts.KeyPrefix) {
+				continue
+			}
 
-			// Get storage class, default to STANDARD if not specified
+			// Get storage class, defaulting to the one of the bucket type
 			storageClass := "STANDARD"
+			if bucket.BucketType == pkgtypes.BucketTypeDirectory {
+				storageClass = string(s3types.StorageClassExpressOnezone)
+			}
 			if upload.StorageClass != "" {
 				storageClass = string(upload.StorageClass)
 			}
@@ -350,22 +959,68 @@ func (s *UploadService) listUploadsForBucket(ctx context.Context, bucket pkgtype
 				StorageClass: storageClass,
 				Region:       bucket.Region,
 				Size:         0, // Will be calculated separately if needed
+				BucketTags:   bucket.Tags,
+				BucketType:   bucket.BucketType,
+			}
+
+			// Record who started the upload so service-initiated uploads can be protected
+			if upload.Initiator != nil {
+				multipartUpload.Initiator = aws.ToString(upload.Initiator.ID)
+				multipartUpload.InitiatorName = aws.ToString(upload.Initiator.DisplayName)
+				if multipartUpload.Initiator == "" {
+					multipartUpload.Initiator = multipartUpload.InitiatorName
+				}
 			}
+			if upload.Owner != nil {
+				multipartUpload.Owner = aws.ToString(upload.Owner.DisplayName)
+				multipartUpload.OwnerID = aws.ToString(upload.Owner.ID)
+			}
+			s.classifier().Apply(&multipartUpload)
+
+			page = append(page, multipartUpload)
+		}
 
-			allUploads = append(allUploads, multipartUpload)
+		pages++
+		listed += len(page)
+		s.log().Debug("Listed page of uploads", map[string]interface{}{
+			"bucket":  bucket.Name,
+			"region":  bucket.Region,
+			"page":    pages,
+			"uploads": len(page),
+			"elapsed": time.Since(start).Round(time.Millisecond),
+		})
+		if err := fn(page); err != nil {
+			return err
 		}
 
 		// Check if there are more results
-		if output.IsTruncated == nil || !*output.IsTruncated {
+		if !aws.ToBool(output.IsTruncated) {
 			break
 		}
 
-		// Set markers for next iteration
-		keyMarker = output.NextKeyMarker
-		uploadIDMarker = output.NextUploadIdMarker
+		// Set markers for next iteration. A truncated page without new
+		// markers would list the same page again, or nothing more.
+		nextKeyMarker, nextUploadIDMarker := aws.ToString(output.NextKeyMarker), aws.ToString(output.NextUploadIdMarker)
+		if nextKeyMarker == "" && nextUploadIDMarker == "" {
+			return fmt.Errorf("failed to list multipart uploads for bucket %s: page %d is truncated but has no next marker", bucket.Name, pages)
+		}
+		if nextKeyMarker == keyMarker && nextUploadIDMarker == uploadIDMarker {
+			return fmt.Errorf("failed to list multipart uploads for bucket %s: page %d repeats the marker %q of the page before", bucket.Name, pages, keyMarker)
+		}
+		if pages >= maxUploadPages {
+			return fmt.Errorf("failed to list multipart uploads for bucket %s: still truncated after %d pages", bucket.Name, pages)
+		}
+		keyMarker, uploadIDMarker = nextKeyMarker, nextUploadIDMarker
 	}
 
-	return allUploads, nil
+	s.log().Debug("Listed uploads in bucket", map[string]interface{}{
+		"bucket":   bucket.Name,
+		"region":   bucket.Region,
+		"pages":    pages,
+		"uploads":  listed,
+		"duration": time.Since(start).Round(time.Millisecond),
+	})
+	return nil
 }
 
 // applyPagination applies offset and limit to the results
@@ -385,45 +1040,193 @@ func (s *UploadService) applyPagination(uploads []pkgtypes.MultipartUpload, opts
 
 // GetUploadSize calculates the size of an incomplete upload
 func (s *UploadService) GetUploadSize(ctx context.Context, upload pkgtypes.MultipartUpload) (int64, error) {
+	detail, err := s.GetUploadDetail(ctx, upload)
+	return detail.Size, err
+}
+
+// GetUploadDetail measures the size and number of parts of an incomplete
+// upload, and when a lifecycle rule will abort it
+func (s *UploadService) GetUploadDetail(ctx context.Context, upload pkgtypes.MultipartUpload) (pkgtypes.UploadDetail, error) {
+	return s.uploadDetail(ctx, upload, false)
+}
+
+// GetUploadDetailWithParts measures an upload as GetUploadDetail does in the
+// same walk of its parts, and also returns every part in Parts
+func (s *UploadService) GetUploadDetailWithParts(ctx context.Context, upload pkgtypes.MultipartUpload) (pkgtypes.UploadDetail, error) {
+	return s.uploadDetail(ctx, upload, true)
+}
+
+// uploadDetail walks the parts of an upload, keeping them when withParts is set
+func (s *UploadService) uploadDetail(ctx context.Context, upload pkgtypes.MultipartUpload, withParts bool) (pkgtypes.UploadDetail, error) {
+	var detail pkgtypes.UploadDetail
 	if err := upload.Validate(); err != nil {
-		return 0, fmt.Errorf("invalid upload: %w", err)
+		return detail, fmt.Errorf("invalid upload: %w", err)
 	}
 
-	var totalSize int64
-	var partNumberMarker *string
+	// Parts must be listed in the bucket's own region
+	client := s.client
+	if upload.Region != "" {
+		regionalClient, err := s.getRegionalClient(ctx, upload.Region)
+		if err != nil {
+			return detail, fmt.Errorf("failed to create regional client for bucket %s: %w", upload.Bucket, err)
+		}
+		client = regionalClient
+	}
 
-	for {
+	var partNumberMarker string
+
+	for pages := 1; ; pages++ {
 		input := &s3.ListPartsInput{
 			Bucket:   aws.String(upload.Bucket),
 			Key:      aws.String(upload.Key),
 			UploadId: aws.String(upload.UploadID),
 		}
 
-		if partNumberMarker != nil {
-			input.PartNumberMarker = partNumberMarker
+		// S3 rejects an empty marker, so the first page has none
+		if partNumberMarker != "" {
+			input.PartNumberMarker = aws.String(partNumberMarker)
 		}
 
-		output, err := s.client.ListParts(ctx, input)
+		output, err := client.ListParts(ctx, input)
 		if err != nil {
-			return 0, fmt.Errorf("failed to list parts for upload %s in bucket %s: %w", upload.UploadID, upload.Bucket, err)
+			return pkgtypes.UploadDetail{}, fmt.Errorf("failed to list parts for upload %s in bucket %s: %w", upload.UploadID, upload.Bucket, err)
 		}
 
 		// Sum up the sizes of all parts
 		for _, part := range output.Parts {
 			if part.Size != nil {
-				totalSize += *part.Size
+				detail.Size += *part.Size
+			}
+			if withParts {
+				detail.Parts = append(detail.Parts, pkgtypes.UploadPart{
+					PartNumber:   aws.ToInt32(part.PartNumber),
+					Size:         aws.ToInt64(part.Size),
+					ETag:         aws.ToString(part.ETag),
+					LastModified: aws.ToTime(part.LastModified).UTC(),
+				})
 			}
 		}
+		detail.PartsCount += len(output.Parts)
+		
+		// Every page repeats the lifecycle rule that will abort the upload, if any
+		if output.AbortDate != nil {
+			abortDate := output.AbortDate.UTC()
+			detail.AbortDate = &abortDate
+			detail.AbortRuleID = aws.ToString(output.AbortRuleId)
+		}
 
 		// Check if there are more parts
-		if output.IsTruncated == nil || !*output.IsTruncated {
+		if !aws.ToBool(output.IsTruncated) {
 			break
 		}
 
-		partNumberMarker = output.NextPartNumberMarker
+		// A truncated page must move the marker on, or the next page would
+		// repeat it and count its parts twice
+		next := aws.ToString(output.NextPartNumberMarker)
+		if next == "" || next == partNumberMarker {
+			return pkgtypes.UploadDetail{}, fmt.Errorf("failed to list parts for upload %s in bucket %s: page %d is truncated but has no new part number marker", upload.UploadID, upload.Bucket, pages)
+		}
+		if pages >= maxPartPages {
+			return pkgtypes.UploadDetail{}, fmt.Errorf("failed to list parts for upload %s in bucket %s: still truncated after %d pages", upload.UploadID, upload.Bucket, pages)
+		}
+		partNumberMarker = next
+	}
+
+	return detail, nil
+}
+
+// GetObjectLastModified returns when the completed object at the key of an
+// upload was last written, and false when there is no such object
+func (s *UploadService) GetObjectLastModified(ctx context.Context, upload pkgtypes.MultipartUpload) (time.Time, bool, error) {
+	if err := upload.Validate(); err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid upload: %w", err)
+	}
+
+	// Objects are read in the bucket's own region, sharing the rate limit of its listing client
+	client := s.client
+	if upload.Region != "" {
+		regionalClient, err := s.getRegionalClient(ctx, upload.Region)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("failed to create regional client for bucket %s: %w", upload.Bucket, err)
+		}
+		client = regionalClient
+	}
+
+	output, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(upload.Bucket),
+		Key:    aws.String(upload.Key),
+	})
+	if err != nil {
+		var notFound *s3types.NotFound
+		if errors.As(err, &notFound) || awsclient.ErrorCode(err) == "NotFound" || awsclient.ErrorCode(err) == "NoSuchKey" {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, fmt.Errorf("failed to head object %s in bucket %s: %w", pkgtypes.DisplayString(upload.Key), upload.Bucket, err)
+	}
+
+	return aws.ToTime(output.LastModified), true, nil
+}
+
+// FindRemainingUploads splits uploads into those S3 still has and those it
+// answers with NoSuchUpload, checking as many at once as deletions run.
+// Uploads that cannot be checked are kept, so deleting them reports the error.
+func (s *UploadService) FindRemainingUploads(ctx context.Context, uploads []pkgtypes.MultipartUpload) ([]pkgtypes.MultipartUpload, []pkgtypes.MultipartUpload, error) {
+	gone := make([]bool, len(uploads))
+	semaphore := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+	for i, upload := range uploads {
+		wg.Add(1)
+		go func(i int, upload pkgtypes.MultipartUpload) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			gone[i] = s.uploadGone(ctx, upload)
+		}(i, upload)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var remaining, cleaned []pkgtypes.MultipartUpload
+	for i, upload := range uploads {
+		if gone[i] {
+			cleaned = append(cleaned, upload)
+		} else {
+			remaining = append(remaining, upload)
+		}
+	}
+	return remaining, cleaned, nil
+}
+
+// uploadGone reports whether S3 answers a listing of the upload's first part
+// with NoSuchUpload
+func (s *UploadService) uploadGone(ctx context.Context, upload pkgtypes.MultipartUpload) bool {
+	client := s.client
+	if upload.Region != "" {
+		regionalClient, err := s.getRegionalClient(ctx, upload.Region)
+		if err != nil {
+			return false
+		}
+		client = regionalClient
 	}
 
-	return totalSize, nil
+	_, err := client.ListParts(ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(upload.Bucket),
+		Key:      aws.String(upload.Key),
+		UploadId: aws.String(upload.UploadID),
+		MaxParts: aws.Int32(1),
+	})
+	return isNoSuchUpload(err)
+}
+
+// isNoSuchUpload reports whether err is S3 answering that the upload does not exist
+func isNoSuchUpload(err error) bool {
+	var noSuchUpload *s3types.NoSuchUpload
+	return err != nil && (errors.As(err, &noSuchUpload) || awsclient.ErrorCode(err) == "NoSuchUpload")
 }
 
 // DeleteUpload deletes a specific multipart upload
@@ -432,17 +1235,39 @@ func (s *UploadService) DeleteUpload(ctx context.Context, upload pkgtypes.Multip
 		return fmt.Errorf("invalid upload: %w", err)
 	}
 
-	input := &s3.AbortMultipartUploadInput{
+	client, err := s.deleteClient(ctx, upload)
+	if err != nil {
+		return err
+	}
+	return abortUpload(ctx, client, upload)
+}
+
+// deleteClient returns the client that aborts the uploads of upload's bucket:
+// the client of its region, as for listing, or the main client when the
+// region is not known. Directory buckets are only served by the zonal
+// endpoints of their region, which the regional client resolves from the
+// bucket name.
+func (s *UploadService) deleteClient(ctx context.Context, upload pkgtypes.MultipartUpload) (S3UploadClientInterface, error) {
+	if upload.Region == "" {
+		return s.client, nil
+	}
+	regionalClient, err := s.getRegionalClient(ctx, upload.Region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create regional client for bucket %s: %w", upload.Bucket, err)
+	}
+	return regionalClient, nil
+}
+
+// abortUpload aborts an upload that has been validated with client
+func abortUpload(ctx context.Context, client S3UploadClientInterface, upload pkgtypes.MultipartUpload) error {
+	_, err := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
 		Bucket:   aws.String(upload.Bucket),
 		Key:      aws.String(upload.Key),
 		UploadId: aws.String(upload.UploadID),
-	}
-
-	_, err := s.client.AbortMultipartUpload(ctx, input)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to abort multipart upload %s in bucket %s: %w", upload.UploadID, upload.Bucket, err)
 	}
-
 	return nil
 }
 
@@ -452,11 +1277,25 @@ func (s *UploadService) DeleteUploads(ctx context.Context, uploads []pkgtypes.Mu
 		return fmt.Errorf("invalid delete options: %w", err)
 	}
 
-	// Filter uploads based on options
-	filteredUploads := s.filterUploadsForDeletion(uploads, opts)
+	// Filter uploads based on options, then keep the oldest if there are more than --max-deletes
+	matchingUploads, excludedServiceInitiated := s.filterUploadsForDeletion(uploads, opts)
+	matchingUploads, autoExpiring := excludeAutoExpiring(matchingUploads, opts, time.Now())
+	filteredUploads := limitDeletions(matchingUploads, opts.MaxDeletes)
 
 	if len(filteredUploads) == 0 {
-		return fmt.Errorf("no uploads match the specified criteria")
+		// Show which condition left nothing to delete
+		if opts.DryRun && !opts.Quiet && s.dryRunService != nil {
+			if result, err := s.dryRunService.SimulateDeletion(ctx, uploads, opts); err == nil {
+				s.reportFilterFunnel(result.Funnel)
+			}
+		}
+		if excludedServiceInitiated > 0 {
+			return fmt.Errorf("%w (%d service-initiated uploads excluded, use --include-service-initiated to include them)", ErrNoUploadsMatched, excludedServiceInitiated)
+		}
+		if len(autoExpiring) > 0 {
+			return fmt.Errorf("%w (%d uploads left to lifecycle rules by --skip-auto-expiring)", ErrNoUploadsMatched, len(autoExpiring))
+		}
+		return ErrNoUploadsMatched
 	}
 
 	// Calculate total size for reporting
@@ -468,21 +1307,38 @@ func (s *UploadService) DeleteUploads(ctx context.Context, uploads []pkgtypes.Mu
 	if opts.DryRun {
 		// Use the dry-run service for comprehensive dry-run functionality
 		if s.dryRunService != nil {
-			result, err := s.dryRunService.SimulateDeletion(ctx, filteredUploads, opts)
+			// The dry-run service applies the same filters, and reports what each one selects
+			result, err := s.dryRunService.SimulateDeletion(ctx, uploads, opts)
 			if err != nil {
 				return fmt.Errorf("dry-run simulation failed: %w", err)
 			}
-			s.reportDryRunResultsFromService(result)
+			if opts.VerifyAccess {
+				if !opts.Quiet {
+					fmt.Fprintf(s.outputWriter, "Verifying delete access in %d buckets: one AbortMultipartUpload call each, for upload ID %s, which does not exist\n", len(result.UploadsByBucket), AccessProbeUploadID)
+				}
+				result.AccessByBucket, result.AccessErrors, err = s.VerifyDeleteAccess(ctx, result.Uploads)
+				if err != nil {
+					return fmt.Errorf("failed to verify delete access: %w", err)
+				}
+			}
+			if opts.OnDryRun != nil {
+				opts.OnDryRun(result)
+			}
+			if opts.ReportDryRun != nil {
+				opts.ReportDryRun(result)
+			} else {
+				s.reportDryRunResultsFromService(result, opts.Quiet)
+			}
 		} else {
 			// Fallback to legacy dry-run reporting
-			s.reportDryRunResults(filteredUploads, totalSize)
+			s.reportDryRunResults(filteredUploads, totalSize, opts.Quiet)
 		}
 		return nil
 	}
 
 	// Show confirmation prompt unless --force is used
 	if !opts.Force {
-		confirmed, err := s.promptForConfirmation(filteredUploads, totalSize)
+		confirmed, err := s.promptForConfirmation(filteredUploads, len(matchingUploads), totalSize, excludedServiceInitiated, summarizeAutoExpiring(autoExpiring), opts.Quiet)
 		if err != nil {
 			return fmt.Errorf("failed to get confirmation: %w", err)
 		}
@@ -491,77 +1347,134 @@ func (s *UploadService) DeleteUploads(ctx context.Context, uploads []pkgtypes.Mu
 		}
 	}
 
+	if opts.OnStart != nil {
+		opts.OnStart(filteredUploads)
+	}
+
 	// Delete uploads with progress reporting
-	return s.deleteUploadsWithProgress(ctx, filteredUploads)
+	return s.deleteUploadsWithProgress(ctx, filteredUploads, opts)
 }
 
-// filterUploadsForDeletion filters uploads based on delete options
-func (s *UploadService) filterUploadsForDeletion(uploads []pkgtypes.MultipartUpload, opts pkgtypes.DeleteOptions) []pkgtypes.MultipartUpload {
-	var filtered []pkgtypes.MultipartUpload
-
-	for _, upload := range uploads {
-		// Filter by bucket if specified
-		if opts.BucketName != "" && upload.Bucket != opts.BucketName {
-			continue
-		}
-
-		// Filter by age if specified
-		if opts.OlderThan != nil {
-			age := time.Since(upload.Initiated)
-			if age < *opts.OlderThan {
-				continue
-			}
-		}
-
-		// Filter by size if specified (requires size to be calculated)
-		if opts.SmallerThan != nil && upload.Size >= *opts.SmallerThan {
-			continue
-		}
-
-		if opts.LargerThan != nil && upload.Size <= *opts.LargerThan {
-			continue
-		}
-
-		filtered = append(filtered, upload)
-	}
-
-	return filtered
+// filterUploadsForDeletion filters uploads based on delete options and returns
+// the number of matching service-initiated uploads that were excluded
+func (s *UploadService) filterUploadsForDeletion(uploads []pkgtypes.MultipartUpload, opts pkgtypes.DeleteOptions) ([]pkgtypes.MultipartUpload, int) {
+	return selectForDeletion(uploads, opts)
 }
 
-// promptForConfirmation prompts the user for confirmation before deletion
-func (s *UploadService) promptForConfirmation(uploads []pkgtypes.MultipartUpload, totalSize int64) (bool, error) {
-	// Group uploads by bucket for summary
+// promptForConfirmation prompts the user for confirmation before deletion.
+// matching is the number of uploads selected before --max-deletes, and
+// autoExpiring the matching uploads left to lifecycle rules. When quiet, the
+// summary is reduced to the prompt itself.
+func (s *UploadService) promptForConfirmation(uploads []pkgtypes.MultipartUpload, matching int, totalSize int64, excludedServiceInitiated int, autoExpiring []pkgtypes.AutoExpiringUploads, quiet bool) (bool, error) {
+	if quiet {
+		return s.confirmPrompter().Confirm(fmt.Sprintf("Delete %d uploads (%s)? This action cannot be undone.", len(uploads), units.FormatBytes(totalSize)))
+	}
+	
+	// Group uploads by bucket and storage class for summary
 	bucketCounts := make(map[string]int)
+	classCounts := make(map[string]int)
+	classSizes := make(map[string]int64)
+	serviceInitiated := 0
 	for _, upload := range uploads {
 		bucketCounts[upload.Bucket]++
+		storageClass := pkgtypes.NormalizeStorageClass(upload.StorageClass)
+		classCounts[storageClass]++
+		classSizes[storageClass] += upload.Size
+		if upload.ServiceInitiated {
+			serviceInitiated++
+		}
 	}
 
 	fmt.Fprintf(s.outputWriter, "\nDeletion Summary:\n")
 	fmt.Fprintf(s.outputWriter, "  Total uploads to delete: %d\n", len(uploads))
-	fmt.Fprintf(s.outputWriter, "  Total storage to free: %s\n", FormatBytes(totalSize))
+	if matching > len(uploads) {
+		fmt.Fprintf(s.outputWriter, "  %s\n", maxDeletesSummary(matching, len(uploads)))
+	}
+	fmt.Fprintf(s.outputWriter, "  Total storage to free: %s\n", units.FormatBytes(totalSize))
 	fmt.Fprintf(s.outputWriter, "  Buckets affected: %d\n", len(bucketCounts))
+	if serviceInitiated > 0 {
+		fmt.Fprintf(s.outputWriter, "  Service-initiated uploads included: %d\n", serviceInitiated)
+	}
+	if excludedServiceInitiated > 0 {
+		fmt.Fprintf(s.outputWriter, "  Service-initiated uploads excluded: %d (use --include-service-initiated to include)\n", excludedServiceInitiated)
+	}
+	s.reportAutoExpiring(autoExpiring)
+	s.reportMeasurementAge(pkgtypes.OldestMeasurement(uploads))
 	
 	if len(bucketCounts) <= 10 {
 		fmt.Fprintf(s.outputWriter, "\nUploads per bucket:\n")
 		for bucket, count := range bucketCounts {
-			fmt.Fprintf(s.outputWriter, "  %s: %d uploads\n", bucket, count)
+			fmt.Fprintf(s.outputWriter, "  %s: %d uploads\n", pkgtypes.DisplayString(bucket), count)
 		}
 	}
+	
+	// Always shown, so a --storage-class selection can be checked before confirming
+	storageClasses := make([]string, 0, len(classCounts))
+	for storageClass := range classCounts {
+		storageClasses = append(storageClasses, storageClass)
+	}
+	sort.Strings(storageClasses)
+	fmt.Fprintf(s.outputWriter, "\nUploads per storage class:\n")
+	for _, storageClass := range storageClasses {
+		fmt.Fprintf(s.outputWriter, "  %s: %d uploads (%s)\n", pkgtypes.DisplayString(storageClass), classCounts[storageClass], units.FormatBytes(classSizes[storageClass]))
+	}
 
-	fmt.Fprintf(s.outputWriter, "\nThis action cannot be undone. Are you sure you want to proceed? (y/N): ")
+	fmt.Fprintf(s.outputWriter, "\n")
+	return s.confirmPrompter().Confirm("This action cannot be undone. Are you sure you want to proceed?")
+}
 
-	reader := bufio.NewReader(s.confirmationReader)
-	response, err := reader.ReadString('\n')
-	if err != nil {
-		return false, fmt.Errorf("failed to read user input: %w", err)
+// maxDeletesSummary says how many matching uploads --max-deletes left for a later run
+func maxDeletesSummary(matching, deleting int) string {
+	return fmt.Sprintf("Matching: %s, will delete: %s (limited by --max-deletes, oldest first)", formatCount(matching), formatCount(deleting))
+}
+
+// formatCount formats a count with thousands separators, e.g. 12,000
+func formatCount(n int) string {
+	if n < 0 {
+		return "-" + formatCount(-n)
 	}
+	digits := strconv.Itoa(n)
+	var result strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			result.WriteByte(',')
+		}
+		result.WriteRune(digit)
+	}
+	return result.String()
+}
 
-	response = strings.TrimSpace(strings.ToLower(response))
-	return response == "y" || response == "yes", nil
+// reportMeasurementAge reports when the sizes being shown were measured and
+// warns when they may no longer match what exports or S3 show. Uploads whose
+// size was never measured report nothing.
+func (s *UploadService) reportMeasurementAge(measuredAt time.Time) {
+	if measuredAt.IsZero() {
+		return
+	}
+	age := time.Since(measuredAt)
+	fmt.Fprintf(s.outputWriter, "  Sizes measured %s\n", formatMeasurementAge(age))
+	if age > SizeStalenessWarning {
+		fmt.Fprintf(s.outputWriter, "  Warning: sizes may be stale; use --remeasure-older-than %s to measure them again\n", units.FormatDuration(SizeStalenessWarning))
+	}
+}
+
+// confirmPrompter returns the prompter, defaulting to one that declines as
+// if prompts were disabled
+func (s *UploadService) confirmPrompter() *prompt.Prompter {
+	if s.prompter == nil {
+		return prompt.New(nil, s.outputWriter, true)
+	}
+	return s.prompter
 }
 
 // reportDryRunResults reports what would be deleted in a dry run (legacy method)
-func (s *UploadService) reportDryRunResults(uploads []pkgtypes.MultipartUpload, totalSize int64) {
+func (s *UploadService) reportDryRunResults(uploads []pkgtypes.MultipartUpload, totalSize int64, quiet bool) {
+	if quiet {
+		fmt.Fprintf(s.outputWriter, "Total uploads that would be deleted: %d\n", len(uploads))
+		fmt.Fprintf(s.outputWriter, "Total storage that would be freed: %s\n", units.FormatBytes(totalSize))
+		return
+	}
+	
 	// Group uploads by bucket for summary
 	bucketCounts := make(map[string]int)
 	bucketSizes := make(map[string]int64)
@@ -573,176 +1486,236 @@ func (s *UploadService) reportDryRunResults(uploads []pkgtypes.MultipartUpload,
 
 	fmt.Fprintf(s.outputWriter, "\nDry Run Results:\n")
 	fmt.Fprintf(s.outputWriter, "  Total uploads that would be deleted: %d\n", len(uploads))
-	fmt.Fprintf(s.outputWriter, "  Total storage that would be freed: %s\n", FormatBytes(totalSize))
+	fmt.Fprintf(s.outputWriter, "  Total storage that would be freed: %s\n", units.FormatBytes(totalSize))
 	fmt.Fprintf(s.outputWriter, "  Buckets that would be affected: %d\n", len(bucketCounts))
 	
 	fmt.Fprintf(s.outputWriter, "\nBreakdown by bucket:\n")
 	for bucket, count := range bucketCounts {
 		size := bucketSizes[bucket]
-		fmt.Fprintf(s.outputWriter, "  %s: %d uploads (%s)\n", bucket, count, FormatBytes(size))
+		fmt.Fprintf(s.outputWriter, "  %s: %d uploads (%s)\n", bucket, count, units.FormatBytes(size))
 	}
 
 	fmt.Fprintf(s.outputWriter, "\nTo execute this deletion, run the same command without --dry-run\n")
 }
 
-// reportDryRunResultsFromService reports comprehensive dry-run results from the dry-run service
-func (s *UploadService) reportDryRunResultsFromService(result pkgtypes.DryRunResult) {
+// reportDryRunResultsFromService reports comprehensive dry-run results from the
+// dry-run service. When quiet, only the totals are reported.
+func (s *UploadService) reportDryRunResultsFromService(result pkgtypes.DryRunResult, quiet bool) {
+	if quiet {
+		fmt.Fprintf(s.outputWriter, "Total uploads that would be deleted: %d\n", result.TotalUploads)
+		fmt.Fprintf(s.outputWriter, "Total storage that would be freed: %s\n", units.FormatBytes(result.TotalSize))
+		fmt.Fprintf(s.outputWriter, "Estimated monthly savings: %s\n", formatCurrency(result.EstimatedSavings, result.Currency))
+		return
+	}
+	
 	fmt.Fprintf(s.outputWriter, "\nDry Run Results:\n")
 	fmt.Fprintf(s.outputWriter, "  Total uploads that would be deleted: %d\n", result.TotalUploads)
-	fmt.Fprintf(s.outputWriter, "  Total storage that would be freed: %s\n", FormatBytes(result.TotalSize))
-	fmt.Fprintf(s.outputWriter, "  Estimated monthly cost savings: $%.2f %s\n", result.EstimatedSavings, result.Currency)
+	if result.MatchingUploads > result.TotalUploads {
+		fmt.Fprintf(s.outputWriter, "  %s\n", maxDeletesSummary(result.MatchingUploads, result.TotalUploads))
+	}
+	fmt.Fprintf(s.outputWriter, "  Total storage that would be freed: %s\n", units.FormatBytes(result.TotalSize))
+	fmt.Fprintf(s.outputWriter, "  Estimated monthly savings: %s\n", formatCurrency(result.EstimatedSavings, result.Currency))
+	fmt.Fprintf(s.outputWriter, "  Already wasted (accrued since initiated): %s\n", formatCurrency(result.AlreadyWasted, result.Currency))
 	fmt.Fprintf(s.outputWriter, "  Buckets that would be affected: %d\n", len(result.UploadsByBucket))
+	if result.ExcludedServiceInitiated > 0 {
+		fmt.Fprintf(s.outputWriter, "  Service-initiated uploads excluded: %d (use --include-service-initiated to include)\n", result.ExcludedServiceInitiated)
+	}
+	if result.SupersededUploads > 0 {
+		fmt.Fprintf(s.outputWriter, "  Probably superseded (a newer object exists at the key): %d uploads (%s)\n", result.SupersededUploads, units.FormatBytes(result.SupersededSize))
+	}
+	reportDeleteAccess(s.outputWriter, result)
+	s.reportAutoExpiring(result.ExcludedAutoExpiring)
+	s.reportMeasurementAge(result.SizesMeasuredAt)
 	
+	// Largest first, as in the markdown summary, so runs can be compared
 	if len(result.UploadsByBucket) > 0 {
 		fmt.Fprintf(s.outputWriter, "\nBreakdown by bucket:\n")
-		for bucket, count := range result.UploadsByBucket {
-			size := result.SizeByBucket[bucket]
+		for _, bucket := range sortedKeysByValue(result.SizeByBucket) {
+			count, size := result.UploadsByBucket[bucket], result.SizeByBucket[bucket]
 			savings := result.SavingsByBucket[bucket]
-			fmt.Fprintf(s.outputWriter, "  %s: %d uploads (%s, $%.2f/month)\n", 
-				bucket, count, FormatBytes(size), savings)
+			deletable := ""
+			if label := deletableLabel(result, bucket); label != "" {
+				deletable = ", deletable: " + label
+			}
+			fmt.Fprintf(s.outputWriter, "  %s: %d uploads (%s, %s/month%s)\n", 
+				bucket, count, units.FormatBytes(size), formatAmount(savings, result.Currency), deletable)
 		}
 	}
 	
 	if len(result.UploadsByRegion) > 1 {
 		fmt.Fprintf(s.outputWriter, "\nBreakdown by region:\n")
-		for region, count := range result.UploadsByRegion {
-			size := result.SizeByRegion[region]
+		for _, region := range sortedKeysByValue(result.SizeByRegion) {
+			count, size := result.UploadsByRegion[region], result.SizeByRegion[region]
 			savings := result.SavingsByRegion[region]
-			fmt.Fprintf(s.outputWriter, "  %s: %d uploads (%s, $%.2f/month)\n", 
-				region, count, FormatBytes(size), savings)
+			fmt.Fprintf(s.outputWriter, "  %s: %d uploads (%s, %s/month)\n", 
+				region, count, units.FormatBytes(size), formatAmount(savings, result.Currency))
 		}
 	}
 	
 	if len(result.UploadsByStorageClass) > 1 {
 		fmt.Fprintf(s.outputWriter, "\nBreakdown by storage class:\n")
-		for storageClass, count := range result.UploadsByStorageClass {
-			size := result.SizeByStorageClass[storageClass]
+		for _, storageClass := range sortedKeysByValue(result.SizeByStorageClass) {
+			count, size := result.UploadsByStorageClass[storageClass], result.SizeByStorageClass[storageClass]
 			savings := result.SavingsByStorageClass[storageClass]
-			fmt.Fprintf(s.outputWriter, "  %s: %d uploads (%s, $%.2f/month)\n", 
-				storageClass, count, FormatBytes(size), savings)
+			fmt.Fprintf(s.outputWriter, "  %s: %d uploads (%s, %s/month)\n", 
+				storageClass, count, units.FormatBytes(size), formatAmount(savings, result.Currency))
 		}
 	}
 	
 	if result.Filters != "" {
-		fmt.Fprintf(s.outputWriter, "\nFilters applied: %s\n", result.Filters)
+		fmt.Fprintf(s.outputWriter, "\nFilters applied: %s\n", pkgtypes.DisplayString(result.Filters))
 	}
+	s.reportFilterFunnel(result.Funnel)
 	
 	fmt.Fprintf(s.outputWriter, "\nTo execute this deletion, run the same command without --dry-run\n")
 }
 
-// deleteUploadsWithProgress deletes uploads with progress reporting
-func (s *UploadService) deleteUploadsWithProgress(ctx context.Context, uploads []pkgtypes.MultipartUpload) error {
-	if len(uploads) == 0 {
-		return nil
+// reportAutoExpiring reports the matching uploads left out because a
+// lifecycle rule will abort them, by bucket and rule
+func (s *UploadService) reportAutoExpiring(autoExpiring []pkgtypes.AutoExpiringUploads) {
+	if len(autoExpiring) == 0 {
+		return
+	}
+	
+	var count int
+	var size int64
+	for _, rule := range autoExpiring {
+		count += rule.Uploads
+		size += rule.Size
+	}
+	fmt.Fprintf(s.outputWriter, "  Excluded because lifecycle rules will abort them: %d uploads (%s)\n", count, units.FormatBytes(size))
+	for _, rule := range autoExpiring {
+		fmt.Fprintf(s.outputWriter, "    %s: %d uploads (%s), rule %q, first on %s\n",
+			pkgtypes.DisplayString(rule.Bucket), rule.Uploads, units.FormatBytes(rule.Size), pkgtypes.DisplayString(rule.RuleID), rule.FirstAbortDate.Format("2006-01-02"))
 	}
+}
 
-	startTime := time.Now()
-	progress := DeletionProgress{
-		TotalUploads:      len(uploads),
-		ProcessedUploads:  0,
-		SuccessfulDeletes: 0,
-		FailedDeletes:     0,
-		StartTime:         startTime,
-		Errors:            make([]DeletionError, 0),
+// reportFilterFunnel shows how many uploads are left after each condition of a
+// dry-run, and how many each condition matches on its own
+func (s *UploadService) reportFilterFunnel(funnel *pkgtypes.FilterFunnel) {
+	if funnel == nil {
+		return
+	}
+	
+	const all = "All uploads"
+	width := len(all)
+	clauses := make([]string, len(funnel.Steps))
+	for i, step := range funnel.Steps {
+		clauses[i] = pkgtypes.DisplayString(step.Clause)
+		if len(clauses[i]) > width {
+			width = len(clauses[i])
+		}
+	}
+	
+	fmt.Fprintf(s.outputWriter, "\nFilter funnel:\n")
+	fmt.Fprintf(s.outputWriter, "  %-*s  %d uploads (%s)\n", width, all, funnel.Uploads, units.FormatBytes(funnel.Size))
+	for i, step := range funnel.Steps {
+		fmt.Fprintf(s.outputWriter, "  %-*s  %d uploads (%s), %d (%s) on its own\n",
+			width, clauses[i], step.Uploads, units.FormatBytes(step.Size), step.Matched, units.FormatBytes(step.MatchedSize))
 	}
+}
 
-	type deleteResult struct {
-		upload pkgtypes.MultipartUpload
-		err    error
+// deleteUploadsWithProgress deletes uploads with progress reporting, passing
+// each failure to opts.OnFailure and each outcome to opts.OnOutcome, if set,
+// as it happens. With opts.OnAlreadyCleaned, uploads S3 no longer has are
+// passed to it instead.
+func (s *UploadService) deleteUploadsWithProgress(ctx context.Context, uploads []pkgtypes.MultipartUpload, opts pkgtypes.DeleteOptions) error {
+	if len(uploads) == 0 {
+		return nil
 	}
 
-	resultChan := make(chan deleteResult, len(uploads))
-	semaphore := make(chan struct{}, s.concurrency)
+	startTime := time.Now()
+	progress := newProgressState("", len(uploads), startTime)
 
-	var wg sync.WaitGroup
-	var processedCount int64
-	var successCount int64
-	var failedCount int64
 	var errors []DeletionError
 	var errorsMutex sync.Mutex
+	var totalStorageFreed int64
 
-	// Progress reporting goroutine
-	progressTicker := time.NewTicker(1 * time.Second)
-	defer progressTicker.Stop()
+	// Workers only update counters; one goroutine reads them for the reporter
+	stopProgress := make(chan struct{})
+	interval := s.progressInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	progressStopped := reportEvery(ctx, s.progressReporter, progress, interval, stopProgress)
 
-	progressDone := make(chan struct{})
+	// A fixed pool of workers takes the uploads bucket by bucket, so the
+	// current bucket on the progress line is the one being deleted from and
+	// each worker looks its client up once per bucket. After an interrupt the
+	// rest still pass through, failing at once, so every upload is recorded.
+	next := make(chan int, s.concurrency)
 	go func() {
-		defer close(progressDone)
-		for {
-			select {
-			case <-progressTicker.C:
-				progress.ProcessedUploads = atomic.LoadInt64(&processedCount)
-				progress.SuccessfulDeletes = atomic.LoadInt64(&successCount)
-				progress.FailedDeletes = atomic.LoadInt64(&failedCount)
-				s.progressReporter.ReportProgress(progress)
-			case <-ctx.Done():
-				return
-			case <-progressDone:
-				return
-			}
+		defer close(next)
+		for _, i := range deletionOrder(uploads) {
+			next <- i
 		}
 	}()
 
-	// Delete each upload concurrently
-	for _, upload := range uploads {
+	var wg sync.WaitGroup
+	for w := 0; w < s.concurrency; w++ {
 		wg.Add(1)
-		go func(u pkgtypes.MultipartUpload) {
+		go func() {
 			defer wg.Done()
-
-			// Acquire semaphore
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
-			// Update current bucket for progress reporting
-			progress.CurrentBucket = u.Bucket
-
-			err := s.DeleteUpload(ctx, u)
-			
-			atomic.AddInt64(&processedCount, 1)
-			
-			if err != nil {
-				atomic.AddInt64(&failedCount, 1)
-				errorsMutex.Lock()
-				errors = append(errors, DeletionError{
-					Upload: u,
-					Error:  err,
-					Time:   time.Now(),
-				})
-				errorsMutex.Unlock()
-			} else {
-				atomic.AddInt64(&successCount, 1)
+			var started bool
+			var bucket string
+			var client S3UploadClientInterface
+			var clientErr error
+			for i := range next {
+				u := uploads[i]
+				if !started || u.Bucket != bucket {
+					started, bucket = true, u.Bucket
+					progress.begin(bucket)
+					client, clientErr = s.deleteClient(ctx, u)
+				}
+
+				err := u.Validate()
+				if err != nil {
+					err = fmt.Errorf("invalid upload: %w", err)
+				} else if err = clientErr; err == nil {
+					err = abortUpload(ctx, client, u)
+				}
+				if opts.OnAlreadyCleaned != nil && isNoSuchUpload(err) {
+					progress.finish(nil)
+					opts.OnAlreadyCleaned(u)
+					continue
+				}
+
+				if err != nil {
+					deletionError := DeletionError{
+						Upload: u,
+						Error:  err,
+						Time:   time.Now(),
+					}
+					errorsMutex.Lock()
+					errors = append(errors, deletionError)
+					errorsMutex.Unlock()
+					s.progressReporter.ReportError(deletionError)
+					if opts.OnFailure != nil {
+						opts.OnFailure(u, err)
+					}
+				} else {
+					atomic.AddInt64(&totalStorageFreed, u.Size)
+				}
+				progress.finish(err)
+				if opts.OnOutcome != nil {
+					opts.OnOutcome(u, err)
+				}
 			}
-
-			resultChan <- deleteResult{upload: u, err: err}
-		}(upload)
+		}()
 	}
+	wg.Wait()
 
-	// Close channel when all goroutines complete
-	go func() {
-		wg.Wait()
-		close(resultChan)
-		progressDone <- struct{}{}
-	}()
-
-	// Collect results
-	var totalStorageFreed int64
-	for result := range resultChan {
-		if result.err == nil {
-			totalStorageFreed += result.upload.Size
-		}
-	}
-
-	// Final progress report
-	progress.ProcessedUploads = atomic.LoadInt64(&processedCount)
-	progress.SuccessfulDeletes = atomic.LoadInt64(&successCount)
-	progress.FailedDeletes = atomic.LoadInt64(&failedCount)
-	s.progressReporter.ReportProgress(progress)
+	// Final progress report, once the ticker can no longer report
+	close(stopProgress)
+	<-progressStopped
+	final := progress.snapshot()
+	s.progressReporter.ReportProgress(final)
 
 	// Report completion
 	result := DeletionResult{
 		TotalProcessed:    len(uploads),
-		SuccessfulDeletes: int(atomic.LoadInt64(&successCount)),
-		FailedDeletes:     int(atomic.LoadInt64(&failedCount)),
+		SuccessfulDeletes: int(final.SuccessfulDeletes),
+		FailedDeletes:     int(final.FailedDeletes),
 		StorageFreed:      totalStorageFreed,
 		Duration:          time.Since(startTime),
 		Errors:            errors,
@@ -750,15 +1723,51 @@ func (s *UploadService) deleteUploadsWithProgress(ctx context.Context, uploads [
 	s.progressReporter.ReportCompletion(result)
 
 	if len(errors) > 0 {
-		return fmt.Errorf("failed to delete %d out of %d uploads", len(errors), len(uploads))
+		return &DeletionFailuresError{Failed: len(errors), Total: len(uploads), Err: errors[0].Error}
 	}
 
 	return nil
 }
 
+// deletionOrder returns the indexes of uploads grouped by region and then
+// bucket, each in the order it first appears, keeping the order of the
+// uploads within a bucket
+func deletionOrder(uploads []pkgtypes.MultipartUpload) []int {
+	regionRank := make(map[string]int)
+	bucketRank := make(map[string]int)
+	regions := make([]int, len(uploads))
+	buckets := make([]int, len(uploads))
+	order := make([]int, len(uploads))
+	for i, u := range uploads {
+		if _, ok := regionRank[u.Region]; !ok {
+			regionRank[u.Region] = len(regionRank)
+		}
+		if _, ok := bucketRank[u.Bucket]; !ok {
+			bucketRank[u.Bucket] = len(bucketRank)
+		}
+		regions[i], buckets[i], order[i] = regionRank[u.Region], bucketRank[u.Bucket], i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		if regions[i] != regions[j] {
+			return regions[i] < regions[j]
+		}
+		return buckets[i] < buckets[j]
+	})
+	return order
+}
+
 // deleteUploadsParallel deletes uploads in parallel (legacy method for backward compatibility)
 func (s *UploadService) deleteUploadsParallel(ctx context.Context, uploads []pkgtypes.MultipartUpload) error {
-	return s.deleteUploadsWithProgress(ctx, uploads)
+	return s.deleteUploadsWithProgress(ctx, uploads, pkgtypes.DeleteOptions{})
+}
+
+// classifier returns the initiator classifier, defaulting to the built-in rules
+func (s *UploadService) classifier() *InitiatorClassifier {
+	if s.initiatorClassifier == nil {
+		return defaultInitiatorClassifier()
+	}
+	return s.initiatorClassifier
 }
 
 // getRegionalClient returns a region-specific S3 client, creating it if needed
@@ -788,11 +1797,16 @@ func (s *UploadService) getRegionalClient(ctx context.Context, region string) (S
 		return client, nil
 	}
 
-	// Create AWS client wrapper for this region
-	clientConfig := awsclient.ClientConfig{
-		Region:    region,
-		RateLimit: 10.0,
+	// Regional clients share the main client's credentials, including assumed roles
+	if parent, ok := s.client.(*awsclient.S3Client); ok && parent != nil {
+		client := parent.ForRegion(region)
+		s.regionalClients[region] = client
+		return client, nil
 	}
+
+	// Create AWS client wrapper for this region
+	clientConfig := s.clientConfig
+	clientConfig.Region = region
 	
 	client, err := awsclient.NewS3Client(ctx, clientConfig)
 	if err != nil {
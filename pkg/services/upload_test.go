@@ -0,0 +1,755 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/Garvitkul/s3mpc/internal/logging"
+	"github.com/Garvitkul/s3mpc/internal/prompt"
+	"github.com/Garvitkul/s3mpc/pkg/types"
+)
+
+func TestDeleteUploadsConfirmation(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.bin", UploadID: "u1", Initiated: time.Now().Add(-48 * time.Hour), StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		noInput bool
+		aborts  int32
+		wantErr error
+	}{
+		{name: "no-input declines", noInput: true, wantErr: prompt.ErrConfirmationRequired},
+		{name: "interactive yes", input: "y\n", aborts: 1},
+		{name: "interactive no", input: "n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var aborts int32
+			client := &mockAbortClient{onAbort: func() { atomic.AddInt32(&aborts, 1) }}
+			service := &UploadService{
+				client:           client,
+				regionalClients:  map[string]S3UploadClientInterface{"us-east-1": client},
+				concurrency:      1,
+				progressReporter: NewConsoleProgressReporter(io.Discard, true),
+				prompter:         prompt.New(strings.NewReader(tt.input), io.Discard, tt.noInput),
+				outputWriter:     io.Discard,
+			}
+
+			err := service.DeleteUploads(context.Background(), uploads, types.DeleteOptions{})
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("DeleteUploads() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && tt.aborts == 0 && err == nil {
+				t.Error("DeleteUploads() declined without an error")
+			}
+			if aborts != tt.aborts {
+				t.Errorf("aborted %d uploads, want %d", aborts, tt.aborts)
+			}
+		})
+	}
+
+	// --force never prompts, even under no-input
+	var aborts int32
+	client := &mockAbortClient{onAbort: func() { atomic.AddInt32(&aborts, 1) }}
+	service := &UploadService{
+		client:           client,
+		regionalClients:  map[string]S3UploadClientInterface{"us-east-1": client},
+		concurrency:      1,
+		progressReporter: NewConsoleProgressReporter(io.Discard, true),
+		prompter:         prompt.New(nil, io.Discard, true),
+		outputWriter:     io.Discard,
+	}
+	if err := service.DeleteUploads(context.Background(), uploads, types.DeleteOptions{Force: true}); err != nil || aborts != 1 {
+		t.Errorf("DeleteUploads(--force) = %v with %d aborts, want 1 abort", err, aborts)
+	}
+
+	// Without a prompter the service never reads stdin; it declines as under no-input
+	service = NewUploadServiceWithConcurrency(nil, nil, nil, 1).(*UploadService)
+	service.client = client
+	service.regionalClients["us-east-1"] = client
+	if err := service.DeleteUploads(context.Background(), uploads, types.DeleteOptions{}); !errors.Is(err, prompt.ErrConfirmationRequired) {
+		t.Errorf("DeleteUploads() without a prompter error = %v, want %v", err, prompt.ErrConfirmationRequired)
+	}
+}
+
+func TestConfirmationReportsMeasurementAge(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.bin", UploadID: "u1", Initiated: time.Now().Add(-48 * time.Hour), Size: 1024, MeasuredAt: time.Now().Add(-32 * time.Minute), StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "b.bin", UploadID: "u2", Initiated: time.Now().Add(-48 * time.Hour), Size: 1024, MeasuredAt: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+
+	var output strings.Builder
+	service := &UploadService{
+		concurrency:      1,
+		progressReporter: NewConsoleProgressReporter(io.Discard, true),
+		prompter:         prompt.New(strings.NewReader("n\n"), io.Discard, false),
+		outputWriter:     &output,
+	}
+	if _, err := service.promptForConfirmation(uploads, len(uploads), 2048, 0, nil, false); err != nil {
+		t.Fatalf("promptForConfirmation() error = %v", err)
+	}
+
+	// The oldest measurement is reported, with a warning since it is stale
+	for _, want := range []string{"Sizes measured 32 minutes ago", "--remeasure-older-than 15min"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("summary = %q, want %q", output.String(), want)
+		}
+	}
+
+	output.Reset()
+	service.prompter = prompt.New(strings.NewReader("n\n"), io.Discard, false)
+	if _, err := service.promptForConfirmation(uploads[1:], len(uploads[1:]), 1024, 0, nil, false); err != nil {
+		t.Fatalf("promptForConfirmation() error = %v", err)
+	}
+	if !strings.Contains(output.String(), "Sizes measured just now") || strings.Contains(output.String(), "Warning") {
+		t.Errorf("summary = %q, want a fresh measurement without a warning", output.String())
+	}
+}
+
+func TestConfirmationBreaksDownStorageClasses(t *testing.T) {
+	initiated := time.Now().Add(-48 * time.Hour)
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a.bin", UploadID: "u1", Initiated: initiated, Size: 1024, StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "logs", Key: "b.bin", UploadID: "u2", Initiated: initiated, Size: 2048, StorageClass: "GLACIER", Region: "us-east-1"},
+		{Bucket: "logs", Key: "c.bin", UploadID: "u3", Initiated: initiated, Size: 2048, StorageClass: "glacier", Region: "us-east-1"},
+	}
+
+	var output strings.Builder
+	service := &UploadService{
+		concurrency:      1,
+		progressReporter: NewConsoleProgressReporter(io.Discard, true),
+		prompter:         prompt.New(strings.NewReader("n\n"), io.Discard, false),
+		outputWriter:     &output,
+	}
+	if _, err := service.promptForConfirmation(uploads, len(uploads), 5120, 0, nil, false); err != nil {
+		t.Fatalf("promptForConfirmation() error = %v", err)
+	}
+	want := "Uploads per storage class:\n  GLACIER: 2 uploads (4.0 KB)\n  STANDARD: 1 uploads (1.0 KB)\n"
+	if !strings.Contains(output.String(), want) {
+		t.Errorf("summary = %q, want %q", output.String(), want)
+	}
+}
+
+func TestHostileKeysCannotCorruptTerminal(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs\x1b[2K", Key: hostileKey, UploadID: "u1", Initiated: time.Now().Add(-48 * time.Hour), StorageClass: "STANDARD", Region: "us-east-1"},
+	}
+
+	var output strings.Builder
+	service := &UploadService{
+		concurrency:      1,
+		progressReporter: NewConsoleProgressReporter(io.Discard, true),
+		prompter:         prompt.New(strings.NewReader("n\n"), io.Discard, false),
+		outputWriter:     &output,
+	}
+	if _, err := service.promptForConfirmation(uploads, len(uploads), 1024, 0, nil, false); err != nil {
+		t.Fatalf("promptForConfirmation() error = %v", err)
+	}
+	assertTerminalSafe(t, output.String(), 11)
+
+	// Deletion errors show the key escaped and cut to one line
+	var report strings.Builder
+	reporter := NewConsoleProgressReporter(&report, false)
+	reporter.ReportCompletion(DeletionResult{TotalProcessed: 1, FailedDeletes: 1, Errors: []DeletionError{
+		{Upload: uploads[0], Error: errors.New("failed to abort:\n\x1b[31mforged line"), Time: time.Now()},
+	}})
+	assertTerminalSafe(t, report.String(), 20)
+	for _, line := range strings.Split(report.String(), "\n") {
+		if displayWidth(line) > 200 {
+			t.Errorf("error line is %d columns wide, want the key truncated: %q", displayWidth(line), line)
+		}
+	}
+
+	// Keys beyond S3's limit are rejected before any API call
+	uploads[0].Key = strings.Repeat("k", types.MaxKeyLength+1)
+	if err := service.DeleteUpload(context.Background(), uploads[0]); err == nil || !strings.Contains(err.Error(), "S3 allows at most 1024") {
+		t.Errorf("DeleteUpload() error = %v, want the key length limit", err)
+	}
+}
+
+// regionBucketService implements interfaces.BucketService with buckets in
+// several regions and buckets whose region is unknown
+type regionBucketService struct {
+	staticBucketService
+	buckets []types.Bucket
+	skipped []types.SkippedBucket
+}
+
+func (b *regionBucketService) ListBuckets(ctx context.Context, region string) ([]types.Bucket, []types.SkippedBucket, error) {
+	return b.buckets, b.skipped, nil
+}
+
+func (b *regionBucketService) GetBucketRegion(ctx context.Context, bucketName string) (string, error) {
+	for _, bucket := range b.buckets {
+		if bucket.Name == bucketName {
+			return bucket.Region, nil
+		}
+	}
+	return "", errors.New("NoSuchBucket")
+}
+
+func TestListUploadsOnlyListsBucketsInRequestedRegions(t *testing.T) {
+	regional := &mockPartsClient{uploads: []s3types.MultipartUpload{{
+		Key:       aws.String("a.bin"),
+		UploadId:  aws.String("u1"),
+		Initiated: aws.Time(time.Now().Add(-48 * time.Hour)),
+	}}}
+	// Listing a bucket in ap-south-1 would fail the scan
+	service := &UploadService{
+		client: failingClient{},
+		bucketService: &regionBucketService{buckets: []types.Bucket{
+			{Name: "east", Region: "us-east-1"},
+			{Name: "mumbai", Region: "ap-south-1"},
+			{Name: "west", Region: "eu-west-1"},
+		}},
+		concurrency:     2,
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": regional, "eu-west-1": regional, "ap-south-1": failingClient{}},
+	}
+
+	ctx := context.Background()
+	uploads, err := service.ListUploads(ctx, types.ListOptions{Regions: []string{"us-east-1", "eu-west-1"}})
+	if err != nil {
+		t.Fatalf("ListUploads() error = %v", err)
+	}
+	buckets := map[string]bool{}
+	for _, upload := range uploads {
+		buckets[upload.Bucket] = true
+	}
+	if len(uploads) != 2 || !buckets["east"] || !buckets["west"] {
+		t.Errorf("ListUploads() = %+v, want the uploads of east and west only", uploads)
+	}
+
+	_, err = service.ListUploads(ctx, types.ListOptions{BucketName: "mumbai", Regions: []string{"us-east-1"}})
+	if err == nil || !strings.Contains(err.Error(), "bucket mumbai is in ap-south-1") {
+		t.Errorf("ListUploads(mumbai) error = %v, want the bucket rejected as outside the regions", err)
+	}
+
+	if _, err := service.ListUploads(ctx, types.ListOptions{Regions: []string{""}}); err == nil {
+		t.Error("ListUploads() with an empty region error = nil, want a validation error")
+	}
+
+	// Regions match whatever their case and surrounding whitespace
+	for _, opts := range []types.ListOptions{{Region: " US-EAST-1"}, {Regions: []string{"Us-East-1 "}}} {
+		uploads, err := service.ListUploads(ctx, opts)
+		if err != nil || len(uploads) != 1 || uploads[0].Bucket != "east" {
+			t.Errorf("ListUploads(%+v) = %+v, %v, want the upload of east", opts, uploads, err)
+		}
+	}
+	_, err = service.ListUploads(ctx, types.ListOptions{BucketName: "mumbai", Region: "us-east-1"})
+	if err == nil || !strings.Contains(err.Error(), "not in the requested region us-east-1") {
+		t.Errorf("ListUploads(mumbai) with a region error = %v, want the bucket rejected as outside it", err)
+	}
+
+	// Excluded buckets are not listed, unless one is asked for by name
+	uploads, err = service.ListUploads(ctx, types.ListOptions{ExcludeBuckets: []string{"mumbai", "west"}})
+	if err != nil || len(uploads) != 1 || uploads[0].Bucket != "east" {
+		t.Errorf("ListUploads() excluding mumbai and west = %+v, %v, want the upload of east", uploads, err)
+	}
+	uploads, err = service.ListUploads(ctx, types.ListOptions{BucketName: "west", ExcludeBuckets: []string{"west"}})
+	if err != nil || len(uploads) != 1 || uploads[0].Bucket != "west" {
+		t.Errorf("ListUploads(west) excluding west = %+v, %v, want the upload of west", uploads, err)
+	}
+}
+
+func TestListUploadsRecordsSkippedBuckets(t *testing.T) {
+	skipped := []types.SkippedBucket{{Bucket: "locked", Error: "failed to get bucket location for locked: AccessDenied"}}
+	service := &UploadService{
+		client:          failingClient{},
+		bucketService:   &regionBucketService{buckets: []types.Bucket{{Name: "east", Region: "us-east-1"}}, skipped: skipped},
+		concurrency:     2,
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": &mockPartsClient{}},
+	}
+
+	// Skipped buckets are reported whatever regions were requested
+	ctx := context.Background()
+	if _, err := service.ListUploads(ctx, types.ListOptions{Regions: []string{"eu-west-1"}}); err != nil {
+		t.Fatalf("ListUploads() error = %v", err)
+	}
+	if got := service.SkippedBuckets(); len(got) != 1 || got[0] != skipped[0] {
+		t.Errorf("SkippedBuckets() = %+v, want %+v", got, skipped)
+	}
+
+	// A listing of one bucket skips none
+	if _, err := service.ListUploads(ctx, types.ListOptions{BucketName: "east"}); err != nil {
+		t.Fatalf("ListUploads(east) error = %v", err)
+	}
+	if got := service.SkippedBuckets(); len(got) != 0 {
+		t.Errorf("SkippedBuckets() after listing one bucket = %+v, want none", got)
+	}
+}
+
+// prefixRecordingClient records the Prefix of each ListMultipartUploads call
+type prefixRecordingClient struct {
+	mockPartsClient
+	prefixes []*string
+}
+
+func (c *prefixRecordingClient) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (*s3.ListMultipartUploadsOutput, error) {
+	c.prefixes = append(c.prefixes, input.Prefix)
+	return c.mockPartsClient.ListMultipartUploads(ctx, input)
+}
+
+func TestListUploadsSendsKeyPrefix(t *testing.T) {
+	client := &prefixRecordingClient{}
+	service := &UploadService{
+		client:          failingClient{},
+		bucketService:   &staticBucketService{bucket: types.Bucket{Name: "logs", Region: "us-east-1"}},
+		concurrency:     1,
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": client},
+	}
+
+	ctx := context.Background()
+	if _, err := service.ListUploads(ctx, types.ListOptions{KeyPrefix: "logs/2023/"}); err != nil {
+		t.Fatalf("ListUploads() error = %v", err)
+	}
+	if _, err := service.ListUploads(ctx, types.ListOptions{}); err != nil {
+		t.Fatalf("ListUploads() error = %v", err)
+	}
+	if len(client.prefixes) != 2 || aws.ToString(client.prefixes[0]) != "logs/2023/" || client.prefixes[1] != nil {
+		t.Errorf("ListMultipartUploads prefixes = %v, want logs/2023/ then none", client.prefixes)
+	}
+
+	// Uploads loaded from a file are matched locally, as S3 would
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "logs/2023/a.bin", UploadID: "u1", Initiated: time.Now(), StorageClass: "STANDARD"},
+		{Bucket: "logs", Key: "Logs/2023/b.bin", UploadID: "u2", Initiated: time.Now(), StorageClass: "STANDARD"},
+	}
+	filtered, _ := service.filterUploadsForDeletion(uploads, types.DeleteOptions{KeyPrefix: "logs/2023/"})
+	if len(filtered) != 1 || filtered[0].UploadID != "u1" {
+		t.Errorf("filterUploadsForDeletion() = %+v, want only the case-sensitive match", filtered)
+	}
+}
+
+func TestListUploadsRecordsInitiatorAndOwner(t *testing.T) {
+	regional := &mockPartsClient{uploads: []s3types.MultipartUpload{
+		{
+			Key:       aws.String("a.bin"),
+			UploadId:  aws.String("u1"),
+			Initiated: aws.Time(time.Now().Add(-48 * time.Hour)),
+			Initiator: &s3types.Initiator{ID: aws.String("arn:aws:iam::123456789012:user/alice"), DisplayName: aws.String("alice")},
+			Owner:     &s3types.Owner{ID: aws.String("75aa57f09aa0c8caeab4f8c24e99d10f"), DisplayName: aws.String("ops")},
+		},
+		// S3-compatible stores may leave both out
+		{Key: aws.String("b.bin"), UploadId: aws.String("u2"), Initiated: aws.Time(time.Now().Add(-48 * time.Hour))},
+	}}
+	service := &UploadService{
+		client:          failingClient{},
+		bucketService:   &regionBucketService{buckets: []types.Bucket{{Name: "east", Region: "us-east-1"}}},
+		concurrency:     1,
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": regional},
+	}
+
+	uploads, err := service.ListUploads(context.Background(), types.ListOptions{})
+	if err != nil {
+		t.Fatalf("ListUploads() error = %v", err)
+	}
+	if len(uploads) != 2 {
+		t.Fatalf("ListUploads() = %+v, want 2 uploads", uploads)
+	}
+	alice := uploads[0]
+	if alice.Initiator != "arn:aws:iam::123456789012:user/alice" || alice.InitiatorName != "alice" || alice.Owner != "ops" || alice.OwnerID != "75aa57f09aa0c8caeab4f8c24e99d10f" {
+		t.Errorf("upload = %+v, want the initiator and owner", alice)
+	}
+	if anonymous := uploads[1]; anonymous.Initiator != "" || anonymous.Owner != "" || anonymous.Validate() != nil {
+		t.Errorf("upload without initiator or owner = %+v, validation error %v", anonymous, anonymous.Validate())
+	}
+}
+
+// inFlightClient records the most ListMultipartUploads and AbortMultipartUpload
+// calls in flight at once, holding each call for a moment so they overlap
+type inFlightClient struct {
+	mockAbortClient
+	listing, aborting       atomic.Int32
+	maxListing, maxAborting atomic.Int32
+}
+
+func (c *inFlightClient) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (*s3.ListMultipartUploadsOutput, error) {
+	track(&c.listing, &c.maxListing)
+	return &s3.ListMultipartUploadsOutput{}, nil
+}
+
+func (c *inFlightClient) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	track(&c.aborting, &c.maxAborting)
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+// track counts a call in flight for a moment and raises max to the peak
+func track(current, max *atomic.Int32) {
+	n := current.Add(1)
+	defer current.Add(-1)
+	for {
+		peak := max.Load()
+		if n <= peak || max.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestBucketAndOpConcurrencyAreIndependent(t *testing.T) {
+	client := &inFlightClient{}
+	service := &UploadService{
+		client:           client,
+		regionalClients:  map[string]S3UploadClientInterface{"us-east-1": client},
+		concurrency:      8,
+		progressReporter: NewConsoleProgressReporter(io.Discard, true),
+	}
+	service.SetBucketConcurrency(2)
+
+	var buckets []types.Bucket
+	var uploads []types.MultipartUpload
+	for i := 0; i < 12; i++ {
+		buckets = append(buckets, types.Bucket{Name: fmt.Sprintf("bucket-%02d", i), Region: "us-east-1"})
+	}
+	for i := 0; i < 32; i++ {
+		uploads = append(uploads, types.MultipartUpload{Bucket: "bucket-00", Key: fmt.Sprintf("k%02d", i), UploadID: "u", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"})
+	}
+
+	if _, err := service.listUploadsForBuckets(context.Background(), buckets, types.ListOptions{}); err != nil {
+		t.Fatalf("listUploadsForBuckets() error = %v", err)
+	}
+	if err := service.deleteUploadsWithProgress(context.Background(), uploads, types.DeleteOptions{}); err != nil {
+		t.Fatalf("deleteUploadsWithProgress() error = %v", err)
+	}
+
+	if got := client.maxListing.Load(); got != 2 {
+		t.Errorf("%d buckets listed at once, want the bucket concurrency of 2", got)
+	}
+	if got := client.maxAborting.Load(); got != 8 {
+		t.Errorf("%d deletes at once, want the op concurrency of 8", got)
+	}
+
+	// Without a bucket concurrency, buckets follow the op concurrency as --concurrency alone did
+	service.SetBucketConcurrency(0)
+	client.maxListing.Store(0)
+	if _, err := service.listUploadsForBuckets(context.Background(), buckets, types.ListOptions{}); err != nil {
+		t.Fatalf("listUploadsForBuckets() error = %v", err)
+	}
+	if got := client.maxListing.Load(); got != 8 {
+		t.Errorf("%d buckets listed at once without a bucket concurrency, want 8", got)
+	}
+}
+
+// failingBucketClient lists one upload in every bucket except archive, which is denied
+type failingBucketClient struct {
+	mockAbortClient
+}
+
+func (c *failingBucketClient) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (*s3.ListMultipartUploadsOutput, error) {
+	if aws.ToString(input.Bucket) == "archive" {
+		return nil, errors.New("AccessDenied: Access Denied")
+	}
+	return &s3.ListMultipartUploadsOutput{Uploads: []s3types.MultipartUpload{
+		{Key: aws.String("a.tmp"), UploadId: aws.String("1"), Initiated: aws.Time(time.Now())},
+	}}, nil
+}
+
+func TestListUploadsReturnsBucketErrors(t *testing.T) {
+	client := &failingBucketClient{}
+	var logs strings.Builder
+	service := &UploadService{
+		client:          client,
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": client, "eu-west-1": client},
+		concurrency:     2,
+		logger:          logging.NewLogger(logging.LevelDebug, &logs, false),
+	}
+	buckets := []types.Bucket{{Name: "logs", Region: "us-east-1"}, {Name: "archive", Region: "eu-west-1"}, {Name: "media", Region: "us-east-1"}}
+
+	uploads, err := service.listUploadsForBuckets(context.Background(), buckets, types.ListOptions{})
+	if len(uploads) != 2 {
+		t.Errorf("listed %d uploads, want the 2 of the buckets that could be listed", len(uploads))
+	}
+	var failed types.BucketErrors
+	if !errors.As(err, &failed) {
+		t.Fatalf("error = %v, want BucketErrors", err)
+	}
+	if len(failed) != 1 || failed[0].Bucket != "archive" || failed[0].Region != "eu-west-1" || !strings.Contains(failed[0].Err.Error(), "AccessDenied") {
+		t.Errorf("failed buckets = %+v, want archive in eu-west-1 with its error", failed)
+	}
+	// The failure is a diagnostic for the logger; the caller decides what the user sees
+	if got := logs.String(); !strings.Contains(got, "bucket=archive") || !strings.Contains(got, "AccessDenied") {
+		t.Errorf("logged %q, want the failed bucket and its error", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := service.listUploadsForBuckets(ctx, buckets, types.ListOptions{}); !errors.Is(err, context.Canceled) || errors.As(err, &failed) {
+		t.Errorf("cancelled listing error = %v, want context.Canceled", err)
+	}
+}
+
+// stuckBucketClient lists one upload in every bucket except archive, whose
+// listing hangs until the context ends
+type stuckBucketClient struct {
+	mockAbortClient
+}
+
+func (c *stuckBucketClient) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (*s3.ListMultipartUploadsOutput, error) {
+	if aws.ToString(input.Bucket) == "archive" {
+		<-ctx.Done()
+		return nil, fmt.Errorf("ListMultipartUploads on bucket archive: %w", ctx.Err())
+	}
+	return &s3.ListMultipartUploadsOutput{Uploads: []s3types.MultipartUpload{
+		{Key: aws.String("a.tmp"), UploadId: aws.String("1"), Initiated: aws.Time(time.Now())},
+	}}, nil
+}
+
+func TestListingThatRunsOutOfTimeKeepsPartialResults(t *testing.T) {
+	client := &stuckBucketClient{}
+	service := &UploadService{
+		client:          client,
+		regionalClients: map[string]S3UploadClientInterface{"us-east-1": client},
+		concurrency:     3,
+	}
+	buckets := []types.Bucket{{Name: "logs", Region: "us-east-1"}, {Name: "archive", Region: "us-east-1"}, {Name: "media", Region: "us-east-1"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	uploads, err := service.listUploadsForBuckets(ctx, buckets, types.ListOptions{})
+	if len(uploads) != 2 {
+		t.Errorf("listed %d uploads, want the 2 of the buckets listed before the deadline", len(uploads))
+	}
+	var failed types.BucketErrors
+	if !errors.As(err, &failed) || len(failed) != 1 || failed[0].Bucket != "archive" || !errors.Is(failed[0].Err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want archive reported as not finished before the deadline", err)
+	}
+}
+
+// pagedClient answers ListParts and ListMultipartUploads with the page
+// stored under the marker of each request, "" for the first page
+type pagedClient struct {
+	mockAbortClient
+	partPages   map[string]*s3.ListPartsOutput
+	uploadPages map[string]*s3.ListMultipartUploadsOutput
+	markers     []string // marker of each call, "<nil>" when none was sent
+}
+
+func (c *pagedClient) ListParts(ctx context.Context, input *s3.ListPartsInput) (*s3.ListPartsOutput, error) {
+	c.markers = append(c.markers, aws.ToString(input.PartNumberMarker))
+	if input.PartNumberMarker == nil {
+		c.markers[len(c.markers)-1] = "<nil>"
+	}
+	return c.partPages[aws.ToString(input.PartNumberMarker)], nil
+}
+
+func (c *pagedClient) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (*s3.ListMultipartUploadsOutput, error) {
+	c.markers = append(c.markers, aws.ToString(input.KeyMarker)+"/"+aws.ToString(input.UploadIdMarker))
+	if input.KeyMarker == nil {
+		c.markers[len(c.markers)-1] = "<nil>"
+	}
+	return c.uploadPages[aws.ToString(input.KeyMarker)], nil
+}
+
+// partsPage returns a page of count parts of 5 MiB numbered from first
+func partsPage(first, count int, next string) *s3.ListPartsOutput {
+	page := &s3.ListPartsOutput{IsTruncated: aws.Bool(next != ""), NextPartNumberMarker: aws.String(next)}
+	for i := 0; i < count; i++ {
+		page.Parts = append(page.Parts, s3types.Part{PartNumber: aws.Int32(int32(first + i)), Size: aws.Int64(5 << 20)})
+	}
+	return page
+}
+
+func TestGetUploadDetailFollowsPartPages(t *testing.T) {
+	upload := types.MultipartUpload{Bucket: "logs", Key: "big.bin", UploadID: "u", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"}
+	newService := func(client *pagedClient) *UploadService {
+		return &UploadService{client: client, regionalClients: map[string]S3UploadClientInterface{"us-east-1": client}}
+	}
+
+	client := &pagedClient{partPages: map[string]*s3.ListPartsOutput{
+		"":     partsPage(1, 1000, "1000"),
+		"1000": partsPage(1001, 1000, "2000"),
+		"2000": partsPage(2001, 500, ""),
+	}}
+	detail, err := newService(client).GetUploadDetail(context.Background(), upload)
+	if err != nil {
+		t.Fatalf("GetUploadDetail() error = %v", err)
+	}
+	if detail.Size != 2500*(5<<20) || detail.PartsCount != 2500 {
+		t.Errorf("GetUploadDetail() = %d bytes in %d parts, want %d bytes in 2500 parts", detail.Size, detail.PartsCount, 2500*(5<<20))
+	}
+	if got := strings.Join(client.markers, ","); got != "<nil>,1000,2000" {
+		t.Errorf("ListParts markers = %s, want exactly three calls: none, 1000 and 2000", got)
+	}
+	if detail.Parts != nil {
+		t.Errorf("GetUploadDetail() kept %d parts, want none", len(detail.Parts))
+	}
+
+	// The parts are kept from the same walk when asked for
+	client.markers = nil
+	detail, err = newService(client).GetUploadDetailWithParts(context.Background(), upload)
+	if err != nil {
+		t.Fatalf("GetUploadDetailWithParts() error = %v", err)
+	}
+	if len(detail.Parts) != 2500 || detail.Parts[2499].PartNumber != 2500 || detail.Size != 2500*(5<<20) || len(client.markers) != 3 {
+		t.Errorf("GetUploadDetailWithParts() = %d parts, %d bytes in %d calls, want 2500 parts in 3 calls", len(detail.Parts), detail.Size, len(client.markers))
+	}
+
+	// Truncated pages that do not move the marker on are errors, not loops or short sizes
+	emptyMarker := partsPage(1, 1000, "")
+	emptyMarker.IsTruncated = aws.Bool(true)
+	for name, pages := range map[string]map[string]*s3.ListPartsOutput{
+		"empty marker":    {"": emptyMarker},
+		"repeated marker": {"": partsPage(1, 1000, "1000"), "1000": partsPage(1001, 1000, "1000")},
+	} {
+		client := &pagedClient{partPages: pages}
+		_, err := newService(client).GetUploadDetail(context.Background(), upload)
+		if err == nil || !strings.Contains(err.Error(), "truncated but has no new part number marker") {
+			t.Errorf("%s: GetUploadDetail() error = %v, want the stalled marker named", name, err)
+		}
+		if len(client.markers) > 2 {
+			t.Errorf("%s: ListParts called %d times, want the listing stopped", name, len(client.markers))
+		}
+	}
+}
+
+func TestListUploadsFollowsUploadPages(t *testing.T) {
+	uploadsPage := func(keys []string, nextKey, nextUploadID string) *s3.ListMultipartUploadsOutput {
+		page := &s3.ListMultipartUploadsOutput{IsTruncated: aws.Bool(nextKey != ""), NextKeyMarker: aws.String(nextKey), NextUploadIdMarker: aws.String(nextUploadID)}
+		for _, key := range keys {
+			page.Uploads = append(page.Uploads, s3types.MultipartUpload{Key: aws.String(key), UploadId: aws.String("u-" + key), Initiated: aws.Time(time.Now())})
+		}
+		return page
+	}
+	newService := func(client *pagedClient) *UploadService {
+		return &UploadService{
+			client:           client,
+			regionalClients:  map[string]S3UploadClientInterface{"us-east-1": client},
+			concurrency:      1,
+			progressReporter: NewConsoleProgressReporter(io.Discard, true),
+		}
+	}
+	buckets := []types.Bucket{{Name: "logs", Region: "us-east-1"}}
+
+	client := &pagedClient{uploadPages: map[string]*s3.ListMultipartUploadsOutput{
+		"":  uploadsPage([]string{"a", "b"}, "b", "u-b"),
+		"b": uploadsPage([]string{"c", "d"}, "d", "u-d"),
+		"d": uploadsPage([]string{"e"}, "", ""),
+	}}
+	uploads, err := newService(client).listUploadsForBuckets(context.Background(), buckets, types.ListOptions{})
+	if err != nil {
+		t.Fatalf("listUploadsForBuckets() error = %v", err)
+	}
+	if len(uploads) != 5 {
+		t.Errorf("listed %d uploads, want 5 across three pages", len(uploads))
+	}
+	if got := strings.Join(client.markers, ","); got != "<nil>,b/u-b,d/u-d" {
+		t.Errorf("ListMultipartUploads markers = %s, want exactly three calls", got)
+	}
+
+	client = &pagedClient{uploadPages: map[string]*s3.ListMultipartUploadsOutput{
+		"":  uploadsPage([]string{"a"}, "a", "u-a"),
+		"a": uploadsPage([]string{"a"}, "a", "u-a"),
+	}}
+	_, err = newService(client).listUploadsForBuckets(context.Background(), buckets, types.ListOptions{})
+	if err == nil || !strings.Contains(err.Error(), `repeats the marker "a"`) {
+		t.Errorf("listUploadsForBuckets() error = %v, want the repeated marker named", err)
+	}
+	if len(client.markers) != 2 {
+		t.Errorf("ListMultipartUploads called %d times on a repeated marker, want 2", len(client.markers))
+	}
+}
+
+func TestDeletionOrderGroupsByRegionAndBucket(t *testing.T) {
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "1", Region: "us-east-1"},
+		{Bucket: "media", Key: "2", Region: "eu-west-1"},
+		{Bucket: "backups", Key: "3", Region: "us-east-1"},
+		{Bucket: "logs", Key: "4", Region: "us-east-1"},
+		{Bucket: "media", Key: "5", Region: "eu-west-1"},
+	}
+	var keys []string
+	for _, i := range deletionOrder(uploads) {
+		keys = append(keys, uploads[i].Key)
+	}
+	if got := strings.Join(keys, ","); got != "1,4,3,2,5" {
+		t.Errorf("deletion order = %s, want us-east-1 logs, then backups, then eu-west-1 media", got)
+	}
+}
+
+func TestDeleteUploadsUsesAFixedWorkerPool(t *testing.T) {
+	var peak atomic.Int32
+	client := &mockAbortClient{onAbort: func() {
+		for n := int32(runtime.NumGoroutine()); ; {
+			current := peak.Load()
+			if n <= current || peak.CompareAndSwap(current, n) {
+				break
+			}
+		}
+	}}
+	service := &UploadService{
+		client:           client,
+		concurrency:      4,
+		regionalClients:  map[string]S3UploadClientInterface{"us-east-1": client},
+		progressReporter: NewConsoleProgressReporter(io.Discard, true),
+	}
+
+	uploads := make([]types.MultipartUpload, 5000)
+	for i := range uploads {
+		uploads[i] = types.MultipartUpload{Bucket: fmt.Sprintf("bucket-%d", i%7), Key: fmt.Sprintf("k%d", i), UploadID: "u", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"}
+	}
+	before := runtime.NumGoroutine()
+	if err := service.deleteUploadsWithProgress(context.Background(), uploads, types.DeleteOptions{}); err != nil {
+		t.Fatalf("deleteUploadsWithProgress() error = %v", err)
+	}
+	// The workers, the feed and the progress ticker, not a goroutine per upload
+	if got := int(peak.Load()) - before; got > 10 {
+		t.Errorf("%d goroutines during deletion, want the 4 workers and a few helpers", got)
+	}
+}
+
+func TestDeleteUploadsAbortsThroughRegionalClients(t *testing.T) {
+	main, east, west := &accessClient{}, &accessClient{}, &accessClient{}
+	service := &UploadService{
+		client:           main,
+		concurrency:      2,
+		regionalClients:  map[string]S3UploadClientInterface{"us-east-1": east, "eu-west-1": west},
+		progressReporter: NewConsoleProgressReporter(io.Discard, true),
+	}
+
+	uploads := []types.MultipartUpload{
+		{Bucket: "logs", Key: "a", UploadID: "1", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "media", Key: "b", UploadID: "2", Initiated: time.Now(), StorageClass: "STANDARD", Region: "eu-west-1"},
+		{Bucket: "logs", Key: "c", UploadID: "3", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"},
+		{Bucket: "archive", Key: "d", UploadID: "4", Initiated: time.Now(), StorageClass: "STANDARD", Region: "eu-west-1"},
+	}
+	if err := service.deleteUploadsWithProgress(context.Background(), uploads, types.DeleteOptions{}); err != nil {
+		t.Fatalf("deleteUploadsWithProgress() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		region  string
+		client  *accessClient
+		buckets string
+	}{
+		{"us-east-1", east, "logs,logs"},
+		{"eu-west-1", west, "archive,media"},
+	} {
+		var buckets []string
+		for _, abort := range tt.client.aborts {
+			buckets = append(buckets, *abort.Bucket)
+		}
+		sort.Strings(buckets)
+		if got := strings.Join(buckets, ","); got != tt.buckets {
+			t.Errorf("%s client aborted uploads in %s, want %s", tt.region, got, tt.buckets)
+		}
+	}
+	if len(main.aborts) != 0 {
+		t.Errorf("main client aborted %d uploads, want them all sent to their region", len(main.aborts))
+	}
+}
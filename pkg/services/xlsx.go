@@ -0,0 +1,312 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// xlsxCellKind is the type of a worksheet cell, which selects its style
+type xlsxCellKind int
+
+const (
+	xlsxText xlsxCellKind = iota
+	xlsxInteger
+	xlsxDecimal
+	xlsxDate
+)
+
+// Style indexes into the cellXfs of xlsxStyles, one per cell kind plus the header
+const (
+	xlsxStyleDefault = 0
+	xlsxStyleHeader  = 1
+	xlsxStyleDate    = 2
+	xlsxStyleInteger = 3
+	xlsxStyleDecimal = 4
+)
+
+// xlsxMaxColumnWidth is the widest an auto-sized column gets, in characters
+const xlsxMaxColumnWidth = 60
+
+// xlsxMaxRows is the most rows a worksheet holds, its header row included
+const xlsxMaxRows = 1048576
+
+// xlsxCell is one cell of a worksheet. Empty text cells are left out.
+type xlsxCell struct {
+	kind   xlsxCellKind
+	text   string
+	number float64
+}
+
+// xlsxString returns a text cell
+func xlsxString(s string) xlsxCell {
+	return xlsxCell{kind: xlsxText, text: s}
+}
+
+// xlsxInt returns a whole number cell
+func xlsxInt(n int64) xlsxCell {
+	return xlsxCell{kind: xlsxInteger, number: float64(n)}
+}
+
+// xlsxFloat returns a decimal number cell
+func xlsxFloat(n float64) xlsxCell {
+	return xlsxCell{kind: xlsxDecimal, number: n}
+}
+
+// xlsxTime returns a date cell, or an empty cell for the zero time
+func xlsxTime(t time.Time) xlsxCell {
+	if t.IsZero() {
+		return xlsxString("")
+	}
+	return xlsxCell{kind: xlsxDate, number: xlsxSerialDate(t)}
+}
+
+// xlsxEpoch is day zero of spreadsheet serial dates
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// xlsxSerialDate converts t to a spreadsheet serial date in UTC: days since
+// xlsxEpoch, with the time of day as the fraction
+func xlsxSerialDate(t time.Time) float64 {
+	return t.UTC().Sub(xlsxEpoch).Hours() / 24
+}
+
+// width returns the number of characters the cell shows
+func (c xlsxCell) width() int {
+	switch c.kind {
+	case xlsxDate:
+		return len("2006-01-02 15:04:05")
+	case xlsxInteger:
+		n := int64(c.number)
+		return len(strconv.FormatInt(n, 10)) + len(strconv.FormatInt(n, 10))/3
+	case xlsxDecimal:
+		return len(strconv.FormatFloat(c.number, 'f', 2, 64)) + 2
+	}
+	return displayWidth(c.text)
+}
+
+// xlsxSheet is a worksheet with a frozen header row
+type xlsxSheet struct {
+	name   string
+	header []string
+	rows   [][]xlsxCell
+}
+
+// writeXLSX writes sheets as an Office Open XML workbook. Strings are written
+// inline, so the workbook needs no shared string table.
+func writeXLSX(w io.Writer, sheets []xlsxSheet) error {
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes(len(sheets))},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook(sheets)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels(len(sheets))},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, file := range files {
+		if err := writeZipFile(archive, file.name, file.content); err != nil {
+			return err
+		}
+	}
+	for i, sheet := range sheets {
+		name := fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)
+		file, err := archive.Create(name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to workbook: %w", name, err)
+		}
+		buffered := bufio.NewWriter(file)
+		writeXLSXWorksheet(buffered, sheet)
+		if err := buffered.Flush(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to finish workbook: %w", err)
+	}
+	return nil
+}
+
+// writeZipFile adds a compressed file to archive
+func writeZipFile(archive *zip.Writer, name, content string) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to workbook: %w", name, err)
+	}
+	if _, err := io.WriteString(file, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+const xlsxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+const xlsxRootRels = xlsxHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// xlsxStyles defines the cell styles: default, bold header, date and time,
+// whole number with thousands separators, and two-decimal number
+const xlsxStyles = xlsxHeader + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="5">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="3" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+// xlsxContentTypes lists the parts of a workbook with sheets worksheets
+func xlsxContentTypes(sheets int) string {
+	var b strings.Builder
+	b.WriteString(xlsxHeader)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+// xlsxWorkbook lists the sheets by name
+func xlsxWorkbook(sheets []xlsxSheet) string {
+	var b strings.Builder
+	b.WriteString(xlsxHeader)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xlsxEscape(sheet.name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	return b.String()
+}
+
+// xlsxWorkbookRels links the workbook to its sheets and styles
+func xlsxWorkbookRels(sheets int) string {
+	var b strings.Builder
+	b.WriteString(xlsxHeader)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheets+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+// writeXLSXWorksheet writes a sheet with its header row frozen and
+// filterable, and each column as wide as its widest cell. Write errors are
+// reported by flushing w.
+func writeXLSXWorksheet(w *bufio.Writer, sheet xlsxSheet) {
+	widths := make([]int, len(sheet.header))
+	for i, name := range sheet.header {
+		widths[i] = displayWidth(name)
+	}
+	for _, row := range sheet.rows {
+		for i, cell := range row {
+			if i < len(widths) && cell.width() > widths[i] {
+				widths[i] = cell.width()
+			}
+		}
+	}
+
+	w.WriteString(xlsxHeader)
+	w.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	w.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	w.WriteString(`<cols>`)
+	for i, width := range widths {
+		if width > xlsxMaxColumnWidth {
+			width = xlsxMaxColumnWidth
+		}
+		fmt.Fprintf(w, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width+2)
+	}
+	w.WriteString(`</cols><sheetData>`)
+
+	w.WriteString(`<row r="1">`)
+	for i, name := range sheet.header {
+		fmt.Fprintf(w, `<c r="%s1" s="%d" t="inlineStr"><is><t>%s</t></is></c>`, xlsxColumn(i), xlsxStyleHeader, xlsxEscape(name))
+	}
+	w.WriteString(`</row>`)
+
+	for r, row := range sheet.rows {
+		fmt.Fprintf(w, `<row r="%d">`, r+2)
+		for i, cell := range row {
+			ref := xlsxColumn(i) + strconv.Itoa(r+2)
+			switch cell.kind {
+			case xlsxText:
+				if cell.text != "" {
+					fmt.Fprintf(w, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xlsxEscape(cell.text))
+				}
+			case xlsxDate:
+				fmt.Fprintf(w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleDate, strconv.FormatFloat(cell.number, 'f', -1, 64))
+			case xlsxInteger:
+				fmt.Fprintf(w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleInteger, strconv.FormatFloat(cell.number, 'f', -1, 64))
+			case xlsxDecimal:
+				fmt.Fprintf(w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleDecimal, strconv.FormatFloat(cell.number, 'f', -1, 64))
+			}
+		}
+		w.WriteString(`</row>`)
+	}
+	w.WriteString(`</sheetData>`)
+	if len(sheet.header) > 0 {
+		fmt.Fprintf(w, `<autoFilter ref="A1:%s%d"/>`, xlsxColumn(len(sheet.header)-1), len(sheet.rows)+1)
+	}
+	w.WriteString(`</worksheet>`)
+}
+
+// xlsxColumn returns the letters of a zero-based column index: A..Z, AA..
+func xlsxColumn(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xlsxEscape escapes s for XML text. Characters XML cannot hold, such as
+// control characters in hostile keys, are written as _xHHHH_, which
+// spreadsheets decode back to the character; a literal "_x" is written as
+// "_x005F_x" so it is not decoded.
+func xlsxEscape(s string) string {
+	s = strings.ReplaceAll(s, "_x", "_x005F_x")
+	if strings.IndexFunc(s, xlsxInvalidRune) >= 0 {
+		var cleaned strings.Builder
+		for _, r := range s {
+			if xlsxInvalidRune(r) {
+				fmt.Fprintf(&cleaned, "_x%04X_", r)
+				continue
+			}
+			cleaned.WriteRune(r)
+		}
+		s = cleaned.String()
+	}
+
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// xlsxInvalidRune reports whether r cannot appear in XML text
+func xlsxInvalidRune(r rune) bool {
+	return r == utf8.RuneError || (r < 0x20 && r != '\t' && r != '\n' && r != '\r') || r == 0xFFFE || r == 0xFFFF
+}
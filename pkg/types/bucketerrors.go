@@ -0,0 +1,73 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// BucketError records a bucket whose uploads could not be listed. Account is
+// only set in multi-account runs.
+type BucketError struct {
+	Bucket  string
+	Region  string
+	Account string
+	Err     error
+}
+
+// Error returns the bucket name and its error
+func (e BucketError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Bucket, e.Err)
+}
+
+// Unwrap returns the error the bucket failed with
+func (e BucketError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON writes the error as a string, since error values have no fields to encode
+func (e BucketError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Bucket  string `json:"bucket"`
+		Region  string `json:"region,omitempty"`
+		Account string `json:"account,omitempty"`
+		Error   string `json:"error"`
+	}{e.Bucket, e.Region, e.Account, e.Err.Error()})
+}
+
+// BucketErrors is returned together with the uploads of the other buckets
+// when some buckets of a listing fail, so callers can report which buckets
+// were left out and still use the rest
+type BucketErrors []BucketError
+
+// NewBucketErrors returns the errors sorted by account and bucket
+func NewBucketErrors(errs []BucketError) BucketErrors {
+	sorted := append(BucketErrors(nil), errs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Account != sorted[j].Account {
+			return sorted[i].Account < sorted[j].Account
+		}
+		return sorted[i].Bucket < sorted[j].Bucket
+	})
+	return sorted
+}
+
+// Error names the first failed bucket and counts the rest
+func (e BucketErrors) Error() string {
+	switch len(e) {
+	case 0:
+		return "no bucket errors"
+	case 1:
+		return "failed to list uploads for bucket " + e[0].Error()
+	}
+	return fmt.Sprintf("failed to list uploads for %d buckets; %s and %d more", len(e), e[0].Error(), len(e)-1)
+}
+
+// Unwrap returns the error of each bucket
+func (e BucketErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, bucketErr := range e {
+		errs[i] = bucketErr
+	}
+	return errs
+}
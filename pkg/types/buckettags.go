@@ -0,0 +1,146 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BucketTag is one key=value bucket tag in a selection
+type BucketTag struct {
+	Key   string
+	Value string
+}
+
+// ParseBucketTag parses a key=value bucket tag. The value may be empty, but
+// the key may not.
+func ParseBucketTag(value string) (BucketTag, error) {
+	key, tagValue, ok := strings.Cut(value, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return BucketTag{}, fmt.Errorf("invalid bucket tag %q, expected key=value", value)
+	}
+	return BucketTag{Key: key, Value: tagValue}, nil
+}
+
+// String writes the tag as key=value
+func (t BucketTag) String() string {
+	return t.Key + "=" + t.Value
+}
+
+// BucketTagFilter selects buckets by their tags. A bucket matches when, for
+// every key in Include, its tag has one of the values given for that key, and
+// it has none of the Exclude tags. Keys and values are case-sensitive, as in S3.
+type BucketTagFilter struct {
+	Include []BucketTag
+	Exclude []BucketTag
+}
+
+// IsEmpty reports whether the filter has no tags
+func (f *BucketTagFilter) IsEmpty() bool {
+	return f == nil || (len(f.Include) == 0 && len(f.Exclude) == 0)
+}
+
+// Matches reports whether a bucket with tags passes the filter
+func (f *BucketTagFilter) Matches(tags map[string]string) bool {
+	if f.IsEmpty() {
+		return true
+	}
+	for key, values := range f.IncludedValues() {
+		value, ok := tags[key]
+		if !ok || !containsString(values, value) {
+			return false
+		}
+	}
+	for _, tag := range f.Exclude {
+		if value, ok := tags[tag.Key]; ok && value == tag.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// IncludedValues groups the Include tags by key, in the order they were given
+func (f *BucketTagFilter) IncludedValues() map[string][]string {
+	if f == nil {
+		return nil
+	}
+	values := make(map[string][]string)
+	for _, tag := range f.Include {
+		values[tag.Key] = append(values[tag.Key], tag.Value)
+	}
+	return values
+}
+
+// Selected returns the tags of a bucket whose keys the filter looks at, so a
+// selection can be audited without listing every tag
+func (f *BucketTagFilter) Selected(tags map[string]string) map[string]string {
+	if f.IsEmpty() {
+		return nil
+	}
+	selected := make(map[string]string)
+	for _, tag := range append(append([]BucketTag(nil), f.Include...), f.Exclude...) {
+		if value, ok := tags[tag.Key]; ok {
+			selected[tag.Key] = value
+		}
+	}
+	return selected
+}
+
+// IncludedKeys returns the keys of the Include tags in the order they were
+// first given
+func (f *BucketTagFilter) IncludedKeys() []string {
+	if f == nil {
+		return nil
+	}
+	var keys []string
+	seen := make(map[string]bool)
+	for _, tag := range f.Include {
+		if !seen[tag.Key] {
+			keys = append(keys, tag.Key)
+			seen[tag.Key] = true
+		}
+	}
+	return keys
+}
+
+// String describes the filter, e.g. "env=prod|staging, not protected=true"
+func (f *BucketTagFilter) String() string {
+	if f.IsEmpty() {
+		return ""
+	}
+	var parts []string
+	values := f.IncludedValues()
+	for _, key := range f.IncludedKeys() {
+		parts = append(parts, key+"="+strings.Join(values[key], "|"))
+	}
+	for _, tag := range f.Exclude {
+		parts = append(parts, "not "+tag.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// containsString reports whether values holds value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// TagsByBucket collects the selected bucket tags carried by uploads, keyed by
+// bucket. It returns nil when the uploads were not selected by tag.
+func TagsByBucket(uploads []MultipartUpload) map[string]map[string]string {
+	var byBucket map[string]map[string]string
+	for _, upload := range uploads {
+		if upload.BucketTags == nil {
+			continue
+		}
+		if byBucket == nil {
+			byBucket = make(map[string]map[string]string)
+		}
+		byBucket[upload.Bucket] = upload.BucketTags
+	}
+	return byBucket
+}
@@ -0,0 +1,77 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits enforced by MultipartUpload.Validate. S3 keys are at most 1024 bytes
+// of UTF-8. S3 does not document a limit for upload IDs; real ones are a few
+// hundred bytes, so anything longer than a key comes from an out-of-spec endpoint.
+const (
+	MaxKeyLength      = 1024
+	MaxUploadIDLength = 1024
+)
+
+// DisplayString returns s made safe for a terminal or a log line: control
+// characters become visible escapes such as \n, \t and \x1b, invalid UTF-8
+// bytes become \xNN, and characters that reorder or break lines (bidi
+// controls, U+2028, U+2029) become \uNNNN. Use it for display only; API
+// calls and machine exports use the raw value.
+func DisplayString(s string) string {
+	if !needsEscape(s) {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&b, `\x%02x`, s[i])
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x80 && unicode.IsControl(r):
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case unsafeRune(r):
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// needsEscape reports whether DisplayString would change s
+func needsEscape(s string) bool {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if (r == utf8.RuneError && size == 1) || unicode.IsControl(r) || unsafeRune(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// unsafeRune reports whether r is a non-ASCII control character or one that
+// changes how the rest of a line is laid out
+func unsafeRune(r rune) bool {
+	switch {
+	case r >= 0x80 && unicode.IsControl(r): // C1 controls
+		return true
+	case r == '\u2028' || r == '\u2029': // line and paragraph separators
+		return true
+	case r >= '\u202a' && r <= '\u202e', r >= '\u2066' && r <= '\u2069', r == '\u200e', r == '\u200f': // bidi controls
+		return true
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package types
+
+import "strings"
+
+// KeySuffixFilter selects uploads by the end of their object key. A key
+// matches when it ends with any Include suffix (or Include is empty) and with
+// no Exclude suffix.
+type KeySuffixFilter struct {
+	Include       []string
+	Exclude       []string
+	CaseSensitive bool
+}
+
+// ParseSuffixList splits a comma-separated list of suffixes, dropping blanks
+func ParseSuffixList(value string) []string {
+	var suffixes []string
+	for _, suffix := range strings.Split(value, ",") {
+		if suffix = strings.TrimSpace(suffix); suffix != "" {
+			suffixes = append(suffixes, suffix)
+		}
+	}
+	return suffixes
+}
+
+// IsEmpty reports whether the filter has no suffixes
+func (f *KeySuffixFilter) IsEmpty() bool {
+	return f == nil || (len(f.Include) == 0 && len(f.Exclude) == 0)
+}
+
+// Matches reports whether a key passes the filter. Suffixes are anchored to
+// the end of the key, so ".tmp" does not match "data.tmp.gz".
+func (f *KeySuffixFilter) Matches(key string) bool {
+	if f.IsEmpty() {
+		return true
+	}
+	if len(f.Include) > 0 && !f.hasAnySuffix(key, f.Include) {
+		return false
+	}
+	return !f.hasAnySuffix(key, f.Exclude)
+}
+
+// hasAnySuffix reports whether key ends with one of suffixes
+func (f *KeySuffixFilter) hasAnySuffix(key string, suffixes []string) bool {
+	if !f.CaseSensitive {
+		key = strings.ToLower(key)
+	}
+	for _, suffix := range suffixes {
+		if !f.CaseSensitive {
+			suffix = strings.ToLower(suffix)
+		}
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// SnapshotKind identifies a snapshot written by snapshot save
+const SnapshotKind = "snapshot"
+
+// Snapshot is a compact summary of the uploads in each bucket at one point in
+// time, saved so that a later scan can be compared against it
+type Snapshot struct {
+	SchemaVersion       int              `json:"schema_version"`
+	Kind                string           `json:"kind"`
+	Version             string           `json:"s3mpc_version,omitempty"`
+	Profile             string           `json:"profile,omitempty"`
+	Region              string           `json:"region,omitempty"`
+	TakenAt             time.Time        `json:"taken_at"`
+	TotalCount          int              `json:"total_count"`
+	TotalSize           int64            `json:"total_size"`
+	Buckets             []SnapshotBucket `json:"buckets"` // buckets with uploads, sorted by name
+	InaccessibleBuckets []string         `json:"inaccessible_buckets,omitempty"`
+}
+
+// SnapshotBucket is the number and total size of a bucket's uploads
+type SnapshotBucket struct {
+	Bucket string `json:"bucket"`
+	Count  int    `json:"count"`
+	Size   int64  `json:"size"`
+}
+
+// Validate checks that a snapshot is one this version of s3mpc can read and
+// that its totals add up
+func (s *Snapshot) Validate() error {
+	if s.Kind != SnapshotKind {
+		return ValidationError{Field: "Kind", Message: fmt.Sprintf("not a snapshot (kind %q)", s.Kind)}
+	}
+	if s.SchemaVersion < 1 || s.SchemaVersion > SchemaVersion {
+		return ValidationError{Field: "SchemaVersion", Message: fmt.Sprintf("unsupported schema version %d, this version of s3mpc reads up to %d", s.SchemaVersion, SchemaVersion)}
+	}
+	if s.TakenAt.IsZero() {
+		return ValidationError{Field: "TakenAt", Message: "taken_at is required"}
+	}
+
+	seen := make(map[string]bool, len(s.Buckets))
+	var count int
+	var size int64
+	for _, bucket := range s.Buckets {
+		if bucket.Bucket == "" {
+			return ValidationError{Field: "Buckets", Message: "bucket name cannot be empty"}
+		}
+		if seen[bucket.Bucket] {
+			return ValidationError{Field: "Buckets", Message: fmt.Sprintf("bucket '%s' is listed more than once", bucket.Bucket)}
+		}
+		seen[bucket.Bucket] = true
+		if bucket.Count < 0 || bucket.Size < 0 {
+			return ValidationError{Field: "Buckets", Message: fmt.Sprintf("count and size for bucket '%s' cannot be negative", bucket.Bucket)}
+		}
+		count += bucket.Count
+		size += bucket.Size
+	}
+	if count != s.TotalCount || size != s.TotalSize {
+		return ValidationError{Field: "TotalCount", Message: fmt.Sprintf("totals (%d uploads, %d bytes) do not match the buckets (%d uploads, %d bytes)", s.TotalCount, s.TotalSize, count, size)}
+	}
+	return nil
+}
+
+// SnapshotDiff is what changed between two snapshots
+type SnapshotDiff struct {
+	From           time.Time              `json:"from"`
+	To             time.Time              `json:"to"`
+	FromCount      int                    `json:"from_count"`
+	ToCount        int                    `json:"to_count"`
+	FromSize       int64                  `json:"from_size"`
+	ToSize         int64                  `json:"to_size"`
+	CountChange    int                    `json:"count_change"`
+	SizeChange     int64                  `json:"size_change"`
+	NewBuckets     []SnapshotBucketChange `json:"new_buckets"`     // buckets that had no uploads before
+	CleanedBuckets []SnapshotBucketChange `json:"cleaned_buckets"` // buckets that have no uploads any more
+	TopGrowing     []SnapshotBucketChange `json:"top_growing"`     // buckets that grew the most, largest first
+}
+
+// SnapshotBucketChange is how a bucket's uploads changed between two snapshots
+type SnapshotBucketChange struct {
+	Bucket      string `json:"bucket"`
+	FromCount   int    `json:"from_count"`
+	ToCount     int    `json:"to_count"`
+	FromSize    int64  `json:"from_size"`
+	ToSize      int64  `json:"to_size"`
+	SizeChange  int64  `json:"size_change"`
+	CountChange int    `json:"count_change"`
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"sort"
+	"strings"
+)
+
+// storageClassAliases maps the spellings of storage classes people use to the
+// names S3 reports
+var storageClassAliases = map[string]string{
+	"STANDARD":            "STANDARD",
+	"STANDARD_IA":         "STANDARD_IA",
+	"STANDARD-IA":         "STANDARD_IA",
+	"ONEZONE_IA":          "ONEZONE_IA",
+	"ONEZONE-IA":          "ONEZONE_IA",
+	"REDUCED_REDUNDANCY":  "REDUCED_REDUNDANCY",
+	"GLACIER":             "GLACIER",
+	"GLACIER_IR":          "GLACIER_IR",
+	"GLACIER-IR":          "GLACIER_IR",
+	"DEEP_ARCHIVE":        "DEEP_ARCHIVE",
+	"DEEP-ARCHIVE":        "DEEP_ARCHIVE",
+	"INTELLIGENT_TIERING": "INTELLIGENT_TIERING",
+	"INTELLIGENT-TIERING": "INTELLIGENT_TIERING",
+}
+
+// NormalizeStorageClass returns the S3 name of a storage class, such as
+// STANDARD_IA for standard-ia. Unknown classes are upper-cased.
+func NormalizeStorageClass(storageClass string) string {
+	storageClass = strings.ToUpper(strings.TrimSpace(storageClass))
+	if normalized, exists := storageClassAliases[storageClass]; exists {
+		return normalized
+	}
+	return storageClass
+}
+
+// StorageClasses returns the S3 names of the storage classes, sorted
+func StorageClasses() []string {
+	seen := make(map[string]bool)
+	var classes []string
+	for _, class := range storageClassAliases {
+		if !seen[class] {
+			seen[class] = true
+			classes = append(classes, class)
+		}
+	}
+	sort.Strings(classes)
+	return classes
+}
@@ -0,0 +1,231 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimestampLayout is the layout used for every serialized timestamp (always in UTC)
+const TimestampLayout = time.RFC3339
+
+// legacyTimestampLayouts are layouts written by earlier versions, accepted when loading.
+// Layouts without a zone are interpreted as UTC.
+var legacyTimestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// FormatTimestamp formats t as RFC3339 in UTC
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Format(TimestampLayout)
+}
+
+// ParseTimestamp parses an RFC3339 timestamp or any historical s3mpc variant, returning UTC
+func ParseTimestamp(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if t, err := time.Parse(TimestampLayout, value); err == nil {
+		return t.UTC(), nil
+	}
+	for _, layout := range legacyTimestampLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid timestamp %q, expected RFC3339 such as 2006-01-02T15:04:05Z", value)
+}
+
+// DateLayout is the layout of a plain calendar date, interpreted as midnight UTC
+const DateLayout = "2006-01-02"
+
+// localTimestampLayout is an RFC3339 timestamp without an offset, read as UTC
+const localTimestampLayout = "2006-01-02T15:04:05"
+
+// ParseCutoff parses an absolute cut-off given as a plain date (midnight UTC) or
+// an RFC3339 timestamp, returning UTC. A timestamp without an offset is UTC.
+func ParseCutoff(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if t, err := time.Parse(DateLayout, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse(localTimestampLayout, value); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC3339", value)
+}
+
+// FormatCutoff formats a cut-off as a plain date when it is midnight UTC, otherwise as RFC3339
+func FormatCutoff(t time.Time) string {
+	t = t.UTC()
+	if t.Equal(t.Truncate(24 * time.Hour)) {
+		return t.Format(DateLayout)
+	}
+	return t.Format(TimestampLayout)
+}
+
+// multipartUploadJSON has the fields of MultipartUpload without its JSON methods
+type multipartUploadJSON MultipartUpload
+
+// MarshalJSON encodes Initiated and MeasuredAt as RFC3339 UTC timestamps
+func (m MultipartUpload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		multipartUploadJSON
+		Initiated  string `json:"initiated"`
+		MeasuredAt string `json:"measured_at,omitempty"`
+	}{
+		multipartUploadJSON: multipartUploadJSON(m),
+		Initiated:           FormatTimestamp(m.Initiated),
+		MeasuredAt:          formatOptionalTimestamp(m.MeasuredAt),
+	})
+}
+
+// UnmarshalJSON decodes an upload, accepting historical timestamp formats for Initiated
+func (m *MultipartUpload) UnmarshalJSON(data []byte) error {
+	var decoded struct {
+		multipartUploadJSON
+		Initiated  string `json:"initiated"`
+		MeasuredAt string `json:"measured_at"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*m = MultipartUpload(decoded.multipartUploadJSON)
+	if decoded.Initiated != "" {
+		initiated, err := ParseTimestamp(decoded.Initiated)
+		if err != nil {
+			return fmt.Errorf("invalid initiated time: %w", err)
+		}
+		m.Initiated = initiated
+	}
+	if decoded.MeasuredAt != "" {
+		measuredAt, err := ParseTimestamp(decoded.MeasuredAt)
+		if err != nil {
+			return fmt.Errorf("invalid measured_at time: %w", err)
+		}
+		m.MeasuredAt = measuredAt
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the upload and failure fields as one object
+func (f FailedUpload) MarshalJSON() ([]byte, error) {
+	upload, err := json.Marshal(f.MultipartUpload)
+	if err != nil {
+		return nil, err
+	}
+	failure, err := json.Marshal(f.UploadFailure)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(upload[:len(upload)-1], ','), failure[1:]...), nil
+}
+
+// UnmarshalJSON decodes the upload and failure fields of one object
+func (f *FailedUpload) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &f.MultipartUpload); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &f.UploadFailure)
+}
+
+// MarshalJSON encodes the upload and status fields as one object
+func (o DeletionOutcome) MarshalJSON() ([]byte, error) {
+	upload, err := json.Marshal(o.MultipartUpload)
+	if err != nil {
+		return nil, err
+	}
+	status, err := json.Marshal(o.DeletionStatus)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(upload[:len(upload)-1], ','), status[1:]...), nil
+}
+
+// UnmarshalJSON decodes the upload and status fields of one object
+func (o *DeletionOutcome) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &o.MultipartUpload); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &o.DeletionStatus)
+}
+
+// formatOptionalTimestamp formats t like FormatTimestamp, or "" when t is zero
+func formatOptionalTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return FormatTimestamp(t)
+}
+
+// OldestMeasurement returns the earliest MeasuredAt of uploads, or zero when
+// no upload size has been measured
+func OldestMeasurement(uploads []MultipartUpload) time.Time {
+	var oldest time.Time
+	for _, upload := range uploads {
+		if !upload.MeasuredAt.IsZero() && (oldest.IsZero() || upload.MeasuredAt.Before(oldest)) {
+			oldest = upload.MeasuredAt
+		}
+	}
+	return oldest
+}
+
+// dryRunResultJSON has the fields of DryRunResult without its JSON methods
+type dryRunResultJSON DryRunResult
+
+// MarshalJSON encodes GeneratedAt and SizesMeasuredAt as RFC3339 UTC timestamps
+func (d DryRunResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		dryRunResultJSON
+		GeneratedAt     string `json:"generated_at"`
+		SizesMeasuredAt string `json:"sizes_measured_at,omitempty"`
+	}{
+		dryRunResultJSON: dryRunResultJSON(d),
+		GeneratedAt:      FormatTimestamp(d.GeneratedAt),
+		SizesMeasuredAt:  formatOptionalTimestamp(d.SizesMeasuredAt),
+	})
+}
+
+// UnmarshalJSON decodes a dry-run result, accepting historical timestamp formats
+func (d *DryRunResult) UnmarshalJSON(data []byte) error {
+	var decoded struct {
+		dryRunResultJSON
+		GeneratedAt     string `json:"generated_at"`
+		SizesMeasuredAt string `json:"sizes_measured_at"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*d = DryRunResult(decoded.dryRunResultJSON)
+	if decoded.GeneratedAt != "" {
+		generatedAt, err := ParseTimestamp(decoded.GeneratedAt)
+		if err != nil {
+			return fmt.Errorf("invalid generated_at time: %w", err)
+		}
+		d.GeneratedAt = generatedAt
+	}
+	if decoded.SizesMeasuredAt != "" {
+		sizesMeasuredAt, err := ParseTimestamp(decoded.SizesMeasuredAt)
+		if err != nil {
+			return fmt.Errorf("invalid sizes_measured_at time: %w", err)
+		}
+		d.SizesMeasuredAt = sizesMeasuredAt
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package types
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimestampVariants(t *testing.T) {
+	want := time.Date(2024, 3, 5, 14, 30, 15, 0, time.UTC)
+
+	tests := []string{
+		"2024-03-05T14:30:15Z",
+		"2024-03-05T15:30:15+01:00",
+		"2024-03-05T14:30:15.000000000Z",
+		"2024-03-05T14:30:15",
+		"2024-03-05 14:30:15",
+		"2024-03-05 14:30:15 +0000 UTC",
+	}
+
+	for _, value := range tests {
+		got, err := ParseTimestamp(value)
+		if err != nil {
+			t.Errorf("ParseTimestamp(%q) error = %v", value, err)
+			continue
+		}
+		if !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("ParseTimestamp(%q) = %v, want %v", value, got, want)
+		}
+	}
+
+	if _, err := ParseTimestamp("last tuesday"); err == nil {
+		t.Error("expected error for unparseable timestamp")
+	}
+}
+
+func TestFormatTimestampIsUTC(t *testing.T) {
+	local := time.Date(2024, 3, 5, 15, 30, 15, 123, time.FixedZone("CET", 3600))
+	if got := FormatTimestamp(local); got != "2024-03-05T14:30:15Z" {
+		t.Errorf("FormatTimestamp() = %s, want 2024-03-05T14:30:15Z", got)
+	}
+}
+
+func TestParseCutoff(t *testing.T) {
+	midnight := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	for value, want := range map[string]time.Time{
+		"2024-05-01":                midnight,
+		"2024-05-01T00:00:00Z":      midnight,
+		"2024-05-01T02:00:00+02:00": midnight,
+		"2024-05-01T09:30:00Z":      midnight.Add(9*time.Hour + 30*time.Minute),
+		"2024-05-01T09:30:00":       midnight.Add(9*time.Hour + 30*time.Minute),
+	} {
+		got, err := ParseCutoff(value)
+		if err != nil {
+			t.Errorf("ParseCutoff(%q) error = %v", value, err)
+			continue
+		}
+		if !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("ParseCutoff(%q) = %v, want %v", value, got, want)
+		}
+	}
+
+	for _, value := range []string{"7d", "2024-13-01", "05/01/2024"} {
+		if _, err := ParseCutoff(value); err == nil {
+			t.Errorf("ParseCutoff(%q) expected error", value)
+		}
+	}
+
+	if got := FormatCutoff(midnight); got != "2024-05-01" {
+		t.Errorf("FormatCutoff(midnight) = %s, want 2024-05-01", got)
+	}
+	if got := FormatCutoff(midnight.Add(time.Second)); got != "2024-05-01T00:00:01Z" {
+		t.Errorf("FormatCutoff() = %s, want 2024-05-01T00:00:01Z", got)
+	}
+}
+
+func TestDeleteOptionsRejectFutureCutoff(t *testing.T) {
+	future := time.Now().Add(24 * time.Hour)
+	if err := (&DeleteOptions{InitiatedBefore: &future}).Validate(); err == nil {
+		t.Error("Validate() accepted an older-than date in the future")
+	}
+
+	past := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	if err := (&DeleteOptions{InitiatedBefore: &past}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestDeleteOptionsRejectEmptyAgeWindow(t *testing.T) {
+	day := 24 * time.Hour
+	week := 7 * day
+	june10 := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+	june12 := time.Date(2024, 6, 12, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		opts  DeleteOptions
+		valid bool
+	}{
+		{"durations", DeleteOptions{OlderThan: &day, NewerThan: &week}, true},
+		{"durations reversed", DeleteOptions{OlderThan: &week, NewerThan: &day}, false},
+		{"equal durations", DeleteOptions{OlderThan: &day, NewerThan: &day}, false},
+		{"dates", DeleteOptions{InitiatedAfter: &june10, InitiatedBefore: &june12}, true},
+		{"dates reversed", DeleteOptions{InitiatedAfter: &june12, InitiatedBefore: &june10}, false},
+		{"same date", DeleteOptions{InitiatedAfter: &june10, InitiatedBefore: &june10}, false},
+		{"date and duration", DeleteOptions{InitiatedAfter: &june10, OlderThan: &day}, true},
+		{"duration after date", DeleteOptions{NewerThan: &day, InitiatedBefore: &june10}, false},
+		{"newer than only", DeleteOptions{NewerThan: &day}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.opts.Validate()
+		if tt.valid && err != nil {
+			t.Errorf("%s: Validate() error = %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: Validate() accepted an empty window", tt.name)
+		}
+	}
+
+	future := time.Now().Add(day)
+	if err := (&DeleteOptions{InitiatedAfter: &future}).Validate(); err == nil {
+		t.Error("Validate() accepted a newer-than date in the future")
+	}
+}
+
+func TestMultipartUploadJSONTimestamp(t *testing.T) {
+	upload := MultipartUpload{
+		Bucket:    "b",
+		Key:       "k",
+		UploadID:  "u",
+		Initiated: time.Date(2024, 3, 5, 15, 30, 15, 999, time.FixedZone("CET", 3600)),
+	}
+
+	data, err := json.Marshal(upload)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"initiated":"2024-03-05T14:30:15Z"`) {
+		t.Errorf("Marshal() = %s, want RFC3339 UTC initiated", data)
+	}
+
+	var decoded MultipartUpload
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if decoded.Key != "k" || !decoded.Initiated.Equal(upload.Initiated.Truncate(time.Second)) {
+		t.Errorf("Unmarshal() = %+v, want round-tripped upload", decoded)
+	}
+
+	// Older exports used Go's default RFC3339Nano with a local offset
+	legacy := []byte(`{"bucket":"b","key":"k","upload_id":"u","initiated":"2024-03-05T15:30:15.000000999+01:00"}`)
+	if err := json.Unmarshal(legacy, &decoded); err != nil {
+		t.Fatalf("Unmarshal(legacy) error = %v", err)
+	}
+	if !decoded.Initiated.Equal(upload.Initiated) {
+		t.Errorf("Unmarshal(legacy) initiated = %v, want %v", decoded.Initiated, upload.Initiated)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // MultipartUpload represents an incomplete multipart upload
@@ -13,17 +14,56 @@ type MultipartUpload struct {
 	UploadID     string    `json:"upload_id" csv:"upload_id"`
 	Initiated    time.Time `json:"initiated" csv:"initiated"`
 	Size         int64     `json:"size" csv:"size"`
+	PartsCount   int       `json:"parts_count,omitempty" csv:"parts_count"` // measured with Size
 	StorageClass string    `json:"storage_class" csv:"storage_class"`
 	Region       string    `json:"region" csv:"region"`
+	Account      string    `json:"account,omitempty" csv:"account"` // set in multi-account runs
+	Initiator    string    `json:"initiator,omitempty" csv:"initiator"` // initiator ID (an ARN on AWS), or display name when there is no ID
+	InitiatorName string   `json:"initiator_name,omitempty" csv:"initiator_name"`
+	Owner        string    `json:"owner,omitempty" csv:"owner"` // owner display name; S3-compatible stores may omit the owner
+	OwnerID      string    `json:"owner_id,omitempty" csv:"owner_id"`
+	ServiceInitiated bool  `json:"service_initiated" csv:"service_initiated"`
+	MeasuredAt   time.Time `json:"measured_at,omitempty" csv:"measured_at"` // when Size was resolved; zero if never measured
+	Superseded   bool      `json:"superseded,omitempty" csv:"-"` // a completed object written after Initiated exists at Key; only set with --check-objects
+	AbortDate    *time.Time `json:"abort_date,omitempty" csv:"-"` // when a lifecycle rule will abort the upload, as ListParts reports it; measured with Size
+	AbortRuleID  string    `json:"abort_rule_id,omitempty" csv:"-"` // the lifecycle rule that sets AbortDate
+	BucketTags   map[string]string `json:"bucket_tags,omitempty" csv:"-"` // the bucket's values for the tags a --bucket-tag selection looked at
+	EstimatedMonthlyCost float64 `json:"estimated_monthly_cost,omitempty" csv:"estimated_monthly_cost"` // set once Size is measured and priced
+	BucketType   string    `json:"bucket_type,omitempty" csv:"-"` // BucketTypeDirectory for directory buckets; empty for general purpose buckets
+	Parts        []UploadPart `json:"parts,omitempty" csv:"-"` // every part, only listed for export --include-parts
+}
+
+// UploadPart is one uploaded part of an incomplete multipart upload
+type UploadPart struct {
+	PartNumber   int32     `json:"part_number"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
 }
 
 // Bucket represents an S3 bucket
 type Bucket struct {
 	Name    string            `json:"name" csv:"name"`
 	Region  string            `json:"region" csv:"region"`
+	Tags    map[string]string `json:"tags,omitempty" csv:"-"` // only the tags a selection looked at
+	BucketType string         `json:"bucket_type,omitempty" csv:"-"` // BucketTypeDirectory for directory buckets; empty for general purpose buckets
 	Uploads []MultipartUpload `json:"uploads,omitempty" csv:"-"`
 }
 
+// BucketTypeDirectory is the type of S3 Express One Zone directory buckets,
+// which ListBuckets does not return
+const BucketTypeDirectory = "Directory"
+
+// directoryBucketSuffix ends the name of every directory bucket, and of no
+// general purpose bucket
+const directoryBucketSuffix = "--x-s3"
+
+// IsDirectoryBucketName reports whether name is the name of a directory bucket,
+// such as logs--use1-az4--x-s3
+func IsDirectoryBucketName(name string) bool {
+	return strings.HasSuffix(name, directoryBucketSuffix)
+}
+
 // SizeReport represents storage usage information
 type SizeReport struct {
 	TotalSize           int64             `json:"total_size" csv:"total_size"`
@@ -31,6 +71,85 @@ type SizeReport struct {
 	ByStorageClass      map[string]int64  `json:"by_storage_class" csv:"-"`
 	ByBucket            map[string]int64  `json:"by_bucket" csv:"-"`
 	InaccessibleBuckets []string          `json:"inaccessible_buckets" csv:"-"`
+	ServiceInitiatedCount int             `json:"service_initiated_count" csv:"service_initiated_count"`
+	ServiceInitiatedSize  int64           `json:"service_initiated_size" csv:"service_initiated_size"`
+	ErrorSummary        []ErrorSummary    `json:"error_summary,omitempty" csv:"-"`
+	ByAccount           map[string]int64  `json:"by_account,omitempty" csv:"-"`
+	FailedAccounts      []AccountFailure  `json:"failed_accounts,omitempty" csv:"-"`
+	Distribution        *SizeDistribution `json:"distribution,omitempty" csv:"-"` // set by size --histogram
+	TagsByBucket        map[string]map[string]string `json:"tags_by_bucket,omitempty" csv:"-"` // set when buckets were selected by tag
+	TrippedBuckets      []TrippedBucket   `json:"tripped_buckets,omitempty" csv:"-"` // buckets whose uploads stopped being measured
+	BucketErrors        BucketErrors      `json:"errors,omitempty" csv:"-"` // buckets whose uploads could not be listed
+}
+
+// SizeDistribution represents upload counts by size range
+type SizeDistribution struct {
+	Buckets []SizeBucket `json:"buckets"`
+}
+
+// SizeBucket represents a size range in distribution analysis
+type SizeBucket struct {
+	Label     string `json:"label"`
+	MinSize   int64  `json:"min_size"`
+	MaxSize   int64  `json:"max_size"` // 0 means no upper limit
+	Count     int    `json:"count"`
+	TotalSize int64  `json:"total_size"`
+}
+
+// AccountFailure records an account that could not be scanned in a multi-account run
+type AccountFailure struct {
+	Account string `json:"account"`
+	Error   string `json:"error"`
+}
+
+// SkippedBucket records a bucket left out of a scan because its region, or
+// the tags it was to be selected by, could not be determined. Account is only
+// set in multi-account runs.
+type SkippedBucket struct {
+	Bucket  string `json:"bucket"` // empty when the directory buckets of Region could not be listed
+	Account string `json:"account,omitempty"`
+	Region  string `json:"region,omitempty"`
+	Error   string `json:"error"`
+	Lookup  string `json:"lookup,omitempty"` // SkippedTagLookup or SkippedDirectoryBucketLookup; empty for the region
+}
+
+// SkippedTagLookup marks a bucket skipped because its tags could not be read
+const SkippedTagLookup = "tags"
+
+// SkippedDirectoryBucketLookup marks the directory buckets of a region
+// skipped because they could not be listed
+const SkippedDirectoryBucketLookup = "directory-buckets"
+
+// Name returns how the skipped bucket is shown, with its account if any
+func (b SkippedBucket) Name() string {
+	name := DisplayString(b.Bucket)
+	if b.Lookup == SkippedDirectoryBucketLookup {
+		name = "directory buckets in " + DisplayString(b.Region)
+	}
+	if b.Account != "" {
+		name = DisplayString(b.Account) + "/" + name
+	}
+	return name
+}
+
+// TrippedBucket records a bucket whose remaining uploads were not measured
+// after repeated failures that retrying could not fix, such as AccessDenied
+type TrippedBucket struct {
+	Bucket         string `json:"bucket"`
+	ErrorCode      string `json:"error_code,omitempty"` // code of the failure that tripped it
+	Failures       int    `json:"failures"`             // consecutive failures before it tripped
+	SkippedUploads int    `json:"skipped_uploads"`
+}
+
+// ErrorSummary groups errors that share an AWS error code and cause
+type ErrorSummary struct {
+	Code        string   `json:"code"`
+	Category    string   `json:"category"`
+	Explanation string   `json:"explanation"`
+	Remediation string   `json:"remediation,omitempty"`
+	Benign      bool     `json:"benign,omitempty"`
+	Count       int      `json:"count"`
+	Buckets     []string `json:"buckets"`
 }
 
 // CostBreakdown represents cost analysis
@@ -39,11 +158,184 @@ type CostBreakdown struct {
 	ByRegion         map[string]float64 `json:"by_region" csv:"-"`
 	ByStorageClass   map[string]float64 `json:"by_storage_class" csv:"-"`
 	Currency         string             `json:"currency" csv:"currency"`
+	AnnualizedCost   float64            `json:"annualized_cost" csv:"annualized_cost"`
+	AccruedCost      float64            `json:"accrued_cost,omitempty" csv:"accrued_cost"`
+	ByAccount        map[string]float64 `json:"by_account,omitempty" csv:"-"`
+	FailedAccounts   []AccountFailure   `json:"failed_accounts,omitempty" csv:"-"`
+	SkippedBuckets   []SkippedBucket    `json:"skipped_buckets,omitempty" csv:"-"`
+	BucketErrors     BucketErrors       `json:"errors,omitempty" csv:"-"`
+	PricingWarnings  []string           `json:"pricing_warnings,omitempty" csv:"-"`
+}
+
+// StatsReport combines size, cost and age analysis of a single scan
+type StatsReport struct {
+	Size                SizeReport      `json:"size"`
+	Cost                CostBreakdown   `json:"cost"`
+	Age                 AgeDistribution `json:"age"`
+	ByBucket            []BucketStats   `json:"by_bucket"`
+	InaccessibleBuckets []string        `json:"inaccessible_buckets"`
+	BucketErrors        BucketErrors    `json:"errors,omitempty"`
+}
+
+// MetricsReport holds the upload totals exported as OpenMetrics, grouped by
+// bucket, region and storage class
+type MetricsReport struct {
+	GeneratedAt         time.Time      `json:"generated_at"`
+	Series              []UploadSeries `json:"series"`
+	OldestUploads       []BucketOldest `json:"oldest_uploads"`
+	Currency            string         `json:"currency"`
+	InaccessibleBuckets []string       `json:"inaccessible_buckets"`
+}
+
+// UploadSeries totals the uploads sharing a bucket, region and storage class
+type UploadSeries struct {
+	Bucket       string  `json:"bucket"`
+	Region       string  `json:"region"`
+	StorageClass string  `json:"storage_class"`
+	Count        int     `json:"count"`
+	Size         int64   `json:"size"`
+	MonthlyCost  float64 `json:"monthly_cost"`
+}
+
+// BucketOldest records when the oldest upload in a bucket was initiated
+type BucketOldest struct {
+	Bucket    string    `json:"bucket"`
+	Initiated time.Time `json:"initiated"`
+}
+
+// BucketStats summarizes the uploads of one bucket in a StatsReport
+type BucketStats struct {
+	Bucket      string  `json:"bucket"`
+	Count       int     `json:"count"`
+	Size        int64   `json:"size"`
+	MonthlyCost float64 `json:"monthly_cost"`
+}
+
+// LifecycleStatus reports whether a bucket has a lifecycle rule that aborts
+// incomplete multipart uploads
+type LifecycleStatus struct {
+	Bucket              string       `json:"bucket"`
+	Region              string       `json:"region"`
+	HasConfiguration    bool         `json:"has_configuration"`
+	HasAbortRule        bool         `json:"has_abort_rule"`
+	RuleID              string       `json:"rule_id,omitempty"`
+	RuleEnabled         bool         `json:"rule_enabled"`
+	DaysAfterInitiation int32        `json:"days_after_initiation,omitempty"`
+	Prefix              string       `json:"prefix,omitempty"` // the rule only applies to keys with this prefix
+	UploadCount         *int         `json:"upload_count"`     // nil when uploads could not be listed
+	ListError           string       `json:"list_error,omitempty"`
+	Error               string       `json:"error,omitempty"`
+	Access              BucketAccess `json:"access"`
+}
+
+// BucketAccess records which read operations succeeded on a bucket
+type BucketAccess struct {
+	ListUploads  bool `json:"list_uploads"`
+	GetLifecycle bool `json:"get_lifecycle"`
+}
+
+// Covered reports whether an enabled rule aborts incomplete uploads anywhere in the bucket
+func (s LifecycleStatus) Covered() bool {
+	return s.HasAbortRule && s.RuleEnabled && s.Prefix == ""
+}
+
+// AbortLifecycleRule is the lifecycle rule s3mpc writes, in the JSON shape of the S3 API
+type AbortLifecycleRule struct {
+	ID                             string                      `json:"ID"`
+	Status                         string                      `json:"Status"`
+	Filter                         LifecycleRuleFilter         `json:"Filter"`
+	AbortIncompleteMultipartUpload AbortIncompleteUploadAction `json:"AbortIncompleteMultipartUpload"`
+}
+
+// LifecycleRuleFilter selects the keys a lifecycle rule applies to
+type LifecycleRuleFilter struct {
+	Prefix string `json:"Prefix"`
+}
+
+// AbortIncompleteUploadAction is the abort action of a lifecycle rule
+type AbortIncompleteUploadAction struct {
+	DaysAfterInitiation int32 `json:"DaysAfterInitiation"`
+}
+
+// LifecycleChange describes adding the s3mpc abort rule to one bucket
+type LifecycleChange struct {
+	Bucket        string             `json:"bucket"`
+	Region        string             `json:"region"`
+	Rule          AbortLifecycleRule `json:"rule"`
+	ExistingRules int                `json:"existing_rules"` // rules kept alongside the abort rule
+	Replaces      bool               `json:"replaces"`       // an earlier s3mpc rule is updated
+	Skipped       string             `json:"skipped,omitempty"`
+	Applied       bool               `json:"applied"`
+	Error         string             `json:"error,omitempty"`
+}
+
+// Pending reports whether the change still has to be written
+func (c LifecycleChange) Pending() bool {
+	return c.Skipped == "" && c.Error == "" && !c.Applied
+}
+
+// LifecycleApplyResult reports the outcome of writing abort rules
+type LifecycleApplyResult struct {
+	Changes      []LifecycleChange `json:"changes"`
+	Applied      int               `json:"applied"`
+	Failed       int               `json:"failed"`
+	ErrorSummary []ErrorSummary    `json:"error_summary,omitempty"`
+}
+
+// Doctor check statuses
+const (
+	DoctorPass = "pass"
+	DoctorWarn = "warn"
+	DoctorFail = "fail"
+	DoctorSkip = "skip"
+)
+
+// DoctorCheck is the outcome of one check made by the doctor command
+type DoctorCheck struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Detail      string `json:"detail,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
+	// Required checks make the doctor command fail; others only warn
+	Required bool `json:"required"`
+}
+
+// DoctorOptions selects what the doctor command probes
+type DoctorOptions struct {
+	Bucket string // probe this bucket instead of the first one listed
+}
+
+// DoctorFailures returns how many required checks failed
+func DoctorFailures(checks []DoctorCheck) int {
+	failed := 0
+	for _, check := range checks {
+		if check.Required && check.Status == DoctorFail {
+			failed++
+		}
+	}
+	return failed
 }
 
 // AgeDistribution represents upload age analysis
 type AgeDistribution struct {
-	Buckets []AgeBucket `json:"buckets"`
+	Buckets      []AgeBucket     `json:"buckets"`
+	Percentiles  *AgePercentiles `json:"percentiles,omitempty"` // nil when there are no uploads
+	BucketErrors BucketErrors    `json:"errors,omitempty"`
+}
+
+// AgePercentiles summarizes upload ages by rank
+type AgePercentiles struct {
+	Min    AgePercentile `json:"min"`
+	Median AgePercentile `json:"median"`
+	P90    AgePercentile `json:"p90"`
+	Max    AgePercentile `json:"max"`
+}
+
+// AgePercentile is an upload age and the total size of the uploads at least
+// that old, which is what aborting uploads from that age on would reclaim
+type AgePercentile struct {
+	Age  time.Duration `json:"age"`
+	Size int64         `json:"size"`
 }
 
 // AgeBucket represents an age bucket in distribution analysis
@@ -58,9 +350,21 @@ type AgeBucket struct {
 // ListOptions contains options for listing operations
 type ListOptions struct {
 	Region      string
+	// Regions, if set, limits the listing to buckets in these regions
+	Regions     []string
+	// ExcludeBuckets are left out of a listing of every bucket; a BucketName
+	// is listed even when it is one of them
+	ExcludeBuckets []string
 	BucketName  string
+	// KeyPrefix, if set, is sent to S3, which matches it case-sensitively and without wildcards
+	KeyPrefix   string
 	MaxResults  int
 	Offset      int
+	// BucketTags, if set, limits the listing to buckets whose tags match it
+	BucketTags  *BucketTagFilter
+	// IncludeDirectoryBuckets also lists the directory buckets of the scanned
+	// regions, which takes a ListDirectoryBuckets call per region
+	IncludeDirectoryBuckets bool
 }
 
 // DeleteOptions contains options for delete operations
@@ -68,24 +372,111 @@ type DeleteOptions struct {
 	DryRun      bool
 	Force       bool
 	OlderThan   *time.Duration
+	InitiatedBefore *time.Time // absolute --older-than cut-off; fixed between dry-run and execution
+	NewerThan   *time.Duration
+	InitiatedAfter *time.Time // absolute --newer-than cut-off, inclusive
 	SmallerThan *int64
 	LargerThan  *int64
 	BucketName  string
+	KeyPrefix   string // case-sensitive, as in ListOptions
+	KeySuffix   *KeySuffixFilter
+	BucketTags  *BucketTagFilter // the bucket tag selection the uploads were listed with
+	StorageClasses []string // any of these storage classes, as NormalizeStorageClass returns them; empty means all
+	Filter      string // the --filter expression, as written
+	FilterMatch func(upload MultipartUpload) bool // reports whether an upload matches Filter; nil without one
 	Quiet       bool
+	IncludeServiceInitiated bool
+	MaxDeletes  int // at most this many uploads, oldest first; 0 means no limit
+	SkipAutoExpiring   bool          // leave out uploads a lifecycle rule will abort
+	AutoExpiringWithin time.Duration // with SkipAutoExpiring, only those aborted within this long; 0 means any abort date
+	VerifyAccess bool // with DryRun, probe whether the uploads of each bucket may be aborted
+	OnFailure   func(upload MultipartUpload, err error) // called as each deletion fails, from concurrent goroutines
+	OnOutcome   func(upload MultipartUpload, err error) // called after each deletion, with a nil err if it succeeded, from concurrent goroutines
+	OnStart     func(uploads []MultipartUpload) // called with the uploads about to be deleted, once they are selected and confirmed
+	OnAlreadyCleaned func(upload MultipartUpload) // if set, a deletion S3 answers with NoSuchUpload succeeds and is passed here instead of to OnOutcome
+	OnDryRun    func(result DryRunResult) // called with the dry-run result before it is reported
+	ReportDryRun func(result DryRunResult) // reports the dry-run result instead of the text summary, e.g. as markdown
+}
+
+// UploadGroup is one section of list --group-by: the uploads sharing a bucket,
+// region, storage class or key prefix, with their subtotals
+type UploadGroup struct {
+	Name    string            `json:"name"`
+	Count   int               `json:"count"`
+	Size    int64             `json:"size"`
+	Uploads []MultipartUpload `json:"uploads"`
+}
+
+// UploadDelta is how the incomplete uploads changed between two scans, as
+// shown by list --watch
+type UploadDelta struct {
+	New        []MultipartUpload // uploads not in the previous scan
+	Gone       []MultipartUpload // uploads completed or aborted since the previous scan
+	Grown      int               // uploads in both scans whose size grew
+	SizeChange int64             // change in the total size of the uploads
+}
+
+// UploadDetail is what one walk of an upload's parts measures
+
+type UploadDetail struct {
+	Size         int64
+	PartsCount   int
+	AbortDate    *time.Time // nil when no lifecycle rule will abort the upload
+	AbortRuleID  string
+	AbortChecked bool         // AbortDate was looked up; false for sizes cached before abort dates were
+	Parts        []UploadPart // only kept when asked for
+}
+
+// LifecycleApplyOptions contains options for adding an abort-incomplete-uploads rule
+type LifecycleApplyOptions struct {
+	Days       int32
+	BucketName string
+	AllMissing bool // every bucket without an enabled rule covering the whole bucket
+	Region     string
 }
 
 // ExportOptions contains options for export operations
 type ExportOptions struct {
-	Format     string // csv, json
-	OutputFile string
+	Format     string // csv, json, xlsx
+	OutputFile string // gzip-compressed when it ends in .gz
 	Filter     string
+	Compress   bool
+	// SplitBy "bucket" writes one file per bucket; MaxRows and MaxFileSize
+	// (uncompressed bytes) cut files into chunks. Zero values do not split.
+	SplitBy     string
+	MaxRows     int
+	MaxFileSize int64
+	// BucketFiles names the file of each bucket when SplitBy is "bucket",
+	// such as from --filename-template; other buckets are named after OutputFile
+	BucketFiles map[string]string
 }
 
+// ExportPart is one file of a split export
+type ExportPart struct {
+	File   string
+	Bucket string // set when the export is split by bucket
+	Rows   int
+	Bytes  int64 // size of the file as written, compressed or not
+}
+
+// ExportManifest lists the files a split export wrote and the index file
+// that lists them
+type ExportManifest struct {
+	Index string
+	Parts []ExportPart
+}
+
+// SchemaVersion is the version of the JSON files s3mpc writes (exports,
+// dry-run reports and failure reports). Readers refuse files with a newer version.
+const SchemaVersion = 1
+
 // DryRunResult represents the result of a dry-run deletion operation
 type DryRunResult struct {
+	SchemaVersion       int                    `json:"schema_version,omitempty"`
 	TotalUploads        int                    `json:"total_uploads"`
 	TotalSize           int64                  `json:"total_size"`
 	EstimatedSavings    float64                `json:"estimated_savings"`
+	AlreadyWasted       float64                `json:"already_wasted"`
 	Currency            string                 `json:"currency"`
 	UploadsByBucket     map[string]int         `json:"uploads_by_bucket"`
 	SizeByBucket        map[string]int64       `json:"size_by_bucket"`
@@ -100,6 +491,192 @@ type DryRunResult struct {
 	GeneratedAt         time.Time              `json:"generated_at"`
 	Command             string                 `json:"command"`
 	Filters             string                 `json:"filters,omitempty"`
+	Version             string                 `json:"s3mpc_version,omitempty"` // set when the report is saved by the CLI
+	ExcludedServiceInitiated int           `json:"excluded_service_initiated"`
+	SizesMeasuredAt     time.Time              `json:"sizes_measured_at,omitempty"` // oldest size measurement of the uploads
+	Funnel              *FilterFunnel          `json:"funnel,omitempty"`            // set when the deletion has any conditions
+	SupersededUploads   int                    `json:"superseded_uploads,omitempty"` // uploads whose key already holds a newer completed object
+	SupersededSize      int64                  `json:"superseded_size,omitempty"`
+	MatchingUploads     int                    `json:"matching_uploads,omitempty"` // set when --max-deletes left some matching uploads out
+	ExcludedAutoExpiring []AutoExpiringUploads `json:"excluded_auto_expiring,omitempty"` // matching uploads left to lifecycle rules by --skip-auto-expiring
+	TagsByBucket        map[string]map[string]string `json:"tags_by_bucket,omitempty"` // set when buckets were selected by tag
+	AccessByBucket      map[string]bool        `json:"access_by_bucket,omitempty"` // whether each bucket's uploads may be aborted; set by --verify-access
+	AccessErrors        map[string]string      `json:"access_errors,omitempty"`    // buckets whose access could not be verified, with why
+}
+
+// AutoExpiringUploads are the matching uploads of a bucket that one lifecycle
+// rule will abort, left out of a deletion by --skip-auto-expiring
+type AutoExpiringUploads struct {
+	Bucket         string    `json:"bucket"`
+	RuleID         string    `json:"rule_id"`
+	Uploads        int       `json:"uploads"`
+	Size           int64     `json:"size"`
+	FirstAbortDate time.Time `json:"first_abort_date"`
+}
+
+// FilterFunnel shows which conditions of a deletion narrow the candidate
+// uploads down to the ones that would be deleted
+type FilterFunnel struct {
+	Uploads int          `json:"uploads"` // candidates before any condition
+	Size    int64        `json:"size"`
+	Steps   []FunnelStep `json:"steps"`
+}
+
+// FunnelStep is one condition of a FilterFunnel. Uploads and Size are what is
+// left after it and every earlier step; Matched and MatchedSize are what it
+// selects from all the candidates on its own.
+type FunnelStep struct {
+	Clause      string `json:"clause"`
+	Uploads     int    `json:"uploads"`
+	Size        int64  `json:"size"`
+	Matched     int    `json:"matched"`
+	MatchedSize int64  `json:"matched_size"`
+}
+
+// FailureReportKind identifies a failure report written by delete --save-failures
+const FailureReportKind = "delete-failures"
+
+// FailureReport lists the uploads a delete run could not delete, with the run
+// metadata needed to audit a retry. Uploads is readable by --from-file.
+type FailureReport struct {
+	SchemaVersion int            `json:"schema_version"`
+	Kind          string         `json:"kind"`
+	Command       string         `json:"command"`
+	Version       string         `json:"s3mpc_version"`
+	Profile       string         `json:"profile,omitempty"`
+	Region        string         `json:"region,omitempty"`
+	RoleARN       string         `json:"role_arn,omitempty"`
+	SourceFile    string         `json:"source_file,omitempty"` // the --from-file or --retry-from the run retried, if any
+	GeneratedAt   time.Time      `json:"generated_at"`          // when the run started
+	Uploads       []FailedUpload `json:"uploads"`
+	FinishedAt    time.Time      `json:"finished_at,omitempty"` // zero if the run was killed before it finished
+	Failed        int            `json:"failed"`
+	Interrupted   bool           `json:"interrupted"`
+}
+
+// FailedUpload is an upload that could not be deleted and why. It is encoded
+// as the upload's fields followed by the failure's.
+type FailedUpload struct {
+	MultipartUpload
+	UploadFailure
+}
+
+// UploadFailure describes why an upload could not be deleted
+type UploadFailure struct {
+	Error         string    `json:"error"`
+	ErrorCode     string    `json:"error_code,omitempty"`
+	ErrorCategory string    `json:"error_category"`
+	Attempts      int       `json:"attempts"` // S3 calls made, including retries
+	FailedAt      time.Time `json:"failed_at"`
+}
+
+// DeletionReportKind identifies a deletion report written by delete --report-file
+const DeletionReportKind = "delete-report"
+
+// DeletionReport records what a delete run did to every upload it attempted,
+// with the options it was run with, for auditing a cleanup
+type DeletionReport struct {
+	SchemaVersion     int               `json:"schema_version"`
+	Kind              string            `json:"kind"`
+	Command           string            `json:"command"` // the command line as given
+	Options           string            `json:"options"` // the delete options in effect, as flags
+	Filters           string            `json:"filters,omitempty"`
+	Version           string            `json:"s3mpc_version"`
+	Profile           string            `json:"profile,omitempty"`
+	Region            string            `json:"region,omitempty"`
+	RoleARN           string            `json:"role_arn,omitempty"`
+	SourceFile        string            `json:"source_file,omitempty"`
+	StartedAt         time.Time         `json:"started_at"`
+	FinishedAt        time.Time         `json:"finished_at"`
+	DurationSeconds   float64           `json:"duration_seconds"`
+	TotalProcessed    int               `json:"total_processed"`
+	SuccessfulDeletes int               `json:"successful_deletes"`
+	FailedDeletes     int               `json:"failed_deletes"`
+	AlreadyCleaned    int               `json:"already_cleaned,omitempty"` // retried uploads S3 no longer had
+	StorageFreed      int64             `json:"storage_freed"`
+	Interrupted       bool              `json:"interrupted"`
+	Error             string            `json:"error,omitempty"`    // why the run failed, if it did
+	RetryOf           string            `json:"retry_of,omitempty"` // the report whose failures this run retried
+	Outcomes          []DeletionOutcome `json:"outcomes"`
+}
+
+// DeletionOutcome is the result of deleting one upload. It is encoded as the
+// upload's fields followed by the status's, so a retry can rebuild the upload.
+type DeletionOutcome struct {
+	MultipartUpload
+	DeletionStatus
+}
+
+// DeletionStatus describes whether an upload was deleted
+type DeletionStatus struct {
+	Success        bool      `json:"success"`
+	AlreadyCleaned bool      `json:"already_cleaned,omitempty"` // S3 no longer had the upload when a retry checked it
+	Error          string    `json:"error,omitempty"`
+	ErrorCode      string    `json:"error_code,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// DeletionCheckpointKind identifies a checkpoint written by delete --checkpoint
+const DeletionCheckpointKind = "delete-checkpoint"
+
+// DeletionCheckpoint is the state of a delete run that was cut short: the
+// uploads it had yet to delete, and a hash of the options that selected them
+// so that it is only resumed with the same ones
+
+type DeletionCheckpoint struct {
+	SchemaVersion int                `json:"schema_version"`
+	Kind          string             `json:"kind"`
+	Command       string             `json:"command"` // the command line of the run that wrote it
+	Options       string             `json:"options"` // the delete options in effect, as flags
+	OptionsHash   string             `json:"options_hash"`
+	Version       string             `json:"s3mpc_version"`
+	Profile       string             `json:"profile,omitempty"`
+	Region        string             `json:"region,omitempty"`
+	RoleARN       string             `json:"role_arn,omitempty"`
+	ExternalID    string             `json:"-"`                       // hashed, but not saved
+	Regions       []string           `json:"regions,omitempty"`       // --regions
+	SourceFile    string             `json:"source_file,omitempty"`   // --from-file
+	RetryOf       string             `json:"retry_of,omitempty"`      // --retry-from
+	SourceHash    string             `json:"source_sha256,omitempty"` // of the content of SourceFile or RetryOf
+	StartedAt     time.Time          `json:"started_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+	Total         int                `json:"total"` // uploads the run set out to delete
+	Remaining     []CheckpointUpload `json:"remaining"`
+}
+
+// CheckpointUpload is an upload left in a checkpoint: what identifies it, and
+// what deleting it checks
+type CheckpointUpload struct {
+	Bucket       string    `json:"bucket"`
+	Key          string    `json:"key"`
+	UploadID     string    `json:"upload_id"`
+	Region       string    `json:"region"`
+	StorageClass string    `json:"storage_class"`
+	Initiated    time.Time `json:"initiated"`
+}
+
+// NewCheckpointUpload returns what a checkpoint keeps of upload
+func NewCheckpointUpload(upload MultipartUpload) CheckpointUpload {
+	return CheckpointUpload{
+		Bucket:       upload.Bucket,
+		Key:          upload.Key,
+		UploadID:     upload.UploadID,
+		Region:       upload.Region,
+		StorageClass: upload.StorageClass,
+		Initiated:    upload.Initiated,
+	}
+}
+
+// Upload returns the upload to delete; its size is not known
+func (c CheckpointUpload) Upload() MultipartUpload {
+	return MultipartUpload{
+		Bucket:       c.Bucket,
+		Key:          c.Key,
+		UploadID:     c.UploadID,
+		Region:       c.Region,
+		StorageClass: c.StorageClass,
+		Initiated:    c.Initiated,
+	}
 }
 
 // ValidationError represents a validation error
@@ -126,6 +703,23 @@ func (m *MultipartUpload) Validate() error {
 		return ValidationError{Field: "UploadID", Message: "upload ID cannot be empty"}
 	}
 	
+	// Out-of-spec values come from S3-compatible endpoints and hand-edited files
+	if len(m.Key) > MaxKeyLength {
+		return ValidationError{Field: "Key", Message: fmt.Sprintf("key is %d bytes; S3 allows at most %d", len(m.Key), MaxKeyLength)}
+	}
+	
+	if !utf8.ValidString(m.Key) {
+		return ValidationError{Field: "Key", Message: "key is not valid UTF-8"}
+	}
+	
+	if len(m.UploadID) > MaxUploadIDLength {
+		return ValidationError{Field: "UploadID", Message: fmt.Sprintf("upload ID is %d bytes; s3mpc accepts at most %d", len(m.UploadID), MaxUploadIDLength)}
+	}
+	
+	if needsEscape(m.UploadID) {
+		return ValidationError{Field: "UploadID", Message: fmt.Sprintf("upload ID \"%s\" contains control characters", DisplayString(m.UploadID))}
+	}
+	
 	if m.Initiated.IsZero() {
 		return ValidationError{Field: "Initiated", Message: "initiated time cannot be zero"}
 	}
@@ -267,6 +861,20 @@ func (l *ListOptions) Validate() error {
 		return ValidationError{Field: "Offset", Message: "offset cannot be negative"}
 	}
 	
+	for _, region := range l.Regions {
+		if strings.TrimSpace(region) == "" {
+			return ValidationError{Field: "Regions", Message: "regions cannot be empty"}
+		}
+	}
+	
+	if l.BucketTags != nil {
+		for _, tag := range append(append([]BucketTag(nil), l.BucketTags.Include...), l.BucketTags.Exclude...) {
+			if tag.Key == "" {
+				return ValidationError{Field: "BucketTags", Message: "bucket tag keys cannot be empty"}
+			}
+		}
+	}
+	
 	return nil
 }
 
@@ -280,6 +888,28 @@ func (d *DeleteOptions) Validate() error {
 		return ValidationError{Field: "LargerThan", Message: "larger than value cannot be negative"}
 	}
 	
+	if d.MaxDeletes < 0 {
+		return ValidationError{Field: "MaxDeletes", Message: "max deletes cannot be negative"}
+	}
+	
+	if d.AutoExpiringWithin < 0 {
+		return ValidationError{Field: "AutoExpiringWithin", Message: "auto-expiring window cannot be negative"}
+	}
+	
+	if d.InitiatedBefore != nil && d.InitiatedBefore.After(time.Now()) {
+		return ValidationError{Field: "InitiatedBefore", Message: "older than date cannot be in the future"}
+	}
+	
+	if d.InitiatedAfter != nil && d.InitiatedAfter.After(time.Now()) {
+		return ValidationError{Field: "InitiatedAfter", Message: "newer than date cannot be in the future"}
+	}
+	
+	// Both age bounds must leave a non-empty window of initiation times
+	now := time.Now()
+	if before, after := d.initiatedBeforeAt(now), d.initiatedAfterAt(now); before != nil && after != nil && !after.Before(*before) {
+		return ValidationError{Field: "NewerThan", Message: "newer than bound must be more recent than older than bound, the time window is empty"}
+	}
+	
 	// SmallerThan should be greater than LargerThan (e.g., delete files smaller than 100MB but larger than 50MB)
 	if d.SmallerThan != nil && d.LargerThan != nil && *d.SmallerThan <= *d.LargerThan {
 		return ValidationError{Field: "SmallerThan", Message: "smaller than value must be greater than larger than value"}
@@ -288,15 +918,68 @@ func (d *DeleteOptions) Validate() error {
 	return nil
 }
 
+// Validate validates LifecycleApplyOptions struct
+func (l *LifecycleApplyOptions) Validate() error {
+	if l.Days < 1 {
+		return ValidationError{Field: "Days", Message: "days must be at least 1"}
+	}
+	
+	if l.BucketName == "" && !l.AllMissing {
+		return ValidationError{Field: "BucketName", Message: "a bucket or all missing buckets must be selected"}
+	}
+	
+	if l.BucketName != "" && l.AllMissing {
+		return ValidationError{Field: "BucketName", Message: "a bucket cannot be combined with all missing buckets"}
+	}
+	
+	return nil
+}
+
+// InitiatedWindow returns the --newer-than and --older-than bounds as
+// initiation times, as of now; either is nil when it is not set. Uploads
+// initiated on or after the first and before the second are selected.
+func (d *DeleteOptions) InitiatedWindow(now time.Time) (*time.Time, *time.Time) {
+	return d.initiatedAfterAt(now), d.initiatedBeforeAt(now)
+}
+
+// initiatedBeforeAt returns the --older-than bound as an initiation time, as of now
+func (d *DeleteOptions) initiatedBeforeAt(now time.Time) *time.Time {
+	if d.InitiatedBefore != nil {
+		return d.InitiatedBefore
+	}
+	if d.OlderThan != nil {
+		cutoff := now.Add(-*d.OlderThan)
+		return &cutoff
+	}
+	return nil
+}
+
+// initiatedAfterAt returns the --newer-than bound as an initiation time, as of now
+func (d *DeleteOptions) initiatedAfterAt(now time.Time) *time.Time {
+	if d.InitiatedAfter != nil {
+		return d.InitiatedAfter
+	}
+	if d.NewerThan != nil {
+		cutoff := now.Add(-*d.NewerThan)
+		return &cutoff
+	}
+	return nil
+}
+
 // Validate validates ExportOptions struct
 func (e *ExportOptions) Validate() error {
 	validFormats := map[string]bool{
 		"csv":  true,
 		"json": true,
+		"xlsx": true,
 	}
 	
 	if !validFormats[strings.ToLower(e.Format)] {
-		return ValidationError{Field: "Format", Message: "format must be 'csv' or 'json'"}
+		return ValidationError{Field: "Format", Message: "format must be 'csv', 'json' or 'xlsx'"}
+	}
+	
+	if e.Compress && strings.ToLower(e.Format) == "xlsx" {
+		return ValidationError{Field: "Compress", Message: "xlsx files are already compressed"}
 	}
 	
 	if strings.TrimSpace(e.OutputFile) == "" {
@@ -1,6 +1,9 @@
 package types
 
 import (
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -65,6 +68,28 @@ func TestMultipartUploadValidation(t *testing.T) {
 		},
 	}
 
+	// Keys and upload IDs at and beyond the limits
+	valid := MultipartUpload{Bucket: "test-bucket", Key: "test-key", UploadID: "test-upload-id", Initiated: time.Now(), StorageClass: "STANDARD", Region: "us-east-1"}
+	for _, limit := range []struct {
+		name    string
+		modify  func(*MultipartUpload)
+		wantErr bool
+	}{
+		{"key at the limit, full of newlines", func(m *MultipartUpload) { m.Key = strings.Repeat("a\n", MaxKeyLength/2) }, false},
+		{"key over the limit", func(m *MultipartUpload) { m.Key = strings.Repeat("a", MaxKeyLength+1) }, true},
+		{"key with invalid UTF-8", func(m *MultipartUpload) { m.Key = "bad\xffkey" }, true},
+		{"upload ID over the limit", func(m *MultipartUpload) { m.UploadID = strings.Repeat("u", MaxUploadIDLength+1) }, true},
+		{"upload ID with a newline", func(m *MultipartUpload) { m.UploadID = "upload\nid" }, true},
+	} {
+		upload := valid
+		limit.modify(&upload)
+		tests = append(tests, struct {
+			name    string
+			upload  MultipartUpload
+			wantErr bool
+		}{limit.name, upload, limit.wantErr})
+	}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			err := tt.upload.Validate()
@@ -75,6 +100,26 @@ func TestMultipartUploadValidation(t *testing.T) {
 	}
 }
 
+func TestDisplayString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"logs/2024/a.bin", "logs/2024/a.bin"},
+		{"日本語/ファイル", "日本語/ファイル"},
+		{"line\nbreak\r\ttab", `line\nbreak\r\ttab`},
+		{"\x1b[2Jclear\x07", `\x1b[2Jclear\x07`},
+		{"bad\xffbyte", `bad\xffbyte`},
+		{"\u202egnp.exe", `\u202egnp.exe`},
+		{"para\u2028graph\u0085", `para\u2028graph\u0085`},
+	}
+	for _, tt := range tests {
+		if got := DisplayString(tt.in); got != tt.want {
+			t.Errorf("DisplayString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
 func TestListOptionsValidation(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -154,4 +199,79 @@ func TestDeleteOptionsValidation(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestNormalizeStorageClass(t *testing.T) {
+	for value, want := range map[string]string{
+		"standard":             "STANDARD",
+		" Standard-IA ":        "STANDARD_IA",
+		"glacier-ir":           "GLACIER_IR",
+		"intelligent_tiering":  "INTELLIGENT_TIERING",
+		"express_onezone":      "EXPRESS_ONEZONE",
+	} {
+		if got := NormalizeStorageClass(value); got != want {
+			t.Errorf("NormalizeStorageClass(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestBucketErrors(t *testing.T) {
+	denied := errors.New("AccessDenied")
+	failed := NewBucketErrors([]BucketError{
+		{Bucket: "media", Region: "us-east-1", Err: errors.New("timeout")},
+		{Bucket: "archive", Region: "eu-west-1", Err: denied},
+	})
+
+	if got, want := failed.Error(), "failed to list uploads for 2 buckets; archive: AccessDenied and 1 more"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if !errors.Is(failed, denied) {
+		t.Error("errors.Is() cannot find the error of a failed bucket")
+	}
+
+	data, err := json.Marshal(failed)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := `[{"bucket":"archive","region":"eu-west-1","error":"AccessDenied"},{"bucket":"media","region":"us-east-1","error":"timeout"}]`; string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+}
+
+func TestBucketTagFilter(t *testing.T) {
+	if _, err := ParseBucketTag("env"); err == nil {
+		t.Error("ParseBucketTag(\"env\") error = nil, want key=value required")
+	}
+	if tag, err := ParseBucketTag("owner=a=b"); err != nil || tag.Key != "owner" || tag.Value != "a=b" {
+		t.Errorf("ParseBucketTag(\"owner=a=b\") = %+v, %v, want the value after the first =", tag, err)
+	}
+
+	filter := &BucketTagFilter{
+		Include: []BucketTag{{Key: "env", Value: "prod"}, {Key: "env", Value: "staging"}, {Key: "team", Value: "data"}},
+		Exclude: []BucketTag{{Key: "protected", Value: "true"}},
+	}
+	tests := []struct {
+		tags map[string]string
+		want bool
+	}{
+		{map[string]string{"env": "prod", "team": "data"}, true},
+		{map[string]string{"env": "staging", "team": "data", "protected": "false"}, true},
+		{map[string]string{"env": "prod"}, false},
+		{map[string]string{"env": "dev", "team": "data"}, false},
+		{map[string]string{"env": "prod", "team": "data", "protected": "true"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := filter.Matches(tt.tags); got != tt.want {
+			t.Errorf("Matches(%v) = %v, want %v", tt.tags, got, tt.want)
+		}
+	}
+
+	selected := filter.Selected(map[string]string{"env": "prod", "team": "data", "cost-center": "42"})
+	if len(selected) != 2 || selected["env"] != "prod" || selected["team"] != "data" {
+		t.Errorf("Selected() = %v, want only the env and team tags", selected)
+	}
+	if got, want := filter.String(), "env=prod|staging, team=data, not protected=true"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,206 @@
+// Package units formats and parses the sizes and durations that s3mpc shows
+// and accepts, so that every flag and filter reads them the same way.
+//
+// Sizes are a number followed by an optional unit, e.g. "100MB", "1.5 GiB",
+// "10k" or "512". Units are case-insensitive and binary: K, KB and KiB are all
+// 1024 bytes, and likewise M, G, T and P. A number without a unit is bytes.
+//
+// Durations are one or more number and unit pairs, e.g. "7d", "1.5h",
+// "2w 3d" or "1h30m". Units are case-insensitive:
+//
+//	s, sec      seconds
+//	m, min      minutes
+//	h, hr       hours
+//	d           days (24 hours)
+//	w           weeks (7 days)
+//	mo          months (30 days)
+//	y           years (365 days)
+//
+// Both accept decimal numbers and spaces around the number and unit; neither
+// accepts negative values.
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// byteUnits maps each size unit, lowercased, to its multiplier
+var byteUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1 << 30,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1 << 40,
+	"tib": 1 << 40,
+	"p":   1 << 50,
+	"pb":  1 << 50,
+	"pib": 1 << 50,
+}
+
+// durationUnits maps each duration unit, lowercased, to its length
+var durationUnits = map[string]time.Duration{
+	"s":   time.Second,
+	"sec": time.Second,
+	"m":   time.Minute,
+	"min": time.Minute,
+	"h":   time.Hour,
+	"hr":  time.Hour,
+	"d":   24 * time.Hour,
+	"w":   7 * 24 * time.Hour,
+	"mo":  30 * 24 * time.Hour,
+	"y":   365 * 24 * time.Hour,
+}
+
+// FormatBytes formats bytes with one decimal in the largest binary unit that
+// keeps the number at least 1, e.g. "512 B" or "1.5 KB"
+func FormatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// ParseBytes parses a size such as "100MB" or "1.5 GiB" into bytes
+func ParseBytes(value string) (int64, error) {
+	s := strings.TrimSpace(value)
+	if s == "" {
+		return 0, fmt.Errorf("size cannot be empty")
+	}
+
+	number, rest := splitNumber(s)
+	if number == "" {
+		if strings.HasPrefix(s, "-") {
+			return 0, fmt.Errorf("size cannot be negative: %q", value)
+		}
+		return 0, fmt.Errorf("invalid size %q, expected a number and a unit such as 100MB or 1.5 GB", value)
+	}
+	unit := strings.ToLower(strings.TrimSpace(rest))
+	multiplier, ok := byteUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit %q in %q, use B, KB, MB, GB, TB or PB", strings.TrimSpace(rest), value)
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", value, err)
+	}
+	bytes := n * float64(multiplier)
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", value)
+	}
+	return int64(bytes), nil
+}
+
+// FormatDuration formats a duration in its largest whole unit of days, hours,
+// minutes or seconds, e.g. "7d" for 7 days and 5 hours. Minutes are "min",
+// since ages refuse a bare "m", so the result parses as an age too.
+func FormatDuration(duration time.Duration) string {
+	days := int(duration.Hours() / 24)
+	if days > 0 {
+		return fmt.Sprintf("%dd", days)
+	}
+
+	hours := int(duration.Hours())
+	if hours > 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+
+	minutes := int(duration.Minutes())
+	if minutes > 0 {
+		return fmt.Sprintf("%dmin", minutes)
+	}
+
+	return fmt.Sprintf("%ds", int(duration.Seconds()))
+}
+
+// ParseDuration parses a duration such as "7d", "1.5h" or "1h30m"
+func ParseDuration(value string) (time.Duration, error) {
+	s := strings.TrimSpace(value)
+	if s == "" {
+		return 0, fmt.Errorf("duration cannot be empty")
+	}
+	if strings.HasPrefix(s, "-") {
+		return 0, fmt.Errorf("duration cannot be negative: %q", value)
+	}
+
+	var total float64
+	for s != "" {
+		number, rest := splitNumber(s)
+		if number == "" {
+			return 0, fmt.Errorf("invalid duration %q, expected a number and a unit such as 7d or 12h", value)
+		}
+		rest = strings.TrimLeft(rest, " ")
+		end := strings.IndexFunc(rest, func(r rune) bool { return !isLetter(r) })
+		if end < 0 {
+			end = len(rest)
+		}
+		unit := strings.ToLower(rest[:end])
+		if unit == "" && rest == "" {
+			return 0, fmt.Errorf("missing unit in duration %q, use s, m, h, d, w, mo or y", value)
+		}
+		if unit == "" {
+			return 0, fmt.Errorf("invalid duration %q, expected a number and a unit such as 7d or 12h", value)
+		}
+		length, ok := durationUnits[unit]
+		if !ok {
+			return 0, fmt.Errorf("invalid duration unit %q in %q, use s, m, h, d, w, mo or y", rest[:end], value)
+		}
+
+		n, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", value, err)
+		}
+		total += n * float64(length)
+		s = strings.TrimLeft(rest[end:], " ")
+	}
+
+	if total >= math.MaxInt64 {
+		return 0, fmt.Errorf("duration %q is too long", value)
+	}
+	return time.Duration(total), nil
+}
+
+// splitNumber splits s after its leading decimal number, such as "1.5" in
+// "1.5GB"; the number is empty when s does not start with one
+func splitNumber(s string) (string, string) {
+	end, digits, dot := 0, 0, false
+	for ; end < len(s); end++ {
+		switch c := s[end]; {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !dot:
+			dot = true
+		default:
+			if digits == 0 {
+				return "", s
+			}
+			return s[:end], s[end:]
+		}
+	}
+	if digits == 0 {
+		return "", s
+	}
+	return s, ""
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
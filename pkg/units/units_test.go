@@ -0,0 +1,233 @@
+package units
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes    int64
+		expected string
+	}{
+		{0, "0 B"},
+		{512, "512 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{1572864, "1.5 MB"},
+		{1610612736, "1.5 GB"},
+		{1 << 40, "1.0 TB"},
+		{5 << 50, "5.0 PB"},
+		{1 << 60, "1.0 EB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatBytes(tt.bytes); got != tt.expected {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.bytes, got, tt.expected)
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		// A number alone is bytes
+		{"0", 0},
+		{"512", 512},
+		{"512B", 512},
+		{"512 b", 512},
+
+		// Short, long and binary spellings are the same unit
+		{"10K", 10 << 10},
+		{"10KB", 10 << 10},
+		{"10KiB", 10 << 10},
+		{"100MB", 100 << 20},
+		{"100M", 100 << 20},
+		{"100MiB", 100 << 20},
+		{"1GB", 1 << 30},
+		{"2TB", 2 << 40},
+		{"3PB", 3 << 50},
+
+		// Units are case-insensitive
+		{"100mb", 100 << 20},
+		{"100Mb", 100 << 20},
+		{"10k", 10 << 10},
+		{"1gib", 1 << 30},
+
+		// Decimal values
+		{"1.5GB", 3 << 29},
+		{"0.5 KB", 512},
+		{".5K", 512},
+		{"1.GB", 1 << 30},
+		{"2.25 MB", 2359296},
+
+		// Spaces around the number and the unit
+		{"100 MB", 100 << 20},
+		{"  100MB  ", 100 << 20},
+		{"100   MB", 100 << 20},
+		{"\t1 GB\n", 1 << 30},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBytes(tt.value)
+		if err != nil {
+			t.Errorf("ParseBytes(%q) error = %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseBytes(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseBytesErrors(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", "cannot be empty"},
+		{"   ", "cannot be empty"},
+		{"-1MB", "cannot be negative"},
+		{"-5", "cannot be negative"},
+		{"MB", "invalid size"},
+		{"invalid", "invalid size"},
+		{".", "invalid size"},
+		{"100XB", "invalid size unit"},
+		{"100 megabytes", "invalid size unit"},
+		{"1.5.2MB", "invalid size unit"},
+		{"1 0MB", "invalid size unit"},
+		{"100MB extra", "invalid size unit"},
+		{"1,000MB", "invalid size unit"},
+		{"10EB", "invalid size unit"},
+		{"99999999PB", "too large"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseBytes(tt.value)
+		if err == nil {
+			t.Errorf("ParseBytes(%q) succeeded, want an error", tt.value)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseBytes(%q) error = %q, want it to mention %q", tt.value, err, tt.want)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "0s"},
+		{45 * time.Second, "45s"},
+		{15 * time.Minute, "15min"},
+		{90 * time.Minute, "1h"},
+		{23 * time.Hour, "23h"},
+		{24 * time.Hour, "1d"},
+		{7*24*time.Hour + 5*time.Hour, "7d"},
+		{400 * 24 * time.Hour, "400d"},
+	}
+
+	for _, tt := range tests {
+		got := FormatDuration(tt.duration)
+		if got != tt.expected {
+			t.Errorf("FormatDuration(%v) = %q, want %q", tt.duration, got, tt.expected)
+		}
+		// Everything formatted can be given back as a flag or filter value
+		if _, err := ParseDuration(got); err != nil {
+			t.Errorf("ParseDuration(%q) error = %v", got, err)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"30s", 30 * time.Second},
+		{"30sec", 30 * time.Second},
+		{"15m", 15 * time.Minute},
+		{"15min", 15 * time.Minute},
+		{"6h", 6 * time.Hour},
+		{"6hr", 6 * time.Hour},
+		{"7d", 7 * day},
+		{"2w", 14 * day},
+		{"1mo", 30 * day},
+		{"1y", 365 * day},
+		{"0d", 0},
+
+		// Units are case-insensitive, so M is minutes like m
+		{"7D", 7 * day},
+		{"15M", 15 * time.Minute},
+		{"1MO", 30 * day},
+		{"2W", 14 * day},
+
+		// Decimal values
+		{"1.5d", 36 * time.Hour},
+		{"0.5h", 30 * time.Minute},
+		{".5d", 12 * time.Hour},
+		{"1.25y", day * 365 * 5 / 4},
+
+		// Spaces around the number and the unit
+		{"7 d", 7 * day},
+		{" 7d ", 7 * day},
+		{"\t12 h\n", 12 * time.Hour},
+
+		// Several units add up
+		{"1h30m", 90 * time.Minute},
+		{"1d12h", 36 * time.Hour},
+		{"2w 3d", 17 * day},
+		{"1 h 30 min", 90 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.value)
+		if err != nil {
+			t.Errorf("ParseDuration(%q) error = %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseDurationErrors(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", "cannot be empty"},
+		{"  ", "cannot be empty"},
+		{"-7d", "cannot be negative"},
+		{"7", "missing unit"},
+		{"1h30", "missing unit"},
+		{"d", "invalid duration"},
+		{"abc", "invalid duration"},
+		{"7d d", "invalid duration"},
+		{"7x", "invalid duration unit"},
+		{"7days", "invalid duration unit"},
+		{"5ms", "invalid duration unit"},
+		{"1.5.2d", "invalid duration"},
+		{"7d!", "invalid duration"},
+		{"99999999999y", "too long"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseDuration(tt.value)
+		if err == nil {
+			t.Errorf("ParseDuration(%q) succeeded, want an error", tt.value)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseDuration(%q) error = %q, want it to mention %q", tt.value, err, tt.want)
+		}
+	}
+}